@@ -0,0 +1,453 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Admin API for operating a long-running instance:
+//
+//	GET  /admin/sessions           list live sessions
+//	GET  /admin/concepts?n=20      top concept activations
+//	GET  /admin/reservoir          reservoir metrics
+//	POST /admin/reservoir/pause    freeze neuron dynamics
+//	POST /admin/reservoir/resume   resume neuron dynamics
+//	POST /admin/snapshot           write reservoir/concept .npz snapshots
+//	GET  /admin/loglevel           current log level
+//	PUT  /admin/loglevel           {"level": "warn"}
+//	GET  /admin/degradation        resource pressure and degraded mode
+//	GET  /admin/words?word=hi      a word's frequency and top k=10 successors
+//	GET  /admin/starters?n=20      sentence starter distribution
+//	GET  /admin/waves?axis=z       a rendered wave frame; plane, width and
+//	                               height override the configured view
+//	GET  /admin/channels           sends and overflow drops per bounded channel
+//	GET  /admin/profile            Think/Understand stage latency percentiles
+//
+// Requests need "Authorization: Bearer <token>" when a token is configured;
+// without one, only loopback clients are served.
+
+// Environment variable overriding the configured admin token
+const adminTokenEnv = "GENESIS_ADMIN_TOKEN"
+
+// AdminConfig controls the admin API
+type AdminConfig struct {
+	Token       string `json:"token"`
+	SnapshotDir string `json:"snapshot_dir"`
+}
+
+// ConceptActivation is one concept's current activation
+type ConceptActivation struct {
+	Concept    string  `json:"concept"`
+	Activation float64 `json:"activation"`
+}
+
+// ReservoirMetrics summarizes the liquid reservoir's current state
+type ReservoirMetrics struct {
+	Dimensions  [3]int                  `json:"dimensions"`
+	Neurons     int                     `json:"neurons"`
+	MeanState   float64                 `json:"mean_state"`
+	AboveHalf   float64                 `json:"above_half"` // fraction of neurons with state > 0.5
+	ActiveWaves int64                   `json:"active_waves"`
+	Paused      bool                    `json:"paused"`
+	Goroutines  int                     `json:"goroutines"`
+	Channels    map[string]ChannelStats `json:"channels"` // wave channel sends and drops
+	Wiring      ConnectivityStats       `json:"wiring"`   // reservoir degree distribution
+}
+
+// Pause freezes neuron dynamics; state is held until Resume
+func (brain *LiquidStateBrain) Pause() {
+	brain.paused.Store(true)
+	fmt.Println("⏸️  Reservoir paused")
+}
+
+// Resume restarts neuron dynamics after Pause
+func (brain *LiquidStateBrain) Resume() {
+	brain.paused.Store(false)
+	fmt.Println("▶️  Reservoir resumed")
+}
+
+// Paused reports whether the reservoir is paused
+func (brain *LiquidStateBrain) Paused() bool {
+	return brain.paused.Load()
+}
+
+// Metrics summarizes the reservoir state
+func (brain *LiquidStateBrain) Metrics() ReservoirMetrics {
+	states := brain.StateSnapshot()
+	m := ReservoirMetrics{
+		Dimensions:  [3]int{brain.dimensions.X, brain.dimensions.Y, brain.dimensions.Z},
+		Neurons:     len(states),
+		ActiveWaves: atomic.LoadInt64(&brain.activeWaves),
+		Paused:      brain.Paused(),
+		Goroutines:  runtime.NumGoroutine(),
+		Channels:    brain.ChannelStats(),
+		Wiring:      brain.ConnectivityStats(),
+	}
+	above := 0
+	for _, s := range states {
+		m.MeanState += s
+		if s > 0.5 {
+			above++
+		}
+	}
+	if len(states) > 0 {
+		m.MeanState /= float64(len(states))
+		m.AboveHalf = float64(above) / float64(len(states))
+	}
+	return m
+}
+
+// ConceptActivations returns the n most activated concepts, strongest first
+func (llm *TransparentLLM) ConceptActivations(n int) []ConceptActivation {
+	var result []ConceptActivation
+	llm.concepts.Range(func(concept string, neuron *ConceptNeuron) bool {
+		if act := neuron.getActivation(); act > 0 {
+			result = append(result, ConceptActivation{Concept: concept, Activation: act})
+		}
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Activation != result[j].Activation {
+			return result[i].Activation > result[j].Activation
+		}
+		return result[i].Concept < result[j].Concept
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// AdminHandler serves the admin API. Any of sessions, brain, llm and loader
+// may be nil; their endpoints then answer 503.
+type AdminHandler struct {
+	sessions    *SessionManager
+	brain       *LiquidStateBrain
+	llm         *TransparentLLM
+	loader      *DatasetLoader
+	token       string
+	snapshotDir string
+	degrader    *Degrader
+}
+
+func NewAdminHandler(config AdminConfig, sessions *SessionManager, brain *LiquidStateBrain, llm *TransparentLLM) *AdminHandler {
+	token := config.Token
+	if env := os.Getenv(adminTokenEnv); env != "" {
+		token = env
+	}
+	dir := config.SnapshotDir
+	if dir == "" {
+		dir = "snapshots"
+	}
+	h := &AdminHandler{
+		sessions:    sessions,
+		brain:       brain,
+		llm:         llm,
+		token:       token,
+		snapshotDir: dir,
+		degrader:    SharedDegrader(),
+	}
+	if llm != nil {
+		h.loader = llm.dataLoader
+	}
+	return h
+}
+
+// SetLoader sets the dataset whose language model the word endpoints inspect
+func (h *AdminHandler) SetLoader(loader *DatasetLoader) {
+	h.loader = loader
+}
+
+// SetBrain sets the liquid brain the reservoir endpoints operate on
+func (h *AdminHandler) SetBrain(brain *LiquidStateBrain) {
+	h.brain = brain
+}
+
+// SetLLM sets the transparent model whose concepts the admin API reports,
+// and its dataset when no loader has been set
+func (h *AdminHandler) SetLLM(llm *TransparentLLM) {
+	h.llm = llm
+	if llm != nil && h.loader == nil {
+		h.loader = llm.dataLoader
+	}
+}
+
+// authorized checks the bearer token, or loopback when no token is set
+func (h *AdminHandler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) == 1
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		writeAPIError(w, http.StatusUnauthorized, "authentication_error", "admin access denied")
+		return
+	}
+
+	route := r.Method + " " + strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/admin"), "/")
+	switch route {
+	case "GET /sessions":
+		h.listSessions(w)
+	case "GET /concepts":
+		h.concepts(w, r)
+	case "GET /reservoir":
+		if h.requireBrain(w) {
+			writeJSON(w, http.StatusOK, h.brain.Metrics())
+		}
+	case "POST /reservoir/pause":
+		if h.requireBrain(w) {
+			h.brain.Pause()
+			writeJSON(w, http.StatusOK, h.brain.Metrics())
+		}
+	case "POST /reservoir/resume":
+		if h.requireBrain(w) {
+			h.brain.Resume()
+			writeJSON(w, http.StatusOK, h.brain.Metrics())
+		}
+	case "POST /snapshot":
+		h.snapshot(w)
+	case "GET /loglevel":
+		writeJSON(w, http.StatusOK, map[string]string{"level": CurrentLogLevel().String()})
+	case "PUT /loglevel":
+		h.setLogLevel(w, r)
+	case "GET /degradation":
+		writeJSON(w, http.StatusOK, h.degrader.Status())
+	case "GET /words":
+		h.words(w, r)
+	case "GET /starters":
+		h.starters(w, r)
+	case "GET /waves":
+		h.waves(w, r)
+	case "GET /channels":
+		h.channels(w)
+	case "GET /profile":
+		h.profile(w)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found_error", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// profile reports the stage latencies of the brain's Think and the
+// concept network's Understand calls, keyed by operation; both are empty
+// unless profiling is enabled
+func (h *AdminHandler) profile(w http.ResponseWriter) {
+	profile := make(map[string][]StagePercentiles)
+	if h.brain != nil {
+		for operation, stages := range h.brain.Profiler().Percentiles() {
+			profile[operation] = stages
+		}
+	}
+	if h.llm != nil {
+		for operation, stages := range h.llm.Profiler().Percentiles() {
+			profile[operation] = stages
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"profile": profile})
+}
+
+func (h *AdminHandler) requireBrain(w http.ResponseWriter) bool {
+	if h.brain == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "no reservoir is running")
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) listSessions(w http.ResponseWriter) {
+	if h.sessions == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "sessions are not enabled")
+		return
+	}
+	summaries, err := h.sessions.List()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": summaries})
+}
+
+func (h *AdminHandler) concepts(w http.ResponseWriter, r *http.Request) {
+	if h.llm == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "no concept network is running")
+		return
+	}
+	n, ok := countParam(w, r, "n", 20)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"concepts": h.llm.ConceptActivations(n)})
+}
+
+// countParam reads a positive integer query parameter, answering 400 and
+// returning false when it is malformed
+func countParam(w http.ResponseWriter, r *http.Request, name string, fallback int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return fallback, true
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil || parsed <= 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", name+" must be a positive integer")
+		return 0, false
+	}
+	return parsed, true
+}
+
+func (h *AdminHandler) requireLoader(w http.ResponseWriter) bool {
+	if h.loader == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "no dataset is loaded")
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) words(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLoader(w) {
+		return
+	}
+	word := r.URL.Query().Get("word")
+	if word == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "word is required")
+		return
+	}
+	k, ok := countParam(w, r, "k", 10)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"word":          strings.ToLower(word),
+		"frequency":     h.loader.GetWordFrequency(word),
+		"in_vocabulary": h.loader.InVocabulary(strings.ToLower(word)),
+		"transitions":   h.loader.GetTopTransitions(word, k),
+	})
+}
+
+func (h *AdminHandler) starters(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLoader(w) {
+		return
+	}
+	n, ok := countParam(w, r, "n", 20)
+	if !ok {
+		return
+	}
+	starters := h.loader.GetStarterDistribution()
+	if len(starters) > n {
+		starters = starters[:n]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"starters": starters})
+}
+
+func (h *AdminHandler) waves(w http.ResponseWriter, r *http.Request) {
+	if !h.requireBrain(w) {
+		return
+	}
+	config := h.brain.config.Visualization.orDefault()
+	query := r.URL.Query()
+	if axis := query.Get("axis"); axis != "" {
+		config.Axis = axis
+	}
+	if plane := query.Get("plane"); plane != "" {
+		parsed, err := strconv.Atoi(plane)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "plane must be an integer")
+			return
+		}
+		config.Plane = parsed
+	}
+	var ok bool
+	if config.Width, ok = countParam(w, r, "width", config.Width); !ok {
+		return
+	}
+	if config.Height, ok = countParam(w, r, "height", config.Height); !ok {
+		return
+	}
+	frame, err := h.brain.RenderWaveFrame(config)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, frame)
+}
+
+func (h *AdminHandler) channels(w http.ResponseWriter) {
+	if h.brain == nil && h.llm == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "nothing is running")
+		return
+	}
+	out := make(map[string]map[string]ChannelStats)
+	if h.brain != nil {
+		out["reservoir"] = h.brain.ChannelStats()
+	}
+	if h.llm != nil {
+		out["concepts"] = h.llm.ChannelStats()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *AdminHandler) snapshot(w http.ResponseWriter) {
+	if h.brain == nil && h.llm == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable_error", "nothing to snapshot")
+		return
+	}
+	if err := os.MkdirAll(h.snapshotDir, 0755); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405.000")
+	var files []string
+	if h.brain != nil {
+		path := filepath.Join(h.snapshotDir, "reservoir-"+stamp+".npz")
+		if err := ExportReservoirStatesNpz(h.brain, path, 1, time.Millisecond); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		files = append(files, path)
+	}
+	if h.llm != nil {
+		path := filepath.Join(h.snapshotDir, "concepts-"+stamp+".npz")
+		if err := ExportConceptsNpz(h.llm, path); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		files = append(files, path)
+	}
+	fmt.Printf("📸 Wrote admin snapshot: %v\n", files)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"files": files})
+}
+
+func (h *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	level, err := ParseLogLevel(req.Level)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	SetLogLevel(level)
+	writeJSON(w, http.StatusOK, map[string]string{"level": level.String()})
+}
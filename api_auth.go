@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API authentication: serve mode trusts every caller, which is fine on
+// localhost but not once the server is reachable from elsewhere. With API
+// keys in the server config section, every API request must carry one
+// ("Authorization: Bearer <key>"), and each key can be held to a request
+// rate and a token rate, both per minute:
+//
+//	"server": {"api_keys": [{"name": "ci", "key": "...", "requests_per_minute": 60, "tokens_per_minute": 5000}]}
+//
+// Genesis tokens are words, so a request costs the words in its request
+// and response bodies. Its cost is only known once it has been answered, so
+// tokens are charged afterwards: a key over its token rate is refused until
+// the rate has paid the overdraft back. Refused requests get 401 for a
+// missing or unknown key and 429 with Retry-After for a key over its rate.
+// Health checks and routes with access control of their own aren't
+// wrapped. With no keys configured nothing is checked. Handlers learn which
+// key a request was admitted with from APIKeyName, which is how
+// capability_policies.api_keys picks its policy.
+
+// ServerConfig controls how serve mode admits callers
+type ServerConfig struct {
+	APIKeys []APIKeyConfig `json:"api_keys"` // none leaves the API open
+}
+
+// APIKeyConfig is one caller's key and limits
+type APIKeyConfig struct {
+	Name              string `json:"name"` // names the caller in errors
+	Key               string `json:"key"`
+	RequestsPerMinute int    `json:"requests_per_minute"` // 0 is unlimited
+	TokensPerMinute   int    `json:"tokens_per_minute"`   // 0 is unlimited
+}
+
+func (c ServerConfig) validate() error {
+	names := make(map[string]bool, len(c.APIKeys))
+	keys := make(map[string]bool, len(c.APIKeys))
+	for _, k := range c.APIKeys {
+		if k.Name == "" || k.Key == "" {
+			return fmt.Errorf("server api_keys need a name and a key")
+		}
+		if names[k.Name] || keys[k.Key] {
+			return fmt.Errorf("server api_keys: duplicate name or key for %q", k.Name)
+		}
+		names[k.Name], keys[k.Key] = true, true
+		if k.RequestsPerMinute < 0 || k.TokensPerMinute < 0 {
+			return fmt.Errorf("server api_keys %q: rates must not be negative", k.Name)
+		}
+	}
+	return nil
+}
+
+// rateBucket refills at a per-minute rate up to one minute's worth
+type rateBucket struct {
+	perMinute float64
+	level     float64   // below 0 is an overdraft
+	at        time.Time // zero until first used
+}
+
+func newRateBucket(perMinute int) *rateBucket {
+	return &rateBucket{perMinute: float64(perMinute), level: float64(perMinute)}
+}
+
+// refill adds what the rate has earned since the last call
+func (b *rateBucket) refill(now time.Time) {
+	if b.at.IsZero() {
+		b.at = now
+		return
+	}
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.level = math.Min(b.perMinute, b.level+elapsed.Minutes()*b.perMinute)
+		b.at = now
+	}
+}
+
+// wait is how long until the bucket holds at least need
+func (b *rateBucket) wait(need float64) time.Duration {
+	if b.level >= need {
+		return 0
+	}
+	return time.Duration((need - b.level) / b.perMinute * float64(time.Minute))
+}
+
+// apiKey is a key's limits and what it has used of them; nil buckets are
+// unlimited
+type apiKey struct {
+	name     string
+	key      []byte
+	requests *rateBucket
+	tokens   *rateBucket
+}
+
+// APIAuth checks API keys and holds each to its rates
+type APIAuth struct {
+	mu   sync.Mutex
+	keys []*apiKey
+	now  func() time.Time
+}
+
+// NewAPIAuth returns the authenticator for config's keys, or nil when it
+// has none
+func NewAPIAuth(config ServerConfig) *APIAuth {
+	if len(config.APIKeys) == 0 {
+		return nil
+	}
+	auth := &APIAuth{now: time.Now}
+	for _, k := range config.APIKeys {
+		key := &apiKey{name: k.Name, key: []byte(k.Key)}
+		if k.RequestsPerMinute > 0 {
+			key.requests = newRateBucket(k.RequestsPerMinute)
+		}
+		if k.TokensPerMinute > 0 {
+			key.tokens = newRateBucket(k.TokensPerMinute)
+		}
+		auth.keys = append(auth.keys, key)
+	}
+	return auth
+}
+
+// lookup finds the key a request presents, comparing against every key in
+// constant time
+func (a *APIAuth) lookup(r *http.Request) *apiKey {
+	given := r.Header.Get("Authorization")
+	if !strings.HasPrefix(given, "Bearer ") {
+		return nil
+	}
+	given = strings.TrimPrefix(given, "Bearer ")
+	var found *apiKey
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(given), key.key) == 1 {
+			found = key
+		}
+	}
+	return found
+}
+
+// admit takes one request from key's rates, or returns how long to wait
+func (a *APIAuth) admit(key *apiKey) (time.Duration, string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if key.tokens != nil {
+		key.tokens.refill(now)
+		// A request's tokens aren't known yet, so only an overdraft refuses it
+		if wait := key.tokens.wait(0); wait > 0 {
+			return wait, "token"
+		}
+	}
+	if key.requests != nil {
+		key.requests.refill(now)
+		if wait := key.requests.wait(1); wait > 0 {
+			return wait, "request"
+		}
+		key.requests.level--
+	}
+	return 0, ""
+}
+
+// charge takes a request's tokens from key's token rate
+func (a *APIAuth) charge(key *apiKey, tokens int) {
+	if key.tokens == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key.tokens.refill(a.now())
+	key.tokens.level -= float64(tokens)
+}
+
+// wordCounter counts words across the chunks it is shown
+type wordCounter struct {
+	words  int
+	inWord bool
+}
+
+func (c *wordCounter) count(p []byte) {
+	for _, b := range p {
+		space := b == ' ' || b == '\n' || b == '\t' || b == '\r'
+		if !space && !c.inWord {
+			c.words++
+		}
+		c.inWord = !space
+	}
+}
+
+// countingBody counts the words read from a request body
+type countingBody struct {
+	io.ReadCloser
+	wordCounter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.count(p[:n])
+	return n, err
+}
+
+// countingWriter counts the words written to a response
+type countingWriter struct {
+	http.ResponseWriter
+	wordCounter
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.count(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush lets server-sent events through
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// apiKeyContextKey carries the name of the key a request was admitted with
+type apiKeyContextKey struct{}
+
+// APIKeyName returns the name of the API key r was admitted with, or ""
+// when the API is open
+func APIKeyName(r *http.Request) string {
+	name, _ := r.Context().Value(apiKeyContextKey{}).(string)
+	return name
+}
+
+// Middleware refuses requests without a known key or over their key's
+// rates, and charges answered requests to their key
+func (a *APIAuth) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := a.lookup(r)
+		if key == nil {
+			writeAPIError(w, http.StatusUnauthorized, "authentication_error", "missing or unknown API key")
+			return
+		}
+		if wait, limit := a.admit(key); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limit_error", fmt.Sprintf("API key %q is over its %s rate", key.name, limit))
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key.name))
+		if key.tokens == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+		counted := &countingWriter{ResponseWriter: w}
+		next.ServeHTTP(counted, r)
+		a.charge(key, body.words+counted.words)
+	})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reasoning audit log: with audit mode on, every answered message is
+// appended to a JSON Lines file as a record of the input, the explanation,
+// the response and which model and version produced it. Each record carries
+// the hash of the one before it, so editing, removing or reordering records
+// breaks the chain, and an HMAC signature when a key is configured, so the
+// chain can't simply be recomputed. `genesis audit verify` checks both.
+
+// auditKeyEnv overrides the configured signing key, keeping it out of
+// config files
+const auditKeyEnv = "GENESIS_AUDIT_KEY"
+
+// AuditConfig controls the reasoning audit log
+type AuditConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	Key     string `json:"key"` // HMAC signing key; GENESIS_AUDIT_KEY overrides it
+}
+
+func (c AuditConfig) validate() error {
+	if c.Enabled && c.Path == "" {
+		return fmt.Errorf("audit path is required when the audit log is enabled")
+	}
+	return nil
+}
+
+// signingKey returns the key records are signed with, if any
+func (c AuditConfig) signingKey() string {
+	if env := os.Getenv(auditKeyEnv); env != "" {
+		return env
+	}
+	return c.Key
+}
+
+// AuditRecord is one line of the audit log
+type AuditRecord struct {
+	Seq          int64           `json:"seq"`
+	Time         time.Time       `json:"time"`
+	Session      string          `json:"session,omitempty"`
+	Model        string          `json:"model"`
+	ModelVersion string          `json:"model_version"`
+	Input        string          `json:"input"`
+	Explanation  json.RawMessage `json:"explanation,omitempty"`
+	Response     string          `json:"response"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash,omitempty"`      // SHA-256 of the record without hash and signature
+	Signature    string          `json:"signature,omitempty"` // HMAC-SHA256 of hash
+}
+
+// digest returns the record's hash, computed without its hash and signature
+func (rec AuditRecord) digest() (string, error) {
+	rec.Hash, rec.Signature = "", ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func signAuditHash(key, hash string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// modelIdentity names the model type and a version derived from the
+// corpus and config it serves with
+func modelIdentity(config *Config, loader *DatasetLoader) (model, version string) {
+	model = config.Model.Type
+	if model == "" {
+		model = "transparent"
+	}
+	configJSON, _ := json.Marshal(config)
+	configSum := sha256.Sum256(configJSON)
+	version = "config:" + hex.EncodeToString(configSum[:6])
+	if loader != nil {
+		version = "corpus:" + loader.CorpusFingerprint()[:12] + " " + version
+	}
+	return model, version
+}
+
+// AuditLog appends hash-chained records to an audit file
+type AuditLog struct {
+	mu       sync.Mutex
+	file     *os.File
+	key      string
+	model    string
+	version  string
+	seq      int64
+	lastHash string
+}
+
+// OpenAuditLog opens path for appending, continuing the chain of any
+// records already in it. An empty key leaves records unsigned.
+func OpenAuditLog(path, key, model, version string) (*AuditLog, error) {
+	log := &AuditLog{key: key, model: model, version: version}
+	if err := readAuditLog(path, func(rec AuditRecord) error {
+		log.seq, log.lastHash = rec.Seq, rec.Hash
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	log.file = file
+	fmt.Printf("🔏 Auditing responses to %s\n", path)
+	return log, nil
+}
+
+// Append records one answered input
+func (l *AuditLog) Append(session, input, response string, explanation *Explanation) error {
+	return l.AppendFrom(l.model, session, input, response, explanation)
+}
+
+// AppendFrom records one input answered by model rather than the log's
+// own model, such as the liquid brain behind /v1/think
+func (l *AuditLog) AppendFrom(model, session, input, response string, explanation *Explanation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := AuditRecord{
+		Seq:          l.seq + 1,
+		Time:         time.Now().UTC(),
+		Session:      session,
+		Model:        model,
+		ModelVersion: l.version,
+		Input:        input,
+		Response:     response,
+		PrevHash:     l.lastHash,
+	}
+	if explanation != nil {
+		data, err := json.Marshal(explanation)
+		if err != nil {
+			return fmt.Errorf("failed to encode explanation: %w", err)
+		}
+		rec.Explanation = data
+	}
+	hash, err := rec.digest()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit record: %w", err)
+	}
+	rec.Hash = hash
+	if l.key != "" {
+		rec.Signature = signAuditHash(l.key, hash)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.seq, l.lastHash = rec.Seq, hash
+	return nil
+}
+
+// Close closes the audit file
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// readAuditLog calls fn with each record of the file at path, in order
+func readAuditLog(path string, fn func(AuditRecord) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// VerifyAuditLog checks that every record's hash matches its contents and
+// links to the record before it, and with a key that every record is
+// signed with it. It returns the number of records verified before any
+// problem.
+func VerifyAuditLog(path, key string) (int, error) {
+	count := 0
+	prev := ""
+	err := readAuditLog(path, func(rec AuditRecord) error {
+		if rec.Seq != int64(count+1) {
+			return fmt.Errorf("record %d has sequence number %d", count+1, rec.Seq)
+		}
+		if rec.PrevHash != prev {
+			return fmt.Errorf("record %d doesn't follow the record before it", rec.Seq)
+		}
+		hash, err := rec.digest()
+		if err != nil {
+			return err
+		}
+		if hash != rec.Hash {
+			return fmt.Errorf("record %d was modified: hash mismatch", rec.Seq)
+		}
+		if key != "" && !hmac.Equal([]byte(rec.Signature), []byte(signAuditHash(key, rec.Hash))) {
+			return fmt.Errorf("record %d has a missing or invalid signature", rec.Seq)
+		}
+		prev = rec.Hash
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// AuditMain implements `go run . audit verify <file>`
+func AuditMain(args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Println("usage: genesis audit verify [-key k] <audit-log>")
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	key := fs.String("key", os.Getenv(auditKeyEnv), "HMAC key the records were signed with (default $"+auditKeyEnv+")")
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fmt.Println("usage: genesis audit verify [-key k] <audit-log>")
+		os.Exit(2)
+	}
+
+	count, err := VerifyAuditLog(fs.Arg(0), *key)
+	if err != nil {
+		fmt.Printf("❌ Audit log invalid after %d good records: %v\n", count, err)
+		os.Exit(1)
+	}
+	if *key == "" {
+		fmt.Printf("✅ %d records, hash chain intact (signatures not checked: no key)\n", count)
+		return
+	}
+	fmt.Printf("✅ %d records, hash chain and signatures intact\n", count)
+}
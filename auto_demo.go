@@ -35,7 +35,7 @@ func AutoDemo() {
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	
 	brain := NewLiquidStateBrain(20)
-	time.Sleep(500 * time.Millisecond)
+	brain.settle()
 	
 	inputs := []string{
 		"hello world",
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Beam search controls: beam scores add up word by word, so beams are
+// compared after length normalization, dividing by ((5 + length) / 6) to
+// the power of the length penalty as in GNMT. Beams are also split into
+// groups searched one after another at each step (diverse beam search);
+// a candidate word already chosen at the same step by an earlier group has
+// its score divided by 1 + the diversity penalty for each time it was
+// chosen, a Hamming diversity penalty that keeps groups from collapsing
+// onto the same near-identical candidates.
+
+// BeamSearchConfig controls length normalization and beam diversity
+type BeamSearchConfig struct {
+	LengthPenalty    float64 `json:"length_penalty"`    // 0 compares raw scores; higher favours longer beams less
+	Groups           int     `json:"groups"`            // diverse beam groups; 1 is plain beam search
+	DiversityPenalty float64 `json:"diversity_penalty"` // per repeat of a word chosen by an earlier group; 0 disables
+}
+
+// defaultBeamSearch matches the starter config
+var defaultBeamSearch = BeamSearchConfig{LengthPenalty: 0.6, Groups: 2, DiversityPenalty: 0.5}
+
+func (c BeamSearchConfig) validate() error {
+	if c.LengthPenalty < 0 || c.DiversityPenalty < 0 {
+		return fmt.Errorf("beam search penalties must not be negative")
+	}
+	if c.Groups < 1 {
+		return fmt.Errorf("beam search needs at least one group")
+	}
+	return nil
+}
+
+// SetBeamSearch sets the length normalization and diversity controls. An
+// invalid config, such as the zero value, is rejected and the current
+// settings kept.
+func (gen *ResponseGenerator) SetBeamSearch(config BeamSearchConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.search = config
+	return nil
+}
+
+// normalizedScore returns a beam's score divided by its length penalty
+func (gen *ResponseGenerator) normalizedScore(beam Beam) float64 {
+	if gen.search.LengthPenalty == 0 {
+		return beam.score
+	}
+	return beam.score / math.Pow((5+float64(len(beam.words)))/6, gen.search.LengthPenalty)
+}
+
+// beamGroups returns how many groups to split the starter beams into: at
+// most one per starter
+func (gen *ResponseGenerator) beamGroups(starters int) int {
+	return max(1, min(gen.search.Groups, starters))
+}
+
+// groupWidth returns the beams each of groups keeps per step, so all groups
+// together keep about as many as plain beam search
+func (gen *ResponseGenerator) groupWidth(groups int) int {
+	return max(1, gen.width()*2/groups)
+}
+
+// width returns the beams to keep per step, fewer while degraded
+func (gen *ResponseGenerator) width() int {
+	return SharedDegrader().BeamWidth(gen.beamWidth)
+}
+
+// diversify divides the scores of candidates earlier groups already chose
+// at this step and re-ranks them
+func (gen *ResponseGenerator) diversify(candidates []wordCandidate, chosen map[string]int) {
+	if len(chosen) == 0 || gen.search.DiversityPenalty == 0 {
+		return
+	}
+	for i := range candidates {
+		if n := chosen[candidates[i].word]; n > 0 {
+			candidates[i].score /= 1 + gen.search.DiversityPenalty*float64(n)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+}
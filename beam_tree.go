@@ -0,0 +1,89 @@
+package main
+
+// Beam-search diagnostics: with GenerationOptions.Diagnostics set, the
+// generator records every beam it considered as a tree, with each
+// expansion's word and scores and why beams were pruned, and returns it in
+// the explanation. A degenerate response can then be traced to where the
+// search went wrong instead of guessed at from the final string.
+
+// Reasons a beam stopped being searched
+const (
+	pruneBeamWidth = "beam_width" // outscored when the top beams were kept
+	pruneHook      = "hook"       // dropped or aborted by a token hook
+	pruneStop      = "stop"       // a starter matching a stop sequence
+	pruneBanned    = "banned"     // a starter the request's word bias bans
+)
+
+// BeamTree is the search a Generate call ran
+type BeamTree struct {
+	Nodes  []BeamNode `json:"nodes"`  // in creation order; a node's ID is its index
+	Chosen int        `json:"chosen"` // the node whose words became the response, -1 if none
+}
+
+// BeamNode is one beam: its parent's words plus Word
+type BeamNode struct {
+	ID         int     `json:"id"`
+	Parent     int     `json:"parent"` // -1 for starters
+	Step       int     `json:"step"`   // words before this one
+	Word       string  `json:"word"`
+	WordScore  float64 `json:"word_score"`            // the candidate's score
+	Score      float64 `json:"score"`                 // the beam's cumulative score
+	Candidates int     `json:"candidates,omitempty"`  // ranked next words when the beam was expanded
+	Complete   bool    `json:"complete"`              // ended by an ender, the length limit or a stop sequence
+	Pruned     string  `json:"pruned,omitempty"`      // why the beam stopped being searched, if it did
+	FinalScore float64 `json:"final_score,omitempty"` // response score, for beams that survived the search
+}
+
+// add records a beam and returns its node ID; -1 when not recording
+func (t *BeamTree) add(parent, step int, word string, wordScore, score float64) int {
+	if t == nil {
+		return -1
+	}
+	t.Nodes = append(t.Nodes, BeamNode{ID: len(t.Nodes), Parent: parent, Step: step, Word: word, WordScore: wordScore, Score: score})
+	return len(t.Nodes) - 1
+}
+
+// node returns the node with ID id, or nil
+func (t *BeamTree) node(id int) *BeamNode {
+	if t == nil || id < 0 || id >= len(t.Nodes) {
+		return nil
+	}
+	return &t.Nodes[id]
+}
+
+// prune records why a beam stopped being searched
+func (t *BeamTree) prune(id int, reason string) {
+	if n := t.node(id); n != nil {
+		n.Pruned = reason
+	}
+}
+
+// expanded records that a beam was expanded from candidates ranked words
+func (t *BeamTree) expanded(id, candidates int) {
+	if n := t.node(id); n != nil {
+		n.Candidates = candidates
+	}
+}
+
+// complete records that a beam ended
+func (t *BeamTree) complete(id int) {
+	if n := t.node(id); n != nil {
+		n.Complete = true
+	}
+}
+
+// scored records a surviving beam's response score
+func (t *BeamTree) scored(id int, score float64) {
+	if n := t.node(id); n != nil {
+		n.FinalScore = score
+	}
+}
+
+// Path returns the words from the starter to node id
+func (t *BeamTree) Path(id int) []string {
+	var words []string
+	for n := t.node(id); n != nil; n = t.node(n.Parent) {
+		words = append([]string{n.Word}, words...)
+	}
+	return words
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Standalone liquid brain checkpoints: Load restores a checkpoint's states
+// and trained outputs into a brain that was already built, which means
+// wiring a fresh reservoir first, only to overwrite part of it. Liquid
+// checkpoints also record the reservoir's topology (thresholds, refractory
+// periods, connections, inhibitory synapses and I/O wiring), so
+// LoadLiquidStateBrain can rebuild the saved brain exactly, warm states
+// included, without connecting anything from scratch. Checkpoints are
+// versioned JSON; see checkpointVersion.
+
+// checkpointTopology is a liquid reservoir's wiring. Neurons are numbered
+// in x, y, z order, as in States.
+type checkpointTopology struct {
+	Thresholds  []float64         `json:"thresholds"`
+	Refractory  []int64           `json:"refractory_ms"`
+	Connections [][]int32         `json:"connections"`          // per neuron, the neurons it drives
+	Inhibitory  [][]bool          `json:"inhibitory,omitempty"` // per neuron and connection; null when all excite
+	Inputs      []checkpointWires `json:"inputs"`               // input neurons, in input layer order
+	Outputs     []checkpointWires `json:"outputs"`              // output neurons, in output layer order
+	Taps        []checkpointTap   `json:"taps,omitempty"`
+}
+
+// checkpointWires is an input or output neuron and the reservoir neurons
+// it connects to
+type checkpointWires struct {
+	Name    string  `json:"name"` // input word or output meaning
+	Neurons []int32 `json:"neurons"`
+}
+
+// checkpointTap is an output tap's wiring, one entry per output
+type checkpointTap struct {
+	Name    string    `json:"name"`
+	Outputs [][]int32 `json:"outputs"`
+}
+
+// topology returns the template's wiring in checkpoint form
+func (t *BrainTemplate) topology() *checkpointTopology {
+	topology := &checkpointTopology{
+		Thresholds:  t.thresholds,
+		Refractory:  t.refractory,
+		Connections: t.connections,
+	}
+	for _, inhibitory := range t.inhibitory {
+		if inhibitory != nil {
+			topology.Inhibitory = t.inhibitory
+			break
+		}
+	}
+	for i, word := range t.inputWords {
+		topology.Inputs = append(topology.Inputs, checkpointWires{Name: word, Neurons: t.inputs[i]})
+	}
+	for i, meaning := range t.outputWords {
+		topology.Outputs = append(topology.Outputs, checkpointWires{Name: meaning, Neurons: t.outputs[i]})
+	}
+	for _, tap := range t.taps {
+		topology.Taps = append(topology.Taps, checkpointTap{Name: tap.name, Outputs: tap.outputs})
+	}
+	return topology
+}
+
+// validate checks the wiring fits a reservoir of the given dimensions
+func (c *checkpointTopology) validate(dims Dimensions) error {
+	n := dims.X * dims.Y * dims.Z
+	if len(c.Thresholds) != n || len(c.Refractory) != n || len(c.Connections) != n {
+		return fmt.Errorf("checkpoint topology doesn't cover %d neurons", n)
+	}
+	if c.Inhibitory != nil && len(c.Inhibitory) != n {
+		return fmt.Errorf("checkpoint topology has inhibitory flags for %d of %d neurons", len(c.Inhibitory), n)
+	}
+	inRange := func(indices []int32) bool {
+		for _, i := range indices {
+			if i < 0 || int(i) >= n {
+				return false
+			}
+		}
+		return true
+	}
+	for i, targets := range c.Connections {
+		if !inRange(targets) {
+			return fmt.Errorf("checkpoint neuron %d connects outside the reservoir", i)
+		}
+		if c.Inhibitory != nil && c.Inhibitory[i] != nil && len(c.Inhibitory[i]) != len(targets) {
+			return fmt.Errorf("checkpoint neuron %d has %d connections but %d inhibitory flags", i, len(targets), len(c.Inhibitory[i]))
+		}
+	}
+	for _, wires := range append(append([]checkpointWires(nil), c.Inputs...), c.Outputs...) {
+		if !inRange(wires.Neurons) {
+			return fmt.Errorf("checkpoint neuron %q connects outside the reservoir", wires.Name)
+		}
+	}
+	for _, tap := range c.Taps {
+		if len(tap.Outputs) != len(c.Outputs) {
+			return fmt.Errorf("checkpoint tap %q has %d outputs, want %d", tap.Name, len(tap.Outputs), len(c.Outputs))
+		}
+		for _, neurons := range tap.Outputs {
+			if !inRange(neurons) {
+				return fmt.Errorf("checkpoint tap %q connects outside the reservoir", tap.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadLiquidStateBrain rebuilds a liquid brain saved by Save, using the
+// default configuration
+func LoadLiquidStateBrain(path string) (*LiquidStateBrain, error) {
+	return LoadLiquidStateBrainWithConfig(path, DefaultConfig())
+}
+
+// LoadLiquidStateBrainWithConfig rebuilds a liquid brain saved by Save: the
+// reservoir is wired from the checkpoint's topology, then its states,
+// trained outputs and calibrations are restored. A brain saved paused comes
+// back paused.
+func LoadLiquidStateBrainWithConfig(path string, config *Config) (*LiquidStateBrain, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	checkpoint, err := readCheckpoint(path, "liquid")
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Dimensions == nil || checkpoint.Topology == nil {
+		return nil, fmt.Errorf("checkpoint has no reservoir topology; Load it into a brain of the same size instead")
+	}
+	dims := *checkpoint.Dimensions
+	if dims.X < 1 || dims.Y < 1 || dims.Z < 1 {
+		return nil, fmt.Errorf("checkpoint reservoir %dx%dx%d is empty", dims.X, dims.Y, dims.Z)
+	}
+	topology := checkpoint.Topology
+	if err := topology.validate(dims); err != nil {
+		return nil, err
+	}
+
+	n := dims.X * dims.Y * dims.Z
+	t := &BrainTemplate{
+		config:      config,
+		dims:        dims,
+		clock:       RealClock,
+		thresholds:  topology.Thresholds,
+		refractory:  topology.Refractory,
+		connections: topology.Connections,
+		inhibitory:  topology.Inhibitory,
+		schema:      conceptSchemaFromConfig(config),
+		templates:   templateLibraryFromConfig(config),
+	}
+	if t.inhibitory == nil {
+		t.inhibitory = make([][]bool, n)
+	}
+	for _, in := range topology.Inputs {
+		t.inputWords = append(t.inputWords, in.Name)
+		t.inputs = append(t.inputs, in.Neurons)
+	}
+	for _, out := range topology.Outputs {
+		t.outputWords = append(t.outputWords, out.Name)
+		t.outputs = append(t.outputs, out.Neurons)
+	}
+	t.calibration = make([]*OutputCalibration, len(t.outputWords)) // restored below
+	for _, tap := range topology.Taps {
+		t.taps = append(t.taps, tapTemplate{name: tap.Name, outputs: tap.Outputs})
+	}
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		fmt.Printf("Warning: failed to load dataset: %v\n", err)
+	} else {
+		t.loader = loader
+	}
+
+	fmt.Printf("📂 Loading Liquid State Brain: %d x %d x %d = %d neurons from %s\n", dims.X, dims.Y, dims.Z, n, path)
+	brain := t.build()
+	brain.paused.Store(checkpoint.Paused)
+	if err := brain.restore(checkpoint); err != nil {
+		brain.Cleanup()
+		return nil, err
+	}
+	brain.startDynamics()
+	ModelUpdated()
+	return brain, nil
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BrainManager owns named model instances, each with its own config. An
+// instance is created on first use, and idle instances are evicted least
+// recently used first when the manager exceeds its instance limit or the
+// heap grows past the memory budget. Instances that are processing a request
+// are never evicted, and pinned instances are only removed explicitly.
+
+var ErrUnknownInstance = errors.New("unknown model instance")
+
+// InstanceSpec describes a model instance the manager can create
+type InstanceSpec struct {
+	Name    string
+	Config  *Config       // Model.Type picks the model NewModel builds
+	Size    int           // reservoir size for liquid brains and orchestrators
+	Pinned  bool          // never evicted automatically
+	IdleTTL time.Duration // evict after this long unused; 0 keeps it
+}
+
+type managedInstance struct {
+	spec     InstanceSpec
+	model    Model // nil until created
+	inUse    int
+	lastUsed time.Time
+	memoryMB float64 // heap growth observed while creating the instance
+	ready    chan struct{}
+	err      error
+}
+
+// InstanceStatus reports one registered instance
+type InstanceStatus struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Loaded   bool      `json:"loaded"`
+	Pinned   bool      `json:"pinned"`
+	InUse    int       `json:"in_use"`
+	LastUsed time.Time `json:"last_used,omitempty"`
+	MemoryMB float64   `json:"memory_mb"`
+}
+
+// BrainManager creates, routes to and evicts model instances
+type BrainManager struct {
+	mu           sync.Mutex
+	instances    map[string]*managedInstance
+	maxLoaded    int     // 0 means no instance limit
+	memoryBudget float64 // MB of heap; 0 means no budget
+	router       func(tenant, input string) string
+	fallback     string
+	heapMB       func() float64         // current heap in MB
+	policies     CapabilityPolicyConfig // capability policy per tenant
+}
+
+// NewBrainManager creates a manager keeping at most maxLoaded instances and
+// evicting when the heap exceeds memoryBudgetMB
+func NewBrainManager(maxLoaded, memoryBudgetMB int) *BrainManager {
+	return &BrainManager{
+		instances:    make(map[string]*managedInstance),
+		maxLoaded:    maxLoaded,
+		memoryBudget: float64(memoryBudgetMB),
+		heapMB: func() float64 {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return float64(m.HeapAlloc) / (1 << 20)
+		},
+	}
+}
+
+// Register adds an instance spec. The first registered instance is the
+// fallback for requests no route matches.
+func (bm *BrainManager) Register(spec InstanceSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("instance name is required")
+	}
+	if spec.Config == nil {
+		spec.Config = DefaultConfig()
+	}
+	if err := spec.Config.Validate(); err != nil {
+		return fmt.Errorf("instance %q: %w", spec.Name, err)
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if _, exists := bm.instances[spec.Name]; exists {
+		return fmt.Errorf("instance %q is already registered", spec.Name)
+	}
+	bm.instances[spec.Name] = &managedInstance{spec: spec}
+	if bm.fallback == "" {
+		bm.fallback = spec.Name
+	}
+	return nil
+}
+
+// SetRouter installs a function choosing the instance for a request. An
+// empty or unknown result falls back to the tenant name, then to the first
+// registered instance.
+func (bm *BrainManager) SetRouter(router func(tenant, input string) string) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	bm.router = router
+}
+
+// SetCapabilityPolicies restricts the capabilities each tenant's requests
+// may engage
+func (bm *BrainManager) SetCapabilityPolicies(policies CapabilityPolicyConfig) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	bm.policies = policies
+}
+
+// Route returns the instance name that should handle a request
+func (bm *BrainManager) Route(tenant, input string) string {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if bm.router != nil {
+		if name := bm.router(tenant, input); bm.instances[name] != nil {
+			return name
+		}
+	}
+	if bm.instances[tenant] != nil {
+		return tenant
+	}
+	return bm.fallback
+}
+
+// Process routes input to an instance, creating it if needed, under the
+// tenant's capability policy
+func (bm *BrainManager) Process(tenant, input string) (string, error) {
+	name := bm.Route(tenant, input)
+	bm.mu.Lock()
+	policy, err := bm.policies.Policy(bm.policies.ForTenant(tenant))
+	bm.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	model, release, err := bm.acquire(name)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	response, err := model.Respond(context.Background(), Request{Input: input, Policy: policy})
+	if err != nil {
+		return "", err
+	}
+	return response.Output, nil
+}
+
+// acquire returns the loaded model for name, creating it on first use.
+// The instance can't be evicted until release is called.
+func (bm *BrainManager) acquire(name string) (Model, func(), error) {
+	bm.mu.Lock()
+	inst, ok := bm.instances[name]
+	if !ok {
+		bm.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownInstance, name)
+	}
+	inst.inUse++
+	inst.lastUsed = time.Now()
+	creating := inst.ready == nil
+	if creating {
+		inst.ready = make(chan struct{})
+	}
+	ready := inst.ready
+	bm.mu.Unlock()
+
+	release := func() {
+		bm.mu.Lock()
+		inst.inUse--
+		inst.lastUsed = time.Now()
+		bm.mu.Unlock()
+	}
+
+	if creating {
+		bm.create(inst)
+	}
+	<-ready
+
+	bm.mu.Lock()
+	model, err := inst.model, inst.err
+	bm.mu.Unlock()
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return model, release, nil
+}
+
+// create builds inst's model and then enforces the limits
+func (bm *BrainManager) create(inst *managedInstance) {
+	fmt.Printf("🧠 Loading model instance %q\n", inst.spec.Name)
+	before := bm.heapMB()
+	model, err := NewModel(inst.spec.Config, inst.spec.Size)
+	grown := bm.heapMB() - before
+
+	bm.mu.Lock()
+	inst.model, inst.err = model, err
+	if grown > 0 {
+		inst.memoryMB = grown
+	}
+	ready := inst.ready
+	if err != nil {
+		// Let a later request retry creation
+		inst.ready = nil
+	}
+	bm.mu.Unlock()
+	close(ready)
+
+	if err == nil {
+		bm.enforceLimits()
+	}
+}
+
+// enforceLimits evicts idle, unpinned instances in LRU order until the
+// instance limit and memory budget are met or nothing more can go. The heap
+// only shrinks once the collector runs, so each eviction is counted as
+// freeing the memory its instance took to create; when that is unknown the
+// heap is collected and measured again.
+func (bm *BrainManager) enforceLimits() {
+	heap := bm.heapMB()
+	for {
+		bm.mu.Lock()
+		loaded := 0
+		for _, inst := range bm.instances {
+			if inst.model != nil {
+				loaded++
+			}
+		}
+		overCount := bm.maxLoaded > 0 && loaded > bm.maxLoaded
+		bm.mu.Unlock()
+
+		overMemory := bm.memoryBudget > 0 && heap > bm.memoryBudget
+		if !overCount && !overMemory {
+			return
+		}
+		victim, victimMB := bm.lruVictim()
+		if victim == "" {
+			return
+		}
+		reason := "instance limit"
+		if !overCount {
+			reason = "memory pressure"
+		}
+		if !bm.evict(victim, reason) {
+			continue
+		}
+		if victimMB > 0 {
+			heap -= victimMB
+		} else if overMemory {
+			runtime.GC()
+			heap = bm.heapMB()
+		}
+	}
+}
+
+// lruVictim returns the least recently used evictable instance and the
+// memory it was recorded as using, or ""
+func (bm *BrainManager) lruVictim() (string, float64) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	victim, memoryMB := "", 0.0
+	var oldest time.Time
+	for name, inst := range bm.instances {
+		if inst.model == nil || inst.inUse > 0 || inst.spec.Pinned {
+			continue
+		}
+		if victim == "" || inst.lastUsed.Before(oldest) {
+			victim, oldest, memoryMB = name, inst.lastUsed, inst.memoryMB
+		}
+	}
+	return victim, memoryMB
+}
+
+// evict unloads name if it is loaded and idle
+func (bm *BrainManager) evict(name, reason string) bool {
+	bm.mu.Lock()
+	inst, ok := bm.instances[name]
+	if !ok || inst.model == nil || inst.inUse > 0 {
+		bm.mu.Unlock()
+		return false
+	}
+	model := inst.model
+	inst.model, inst.ready, inst.memoryMB = nil, nil, 0
+	bm.mu.Unlock()
+
+	fmt.Printf("♻️  Evicting model instance %q (%s)\n", name, reason)
+	model.Close()
+	return true
+}
+
+// Evict unloads an idle instance; it is recreated on its next request
+func (bm *BrainManager) Evict(name string) bool {
+	return bm.evict(name, "requested")
+}
+
+// ReapIdle evicts instances unused for longer than their IdleTTL and
+// returns how many were unloaded
+func (bm *BrainManager) ReapIdle() int {
+	bm.mu.Lock()
+	var stale []string
+	for name, inst := range bm.instances {
+		if inst.model != nil && inst.spec.IdleTTL > 0 && inst.inUse == 0 && time.Since(inst.lastUsed) > inst.spec.IdleTTL {
+			stale = append(stale, name)
+		}
+	}
+	bm.mu.Unlock()
+
+	reaped := 0
+	for _, name := range stale {
+		if bm.evict(name, "idle") {
+			reaped++
+		}
+	}
+	return reaped
+}
+
+// Instances reports every registered instance, sorted by name
+func (bm *BrainManager) Instances() []InstanceStatus {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	statuses := make([]InstanceStatus, 0, len(bm.instances))
+	for name, inst := range bm.instances {
+		modelType := inst.spec.Config.Model.Type
+		if modelType == "" {
+			modelType = "transparent"
+		}
+		statuses = append(statuses, InstanceStatus{
+			Name:     name,
+			Type:     modelType,
+			Loaded:   inst.model != nil,
+			Pinned:   inst.spec.Pinned,
+			InUse:    inst.inUse,
+			LastUsed: inst.lastUsed,
+			MemoryMB: inst.memoryMB,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Close unloads every instance, waiting for none; callers should stop
+// sending requests first
+func (bm *BrainManager) Close() {
+	bm.mu.Lock()
+	names := make([]string, 0, len(bm.instances))
+	for name := range bm.instances {
+		names = append(names, name)
+	}
+	bm.mu.Unlock()
+
+	for _, name := range names {
+		bm.evict(name, "shutdown")
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A warm pool hands out ready-to-use liquid brains without waiting for
+// dataset loading and reservoir wiring. Brains are stamped out of a
+// BrainTemplate: the topology (thresholds, refractory periods, connections,
+// I/O wiring) is captured once from a built brain and copied into fresh
+// neurons, and the dataset loader is shared. Each brain runs on its own
+// copy of the template brain's clock, starting at the time it was built, so
+// stepping one brain's VirtualClock (see Clock) leaves the others alone.
+// Pooled brains are wired but their dynamics aren't started, so idle spares
+// hold no goroutines or worker budget; Get starts them.
+
+// BrainTemplate is a reusable reservoir topology
+type BrainTemplate struct {
+	config      *Config
+	dims        Dimensions
+	clock       Clock
+	thresholds  []float64
+	refractory  []int64
+	connections [][]int32 // per neuron, flat indices of its targets
+	inhibitory  [][]bool  // per neuron, which connections inhibit; nil when all excite
+	inputWords  []string
+	inputs      [][]int32
+	outputWords []string
+	outputs     [][]int32
+	calibration []*OutputCalibration // per output; nil when uncalibrated
+	taps        []tapTemplate
+	loader      *DatasetLoader
+	schema      *ConceptSchema
+	templates   *TemplateLibrary
+}
+
+// tapTemplate is an output tap's wiring
+type tapTemplate struct {
+	name    string
+	outputs [][]int32 // per output, flat indices of the neurons it reads
+}
+
+// flatIndex numbers neurons in x, y, z order, matching StateSnapshot
+func (d Dimensions) flatIndex(x, y, z int) int32 {
+	return int32((x*d.Y+y)*d.Z + z)
+}
+
+// Template captures the brain's topology so it can be cloned
+func (brain *LiquidStateBrain) Template() *BrainTemplate {
+	dims := brain.dimensions
+	n := dims.X * dims.Y * dims.Z
+	t := &BrainTemplate{
+		config:      brain.config,
+		dims:        dims,
+		clock:       brain.clock,
+		thresholds:  make([]float64, n),
+		refractory:  make([]int64, n),
+		connections: make([][]int32, n),
+		inhibitory:  make([][]bool, n),
+		loader:      brain.dataLoader,
+		schema:      brain.schema,
+		templates:   brain.templates,
+	}
+
+	index := make(map[*LiquidNeuron]int32, n)
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				index[brain.reservoir[x][y][z]] = dims.flatIndex(x, y, z)
+			}
+		}
+	}
+	indices := func(neurons []*LiquidNeuron) []int32 {
+		out := make([]int32, len(neurons))
+		for i, neuron := range neurons {
+			out[i] = index[neuron]
+		}
+		return out
+	}
+
+	for neuron, i := range index {
+		t.thresholds[i] = neuron.threshold
+		t.refractory[i] = neuron.refractoryMs
+		t.connections[i] = indices(neuron.connections)
+		t.inhibitory[i] = neuron.inhibitory
+	}
+	for _, in := range brain.inputLayer {
+		t.inputWords = append(t.inputWords, in.word)
+		t.inputs = append(t.inputs, indices(in.connections))
+	}
+	for _, out := range brain.outputLayer {
+		t.outputWords = append(t.outputWords, out.meaning)
+		t.outputs = append(t.outputs, indices(out.connections))
+		t.calibration = append(t.calibration, out.calibration.Load())
+	}
+	for _, tap := range brain.taps {
+		tt := tapTemplate{name: tap.name}
+		for _, out := range tap.outputs {
+			tt.outputs = append(tt.outputs, indices(out.connections))
+		}
+		t.taps = append(t.taps, tt)
+	}
+	return t
+}
+
+// NewBrainTemplate builds one brain of the given size and captures its
+// topology. The brain itself is shut down afterwards.
+func NewBrainTemplate(size int, config *Config) (*BrainTemplate, error) {
+	brain := NewLiquidStateBrainWithConfig(size, config)
+	if brain == nil {
+		return nil, fmt.Errorf("failed to build template brain of size %d", size)
+	}
+	defer brain.Cleanup()
+	return brain.Template(), nil
+}
+
+// Instantiate builds a new running brain with the template's topology
+func (t *BrainTemplate) Instantiate() *LiquidStateBrain {
+	brain := t.build()
+	brain.startDynamics()
+	return brain
+}
+
+// build wires a brain with the template's topology without starting its
+// dynamics
+func (t *BrainTemplate) build() *LiquidStateBrain {
+	brain := newBrainShell(t.dims, t.config, cloneClock(t.clock))
+	brain.schema = t.schema
+	brain.templates = t.templates
+	if t.loader != nil {
+		brain.dataLoader = t.loader
+		brain.generator = NewResponseGenerator(t.loader)
+		brain.generator.SetConceptSchema(t.schema)
+		brain.generator.SetBeamSearch(t.config.BeamSearch)
+		brain.generator.SetTemplates(t.templates, t.config.Templates)
+		brain.generator.SetSeed(t.config.Seed)
+	}
+
+	dims := t.dims
+	neurons := make([]*LiquidNeuron, len(t.thresholds))
+	for x := 0; x < dims.X; x++ {
+		brain.reservoir[x] = make([][]*LiquidNeuron, dims.Y)
+		for y := 0; y < dims.Y; y++ {
+			brain.reservoir[x][y] = make([]*LiquidNeuron, dims.Z)
+			for z := 0; z < dims.Z; z++ {
+				i := dims.flatIndex(x, y, z)
+				neuron := &LiquidNeuron{
+					x: x, y: y, z: z,
+					threshold:    t.thresholds[i],
+					refractoryMs: t.refractory[i],
+					ctx:          brain.ctx,
+					activity:     brain.activity,
+					paused:       &brain.paused,
+					clock:        brain.clock,
+					chaos:        &brain.chaos,
+					energy:       &brain.energy,
+					topology:     &brain.topology,
+				}
+				neuron.state.Store(brain.rng.Float64() * 0.1)
+				brain.reservoir[x][y][z] = neuron
+				neurons[i] = neuron
+			}
+		}
+	}
+
+	lookup := func(indices []int32) []*LiquidNeuron {
+		out := make([]*LiquidNeuron, len(indices))
+		for i, idx := range indices {
+			out[i] = neurons[idx]
+		}
+		return out
+	}
+	for i, neuron := range neurons {
+		neuron.connections = lookup(t.connections[i])
+		neuron.inhibitory = t.inhibitory[i]
+	}
+	brain.assignDelays()
+	brain.initializeModulation()
+	brain.inputLayer = make([]*InputNeuron, len(t.inputWords))
+	for i, word := range t.inputWords {
+		brain.inputLayer[i] = &InputNeuron{word: word, connections: lookup(t.inputs[i])}
+	}
+	wire := func(connections [][]int32) []*OutputNeuron {
+		outputs := make([]*OutputNeuron, len(t.outputWords))
+		for i, meaning := range t.outputWords {
+			output := &OutputNeuron{meaning: meaning, connections: lookup(connections[i])}
+			output.activation.Store(0.0)
+			outputs[i] = output
+		}
+		return outputs
+	}
+	brain.outputLayer = wire(t.outputs)
+	for i, output := range brain.outputLayer {
+		output.calibration.Store(t.calibration[i])
+	}
+	for _, tap := range t.taps {
+		brain.taps = append(brain.taps, &outputTap{name: tap.name, outputs: wire(tap.outputs)})
+	}
+	return brain
+}
+
+// BrainPool keeps a number of wired brains ready for use
+type BrainPool struct {
+	template *BrainTemplate
+	ready    chan *LiquidStateBrain
+	refill   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	hits     int64 // atomic: Gets served from the pool
+	misses   int64 // atomic: Gets that had to build a brain
+}
+
+// BrainPoolStats reports pool usage
+type BrainPoolStats struct {
+	Ready  int   `json:"ready"`
+	Target int   `json:"target"`
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// NewBrainPool starts filling a pool of size brains in the background
+func NewBrainPool(template *BrainTemplate, size int) *BrainPool {
+	if size < 1 {
+		size = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &BrainPool{
+		template: template,
+		ready:    make(chan *LiquidStateBrain, size),
+		refill:   make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	p.wg.Add(1)
+	go p.fill()
+	return p
+}
+
+// fill tops the pool up whenever it drops below its target. Only this
+// goroutine sends on ready, so a send with spare capacity never blocks.
+func (p *BrainPool) fill() {
+	defer p.wg.Done()
+
+	for {
+		for len(p.ready) < cap(p.ready) {
+			if p.ctx.Err() != nil {
+				return
+			}
+			start := time.Now()
+			brain := p.template.build()
+			p.ready <- brain
+			fmt.Printf("🔥 Warm pool: brain ready in %v (%d/%d)\n", time.Since(start).Round(time.Millisecond), len(p.ready), cap(p.ready))
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.refill:
+		}
+	}
+}
+
+// Get returns a running brain, from the pool when one is ready and built
+// on the spot otherwise. The caller owns the brain and must Cleanup it.
+func (p *BrainPool) Get() *LiquidStateBrain {
+	defer func() {
+		select {
+		case p.refill <- struct{}{}:
+		default:
+		}
+	}()
+
+	select {
+	case brain := <-p.ready:
+		atomic.AddInt64(&p.hits, 1)
+		brain.startDynamics()
+		return brain
+	default:
+		atomic.AddInt64(&p.misses, 1)
+		return p.template.Instantiate()
+	}
+}
+
+// Stats returns pool usage so far
+func (p *BrainPool) Stats() BrainPoolStats {
+	return BrainPoolStats{
+		Ready:  len(p.ready),
+		Target: cap(p.ready),
+		Hits:   atomic.LoadInt64(&p.hits),
+		Misses: atomic.LoadInt64(&p.misses),
+	}
+}
+
+// Close stops refilling and shuts down every pooled brain
+func (p *BrainPool) Close() {
+	p.cancel()
+	p.wg.Wait()
+
+	for {
+		select {
+		case brain := <-p.ready:
+			brain.Cleanup()
+		default:
+			return
+		}
+	}
+}
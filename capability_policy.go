@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Capability policies restrict which capabilities a caller may trigger,
+// e.g. no external LLM calls for a privacy-sensitive tenant. Policies are
+// named in capability_policies and picked by the name of the caller's
+// server API key, by tenant, or by the default. A session keeps the policy
+// it was created under. In serve mode every request that reaches an
+// orchestrator carries its caller's policy. The
+// orchestrator enforces a request's policy while routing: a denied intent
+// capability is passed over for the next route, denied fallback hops are
+// skipped, and every denial is recorded in the decision trace along with
+// the policy's name.
+
+// CapabilityPolicy allows and denies capabilities by name; a nil policy
+// permits everything
+type CapabilityPolicy struct {
+	Name  string   `json:"-"`
+	Allow []string `json:"allow"` // empty allows every capability not denied
+	Deny  []string `json:"deny"`
+}
+
+// Permits reports whether the policy lets capability run
+func (p *CapabilityPolicy) Permits(capability string) bool {
+	if p == nil {
+		return true
+	}
+	for _, denied := range p.Deny {
+		if denied == capability {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, allowed := range p.Allow {
+		if allowed == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilityPolicyConfig names the policies and who they apply to
+type CapabilityPolicyConfig struct {
+	Default  string                      `json:"default"` // policy for callers without one; empty is unrestricted
+	Policies map[string]CapabilityPolicy `json:"policies"`
+	APIKeys  map[string]string           `json:"api_keys"` // server api key name -> policy name
+	Tenants  map[string]string           `json:"tenants"`  // brain manager tenant -> policy name
+}
+
+func (c CapabilityPolicyConfig) validate() error {
+	known := func(name string) bool {
+		_, ok := c.Policies[name]
+		return ok
+	}
+	if c.Default != "" && !known(c.Default) {
+		return fmt.Errorf("capability_policies default %q is not a policy", c.Default)
+	}
+	for key, name := range c.APIKeys {
+		if !known(name) {
+			return fmt.Errorf("capability_policies api key %q maps to unknown policy %q", key, name)
+		}
+	}
+	for tenant, name := range c.Tenants {
+		if !known(name) {
+			return fmt.Errorf("capability_policies tenant %q maps to unknown policy %q", tenant, name)
+		}
+	}
+	return nil
+}
+
+// Policy returns the named policy; "" is unrestricted and returns nil
+func (c CapabilityPolicyConfig) Policy(name string) (*CapabilityPolicy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	policy, ok := c.Policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown capability policy %q", name)
+	}
+	policy.Name = name
+	return &policy, nil
+}
+
+// ForAPIKey returns the policy name for the API key named key (see
+// APIKeyName), or the default for callers without a key or a policy of
+// their own
+func (c CapabilityPolicyConfig) ForAPIKey(key string) string {
+	if name, ok := c.APIKeys[key]; ok && key != "" {
+		return name
+	}
+	return c.Default
+}
+
+// ForTenant returns the policy name for a tenant, or the default
+func (c CapabilityPolicyConfig) ForTenant(tenant string) string {
+	if name, ok := c.Tenants[tenant]; ok {
+		return name
+	}
+	return c.Default
+}
+
+// Names returns the configured policy names, sorted
+func (c CapabilityPolicyConfig) Names() []string {
+	names := make([]string, 0, len(c.Policies))
+	for name := range c.Policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// policyName names a policy in decisions; empty when unrestricted
+func policyName(policy *CapabilityPolicy) string {
+	if policy == nil {
+		return ""
+	}
+	return policy.Name
+}
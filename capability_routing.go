@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Capability routing: every smart neuron carries the embedding of its
+// capability, built from a short description of what the capability is for.
+// With routing on, the orchestrator embeds the input the same way and gives
+// each neuron a gain from how well the two match: the best matching
+// capability gets 1, the others less in proportion, and strength sets how
+// far gains may fall from 1. The input is injected preferentially into
+// high-gain neurons and propagation into a neuron is scaled by its gain, so
+// "write a poem" mostly activates the creative neurons instead of spreading
+// uniformly. Without a dataset, text is embedded by hashing its words, so
+// matching is lexical; SetEmbedder swaps in corpus sentence embeddings.
+
+// CapabilityRoutingConfig controls routing activation by capability
+type CapabilityRoutingConfig struct {
+	Enabled  bool    `json:"enabled"`
+	Strength float64 `json:"strength"` // 0 routes uniformly, 1 gives non-matching neurons no activation
+}
+
+func (c CapabilityRoutingConfig) validate() error {
+	if c.Enabled && (c.Strength <= 0 || c.Strength > 1) {
+		return fmt.Errorf("capability_routing strength must be above 0 and at most 1")
+	}
+	return nil
+}
+
+// capabilityDescriptions are embedded as each capability's embedding
+var capabilityDescriptions = map[string]string{
+	"gpt_caller":    "process explain answer question questions summarize reason understand solve problem problems perspectives",
+	"claude_caller": "create write poem poems story stories imagine compose design art creative beautiful meaningful song",
+	"tool_caller":   "analyze data compute calculate search find pattern patterns measure count statistics tool",
+}
+
+// Dimension of hashed text embeddings
+const hashedEmbeddingDim = 256
+
+// Embedder embeds text, reporting false when it can't
+type Embedder func(text string) ([]float64, bool)
+
+// hashedEmbedding mean-pools a pseudo-random unit vector per word, seeded
+// by the word, so texts sharing words are similar
+func hashedEmbedding(text string) ([]float64, bool) {
+	words := strings.Fields(strings.ToLower(text))
+	vec := make([]float64, hashedEmbeddingDim)
+	for _, word := range words {
+		word = strings.Trim(word, ".,;:!?'\"()")
+		if word == "" {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		rng := rand.New(rand.NewSource(int64(h.Sum64())))
+		for i := range vec {
+			vec[i] += rng.NormFloat64()
+		}
+	}
+	return vec, normalizeVector(vec)
+}
+
+// SetEmbedder embeds capabilities and inputs with embed, such as a corpus'
+// sentence embeddings; nil restores hashed word embeddings
+func (po *ParallelOrchestrator) SetEmbedder(embed Embedder) {
+	if embed == nil {
+		embed = hashedEmbedding
+	}
+	po.embed = embed
+	embeddings := make(map[string][]float64)
+	for _, n := range po.neurons {
+		if _, ok := embeddings[n.capability]; !ok {
+			embeddings[n.capability], _ = embed(capabilityDescriptions[n.capability])
+		}
+		n.embedding = embeddings[n.capability]
+	}
+}
+
+// SetCapabilityRouting turns routing by capability on or off
+func (po *ParallelOrchestrator) SetCapabilityRouting(config CapabilityRoutingConfig) {
+	po.routing = config
+}
+
+// routingGains returns each neuron's gain for input, or nil when routing is
+// off or the input can't be embedded
+func (po *ParallelOrchestrator) routingGains(input string) []float64 {
+	if !po.routing.Enabled || po.embed == nil {
+		return nil
+	}
+	vec, ok := po.embed(input)
+	if !ok {
+		return nil
+	}
+	affinities := make([]float64, len(po.neurons))
+	best := 0.0
+	for i, n := range po.neurons {
+		if len(n.embedding) == len(vec) {
+			affinities[i] = math.Max(0, dotProduct(vec, n.embedding))
+		}
+		best = math.Max(best, affinities[i])
+	}
+	if best == 0 {
+		return nil
+	}
+	gains := affinities
+	for i, a := range affinities {
+		gains[i] = 1 - po.routing.Strength + po.routing.Strength*a/best
+	}
+	return gains
+}
+
+// gain returns a neuron's routing gain; 1 without routing
+func gain(gains []float64, neuronID int) float64 {
+	if gains == nil {
+		return 1
+	}
+	return gains[neuronID]
+}
+
+// injectionPoints picks random neurons to simulate distributed input,
+// weighted by their routing gains
+func (po *ParallelOrchestrator) injectionPoints(gains []float64) []int {
+	points := make([]int, 10)
+	if gains == nil {
+		for i := range points {
+			points[i] = po.rng.Intn(len(po.neurons))
+		}
+		return points
+	}
+	total := 0.0
+	for _, g := range gains {
+		total += g
+	}
+	for i := range points {
+		r := po.rng.Float64() * total
+		points[i] = len(gains) - 1
+		for j, g := range gains {
+			if r < g {
+				points[i] = j
+				break
+			}
+			r -= g
+		}
+	}
+	return points
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Capability sandbox: external capabilities are other people's code behind
+// a network, and one that hangs or answers with megabytes of HTML shouldn't
+// take orchestration down with it. Every capability call runs inside
+// limits: prompts and responses larger than their byte limits are truncated
+// or rejected, responses whose sniffed content type isn't allowed are
+// rejected, and calls that outlast the time limit are abandoned. Each
+// broken limit is a SandboxViolation, recorded in the call's decision;
+// rejections fail the call with the violation, which wraps one of the
+// ErrCapability* errors. capability_sandbox sets the limits for every
+// capability, and capabilities.<name> replaces them for one.
+
+// SandboxLimits bounds capability calls. Zero values are unlimited.
+type SandboxLimits struct {
+	MaxPromptBytes   int      `json:"max_prompt_bytes"`
+	MaxResponseBytes int      `json:"max_response_bytes"`
+	TimeoutMS        int      `json:"timeout_ms"`
+	ContentTypes     []string `json:"content_types,omitempty"` // media types responses may sniff as; empty allows any
+	Oversize         string   `json:"oversize"`                // "truncate" or "reject" what's too large
+}
+
+// CapabilitySandboxConfig sets the limits capability calls run under
+type CapabilitySandboxConfig struct {
+	SandboxLimits
+	Capabilities map[string]SandboxLimits `json:"capabilities,omitempty"` // per capability, replacing the limits above
+}
+
+// What to do with oversized prompts and responses
+const (
+	OversizeTruncate = "truncate"
+	OversizeReject   = "reject"
+)
+
+func (l SandboxLimits) validate() error {
+	if l.MaxPromptBytes < 0 || l.MaxResponseBytes < 0 || l.TimeoutMS < 0 {
+		return fmt.Errorf("capability_sandbox limits must not be negative")
+	}
+	switch l.Oversize {
+	case "", OversizeTruncate, OversizeReject:
+	default:
+		return fmt.Errorf("unknown capability_sandbox oversize %q (want %s or %s)", l.Oversize, OversizeTruncate, OversizeReject)
+	}
+	for _, t := range l.ContentTypes {
+		if _, _, err := mime.ParseMediaType(t); err != nil {
+			return fmt.Errorf("capability_sandbox content type %q: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func (c CapabilitySandboxConfig) validate() error {
+	if err := c.SandboxLimits.validate(); err != nil {
+		return err
+	}
+	for name, limits := range c.Capabilities {
+		if err := limits.validate(); err != nil {
+			return fmt.Errorf("capability %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// limitsFor returns the limits calls to capability run under
+func (c CapabilitySandboxConfig) limitsFor(capability string) SandboxLimits {
+	if limits, ok := c.Capabilities[capability]; ok {
+		return limits
+	}
+	return c.SandboxLimits
+}
+
+// SetCapabilitySandbox sets the limits capability calls run under,
+// registered capabilities included
+func (go_ *GenesisOrchestrator) SetCapabilitySandbox(config CapabilitySandboxConfig) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	go_.sandbox = config
+	for name, n := range go_.neurons {
+		n.mu.Lock()
+		n.sandbox = config.limitsFor(name)
+		n.mu.Unlock()
+	}
+}
+
+// Errors rejected capability calls wrap
+var (
+	ErrCapabilityPromptSize   = errors.New("capability prompt too large")
+	ErrCapabilityResponseSize = errors.New("capability response too large")
+	ErrCapabilityContentType  = errors.New("capability response content type not allowed")
+	ErrCapabilityTimeout      = errors.New("capability call timed out")
+)
+
+// Sandbox limits, as violations name them
+const (
+	limitPromptSize   = "prompt_size"
+	limitResponseSize = "response_size"
+	limitContentType  = "content_type"
+	limitTimeout      = "timeout"
+)
+
+var sandboxErrors = map[string]error{
+	limitPromptSize:   ErrCapabilityPromptSize,
+	limitResponseSize: ErrCapabilityResponseSize,
+	limitContentType:  ErrCapabilityContentType,
+	limitTimeout:      ErrCapabilityTimeout,
+}
+
+// SandboxViolation is a capability call breaking a sandbox limit
+type SandboxViolation struct {
+	Capability string `json:"capability"`
+	Limit      string `json:"limit"`  // "prompt_size", "response_size", "content_type" or "timeout"
+	Action     string `json:"action"` // "truncated" or "rejected"
+	Detail     string `json:"detail"` // what was seen against what was allowed
+}
+
+// Error describes the violation
+func (v SandboxViolation) Error() string {
+	return fmt.Sprintf("%s: %v, %s (%s)", v.Capability, v.Unwrap(), v.Action, v.Detail)
+}
+
+// Unwrap returns the ErrCapability* error for the broken limit
+func (v SandboxViolation) Unwrap() error {
+	return sandboxErrors[v.Limit]
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a character
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// sandboxed calls endpoint with input within limits, returning the
+// (possibly truncated) response and the limits it broke. A rejected call's
+// error is its last violation.
+func sandboxed(ctx context.Context, capability string, limits SandboxLimits, endpoint func(context.Context, string) (string, error), input string) (string, []SandboxViolation, error) {
+	var violations []SandboxViolation
+	violate := func(limit, detail string, reject bool) error {
+		v := SandboxViolation{Capability: capability, Limit: limit, Action: "truncated", Detail: detail}
+		if reject {
+			v.Action = "rejected"
+		}
+		violations = append(violations, v)
+		fmt.Printf("🚧 Capability %s %s: %s\n", capability, v.Action, v.Unwrap())
+		if reject {
+			return v
+		}
+		return nil
+	}
+	reject := limits.Oversize == OversizeReject
+
+	if most := limits.MaxPromptBytes; most > 0 && len(input) > most {
+		if err := violate(limitPromptSize, fmt.Sprintf("%d > %d bytes", len(input), most), reject); err != nil {
+			return "", violations, err
+		}
+		input = truncateBytes(input, most)
+	}
+
+	// A call that ignores its context is abandoned, not waited for
+	type outcome struct {
+		result string
+		err    error
+	}
+	callCtx, cancel := ctx, context.CancelFunc(func() {})
+	if limits.TimeoutMS > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, time.Duration(limits.TimeoutMS)*time.Millisecond)
+	}
+	defer cancel()
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := endpoint(callCtx, input)
+		done <- outcome{result, err}
+	}()
+	var result string
+	select {
+	case o := <-done:
+		if o.err != nil {
+			if callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return "", violations, violate(limitTimeout, fmt.Sprintf("over %dms", limits.TimeoutMS), true)
+			}
+			return "", violations, o.err
+		}
+		result = o.result
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return "", violations, ctx.Err()
+		}
+		return "", violations, violate(limitTimeout, fmt.Sprintf("over %dms", limits.TimeoutMS), true)
+	}
+
+	if len(limits.ContentTypes) > 0 {
+		// Sniffing reads at most 512 bytes; copy no more than that
+		sniffed, _, _ := mime.ParseMediaType(http.DetectContentType([]byte(result[:min(len(result), 512)])))
+		allowed := false
+		for _, t := range limits.ContentTypes {
+			if media, _, _ := mime.ParseMediaType(t); strings.EqualFold(media, sniffed) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return "", violations, violate(limitContentType, sniffed, true)
+		}
+	}
+	if most := limits.MaxResponseBytes; most > 0 && len(result) > most {
+		if err := violate(limitResponseSize, fmt.Sprintf("%d > %d bytes", len(result), most), reject); err != nil {
+			return "", violations, err
+		}
+		result = truncateBytes(result, most)
+	}
+	return result, violations, nil
+}
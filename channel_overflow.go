@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Backpressure: the concept network's thought streams and pulse channels
+// and the reservoir's wave channel are bounded. When one is full, its
+// configured policy decides what happens to a send: "block" waits up to a
+// deadline before dropping, "drop_oldest" discards the oldest queued item to
+// make room, and "drop_newest" discards the item being sent. Every drop is
+// counted per channel, so a transparent trace that lost thoughts or pulses
+// says so in the admin metrics instead of silently.
+
+// Backpressure policies
+const (
+	policyBlock      = "block"
+	policyDropOldest = "drop_oldest"
+	policyDropNewest = "drop_newest"
+)
+
+// ChannelPolicy is how sends on one full channel behave
+type ChannelPolicy struct {
+	Policy    string `json:"policy"`     // "block", "drop_oldest" or "drop_newest"
+	TimeoutMS int    `json:"timeout_ms"` // longest a "block" send waits before dropping
+}
+
+// BackpressureConfig sets the policy of each bounded channel
+type BackpressureConfig struct {
+	Thoughts ChannelPolicy `json:"thoughts"` // Understand's thought stream and its visualization
+	Pulses   ChannelPolicy `json:"pulses"`   // concept neuron pulses
+	Waves    ChannelPolicy `json:"waves"`    // reservoir wave patterns
+}
+
+// defaultBackpressure keeps thoughts, waiting briefly for the streamer,
+// and drops new pulses and waves as before
+var defaultBackpressure = BackpressureConfig{
+	Thoughts: ChannelPolicy{Policy: policyBlock, TimeoutMS: 100},
+	Pulses:   ChannelPolicy{Policy: policyDropNewest},
+	Waves:    ChannelPolicy{Policy: policyDropNewest},
+}
+
+func (p ChannelPolicy) validate(name string) error {
+	switch p.Policy {
+	case "", policyDropOldest, policyDropNewest:
+	case policyBlock:
+		if p.TimeoutMS <= 0 {
+			return fmt.Errorf("backpressure %s timeout_ms must be positive when blocking", name)
+		}
+	default:
+		return fmt.Errorf("backpressure %s policy must be block, drop_oldest or drop_newest, got %q", name, p.Policy)
+	}
+	return nil
+}
+
+func (c BackpressureConfig) validate() error {
+	if err := c.Thoughts.validate("thoughts"); err != nil {
+		return err
+	}
+	if err := c.Pulses.validate("pulses"); err != nil {
+		return err
+	}
+	return c.Waves.validate("waves")
+}
+
+// orDefault fills channels without a policy with the default's
+func (c BackpressureConfig) orDefault() BackpressureConfig {
+	if c.Thoughts.Policy == "" {
+		c.Thoughts = defaultBackpressure.Thoughts
+	}
+	if c.Pulses.Policy == "" {
+		c.Pulses = defaultBackpressure.Pulses
+	}
+	if c.Waves.Policy == "" {
+		c.Waves = defaultBackpressure.Waves
+	}
+	return c
+}
+
+// ChannelCounter counts one channel's deliveries and drops; a nil counter
+// counts nothing
+type ChannelCounter struct {
+	sent    atomic.Int64
+	dropped atomic.Int64
+}
+
+// ChannelStats reports one channel's traffic
+type ChannelStats struct {
+	Policy  string `json:"policy"`
+	Sent    int64  `json:"sent"`
+	Dropped int64  `json:"dropped"`
+}
+
+// Stats returns the counts under policy
+func (c *ChannelCounter) Stats(policy ChannelPolicy) ChannelStats {
+	if c == nil {
+		return ChannelStats{Policy: policy.Policy}
+	}
+	return ChannelStats{Policy: policy.Policy, Sent: c.sent.Load(), Dropped: c.dropped.Load()}
+}
+
+func (c *ChannelCounter) delivered() {
+	if c != nil {
+		c.sent.Add(1)
+	}
+}
+
+func (c *ChannelCounter) lost() {
+	if c != nil {
+		c.dropped.Add(1)
+	}
+}
+
+// offer sends v on ch under policy, returning whether v was delivered.
+// Dropped items, whether v or one it displaced, are counted and passed to
+// discard when it is set.
+func offer[T any](ch chan T, v T, policy ChannelPolicy, counter *ChannelCounter, discard func(T)) bool {
+	select {
+	case ch <- v:
+		counter.delivered()
+		return true
+	default:
+	}
+
+	drop := func(item T) {
+		counter.lost()
+		if discard != nil {
+			discard(item)
+		}
+	}
+	switch policy.Policy {
+	case policyBlock:
+		timer := time.NewTimer(time.Duration(policy.TimeoutMS) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case ch <- v:
+			counter.delivered()
+			return true
+		case <-timer.C:
+		}
+	case policyDropOldest:
+		select {
+		case oldest := <-ch:
+			drop(oldest)
+		default:
+		}
+		select {
+		case ch <- v:
+			counter.delivered()
+			return true
+		default:
+		}
+	}
+	drop(v)
+	return false
+}
+
+// channelOverflow holds a component's channel policies and drop counts
+type channelOverflow struct {
+	config        BackpressureConfig
+	thoughts      ChannelCounter
+	visualization ChannelCounter
+	pulses        ChannelCounter
+	waves         ChannelCounter
+}
+
+func newChannelOverflow(config BackpressureConfig) *channelOverflow {
+	return &channelOverflow{config: config.orDefault()}
+}
+
+// ChannelStats reports the concept network's channel traffic
+func (llm *TransparentLLM) ChannelStats() map[string]ChannelStats {
+	o := llm.overflow
+	if o == nil {
+		o = newChannelOverflow(BackpressureConfig{})
+	}
+	return map[string]ChannelStats{
+		"thoughts":      o.thoughts.Stats(o.config.Thoughts),
+		"visualization": o.visualization.Stats(o.config.Thoughts),
+		"pulses":        o.pulses.Stats(o.config.Pulses),
+	}
+}
+
+// ChannelStats reports the reservoir's channel traffic
+func (brain *LiquidStateBrain) ChannelStats() map[string]ChannelStats {
+	o := brain.overflow
+	if o == nil {
+		o = newChannelOverflow(BackpressureConfig{})
+	}
+	return map[string]ChannelStats{
+		"waves": o.waves.Stats(o.config.Waves),
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Chaos mode injects faults for robustness testing: it drops injected
+// waves, delays spike batches, kills neuron goroutines (which the brain's
+// supervisor restarts) and fails orchestrator capability calls. It is off
+// unless a Chaos is attached with SetChaos, and meant for tests and soak
+// runs only.
+
+// ChaosConfig sets the probability of each fault
+type ChaosConfig struct {
+	DropWaveRate        float64       // per injected wave
+	SpikeDelayRate      float64       // per spike batch
+	SpikeDelay          time.Duration // extra delay for affected batches
+	NeuronKillRate      float64       // per neuron tick
+	CapabilityErrorRate float64       // per capability call
+	Seed                int64
+}
+
+// ChaosStats counts injected faults
+type ChaosStats struct {
+	DroppedWaves     int64 `json:"dropped_waves"`
+	DelayedSpikes    int64 `json:"delayed_spikes"`
+	KilledNeurons    int64 `json:"killed_neurons"`
+	CapabilityErrors int64 `json:"capability_errors"`
+}
+
+// Chaos decides which faults to inject. A nil *Chaos injects nothing.
+type Chaos struct {
+	config ChaosConfig
+	mu     sync.Mutex
+	rng    *rand.Rand
+	stats  ChaosStats // updated atomically
+}
+
+// errChaosCapability is returned by capabilities failed on purpose
+var errChaosCapability = fmt.Errorf("chaos: injected capability failure")
+
+func NewChaos(config ChaosConfig) *Chaos {
+	if config.SpikeDelay <= 0 {
+		config.SpikeDelay = 20 * time.Millisecond
+	}
+	return &Chaos{config: config, rng: rand.New(rand.NewSource(config.Seed))}
+}
+
+// roll returns true with probability p
+func (c *Chaos) roll(p float64) bool {
+	if c == nil || p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
+
+func (c *Chaos) dropWave() bool {
+	if c.roll(c.configOrZero().DropWaveRate) {
+		atomic.AddInt64(&c.stats.DroppedWaves, 1)
+		return true
+	}
+	return false
+}
+
+// spikeDelay returns the extra delay for a spike batch, usually 0
+func (c *Chaos) spikeDelay() time.Duration {
+	if c.roll(c.configOrZero().SpikeDelayRate) {
+		atomic.AddInt64(&c.stats.DelayedSpikes, 1)
+		return c.config.SpikeDelay
+	}
+	return 0
+}
+
+// maybeKillNeuron panics to simulate a crashed neuron goroutine
+func (c *Chaos) maybeKillNeuron() {
+	if c.roll(c.configOrZero().NeuronKillRate) {
+		atomic.AddInt64(&c.stats.KilledNeurons, 1)
+		panic("chaos: neuron goroutine killed")
+	}
+}
+
+func (c *Chaos) capabilityError() error {
+	if c.roll(c.configOrZero().CapabilityErrorRate) {
+		atomic.AddInt64(&c.stats.CapabilityErrors, 1)
+		return errChaosCapability
+	}
+	return nil
+}
+
+func (c *Chaos) configOrZero() ChaosConfig {
+	if c == nil {
+		return ChaosConfig{}
+	}
+	return c.config
+}
+
+// Stats returns the faults injected so far
+func (c *Chaos) Stats() ChaosStats {
+	if c == nil {
+		return ChaosStats{}
+	}
+	return ChaosStats{
+		DroppedWaves:     atomic.LoadInt64(&c.stats.DroppedWaves),
+		DelayedSpikes:    atomic.LoadInt64(&c.stats.DelayedSpikes),
+		KilledNeurons:    atomic.LoadInt64(&c.stats.KilledNeurons),
+		CapabilityErrors: atomic.LoadInt64(&c.stats.CapabilityErrors),
+	}
+}
+
+// SetChaos attaches (or with nil, detaches) fault injection to the brain
+func (brain *LiquidStateBrain) SetChaos(c *Chaos) {
+	brain.chaos.Store(c)
+}
+
+// Restarts returns how many crashed component goroutines were restarted
+func (brain *LiquidStateBrain) Restarts() int64 {
+	return atomic.LoadInt64(&brain.restarts)
+}
+
+// supervise runs fn until it returns normally or the brain shuts down,
+// restarting it after each panic
+func (brain *LiquidStateBrain) supervise(component string, fn func()) {
+	for brain.ctx.Err() == nil {
+		if !brain.runRecovered(component, fn) {
+			return
+		}
+		if brain.ctx.Err() == nil {
+			atomic.AddInt64(&brain.restarts, 1)
+		}
+	}
+}
+
+// runRecovered runs fn, reporting whether it panicked
+func (brain *LiquidStateBrain) runRecovered(component string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if logEnabled(LogDebug) {
+				fmt.Printf("🚨 %s panic recovered, restarting: %v\n", component, r)
+			}
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// SetChaos makes capability calls fail at the configured rate
+func (go_ *GenesisOrchestrator) SetChaos(c *Chaos) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	go_.chaos = c
+	for _, n := range go_.neurons {
+		n.mu.Lock()
+		n.chaos = c
+		n.mu.Unlock()
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Clarification mode: when the strongest meaning patterns found by
+// crystallizeMeaning are nearly tied, guessing between them gives a
+// confidently wrong answer half the time. With the mode on, TransparentLLM
+// instead asks a question naming the competing concepts ("Do you mean code
+// error or help solution?").
+
+// ClarificationConfig controls clarification questions for ambiguous inputs
+type ClarificationConfig struct {
+	Enabled    bool    `json:"enabled"`
+	Margin     float64 `json:"margin"`      // patterns within this fraction of the strongest compete
+	MaxOptions int     `json:"max_options"` // competing meanings named in the question
+}
+
+func (c ClarificationConfig) validate() error {
+	if c.Margin < 0 || c.Margin >= 1 {
+		return fmt.Errorf("clarification margin must be in [0, 1)")
+	}
+	if c.Enabled && c.MaxOptions < 2 {
+		return fmt.Errorf("clarification max_options must be at least 2")
+	}
+	return nil
+}
+
+// SetClarification replaces the clarification settings
+func (llm *TransparentLLM) SetClarification(config ClarificationConfig) {
+	llm.mu.Lock()
+	defer llm.mu.Unlock()
+
+	llm.clarification = config
+}
+
+// competingMeanings returns the circuit patterns whose total strength is
+// within margin of the strongest, strongest first, when there are at least
+// two of them
+func competingMeanings(circuits []CircuitPath, config ClarificationConfig) []string {
+	strength := make(map[string]float64)
+	for _, circuit := range circuits {
+		if pattern := extractPattern(circuit); pattern != "" {
+			strength[pattern] += circuit.strength
+		}
+	}
+
+	patterns := make([]string, 0, len(strength))
+	for pattern := range strength {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if strength[patterns[i]] != strength[patterns[j]] {
+			return strength[patterns[i]] > strength[patterns[j]]
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	var competing []string
+	for _, pattern := range patterns {
+		if len(competing) == config.MaxOptions || strength[pattern] < strength[patterns[0]]*(1-config.Margin) {
+			break
+		}
+		competing = append(competing, pattern)
+	}
+	if len(competing) < 2 {
+		return nil
+	}
+	return competing
+}
+
+// describeMeaning turns a circuit pattern like "code_error" into the phrase
+// "code error", dropping a repeated concept
+func describeMeaning(pattern string) string {
+	parts := strings.Split(pattern, "_")
+	if len(parts) == 2 && parts[0] == parts[1] {
+		return parts[0]
+	}
+	return strings.Join(parts, " ")
+}
+
+// clarificationQuestion asks which of the competing meanings was intended,
+// using the schema's template and conjunction
+func (s *ConceptSchema) clarificationQuestion(meanings []string) string {
+	s = s.orDefault()
+	template, or := s.ClarificationTemplate, s.ClarificationOr
+	if template == "" {
+		template, or = DefaultConceptSchema().ClarificationTemplate, DefaultConceptSchema().ClarificationOr
+	}
+	options := make([]string, len(meanings))
+	for i, m := range meanings {
+		options[i] = describeMeaning(m)
+	}
+	joined := options[len(options)-1]
+	if len(options) > 1 {
+		joined = strings.Join(options[:len(options)-1], ", ") + " " + or + " " + joined
+	}
+	return fmt.Sprintf(template, joined)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the reservoir's source of time. Neuron tickers, refractory
+// checks, synaptic delays and wave decay all read it, so a simulation can
+// run on the wall clock (RealClock), faster or slower than real time
+// (ScaledClock), or on a VirtualClock that only moves when told to, which
+// makes stepping through dynamics and timing-sensitive tests deterministic.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	Sleep(d time.Duration)
+}
+
+// Ticker delivers ticks on Chan until stopped
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// RealClock is wall-clock time
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                   { return time.Now() }
+func (realClock) Sleep(d time.Duration)            { time.Sleep(d) }
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) Chan() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()                  { r.t.Stop() }
+
+// cloneClock returns a clock that reads the same time as c but moves on its
+// own, so advancing one VirtualClock doesn't advance the other. Real and
+// scaled clocks have no state to share and are returned as they are.
+func cloneClock(c Clock) Clock {
+	if v, ok := c.(*VirtualClock); ok {
+		return NewVirtualClock(v.Now())
+	}
+	return c
+}
+
+// clockSince is time.Since for any clock
+func clockSince(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// ScaledClock runs speed times faster than the wall clock (speed < 1 runs
+// slower). Its time starts at the moment it is created.
+type ScaledClock struct {
+	speed     float64
+	realStart time.Time
+	virtualAt time.Time
+}
+
+func NewScaledClock(speed float64) *ScaledClock {
+	if speed <= 0 {
+		speed = 1
+	}
+	now := time.Now()
+	return &ScaledClock{speed: speed, realStart: now, virtualAt: now}
+}
+
+func (c *ScaledClock) Now() time.Time {
+	elapsed := float64(time.Since(c.realStart)) * c.speed
+	return c.virtualAt.Add(time.Duration(elapsed))
+}
+
+func (c *ScaledClock) real(d time.Duration) time.Duration {
+	if r := time.Duration(float64(d) / c.speed); r > 0 {
+		return r
+	}
+	return 1
+}
+
+func (c *ScaledClock) Sleep(d time.Duration) { time.Sleep(c.real(d)) }
+
+func (c *ScaledClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(c.real(d))}
+}
+
+// VirtualClock only advances through Advance and Step. Tickers fire and
+// sleepers wake as virtual time passes their deadlines; like real tickers,
+// a ticker whose last tick wasn't received drops further ticks.
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*virtualTimer
+}
+
+type virtualTimer struct {
+	clock   *VirtualClock
+	when    time.Time
+	period  time.Duration // 0 for a one-shot sleep
+	ch      chan time.Time
+	stopped bool
+}
+
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) schedule(d, period time.Duration) *virtualTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &virtualTimer{clock: c, when: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *VirtualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for VirtualClock.NewTicker")
+	}
+	return c.schedule(d, d)
+}
+
+// Sleep blocks until virtual time has advanced by d
+func (c *VirtualClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-c.schedule(d, 0).ch
+}
+
+func (t *virtualTimer) Chan() <-chan time.Time { return t.ch }
+
+func (t *virtualTimer) Stop() {
+	t.clock.mu.Lock()
+	t.stopped = true
+	t.clock.mu.Unlock()
+}
+
+// next returns the earliest live timer, due at or before limit when
+// bounded; callers hold c.mu
+func (c *VirtualClock) next(limit time.Time, bounded bool) *virtualTimer {
+	var earliest *virtualTimer
+	live := c.timers[:0]
+	for _, t := range c.timers {
+		if t.stopped {
+			continue
+		}
+		live = append(live, t)
+		if (!bounded || !t.when.After(limit)) && (earliest == nil || t.when.Before(earliest.when)) {
+			earliest = t
+		}
+	}
+	c.timers = live
+	return earliest
+}
+
+// fire delivers t's tick and reschedules or retires it; callers hold c.mu
+func (c *VirtualClock) fire(t *virtualTimer) {
+	if t.when.After(c.now) {
+		c.now = t.when
+	}
+	select {
+	case t.ch <- t.when:
+	default:
+	}
+	if t.period > 0 {
+		t.when = t.when.Add(t.period)
+	} else {
+		t.stopped = true
+	}
+}
+
+// Advance moves time forward by d, firing every deadline passed on the
+// way in order
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for t := c.next(target, true); t != nil; t = c.next(target, true) {
+		c.fire(t)
+	}
+	c.now = target
+}
+
+// Step advances to the next deadline and fires it, returning how far time
+// moved; ok is false when nothing is scheduled
+func (c *VirtualClock) Step() (moved time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := c.next(time.Time{}, false)
+	if t == nil {
+		return 0, false
+	}
+	before := c.now
+	c.fire(t)
+	return c.now.Sub(before), true
+}
+
+// Pending returns the number of live tickers and sleepers
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next(c.now, true) // drops stopped timers
+	return len(c.timers)
+}
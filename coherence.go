@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Conversation coherence: the markov generator only looks at the current
+// input, so in multi-turn chat it can answer with a fluent non-sequitur.
+// The coherence scorer rates how well a candidate response fits the recent
+// session history, by the cosine similarity of their sentence embeddings
+// and by how many of the candidate's entities (keyphrase words and names)
+// the history mentions. Server mode generates the N best responses and
+// reranks them by their generator probability blended with coherence.
+
+// Keyphrases taken from each text as its entities
+const coherenceEntityPhrases = 10
+
+// CoherenceConfig controls reranking session replies by coherence
+type CoherenceConfig struct {
+	Enabled      bool    `json:"enabled"`
+	Candidates   int     `json:"candidates"`    // responses generated for reranking
+	HistoryTurns int     `json:"history_turns"` // recent messages compared with, the new one included
+	Weight       float64 `json:"weight"`        // share of the rerank score from coherence; the rest is generator probability
+	EntityWeight float64 `json:"entity_weight"` // share of coherence from entity overlap; the rest is embedding similarity
+}
+
+func (c CoherenceConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Candidates < 2 || c.Candidates > maxNBest {
+		return fmt.Errorf("coherence candidates must be between 2 and %d", maxNBest)
+	}
+	if c.HistoryTurns < 1 {
+		return fmt.Errorf("coherence history_turns must be at least 1")
+	}
+	if c.Weight < 0 || c.Weight > 1 || c.EntityWeight < 0 || c.EntityWeight > 1 {
+		return fmt.Errorf("coherence weights must be between 0 and 1")
+	}
+	return nil
+}
+
+// CoherenceScore is how well a response fits the conversation
+type CoherenceScore struct {
+	Similarity    float64 `json:"similarity"`     // cosine similarity of the embeddings, clamped to [0, 1]
+	EntityOverlap float64 `json:"entity_overlap"` // shared entities over the smaller entity set
+	Score         float64 `json:"score"`          // the weighted blend
+}
+
+// CoherenceScorer scores and reranks responses against session history
+type CoherenceScorer struct {
+	loader *DatasetLoader
+	config CoherenceConfig
+	ner    NERModel
+}
+
+func NewCoherenceScorer(loader *DatasetLoader, config CoherenceConfig) *CoherenceScorer {
+	return &CoherenceScorer{loader: loader, config: config}
+}
+
+// recent returns the last HistoryTurns messages' contents
+func (s *CoherenceScorer) recent(history []ChatMessage) []string {
+	if len(history) > s.config.HistoryTurns {
+		history = history[len(history)-s.config.HistoryTurns:]
+	}
+	texts := make([]string, len(history))
+	for i, msg := range history {
+		texts[i] = msg.Content
+	}
+	return texts
+}
+
+// entities returns the lowercase keyphrase words and names in text
+func (s *CoherenceScorer) entities(text string) map[string]bool {
+	entities := make(map[string]bool)
+	for _, kp := range s.loader.ExtractKeyphrases(text, coherenceEntityPhrases) {
+		for _, word := range kp.Words {
+			entities[word] = true
+		}
+	}
+	for _, name := range s.ner.Names(text) {
+		for _, word := range strings.Fields(strings.ToLower(name)) {
+			entities[word] = true
+		}
+	}
+	return entities
+}
+
+// Score rates how well response fits history
+func (s *CoherenceScorer) Score(response string, history []string) CoherenceScore {
+	var score CoherenceScore
+	context := strings.Join(history, "\n")
+
+	if a, _, ok := s.loader.SentenceEmbedding(response); ok {
+		if b, _, ok := s.loader.SentenceEmbedding(context); ok {
+			dot := 0.0
+			for i := range a {
+				dot += a[i] * b[i]
+			}
+			score.Similarity = clamp01(dot)
+		}
+	}
+
+	mine, theirs := s.entities(response), s.entities(context)
+	if len(mine) > 0 && len(theirs) > 0 {
+		shared := 0
+		for entity := range mine {
+			if theirs[entity] {
+				shared++
+			}
+		}
+		score.EntityOverlap = float64(shared) / math.Min(float64(len(mine)), float64(len(theirs)))
+	}
+
+	score.Score = (1-s.config.EntityWeight)*score.Similarity + s.config.EntityWeight*score.EntityOverlap
+	return score
+}
+
+// Rerank scores each candidate's coherence with history and orders them by
+// probability blended with coherence, best first
+func (s *CoherenceScorer) Rerank(candidates []ResponseCandidate, history []string) []ResponseCandidate {
+	ranked := append([]ResponseCandidate{}, candidates...)
+	blended := make(map[int]float64, len(ranked))
+	for i := range ranked {
+		ranked[i].Coherence = s.Score(ranked[i].Response, history).Score
+		blended[i] = (1-s.config.Weight)*ranked[i].Probability + s.config.Weight*ranked[i].Coherence
+	}
+	order := make([]int, len(ranked))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return blended[order[i]] > blended[order[j]]
+	})
+	result := make([]ResponseCandidate, len(ranked))
+	for i, j := range order {
+		result[i] = ranked[j]
+	}
+	return result
+}
+
+// SetCoherence reranks replies by coherence with the session history; nil
+// keeps the generator's choice
+func (h *SessionHandler) SetCoherence(scorer *CoherenceScorer) {
+	h.coherence = scorer
+}
+
+// coherentReply generates up to the configured number of candidates and
+// returns the one that fits history best. The explanation keeps as many
+// alternatives as the request asked for.
+func (h *SessionHandler) coherentReply(state *GeneratorState, input string, history []ChatMessage, options GenerationOptions) (string, *Explanation) {
+	requested := options.N
+	options.N = max(options.N, h.coherence.config.Candidates)
+	reply, explanation := h.generator.GenerateWithStateOptions(state, input, nil, options)
+	if len(explanation.Alternatives) == 0 {
+		return reply, explanation
+	}
+
+	ranked := h.coherence.Rerank(explanation.Alternatives, h.coherence.recent(history))
+	reply = ranked[0].Response
+	explanation.Response = reply
+	explanation.Coherence = ranked[0].Coherence
+	explanation.Alternatives = nil
+	if requested > 0 {
+		explanation.Alternatives = ranked[:min(requested, len(ranked))]
+	}
+	return reply, explanation
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cross-model comparison: the transparent model, the liquid brain and the
+// orchestrator answer the same input in very different ways, and seeing
+// them side by side is the quickest way to tell which suits a workload.
+// CompareModels runs one request through several models at once and
+// collects each model's answer, explanation or routing decisions, and
+// latency; a model that fails reports its error without failing the
+// others. Server mode exposes it next to the inference endpoints:
+//
+//	POST /v1/compare  {"input": "...", "models": ["liquid", ...], "seed": 1, "max_tokens": 20}
+//
+// models picks some of "transparent", "liquid" and "orchestrator" (all
+// when empty); seed and max_tokens are the only generation options that
+// apply. The orchestrator is held to the caller's capability policy.
+
+// ComparedModel is a model taking part in a comparison
+type ComparedModel struct {
+	Name  string
+	Model Model
+}
+
+// ModelResult is one model's answer in a comparison
+type ModelResult struct {
+	Model       string       `json:"model"`
+	Output      string       `json:"output"`
+	Confidence  Confidence   `json:"confidence"`
+	Energy      EnergyReport `json:"energy"`
+	Explanation *Explanation `json:"explanation,omitempty"` // the transparent model's reasoning
+	Decisions   []Decision   `json:"decisions,omitempty"`   // the orchestrator's routing steps
+	LimitsHit   []string     `json:"limits_hit,omitempty"`
+	LatencyMS   float64      `json:"latency_ms"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Comparison is one input answered by several models
+type Comparison struct {
+	Input     string        `json:"input"`
+	Results   []ModelResult `json:"results"`    // in the order the models were given
+	LatencyMS float64       `json:"latency_ms"` // the whole comparison, so the slowest model
+}
+
+// CompareModels runs req through every model concurrently
+func CompareModels(ctx context.Context, models []ComparedModel, req Request) *Comparison {
+	comparison := &Comparison{Input: req.Input, Results: make([]ModelResult, len(models))}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, m := range models {
+		wg.Add(1)
+		go func(i int, m ComparedModel) {
+			defer wg.Done()
+			comparison.Results[i] = compareModel(ctx, m, req)
+		}(i, m)
+	}
+	wg.Wait()
+	comparison.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	return comparison
+}
+
+// compareModel answers req with one model
+func compareModel(ctx context.Context, m ComparedModel, req Request) ModelResult {
+	start := time.Now()
+	response, err := func() (_ Response, err error) {
+		defer recoverError(&err, m.Name)
+		return m.Model.Respond(ctx, req)
+	}()
+	result := ModelResult{
+		Model:       m.Name,
+		Output:      response.Output,
+		Confidence:  response.Confidence,
+		Energy:      response.Energy,
+		Explanation: response.Explanation,
+		Decisions:   response.Decisions,
+		LimitsHit:   response.LimitsHit,
+		LatencyMS:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// selectModels returns the named models, in the order named; no names
+// selects them all
+func selectModels(models []ComparedModel, names []string) ([]ComparedModel, error) {
+	if len(names) == 0 {
+		return models, nil
+	}
+	available := make([]string, len(models))
+	for i, m := range models {
+		available[i] = m.Name
+	}
+	selected := make([]ComparedModel, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, m := range models {
+			if m.Name == name {
+				selected = append(selected, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown model %q (want one of %s)", name, strings.Join(available, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// Print shows the results side by side
+func (c *Comparison) Print() {
+	fmt.Printf("\n📊 COMPARISON: %q (%.1fms)\n", c.Input, c.LatencyMS)
+	for _, r := range c.Results {
+		fmt.Printf("\n• %s (%.1fms, confidence %.2f)\n", r.Model, r.LatencyMS, r.Confidence.Score)
+		if r.Error != "" {
+			fmt.Printf("   ❌ %s\n", r.Error)
+			continue
+		}
+		fmt.Printf("   %s\n", r.Output)
+		if r.Explanation != nil && len(r.Explanation.ActiveConcepts) > 0 {
+			fmt.Printf("   ↳ concepts: %s\n", strings.Join(r.Explanation.ActiveConcepts, ", "))
+		}
+		for _, d := range r.Decisions {
+			fmt.Printf("   ↳ %s: %s\n", strings.Join(d.Path, " → "), d.Reasoning)
+		}
+	}
+}
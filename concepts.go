@@ -0,0 +1,217 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The seed concepts, output meanings and their word lists, fallback
+// similarities and response starters are data, not code: they are read
+// from a concept schema file so non-English or domain-specific deployments
+// only need a different file. The default schema is embedded at
+// starter/concepts.json and used when concepts.schema_path is empty.
+
+//go:embed starter/concepts.json
+var defaultConceptSchemaJSON []byte
+
+// ConceptConfig selects the concept schema file
+type ConceptConfig struct {
+	SchemaPath string `json:"schema_path"` // "" uses the embedded default
+}
+
+// ConceptSchema holds the language- and domain-specific word lists
+type ConceptSchema struct {
+	FallbackConcepts  []string                      `json:"fallback_concepts"`  // concept neurons when no dataset loads
+	FallbackLinks     []ConceptLink                 `json:"fallback_links"`     // connections between fallback concepts
+	FallbackResponses []FallbackResponse            `json:"fallback_responses"` // canned responses when no dataset loads
+	DefaultResponse   string                        `json:"default_response"`
+	Similarities      map[string]map[string]float64 `json:"similarities"` // fallback word similarities
+	InputWords        []string                      `json:"input_words"`  // liquid brain input neurons
+	Meanings          []ConceptMeaning              `json:"meanings"`     // liquid brain output neurons
+	QuestionWords     []string                      `json:"question_words"`
+	GreetingWords     []string                      `json:"greeting_words"`
+	Starters          map[string][]string           `json:"starters"` // response type -> starter words
+
+	ClarificationTemplate string `json:"clarification_template"` // question with one %s for the options
+	ClarificationOr       string `json:"clarification_or"`       // joins the last two options
+
+	RepetitionTemplate  string `json:"repetition_template"`  // remark on a repeated concept, with one %s for it
+	AlternationTemplate string `json:"alternation_template"` // remark on alternation, with two %s for the concepts
+	EscalationTemplate  string `json:"escalation_template"`  // remark on escalating inputs
+
+	meanings map[string]*ConceptMeaning
+}
+
+// ConceptLink is a weighted connection between two fallback concepts
+type ConceptLink struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Strength float64 `json:"strength"`
+}
+
+// FallbackResponse is used when the crystallized meaning mentions every
+// concept in When
+type FallbackResponse struct {
+	When     []string `json:"when"`
+	Response string   `json:"response"`
+}
+
+// ConceptMeaning describes one output meaning of the liquid brain
+type ConceptMeaning struct {
+	Name       string   `json:"name"`
+	Concepts   []string `json:"concepts"`    // handed to the generator when active
+	SeedWords  []string `json:"seed_words"`  // words that start a response
+	MatchWords []string `json:"match_words"` // words that signal the meaning
+	Response   string   `json:"response"`    // reply when no dataset is loaded
+}
+
+var (
+	defaultConceptSchema     *ConceptSchema
+	defaultConceptSchemaOnce sync.Once
+)
+
+// DefaultConceptSchema returns the embedded schema; callers must not modify it
+func DefaultConceptSchema() *ConceptSchema {
+	defaultConceptSchemaOnce.Do(func() {
+		schema, err := parseConceptSchema(defaultConceptSchemaJSON)
+		if err != nil {
+			// The embedded file is fixed at build time, so this is a build bug
+			panic(fmt.Errorf("failed to parse embedded concept schema: %w", err))
+		}
+		defaultConceptSchema = schema
+	})
+	return defaultConceptSchema
+}
+
+// LoadConceptSchema reads a concept schema file
+func LoadConceptSchema(path string) (*ConceptSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read concept schema: %w", err)
+	}
+	schema, err := parseConceptSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse concept schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+func parseConceptSchema(data []byte) (*ConceptSchema, error) {
+	var schema ConceptSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	schema.meanings = make(map[string]*ConceptMeaning, len(schema.Meanings))
+	for i := range schema.Meanings {
+		schema.meanings[schema.Meanings[i].Name] = &schema.Meanings[i]
+	}
+	return &schema, nil
+}
+
+func (s *ConceptSchema) validate() error {
+	if len(s.InputWords) == 0 || len(s.Meanings) == 0 {
+		return fmt.Errorf("input_words and meanings must not be empty")
+	}
+	seen := make(map[string]bool, len(s.Meanings))
+	for _, m := range s.Meanings {
+		if m.Name == "" || seen[m.Name] {
+			return fmt.Errorf("meaning names must be unique and non-empty, got %q", m.Name)
+		}
+		seen[m.Name] = true
+	}
+	if t := s.ClarificationTemplate; t != "" && (strings.Count(t, "%") != 1 || !strings.Contains(t, "%s")) {
+		return fmt.Errorf("clarification_template must contain exactly one %%s")
+	}
+	for _, t := range []struct {
+		name     string
+		template string
+		verbs    int
+	}{
+		{"repetition_template", s.RepetitionTemplate, 1},
+		{"alternation_template", s.AlternationTemplate, 2},
+		{"escalation_template", s.EscalationTemplate, 0},
+	} {
+		if t.template != "" && (strings.Count(t.template, "%") != t.verbs || strings.Count(t.template, "%s") != t.verbs) {
+			return fmt.Errorf("%s must contain exactly %d %%s", t.name, t.verbs)
+		}
+	}
+	for _, link := range s.FallbackLinks {
+		if link.Strength < 0 || link.Strength > 1 {
+			return fmt.Errorf("link %s -> %s: strength must be between 0 and 1", link.From, link.To)
+		}
+	}
+	return nil
+}
+
+// conceptSchemaFromConfig returns the schema configured in config, falling
+// back to the default when the file can't be loaded
+func conceptSchemaFromConfig(config *Config) *ConceptSchema {
+	if config == nil || config.Concepts.SchemaPath == "" {
+		return DefaultConceptSchema()
+	}
+	schema, err := LoadConceptSchema(config.Concepts.SchemaPath)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v, using default concepts\n", err)
+		return DefaultConceptSchema()
+	}
+	return schema
+}
+
+// orDefault lets components built without a config use the default schema
+func (s *ConceptSchema) orDefault() *ConceptSchema {
+	if s == nil {
+		return DefaultConceptSchema()
+	}
+	return s
+}
+
+// Meaning returns the output meaning called name
+func (s *ConceptSchema) Meaning(name string) (*ConceptMeaning, bool) {
+	m, ok := s.orDefault().meanings[name]
+	return m, ok
+}
+
+// MeaningNames returns the output meanings in schema order
+func (s *ConceptSchema) MeaningNames() []string {
+	s = s.orDefault()
+	names := make([]string, len(s.Meanings))
+	for i, m := range s.Meanings {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// Similarity returns the fallback similarity of two words, in either order
+func (s *ConceptSchema) Similarity(word1, word2 string) (float64, bool) {
+	s = s.orDefault()
+	if sim, ok := s.Similarities[word1][word2]; ok {
+		return sim, true
+	}
+	sim, ok := s.Similarities[word2][word1]
+	return sim, ok
+}
+
+// FallbackResponseFor returns the canned response for a crystallized meaning
+func (s *ConceptSchema) FallbackResponseFor(meaning string) string {
+	s = s.orDefault()
+	for _, r := range s.FallbackResponses {
+		matched := true
+		for _, concept := range r.When {
+			if !strings.Contains(meaning, concept) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r.Response
+		}
+	}
+	return s.DefaultResponse
+}
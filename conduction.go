@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Axonal conduction: a spike reaches each target after a delay that grows
+// with the distance between the two neurons, a fixed synaptic part plus a
+// conduction part per unit of reservoir distance. Activity therefore spreads
+// outward from where it starts as a traveling wave, at a speed set by the
+// config, instead of arriving everywhere after a random 1-3ms. Delays are
+// fixed per synapse when the reservoir is wired; jitter, if any, is drawn
+// per spike. The spike batch scheduler delivers them in delay order.
+
+// ConductionConfig sets reservoir synapse delays
+type ConductionConfig struct {
+	SynapticDelayMS float64 `json:"synaptic_delay_ms"` // paid by every spike
+	MSPerUnit       float64 `json:"ms_per_unit"`       // added per unit of distance between neurons
+	Jitter          float64 `json:"jitter"`            // per spike, up to this share of the delay either way
+}
+
+// defaultConduction spans roughly the 1-3ms of the old random delay over
+// the connection radius
+var defaultConduction = ConductionConfig{SynapticDelayMS: 0.5, MSPerUnit: 0.75}
+
+func (c ConductionConfig) validate() error {
+	if c.SynapticDelayMS < 0 || c.MSPerUnit < 0 {
+		return fmt.Errorf("conduction delays must not be negative")
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return fmt.Errorf("conduction jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// orDefault lets configs without a conduction section keep the default
+func (c ConductionConfig) orDefault() ConductionConfig {
+	if c == (ConductionConfig{}) {
+		return defaultConduction
+	}
+	return c
+}
+
+// delay returns the conduction delay over distance
+func (c ConductionConfig) delay(distance float64) time.Duration {
+	ms := c.SynapticDelayMS + c.MSPerUnit*distance
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// jitter returns d shifted by a random share of up to Jitter either way
+func (c ConductionConfig) jitter(d time.Duration) time.Duration {
+	if c.Jitter == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + c.Jitter*(2*rand.Float64()-1)))
+}
+
+// distanceTo returns the reservoir distance between two neurons
+func (n *LiquidNeuron) distanceTo(target *LiquidNeuron) float64 {
+	dx, dy, dz := float64(target.x-n.x), float64(target.y-n.y), float64(target.z-n.z)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// synapseDelay returns a spike's delay over connection i
+func (n *LiquidNeuron) synapseDelay(i int) time.Duration {
+	conduction := n.conduction
+	if conduction == nil {
+		conduction = &defaultConduction
+	}
+	if n.delays == nil {
+		return conduction.jitter(conduction.delay(n.distanceTo(n.connections[i])))
+	}
+	return conduction.jitter(n.delays[i])
+}
+
+// assignDelays fixes every reservoir synapse's delay from its length
+func (brain *LiquidStateBrain) assignDelays() {
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for _, neuron := range brain.reservoir[x][y] {
+				neuron.conduction = &brain.conduction
+				neuron.delays = make([]time.Duration, len(neuron.connections))
+				for i, target := range neuron.connections {
+					neuron.delays[i] = brain.conduction.delay(neuron.distanceTo(target))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Every response carries a confidence estimate in [0, 1] built from the
+// signals each model has: how far the dominant activation stands above the
+// runner-up, how strong the traced meaning circuits are, and how clearly
+// the chosen beam beat the other beams. The orchestrator escalates to an
+// external capability when its own understanding isn't confident enough.
+
+// Confidence is a response's confidence and the signals it was built from.
+// Signals a model doesn't have are left at zero and not counted.
+type Confidence struct {
+	Score            float64 `json:"score"`
+	ActivationMargin float64 `json:"activation_margin,omitempty"` // (top - runner-up) / top activation
+	CircuitStrength  float64 `json:"circuit_strength,omitempty"`  // strongest traced circuit
+	BeamScore        float64 `json:"beam_score,omitempty"`        // probability of the chosen beam among the final beams
+}
+
+// combine sets Score to the mean of the given signals
+func (c *Confidence) combine(signals ...float64) {
+	if len(signals) == 0 {
+		c.Score = 0
+		return
+	}
+	sum := 0.0
+	for _, s := range signals {
+		sum += clamp01(s)
+	}
+	c.Score = sum / float64(len(signals))
+}
+
+func (c Confidence) String() string {
+	return fmt.Sprintf("%.2f (margin %.2f, circuits %.2f, beam %.2f)",
+		c.Score, c.ActivationMargin, c.CircuitStrength, c.BeamScore)
+}
+
+func clamp01(x float64) float64 {
+	return math.Min(1, math.Max(0, x))
+}
+
+// activationMargin returns how far the strongest activation stands above
+// the runner-up, relative to the strongest
+func activationMargin(activations []float64) float64 {
+	top, second := 0.0, 0.0
+	for _, a := range activations {
+		if a > top {
+			top, second = a, top
+		} else if a > second {
+			second = a
+		}
+	}
+	if top <= 0 {
+		return 0
+	}
+	return (top - second) / top
+}
+
+// beamProbability returns the softmax probability of the best of scores
+func beamProbability(best float64, scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, s := range scores {
+		total += math.Exp(s - best)
+	}
+	return 1 / total
+}
@@ -9,18 +9,59 @@ import (
 
 // Config holds all configuration for the LLM system
 type Config struct {
-	Model        ModelConfig        `json:"model"`
-	Training     TrainingConfig     `json:"training"`
-	Resources    ResourceLimits     `json:"resources"`
-	Datasets     DatasetConfig      `json:"datasets"`
+	Model         ModelConfig         `json:"model"`
+	Training      TrainingConfig      `json:"training"`
+	Resources     ResourceLimits      `json:"resources"`
+	Datasets      DatasetConfig       `json:"datasets"`
+	Profiling     ProfilingConfig     `json:"profiling"`
+	Retrieval     RetrievalConfig     `json:"retrieval"`
+	Sessions      SessionConfig       `json:"sessions"`
+	Server        ServerConfig        `json:"server"`
+	Admin         AdminConfig         `json:"admin"`
+	Matching      MatchingConfig      `json:"matching"`
+	Concepts      ConceptConfig       `json:"concepts"`
+	Privacy       PrivacyConfig       `json:"privacy"`
+	Fallback      FallbackConfig      `json:"fallback"`
+	Clarification ClarificationConfig `json:"clarification"`
+	Readout       ReadoutConfig       `json:"readout"`
+	Audit         AuditConfig         `json:"audit"`
+	Distill       DistillConfig       `json:"distill"`
+	BeamSearch    BeamSearchConfig    `json:"beam_search"`
+	Coherence     CoherenceConfig     `json:"coherence"`
+	Templates     TemplateConfig      `json:"templates"`
+	OutputTaps    OutputTapConfig     `json:"output_taps"`
+	Topology      TopologyConfig      `json:"topology"`
+	Modulation    ModulationConfig    `json:"modulation"`
+	Conduction    ConductionConfig    `json:"conduction"`
+	Degradation   DegradationConfig   `json:"degradation"`
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+	Visualization VisualizationConfig `json:"visualization"`
+	StageTimeouts StageTimeoutConfig  `json:"stage_timeouts"`
+	Backpressure  BackpressureConfig  `json:"backpressure"`
+	DecisionStore DecisionStoreConfig `json:"decision_store"`
+	Consensus     ConsensusConfig     `json:"consensus"`
+	CapabilityRouting  CapabilityRoutingConfig `json:"capability_routing"`
+	OrchestratorPlan   PlanConfig              `json:"orchestrator_plan"`
+	CapabilityPolicies CapabilityPolicyConfig  `json:"capability_policies"`
+	RequestLimits      RequestLimits           `json:"request_limits"`
+	InputHistory       InputHistoryConfig      `json:"input_history"`
+	OutputCalibration  CalibrationConfig       `json:"output_calibration"`
+	Ensemble           EnsembleConfig          `json:"ensemble"`
+	Runs               RunTrackingConfig       `json:"runs"`
+	Knowledge          KnowledgeConfig         `json:"knowledge"`
+	Inference          InferenceConfig         `json:"inference"`
+	CapabilitySandbox  CapabilitySandboxConfig `json:"capability_sandbox"`
+	Seed               int64                   `json:"seed"` // seeds wiring, evolution and generation; 0 uses the global math/rand
+	OpenInference      OpenInferenceConfig     `json:"openinference"`
 }
 
 type ModelConfig struct {
-	Type           string `json:"type"` // "transparent", "liquid", "evolving"
-	EmbeddingDim   int    `json:"embedding_dim"`
-	HiddenSize     int    `json:"hidden_size"`
-	NumLayers      int    `json:"num_layers"`
-	MaxConcepts    int    `json:"max_concepts"`
+	Type           string          `json:"type"` // "transparent", "liquid", "enhanced", "orchestrator", "parallel", "ensemble" or a registered plugin
+	EmbeddingDim   int             `json:"embedding_dim"`
+	HiddenSize     int             `json:"hidden_size"`
+	NumLayers      int             `json:"num_layers"`
+	MaxConcepts    int             `json:"max_concepts"`
+	Options        json.RawMessage `json:"options,omitempty"` // decoded by the model type's Options hook
 }
 
 type ResourceLimits struct {
@@ -28,6 +69,10 @@ type ResourceLimits struct {
 	MaxMemoryMB      int `json:"max_memory_mb"`
 	MaxNeurons       int `json:"max_neurons"`
 	ChannelBufferSize int `json:"channel_buffer_size"`
+	MaxConcurrentRequests int `json:"max_concurrent_requests"` // inference requests run at once
+	MaxQueuedRequests     int `json:"max_queued_requests"`     // waiting requests before 429s
+	QueueTimeoutMS        int `json:"queue_timeout_ms"`        // longest wait for a slot; 0 waits for the client
+	GoroutineShares map[string]float64 `json:"goroutine_shares"` // per component share of max_goroutines
 }
 
 type DatasetConfig struct {
@@ -37,44 +82,21 @@ type DatasetConfig struct {
 	TestSplitRatio   float64  `json:"test_split_ratio"`
 }
 
-// DefaultConfig returns a sensible default configuration
+// ProfilingConfig controls per-stage latency recording for Think/Understand
+type ProfilingConfig struct {
+	Enabled    bool `json:"enabled"`
+	WindowSize int  `json:"window_size"` // samples kept per stage for percentiles
+}
+
+// DefaultConfig returns a sensible default configuration, decoded from the
+// config embedded at starter/config.json
 func DefaultConfig() *Config {
-	return &Config{
-		Model: ModelConfig{
-			Type:         "transparent",
-			EmbeddingDim: 128,
-			HiddenSize:   256,
-			NumLayers:    3,
-			MaxConcepts:  10000,
-		},
-		Training: TrainingConfig{
-			DatasetPaths: []string{
-				"datasets/conversational_corpus.txt",
-				"datasets/high_quality_corpus.txt",
-				"datasets/dialogue_patterns.txt",
-			},
-			MaxVocabSize: 50000,
-			EmbeddingDim: 128,
-			MinWordFreq:  2,
-			MaxDocuments: 1000,
-		},
-		Resources: ResourceLimits{
-			MaxGoroutines:     1000,
-			MaxMemoryMB:       4096,
-			MaxNeurons:        100000,
-			ChannelBufferSize: 100,
-		},
-		Datasets: DatasetConfig{
-			Paths: []string{
-				"datasets/conversational_corpus.txt",
-				"datasets/high_quality_corpus.txt",
-				"datasets/dialogue_patterns.txt",
-			},
-			MaxDocuments:     1000,
-			MinWordFrequency: 2,  // Reduced for testing
-			TestSplitRatio:   0.2,
-		},
+	config, err := parseDefaultConfig()
+	if err != nil {
+		// The embedded file is fixed at build time, so this is a build bug
+		panic(err)
 	}
+	return config
 }
 
 // LoadConfig loads configuration from a JSON file
@@ -96,8 +118,9 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	// Sections missing from older config files keep their defaults
+	config := DefaultConfig()
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
@@ -105,8 +128,11 @@ func LoadConfig(path string) (*Config, error) {
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if config.Distill.Enabled {
+		config.Training.DistillPath = config.Distill.Path
+	}
 
-	return &config, nil
+	return config, nil
 }
 
 // SaveConfig saves configuration to a JSON file
@@ -125,6 +151,9 @@ func SaveConfig(path string, config *Config) error {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	if _, _, err := lookupModelType(c.Model); err != nil {
+		return err
+	}
 	if c.Model.EmbeddingDim <= 0 {
 		return fmt.Errorf("embedding_dim must be positive")
 	}
@@ -137,11 +166,158 @@ func (c *Config) Validate() error {
 	if c.Resources.MaxMemoryMB <= 0 {
 		return fmt.Errorf("max_memory_mb must be positive")
 	}
+	if c.Resources.MaxConcurrentRequests <= 0 {
+		return fmt.Errorf("max_concurrent_requests must be positive")
+	}
+	if c.Resources.MaxQueuedRequests < 0 || c.Resources.QueueTimeoutMS < 0 {
+		return fmt.Errorf("max_queued_requests and queue_timeout_ms must not be negative")
+	}
+	if err := validateGoroutineShares(c.Resources.GoroutineShares); err != nil {
+		return err
+	}
 	if len(c.Datasets.Paths) == 0 {
 		return fmt.Errorf("at least one dataset path is required")
 	}
 	if c.Datasets.TestSplitRatio < 0 || c.Datasets.TestSplitRatio > 1 {
 		return fmt.Errorf("test_split_ratio must be between 0 and 1")
 	}
+	if c.Retrieval.ChunkTokens <= 0 {
+		return fmt.Errorf("chunk_tokens must be positive")
+	}
+	if c.Retrieval.ChunkOverlap < 0 || c.Retrieval.ChunkOverlap >= c.Retrieval.ChunkTokens {
+		return fmt.Errorf("chunk_overlap must be between 0 and chunk_tokens-1")
+	}
+	if c.Retrieval.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	if c.Retrieval.AnswerThreshold < 0 {
+		return fmt.Errorf("answer_threshold must not be negative")
+	}
+	switch c.Retrieval.VectorStore.Type {
+	case "", "memory", "qdrant", "sqlite-vec":
+	default:
+		return fmt.Errorf("unknown vector_store type %q", c.Retrieval.VectorStore.Type)
+	}
+	switch c.Sessions.Store {
+	case "", "file", "memory", "sql":
+	default:
+		return fmt.Errorf("unknown sessions store %q", c.Sessions.Store)
+	}
+	if c.Sessions.TTLMinutes < 0 {
+		return fmt.Errorf("sessions ttl_minutes must not be negative")
+	}
+	if err := c.Server.validate(); err != nil {
+		return err
+	}
+	if err := c.Matching.validate(); err != nil {
+		return err
+	}
+	if err := c.Clarification.validate(); err != nil {
+		return err
+	}
+	if err := c.Readout.validate(); err != nil {
+		return err
+	}
+	if err := c.Audit.validate(); err != nil {
+		return err
+	}
+	if err := c.Distill.validate(); err != nil {
+		return err
+	}
+	if err := c.BeamSearch.validate(); err != nil {
+		return err
+	}
+	if err := c.Coherence.validate(); err != nil {
+		return err
+	}
+	if err := c.Templates.validate(); err != nil {
+		return err
+	}
+	if err := c.OutputTaps.validate(); err != nil {
+		return err
+	}
+	if err := c.Topology.validate(); err != nil {
+		return err
+	}
+	if err := c.Modulation.validate(); err != nil {
+		return err
+	}
+	if err := c.Conduction.validate(); err != nil {
+		return err
+	}
+	if err := c.Degradation.validate(); err != nil {
+		return err
+	}
+	if err := c.ResponseCache.validate(); err != nil {
+		return err
+	}
+	if err := c.Visualization.validate(); err != nil {
+		return err
+	}
+	if err := c.StageTimeouts.validate(); err != nil {
+		return err
+	}
+	if err := c.Backpressure.validate(); err != nil {
+		return err
+	}
+	if err := c.Consensus.validate(); err != nil {
+		return err
+	}
+	if err := c.CapabilityRouting.validate(); err != nil {
+		return err
+	}
+	if err := c.OrchestratorPlan.validate(); err != nil {
+		return err
+	}
+	if err := c.CapabilityPolicies.validate(); err != nil {
+		return err
+	}
+	for key := range c.CapabilityPolicies.APIKeys {
+		known := false
+		for _, k := range c.Server.APIKeys {
+			known = known || k.Name == key
+		}
+		if !known {
+			return fmt.Errorf("capability_policies api key %q is not a server api key name", key)
+		}
+	}
+	if err := c.RequestLimits.validate(); err != nil {
+		return err
+	}
+	if err := c.InputHistory.validate(); err != nil {
+		return err
+	}
+	if err := c.OutputCalibration.validate(); err != nil {
+		return err
+	}
+	if err := c.Ensemble.validate(); err != nil {
+		return err
+	}
+	if err := c.Knowledge.validate(); err != nil {
+		return err
+	}
+	if err := c.Inference.validate(); err != nil {
+		return err
+	}
+	if err := c.CapabilitySandbox.validate(); err != nil {
+		return err
+	}
+	if err := c.Training.Pruning.validate(); err != nil {
+		return err
+	}
+	if c.Training.TrigramWeight < 0 || c.Training.TrigramWeight > 1 {
+		return fmt.Errorf("training TrigramWeight must be between 0 and 1")
+	}
+	if err := c.Fallback.validate(); err != nil {
+		return err
+	}
+	if err := c.Privacy.validate(); err != nil {
+		return err
+	}
+	if c.Concepts.SchemaPath != "" {
+		if _, err := LoadConceptSchema(c.Concepts.SchemaPath); err != nil {
+			return err
+		}
+	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Connectivity export: the reservoir's wiring as a sparse N x N matrix, so
+// the same network can be analyzed or simulated in external tools and its
+// dynamics compared against Genesis's own. Neuron i is the one at
+// x, y, z with i = (x*Y + y)*Z + z, the order StateSnapshot and the state
+// recordings use. Entry (i, j) is the synapse from neuron i to neuron j,
+// weighted by its expected signed strength: spikes draw a strength from the
+// synapse's weight range, so the matrix holds the middle of that range,
+// negative for inhibitory synapses. Matrices are written as .npz files in
+// the layout scipy.sparse.save_npz uses, so scipy.sparse.load_npz reads
+// them back directly, or as Matrix Market text.
+
+// Sparse matrix formats the reservoir connectivity is exported in
+const (
+	SparseCOO = "coo"
+	SparseCSR = "csr"
+)
+
+// SparseConnectivity is the reservoir's weighted adjacency in COO form,
+// sorted by row and then column
+type SparseConnectivity struct {
+	Neurons    int
+	Dimensions [3]int // X, Y, Z
+	Rows       []int64
+	Cols       []int64
+	Weights    []float64
+	Thresholds []float64 // firing threshold of each neuron
+}
+
+// mean is the middle of the range
+func (r WeightRange) mean() float64 {
+	return (r.Min + r.Max) / 2
+}
+
+// neuronIndex is the flat index of the neuron at x, y, z
+func (brain *LiquidStateBrain) neuronIndex(n *LiquidNeuron) int64 {
+	dims := brain.dimensions
+	return int64((n.x*dims.Y+n.y)*dims.Z + n.z)
+}
+
+// SparseConnectivity returns the reservoir's wiring as a sparse matrix
+func (brain *LiquidStateBrain) SparseConnectivity() *SparseConnectivity {
+	dims := brain.dimensions
+	m := &SparseConnectivity{
+		Neurons:    dims.X * dims.Y * dims.Z,
+		Dimensions: [3]int{dims.X, dims.Y, dims.Z},
+		Thresholds: make([]float64, 0, dims.X*dims.Y*dims.Z),
+	}
+	excitatory := brain.topology.ExcitatoryWeight.mean()
+	inhibitory := -brain.topology.InhibitoryWeight.mean()
+
+	type synapse struct {
+		col    int64
+		weight float64
+	}
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				neuron := brain.reservoir[x][y][z]
+				m.Thresholds = append(m.Thresholds, neuron.threshold)
+
+				row := make([]synapse, len(neuron.connections))
+				for i, target := range neuron.connections {
+					row[i] = synapse{brain.neuronIndex(target), excitatory}
+					if neuron.inhibitory != nil && neuron.inhibitory[i] {
+						row[i].weight = inhibitory
+					}
+				}
+				sort.Slice(row, func(i, j int) bool { return row[i].col < row[j].col })
+				for _, s := range row {
+					m.Rows = append(m.Rows, brain.neuronIndex(neuron))
+					m.Cols = append(m.Cols, s.col)
+					m.Weights = append(m.Weights, s.weight)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// IndPtr returns the CSR row pointers: row i's entries are
+// [indptr[i], indptr[i+1])
+func (m *SparseConnectivity) IndPtr() []int64 {
+	indptr := make([]int64, m.Neurons+1)
+	for _, row := range m.Rows {
+		indptr[row+1]++
+	}
+	for i := 1; i < len(indptr); i++ {
+		indptr[i] += indptr[i-1]
+	}
+	return indptr
+}
+
+// WriteNpz writes the matrix as format ("coo" or "csr") in the layout
+// scipy.sparse.save_npz uses, plus the neuron coordinates ("coords",
+// N x 3) and firing thresholds ("thresholds", N)
+func (m *SparseConnectivity) WriteNpz(path, format string) error {
+	var arrays []func(nw *NpzWriter) error
+	switch format {
+	case SparseCOO:
+		arrays = []func(nw *NpzWriter) error{
+			func(nw *NpzWriter) error { return nw.AddInt64("row", []int{len(m.Rows)}, m.Rows) },
+			func(nw *NpzWriter) error { return nw.AddInt64("col", []int{len(m.Cols)}, m.Cols) },
+		}
+	case SparseCSR:
+		arrays = []func(nw *NpzWriter) error{
+			func(nw *NpzWriter) error { return nw.AddInt64("indices", []int{len(m.Cols)}, m.Cols) },
+			func(nw *NpzWriter) error { return nw.AddInt64("indptr", []int{m.Neurons + 1}, m.IndPtr()) },
+		}
+	default:
+		return fmt.Errorf("unknown sparse format %q (want %s or %s)", format, SparseCOO, SparseCSR)
+	}
+
+	shape := []int64{int64(m.Neurons), int64(m.Neurons)}
+	coords := make([]int64, 0, m.Neurons*3)
+	for x := 0; x < m.Dimensions[0]; x++ {
+		for y := 0; y < m.Dimensions[1]; y++ {
+			for z := 0; z < m.Dimensions[2]; z++ {
+				coords = append(coords, int64(x), int64(y), int64(z))
+			}
+		}
+	}
+	arrays = append(arrays,
+		func(nw *NpzWriter) error { return nw.AddFloat64("data", []int{len(m.Weights)}, m.Weights) },
+		func(nw *NpzWriter) error { return nw.AddInt64("shape", []int{2}, shape) },
+		func(nw *NpzWriter) error { return nw.AddStrings("format", []string{format}) },
+		func(nw *NpzWriter) error { return nw.AddInt64("coords", []int{m.Neurons, 3}, coords) },
+		func(nw *NpzWriter) error { return nw.AddFloat64("thresholds", []int{m.Neurons}, m.Thresholds) },
+	)
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	for _, add := range arrays {
+		if err := add(nw); err != nil {
+			nw.Close()
+			return err
+		}
+	}
+	return nw.Close()
+}
+
+// WriteMatrixMarket writes the matrix in Matrix Market coordinate format,
+// with 1-based indices as the format requires
+func (m *SparseConnectivity) WriteMatrixMarket(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "%%MatrixMarket matrix coordinate real general")
+	fmt.Fprintf(bw, "%% Genesis reservoir connectivity, %dx%dx%d neurons, i = (x*%d + y)*%d + z + 1\n",
+		m.Dimensions[0], m.Dimensions[1], m.Dimensions[2], m.Dimensions[1], m.Dimensions[2])
+	fmt.Fprintf(bw, "%d %d %d\n", m.Neurons, m.Neurons, len(m.Weights))
+	for i := range m.Weights {
+		fmt.Fprintf(bw, "%d %d %g\n", m.Rows[i]+1, m.Cols[i]+1, m.Weights[i])
+	}
+	return bw.Flush()
+}
+
+// ExportConnectivity writes the reservoir's wiring to path as format:
+// "coo" or "csr" (.npz) or "mtx" (Matrix Market)
+func ExportConnectivity(brain *LiquidStateBrain, path, format string) error {
+	m := brain.SparseConnectivity()
+	if format != "mtx" {
+		return m.WriteNpz(path, format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := m.WriteMatrixMarket(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
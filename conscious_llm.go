@@ -13,15 +13,31 @@ import (
 
 // TransparentLLM - An LLM that shows HOW it understands
 type TransparentLLM struct {
-	concepts      map[string]*ConceptNeuron
+	concepts      *shardedMap[*ConceptNeuron]
 	activeCircuits map[string]*CircuitPath
-	thoughtStream  chan ThoughtTrace
+	thoughtBuffer  int // per-call thought stream capacity
+	activity       *activityTracker // in-flight concept pulses
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 	dataLoader    *DatasetLoader
 	generator     *ResponseGenerator
+	retrieval     *RetrievalIndex // nil when retrieval is disabled
+	profiler      *StageProfiler // nil unless profiling is enabled
+	energy        EnergyMeter    // cumulative concept network work
+	matcher       *WordMatcher   // typo-tolerant word matching; nil when off
+	schema        *ConceptSchema // fallback concepts, responses and similarities
+	templates     *TemplateLibrary // configurable fallback responses; nil uses the schema's
+	clarification ClarificationConfig // guarded by mu
+	budget        *GoroutineBudget    // where the concept workers were granted from
+	workers       int                 // concept workers held from budget
+	responses     *ResponseCache      // repeated inputs; nil when caching is off
+	stageTimeouts StageTimeoutConfig  // per-stage limits within Understand
+	limits        RequestLimits       // bound each Respond
+	overflow      *channelOverflow    // thought and pulse channel policies and drop counts
+	knowledge     *KnowledgeStore     // facts learned from inputs; nil when the store is off
+	rng           *rand.Rand          // concept vectors and word choices, seeded by config.Seed
 }
 
 type ConceptNeuron struct {
@@ -31,6 +47,9 @@ type ConceptNeuron struct {
 	meaning     []float64 // semantic embedding
 	visual      chan Pulse // for visualization
 	ctx         context.Context
+	activity    *activityTracker // shared with the owning LLM
+	energy      *EnergyMeter     // the owning LLM's work counters
+	overflow    *channelOverflow // the owning LLM's pulse policy and drop counts
 }
 
 type Connection struct {
@@ -53,9 +72,10 @@ type CircuitPath struct {
 }
 
 type ThoughtTrace struct {
-	stage    string
-	circuits []CircuitPath
-	insight  string
+	stage       string
+	circuits    []CircuitPath
+	insight     string
+	explanation *Explanation // set on RESPONSE_GENERATION
 }
 
 func NewTransparentLLM() *TransparentLLM {
@@ -70,11 +90,22 @@ func NewTransparentLLMWithConfig(config *Config) *TransparentLLM {
 	
 	ctx, cancel := context.WithCancel(context.Background())
 	llm := &TransparentLLM{
-		concepts:       make(map[string]*ConceptNeuron),
+		concepts:       newShardedMap[*ConceptNeuron](),
 		activeCircuits: make(map[string]*CircuitPath),
-		thoughtStream:  make(chan ThoughtTrace, config.Resources.ChannelBufferSize),
+		thoughtBuffer:  max(1, config.Resources.ChannelBufferSize),
+		activity:       newActivityTracker(),
 		ctx:            ctx,
 		cancel:         cancel,
+		profiler:       newProfilerFromConfig(config),
+		matcher:        newMatcherFromConfig(config),
+		schema:         conceptSchemaFromConfig(config),
+		templates:      templateLibraryFromConfig(config),
+		clarification:  config.Clarification,
+		stageTimeouts:  config.StageTimeouts,
+		limits:         config.RequestLimits,
+		overflow:       newChannelOverflow(config.Backpressure),
+		responses:      NewResponseCache(config.ResponseCache, RealClock),
+		rng:            newRand(config.Seed),
 	}
 	
 	// Load dataset with error handling
@@ -89,12 +120,68 @@ func NewTransparentLLMWithConfig(config *Config) *TransparentLLM {
 	} else {
 		llm.dataLoader = dataLoader
 		llm.generator = NewResponseGenerator(dataLoader)
+		llm.generator.SetConceptSchema(llm.schema)
+		llm.generator.SetBeamSearch(config.BeamSearch)
+		llm.generator.SetTemplates(llm.templates, config.Templates)
+		llm.generator.SetSeed(config.Seed)
+		if config.Retrieval.TopK > 0 {
+			if index, err := NewRetrievalIndex(dataLoader, config.Retrieval); err != nil {
+				fmt.Printf("⚠️  Warning: retrieval disabled: %v\n", err)
+			} else {
+				llm.retrieval = index
+				llm.generator.SetRetrievalIndex(index, config.Retrieval.TopK)
+				llm.generator.SetAnswerThreshold(config.Retrieval.AnswerThreshold)
+			}
+		}
 		llm.initializeFromDataset(config)
 	}
+	if config.Knowledge.Path != "" {
+		if store, err := OpenKnowledgeStore(config.Knowledge); err != nil {
+			fmt.Printf("⚠️  Warning: facts won't be learned: %v\n", err)
+		} else {
+			llm.knowledge = store
+			if llm.generator != nil {
+				llm.generator.SetKnowledgeStore(store)
+			}
+		}
+	}
+	llm.startConcepts(config.Resources)
 	
 	return llm
 }
 
+// startConcepts runs the concept neurons on workers granted by the
+// goroutine budget, batching them when granted fewer than one each
+func (llm *TransparentLLM) startConcepts(limits ResourceLimits) {
+	var neurons []*ConceptNeuron
+	llm.concepts.Range(func(_ string, neuron *ConceptNeuron) bool {
+		neurons = append(neurons, neuron)
+		return true
+	})
+	if len(neurons) == 0 {
+		return
+	}
+	llm.budget = configureGoroutineBudget(limits)
+	llm.workers = llm.budget.Request(budgetLLM, len(neurons), 1)
+	perWorker := (len(neurons) + llm.workers - 1) / llm.workers
+	if perWorker > 1 {
+		fmt.Printf("⚡ Batching %d concept neurons per goroutine to stay within %d granted workers\n", perWorker, llm.workers)
+	}
+	for start := 0; start < len(neurons); start += perWorker {
+		batch := neurons[start:min(start+perWorker, len(neurons))]
+		llm.wg.Add(1)
+		go func() {
+			defer llm.wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Printf("🚨 Concept neuron panic recovered: %v\n", r)
+				}
+			}()
+			liveConcepts(batch)
+		}()
+	}
+}
+
 // Cleanup properly shuts down the LLM with timeout
 func (llm *TransparentLLM) Cleanup() {
 	if llm.cancel == nil {
@@ -117,19 +204,28 @@ func (llm *TransparentLLM) Cleanup() {
 	case <-time.After(3 * time.Second):
 		fmt.Println("⚠️  LLM cleanup timeout - some goroutines may still be running")
 	}
-	
-	// Safely close channels and clear resources
-	if llm.thoughtStream != nil {
-		close(llm.thoughtStream)
-		llm.thoughtStream = nil
+	if llm.budget != nil {
+		llm.budget.Release(budgetLLM, llm.workers)
+		llm.workers = 0
 	}
 	
 	// Clear concept neurons' visual channels
-	for _, neuron := range llm.concepts {
+	llm.concepts.Range(func(_ string, neuron *ConceptNeuron) bool {
 		if neuron.visual != nil {
 			close(neuron.visual)
 			neuron.visual = nil
 		}
+		return true
+	})
+	
+	if err := llm.knowledge.Close(); err != nil {
+		fmt.Printf("⚠️  Warning: failed to close knowledge store: %v\n", err)
+	}
+	llm.knowledge = nil
+	if llm.retrieval != nil {
+		if err := llm.retrieval.Close(); err != nil {
+			fmt.Printf("⚠️  Warning: failed to close vector store: %v\n", err)
+		}
 	}
 	
 	llm.cancel = nil // Mark as cleaned up
@@ -142,59 +238,31 @@ func (llm *TransparentLLM) initializeConceptNetwork() error {
 			fmt.Printf("🚨 Concept network initialization panic recovered: %v\n", r)
 		}
 	}()
-	// Create a rich semantic network
-	concepts := []string{
-		"question", "understand", "meaning", "context",
-		"user", "intent", "emotion", "help", "solve",
-		"pattern", "similar", "experience", "connection",
-		"frustration", "code", "debug", "error", "stuck",
-		"insight", "solution", "approach", "alternative",
-	}
+	// Create a rich semantic network from the schema's fallback concepts
+	schema := llm.schema.orDefault()
 	
 	// Create neurons for each concept
-	for _, concept := range concepts {
+	for _, concept := range schema.FallbackConcepts {
 		neuron := &ConceptNeuron{
 			id:          concept,
 			connections: make(map[string]*Connection),
-			meaning:     generateSemanticVector(concept),
+			meaning:     generateSemanticVector(concept, llm.rng),
 			visual:      make(chan Pulse, 10), // Reduced buffer
 			ctx:         llm.ctx,
+			activity:    llm.activity,
+			energy:      &llm.energy,
+			overflow:    llm.overflow,
 		}
 		neuron.activation.Store(0.0)
-		llm.concepts[concept] = neuron
-		
-		// Start neuron's autonomous processing with error handling
-		llm.wg.Add(1)
-		go func(n *ConceptNeuron) {
-			defer llm.wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("🚨 Concept neuron panic recovered: %v\n", r)
-				}
-			}()
-			n.live()
-		}(neuron)
+		llm.concepts.Set(concept, neuron)
 	}
 	
 	// Create meaningful connections
-	llm.connect("question", "understand", 0.9)
-	llm.connect("question", "intent", 0.8)
-	llm.connect("understand", "meaning", 0.9)
-	llm.connect("understand", "context", 0.7)
-	llm.connect("user", "intent", 0.8)
-	llm.connect("user", "emotion", 0.6)
-	llm.connect("frustration", "stuck", 0.9)
-	llm.connect("frustration", "error", 0.8)
-	llm.connect("code", "debug", 0.7)
-	llm.connect("code", "error", 0.8)
-	llm.connect("stuck", "help", 0.9)
-	llm.connect("help", "solution", 0.8)
-	llm.connect("pattern", "similar", 0.9)
-	llm.connect("pattern", "experience", 0.7)
-	llm.connect("insight", "solution", 0.8)
-	llm.connect("insight", "approach", 0.7)
-	
-	fmt.Printf("✅ Initialized %d concept neurons\n", len(llm.concepts))
+	for _, link := range schema.FallbackLinks {
+		llm.connect(link.From, link.To, link.Strength)
+	}
+	
+	fmt.Printf("✅ Initialized %d concept neurons\n", llm.concepts.Len())
 	return nil
 }
 
@@ -202,7 +270,7 @@ func (llm *TransparentLLM) initializeFromDataset(config *Config) {
 	vocab := llm.dataLoader.GetVocabulary()
 	fmt.Printf("Initializing network with %d concepts from dataset\n", len(vocab))
 	
-	// Limit concepts to configured maximum
+	// Limit concepts to configured maximum, keeping the most frequent words
 	maxConcepts := config.Model.MaxConcepts
 	if len(vocab) > maxConcepts {
 		vocab = vocab[:maxConcepts]
@@ -217,16 +285,12 @@ func (llm *TransparentLLM) initializeFromDataset(config *Config) {
 			meaning:     embedding,
 			visual:      make(chan Pulse, config.Resources.ChannelBufferSize/10),
 			ctx:         llm.ctx,
+			activity:    llm.activity,
+			energy:      &llm.energy,
+			overflow:    llm.overflow,
 		}
 		neuron.activation.Store(0.0)
-		llm.concepts[word] = neuron
-		
-		// Start neuron's autonomous processing
-		llm.wg.Add(1)
-		go func(n *ConceptNeuron) {
-			defer llm.wg.Done()
-			n.live()
-		}(neuron)
+		llm.concepts.Set(word, neuron)
 	}
 	
 	// Create connections based on semantic similarity
@@ -234,10 +298,10 @@ func (llm *TransparentLLM) initializeFromDataset(config *Config) {
 }
 
 func (llm *TransparentLLM) connect(from, to string, strength float64) {
-	fromNeuron := llm.concepts[from]
-	toNeuron := llm.concepts[to]
+	fromNeuron, ok1 := llm.concepts.Get(from)
+	toNeuron, ok2 := llm.concepts.Get(to)
 	
-	if fromNeuron == nil || toNeuron == nil {
+	if !ok1 || !ok2 {
 		return
 	}
 	
@@ -258,8 +322,22 @@ func (llm *TransparentLLM) createSemanticConnections() {
 	connectionCount := 0
 	maxConnectionsPerWord := 10
 	
+	// Look up each word's normalized embedding once; comparing every pair
+	// directly also keeps this sweep from flushing the similarity cache
+	vectors := llm.dataLoader.vectors.Load()
+	if vectors == nil {
+		return
+	}
+	rows := make([][]float64, len(vocab))
+	for i, word := range vocab {
+		rows[i], _ = vectors.vector(word)
+	}
+	
 	// Create connections based on semantic similarity
 	for i, word1 := range vocab {
+		if rows[i] == nil {
+			continue
+		}
 		bestConnections := make([]struct {
 			word string
 			sim  float64
@@ -267,11 +345,11 @@ func (llm *TransparentLLM) createSemanticConnections() {
 		
 		// Find most similar words
 		for j, word2 := range vocab {
-			if i == j {
+			if i == j || rows[j] == nil {
 				continue
 			}
 			
-			sim := llm.dataLoader.ComputeSimilarity(word1, word2)
+			sim := dot(rows[i], rows[j])
 			if sim > 0.5 {
 				bestConnections = append(bestConnections, struct {
 					word string
@@ -298,69 +376,200 @@ func (llm *TransparentLLM) createSemanticConnections() {
 
 // The magic happens here - WATCH the understanding process
 func (llm *TransparentLLM) Understand(input string) (string, <-chan ThoughtTrace) {
+	response, _, visualization := llm.UnderstandExplained(input)
+	return response, visualization
+}
+
+// UnderstandExplained is Understand that also returns the structured
+// explanation of the response, including cited corpus chunks
+func (llm *TransparentLLM) UnderstandExplained(input string) (string, *Explanation, <-chan ThoughtTrace) {
+	return llm.understand(context.Background(), input, GenerationOptions{})
+}
+
+// UnderstandWithOptions is UnderstandExplained generating within the
+// request's options, such as its seed. Spreading activation through the
+// concept network runs concurrently and isn't seeded.
+func (llm *TransparentLLM) UnderstandWithOptions(input string, options GenerationOptions) (string, *Explanation, <-chan ThoughtTrace) {
+	return llm.UnderstandContext(context.Background(), input, options)
+}
+
+// UnderstandContext is UnderstandWithOptions with each stage's deadline
+// derived from ctx; stages that run out of time are reported in the
+// explanation and the thought stream
+func (llm *TransparentLLM) UnderstandContext(ctx context.Context, input string, options GenerationOptions) (string, *Explanation, <-chan ThoughtTrace) {
+	if err := options.validate(); err != nil {
+		fmt.Printf("⚠️  Warning: ignoring invalid generation options: %v\n", err)
+		options = GenerationOptions{Seed: options.Seed, budget: options.budget, thoughts: options.thoughts}
+	}
+	return llm.understand(ctx, input, options)
+}
+
+// understand does the work of UnderstandExplained, generating with options
+// under deadlines from ctx
+func (llm *TransparentLLM) understand(ctx context.Context, input string, options GenerationOptions) (string, *Explanation, <-chan ThoughtTrace) {
+	input = NormalizeInput(input)
+	key := responseCacheKey(input, options, nil)
+	recalled := llm.learnFacts(input)
+	if cached, ok := llm.responses.Get(key); ok && !recalled {
+		fmt.Printf("\n⚡ Cached response for '%s'\n", input)
+		visualization := make(chan ThoughtTrace)
+		close(visualization)
+		return cached.Output, cached.Explanation, visualization
+	}
+	
 	fmt.Println("\n🧠 Watch as I understand your question...")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	
 	// Create visualization channel
 	visualization := make(chan ThoughtTrace, 100)
+	thoughtStream := make(chan ThoughtTrace, llm.thoughtBuffer)
 	var processingDone sync.WaitGroup
 	var response string
+	var explanation *Explanation
+	var timeouts []string // stages that ran out of time
+	limits := llm.stageTimeouts
+	overflow := llm.overflow
+	think := func(thought ThoughtTrace) {
+		offer(thoughtStream, thought, overflow.config.Thoughts, &overflow.thoughts, nil)
+	}
 	
 	processingDone.Add(1)
 	go func() {
 		defer processingDone.Done()
+		defer close(thoughtStream) // signals the streamer that processing is complete
+		defer func() {
+			if explanation != nil && len(timeouts) > 0 {
+				explanation.Timeouts = timeouts
+			}
+			if explanation != nil {
+				explanation.LimitsHit = options.budget.Hit()
+			}
+			think(completeThought(timeouts))
+		}()
+		timedOut := func(stage string, err error) {
+			timeouts = append(timeouts, stage)
+			think(timeoutThought(stage, err))
+		}
+		
+		call := llm.profiler.Begin("understand")
+		defer call.Done()
+		before := llm.energy.Snapshot()
 		
 		// Stage 1: Parallel word activation
-		llm.thoughtStream <- ThoughtTrace{
+		think(ThoughtTrace{
 			stage:   "PARSING",
 			insight: "Activating word concepts in parallel...",
-		}
-		
-		words := strings.Fields(strings.ToLower(input))
-		var wg sync.WaitGroup
+		})
 		
-		// Every word creates ripples through the network
-		for _, word := range words {
-			wg.Add(1)
-			go func(w string) {
-				defer wg.Done()
-				llm.activateWord(w)
-			}(word)
+		parsing, cancelParsing := stageContext(ctx, limits.ParsingMS)
+		if parsing.Err() == nil {
+			words := strings.Fields(strings.ToLower(input))
+			var wg sync.WaitGroup
+			
+			// Every word creates ripples through the network
+			for _, word := range words {
+				wg.Add(1)
+				go func(w string) {
+					defer wg.Done()
+					llm.activateWord(w)
+				}(word)
+			}
+			
+			wg.Wait()
+			
+			// Let activation spread until the pulses die out
+			llm.activity.WaitQuiet(pulseSettleGrace, untilDeadline(parsing, pulseSettleTimeout))
 		}
-		
-		wg.Wait()
-		time.Sleep(50 * time.Millisecond) // Let activation spread
+		if err := parsing.Err(); err != nil {
+			timedOut(stageParsing, err)
+		}
+		cancelParsing()
+		call.Mark("parsing")
 		
 		// Stage 2: Pattern emergence
-		llm.thoughtStream <- ThoughtTrace{
+		think(ThoughtTrace{
 			stage:   "PATTERN_RECOGNITION",
 			insight: "Watching for emerging patterns...",
-		}
+		})
 		
 		// Find active circuits
-		circuits := llm.findActiveCircuits()
+		search, cancelSearch := stageContext(ctx, limits.CircuitSearchMS)
+		circuits, err := llm.findActiveCircuitsContext(search)
+		cancelSearch()
+		if err != nil {
+			timedOut(stageCircuitSearch, err)
+		}
+		circuits = options.budget.circuits(circuits)
+		confidence := llm.understandingConfidence(circuits)
+		call.Mark("circuit_search")
 		
-		llm.thoughtStream <- ThoughtTrace{
+		think(ThoughtTrace{
 			stage:    "CIRCUITS_FOUND",
 			circuits: circuits,
 			insight:  fmt.Sprintf("Found %d active meaning circuits", len(circuits)),
-		}
+		})
 		
 		// Stage 3: Meaning crystallization
 		dominantMeaning := llm.crystallizeMeaning(circuits)
+		call.Mark("crystallization")
 		
-		llm.thoughtStream <- ThoughtTrace{
+		think(ThoughtTrace{
 			stage:   "UNDERSTANDING",
 			insight: fmt.Sprintf("Primary understanding: %s", dominantMeaning),
+		})
+		
+		// Nearly tied meanings get a question instead of a guess
+		llm.mu.RLock()
+		clarification := llm.clarification
+		llm.mu.RUnlock()
+		if clarification.Enabled {
+			if competing := competingMeanings(circuits, clarification); competing != nil {
+				response = llm.schema.clarificationQuestion(competing)
+				explanation = &Explanation{
+					Input:         input,
+					Response:      response,
+					Clarification: competing,
+					Confidence:    confidence,
+					Energy: llm.energy.Snapshot().Sub(before).
+						Add(EnergyReport{CircuitsTraced: int64(len(circuits))}),
+				}
+				think(ThoughtTrace{
+					stage:       "CLARIFICATION",
+					insight:     fmt.Sprintf("Ambiguous between %s", strings.Join(competing, ", ")),
+					explanation: explanation,
+				})
+				return
+			}
 		}
 		
 		// Stage 4: Response generation with visible reasoning
-		response = llm.generateResponse(dominantMeaning, circuits)
-		
-		llm.thoughtStream <- ThoughtTrace{
-			stage:   "RESPONSE_GENERATION",
-			insight: fmt.Sprintf("Generated response: %s", response),
+		generation, cancelGeneration := stageContext(ctx, limits.GenerationMS)
+		response, explanation, err = llm.generateResponseContext(generation, input, dominantMeaning, circuits, options)
+		cancelGeneration()
+		options.budget.generated(response)
+		if explanation.Error != "" {
+			think(ThoughtTrace{
+				stage:   "GENERATION_FAILED",
+				insight: fmt.Sprintf("Generation failed, answering from the fallback: %v", err),
+			})
+		} else if err != nil {
+			timedOut(stageGeneration, err)
+		}
+		call.Mark("generation")
+		if llm.dataLoader != nil && llm.generator != nil {
+			confidence.BeamScore = explanation.Confidence.BeamScore
+			confidence.combine(confidence.ActivationMargin, confidence.CircuitStrength, explanation.Confidence.Score)
 		}
+		explanation.Confidence = confidence
+		explanation.Energy = explanation.Energy.
+			Add(llm.energy.Snapshot().Sub(before)).
+			Add(EnergyReport{CircuitsTraced: int64(len(circuits))})
+		
+		think(ThoughtTrace{
+			stage:       "RESPONSE_GENERATION",
+			insight:     fmt.Sprintf("Generated response: %s", response),
+			explanation: explanation,
+		})
 	}()
 	
 	// Stream thoughts to visualization
@@ -369,30 +578,67 @@ func (llm *TransparentLLM) Understand(input string) (string, <-chan ThoughtTrace
 		defer processingDone.Done()
 		defer close(visualization)
 		
-		for {
-			select {
-			case thought, ok := <-llm.thoughtStream:
-				if !ok {
-					return
-				}
-				visualization <- thought
-				llm.visualizeThought(thought)
-			case <-time.After(2 * time.Second):
-				// Timeout in case processing gets stuck
-				return
+		// The processing goroutine bounds every stage and closes the
+		// stream after its COMPLETE thought
+		for thought := range thoughtStream {
+			offer(visualization, thought, overflow.config.Thoughts, &overflow.visualization, nil)
+			llm.visualizeThought(thought)
+			if options.thoughts != nil {
+				options.thoughts(thought)
 			}
 		}
 	}()
 	
 	// Wait for processing to complete
 	processingDone.Wait()
+	if response != "" && len(timeouts) == 0 && (explanation == nil || explanation.Error == "" && len(explanation.Facts) == 0) && len(options.budget.Hit()) == 0 {
+		llm.responses.Put(key, CachedResponse{Output: response, Explanation: explanation})
+	}
 	
-	return response, visualization
+	return response, explanation, visualization
+}
+
+// learnFacts stores the facts input states, as global facts, and reports
+// whether the store knows the answer to it
+func (llm *TransparentLLM) learnFacts(input string) bool {
+	if llm.knowledge == nil {
+		return false
+	}
+	if _, err := llm.knowledge.Learn("", input); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	facts, err := llm.knowledge.Recall("", input)
+	return err == nil && len(facts) > 0
+}
+
+// understandingConfidence rates the understanding from the concept
+// activation margin and the strongest circuit, before generation adds its
+// own signal
+func (llm *TransparentLLM) understandingConfidence(circuits []CircuitPath) Confidence {
+	var levels []float64
+	for _, a := range llm.ConceptActivations(2) {
+		levels = append(levels, a.Activation)
+	}
+	confidence := Confidence{ActivationMargin: activationMargin(levels)}
+	for _, circuit := range circuits {
+		confidence.CircuitStrength = math.Max(confidence.CircuitStrength, clamp01(circuit.strength))
+	}
+	confidence.combine(confidence.ActivationMargin, confidence.CircuitStrength)
+	return confidence
 }
 
+// Limits for waiting on pulse propagation during Understand
+const (
+	pulseSettleGrace   = 2 * time.Millisecond
+	pulseSettleTimeout = 500 * time.Millisecond
+)
+
+// Pulses weaker than this are absorbed instead of forwarded
+const minPulseIntensity = 0.05
+
 func (llm *TransparentLLM) activateWord(word string) {
 	// Direct activation
-	if neuron, exists := llm.concepts[word]; exists {
+	if neuron, exists := llm.concepts.Get(word); exists {
 		neuron.activate(1.0)
 		
 		// Send pulse for visualization
@@ -402,32 +648,38 @@ func (llm *TransparentLLM) activateWord(word string) {
 			path:      []string{word},
 		}
 		
-		select {
-		case neuron.visual <- pulse:
-		case <-time.After(1 * time.Millisecond):
-			// Channel blocked, skip to prevent deadlock
-		default:
-			// Channel full, skip this pulse
-		}
+		llm.activity.Add(1)
+		neuron.offerPulse(pulse)
 	}
 	
 	// Semantic activation - find related concepts
-	for concept, neuron := range llm.concepts {
-		similarity := llm.semanticSimilarity(word, concept)
+	llm.concepts.Range(func(concept string, neuron *ConceptNeuron) bool {
+		similarity := math.Max(llm.semanticSimilarity(word, concept), llm.matcher.Similarity(word, concept))
 		if similarity > 0.5 {
 			neuron.activate(similarity)
 		}
-	}
+		return true
+	})
 }
 
 func (llm *TransparentLLM) findActiveCircuits() []CircuitPath {
+	circuits, _ := llm.findActiveCircuitsContext(context.Background())
+	return circuits
+}
+
+// findActiveCircuitsContext is findActiveCircuits returning the circuits
+// traced so far once ctx is done
+func (llm *TransparentLLM) findActiveCircuitsContext(ctx context.Context) ([]CircuitPath, error) {
 	circuits := []CircuitPath{}
 	
 	// Use parallel search for circuit detection
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	
-	for _, startNeuron := range llm.concepts {
+	llm.concepts.Range(func(_ string, startNeuron *ConceptNeuron) bool {
+		if ctx.Err() != nil {
+			return false
+		}
 		if startNeuron.getActivation() > 0.5 {
 			wg.Add(1)
 			go func(start *ConceptNeuron) {
@@ -441,17 +693,32 @@ func (llm *TransparentLLM) findActiveCircuits() []CircuitPath {
 				mu.Unlock()
 			}(startNeuron)
 		}
+		return true
+	})
+	
+	traced := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(traced)
+	}()
+	select {
+	case <-traced:
+	case <-ctx.Done():
 	}
 	
-	wg.Wait()
-	return circuits
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]CircuitPath{}, circuits...), ctx.Err()
 }
 
+// Circuit tracing stops past this path length, or the degraded depth
+const maxCircuitDepth = 5
+
 func (llm *TransparentLLM) tracePaths(current *ConceptNeuron, path []string, minStrength float64) []CircuitPath {
 	circuits := []CircuitPath{}
 	
 	// Stop if path is too long or we're in a loop
-	if len(path) > 5 || contains(path[:len(path)-1], current.id) {
+	if len(path) > SharedDegrader().CircuitDepth(maxCircuitDepth) || contains(path[:len(path)-1], current.id) {
 		return circuits
 	}
 	
@@ -503,20 +770,68 @@ func (llm *TransparentLLM) crystallizeMeaning(circuits []CircuitPath) string {
 	return strongestPattern
 }
 
-func (llm *TransparentLLM) generateResponse(meaning string, circuits []CircuitPath) string {
+func (llm *TransparentLLM) generateResponse(input, meaning string, circuits []CircuitPath, options GenerationOptions) (string, *Explanation) {
 	// Use activated concepts to generate a response
 	if llm.dataLoader == nil || llm.generator == nil {
-		// Fallback to simple responses
-		return llm.generateSimpleResponse(meaning, circuits)
+		// Fallback to template or simple responses
+		response, ok := llm.templates.Respond(input, meaning)
+		if !ok {
+			response = llm.generateSimpleResponse(meaning, circuits)
+		}
+		return response, &Explanation{Input: input, Response: response}
 	}
 	
 	// Get most activated concepts
 	activeConcepts := llm.getTopActivatedConcepts(10)
 	
-	// Use the enhanced response generator
-	response := llm.generator.Generate(meaning, activeConcepts)
+	// Use the enhanced response generator on the crystallized meaning,
+	// grounded in chunks retrieved for the raw input
+	options.query = input
+	response, explanation := llm.generator.GenerateWithOptions(meaning, activeConcepts, options)
 	
-	return response
+	return response, explanation
+}
+
+// generateResponseContext is generateResponse answering with a simple
+// response if generation outlasts ctx
+func (llm *TransparentLLM) generateResponseContext(ctx context.Context, input, meaning string, circuits []CircuitPath, options GenerationOptions) (string, *Explanation, error) {
+	if err := ctx.Err(); err == nil {
+		type generated struct {
+			response    string
+			explanation *Explanation
+			err         error
+		}
+		result := make(chan generated, 1)
+		go func() {
+			var g generated
+			defer func() { result <- g }()
+			defer recoverError(&g.err, "generation")
+			g.response, g.explanation = llm.generateResponse(input, meaning, circuits, options)
+		}()
+		select {
+		case g := <-result:
+			if g.err == nil {
+				return g.response, g.explanation, nil
+			}
+			// Fall back as if out of time, keeping the failure
+			response, explanation := llm.fallbackResponse(input, meaning, circuits)
+			explanation.Error = g.err.Error()
+			return response, explanation, g.err
+		case <-ctx.Done():
+		}
+	}
+	response, explanation := llm.fallbackResponse(input, meaning, circuits)
+	return response, explanation, ctx.Err()
+}
+
+// fallbackResponse answers from templates or the meaning alone, when
+// generation can't
+func (llm *TransparentLLM) fallbackResponse(input, meaning string, circuits []CircuitPath) (string, *Explanation) {
+	response, ok := llm.templates.Respond(input, meaning)
+	if !ok {
+		response = llm.generateSimpleResponse(meaning, circuits)
+	}
+	return response, &Explanation{Input: input, Response: response}
 }
 
 func (llm *TransparentLLM) selectNextWord(currentWord string, activeConcepts []string, recent map[string]int) string {
@@ -525,7 +840,7 @@ func (llm *TransparentLLM) selectNextWord(currentWord string, activeConcepts []s
 	if !exists || len(transitions) == 0 {
 		// Fallback: use an activated concept
 		if len(activeConcepts) > 1 {
-			return activeConcepts[rand.Intn(len(activeConcepts))]
+			return activeConcepts[llm.rng.Intn(len(activeConcepts))]
 		}
 		return ""
 	}
@@ -583,11 +898,12 @@ func (llm *TransparentLLM) getTopActivatedConcepts(n int) []string {
 	
 	activations := []conceptActivation{}
 	
-	for concept, neuron := range llm.concepts {
+	llm.concepts.Range(func(concept string, neuron *ConceptNeuron) bool {
 		if act := neuron.getActivation(); act > 0.1 {
 			activations = append(activations, conceptActivation{concept, act})
 		}
-	}
+		return true
+	})
 	
 	// Sort by activation
 	for i := 0; i < len(activations); i++ {
@@ -609,18 +925,14 @@ func (llm *TransparentLLM) getTopActivatedConcepts(n int) []string {
 
 func (llm *TransparentLLM) generateSimpleResponse(meaning string, circuits []CircuitPath) string {
 	// Fallback for when no dataset is loaded
-	switch {
-	case strings.Contains(meaning, "frustration") && strings.Contains(meaning, "code"):
-		return "I understand code frustration. Let me help debug the issue."
-	case strings.Contains(meaning, "help") && strings.Contains(meaning, "solution"):
-		return "I'll help find a solution. What specific challenge are you facing?"
-	default:
-		return "I'm processing your input. Tell me more."
-	}
+	return llm.schema.FallbackResponseFor(meaning)
 }
 
 
 func (llm *TransparentLLM) visualizeThought(thought ThoughtTrace) {
+	if !logEnabled(LogInfo) {
+		return
+	}
 	switch thought.stage {
 	case "PARSING":
 		fmt.Println("\n⚡ PARSING:", thought.insight)
@@ -636,14 +948,35 @@ func (llm *TransparentLLM) visualizeThought(thought ThoughtTrace) {
 		
 	case "UNDERSTANDING":
 		fmt.Println("\n💡 UNDERSTANDING:", thought.insight)
+	case "CLARIFICATION":
+		fmt.Println("\n❓ CLARIFICATION:", thought.insight)
+		fmt.Println("💬 QUESTION:", thought.explanation.Response)
 	case "RESPONSE_GENERATION":
 		fmt.Println("\n💬 RESPONSE:", thought.insight)
+		if thought.explanation != nil && len(thought.explanation.Retrieved) > 0 {
+			fmt.Println("📎 GROUNDING:", thought.explanation)
+		}
+		if thought.explanation != nil {
+			fmt.Println("🎯 CONFIDENCE:", thought.explanation.Confidence)
+		}
 	}
 }
 
+// Profiler returns the stage profiler, or nil when profiling is disabled
+func (llm *TransparentLLM) Profiler() *StageProfiler {
+	return llm.profiler
+}
+
 // Neuron methods
+// conceptPollInterval is how often a batch of concept neurons checks for
+// pulses; a neuron running alone waits on its channel instead
+const conceptPollInterval = 2 * time.Millisecond
+
+// conceptDecay is the share of its activation a concept neuron keeps per
+// decay tick
+const conceptDecay = 0.95
+
 func (n *ConceptNeuron) live() {
-	decay := 0.95
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 	
@@ -652,34 +985,97 @@ func (n *ConceptNeuron) live() {
 		case <-n.ctx.Done():
 			return
 		case pulse := <-n.visual:
-			// Propagate activation
-			n.activate(pulse.intensity)
-			
-			// Spread to connections
-			for _, conn := range n.connections {
-				if rand.Float64() < conn.strength {
-					newPulse := Pulse{
-						intensity: pulse.intensity * conn.strength,
-						source:    pulse.source,
-						path:      append(pulse.path, conn.to.id),
-					}
-					
-					select {
-					case conn.to.visual <- newPulse:
-					default:
-					}
-				}
+			n.receive(pulse)
+		case <-ticker.C:
+			n.decay()
+		}
+	}
+}
+
+// liveConcepts runs a batch of concept neurons on one goroutine, polling
+// their channels for pulses
+func liveConcepts(neurons []*ConceptNeuron) {
+	if len(neurons) == 1 {
+		neurons[0].live()
+		return
+	}
+	ctx := neurons[0].ctx
+	poll := time.NewTicker(conceptPollInterval)
+	defer poll.Stop()
+	decay := time.NewTicker(100 * time.Millisecond)
+	defer decay.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			for _, n := range neurons {
+				n.drain()
+			}
+		case <-decay.C:
+			for _, n := range neurons {
+				n.decay()
+			}
+		}
+	}
+}
+
+// drain receives every pulse waiting on the neuron's channel
+func (n *ConceptNeuron) drain() {
+	for {
+		select {
+		case pulse := <-n.visual:
+			n.receive(pulse)
+		default:
+			return
+		}
+	}
+}
+
+// receive takes in a pulse and spreads it to the neuron's connections
+func (n *ConceptNeuron) receive(pulse Pulse) {
+	// Propagate activation
+	n.activate(pulse.intensity)
+	
+	// Spread to connections
+	for _, conn := range n.connections {
+		if rand.Float64() < conn.strength {
+			newPulse := Pulse{
+				intensity: pulse.intensity * conn.strength,
+				source:    pulse.source,
+				path:      append(pulse.path[:len(pulse.path):len(pulse.path)], conn.to.id), // copy; sibling pulses share pulse.path
+			}
+			if newPulse.intensity < minPulseIntensity {
+				continue
 			}
 			
-		case <-ticker.C:
-			// Decay activation
-			current := n.getActivation()
-			n.activation.Store(current * decay)
+			n.activity.Add(1)
+			if conn.to.offerPulse(newPulse) {
+				n.energy.spikesPropagated(1)
+			}
 		}
 	}
+	n.activity.Done()
+}
+
+// offerPulse queues a pulse for the neuron under the pulse policy; a
+// dropped pulse is no longer in flight
+func (n *ConceptNeuron) offerPulse(pulse Pulse) bool {
+	return offer(n.visual, pulse, n.overflow.config.Pulses, &n.overflow.pulses, func(Pulse) {
+		n.activity.Done()
+	})
+}
+
+// decay lets the neuron's activation fade
+func (n *ConceptNeuron) decay() {
+	current := n.getActivation()
+	n.activation.Store(current * conceptDecay)
+	n.energy.neuronUpdate()
 }
 
 func (n *ConceptNeuron) activate(amount float64) {
+	n.energy.neuronUpdate()
 	current := n.getActivation()
 	n.activation.Store(math.Min(1.0, current+amount))
 }
@@ -691,57 +1087,39 @@ func (n *ConceptNeuron) getActivation() float64 {
 	return 0.0
 }
 
+// ConceptActivation returns a concept's current activation, or 0 for an
+// unknown concept
+func (llm *TransparentLLM) ConceptActivation(concept string) float64 {
+	if neuron, ok := llm.concepts.Get(concept); ok {
+		return neuron.getActivation()
+	}
+	return 0.0
+}
+
 // Helper functions
-func generateSemanticVector(word string) []float64 {
+func generateSemanticVector(word string, rng *rand.Rand) []float64 {
 	// Simplified semantic embedding
 	vec := make([]float64, 64)
 	for i := range vec {
-		vec[i] = rand.Float64()
+		vec[i] = rng.Float64()
 	}
 	return vec
 }
 
-var (
-	similaritiesOnce sync.Once
-	similarities    map[string]map[string]float64
-	similaritiesMu  sync.RWMutex
-)
-
-func initSimilarities() {
-	similaritiesOnce.Do(func() {
-		similarities = map[string]map[string]float64{
-			"frustration": {"stuck": 0.8, "error": 0.7, "problem": 0.8},
-			"code":        {"programming": 0.9, "debug": 0.7, "error": 0.6},
-			"help":        {"assist": 0.9, "support": 0.8, "solve": 0.7},
-		}
-	})
-}
-
 func (llm *TransparentLLM) semanticSimilarity(word1, word2 string) float64 {
 	// Use dataset embeddings if available
 	if llm.dataLoader != nil {
 		return llm.dataLoader.ComputeSimilarity(word1, word2)
 	}
 	
-	// Fallback to hardcoded similarities
-	initSimilarities()
-	
 	// Simplified similarity calculation
 	if strings.Contains(word1, word2) || strings.Contains(word2, word1) {
 		return 0.8
 	}
 	
-	similaritiesMu.RLock()
-	defer similaritiesMu.RUnlock()
-	
-	if sim, ok := similarities[word1][word2]; ok {
-		return sim
-	}
-	if sim, ok := similarities[word2][word1]; ok {
-		return sim
-	}
-	
-	return 0.0
+	// Fallback to the schema's similarity table
+	sim, _ := llm.schema.Similarity(word1, word2)
+	return sim
 }
 
 func semanticSimilarity(word1, word2 string) float64 {
@@ -753,7 +1131,7 @@ func semanticSimilarity(word1, word2 string) float64 {
 func (llm *TransparentLLM) getNodes(path []string) []*ConceptNeuron {
 	nodes := []*ConceptNeuron{}
 	for _, id := range path {
-		if neuron, ok := llm.concepts[id]; ok {
+		if neuron, ok := llm.concepts.Get(id); ok {
 			nodes = append(nodes, neuron)
 		}
 	}
@@ -767,7 +1145,11 @@ func (llm *TransparentLLM) calculatePathStrength(path []string) float64 {
 	
 	strength := 1.0
 	for i := 0; i < len(path)-1; i++ {
-		from := llm.concepts[path[i]]
+		from, exists := llm.concepts.Get(path[i])
+		if !exists {
+			strength *= 0.1
+			continue
+		}
 		if conn, ok := from.connections[path[i+1]]; ok {
 			strength *= conn.strength
 		} else {
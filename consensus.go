@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Consensus strategies for the parallel orchestrator. "highest" takes the
+// single most confident decision. "weighted" has every deciding neuron vote
+// for its capability with its confidence and takes the most confident
+// decision of the capability with the most weight. "quorum" is weighted
+// voting that only reaches consensus when the winner holds at least the
+// quorum share of all the weight. When capabilities tie, the arbiter
+// capability wins if it is among them; otherwise the tie goes to the most
+// confident decision among the tied capabilities.
+
+// Consensus strategies
+const (
+	consensusHighest  = "highest"
+	consensusWeighted = "weighted"
+	consensusQuorum   = "quorum"
+)
+
+// Vote tallies closer than this are a tie
+const consensusTieEpsilon = 1e-9
+
+// ConsensusConfig selects how the parallel orchestrator combines decisions
+type ConsensusConfig struct {
+	Strategy string  `json:"strategy"` // "highest", "weighted" or "quorum"
+	Quorum   float64 `json:"quorum"`   // share of the vote weight the winner needs under "quorum"
+	Arbiter  string  `json:"arbiter"`  // capability that wins ties; empty breaks them by confidence
+}
+
+func (c ConsensusConfig) validate() error {
+	switch c.Strategy {
+	case "", consensusHighest, consensusWeighted:
+	case consensusQuorum:
+		if c.Quorum <= 0 || c.Quorum > 1 {
+			return fmt.Errorf("consensus quorum must be above 0 and at most 1")
+		}
+	default:
+		return fmt.Errorf("consensus strategy must be highest, weighted or quorum, got %q", c.Strategy)
+	}
+	return nil
+}
+
+// ConsensusResult is the orchestrator's combined decision and how it was
+// reached
+type ConsensusResult struct {
+	Strategy    string             `json:"strategy"`
+	Reached     bool               `json:"reached"`
+	Decision    string             `json:"decision,omitempty"`
+	Capability  string             `json:"capability,omitempty"` // capability of the neuron whose decision won
+	Confidence  float64            `json:"confidence"`
+	Decisions   int                `json:"decisions"`        // decisions that voted
+	Tallies     map[string]float64 `json:"tallies"`          // confidence-weighted votes per capability
+	Share       float64            `json:"share"`            // the winning capability's share of the votes
+	Quorum      float64            `json:"quorum,omitempty"` // share required under "quorum"
+	Arbiter     string             `json:"arbiter,omitempty"`
+	TieBrokenBy string             `json:"tie_broken_by,omitempty"` // "arbiter" or "confidence" when capabilities tied
+	Summary     string             `json:"summary"`                 // the result as one line
+}
+
+// SetConsensus selects the consensus strategy
+func (po *ParallelOrchestrator) SetConsensus(config ConsensusConfig) {
+	po.consensus = config
+}
+
+// consensusOf combines decisions under config, with capability giving each
+// deciding neuron's capability
+func consensusOf(decisions []FlowDecision, capability func(neuronID int) string, config ConsensusConfig) ConsensusResult {
+	result := ConsensusResult{
+		Strategy:  config.Strategy,
+		Decisions: len(decisions),
+		Tallies:   make(map[string]float64),
+		Arbiter:   config.Arbiter,
+	}
+	if result.Strategy == "" {
+		result.Strategy = consensusHighest
+	}
+	if result.Strategy == consensusQuorum {
+		result.Quorum = config.Quorum
+	}
+	if len(decisions) == 0 {
+		result.Summary = "No consensus reached - insufficient activation"
+		return result
+	}
+
+	// Every strategy reports the tallies
+	total := 0.0
+	best := make(map[string]FlowDecision) // most confident decision per capability
+	for _, d := range decisions {
+		c := capability(d.NeuronID)
+		result.Tallies[c] += d.Confidence
+		total += d.Confidence
+		if b, ok := best[c]; !ok || d.Confidence > b.Confidence {
+			best[c] = d
+		}
+	}
+
+	var winner string
+	if result.Strategy == consensusHighest {
+		// Simple voting mechanism: the most confident decision
+		for _, d := range decisions {
+			if winner == "" || d.Confidence > best[winner].Confidence {
+				winner = capability(d.NeuronID)
+			}
+		}
+	} else {
+		winner, result.TieBrokenBy = weightedWinner(result.Tallies, best, config.Arbiter)
+	}
+	if total > 0 {
+		result.Share = result.Tallies[winner] / total
+	}
+	if result.Strategy == consensusQuorum && result.Share < config.Quorum {
+		result.Summary = fmt.Sprintf("No consensus reached - %s holds %.0f%% of the vote, quorum is %.0f%%",
+			winner, result.Share*100, config.Quorum*100)
+		return result
+	}
+
+	d := best[winner]
+	result.Reached = true
+	result.Decision = d.Decision
+	result.Capability = winner
+	result.Confidence = d.Confidence
+	if result.Strategy == consensusHighest {
+		result.Summary = fmt.Sprintf("CONSENSUS: %s (confidence: %.2f from %d parallel decisions)",
+			d.Decision, d.Confidence, len(decisions))
+	} else {
+		result.Summary = fmt.Sprintf("CONSENSUS (%s): %s (%s holds %.0f%% of the vote from %d parallel decisions)",
+			result.Strategy, d.Decision, winner, result.Share*100, len(decisions))
+	}
+	return result
+}
+
+// weightedWinner returns the capability with the most vote weight and, when
+// several tie, how the tie was broken
+func weightedWinner(tallies map[string]float64, best map[string]FlowDecision, arbiter string) (string, string) {
+	capabilities := make([]string, 0, len(tallies))
+	for c := range tallies {
+		capabilities = append(capabilities, c)
+	}
+	sort.Strings(capabilities)
+
+	top := 0.0
+	for _, c := range capabilities {
+		if tallies[c] > top {
+			top = tallies[c]
+		}
+	}
+	var tied []string
+	for _, c := range capabilities {
+		if top-tallies[c] < consensusTieEpsilon {
+			tied = append(tied, c)
+		}
+	}
+	if len(tied) == 1 {
+		return tied[0], ""
+	}
+	for _, c := range tied {
+		if c == arbiter {
+			return c, "arbiter"
+		}
+	}
+	winner := tied[0]
+	for _, c := range tied[1:] {
+		if best[c].Confidence > best[winner].Confidence {
+			winner = c
+		}
+	}
+	return winner, "confidence"
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Conversation benchmark: scripted multi-turn dialogues run through each
+// model type, with every response scored against the turn's reference
+// answers. Reference overlap is the unigram F1 between the response's words
+// and a reference's, and embedding similarity is the cosine between their
+// sentence embeddings (the corpus' word vectors, or hashed word embeddings
+// without a corpus); a turn scores its best reference. Each model answers
+// every dialogue in script order on one instance, so later dialogues see
+// what earlier ones left behind, and every turn has its own seed, so a run
+// is comparable with one from another commit. Scripts are YAML (the
+// template library subset) or JSON, chosen by extension:
+//
+//	name: everyday
+//	dialogues:
+//	  - name: greeting
+//	    turns:
+//	      - user: hello
+//	        references: [hello how can i help you]
+
+// DialogueScript is a set of scripted dialogues
+type DialogueScript struct {
+	Name      string     `json:"name"`
+	Dialogues []Dialogue `json:"dialogues"`
+}
+
+// Dialogue is one scripted conversation
+type Dialogue struct {
+	Name  string         `json:"name"`
+	Turns []DialogueTurn `json:"turns"`
+}
+
+// DialogueTurn is a user message and acceptable answers to it
+type DialogueTurn struct {
+	User       string   `json:"user"`
+	References []string `json:"references"`
+}
+
+// LoadDialogueScript reads a script from a YAML or JSON file
+func LoadDialogueScript(path string) (*DialogueScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dialogue script: %w", err)
+	}
+	var script DialogueScript
+	if err := unmarshalYAMLOrJSON(path, data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse dialogue script %s: %w", path, err)
+	}
+	if err := script.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dialogue script %s: %w", path, err)
+	}
+	return &script, nil
+}
+
+// Validate checks every dialogue has turns with references
+func (s *DialogueScript) Validate() error {
+	if len(s.Dialogues) == 0 {
+		return fmt.Errorf("script has no dialogues")
+	}
+	for i, d := range s.Dialogues {
+		if len(d.Turns) == 0 {
+			return fmt.Errorf("dialogue %d has no turns", i)
+		}
+		for j, turn := range d.Turns {
+			if strings.TrimSpace(turn.User) == "" {
+				return fmt.Errorf("dialogue %d turn %d has no user message", i, j)
+			}
+			if len(turn.References) == 0 {
+				return fmt.Errorf("dialogue %d turn %d has no references", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// ConversationBenchConfig sets a benchmark run
+type ConversationBenchConfig struct {
+	Models []string // model types; empty runs every registered type
+	Size   int      // reservoir size for liquid brains and orchestrators
+	Seed   int64    // first turn's seed; each later turn adds one
+}
+
+// TurnResult scores one response
+type TurnResult struct {
+	User       string  `json:"user"`
+	Response   string  `json:"response"`
+	Overlap    float64 `json:"overlap"`    // best unigram F1 against the references
+	Similarity float64 `json:"similarity"` // best embedding cosine against the references
+	LatencyMS  float64 `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// DialogueResult scores one dialogue
+type DialogueResult struct {
+	Name  string       `json:"name"`
+	Turns []TurnResult `json:"turns"`
+}
+
+// ModelBenchResult scores one model type over the script
+type ModelBenchResult struct {
+	Model      string           `json:"model"`
+	Overlap    float64          `json:"overlap"`    // mean over turns
+	Similarity float64          `json:"similarity"` // mean over turns
+	LatencyMS  float64          `json:"latency_ms"` // mean over turns
+	Errors     int              `json:"errors"`
+	Error      string           `json:"error,omitempty"` // set when the model couldn't be built
+	Dialogues  []DialogueResult `json:"dialogues"`
+}
+
+// ConversationBenchReport holds a benchmark run
+type ConversationBenchReport struct {
+	Script    string             `json:"script"`
+	Embedding string             `json:"embedding"` // "corpus" or "hashed"
+	Seed      int64              `json:"seed"`
+	Models    []ModelBenchResult `json:"models"`
+}
+
+// RunConversationBench runs the script through each model type. Models are
+// built from config without a decision store or distillation log, so a
+// benchmark persists nothing.
+func RunConversationBench(config *Config, script *DialogueScript, bench ConversationBenchConfig, embed Embedder, embedding string) (*ConversationBenchReport, error) {
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+	if embed == nil {
+		embed, embedding = hashedEmbedding, "hashed"
+	}
+	models := bench.Models
+	if len(models) == 0 {
+		models = ModelTypeNames()
+	}
+	report := &ConversationBenchReport{Script: script.Name, Embedding: embedding, Seed: bench.Seed}
+	for _, name := range models {
+		modelConfig := *config
+		modelConfig.Model = ModelConfig{Type: name}
+		modelConfig.DecisionStore.Path = ""
+		modelConfig.Distill.Enabled = false
+
+		fmt.Printf("🧪 Conversation benchmark: %s\n", name)
+		result := ModelBenchResult{Model: name}
+		model, err := NewModel(&modelConfig, bench.Size)
+		if err != nil {
+			result.Error = err.Error()
+			report.Models = append(report.Models, result)
+			continue
+		}
+		result.Dialogues = runDialogues(model, script, bench.Seed, embed)
+		model.Close()
+		result.summarize()
+		report.Models = append(report.Models, result)
+	}
+	return report, nil
+}
+
+// runDialogues answers every turn of the script on model
+func runDialogues(model Model, script *DialogueScript, seed int64, embed Embedder) []DialogueResult {
+	var results []DialogueResult
+	for _, dialogue := range script.Dialogues {
+		result := DialogueResult{Name: dialogue.Name}
+		for _, turn := range dialogue.Turns {
+			turnSeed := seed
+			seed++
+			start := time.Now()
+			response, err := model.Respond(context.Background(), Request{Input: turn.User, Seed: &turnSeed})
+			scored := TurnResult{User: turn.User, Response: response.Output, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				scored.Error = err.Error()
+			} else {
+				scored.Overlap, scored.Similarity = scoreTurn(response.Output, turn.References, embed)
+			}
+			result.Turns = append(result.Turns, scored)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// summarize averages the model's turns
+func (r *ModelBenchResult) summarize() {
+	turns := 0
+	for _, d := range r.Dialogues {
+		for _, turn := range d.Turns {
+			turns++
+			r.Overlap += turn.Overlap
+			r.Similarity += turn.Similarity
+			r.LatencyMS += turn.LatencyMS
+			if turn.Error != "" {
+				r.Errors++
+			}
+		}
+	}
+	if turns > 0 {
+		r.Overlap /= float64(turns)
+		r.Similarity /= float64(turns)
+		r.LatencyMS /= float64(turns)
+	}
+}
+
+// scoreTurn returns a response's best overlap and similarity against the
+// references
+func scoreTurn(response string, references []string, embed Embedder) (overlap, similarity float64) {
+	vec, embedded := embed(response)
+	for _, reference := range references {
+		if f1 := unigramF1(response, reference); f1 > overlap {
+			overlap = f1
+		}
+		if !embedded {
+			continue
+		}
+		if ref, ok := embed(reference); ok && len(ref) == len(vec) {
+			if cos := dotProduct(vec, ref); cos > similarity {
+				similarity = cos
+			}
+		}
+	}
+	return overlap, similarity
+}
+
+// benchWords splits text into lowercase words
+func benchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// unigramF1 is the harmonic mean of the precision and recall of the
+// response's words against the reference's, counting repeats
+func unigramF1(response, reference string) float64 {
+	got, want := benchWords(response), benchWords(reference)
+	if len(got) == 0 || len(want) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(want))
+	for _, w := range want {
+		counts[w]++
+	}
+	common := 0
+	for _, w := range got {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(got))
+	recall := float64(common) / float64(len(want))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// corpusEmbedder embeds text with the loader's sentence embeddings
+func corpusEmbedder(loader *DatasetLoader) Embedder {
+	return func(text string) ([]float64, bool) {
+		vec, _, ok := loader.SentenceEmbedding(text)
+		return vec, ok
+	}
+}
+
+// Print writes the report for humans, with changes from baseline when it
+// is set
+func (r *ConversationBenchReport) Print(w io.Writer, baseline *ConversationBenchReport) {
+	fmt.Fprintf(w, "📊 Conversation benchmark %q (%s embeddings, seed %d)\n", r.Script, r.Embedding, r.Seed)
+	previous := make(map[string]ModelBenchResult)
+	if baseline != nil {
+		for _, m := range baseline.Models {
+			previous[m.Model] = m
+		}
+	}
+	for _, m := range r.Models {
+		if m.Error != "" {
+			fmt.Fprintf(w, "   %-12s ❌ %s\n", m.Model, m.Error)
+			continue
+		}
+		fmt.Fprintf(w, "   %-12s overlap %.3f, similarity %.3f, %.1fms/turn, %d errors\n",
+			m.Model, m.Overlap, m.Similarity, m.LatencyMS, m.Errors)
+		if before, ok := previous[m.Model]; ok && before.Error == "" {
+			fmt.Fprintf(w, "   %-12s vs baseline: overlap %+.3f, similarity %+.3f\n",
+				"", m.Overlap-before.Overlap, m.Similarity-before.Similarity)
+		}
+	}
+}
+
+// record logs each model's scores and saves the report in run, finishing
+// the run
+func (r *ConversationBenchReport) record(run *TrackedRun) {
+	for _, m := range r.Models {
+		if m.Error != "" {
+			continue
+		}
+		run.LogMetric(m.Model+"_overlap", m.Overlap)
+		run.LogMetric(m.Model+"_similarity", m.Similarity)
+		run.LogMetric(m.Model+"_latency_ms", m.LatencyMS)
+		run.LogMetric(m.Model+"_errors", float64(m.Errors))
+	}
+	if err := run.SaveJSONArtifact("report.json", r); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	run.Finish(nil)
+}
+
+// ConversationBenchMain implements `go run . bench conversation`
+func ConversationBenchMain(args []string) {
+	fs := flag.NewFlagSet("bench conversation", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	models := fs.String("models", "", "Comma-separated model types; empty runs them all")
+	size := fs.Int("size", defaultModelSize, "Reservoir size for liquid brains and orchestrators")
+	seed := fs.Int64("seed", 1, "Seed of the first turn")
+	baselinePath := fs.String("baseline", "", "Earlier JSON report to compare with")
+	out := fs.String("out", "", "Also write the JSON report here, to compare later runs with")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: genesis bench conversation [flags] <dialogues.yaml>")
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	script, err := LoadDialogueScript(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	var baseline *ConversationBenchReport
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err == nil {
+			baseline = &ConversationBenchReport{}
+			err = json.Unmarshal(data, baseline)
+		}
+		if err != nil {
+			fmt.Printf("❌ ERROR: failed to read baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var embed Embedder
+	embedding := "hashed"
+	if loader, err := NewDatasetLoader(config.Training); err != nil {
+		fmt.Printf("⚠️  Warning: scoring with hashed embeddings: %v\n", err)
+	} else {
+		embed, embedding = corpusEmbedder(loader), "corpus"
+	}
+
+	bench := ConversationBenchConfig{Size: *size, Seed: *seed}
+	if *models != "" {
+		bench.Models = strings.Split(*models, ",")
+	}
+	run := TrackRun(config, "bench-conversation", map[string]string{
+		"script": fs.Arg(0),
+		"models": *models,
+		"size":   fmt.Sprint(*size),
+		"seed":   fmt.Sprint(*seed),
+	})
+	report, err := RunConversationBench(config, script, bench, embed, embedding)
+	if err != nil {
+		run.Finish(err)
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	report.record(run)
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*out, data, 0644)
+		}
+		if err != nil {
+			fmt.Printf("❌ ERROR: failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	report.Print(os.Stdout, baseline)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Corpus statistics: a quality report on what the datasets loaded into, to
+// judge whether a corpus can plausibly support generation. Beyond size and
+// lexical variety it reports how sentences are distributed in length, the
+// most frequent n-grams, and how much of the generator's starter and
+// connector word lists the corpus covers: a starter the corpus never
+// continues from makes a beam end on its first word. Sentences are split at
+// line breaks as well as sentence punctuation, since most conversational
+// corpora hold one utterance per line.
+
+// Thresholds below which the report warns that generation will struggle
+const (
+	corpusMinTokens       = 1000
+	corpusMaxTypeToken    = 0.6 // above this, few words repeat enough to learn transitions
+	corpusMinSentenceMean = 3.0
+	corpusMinCoverage     = 0.5
+)
+
+// Sentence length histogram bucket upper bounds, in tokens; the last
+// bucket is open
+var sentenceLengthBuckets = []int{5, 10, 20, 40}
+
+// CorpusStats is the quality report for a loaded corpus
+type CorpusStats struct {
+	Documents      int                `json:"documents"`
+	Tokens         int                `json:"tokens"`
+	Types          int                `json:"types"`      // distinct tokens
+	TypeTokenRatio float64            `json:"type_token"` // types / tokens
+	Vocabulary     int                `json:"vocabulary"` // words kept after frequency and size limits
+	Languages      map[string]int     `json:"languages"`  // documents per language
+	Sentences      LengthDistribution `json:"sentences"`
+	NGrams         []NGramList        `json:"ngrams"`
+	Coverage       []WordListCoverage `json:"coverage"`
+	Warnings       []string           `json:"warnings,omitempty"`
+}
+
+// LengthDistribution summarizes sentence lengths in tokens
+type LengthDistribution struct {
+	Count     int            `json:"count"`
+	Mean      float64        `json:"mean"`
+	Median    int            `json:"median"`
+	P90       int            `json:"p90"`
+	Max       int            `json:"max"`
+	Histogram []LengthBucket `json:"histogram"`
+}
+
+// LengthBucket counts sentences up to Max tokens long; Max 0 is unbounded
+type LengthBucket struct {
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// NGramList is the most frequent n-grams of one length
+type NGramList struct {
+	N   int          `json:"n"`
+	Top []NGramCount `json:"top"`
+}
+
+// NGramCount is one n-gram and how often it occurs
+type NGramCount struct {
+	Gram  string `json:"gram"`
+	Count int    `json:"count"`
+}
+
+// WordListCoverage is how much of a generator word list the corpus supports
+type WordListCoverage struct {
+	List            string   `json:"list"`
+	Words           int      `json:"words"`
+	InVocabulary    int      `json:"in_vocabulary"`
+	WithTransitions int      `json:"with_transitions"` // words the corpus continues from
+	Missing         []string `json:"missing,omitempty"`
+}
+
+// Share returns the fraction of the list the corpus continues from
+func (c WordListCoverage) Share() float64 {
+	if c.Words == 0 {
+		return 1
+	}
+	return float64(c.WithTransitions) / float64(c.Words)
+}
+
+// ComputeCorpusStats reports on the loader's corpus, with the top n-grams of
+// each length up to three and coverage of schema's starter words
+func ComputeCorpusStats(loader *DatasetLoader, schema *ConceptSchema, top int) *CorpusStats {
+	docs := loader.GetDocuments()
+	stats := &CorpusStats{
+		Documents:  len(docs),
+		Vocabulary: len(loader.GetVocabulary()),
+		Languages:  make(map[string]int),
+	}
+
+	types := make(map[string]bool)
+	grams := []map[string]int{{}, {}, {}}
+	var lengths []int
+	for _, doc := range docs {
+		stats.Languages[doc.Language]++
+		for _, line := range strings.Split(doc.Content, "\n") {
+			for _, sentence := range splitSentences(line) {
+				tokens := loader.tokenize(sentence)
+				if len(tokens) == 0 {
+					continue
+				}
+				lengths = append(lengths, len(tokens))
+				stats.Tokens += len(tokens)
+				for i, token := range tokens {
+					types[token] = true
+					for n := 1; n <= len(grams) && i+n <= len(tokens); n++ {
+						grams[n-1][strings.Join(tokens[i:i+n], " ")]++
+					}
+				}
+			}
+		}
+	}
+	stats.Types = len(types)
+	if stats.Tokens > 0 {
+		stats.TypeTokenRatio = float64(stats.Types) / float64(stats.Tokens)
+	}
+	stats.Sentences = lengthDistribution(lengths)
+	for n, counts := range grams {
+		stats.NGrams = append(stats.NGrams, NGramList{N: n + 1, Top: topNGrams(counts, top)})
+	}
+
+	schema = schema.orDefault()
+	kinds := make([]string, 0, len(schema.Starters))
+	for kind := range schema.Starters {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		stats.Coverage = append(stats.Coverage, wordListCoverage(loader, kind+" starters", schema.Starters[kind]))
+	}
+	stats.Coverage = append(stats.Coverage, wordListCoverage(loader, "connectors", initializeGrammarPatterns()["connectors"]))
+
+	stats.Warnings = stats.assess()
+	return stats
+}
+
+// lengthDistribution summarizes sentence lengths
+func lengthDistribution(lengths []int) LengthDistribution {
+	dist := LengthDistribution{Count: len(lengths)}
+	for _, bound := range sentenceLengthBuckets {
+		dist.Histogram = append(dist.Histogram, LengthBucket{Max: bound})
+	}
+	dist.Histogram = append(dist.Histogram, LengthBucket{})
+	if len(lengths) == 0 {
+		return dist
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+	total := 0
+	for _, length := range sorted {
+		total += length
+		bucket := len(sentenceLengthBuckets)
+		for i, bound := range sentenceLengthBuckets {
+			if length <= bound {
+				bucket = i
+				break
+			}
+		}
+		dist.Histogram[bucket].Count++
+	}
+	dist.Mean = float64(total) / float64(len(sorted))
+	dist.Median = sorted[len(sorted)/2]
+	dist.P90 = sorted[len(sorted)*9/10]
+	dist.Max = sorted[len(sorted)-1]
+	return dist
+}
+
+// topNGrams returns the n most frequent grams, ties in alphabetical order
+func topNGrams(counts map[string]int, n int) []NGramCount {
+	result := make([]NGramCount, 0, len(counts))
+	for gram, count := range counts {
+		result = append(result, NGramCount{Gram: gram, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Gram < result[j].Gram
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// wordListCoverage checks which of words the corpus knows and continues from
+func wordListCoverage(loader *DatasetLoader, list string, words []string) WordListCoverage {
+	coverage := WordListCoverage{List: list, Words: len(words)}
+	for _, word := range words {
+		if loader.InVocabulary(word) {
+			coverage.InVocabulary++
+		}
+		if transitions, ok := loader.GetTransitions(word); ok && len(transitions) > 0 {
+			coverage.WithTransitions++
+		} else {
+			coverage.Missing = append(coverage.Missing, word)
+		}
+	}
+	return coverage
+}
+
+// assess returns why the corpus may not support generation, if it may not
+func (s *CorpusStats) assess() []string {
+	var warnings []string
+	if s.Tokens < corpusMinTokens {
+		warnings = append(warnings, fmt.Sprintf("only %d tokens; generation needs at least %d to learn useful transitions", s.Tokens, corpusMinTokens))
+	}
+	if s.Vocabulary < doctorMinVocabulary {
+		warnings = append(warnings, fmt.Sprintf("vocabulary of %d words is under %d", s.Vocabulary, doctorMinVocabulary))
+	}
+	if s.Tokens >= corpusMinTokens && s.TypeTokenRatio > corpusMaxTypeToken {
+		warnings = append(warnings, fmt.Sprintf("type/token ratio %.2f is high: few words repeat enough to learn what follows them", s.TypeTokenRatio))
+	}
+	if s.Sentences.Count > 0 && s.Sentences.Mean < corpusMinSentenceMean {
+		warnings = append(warnings, fmt.Sprintf("sentences average %.1f tokens; responses will be fragments", s.Sentences.Mean))
+	}
+	for _, coverage := range s.Coverage {
+		if coverage.Share() < corpusMinCoverage {
+			warnings = append(warnings, fmt.Sprintf("the corpus continues from only %d of %d %s", coverage.WithTransitions, coverage.Words, coverage.List))
+		}
+	}
+	return warnings
+}
+
+// Print writes the report for humans
+func (s *CorpusStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "📊 Corpus statistics: %d documents\n", s.Documents)
+	fmt.Fprintf(w, "   Tokens: %d, types: %d, type/token: %.3f, vocabulary: %d\n", s.Tokens, s.Types, s.TypeTokenRatio, s.Vocabulary)
+	languages := make([]string, 0, len(s.Languages))
+	for language := range s.Languages {
+		languages = append(languages, fmt.Sprintf("%s=%d", language, s.Languages[language]))
+	}
+	sort.Strings(languages)
+	fmt.Fprintf(w, "   Languages: %s\n", strings.Join(languages, ", "))
+
+	d := s.Sentences
+	fmt.Fprintf(w, "   Sentences: %d, length mean %.1f, median %d, p90 %d, max %d\n", d.Count, d.Mean, d.Median, d.P90, d.Max)
+	low := 1
+	for _, bucket := range d.Histogram {
+		label := fmt.Sprintf("%d-%d", low, bucket.Max)
+		if bucket.Max == 0 {
+			label = fmt.Sprintf("%d+", low)
+		}
+		fmt.Fprintf(w, "      %6s: %d\n", label, bucket.Count)
+		low = bucket.Max + 1
+	}
+
+	for _, list := range s.NGrams {
+		grams := make([]string, len(list.Top))
+		for i, g := range list.Top {
+			grams[i] = fmt.Sprintf("%q×%d", g.Gram, g.Count)
+		}
+		fmt.Fprintf(w, "   Top %d-grams: %s\n", list.N, strings.Join(grams, ", "))
+	}
+
+	for _, c := range s.Coverage {
+		fmt.Fprintf(w, "   Coverage of %s: %d/%d in vocabulary, %d/%d continued", c.List, c.InVocabulary, c.Words, c.WithTransitions, c.Words)
+		if len(c.Missing) > 0 {
+			fmt.Fprintf(w, " (missing %s)", strings.Join(c.Missing, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(s.Warnings) == 0 {
+		fmt.Fprintln(w, "✅ The corpus looks able to support generation")
+		return
+	}
+	for _, warning := range s.Warnings {
+		fmt.Fprintf(w, "⚠️  %s\n", warning)
+	}
+}
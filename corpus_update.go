@@ -0,0 +1,147 @@
+package main
+
+import "fmt"
+
+// Incremental corpus updates: live systems ingest new material without
+// reconstructing the loader. Under the write lock, a new document updates
+// word frequencies, admits words that now reach MinWordFreq while the
+// vocabulary has room, and adds its transitions, starters, co-occurrences
+// and passages to the raw counts kept from the full build. Only the
+// transition rows and embeddings whose counts changed are recomputed, and
+// tables readers may hold are replaced rather than mutated.
+//
+// Admitted words are appended after the existing vocabulary instead of
+// ranked by frequency, so indices, and the embeddings derived from them,
+// stay stable. A newly admitted word learns transitions and co-occurrences
+// from new material only; its earlier occurrences, from while it was below
+// the threshold, count at the next full build. Document frequency and
+// TF-IDF pruning need whole-corpus statistics and likewise apply only to
+// full builds, but words a build pruned stay out and the drop-list holds.
+
+// CorpusUpdate reports what adding one document changed
+type CorpusUpdate struct {
+	Source      string   `json:"source"`
+	Language    string   `json:"language"`
+	Tokens      int      `json:"tokens"`
+	NewWords    []string `json:"new_words,omitempty"` // admitted to the vocabulary
+	Skipped     int      `json:"skipped"`             // words that qualified with the vocabulary full
+	Transitions int      `json:"transitions"`         // transition rows re-normalized
+	Embeddings  int      `json:"embeddings"`          // embeddings recomputed
+}
+
+// AddDocument adds text to the corpus, attributed to source, and updates
+// the vocabulary, transitions and embeddings in place
+func (dl *DatasetLoader) AddDocument(source, text string) (CorpusUpdate, error) {
+	dl.ensureTables()
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	if err := dl.addDocument(source, text); err != nil {
+		return CorpusUpdate{}, err
+	}
+	return dl.integrateDocument(), nil
+}
+
+// AddDocumentFile adds the dataset file at path to the corpus, subject to
+// the same size limit and .jsonl handling as loading
+func (dl *DatasetLoader) AddDocumentFile(path string) (CorpusUpdate, error) {
+	dl.ensureTables()
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	if err := dl.loadFile(path); err != nil {
+		return CorpusUpdate{}, err
+	}
+	return dl.integrateDocument(), nil
+}
+
+// integrateDocument folds the most recently added document into the built
+// tables. Callers must hold dl.mu.
+func (dl *DatasetLoader) integrateDocument() CorpusUpdate {
+	doc := dl.documents[len(dl.documents)-1]
+	update := CorpusUpdate{Source: doc.Path, Language: doc.Language, Tokens: len(doc.Tokens)}
+
+	update.NewWords, update.Skipped = dl.admitWords(doc.Tokens)
+	if len(update.NewWords) > 0 {
+		dl.vocabView.Store(newVocabularyView(dl.vocabulary))
+	}
+
+	rows, contextRows := make(map[string]bool), make(map[string]bool)
+	dl.countTransitions(doc.Tokens, rows, contextRows)
+	words := make([]string, 0, len(rows))
+	for word := range rows {
+		words = append(words, word)
+	}
+	dl.publishTransitions(words)
+	contexts := make([]string, 0, len(contextRows))
+	for context := range contextRows {
+		contexts = append(contexts, context)
+	}
+	publishRows(dl.trigrams, dl.trigramCounts, contexts)
+	dl.publishStarters()
+	dl.buildLanguageModels() // only tallies languages unless the corpus is multilingual
+	update.Transitions = len(words)
+
+	if dl.embeddingDim > 0 {
+		embed := make(map[string]bool)
+		for _, word := range update.NewWords {
+			embed[word] = true
+		}
+		if dl.cooccurrenceCount < maxCooccurrenceEntries && !dl.countCooccurrence(doc.Tokens, embed) {
+			fmt.Printf("⚠️  Reached co-occurrence limit (%d), new material no longer shapes embeddings\n", maxCooccurrenceEntries)
+		}
+		for word := range embed {
+			dl.embeddings[word] = dl.embed(word)
+		}
+		if len(embed) > 0 {
+			dl.vectors.Store(newEmbeddingMatrix(dl.embeddings, dl.embeddingDim))
+			dl.simCache.Reset()
+		}
+		update.Embeddings = len(embed)
+	}
+
+	dl.countPassages(doc.Content)
+	ModelUpdated()
+
+	fmt.Printf("➕ Added %s to the corpus: %d new words, %d skipped at the vocabulary limit, %d transition rows and %d embeddings updated\n",
+		update.Source, len(update.NewWords), update.Skipped, update.Transitions, update.Embeddings)
+	return update
+}
+
+// admitWords appends the words of tokens that now reach MinWordFreq, or are
+// on the keep-list, to the vocabulary while it has room. It returns the
+// words admitted and how many qualified but found the vocabulary full.
+// Callers must hold dl.mu.
+func (dl *DatasetLoader) admitWords(tokens []string) ([]string, int) {
+	keep := make(map[string]bool, len(dl.pruning.Keep))
+	for _, word := range dl.pruning.Keep {
+		keep[word] = true
+	}
+	drop := make(map[string]bool, len(dl.pruning.Drop))
+	for _, word := range dl.pruning.Drop {
+		drop[word] = true
+	}
+
+	var admitted []string
+	skipped := 0
+	seen := make(map[string]bool)
+	for _, word := range tokens {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if _, ok := dl.vocabulary[word]; ok || drop[word] || dl.rejected[word] {
+			continue
+		}
+		if dl.wordFreq[word] < float64(dl.minWordFreq) && !keep[word] {
+			continue
+		}
+		if len(dl.vocabulary) >= dl.maxVocabSize {
+			skipped++
+			continue
+		}
+		dl.vocabulary[word] = len(dl.vocabulary)
+		admitted = append(admitted, word)
+	}
+	return admitted, skipped
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `genesis dataset fetch <name>` downloads curated public-domain corpora into
+// the datasets directory. Downloads go to <file>.part and resume with an HTTP
+// Range request if interrupted. Checksums are of the file as stored, after
+// the Project Gutenberg boilerplate is stripped, so a corpus on disk can be
+// checked against them at any time. Every completed fetch is recorded in
+// datasets/manifest.json, and a stored corpus that no longer matches is
+// downloaded again.
+//
+// Checksums are pinned in datasets/pins.json, which is checked in, so even a
+// first download is verified. A corpus without a pin is refused unless the
+// fetch is told to trust it (-trust), after which the manifest is its
+// reference. When upstream legitimately revises a text, fetches fail with a
+// checksum mismatch until someone re-pins it: `genesis dataset pin <name>`
+// downloads the current text, stores it and rewrites its pin, which is then
+// reviewed and committed like any other change.
+
+// PublicCorpus describes one downloadable corpus
+type PublicCorpus struct {
+	Name        string
+	Description string
+	URL         string
+	File        string // file name inside the datasets directory
+	SHA256      string // checksum of the stored file; empty uses the pins file
+	Gutenberg   bool   // strip the Project Gutenberg header and license footer
+}
+
+// Curated public-domain texts from Project Gutenberg
+var publicCorpora = []PublicCorpus{
+	{Name: "alice", Description: "Lewis Carroll, Alice's Adventures in Wonderland", URL: "https://www.gutenberg.org/cache/epub/11/pg11.txt", File: "alice.txt", Gutenberg: true},
+	{Name: "pride-and-prejudice", Description: "Jane Austen, Pride and Prejudice", URL: "https://www.gutenberg.org/cache/epub/1342/pg1342.txt", File: "pride_and_prejudice.txt", Gutenberg: true},
+	{Name: "sherlock-holmes", Description: "Arthur Conan Doyle, The Adventures of Sherlock Holmes", URL: "https://www.gutenberg.org/cache/epub/1661/pg1661.txt", File: "sherlock_holmes.txt", Gutenberg: true},
+	{Name: "frankenstein", Description: "Mary Shelley, Frankenstein", URL: "https://www.gutenberg.org/cache/epub/84/pg84.txt", File: "frankenstein.txt", Gutenberg: true},
+	{Name: "tom-sawyer", Description: "Mark Twain, The Adventures of Tom Sawyer", URL: "https://www.gutenberg.org/cache/epub/74/pg74.txt", File: "tom_sawyer.txt", Gutenberg: true},
+	{Name: "origin-of-species", Description: "Charles Darwin, On the Origin of Species", URL: "https://www.gutenberg.org/cache/epub/2009/pg2009.txt", File: "origin_of_species.txt", Gutenberg: true},
+}
+
+// Names of the manifest and pins files inside the datasets directory
+const (
+	datasetManifestFile = "manifest.json"
+	datasetPinsFile     = "pins.json"
+)
+
+// Largest corpus we'll download, matching the loader's file size limit
+const maxFetchBytes = 100 * 1024 * 1024
+
+// ManifestEntry records one fetched corpus
+type ManifestEntry struct {
+	File      string    `json:"file"`
+	URL       string    `json:"url"`
+	SHA256    string    `json:"sha256"`
+	Bytes     int64     `json:"bytes"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// DatasetManifest maps corpus names to what was fetched
+type DatasetManifest struct {
+	Corpora map[string]ManifestEntry `json:"corpora"`
+}
+
+// CorpusPin is the checksum a corpus was pinned at, for the URL it was
+// downloaded from
+type CorpusPin struct {
+	URL      string    `json:"url"`
+	SHA256   string    `json:"sha256"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// CorpusPins maps corpus names to their pins
+type CorpusPins struct {
+	Corpora map[string]CorpusPin `json:"corpora"`
+}
+
+// LoadCorpusPins reads the pins in dir, returning none if the file is missing
+func LoadCorpusPins(dir string) (*CorpusPins, error) {
+	pins := &CorpusPins{Corpora: make(map[string]CorpusPin)}
+	data, err := os.ReadFile(filepath.Join(dir, datasetPinsFile))
+	if os.IsNotExist(err) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pins: %w", err)
+	}
+	if err := json.Unmarshal(data, pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pins: %w", err)
+	}
+	if pins.Corpora == nil {
+		pins.Corpora = make(map[string]CorpusPin)
+	}
+	return pins, nil
+}
+
+// Save writes the pins atomically
+func (p *CorpusPins) Save(dir string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pins: %w", err)
+	}
+	path := filepath.Join(dir, datasetPinsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write pins: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// pinned returns corpus's pinned checksum: the registry's, or the pin taken
+// from its current URL, or ""
+func (p *CorpusPins) pinned(corpus PublicCorpus) string {
+	if corpus.SHA256 != "" {
+		return corpus.SHA256
+	}
+	if pin, ok := p.Corpora[corpus.Name]; ok && pin.URL == corpus.URL {
+		return pin.SHA256
+	}
+	return ""
+}
+
+func findPublicCorpus(name string) (PublicCorpus, bool) {
+	for _, c := range publicCorpora {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return PublicCorpus{}, false
+}
+
+// LoadDatasetManifest reads the manifest in dir, returning an empty one if missing
+func LoadDatasetManifest(dir string) (*DatasetManifest, error) {
+	manifest := &DatasetManifest{Corpora: make(map[string]ManifestEntry)}
+
+	data, err := os.ReadFile(filepath.Join(dir, datasetManifestFile))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Corpora == nil {
+		manifest.Corpora = make(map[string]ManifestEntry)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest atomically
+func (m *DatasetManifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	path := filepath.Join(dir, datasetManifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// How a fetch treats the corpus's checksum
+type fetchMode int
+
+const (
+	fetchPinned  fetchMode = iota // verify against the pin or a trusted fetch; refuse without either
+	fetchTrusted                  // accept an unpinned corpus on its first fetch
+	fetchRepin                    // accept what upstream serves now and pin it
+)
+
+// FetchCorpus downloads corpus into dir, resuming a partial download if one
+// exists, verifies its checksum and records it in the manifest. A corpus that
+// is already present and still matches its checksum is not downloaded again,
+// and one that has never been pinned or trusted is not downloaded at all.
+func FetchCorpus(client *http.Client, corpus PublicCorpus, dir string) (*ManifestEntry, error) {
+	return fetchCorpus(client, corpus, dir, fetchPinned)
+}
+
+// FetchUnpinnedCorpus is FetchCorpus trusting a corpus without a pin on its
+// first fetch; later fetches verify against what it recorded
+func FetchUnpinnedCorpus(client *http.Client, corpus PublicCorpus, dir string) (*ManifestEntry, error) {
+	return fetchCorpus(client, corpus, dir, fetchTrusted)
+}
+
+// PinCorpus downloads corpus afresh, accepting whatever upstream serves,
+// stores it and pins its checksum in dir's pins file
+func PinCorpus(client *http.Client, corpus PublicCorpus, dir string) (*ManifestEntry, error) {
+	if corpus.SHA256 != "" {
+		return nil, fmt.Errorf("%s is pinned in the registry; update its SHA256 there", corpus.Name)
+	}
+	return fetchCorpus(client, corpus, dir, fetchRepin)
+}
+
+func fetchCorpus(client *http.Client, corpus PublicCorpus, dir string, mode fetchMode) (*ManifestEntry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	manifest, err := LoadDatasetManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := LoadCorpusPins(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pins win; otherwise a trusted previous fetch is the reference
+	expected := pins.pinned(corpus)
+	prev, hasPrev := manifest.Corpora[corpus.Name]
+	if expected == "" && hasPrev {
+		expected = prev.SHA256
+	}
+	if mode == fetchRepin {
+		expected = ""
+	} else if expected == "" && mode == fetchPinned {
+		return nil, fmt.Errorf("%s has no pinned checksum: pin it with `genesis dataset pin %s`, or fetch it with -trust", corpus.Name, corpus.Name)
+	}
+
+	target := filepath.Join(dir, corpus.File)
+	if mode != fetchRepin && hasPrev && expected != "" {
+		if sum, err := sha256File(target); err == nil {
+			if strings.EqualFold(sum, expected) {
+				fmt.Printf("✅ %s already fetched (%s)\n", corpus.Name, target)
+				return &prev, nil
+			}
+			fmt.Printf("⚠️  %s no longer matches its checksum, fetching it again\n", target)
+		}
+	}
+
+	partPath := target + ".part"
+	if mode == fetchRepin {
+		// A partial download may be of the old revision
+		os.Remove(partPath)
+	}
+	if _, err := downloadWithResume(client, corpus.URL, partPath); err != nil {
+		return nil, err
+	}
+
+	if corpus.Gutenberg {
+		raw, err := os.ReadFile(partPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read download: %w", err)
+		}
+		if err := os.WriteFile(partPath, []byte(stripGutenbergBoilerplate(string(raw))), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", partPath, err)
+		}
+	}
+	sum, err := sha256File(partPath)
+	if err != nil {
+		return nil, err
+	}
+	if expected != "" && !strings.EqualFold(sum, expected) {
+		// A corrupt partial would poison every resume, so start over next time
+		os.Remove(partPath)
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s; if upstream revised it, re-pin it with `genesis dataset pin %s`", corpus.Name, expected, sum, corpus.Name)
+	}
+	if expected == "" && mode == fetchTrusted {
+		fmt.Printf("⚠️  No pinned checksum for %s, trusting and recording %s\n", corpus.Name, sum)
+	}
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", partPath, err)
+	}
+	if err := os.Rename(partPath, target); err != nil {
+		return nil, fmt.Errorf("failed to move download into place: %w", err)
+	}
+
+	entry := ManifestEntry{
+		File:      corpus.File,
+		URL:       corpus.URL,
+		SHA256:    sum,
+		Bytes:     info.Size(),
+		FetchedAt: time.Now().UTC(),
+	}
+	manifest.Corpora[corpus.Name] = entry
+	if err := manifest.Save(dir); err != nil {
+		return nil, err
+	}
+	if mode == fetchRepin {
+		pins.Corpora[corpus.Name] = CorpusPin{URL: corpus.URL, SHA256: sum, PinnedAt: entry.FetchedAt}
+		if err := pins.Save(dir); err != nil {
+			return nil, err
+		}
+		fmt.Printf("📌 Pinned %s at sha256 %s\n", corpus.Name, sum)
+	}
+	return &entry, nil
+}
+
+// downloadWithResume fetches url into path, continuing from the current size
+// of path when the server honours Range requests. Returns the final size.
+func downloadWithResume(client *http.Client, url, path string) (int64, error) {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL %s: %w", url, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		fmt.Printf("⏩ Resuming %s at %d bytes\n", filepath.Base(path), offset)
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored the range (or there was nothing to resume)
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete
+		return offset, nil
+	default:
+		return 0, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxFetchBytes-offset+1))
+	if err != nil {
+		// Keep what we have so the next attempt can resume
+		return 0, fmt.Errorf("download of %s interrupted after %d bytes: %w", url, offset+n, err)
+	}
+	if offset+n > maxFetchBytes {
+		f.Close()
+		os.Remove(path)
+		return 0, fmt.Errorf("%s exceeds %d bytes", url, maxFetchBytes)
+	}
+	return offset + n, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// stripGutenbergBoilerplate keeps only the text between the
+// "*** START OF ..." and "*** END OF ..." markers, if present
+func stripGutenbergBoilerplate(text string) string {
+	if i := strings.Index(text, "*** START OF"); i >= 0 {
+		if nl := strings.Index(text[i:], "\n"); nl >= 0 {
+			text = text[i+nl+1:]
+		}
+	}
+	if i := strings.Index(text, "*** END OF"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text) + "\n"
+}
+
+// DatasetMain implements `go run . dataset <list|fetch|pin|stats>`
+func DatasetMain(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: genesis dataset <list|fetch|pin|stats> [flags] [names or paths...]")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("dataset list", flag.ExitOnError)
+		dir := fs.String("dir", "datasets", "Datasets directory")
+		fs.Parse(args[1:])
+
+		manifest, err := LoadDatasetManifest(*dir)
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		names := make([]string, 0, len(publicCorpora))
+		for _, c := range publicCorpora {
+			names = append(names, c.Name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c, _ := findPublicCorpus(name)
+			status := "  "
+			if _, ok := manifest.Corpora[name]; ok {
+				status = "✅"
+			}
+			fmt.Printf("%s %-20s %s\n", status, name, c.Description)
+		}
+
+	case "fetch", "pin":
+		fs := flag.NewFlagSet("dataset "+args[0], flag.ExitOnError)
+		dir := fs.String("dir", "datasets", "Datasets directory")
+		all := fs.Bool("all", false, "Fetch every curated corpus")
+		timeout := fs.Duration("timeout", 10*time.Minute, "Per-corpus download timeout")
+		trust := false
+		if args[0] == "fetch" {
+			fs.BoolVar(&trust, "trust", false, "Accept corpora without a pinned checksum on their first fetch")
+		}
+		fs.Parse(args[1:])
+
+		names := fs.Args()
+		if *all {
+			names = names[:0]
+			for _, c := range publicCorpora {
+				names = append(names, c.Name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Printf("Usage: genesis dataset %s [-dir datasets] [-all] <name>...\n", args[0])
+			os.Exit(2)
+		}
+		fetch := FetchCorpus
+		switch {
+		case args[0] == "pin":
+			fetch = PinCorpus
+		case trust:
+			fetch = FetchUnpinnedCorpus
+		}
+
+		client := &http.Client{Timeout: *timeout}
+		failed := false
+		for _, name := range names {
+			corpus, ok := findPublicCorpus(name)
+			if !ok {
+				fmt.Printf("❌ Unknown corpus %q (see `genesis dataset list`)\n", name)
+				failed = true
+				continue
+			}
+			fmt.Printf("📥 Fetching %s from %s\n", corpus.Name, corpus.URL)
+			entry, err := fetch(client, corpus, *dir)
+			if err != nil {
+				fmt.Printf("❌ ERROR: %v\n", err)
+				failed = true
+				continue
+			}
+			fmt.Printf("✅ %s: %d bytes, sha256 %s\n", corpus.Name, entry.Bytes, entry.SHA256)
+		}
+		if failed {
+			os.Exit(1)
+		}
+
+	case "stats":
+		fs := flag.NewFlagSet("dataset stats", flag.ExitOnError)
+		configPath := fs.String("config", "config.json", "Path to configuration file")
+		top := fs.Int("top", 10, "Most frequent n-grams to list per length")
+		asJSON := fs.Bool("json", false, "Print the report as JSON")
+		fs.Parse(args[1:])
+
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		if paths := fs.Args(); len(paths) > 0 {
+			// Report on these files instead of the configured datasets
+			config.Training.DatasetPaths = paths
+			config.Training.DisableStarterCorpus = true
+		}
+		loader, err := NewDatasetLoader(config.Training)
+		if err != nil {
+			fmt.Printf("❌ ERROR: failed to load datasets: %v\n", err)
+			os.Exit(1)
+		}
+		stats := ComputeCorpusStats(loader, conceptSchemaFromConfig(config), *top)
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(stats)
+		} else {
+			stats.Print(os.Stdout)
+		}
+
+	default:
+		fmt.Printf("Unknown dataset command %q\n", args[0])
+		os.Exit(2)
+	}
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // DatasetLoader handles loading and processing text datasets
@@ -18,17 +21,67 @@ type DatasetLoader struct {
 	wordFreq    map[string]float64
 	embeddings  map[string][]float64
 	documents   []Document
-	transitions map[string]map[string]float64 // word -> next word -> probability
+	transitions *shardedMap[map[string]float64] // word -> next word -> probability (inner maps are read-only once stored)
 	starters    map[string]float64            // words that start sentences
 	enders      map[string]bool               // words that end sentences
 	mu          sync.RWMutex
 	maxVocabSize int
+	vocabView   atomic.Pointer[vocabularyView] // rebuilt whenever the vocabulary changes
+	passageFreq  map[string]int // word -> number of corpus sentences containing it
+	passageCount int
+	languages    map[string]*languageModel       // per-language tables; nil for single-language corpora
+	vectors      atomic.Pointer[embeddingMatrix] // normalized embeddings, set once generated
+	simCache     *SimilarityCache                // recent pair similarities
+	copyOnWrite  bool                            // getters hand out private copies rather than shared views
+	pruneStats   PruneStats                      // what the last vocabulary build pruned
+
+	// Build inputs and raw counts kept so AddDocument can update incrementally
+	minWordFreq       int
+	pruning           VocabularyPruning
+	rejected          map[string]bool // candidates the last build pruned or truncated
+	embeddingDim      int
+	transitionCounts  map[string]map[string]float64 // word -> next word -> count
+	trigramCounts     map[string]map[string]float64 // "previous word" -> next word -> count
+	starterCounts     map[string]float64
+	cooccurrence      map[string]map[string]float64 // word -> neighbor -> distance-weighted count
+	cooccurrenceCount int
+
+	trigrams          *shardedMap[map[string]float64] // "previous word" -> next word -> probability
+	trigramWeight     float64                         // share of two-word context in mixed backoff
+	tablesPath        string    // cached transition tables to load on first use
+	tablesFingerprint string    // corpus fingerprint the cached tables must match
+	tablesOnce        sync.Once // loads the cached tables on first use
+}
+
+// vocabularyView is an immutable snapshot of the vocabulary shared by all
+// readers until the vocabulary is rebuilt. Callers must not modify it.
+type vocabularyView struct {
+	words []string            // most frequent first, in vocabulary index order
+	set   map[string]struct{} // membership lookups
+}
+
+func newVocabularyView(vocabulary map[string]int) *vocabularyView {
+	view := &vocabularyView{
+		words: make([]string, 0, len(vocabulary)),
+		set:   make(map[string]struct{}, len(vocabulary)),
+	}
+	for word := range vocabulary {
+		view.words = append(view.words, word)
+		view.set[word] = struct{}{}
+	}
+	// buildVocabulary assigns indices by descending frequency, ties
+	// alphabetically, so this order is stable across runs
+	sort.Slice(view.words, func(i, j int) bool {
+		return vocabulary[view.words[i]] < vocabulary[view.words[j]]
+	})
+	return view
 }
 
 type Document struct {
-	Path    string
-	Content string
-	Tokens  []string
+	Path     string
+	Content  string
+	Tokens   []string
+	Language string // ISO 639-1 code, or "und" when undetermined
 }
 
 type TrainingConfig struct {
@@ -37,6 +90,13 @@ type TrainingConfig struct {
 	EmbeddingDim    int
 	MinWordFreq     int
 	MaxDocuments    int
+	DisableStarterCorpus bool // don't fall back to the embedded starter corpus
+	SimilarityCacheSize  int  // word pairs kept by the similarity cache
+	Pruning              VocabularyPruning // document frequency, TF-IDF and list cuts
+	CacheDir             string            // directory for cached transition tables; empty disables caching
+	CopyOnWrite          bool              // getters return copies callers may modify, at the cost of copying
+	TrigramWeight        float64           // weight of two-word over one-word context when mixing transitions; 0 uses one word
+	DistillPath          string            `json:"-"` // distillation dataset to learn from, set from distill.path; "" for none
 }
 
 func NewDatasetLoader(config TrainingConfig) (*DatasetLoader, error) {
@@ -50,16 +110,27 @@ func NewDatasetLoader(config TrainingConfig) (*DatasetLoader, error) {
 	if config.MaxDocuments <= 0 {
 		config.MaxDocuments = 1000 // Default
 	}
+	if config.SimilarityCacheSize <= 0 {
+		config.SimilarityCacheSize = defaultSimilarityCacheSize
+	}
 	
 	loader := &DatasetLoader{
 		vocabulary:   make(map[string]int),
 		wordFreq:     make(map[string]float64),
 		embeddings:   make(map[string][]float64),
 		documents:    make([]Document, 0, config.MaxDocuments), // Pre-allocate with capacity
-		transitions:  make(map[string]map[string]float64),
+		transitions:  newShardedMap[map[string]float64](),
 		starters:     make(map[string]float64),
+		transitionCounts: make(map[string]map[string]float64),
+		trigramCounts:    make(map[string]map[string]float64),
+		trigrams:         newShardedMap[map[string]float64](),
+		trigramWeight:    config.TrigramWeight,
+		starterCounts:    make(map[string]float64),
+		cooccurrence:     make(map[string]map[string]float64),
 		enders:       make(map[string]bool),
 		maxVocabSize: config.MaxVocabSize,
+		simCache:     NewSimilarityCache(config.SimilarityCacheSize),
+		copyOnWrite:  config.CopyOnWrite,
 	}
 
 	// Load all documents with progress tracking
@@ -72,15 +143,34 @@ func NewDatasetLoader(config TrainingConfig) (*DatasetLoader, error) {
 		}
 	}
 	
+	// Fall back to the embedded starter corpus so the package works out of the box
+	if len(loader.documents) == 0 && !config.DisableStarterCorpus {
+		fmt.Println("📦 No datasets loaded, using embedded starter corpus")
+		if err := loader.loadStarterCorpus(); err != nil {
+			fmt.Printf("⚠️  Warning: failed to load starter corpus: %v\n", err)
+		}
+	}
+	
+	// Learn from the distillation dataset, unless it is already listed
+	if config.DistillPath != "" && !contains(config.DatasetPaths, config.DistillPath) {
+		if _, err := os.Stat(config.DistillPath); err == nil {
+			fmt.Printf("🧪 Loading distillation dataset: %s\n", config.DistillPath)
+			if err := loader.loadFile(config.DistillPath); err != nil {
+				fmt.Printf("⚠️  Warning: failed to load distillation dataset: %v\n", err)
+			}
+		}
+	}
+	
 	// Check if any documents were loaded
 	if len(loader.documents) == 0 {
 		return nil, fmt.Errorf("no documents were successfully loaded from any dataset path")
 	}
 
 	// Build vocabulary and embeddings
-	loader.buildVocabulary(config.MinWordFreq)
+	loader.buildVocabulary(config.MinWordFreq, config.Pruning)
 	loader.generateEmbeddings(config.EmbeddingDim)
-	loader.buildTransitions()
+	loader.loadOrBuildTransitions(config.CacheDir)
+	loader.buildPassageFrequencies()
 
 	return loader, nil
 }
@@ -112,6 +202,7 @@ func (dl *DatasetLoader) loadDirectory(dirPath string, maxDocs int) error {
 			break
 		}
 
+		// .jsonl files are only loaded when named, as distillation datasets
 		if strings.HasSuffix(file.Name(), ".txt") || strings.HasSuffix(file.Name(), ".md") {
 			fullPath := filepath.Join(dirPath, file.Name())
 			if err := dl.loadFile(fullPath); err != nil {
@@ -138,24 +229,39 @@ func (dl *DatasetLoader) loadFile(filePath string) error {
 		return fmt.Errorf("file %s is too large (%d bytes > %d bytes limit)", filePath, fileInfo.Size(), maxFileSize)
 	}
 	
+	if strings.HasSuffix(filePath, ".jsonl") {
+		// A distillation dataset; learn its responses
+		text, err := distillText(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read distillation dataset %s: %w", filePath, err)
+		}
+		return dl.addDocument(filePath, text)
+	}
+	
 	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
 	
+	return dl.addDocument(filePath, string(content))
+}
+
+// addDocument tokenizes content and records it as a document. Callers must hold dl.mu.
+func (dl *DatasetLoader) addDocument(filePath, content string) error {
 	// Validate content is not empty
 	if len(content) == 0 {
 		return fmt.Errorf("file %s is empty", filePath)
 	}
 
 	// Tokenize content
-	tokens := dl.tokenize(string(content))
+	tokens := dl.tokenize(content)
 	
 	doc := Document{
 		Path:    filePath,
-		Content: string(content),
+		Content: content,
 		Tokens:  tokens,
 	}
+	doc.Language, _ = detectTokensLanguage(tokens)
 
 	dl.documents = append(dl.documents, doc)
 
@@ -171,7 +277,7 @@ func (dl *DatasetLoader) loadFile(filePath string) error {
 		}
 	}
 	
-	fmt.Printf("✅ Loaded %s: %d tokens, %d unique words\n", filePath, totalTokens, len(tokens))
+	fmt.Printf("✅ Loaded %s: %d tokens, %d unique words, language %s\n", filePath, totalTokens, len(tokens), doc.Language)
 
 	return nil
 }
@@ -215,18 +321,53 @@ func (dl *DatasetLoader) tokenize(text string) []string {
 	return tokens
 }
 
-func (dl *DatasetLoader) buildVocabulary(minFreq int) {
+func (dl *DatasetLoader) buildVocabulary(minFreq int, pruning VocabularyPruning) {
 	dl.mu.Lock()
 	defer dl.mu.Unlock()
 
-	// Sort words by frequency and build vocabulary
-	vocabIndex := 0
+	dl.minWordFreq, dl.pruning = minFreq, pruning
+	keep := make(map[string]bool, len(pruning.Keep))
+	for _, word := range pruning.Keep {
+		keep[word] = true
+	}
+
+	words := make([]string, 0, len(dl.wordFreq))
 	for word, freq := range dl.wordFreq {
-		if freq >= float64(minFreq) && vocabIndex < dl.maxVocabSize {
-			dl.vocabulary[word] = vocabIndex
-			vocabIndex++
+		if freq >= float64(minFreq) || keep[word] {
+			words = append(words, word)
+		}
+	}
+	stats := PruneStats{Candidates: len(words)}
+	rejected := make(map[string]bool, len(words))
+	for _, word := range words {
+		rejected[word] = true
+	}
+	words = dl.prune(words, pruning, keep, &stats)
+
+	// Sort words by frequency (ties alphabetically) so indices, and the
+	// embeddings derived from them, are the same on every run
+	sort.Slice(words, func(i, j int) bool {
+		if dl.wordFreq[words[i]] != dl.wordFreq[words[j]] {
+			return dl.wordFreq[words[i]] > dl.wordFreq[words[j]]
 		}
+		return words[i] < words[j]
+	})
+	if len(words) > dl.maxVocabSize {
+		stats.Truncated = len(words) - dl.maxVocabSize
+		words = truncateVocabulary(words, dl.maxVocabSize, keep)
 	}
+	if pruned := stats.DocFreq + stats.TFIDF + stats.Dropped + stats.Truncated; pruned > 0 {
+		fmt.Printf("✂️  Pruned %d of %d words: %d by document frequency, %d by TF-IDF, %d dropped, %d truncated (%d kept by list)\n",
+			pruned, stats.Candidates, stats.DocFreq, stats.TFIDF, stats.Dropped, stats.Truncated, stats.Kept)
+	}
+	dl.pruneStats = stats
+	for vocabIndex, word := range words {
+		dl.vocabulary[word] = vocabIndex
+		delete(rejected, word)
+	}
+	dl.rejected = rejected
+
+	dl.vocabView.Store(newVocabularyView(dl.vocabulary))
 
 	fmt.Printf("Built vocabulary with %d words\n", len(dl.vocabulary))
 }
@@ -242,133 +383,196 @@ func (dl *DatasetLoader) generateEmbeddings(dim int) {
 	}
 	
 	fmt.Printf("🧮 Generating %d-dimensional embeddings for %d words...\n", dim, len(dl.vocabulary))
+	dl.embeddingDim = dim
 
 	// Generate embeddings based on word co-occurrence patterns
-	cooccurrence := make(map[string]map[string]float64)
-	windowSize := 5
-
-	// Build co-occurrence matrix with memory limits
-	maxCooccurrenceEntries := 100000 // Limit to prevent memory explosion
-	cooccurrenceCount := 0
-	
 	for docIdx, doc := range dl.documents {
 		// Progress indicator for large datasets
 		if docIdx%100 == 0 && docIdx > 0 {
 			fmt.Printf("⚡ Processing document %d/%d for embeddings\n", docIdx, len(dl.documents))
 		}
-		
-		for i, word1 := range doc.Tokens {
-			if _, exists := dl.vocabulary[word1]; !exists {
-				continue
-			}
-
-			if cooccurrence[word1] == nil {
-				cooccurrence[word1] = make(map[string]float64)
-			}
-
-			// Look at surrounding words
-			start := max(0, i-windowSize)
-			end := min(len(doc.Tokens), i+windowSize+1)
-
-			for j := start; j < end; j++ {
-				if i == j {
-					continue
-				}
-				
-				// Check memory limit
-				if cooccurrenceCount >= maxCooccurrenceEntries {
-					fmt.Printf("⚠️  Reached co-occurrence limit (%d), stopping early to prevent OOM\n", maxCooccurrenceEntries)
-					goto embeddings_generation
-				}
-				
-				word2 := doc.Tokens[j]
-				if _, exists := dl.vocabulary[word2]; exists {
-					distance := math.Abs(float64(i - j))
-					cooccurrence[word1][word2] += 1.0 / distance
-					cooccurrenceCount++
-				}
-			}
+		if !dl.countCooccurrence(doc.Tokens, nil) {
+			fmt.Printf("⚠️  Reached co-occurrence limit (%d), stopping early to prevent OOM\n", maxCooccurrenceEntries)
+			break
 		}
 	}
-	
-embeddings_generation:
 
-	// Generate embeddings from co-occurrence patterns
 	for word := range dl.vocabulary {
-		embedding := make([]float64, dim)
-		
-		// Initialize with small random values
-		for i := range embedding {
-			embedding[i] = (rand.Float64() - 0.5) * 0.1
+		dl.embeddings[word] = dl.embed(word)
+	}
+	dl.vectors.Store(newEmbeddingMatrix(dl.embeddings, dim))
+}
+
+// Co-occurrence window and the most entries counted, to prevent memory explosion
+const (
+	cooccurrenceWindow     = 5
+	maxCooccurrenceEntries = 100000
+)
+
+// countCooccurrence adds the distance-weighted neighbors of each vocabulary
+// word in tokens, recording the words whose counts changed in touched if it
+// is non-nil. It returns false once the entry limit is reached. Callers must
+// hold dl.mu.
+func (dl *DatasetLoader) countCooccurrence(tokens []string, touched map[string]bool) bool {
+	for i, word1 := range tokens {
+		if _, exists := dl.vocabulary[word1]; !exists {
+			continue
+		}
+		if dl.cooccurrence[word1] == nil {
+			dl.cooccurrence[word1] = make(map[string]float64)
 		}
 
-		// Adjust based on co-occurrence
-		if neighbors, exists := cooccurrence[word]; exists {
-			for neighbor, weight := range neighbors {
-				if nIdx, exists := dl.vocabulary[neighbor]; exists {
-					// Simple embedding: use vocabulary index and weight
-					embedding[nIdx%dim] += weight * 0.01
+		// Look at surrounding words
+		start := max(0, i-cooccurrenceWindow)
+		end := min(len(tokens), i+cooccurrenceWindow+1)
+		for j := start; j < end; j++ {
+			if i == j {
+				continue
+			}
+			if dl.cooccurrenceCount >= maxCooccurrenceEntries {
+				return false
+			}
+			word2 := tokens[j]
+			if _, exists := dl.vocabulary[word2]; exists {
+				distance := math.Abs(float64(i - j))
+				dl.cooccurrence[word1][word2] += 1.0 / distance
+				dl.cooccurrenceCount++
+				if touched != nil {
+					touched[word1] = true
 				}
 			}
 		}
+	}
+	return true
+}
 
-		// Normalize
-		norm := 0.0
-		for _, val := range embedding {
-			norm += val * val
-		}
-		norm = math.Sqrt(norm)
-		if norm > 0 {
-			for i := range embedding {
-				embedding[i] /= norm
-			}
+// embed derives word's embedding from its co-occurrence counts. Callers
+// must hold dl.mu.
+func (dl *DatasetLoader) embed(word string) []float64 {
+	dim := dl.embeddingDim
+	embedding := make([]float64, dim)
+
+	// Initialize with small random values, seeded by the word so
+	// embeddings are reproducible across runs
+	h := fnv.New64a()
+	h.Write([]byte(word))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+	for i := range embedding {
+		embedding[i] = (rng.Float64() - 0.5) * 0.1
+	}
+
+	// Adjust based on co-occurrence
+	for neighbor, weight := range dl.cooccurrence[word] {
+		if nIdx, exists := dl.vocabulary[neighbor]; exists {
+			// Simple embedding: use vocabulary index and weight
+			embedding[nIdx%dim] += weight * 0.01
 		}
+	}
 
-		dl.embeddings[word] = embedding
+	// Normalize
+	norm := 0.0
+	for _, val := range embedding {
+		norm += val * val
 	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range embedding {
+			embedding[i] /= norm
+		}
+	}
+	return embedding
 }
 
+// GetEmbedding returns word's embedding. Updates replace embeddings rather
+// than modify them, so the slice is shared read-only unless CopyOnWrite is
+// set.
 func (dl *DatasetLoader) GetEmbedding(word string) ([]float64, bool) {
 	dl.mu.RLock()
 	defer dl.mu.RUnlock()
 
 	embedding, exists := dl.embeddings[strings.ToLower(word)]
+	if exists && dl.copyOnWrite {
+		embedding = append([]float64(nil), embedding...)
+	}
 	return embedding, exists
 }
 
+// GetVocabulary returns the vocabulary, most frequent words first, in the
+// same order on every run, so truncating it keeps the most common words.
+// The slice is a shared read-only view unless CopyOnWrite is set.
 func (dl *DatasetLoader) GetVocabulary() []string {
-	dl.mu.RLock()
-	defer dl.mu.RUnlock()
+	view := dl.vocabView.Load()
+	if view == nil {
+		return nil
+	}
+	if dl.copyOnWrite {
+		return append([]string(nil), view.words...)
+	}
+	return view.words
+}
 
-	words := make([]string, 0, len(dl.vocabulary))
-	for word := range dl.vocabulary {
-		words = append(words, word)
+// InVocabulary reports whether word is part of the vocabulary
+func (dl *DatasetLoader) InVocabulary(word string) bool {
+	view := dl.vocabView.Load()
+	if view == nil {
+		return false
 	}
-	return words
+	_, ok := view.set[word]
+	return ok
 }
 
+// GetDocuments returns a snapshot of the loaded documents. The slice is the
+// caller's own to sort or append to; each document's Tokens are shared
+// read-only unless CopyOnWrite is set, with capacity capped so appending to
+// them reallocates.
 func (dl *DatasetLoader) GetDocuments() []Document {
 	dl.mu.RLock()
 	defer dl.mu.RUnlock()
 
-	return dl.documents
+	docs := make([]Document, len(dl.documents))
+	copy(docs, dl.documents)
+	for i := range docs {
+		tokens := docs[i].Tokens
+		if dl.copyOnWrite {
+			docs[i].Tokens = append([]string(nil), tokens...)
+		} else {
+			docs[i].Tokens = tokens[:len(tokens):len(tokens)]
+		}
+	}
+	return docs
 }
 
 func (dl *DatasetLoader) ComputeSimilarity(word1, word2 string) float64 {
-	emb1, exists1 := dl.GetEmbedding(word1)
-	emb2, exists2 := dl.GetEmbedding(word2)
+	sim, _ := dl.similarity(word1, word2)
+	return sim
+}
 
-	if !exists1 || !exists2 {
-		return 0.0
+// similarity returns the cosine similarity of two words' embeddings and
+// whether both words have one
+func (dl *DatasetLoader) similarity(word1, word2 string) (float64, bool) {
+	word1, word2 = strings.ToLower(word1), strings.ToLower(word2)
+	if sim, ok := dl.simCache.Get(word1, word2); ok {
+		return sim, true
 	}
 
-	// Cosine similarity
-	dot := 0.0
-	for i := range emb1 {
-		dot += emb1[i] * emb2[i]
+	vectors := dl.vectors.Load()
+	if vectors == nil {
+		return 0.0, false
+	}
+	emb1, exists1 := vectors.vector(word1)
+	emb2, exists2 := vectors.vector(word2)
+	if !exists1 || !exists2 {
+		return 0.0, false
 	}
 
-	return dot
+	sim := dot(emb1, emb2)
+	dl.simCache.Put(word1, word2, sim)
+	return sim, true
+}
+
+// SimilarityCacheStats reports how well the similarity cache is doing
+func (dl *DatasetLoader) SimilarityCacheStats() SimilarityCacheStats {
+	return dl.simCache.Stats()
 }
 
 // Training batch for neural models
@@ -426,65 +630,119 @@ func (dl *DatasetLoader) buildTransitions() {
 	dl.mu.Lock()
 	defer dl.mu.Unlock()
 
-	// Build word transition probabilities
 	for _, doc := range dl.documents {
-		tokens := doc.Tokens
+		dl.countTransitions(doc.Tokens, nil, nil)
+	}
+	dl.publishAllTransitions()
+	
+	fmt.Printf("Built transitions for %d words\n", dl.transitions.Len())
+	
+	dl.buildLanguageModels()
+	if len(dl.languages) > 0 {
+		fmt.Printf("🌐 Multilingual corpus: built tables for %d languages\n", len(dl.languages))
+	}
+}
+
+// countTransitions adds the starter, vocabulary bigrams and trigrams, and
+// enders of tokens to the raw counts, recording the words and two-word
+// contexts whose rows changed in touched and contexts if they are non-nil.
+// Callers must hold dl.mu.
+func (dl *DatasetLoader) countTransitions(tokens []string, touched, contexts map[string]bool) {
+	// Track sentence starters
+	if len(tokens) > 0 {
+		dl.starterCounts[tokens[0]]++
+	}
+	
+	for i := 0; i < len(tokens)-1; i++ {
+		current := tokens[i]
+		next := tokens[i+1]
 		
-		// Track sentence starters
-		if len(tokens) > 0 {
-			dl.starters[tokens[0]]++
+		// Only track words in vocabulary
+		if _, inVocab1 := dl.vocabulary[current]; !inVocab1 {
+			continue
+		}
+		if _, inVocab2 := dl.vocabulary[next]; !inVocab2 {
+			continue
 		}
 		
-		// Build transitions
-		for i := 0; i < len(tokens)-1; i++ {
-			current := tokens[i]
-			next := tokens[i+1]
-			
-			// Only track words in vocabulary
-			if _, inVocab1 := dl.vocabulary[current]; !inVocab1 {
-				continue
-			}
-			if _, inVocab2 := dl.vocabulary[next]; !inVocab2 {
-				continue
-			}
-			
-			if dl.transitions[current] == nil {
-				dl.transitions[current] = make(map[string]float64)
-			}
-			dl.transitions[current][next]++
-			
-			// Track potential sentence enders
-			if i == len(tokens)-2 || (i < len(tokens)-2 && isCapitalized(tokens[i+2])) {
-				dl.enders[next] = true
+		if dl.transitionCounts[current] == nil {
+			dl.transitionCounts[current] = make(map[string]float64)
+		}
+		dl.transitionCounts[current][next]++
+		if touched != nil {
+			touched[current] = true
+		}
+		if i > 0 && dl.trigramWeight > 0 {
+			if _, inVocab0 := dl.vocabulary[tokens[i-1]]; inVocab0 {
+				context := trigramContext(tokens[i-1], current)
+				if dl.trigramCounts[context] == nil {
+					dl.trigramCounts[context] = make(map[string]float64)
+				}
+				dl.trigramCounts[context][next]++
+				if contexts != nil {
+					contexts[context] = true
+				}
 			}
 		}
+		
+		// Track potential sentence enders
+		if i == len(tokens)-2 || (i < len(tokens)-2 && isCapitalized(tokens[i+2])) {
+			dl.enders[next] = true
+		}
 	}
-	
-	// Normalize transition probabilities
-	for word, transitions := range dl.transitions {
+}
+
+// publishAllTransitions publishes every transition and starter table from
+// the raw counts. Callers must hold dl.mu.
+func (dl *DatasetLoader) publishAllTransitions() {
+	words := make([]string, 0, len(dl.transitionCounts))
+	for word := range dl.transitionCounts {
+		words = append(words, word)
+	}
+	dl.publishTransitions(words)
+	contexts := make([]string, 0, len(dl.trigramCounts))
+	for context := range dl.trigramCounts {
+		contexts = append(contexts, context)
+	}
+	publishRows(dl.trigrams, dl.trigramCounts, contexts)
+	dl.publishStarters()
+}
+
+// publishTransitions normalizes the counts of words into fresh probability
+// tables and stores them, leaving tables readers already hold untouched.
+// Callers must hold dl.mu.
+func (dl *DatasetLoader) publishTransitions(words []string) {
+	publishRows(dl.transitions, dl.transitionCounts, words)
+}
+
+// publishRows stores the normalized counts of keys into tables as fresh maps
+func publishRows(tables *shardedMap[map[string]float64], counts map[string]map[string]float64, keys []string) {
+	for _, key := range keys {
+		row := counts[key]
 		total := 0.0
-		for _, count := range transitions {
+		for _, count := range row {
 			total += count
 		}
-		if total > 0 {
-			for nextWord, count := range transitions {
-				dl.transitions[word][nextWord] = count / total
-			}
+		probabilities := make(map[string]float64, len(row))
+		for next, count := range row {
+			probabilities[next] = count / total
 		}
+		tables.Set(key, probabilities)
 	}
-	
-	// Normalize starter probabilities
+}
+
+// publishStarters replaces the starter probabilities with the normalized
+// starter counts. Callers must hold dl.mu.
+func (dl *DatasetLoader) publishStarters() {
 	totalStarters := 0.0
-	for _, count := range dl.starters {
+	for _, count := range dl.starterCounts {
 		totalStarters += count
 	}
-	if totalStarters > 0 {
-		for word, count := range dl.starters {
-			dl.starters[word] = count / totalStarters
-		}
+	starters := make(map[string]float64, len(dl.starterCounts))
+	for word, count := range dl.starterCounts {
+		starters[word] = count / totalStarters
 	}
-	
-	fmt.Printf("Built transitions for %d words\n", len(dl.transitions))
+	dl.starters = starters
 }
 
 func isCapitalized(word string) bool {
@@ -496,10 +754,8 @@ func isCapitalized(word string) bool {
 
 // GetNextWord returns a probable next word given the current word
 func (dl *DatasetLoader) GetNextWord(currentWord string, temperature float64) (string, bool) {
-	dl.mu.RLock()
-	defer dl.mu.RUnlock()
-	
-	transitions, exists := dl.transitions[currentWord]
+	dl.ensureTables()
+	transitions, exists := dl.transitions.Get(currentWord)
 	if !exists || len(transitions) == 0 {
 		return "", false
 	}
@@ -528,6 +784,7 @@ func (dl *DatasetLoader) GetNextWord(currentWord string, temperature float64) (s
 
 // GetStarterWord returns a word that commonly starts sentences
 func (dl *DatasetLoader) GetStarterWord() string {
+	dl.ensureTables()
 	dl.mu.RLock()
 	defer dl.mu.RUnlock()
 	
@@ -555,26 +812,35 @@ func (dl *DatasetLoader) GetStarterWord() string {
 
 // IsEnder checks if a word commonly ends sentences
 func (dl *DatasetLoader) IsEnder(word string) bool {
+	dl.ensureTables()
 	dl.mu.RLock()
 	defer dl.mu.RUnlock()
 	
 	return dl.enders[word]
 }
 
-// GetTransitions returns the transition probabilities for a word
+// GetTransitions returns the transition probabilities for a word. Tables
+// are replaced rather than modified once published, so the map is shared
+// rather than copied and must be treated as read-only unless CopyOnWrite
+// is set.
 func (dl *DatasetLoader) GetTransitions(word string) (map[string]float64, bool) {
-	dl.mu.RLock()
-	defer dl.mu.RUnlock()
-	
-	transitions, exists := dl.transitions[word]
+	dl.ensureTables()
+	transitions, exists := dl.transitions.Get(word)
 	if !exists {
 		return nil, false
 	}
-	
-	// Return a copy to avoid concurrent modification
-	copy := make(map[string]float64)
-	for k, v := range transitions {
-		copy[k] = v
+	return dl.transitionsView(transitions), true
+}
+
+// transitionsView hands out a published transition table: shared, or a
+// private copy under CopyOnWrite
+func (dl *DatasetLoader) transitionsView(transitions map[string]float64) map[string]float64 {
+	if !dl.copyOnWrite {
+		return transitions
+	}
+	copied := make(map[string]float64, len(transitions))
+	for next, p := range transitions {
+		copied[next] = p
 	}
-	return copy, true
+	return copied
 }
\ No newline at end of file
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Decision store: the parallel orchestrator appends every run to a JSON
+// Lines file in the trace format, one flow_run per line. Besides the flow
+// decisions, their paths and the consensus, a run records what re-running
+// its input needs: each neuron's threshold, capability, connections and
+// activation before the input, and where the input was injected.
+// `genesis replay-flow <store>` rebuilds the orchestrator from each
+// recorded run, re-runs the input and reports decisions that differ.
+// Neurons decide concurrently and the consensus stops after its first ten
+// decisions, so a large, densely activated run can pick a different ten.
+
+// DecisionStoreConfig controls persisting parallel orchestrator runs
+type DecisionStoreConfig struct {
+	Path string `json:"path"` // JSONL file runs are appended to; empty disables the store
+}
+
+// FlowRun is one recorded parallel orchestration
+type FlowRun struct {
+	Input        string
+	Thresholds   []float64 // per neuron
+	Capabilities []string  // per neuron
+	Connections  [][]int   // per neuron, the neurons it propagates to
+	Activations  []float64 // per neuron, before the input
+	Injected     []int     // neurons the input activated
+	Gains        []float64 // per neuron routing gain; nil without capability routing
+	Decisions    []FlowDecision
+	Pattern      FlowPattern
+	Consensus    string
+	Result       ConsensusResult // the consensus, with the strategy it was reached under
+	Timestamp    time.Time
+}
+
+// DecisionStore appends orchestrator runs to a file; a nil store records
+// nothing
+type DecisionStore struct {
+	mu       sync.Mutex
+	file     *os.File
+	redactor *Redactor // applied to runs before they're written; nil when off
+}
+
+// OpenDecisionStore opens path for appending runs
+func OpenDecisionStore(path string) (*DecisionStore, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision store: %w", err)
+	}
+	return &DecisionStore{file: file}, nil
+}
+
+// SetRedactor sets the redactor applied to runs before they're written;
+// nil disables it. Call it before the store is shared.
+func (s *DecisionStore) SetRedactor(r *Redactor) {
+	s.redactor = r
+}
+
+// Append writes one run, redacted when the store has a redactor
+func (s *DecisionStore) Append(run *FlowRun) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(s.redactor.RedactFlowRun(run))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write flow run: %w", err)
+	}
+	return nil
+}
+
+// Close closes the store's file
+func (s *DecisionStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// LoadFlowRuns reads every run in a decision store
+func LoadFlowRuns(path string) ([]FlowRun, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision store: %w", err)
+	}
+	defer file.Close()
+
+	var runs []FlowRun
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run FlowRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("decision store line %d: %w", line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read decision store: %w", err)
+	}
+	return runs, nil
+}
+
+// newOrchestratorFromRun rebuilds the orchestrator a run was recorded on,
+// in the state it was in before the input
+func newOrchestratorFromRun(run *FlowRun) (*ParallelOrchestrator, error) {
+	size := len(run.Thresholds)
+	if size == 0 || len(run.Capabilities) != size || len(run.Connections) != size || len(run.Activations) != size {
+		return nil, fmt.Errorf("flow run doesn't describe its %d neurons", size)
+	}
+	po := newParallelOrchestratorShell(size)
+	po.SetConsensus(ConsensusConfig{Strategy: run.Result.Strategy, Quorum: run.Result.Quorum, Arbiter: run.Result.Arbiter})
+	for i := range po.neurons {
+		neuron := &SmartNeuron{id: i, threshold: run.Thresholds[i]}
+		neuron.setCapability(run.Capabilities[i])
+		neuron.activation.Store(run.Activations[i])
+		po.neurons[i] = neuron
+	}
+	for i, targets := range run.Connections {
+		for _, target := range targets {
+			if target < 0 || target >= size {
+				return nil, fmt.Errorf("neuron %d connects to unknown neuron %d", i, target)
+			}
+			po.connections[po.neurons[i]] = append(po.connections[po.neurons[i]], po.neurons[target])
+		}
+	}
+	for _, idx := range run.Injected {
+		if idx < 0 || idx >= size {
+			return nil, fmt.Errorf("input injected at unknown neuron %d", idx)
+		}
+	}
+	return po, nil
+}
+
+// FlowReplayReport compares a replayed run with its recording
+type FlowReplayReport struct {
+	Input             string `json:"input"`
+	RecordedConsensus string `json:"recorded_consensus"`
+	ReplayedConsensus string `json:"replayed_consensus"`
+	Missing           []int  `json:"missing"` // neurons that decided only in the recording
+	Extra             []int  `json:"extra"`   // neurons that decided only in the replay
+	Changed           []int  `json:"changed"` // neurons that decided differently
+}
+
+// Matched reports whether the replay made the recorded decisions
+func (r FlowReplayReport) Matched() bool {
+	return r.RecordedConsensus == r.ReplayedConsensus && len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Changed) == 0
+}
+
+// ReplayFlowRun re-runs a recorded run's input on the orchestrator it was
+// recorded on
+func ReplayFlowRun(run *FlowRun) (FlowReplayReport, error) {
+	po, err := newOrchestratorFromRun(run)
+	if err != nil {
+		return FlowReplayReport{}, err
+	}
+	if run.Gains != nil && len(run.Gains) != len(run.Thresholds) {
+		return FlowReplayReport{}, fmt.Errorf("flow run has %d routing gains for %d neurons", len(run.Gains), len(run.Thresholds))
+	}
+	replayed := po.process(run.Input, run.Injected, run.Gains)
+
+	report := FlowReplayReport{
+		Input:             run.Input,
+		RecordedConsensus: run.Consensus,
+		ReplayedConsensus: replayed.Consensus,
+	}
+	recorded := make(map[int]string, len(run.Decisions))
+	for _, d := range run.Decisions {
+		recorded[d.NeuronID] = d.Decision
+	}
+	for _, d := range replayed.Decisions {
+		decision, ok := recorded[d.NeuronID]
+		switch {
+		case !ok:
+			report.Extra = append(report.Extra, d.NeuronID)
+		case decision != d.Decision:
+			report.Changed = append(report.Changed, d.NeuronID)
+		}
+		delete(recorded, d.NeuronID)
+	}
+	for id := range recorded {
+		report.Missing = append(report.Missing, id)
+	}
+	sort.Ints(report.Missing)
+	sort.Ints(report.Extra)
+	sort.Ints(report.Changed)
+	return report, nil
+}
+
+// ReplayFlowMain implements `go run . replay-flow <store>`
+func ReplayFlowMain(args []string) {
+	fs := flag.NewFlagSet("replay-flow", flag.ExitOnError)
+	only := fs.Int("run", 0, "replay only this run, counting from 1")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: genesis replay-flow [-run n] <decision-store>")
+		os.Exit(2)
+	}
+
+	runs, err := LoadFlowRuns(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if *only < 0 || *only > len(runs) {
+		fmt.Printf("❌ ERROR: the store has %d runs\n", len(runs))
+		os.Exit(2)
+	}
+
+	diverged, replayed := 0, 0
+	for i := range runs {
+		if *only > 0 && i+1 != *only {
+			continue
+		}
+		replayed++
+		report, err := ReplayFlowRun(&runs[i])
+		if err != nil {
+			fmt.Printf("❌ Run %d: %v\n", i+1, err)
+			diverged++
+			continue
+		}
+		if report.Matched() {
+			fmt.Printf("✅ Run %d reproduced: %q\n", i+1, report.Input)
+			continue
+		}
+		diverged++
+		fmt.Printf("❗ Run %d diverged: %q\n", i+1, report.Input)
+		fmt.Printf("   recorded: %s\n   replayed: %s\n", report.RecordedConsensus, report.ReplayedConsensus)
+		fmt.Printf("   missing %v, extra %v, changed %v\n", report.Missing, report.Extra, report.Changed)
+	}
+	if diverged > 0 {
+		fmt.Printf("❌ %d of %d runs diverged\n", diverged, replayed)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ All %d runs reproduced\n", replayed)
+}
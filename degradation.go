@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Graceful degradation: when the ResourceMonitor sees memory pressure for
+// several checks in a row, the process switches to cheaper behavior instead
+// of running into its limit. Response generation keeps fewer beams, circuit
+// tracing stops at a shallower depth, the wave visualizer pauses, and
+// plasticity updates such as reward modulation from feedback are queued
+// rather than applied. Once pressure stays below the recovery level for as
+// many checks, full behavior is restored and the queued updates run in
+// order. Components consult the process-wide degrader, so a disabled or
+// unconfigured degrader changes nothing.
+
+// Oldest deferred updates are dropped past this many
+const maxDeferredUpdates = 1024
+
+// DegradationConfig sets when to degrade and how far
+type DegradationConfig struct {
+	Enabled         bool    `json:"enabled"`
+	CheckIntervalMS int     `json:"check_interval_ms"` // how often the resource monitor samples pressure
+	PressureRatio   float64 `json:"pressure_ratio"`    // memory use over max_memory_mb that counts as pressure
+	RecoverRatio    float64 `json:"recover_ratio"`     // memory use over max_memory_mb below which pressure has eased
+	SustainChecks   int     `json:"sustain_checks"`    // consecutive checks before switching either way
+	BeamWidth       int     `json:"beam_width"`        // beams kept per step while degraded
+	CircuitDepth    int     `json:"circuit_depth"`     // longest circuit traced while degraded
+}
+
+func (c DegradationConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.CheckIntervalMS <= 0 || c.SustainChecks <= 0 {
+		return fmt.Errorf("degradation check_interval_ms and sustain_checks must be positive")
+	}
+	if c.RecoverRatio <= 0 || c.RecoverRatio > c.PressureRatio {
+		return fmt.Errorf("degradation recover_ratio must be positive and at most pressure_ratio")
+	}
+	if c.BeamWidth <= 0 || c.CircuitDepth <= 0 {
+		return fmt.Errorf("degradation beam_width and circuit_depth must be positive")
+	}
+	return nil
+}
+
+// DegradationStatus is a snapshot of the degrader
+type DegradationStatus struct {
+	Enabled      bool      `json:"enabled"`
+	Degraded     bool      `json:"degraded"`
+	Since        time.Time `json:"since,omitempty"` // when the current mode began
+	Pressure     float64   `json:"pressure"`        // last sampled memory use over the limit
+	Streak       int       `json:"streak"`          // consecutive checks pointing to the other mode
+	BeamWidth    int       `json:"beam_width"`      // degraded limits, applied only while degraded
+	CircuitDepth int       `json:"circuit_depth"`
+	Deferred     int       `json:"deferred"` // plasticity updates waiting for recovery
+	Dropped      int       `json:"dropped"`  // deferred updates dropped past the queue limit
+	Transitions  int       `json:"transitions"`
+}
+
+// Degrader switches components between full and degraded behavior. A nil
+// Degrader never degrades.
+type Degrader struct {
+	mu          sync.Mutex
+	config      DegradationConfig
+	clock       Clock
+	degraded    atomic.Bool
+	since       time.Time
+	pressure    float64
+	streak      int
+	deferred    []func()
+	dropped     int
+	transitions int
+}
+
+// NewDegrader creates a degrader in full mode
+func NewDegrader(config DegradationConfig, clock Clock) *Degrader {
+	return &Degrader{config: config, clock: clock, since: clock.Now()}
+}
+
+// sharedDegrader is the process-wide degrader components consult
+var sharedDegrader = NewDegrader(DegradationConfig{}, RealClock)
+
+// configureDegradation applies config to the process-wide degrader and
+// returns it
+func configureDegradation(config DegradationConfig) *Degrader {
+	sharedDegrader.mu.Lock()
+	sharedDegrader.config = config
+	sharedDegrader.mu.Unlock()
+	if !config.Enabled {
+		sharedDegrader.restore()
+	}
+	return sharedDegrader
+}
+
+// SharedDegrader returns the process-wide degrader
+func SharedDegrader() *Degrader {
+	return sharedDegrader
+}
+
+// Degraded reports whether degraded behavior is in effect
+func (d *Degrader) Degraded() bool {
+	return d != nil && d.degraded.Load()
+}
+
+// Observe records a pressure sample, memory use over the limit, switching
+// modes once the sample has pointed the same way for sustain_checks checks
+func (d *Degrader) Observe(pressure float64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.pressure = pressure
+	if !d.config.Enabled {
+		d.streak = 0
+		d.mu.Unlock()
+		return
+	}
+	switch {
+	case !d.Degraded() && pressure >= d.config.PressureRatio:
+		d.streak++
+	case d.Degraded() && pressure < d.config.RecoverRatio:
+		d.streak++
+	default:
+		d.streak = 0
+	}
+	sustained := d.streak >= d.config.SustainChecks
+	d.mu.Unlock()
+
+	if !sustained {
+		return
+	}
+	if d.Degraded() {
+		d.restore()
+	} else {
+		d.degrade()
+	}
+}
+
+// degrade switches to degraded behavior
+func (d *Degrader) degrade() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.degraded.Load() {
+		return
+	}
+	d.degraded.Store(true)
+	d.since = d.clock.Now()
+	d.streak = 0
+	d.transitions++
+	fmt.Printf("🐢 Resource pressure at %.0f%% of the memory limit, degrading: beam width %d, circuit depth %d, visualization paused, plasticity deferred\n",
+		d.pressure*100, d.config.BeamWidth, d.config.CircuitDepth)
+}
+
+// restore switches back to full behavior and runs the deferred updates
+func (d *Degrader) restore() {
+	d.mu.Lock()
+	if !d.degraded.Load() {
+		d.mu.Unlock()
+		return
+	}
+	d.degraded.Store(false)
+	d.since = d.clock.Now()
+	d.streak = 0
+	d.transitions++
+	deferred := d.deferred
+	d.deferred = nil
+	d.mu.Unlock()
+
+	fmt.Printf("✅ Resource pressure eased, restoring full behavior and applying %d deferred updates\n", len(deferred))
+	for _, update := range deferred {
+		update()
+	}
+}
+
+// BeamWidth returns the beams to keep per step given the full width
+func (d *Degrader) BeamWidth(full int) int {
+	if !d.Degraded() {
+		return full
+	}
+	return min(full, d.config.BeamWidth)
+}
+
+// CircuitDepth returns the longest circuit to trace given the full depth
+func (d *Degrader) CircuitDepth(full int) int {
+	if !d.Degraded() {
+		return full
+	}
+	return min(full, d.config.CircuitDepth)
+}
+
+// Defer runs a plasticity update now, or queues it until full behavior is
+// restored while degraded
+func (d *Degrader) Defer(update func()) {
+	if !d.Degraded() {
+		update()
+		return
+	}
+	d.mu.Lock()
+	if !d.degraded.Load() {
+		// Restored since the check above
+		d.mu.Unlock()
+		update()
+		return
+	}
+	if len(d.deferred) >= maxDeferredUpdates {
+		d.deferred = d.deferred[1:]
+		d.dropped++
+	}
+	d.deferred = append(d.deferred, update)
+	d.mu.Unlock()
+}
+
+// Status returns a snapshot of the degrader
+func (d *Degrader) Status() DegradationStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DegradationStatus{
+		Enabled:      d.config.Enabled,
+		Degraded:     d.degraded.Load(),
+		Since:        d.since,
+		Pressure:     d.pressure,
+		Streak:       d.streak,
+		BeamWidth:    d.config.BeamWidth,
+		CircuitDepth: d.config.CircuitDepth,
+		Deferred:     len(d.deferred),
+		Dropped:      d.dropped,
+		Transitions:  d.transitions,
+	}
+}
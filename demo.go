@@ -19,7 +19,7 @@ func DemoMain() {
 	// Load configuration
 	config := DefaultConfig()
 	llm := NewTransparentLLMWithConfig(config)
-	defer llm.Cleanup()
+	defer OnShutdown(ShutdownModels, "llm", llm.Cleanup)()
 
 	reader := bufio.NewReader(os.Stdin)
 
@@ -97,7 +97,7 @@ func RunAutoDemo() {
 	
 	fmt.Println("\n1️⃣  Testing Transparent LLM...")
 	transparentLLM := NewTransparentLLMWithConfig(config)
-	defer transparentLLM.Cleanup()
+	defer OnShutdown(ShutdownModels, "llm", transparentLLM.Cleanup)()
 
 	for _, test := range testInputs {
 		fmt.Printf("\n📝 Test: %s\n", test.description)
@@ -121,9 +121,9 @@ func RunAutoDemo() {
 	fmt.Println("\n2️⃣  Testing Liquid State Brain...")
 	
 	liquidBrain := NewLiquidStateBrainWithConfig(20, config) // Smaller brain for demo
-	defer liquidBrain.Cleanup()
+	defer OnShutdown(ShutdownModels, "liquid brain", liquidBrain.Cleanup)()
 	
-	time.Sleep(1 * time.Second) // Let it initialize
+	liquidBrain.settle() // Let it initialize
 
 	for _, test := range testInputs {
 		fmt.Printf("\n📝 Test: %s\n", test.description)
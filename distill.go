@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Distillation datasets: with distillation on, the orchestrator appends the
+// prompts it sends to external capabilities and the responses it gets back
+// to a JSON Lines file. Responses that are too short, too long, mostly
+// symbols, placeholders, echoes of the prompt or duplicates are filtered
+// out. Orchestrators in one process share one log per file, so duplicates
+// are caught across them and their appends don't interleave. The file is
+// loaded as a dataset alongside training.DatasetPaths while distillation is
+// on, so its responses are learned as transitions and concepts and Genesis
+// picks up the external models' behaviour over time. Other .jsonl files in
+// dataset directories aren't loaded.
+
+// DistillConfig controls logging capability exchanges as training data
+type DistillConfig struct {
+	Enabled          bool     `json:"enabled"`
+	Path             string   `json:"path"`               // JSONL dataset the exchanges are appended to
+	Capabilities     []string `json:"capabilities"`       // capabilities logged, usually the external LLMs
+	MinResponseWords int      `json:"min_response_words"` // shorter responses are dropped
+	MaxResponseWords int      `json:"max_response_words"` // longer responses are dropped; 0 for no limit
+}
+
+func (c DistillConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Path == "" {
+		return fmt.Errorf("distill path is required when distillation is enabled")
+	}
+	if !strings.HasSuffix(c.Path, ".jsonl") {
+		return fmt.Errorf("distill path must end in .jsonl to be loaded as a dataset")
+	}
+	if c.MinResponseWords < 0 || c.MaxResponseWords < 0 {
+		return fmt.Errorf("distill word limits must not be negative")
+	}
+	if c.MaxResponseWords > 0 && c.MaxResponseWords < c.MinResponseWords {
+		return fmt.Errorf("distill max_response_words must be at least min_response_words")
+	}
+	return nil
+}
+
+// DistillExample is one line of a distillation dataset
+type DistillExample struct {
+	Time       time.Time `json:"time"`
+	Capability string    `json:"capability"`
+	Prompt     string    `json:"prompt"`
+	Response   string    `json:"response"`
+}
+
+// key identifies an example for deduplication, ignoring case and spacing
+func (e DistillExample) key() string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	sum := sha256.Sum256([]byte(normalize(e.Prompt) + "\x00" + normalize(e.Response)))
+	return hex.EncodeToString(sum[:])
+}
+
+// DistillStats counts the exchanges kept and dropped, by filter
+type DistillStats struct {
+	Kept    int            `json:"kept"`
+	Dropped map[string]int `json:"dropped"`
+}
+
+// DistillationLog appends filtered capability exchanges to a dataset
+type DistillationLog struct {
+	mu           sync.Mutex
+	file         *os.File
+	config       DistillConfig
+	capabilities map[string]bool
+	redactor     *Redactor       // applied before writing; nil when off
+	seen         map[string]bool // keys of the examples in the file
+	stats        DistillStats
+	path         string // absolute; the key in distillLogs
+	refs         int    // opens not yet closed, guarded by distillLogsMu
+}
+
+// Open distillation logs by absolute path
+var (
+	distillLogsMu sync.Mutex
+	distillLogs   = make(map[string]*DistillationLog)
+)
+
+// OpenDistillationLog opens config.Path for appending. Examples already in
+// the file are remembered so they aren't logged twice. A file that is
+// already open returns the same log, which is closed once every opener has
+// closed it; opening it again with other settings fails.
+func OpenDistillationLog(config DistillConfig, redactor *Redactor) (*DistillationLog, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve distillation dataset path: %w", err)
+	}
+	distillLogsMu.Lock()
+	defer distillLogsMu.Unlock()
+
+	if log, ok := distillLogs[path]; ok {
+		if !reflect.DeepEqual(log.config, config) {
+			return nil, fmt.Errorf("distillation dataset %s is already open with other settings", config.Path)
+		}
+		log.refs++
+		return log, nil
+	}
+	log := &DistillationLog{
+		config:       config,
+		capabilities: make(map[string]bool, len(config.Capabilities)),
+		redactor:     redactor,
+		seen:         make(map[string]bool),
+		stats:        DistillStats{Dropped: make(map[string]int)},
+		path:         path,
+		refs:         1,
+	}
+	for _, name := range config.Capabilities {
+		log.capabilities[name] = true
+	}
+	if err := readDistillExamples(config.Path, func(e DistillExample) {
+		log.seen[e.key()] = true
+	}); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open distillation dataset: %w", err)
+	}
+	log.file = file
+	distillLogs[path] = log
+	fmt.Printf("🧪 Distilling %v responses into %s (%d examples so far)\n", config.Capabilities, config.Path, len(log.seen))
+	return log, nil
+}
+
+// filter returns why an exchange should be dropped, or "" to keep it
+func (l *DistillationLog) filter(e DistillExample) string {
+	response := strings.TrimSpace(e.Response)
+	words := strings.Fields(response)
+	switch {
+	case len(words) == 0:
+		return "empty"
+	case len(words) < l.config.MinResponseWords:
+		return "too_short"
+	case l.config.MaxResponseWords > 0 && len(words) > l.config.MaxResponseWords:
+		return "too_long"
+	case strings.HasPrefix(response, "[") && strings.HasSuffix(response, "]"):
+		// Mock and error responses look like "[GPT-4 response to: ...]"
+		return "placeholder"
+	case strings.EqualFold(strings.Join(words, " "), strings.Join(strings.Fields(e.Prompt), " ")):
+		return "echo"
+	}
+	alphabetic := 0
+	for _, word := range words {
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				alphabetic++
+				break
+			}
+		}
+	}
+	if alphabetic*2 < len(words) {
+		return "not_text"
+	}
+	if l.seen[e.key()] {
+		return "duplicate"
+	}
+	return ""
+}
+
+// Record logs one capability exchange if the capability is distilled and
+// the exchange passes the filters. kept reports whether it was written.
+func (l *DistillationLog) Record(capability, prompt, response string) (kept bool, err error) {
+	if l == nil || !l.capabilities[capability] {
+		return false, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	example := DistillExample{
+		Time:       time.Now().UTC(),
+		Capability: capability,
+		Prompt:     l.redactor.Redact(prompt),
+		Response:   l.redactor.Redact(strings.TrimSpace(response)),
+	}
+	if reason := l.filter(example); reason != "" {
+		l.stats.Dropped[reason]++
+		return false, nil
+	}
+
+	line, err := json.Marshal(example)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode distillation example: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return false, fmt.Errorf("failed to write distillation example: %w", err)
+	}
+	l.seen[example.key()] = true
+	l.stats.Kept++
+	return true, nil
+}
+
+// Stats returns the exchanges kept and dropped since the log was opened
+func (l *DistillationLog) Stats() DistillStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := DistillStats{Kept: l.stats.Kept, Dropped: make(map[string]int, len(l.stats.Dropped))}
+	for reason, n := range l.stats.Dropped {
+		stats.Dropped[reason] = n
+	}
+	return stats
+}
+
+// Close releases one open of the log, closing the dataset file with the
+// last
+func (l *DistillationLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	distillLogsMu.Lock()
+	defer distillLogsMu.Unlock()
+
+	if l.refs--; l.refs > 0 {
+		return nil
+	}
+	delete(distillLogs, l.path)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// readDistillExamples calls fn with each example of the dataset at path
+func readDistillExamples(path string, fn func(DistillExample)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var example DistillExample
+		if err := json.Unmarshal(scanner.Bytes(), &example); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		fn(example)
+	}
+	return scanner.Err()
+}
+
+// distillText returns the responses of a distillation dataset as corpus
+// text, one paragraph each, so they are learned like any other document
+func distillText(path string) (string, error) {
+	var paragraphs []string
+	err := readDistillExamples(path, func(e DistillExample) {
+		if e.Response != "" {
+			paragraphs = append(paragraphs, e.Response)
+		}
+	})
+	return strings.Join(paragraphs, "\n\n"), err
+}
+
+// SetDistillationLog logs capability exchanges to log; nil stops logging
+func (go_ *GenesisOrchestrator) SetDistillationLog(log *DistillationLog) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	go_.distill = log
+	for _, n := range go_.neurons {
+		n.mu.Lock()
+		n.distill = log
+		n.mu.Unlock()
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// Doctor: a startup self-test for "why do I only get fallback responses".
+// It checks, in order, that the config loads and validates, which dataset
+// paths exist and what they load into, how much memory headroom is left
+// under the configured limits, that the vector store and the orchestrator's
+// capabilities answer, and finally runs one tiny inference end to end. Each
+// check is ok, warn or fail with a hint on what to do; the report's status
+// is the worst of them.
+
+// Doctor check outcomes, from best to worst
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// Prompt for the end-to-end inference check
+const doctorPrompt = "hello, how are you?"
+
+// Brain size used to build the orchestrator whose capabilities are probed
+const doctorBrainSize = 4
+
+// DoctorCheck is the outcome of one diagnostic
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "ok", "warn" or "fail"
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // what to do about a warning or failure
+}
+
+// DoctorReport is every diagnostic, in the order they ran
+type DoctorReport struct {
+	Config string        `json:"config"`
+	Status string        `json:"status"` // the worst check status
+	Checks []DoctorCheck `json:"checks"`
+}
+
+// add records a check, keeping the worst status
+func (r *DoctorReport) add(check DoctorCheck) {
+	r.Checks = append(r.Checks, check)
+	if doctorSeverity(check.Status) > doctorSeverity(r.Status) {
+		r.Status = check.Status
+	}
+}
+
+func doctorSeverity(status string) int {
+	switch status {
+	case doctorWarn:
+		return 1
+	case doctorFail:
+		return 2
+	}
+	return 0
+}
+
+// RunDoctor runs every diagnostic against the config at configPath. Checks
+// that need a working config or dataset are skipped when those fail.
+func RunDoctor(configPath string) *DoctorReport {
+	report := &DoctorReport{Config: configPath, Status: doctorOK}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		report.add(DoctorCheck{Name: "config", Status: doctorFail, Detail: err.Error(),
+			Hint: "fix the config file; starter/config.json is a valid example"})
+		return report
+	}
+	report.add(DoctorCheck{Name: "config", Status: doctorOK, Detail: "valid"})
+
+	report.add(doctorDatasetPaths(config.Training))
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		report.add(DoctorCheck{Name: "dataset", Status: doctorFail, Detail: err.Error(),
+			Hint: "without a dataset every response is a canned fallback; add dataset paths or enable the starter corpus"})
+		return report
+	}
+	report.add(doctorDatasetStats(loader))
+	report.add(doctorMemory(config))
+	if check, ok := doctorVectorStore(config.Retrieval); ok {
+		report.add(check)
+	}
+	for _, check := range doctorCapabilities(config) {
+		report.add(check)
+	}
+	report.add(doctorInference(config, loader))
+	return report
+}
+
+// doctorDatasetPaths checks that the configured dataset paths exist
+func doctorDatasetPaths(training TrainingConfig) DoctorCheck {
+	check := DoctorCheck{Name: "dataset paths", Status: doctorOK}
+	var missing []string
+	for _, path := range training.DatasetPaths {
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, path)
+		}
+	}
+	found := len(training.DatasetPaths) - len(missing)
+	check.Detail = fmt.Sprintf("%d of %d found", found, len(training.DatasetPaths))
+	switch {
+	case found == 0 && training.DisableStarterCorpus:
+		check.Status = doctorFail
+		check.Hint = "no dataset can load and the starter corpus is disabled; fetch corpora with `genesis dataset`"
+	case found == 0:
+		check.Status = doctorWarn
+		check.Hint = "only the small embedded starter corpus will load, so many responses fall back; fetch corpora with `genesis dataset`"
+	case len(missing) > 0:
+		check.Status = doctorWarn
+		check.Detail += fmt.Sprintf(", missing %v", missing)
+		check.Hint = "remove or fix the missing paths"
+	}
+	return check
+}
+
+// Vocabulary below which generation has too few words to work with
+const doctorMinVocabulary = 200
+
+// doctorDatasetStats reports what the datasets loaded into
+func doctorDatasetStats(loader *DatasetLoader) DoctorCheck {
+	docs, vocab := len(loader.GetDocuments()), len(loader.GetVocabulary())
+	check := DoctorCheck{
+		Name:   "dataset",
+		Status: doctorOK,
+		Detail: fmt.Sprintf("%d documents, %d words", docs, vocab),
+	}
+	if vocab < doctorMinVocabulary {
+		check.Status = doctorWarn
+		check.Hint = fmt.Sprintf("a vocabulary under %d words leaves generation little to work with; add corpora or lower MinWordFreq", doctorMinVocabulary)
+	}
+	return check
+}
+
+// doctorMemory compares memory in use after loading the datasets, plus a
+// reservoir of max_neurons, against max_memory_mb
+func doctorMemory(config *Config) DoctorCheck {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	usedMB := int(m.Alloc / 1024 / 1024)
+	reservoirMB := (config.Resources.MaxNeurons * 200) / (1024 * 1024) // same estimate as brain creation
+	limit := config.Resources.MaxMemoryMB
+	expected := float64(usedMB+reservoirMB) / float64(limit)
+
+	check := DoctorCheck{
+		Name:   "memory",
+		Status: doctorOK,
+		Detail: fmt.Sprintf("%d MB in use + ~%d MB for %d neurons of %d MB limit (%.0f%%)", usedMB, reservoirMB, config.Resources.MaxNeurons, limit, expected*100),
+	}
+	pressure := 0.8
+	if config.Degradation.Enabled {
+		pressure = config.Degradation.PressureRatio
+	}
+	switch {
+	case expected >= 1:
+		check.Status = doctorFail
+		check.Hint = "raise resources.max_memory_mb or lower max_neurons and the dataset sizes"
+	case expected >= pressure:
+		check.Status = doctorWarn
+		check.Hint = "little headroom is left; under load the process will degrade or run out of memory"
+	}
+	return check
+}
+
+// doctorVectorStore checks that the retrieval vector store answers; ok is
+// false when retrieval is off
+func doctorVectorStore(retrieval RetrievalConfig) (DoctorCheck, bool) {
+	if retrieval.TopK <= 0 {
+		return DoctorCheck{}, false
+	}
+	check := DoctorCheck{Name: "vector store", Status: doctorOK}
+	store, err := NewVectorStore(retrieval.VectorStore)
+	if err != nil {
+		check.Status, check.Detail = doctorFail, err.Error()
+		check.Hint = "fix retrieval.vector_store; responses won't be grounded in retrieved passages"
+		return check, true
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	count, err := store.Count(ctx)
+	if err != nil {
+		check.Status, check.Detail = doctorFail, err.Error()
+		check.Hint = "the vector store is unreachable; check its url and api key"
+		return check, true
+	}
+	kind := retrieval.VectorStore.Type
+	if kind == "" {
+		kind = "memory"
+	}
+	check.Detail = fmt.Sprintf("%s store with %d records", kind, count)
+	return check, true
+}
+
+// doctorCapabilities probes every orchestrator capability once and checks
+// that the fallback chain only names registered ones
+func doctorCapabilities(config *Config) []DoctorCheck {
+	probe := *config
+	probe.Distill.Enabled = false // don't record the probes
+	orchestrator := NewGenesisOrchestratorWithConfig(doctorBrainSize, &probe)
+	defer orchestrator.Close()
+
+	orchestrator.mu.RLock()
+	neurons := make(map[string]*OrchestratorNeuron, len(orchestrator.neurons))
+	for name, n := range orchestrator.neurons {
+		neurons[name] = n
+	}
+	orchestrator.mu.RUnlock()
+	names := make([]string, 0, len(neurons))
+	for name := range neurons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var checks []DoctorCheck
+	for _, name := range names {
+		check := DoctorCheck{Name: "capability " + name, Status: doctorOK}
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		start := time.Now()
+		_, err := neurons[name].endpoint(ctx, doctorPrompt)
+		cancel()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			check.Status = doctorFail
+			check.Detail = fmt.Sprintf("no answer within %v", healthCheckTimeout)
+			check.Hint = "the capability is unreachable; the orchestrator will route around it or answer with a fallback"
+		case err != nil:
+			// Reachable, just nothing to say about the probe
+			check.Status = doctorWarn
+			check.Detail = fmt.Sprintf("answered the probe with an error: %v", err)
+		default:
+			check.Detail = fmt.Sprintf("answered in %v", time.Since(start).Round(time.Microsecond))
+		}
+		checks = append(checks, check)
+	}
+
+	chain := DoctorCheck{Name: "fallback chain", Status: doctorOK, Detail: fmt.Sprintf("%v", config.Fallback.Chain)}
+	for _, name := range config.Fallback.Chain {
+		if neurons[name] == nil {
+			chain.Status = doctorFail
+			chain.Detail = fmt.Sprintf("%q is not a registered capability", name)
+			chain.Hint = fmt.Sprintf("use one of %v", names)
+			break
+		}
+	}
+	return append(checks, chain)
+}
+
+// doctorInference generates one response the way the server does and
+// reports whether it came from the model or a fallback
+func doctorInference(config *Config, loader *DatasetLoader) DoctorCheck {
+	generator, closeGenerator := newServingGenerator(config, loader)
+	defer closeGenerator()
+
+	start := time.Now()
+	response, explanation := generator.GenerateExplained(doctorPrompt, nil)
+	check := DoctorCheck{
+		Name:   "inference",
+		Status: doctorOK,
+		Detail: fmt.Sprintf("%q in %v, confidence %.2f", response, time.Since(start).Round(time.Millisecond), explanation.Confidence.Score),
+	}
+	switch {
+	case response == "":
+		check.Status = doctorFail
+		check.Hint = "the generator produced nothing; the dataset may have no usable transitions"
+	case explanation.Template != nil && explanation.Template.Mode == templateReplace:
+		check.Status = doctorWarn
+		check.Detail += fmt.Sprintf(", replaced by the %q template", explanation.Template.Intent)
+		check.Hint = "confidence is below the template threshold; larger corpora or a lower templates threshold help"
+	}
+	return check
+}
+
+// Print writes the report for humans
+func (r *DoctorReport) Print(w io.Writer) {
+	marks := map[string]string{doctorOK: "✅", doctorWarn: "⚠️ ", doctorFail: "❌"}
+	fmt.Fprintf(w, "🩺 Genesis doctor (%s)\n", r.Config)
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "   %s %s: %s\n", marks[check.Status], check.Name, check.Detail)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "      → %s\n", check.Hint)
+		}
+	}
+	fmt.Fprintf(w, "%s Overall: %s\n", marks[r.Status], r.Status)
+}
+
+// DoctorMain implements `go run . doctor`
+func DoctorMain(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	report := RunDoctor(*configPath)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		report.Print(os.Stdout)
+	}
+	if report.Status == doctorFail {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Drift reporting: `genesis diff before.json after.json` compares two
+// checkpoints written by Model.Save. For concept graphs it lists added and
+// removed concepts and the connections whose strength changed most; for
+// reservoirs it compares trained output weights. Both report how the
+// activation distribution shifted, so users running online learning can
+// audit how their model drifts.
+
+// driftHistogramBins splits [0, 1] for comparing activation distributions
+const driftHistogramBins = 10
+
+// DriftReport describes how a model changed between two checkpoints
+type DriftReport struct {
+	Model              string             `json:"model"`
+	AddedConcepts      []string           `json:"added_concepts,omitempty"`
+	RemovedConcepts    []string           `json:"removed_concepts,omitempty"`
+	AddedConnections   int                `json:"added_connections"`
+	RemovedConnections int                `json:"removed_connections"`
+	ChangedConnections []ConnectionChange `json:"changed_connections,omitempty"` // largest change first
+	ChangedOutputs     []OutputChange     `json:"changed_outputs,omitempty"`
+	Activation         DistributionShift  `json:"activation"`
+}
+
+// ConnectionChange is a concept connection whose strength changed
+type ConnectionChange struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+// OutputChange is a trained output neuron whose weights changed
+type OutputChange struct {
+	Meaning      string  `json:"meaning"`
+	WeightChange float64 `json:"weight_change"` // Euclidean distance between the weight vectors, bias included
+	Rewired      bool    `json:"rewired"`       // reads different reservoir neurons, or was trained or untrained
+}
+
+// DistributionShift compares activation distributions
+type DistributionShift struct {
+	BeforeMean     float64 `json:"before_mean"`
+	AfterMean      float64 `json:"after_mean"`
+	BeforeStd      float64 `json:"before_std"`
+	AfterStd       float64 `json:"after_std"`
+	MeanAbsChange  float64 `json:"mean_abs_change"` // over concepts or neurons present in both
+	TotalVariation float64 `json:"total_variation"` // between histograms over [0, 1]; 0 same, 1 disjoint
+}
+
+// DiffCheckpoints reports the drift from before to after, keeping the top
+// largest connection changes (all when top <= 0)
+func DiffCheckpoints(before, after *modelCheckpoint, top int) (*DriftReport, error) {
+	if before.Model != after.Model {
+		return nil, fmt.Errorf("can't diff a %s checkpoint against a %s one", before.Model, after.Model)
+	}
+	report := &DriftReport{Model: before.Model}
+
+	if before.Activations != nil || after.Activations != nil {
+		var paired [][2]float64
+		for id, a := range before.Activations {
+			if b, ok := after.Activations[id]; ok {
+				paired = append(paired, [2]float64{a, b})
+			} else {
+				report.RemovedConcepts = append(report.RemovedConcepts, id)
+			}
+		}
+		for id := range after.Activations {
+			if _, ok := before.Activations[id]; !ok {
+				report.AddedConcepts = append(report.AddedConcepts, id)
+			}
+		}
+		sort.Strings(report.AddedConcepts)
+		sort.Strings(report.RemovedConcepts)
+		report.Activation = distributionShift(mapValues(before.Activations), mapValues(after.Activations), paired)
+	} else {
+		if len(before.States) != len(after.States) {
+			return nil, fmt.Errorf("checkpoints have %d and %d neurons", len(before.States), len(after.States))
+		}
+		paired := make([][2]float64, len(before.States))
+		for i := range before.States {
+			paired[i] = [2]float64{before.States[i], after.States[i]}
+		}
+		report.Activation = distributionShift(before.States, after.States, paired)
+	}
+
+	for from, edges := range before.Connections {
+		for to, a := range edges {
+			b, ok := after.Connections[from][to]
+			if !ok {
+				report.RemovedConnections++
+			} else if a != b {
+				report.ChangedConnections = append(report.ChangedConnections, ConnectionChange{From: from, To: to, Before: a, After: b})
+			}
+		}
+	}
+	for from, edges := range after.Connections {
+		for to := range edges {
+			if _, ok := before.Connections[from][to]; !ok {
+				report.AddedConnections++
+			}
+		}
+	}
+	sort.Slice(report.ChangedConnections, func(i, j int) bool {
+		ci, cj := report.ChangedConnections[i], report.ChangedConnections[j]
+		di, dj := math.Abs(ci.After-ci.Before), math.Abs(cj.After-cj.Before)
+		if di != dj {
+			return di > dj
+		}
+		if ci.From != cj.From {
+			return ci.From < cj.From
+		}
+		return ci.To < cj.To
+	})
+	if top > 0 && len(report.ChangedConnections) > top {
+		report.ChangedConnections = report.ChangedConnections[:top]
+	}
+
+	meanings := make(map[string]bool)
+	for meaning := range before.Outputs {
+		meanings[meaning] = true
+	}
+	for meaning := range after.Outputs {
+		meanings[meaning] = true
+	}
+	for meaning := range meanings {
+		if change, changed := outputChange(meaning, before.Outputs, after.Outputs); changed {
+			report.ChangedOutputs = append(report.ChangedOutputs, change)
+		}
+	}
+	sort.Slice(report.ChangedOutputs, func(i, j int) bool {
+		return report.ChangedOutputs[i].Meaning < report.ChangedOutputs[j].Meaning
+	})
+	return report, nil
+}
+
+// outputChange compares one output neuron across checkpoints; an output
+// trained in only one of them counts as rewired
+func outputChange(meaning string, before, after map[string]checkpointOutput) (OutputChange, bool) {
+	a, inBefore := before[meaning]
+	b, inAfter := after[meaning]
+	change := OutputChange{Meaning: meaning}
+	if !inBefore || !inAfter || fmt.Sprint(a.Connections) != fmt.Sprint(b.Connections) {
+		change.Rewired = true
+	}
+	sum := (a.Bias - b.Bias) * (a.Bias - b.Bias)
+	for i := 0; i < len(a.Weights) || i < len(b.Weights); i++ {
+		var wa, wb float64
+		if i < len(a.Weights) {
+			wa = a.Weights[i]
+		}
+		if i < len(b.Weights) {
+			wb = b.Weights[i]
+		}
+		sum += (wa - wb) * (wa - wb)
+	}
+	change.WeightChange = math.Sqrt(sum)
+	return change, change.Rewired || change.WeightChange > 0
+}
+
+// distributionShift summarizes two activation distributions and the
+// changes of the paired values
+func distributionShift(before, after []float64, paired [][2]float64) DistributionShift {
+	shift := DistributionShift{}
+	shift.BeforeMean, shift.BeforeStd = meanStd(before)
+	shift.AfterMean, shift.AfterStd = meanStd(after)
+	for _, p := range paired {
+		shift.MeanAbsChange += math.Abs(p[1] - p[0])
+	}
+	if len(paired) > 0 {
+		shift.MeanAbsChange /= float64(len(paired))
+	}
+	hb, ha := activationHistogram(before), activationHistogram(after)
+	for i := range hb {
+		shift.TotalVariation += math.Abs(hb[i]-ha[i]) / 2
+	}
+	return shift
+}
+
+func meanStd(values []float64) (mean, std float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(values)))
+}
+
+// activationHistogram returns the share of values in each bin of [0, 1]
+func activationHistogram(values []float64) []float64 {
+	hist := make([]float64, driftHistogramBins)
+	if len(values) == 0 {
+		return hist
+	}
+	for _, v := range values {
+		bin := int(clamp01(v) * driftHistogramBins)
+		if bin == driftHistogramBins {
+			bin--
+		}
+		hist[bin]++
+	}
+	for i := range hist {
+		hist[i] /= float64(len(values))
+	}
+	return hist
+}
+
+func mapValues(m map[string]float64) []float64 {
+	values := make([]float64, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Print writes the report for people
+func (r *DriftReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "📊 Drift report (%s model)\n", r.Model)
+	if len(r.AddedConcepts) > 0 || len(r.RemovedConcepts) > 0 {
+		fmt.Fprintf(w, "   Concepts: +%d -%d\n", len(r.AddedConcepts), len(r.RemovedConcepts))
+		for _, c := range r.AddedConcepts {
+			fmt.Fprintf(w, "      + %s\n", c)
+		}
+		for _, c := range r.RemovedConcepts {
+			fmt.Fprintf(w, "      - %s\n", c)
+		}
+	}
+	if r.AddedConnections > 0 || r.RemovedConnections > 0 || len(r.ChangedConnections) > 0 {
+		fmt.Fprintf(w, "   Connections: +%d -%d, largest changes:\n", r.AddedConnections, r.RemovedConnections)
+		for _, c := range r.ChangedConnections {
+			fmt.Fprintf(w, "      %s → %s: %.3f → %.3f\n", c.From, c.To, c.Before, c.After)
+		}
+	}
+	for _, o := range r.ChangedOutputs {
+		rewired := ""
+		if o.Rewired {
+			rewired = " (rewired)"
+		}
+		fmt.Fprintf(w, "   Output %s: weights moved %.3f%s\n", o.Meaning, o.WeightChange, rewired)
+	}
+	a := r.Activation
+	fmt.Fprintf(w, "   Activation: mean %.3f → %.3f, std %.3f → %.3f\n", a.BeforeMean, a.AfterMean, a.BeforeStd, a.AfterStd)
+	fmt.Fprintf(w, "   Mean change %.3f, distribution shift %.3f\n", a.MeanAbsChange, a.TotalVariation)
+}
+
+// DiffMain implements `go run . diff before.json after.json`
+func DiffMain(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	top := fs.Int("top", 20, "Changed connections to list (0 for all)")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Println("usage: genesis diff [-json] [-top n] <before-checkpoint> <after-checkpoint>")
+		os.Exit(2)
+	}
+
+	before, err := loadCheckpoint(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	after, err := loadCheckpoint(fs.Arg(1))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	report, err := DiffCheckpoints(before, after, *top)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	report.Print(os.Stdout)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+)
+
+// Name reported in the "model" field of embedding responses
+const embeddingModelName = "genesis-cooccurrence"
+
+// EmbeddingRequest mirrors the OpenAI /v1/embeddings request body. Input may
+// be a single string or an array of strings.
+type EmbeddingRequest struct {
+	Input          json.RawMessage `json:"input"`
+	Model          string          `json:"model,omitempty"`
+	EncodingFormat string          `json:"encoding_format,omitempty"` // "float" (default) or "base64"
+}
+
+type EmbeddingResponse struct {
+	Object string          `json:"object"`
+	Data   []EmbeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  EmbeddingUsage  `json:"usage"`
+}
+
+type EmbeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // []float64, or a base64 string of float32s
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// SentenceEmbedding mean-pools the embeddings of the known tokens in text
+// and L2-normalizes the result. Single words return their word vector. The
+// token count is returned for usage accounting; ok is false when no token
+// has an embedding.
+func (dl *DatasetLoader) SentenceEmbedding(text string) (vec []float64, tokens int, ok bool) {
+	words := dl.tokenize(text)
+	tokens = len(words)
+
+	found := 0
+	for _, word := range words {
+		emb, exists := dl.GetEmbedding(word)
+		if !exists {
+			continue
+		}
+		if vec == nil {
+			vec = make([]float64, len(emb))
+		}
+		for i := range emb {
+			vec[i] += emb[i]
+		}
+		found++
+	}
+	if found == 0 {
+		return nil, tokens, false
+	}
+
+	norm := 0.0
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, tokens, true
+}
+
+// EmbeddingDim returns the dimension of the loaded word vectors
+func (dl *DatasetLoader) EmbeddingDim() int {
+	vocab := dl.GetVocabulary()
+	if len(vocab) == 0 {
+		return 0
+	}
+	emb, _ := dl.GetEmbedding(vocab[0])
+	return len(emb)
+}
+
+// EmbeddingsHandler serves DatasetLoader vectors through an
+// OpenAI-compatible /v1/embeddings endpoint
+type EmbeddingsHandler struct {
+	loader *DatasetLoader
+}
+
+func NewEmbeddingsHandler(loader *DatasetLoader) *EmbeddingsHandler {
+	return &EmbeddingsHandler{loader: loader}
+}
+
+func (h *EmbeddingsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+
+	var req EmbeddingRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	inputs, err := parseEmbeddingInput(req.Input)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "encoding_format must be 'float' or 'base64'")
+		return
+	}
+
+	resp := EmbeddingResponse{
+		Object: "list",
+		Data:   make([]EmbeddingData, 0, len(inputs)),
+		Model:  embeddingModelName,
+	}
+	dim := h.loader.EmbeddingDim()
+
+	for i, text := range inputs {
+		vec, tokens, ok := h.loader.SentenceEmbedding(text)
+		if !ok {
+			// Unknown text still gets a vector so indices line up
+			vec = make([]float64, dim)
+		}
+		resp.Usage.PromptTokens += tokens
+
+		var embedding interface{} = vec
+		if req.EncodingFormat == "base64" {
+			embedding = encodeFloat32Base64(vec)
+		}
+		resp.Data = append(resp.Data, EmbeddingData{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: embedding,
+		})
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func parseEmbeddingInput(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+	if len(many) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	return many, nil
+}
+
+// encodeFloat32Base64 packs vec as little-endian float32s, matching the
+// OpenAI base64 encoding format
+func encodeFloat32Base64(vec []float64) string {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: apiErrorBody{Message: message, Type: errType}})
+}
+
+// EmbeddingsMain implements `go run . embeddings`
+func EmbeddingsMain(args []string) {
+	fs := flag.NewFlagSet("embeddings", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	addr := fs.String("addr", ":8080", "Listen address")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		fmt.Printf("❌ ERROR: failed to load datasets: %v\n", err)
+		os.Exit(1)
+	}
+
+	queue := NewInferenceQueueFromLimits(config.Resources)
+
+	health := NewHealthChecker()
+	health.Register("goroutines", GoroutineBudgetCheck(config.Resources.MaxGoroutines), true)
+	health.Register("dataset", DatasetHealthCheck(loader), false)
+	health.Register("inference_queue", QueueHealthCheck(queue), false)
+
+	mux := http.NewServeMux()
+	health.Mount(mux)
+	mux.Handle("/v1/embeddings", queue.Middleware(NewEmbeddingsHandler(loader)))
+	mux.Handle("/summarize", queue.Middleware(NewSummarizeHandler(loader)))
+
+	fmt.Printf("🚀 Serving embeddings on %s/v1/embeddings and summaries on %s/summarize\n", *addr, *addr)
+	if err := serveUntilShutdown(*addr, mux); err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Energy accounting counts the work a request costs so efficiency claims
+// can be measured instead of asserted. Components keep cumulative counters
+// in an EnergyMeter; a request's report is the difference between meter
+// snapshots taken around it, plus the work done synchronously for it
+// (circuits traced, beams expanded, external tokens). Reservoirs are shared
+// and keep ticking between requests, so concurrent requests on one brain
+// see each other's neuron updates and spikes.
+
+// EnergyReport counts units of work
+type EnergyReport struct {
+	NeuronUpdates    int64 `json:"neuron_updates"`
+	SpikesPropagated int64 `json:"spikes_propagated"`
+	CircuitsTraced   int64 `json:"circuits_traced"`
+	BeamsExpanded    int64 `json:"beams_expanded"`
+	ExternalTokens   int64 `json:"external_tokens"`
+}
+
+// Add returns the sum of two reports
+func (r EnergyReport) Add(o EnergyReport) EnergyReport {
+	return EnergyReport{
+		NeuronUpdates:    r.NeuronUpdates + o.NeuronUpdates,
+		SpikesPropagated: r.SpikesPropagated + o.SpikesPropagated,
+		CircuitsTraced:   r.CircuitsTraced + o.CircuitsTraced,
+		BeamsExpanded:    r.BeamsExpanded + o.BeamsExpanded,
+		ExternalTokens:   r.ExternalTokens + o.ExternalTokens,
+	}
+}
+
+// Sub returns the work done between an earlier snapshot o and r
+func (r EnergyReport) Sub(o EnergyReport) EnergyReport {
+	return EnergyReport{
+		NeuronUpdates:    r.NeuronUpdates - o.NeuronUpdates,
+		SpikesPropagated: r.SpikesPropagated - o.SpikesPropagated,
+		CircuitsTraced:   r.CircuitsTraced - o.CircuitsTraced,
+		BeamsExpanded:    r.BeamsExpanded - o.BeamsExpanded,
+		ExternalTokens:   r.ExternalTokens - o.ExternalTokens,
+	}
+}
+
+func (r EnergyReport) String() string {
+	return fmt.Sprintf("%d neuron updates, %d spikes, %d circuits, %d beams, %d external tokens",
+		r.NeuronUpdates, r.SpikesPropagated, r.CircuitsTraced, r.BeamsExpanded, r.ExternalTokens)
+}
+
+// EnergyMeter accumulates work counts. It is safe for concurrent use, and a
+// nil *EnergyMeter counts nothing.
+type EnergyMeter struct {
+	neuronUpdates int64
+	spikes        int64
+}
+
+func (m *EnergyMeter) neuronUpdate() {
+	if m != nil {
+		atomic.AddInt64(&m.neuronUpdates, 1)
+	}
+}
+
+func (m *EnergyMeter) spikesPropagated(n int) {
+	if m != nil {
+		atomic.AddInt64(&m.spikes, int64(n))
+	}
+}
+
+// Snapshot returns the totals counted so far
+func (m *EnergyMeter) Snapshot() EnergyReport {
+	if m == nil {
+		return EnergyReport{}
+	}
+	return EnergyReport{
+		NeuronUpdates:    atomic.LoadInt64(&m.neuronUpdates),
+		SpikesPropagated: atomic.LoadInt64(&m.spikes),
+	}
+}
+
+// externalTokens approximates the tokens exchanged with an external
+// capability by whitespace-separated words in both directions
+func externalTokens(input, output string) int64 {
+	return int64(len(strings.Fields(input)) + len(strings.Fields(output)))
+}
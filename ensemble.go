@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Liquid brain ensembles: a single small reservoir's output layer is noisy,
+// since it depends on one random wiring. The "ensemble" model type runs
+// ensemble.size reservoirs, each wired from its own seed, on the same input
+// and combines their output layers, either by averaging activations
+// ("mean") or by letting each reservoir vote for its strongest output
+// ("vote"). The lead reservoir turns the combined readout into a response,
+// and keeps the response cache and input history. Small reservoirs are
+// cheap, so several of them cost little more than one large one.
+
+// EnsembleConfig controls liquid brain ensembles
+type EnsembleConfig struct {
+	Size    int    `json:"size"`    // reservoirs in the ensemble
+	Combine string `json:"combine"` // "mean" or "vote"
+	Seed    int64  `json:"seed"`    // reservoir k is wired from seed+k; 0 uses the config's seed, if any
+}
+
+// Ways to combine ensemble readouts
+const (
+	CombineMean = "mean"
+	CombineVote = "vote"
+)
+
+// Upper bound on reservoirs in an ensemble
+const maxEnsembleSize = 32
+
+func (c EnsembleConfig) validate() error {
+	if c.Size < 0 || c.Size > maxEnsembleSize {
+		return fmt.Errorf("ensemble size must be between 0 and %d", maxEnsembleSize)
+	}
+	switch c.Combine {
+	case "", CombineMean, CombineVote:
+		return nil
+	}
+	return fmt.Errorf("unknown ensemble combine %q (want %s or %s)", c.Combine, CombineMean, CombineVote)
+}
+
+// LiquidEnsemble runs several liquid brains on the same input
+type LiquidEnsemble struct {
+	members []*LiquidStateBrain // members[0] leads
+	combine string
+	config  *Config
+}
+
+// NewLiquidEnsemble builds ensemble.size reservoirs of the given size
+func NewLiquidEnsemble(size int, config *Config) (*LiquidEnsemble, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	settings := config.Ensemble
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	if settings.Size < 1 {
+		settings.Size = 1
+	}
+	if settings.Combine == "" {
+		settings.Combine = CombineMean
+	}
+
+	e := &LiquidEnsemble{combine: settings.Combine, config: config}
+	fmt.Printf("🧠 Building an ensemble of %d reservoirs (%s readout)\n", settings.Size, settings.Combine)
+	seed := settings.Seed
+	if seed == 0 {
+		seed = config.Seed
+	}
+	for k := 0; k < settings.Size; k++ {
+		// Each member gets its own seed so seeded members aren't wired alike
+		memberConfig := config
+		if seed != 0 {
+			memberConfig = new(Config)
+			*memberConfig = *config
+			memberConfig.Seed = seed + int64(k)
+		}
+		member := NewLiquidStateBrainWithConfig(size, memberConfig)
+		if member == nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to create ensemble reservoir %d of size %d", k, size)
+		}
+		e.members = append(e.members, member)
+	}
+	return e, nil
+}
+
+// Members returns the ensemble's reservoirs, the lead first
+func (e *LiquidEnsemble) Members() []*LiquidStateBrain {
+	return e.members
+}
+
+// ThinkWithOptions runs input through every reservoir at once and answers
+// from the combined readout
+func (e *LiquidEnsemble) ThinkWithOptions(input string, options GenerationOptions) (string, Confidence, EnergyReport) {
+	lead := e.members[0]
+	input = NormalizeInput(input)
+	var keywords map[string]float64
+	if lead.dataLoader != nil {
+		keywords = keywordWeights(lead.dataLoader.ExtractKeyphrases(input, inputKeyphrases))
+	}
+	lead.noteInput(input, keywords)
+
+	key := responseCacheKey(input, options, nil)
+	if cached, ok := lead.responses.Get(key); ok {
+		fmt.Printf("\n⚡ Cached ensemble response for '%s'\n", input)
+		return lead.reactToPatterns(cached.Output), cached.Confidence, EnergyReport{}
+	}
+	fmt.Printf("\n🧠 Ensemble of %d reservoirs processing: '%s'\n", len(e.members), input)
+
+	before := make([]EnergyReport, len(e.members))
+	readouts := make([]map[string]float64, len(e.members))
+	var wg sync.WaitGroup
+	for k, member := range e.members {
+		before[k] = member.energy.Snapshot()
+		wg.Add(1)
+		go func(k int, member *LiquidStateBrain) {
+			defer wg.Done()
+			call := member.profiler.Begin("think")
+			defer call.Done()
+			readouts[k] = member.perceive(context.Background(), input, keywords, options, call)
+		}(k, member)
+	}
+	wg.Wait()
+
+	activations := combineReadouts(readouts, e.combine)
+	fmt.Printf("🗳️  Ensemble readout (%s, %.0f%% agreement):\n", e.combine, readoutAgreement(readouts)*100)
+	for _, meaning := range sortedMeanings(activations) {
+		fmt.Printf("   %-15s %.2f\n", meaning, activations[meaning])
+	}
+
+	response, beams, confidence := lead.respondTo(activations, options)
+	fmt.Printf("🎯 Confidence: %s\n", confidence)
+
+	var energy EnergyReport
+	for k, member := range e.members {
+		energy = energy.Add(member.energy.Snapshot().Sub(before[k]))
+	}
+	energy.BeamsExpanded = beams
+	options.budget.generated(response)
+	if len(options.budget.Hit()) == 0 {
+		lead.responses.Put(key, CachedResponse{Output: response, Confidence: confidence})
+	}
+	return lead.reactToPatterns(response), confidence, energy
+}
+
+// combineReadouts merges the reservoirs' output layers: the mean activation
+// of each output, or the share of reservoirs whose strongest output it is
+func combineReadouts(readouts []map[string]float64, combine string) map[string]float64 {
+	combined := make(map[string]float64)
+	if len(readouts) == 0 {
+		return combined
+	}
+	share := 1 / float64(len(readouts))
+	for _, readout := range readouts {
+		if combine != CombineVote {
+			for meaning, activation := range readout {
+				combined[meaning] += activation * share
+			}
+			continue
+		}
+		for meaning := range readout {
+			combined[meaning] += 0
+		}
+		// Tied strongest outputs split the reservoir's vote
+		winners := strongestOutputs(readout)
+		for _, meaning := range winners {
+			combined[meaning] += share / float64(len(winners))
+		}
+	}
+	return combined
+}
+
+// strongestOutputs returns the outputs with the highest activation
+func strongestOutputs(readout map[string]float64) []string {
+	var winners []string
+	best := 0.0
+	for _, meaning := range sortedMeanings(readout) {
+		switch activation := readout[meaning]; {
+		case winners == nil || activation > best:
+			winners, best = []string{meaning}, activation
+		case activation == best:
+			winners = append(winners, meaning)
+		}
+	}
+	return winners
+}
+
+// readoutAgreement is the share of reservoirs whose strongest output is the
+// most common one
+func readoutAgreement(readouts []map[string]float64) float64 {
+	if len(readouts) == 0 {
+		return 0
+	}
+	votes := make(map[string]int)
+	most := 0
+	for _, readout := range readouts {
+		if winners := strongestOutputs(readout); len(winners) > 0 {
+			votes[winners[0]]++
+			most = max(most, votes[winners[0]])
+		}
+	}
+	return float64(most) / float64(len(readouts))
+}
+
+// sortedKeys returns the map's keys in order
+func sortedMeanings(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Respond implements Model
+func (e *LiquidEnsemble) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "ensemble model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	budget, err := startBudget(e.config.RequestLimits, req)
+	if err != nil {
+		return Response{}, err
+	}
+	output, confidence, energy := e.ThinkWithOptions(req.Input, budget.options(GenerationOptions{Seed: req.Seed}))
+	return Response{Output: output, Confidence: confidence, Energy: energy, LimitsHit: budget.Hit(), Patterns: e.members[0].history.Patterns()}, nil
+}
+
+// memberPath is where reservoir k of an ensemble saved at path is
+// checkpointed: brain.json becomes brain.0.json, brain.1.json and so on
+func memberPath(path string, k int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), k, ext)
+}
+
+// Save implements Model, checkpointing each reservoir next to path
+func (e *LiquidEnsemble) Save(path string) error {
+	for k, member := range e.members {
+		if err := member.Save(memberPath(path, k)); err != nil {
+			return fmt.Errorf("ensemble reservoir %d: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Load implements Model, restoring each reservoir saved by Save
+func (e *LiquidEnsemble) Load(path string) error {
+	for k, member := range e.members {
+		if err := member.Load(memberPath(path, k)); err != nil {
+			return fmt.Errorf("ensemble reservoir %d: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Close implements Model
+func (e *LiquidEnsemble) Close() error {
+	for _, member := range e.members {
+		member.Cleanup()
+	}
+	return nil
+}
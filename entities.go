@@ -0,0 +1,215 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entity tracking: within a conversation people say "my project" once and
+// "it" or "this project" afterwards. Without tracking, every such reference
+// reads as a new, unknown concept: "it uses Go" teaches nothing, and "what
+// does it use?" recalls nothing. An EntityTracker remembers the entities a
+// session mentioned, the user's possessions ("my project") and named
+// entities ("Acme"), most recent last, and resolves later references to
+// them: "it" to the most recent entity a sentence was about (its first
+// mention, so after "my project uses Go", "it" is the project, not Go), and
+// "this project" or "the project" to the most recent possession with that
+// noun. Resolution is lightweight, with no parsing and no gender or number
+// agreement. When the session API has a knowledge store, each session
+// tracks its entities, and the resolved input is what the store learns
+// from, recalls with and the generator answers, so references link to the
+// store's subjects. The explanation lists each link.
+
+// Entities remembered per conversation
+const maxTrackedEntities = 8
+
+// Entity is something a conversation mentioned
+type Entity struct {
+	Subject string `json:"subject"`         // knowledge store subject: "user's project", "acme"
+	Mention string `json:"mention"`         // how references are resolved: "my project", "Acme"
+	Topic   bool   `json:"topic,omitempty"` // a sentence was about it
+}
+
+// EntityLink is a reference resolved to an entity
+type EntityLink struct {
+	Mention string `json:"mention"` // as written: "it", "this project"
+	Subject string `json:"subject"`
+	Known   bool   `json:"known"` // the knowledge store has facts about the subject
+}
+
+// EntityTracker remembers a conversation's entities. The zero value is
+// ready to use.
+type EntityTracker struct {
+	Recent []Entity `json:"recent,omitempty"` // most recently mentioned last
+}
+
+var (
+	possessiveMention = regexp.MustCompile(`(?i)\b(?:my|our)\s+([a-z]+)`)
+	namedMention      = regexp.MustCompile(`\b[A-Z][A-Za-z0-9]*(?:[ \t]+[A-Z][A-Za-z0-9]*)*\b`)
+	reference         = regexp.MustCompile(`(?i)\b(?:(?:this|that|the)\s+([a-z]+)|it)\b`)
+)
+
+// notNames are capitalized words that start sentences rather than name
+// anything
+var notNames = map[string]bool{
+	"i": true, "a": true, "an": true, "the": true, "this": true, "that": true, "these": true, "those": true,
+	"it": true, "my": true, "our": true, "we": true, "you": true, "your": true, "he": true, "she": true, "they": true,
+	"what": true, "how": true, "who": true, "why": true, "when": true, "where": true, "which": true,
+	"do": true, "does": true, "did": true, "is": true, "are": true, "was": true, "can": true, "could": true,
+	"would": true, "should": true, "will": true, "hello": true, "hi": true, "hey": true, "yes": true, "no": true,
+	"please": true, "thanks": true, "thank": true, "and": true, "but": true, "so": true, "if": true,
+	"tell": true, "explain": true, "let": true, "ok": true, "okay": true, "sure": true, "also": true,
+	"then": true, "there": true, "here": true, "me": true,
+}
+
+// entityName strips the sentence-starting words from a capitalized run,
+// returning "" when nothing is left
+func entityName(run string) string {
+	words := strings.Fields(run)
+	for len(words) > 0 && notNames[strings.ToLower(words[0])] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// namedEntities returns the names mentioned in text
+func namedEntities(text string) []string {
+	var names []string
+	for _, run := range namedMention.FindAllString(text, -1) {
+		if name := entityName(run); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// entityEvent is an entity mention or a reference at a position in the input
+type entityEvent struct {
+	start, end int
+	entity     *Entity // nil for references
+	noun       string  // the noun of "this project"; "" for "it"
+}
+
+// sentenceOf numbers the sentences of text by position
+func sentenceOf(text string, pos int) int {
+	return len(sentenceEnd.FindAllStringIndex(text[:pos], -1))
+}
+
+var sentenceEnd = regexp.MustCompile(`[.!?\n]+`)
+
+// Resolve rewrites the references in input to the entities they refer to,
+// remembering the entities input mentions along the way, and returns the
+// rewritten input and the links made
+func (t *EntityTracker) Resolve(input string) (string, []EntityLink) {
+	var events []entityEvent
+	for _, m := range possessiveMention.FindAllStringSubmatchIndex(input, -1) {
+		noun := strings.ToLower(input[m[2]:m[3]])
+		// Facts name two-word possessions, as in "my side project uses Go"
+		rest := input[m[0]:]
+		if end := strings.IndexAny(rest, ".!?\n"); end >= 0 {
+			rest = rest[:end]
+		}
+		if f := possessiveFact.FindStringSubmatch(rest); f != nil {
+			noun = strings.ToLower(strings.Join(strings.Fields(f[1]), " "))
+		}
+		events = append(events, entityEvent{start: m[0], end: m[1], entity: &Entity{Subject: "user's " + noun, Mention: "my " + noun}})
+	}
+	for _, m := range namedMention.FindAllStringIndex(input, -1) {
+		if name := entityName(input[m[0]:m[1]]); name != "" {
+			events = append(events, entityEvent{start: m[1] - len(name), end: m[1], entity: &Entity{Subject: strings.ToLower(name), Mention: name}})
+		}
+	}
+	for _, m := range reference.FindAllStringSubmatchIndex(input, -1) {
+		event := entityEvent{start: m[0], end: m[1]}
+		if m[2] >= 0 {
+			event.noun = strings.ToLower(input[m[2]:m[3]])
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].start < events[j].start })
+
+	var resolved strings.Builder
+	var links []EntityLink
+	done, sentence := 0, -1
+	for _, event := range events {
+		if event.start < done {
+			continue // inside a mention already handled
+		}
+		// A sentence is about what it mentions first
+		topic := sentenceOf(input, event.start) != sentence
+		if event.entity != nil {
+			sentence = sentenceOf(input, event.start)
+			event.entity.Topic = topic
+			t.note(*event.entity)
+			continue
+		}
+		entity, ok := t.referent(event.noun)
+		if !ok {
+			continue
+		}
+		sentence = sentenceOf(input, event.start)
+		entity.Topic = entity.Topic || topic
+		resolved.WriteString(input[done:event.start])
+		resolved.WriteString(entity.Mention)
+		done = event.end
+		links = append(links, EntityLink{Mention: input[event.start:event.end], Subject: entity.Subject})
+		t.note(entity)
+	}
+	resolved.WriteString(input[done:])
+	return resolved.String(), links
+}
+
+// referent returns the entity a reference means: the most recent topic,
+// or failing that entity, for "it", and the most recent possession with
+// that noun for "this noun"
+func (t *EntityTracker) referent(noun string) (Entity, bool) {
+	if noun == "" {
+		for i := len(t.Recent) - 1; i >= 0; i-- {
+			if t.Recent[i].Topic {
+				return t.Recent[i], true
+			}
+		}
+		if len(t.Recent) > 0 {
+			return t.Recent[len(t.Recent)-1], true
+		}
+		return Entity{}, false
+	}
+	for i := len(t.Recent) - 1; i >= 0; i-- {
+		entity := t.Recent[i]
+		if possession, ok := strings.CutPrefix(entity.Subject, "user's "); ok {
+			if words := strings.Fields(possession); words[len(words)-1] == noun {
+				return entity, true
+			}
+		}
+	}
+	return Entity{}, false
+}
+
+// note makes entity the most recent one. Once a topic, an entity stays one.
+func (t *EntityTracker) note(entity Entity) {
+	for i, known := range t.Recent {
+		if known.Subject == entity.Subject {
+			entity.Topic = entity.Topic || known.Topic
+			t.Recent = append(t.Recent[:i], t.Recent[i+1:]...)
+			break
+		}
+	}
+	t.Recent = append(t.Recent, entity)
+	if len(t.Recent) > maxTrackedEntities {
+		t.Recent = t.Recent[len(t.Recent)-maxTrackedEntities:]
+	}
+}
+
+// Link marks the links whose subjects the store has facts about, as seen
+// from session
+func (s *KnowledgeStore) Link(session string, links []EntityLink) error {
+	for i := range links {
+		facts, err := s.Query(session, links[i].Subject, "")
+		if err != nil {
+			return err
+		}
+		links[i].Known = len(facts) > 0
+	}
+	return nil
+}
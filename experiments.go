@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-func RunXORExperiment() {
+func RunXORExperiment(run *TrackedRun, rng *rand.Rand) {
 	fmt.Println("=== XOR Gate Discovery Experiment ===")
 	fmt.Println("Evolving a circuit to implement XOR logic...")
 	
@@ -17,8 +17,8 @@ func RunXORExperiment() {
 		{[]bool{true, true}, false},
 	}
 	
-	evolution := NewEvolution(50, testCases)
-	logger := NewEvolutionLogger(evolution)
+	evolution := NewEvolutionWithRand(50, testCases, rng)
+	logger := NewEvolutionLogger(evolution).Track(run, "xor")
 	
 	for gen := 0; gen < 100; gen++ {
 		evolution.RunGeneration()
@@ -33,7 +33,7 @@ func RunXORExperiment() {
 	logger.PrintFinalReport()
 }
 
-func RunParityExperiment() {
+func RunParityExperiment(run *TrackedRun, rng *rand.Rand) {
 	fmt.Println("\n=== 3-bit Parity Checker Discovery ===")
 	fmt.Println("Evolving a circuit to check if number of true inputs is odd...")
 	
@@ -48,8 +48,8 @@ func RunParityExperiment() {
 		{[]bool{true, true, true}, true},
 	}
 	
-	evolution := NewEvolution(100, testCases)
-	logger := NewEvolutionLogger(evolution)
+	evolution := NewEvolutionWithRand(100, testCases, rng)
+	logger := NewEvolutionLogger(evolution).Track(run, "parity")
 	
 	for gen := 0; gen < 200; gen++ {
 		evolution.RunGeneration()
@@ -64,7 +64,7 @@ func RunParityExperiment() {
 	logger.PrintFinalReport()
 }
 
-func RunMajorityExperiment() {
+func RunMajorityExperiment(run *TrackedRun, rng *rand.Rand) {
 	fmt.Println("\n=== Majority Vote Circuit Discovery ===")
 	fmt.Println("Evolving a circuit to output true if majority of inputs are true...")
 	
@@ -79,8 +79,8 @@ func RunMajorityExperiment() {
 		{[]bool{true, true, true}, true},
 	}
 	
-	evolution := NewEvolution(100, testCases)
-	logger := NewEvolutionLogger(evolution)
+	evolution := NewEvolutionWithRand(100, testCases, rng)
+	logger := NewEvolutionLogger(evolution).Track(run, "majority")
 	
 	for gen := 0; gen < 200; gen++ {
 		evolution.RunGeneration()
@@ -95,7 +95,7 @@ func RunMajorityExperiment() {
 	logger.PrintFinalReport()
 }
 
-func RunSelfDiscoveryExperiment() {
+func RunSelfDiscoveryExperiment(run *TrackedRun, rng *rand.Rand) {
 	fmt.Println("\n=== Self-Discovery Experiment ===")
 	fmt.Println("Circuit discovers its own function from random test cases...")
 	
@@ -113,7 +113,7 @@ func RunSelfDiscoveryExperiment() {
 	for i := 0; i < numTests; i++ {
 		inputs := make([]bool, numInputs)
 		for j := 0; j < numInputs; j++ {
-			inputs[j] = rand.Float32() < 0.5
+			inputs[j] = rng.Float32() < 0.5
 		}
 		testCases[i] = TestCase{
 			Input:    inputs,
@@ -126,8 +126,8 @@ func RunSelfDiscoveryExperiment() {
 		fmt.Printf("  %v -> %v\n", tc.Input, tc.Expected)
 	}
 	
-	evolution := NewEvolution(150, testCases)
-	logger := NewEvolutionLogger(evolution)
+	evolution := NewEvolutionWithRand(150, testCases, rng)
+	logger := NewEvolutionLogger(evolution).Track(run, "self_discovery")
 	
 	for gen := 0; gen < 300; gen++ {
 		evolution.RunGeneration()
@@ -175,17 +175,27 @@ func RunSelfDiscoveryExperiment() {
 }
 
 func RunAllExperiments() {
-	// Go 1.20+ uses automatic seeding
+	// Seed the config to evolve the same circuits every run
+	config := DefaultConfig()
+	rng := newRand(config.Seed)
 	
-	experiments := []func(){
+	experiments := []func(run *TrackedRun, rng *rand.Rand){
 		RunXORExperiment,
 		RunParityExperiment,
 		RunMajorityExperiment,
 		RunSelfDiscoveryExperiment,
 	}
 	
+	run, err := StartRun(config.Runs, "evolution", nil, map[string]string{
+		"experiments": "xor,parity,majority,self_discovery",
+		"seed":        fmt.Sprint(config.Seed),
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Warning: run won't be tracked: %v\n", err)
+	}
 	for _, exp := range experiments {
-		exp()
+		exp(run, rng)
 		fmt.Println("\n" + strings.Repeat("=", 50) + "\n")
 	}
+	run.Finish(nil)
 }
\ No newline at end of file
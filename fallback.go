@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Fallback chain: when the liquid brain isn't confident about a general
+// query, the orchestrator tries a configurable list of capabilities in
+// order (by default retrieval QA, then an external LLM, then a canned
+// clarification question) and keeps the first one that answers. Every hop,
+// including failures and unknown capabilities, is recorded in the decision
+// trace.
+
+// FallbackConfig controls the orchestrator's fallback chain
+type FallbackConfig struct {
+	Threshold             float64  `json:"threshold"`              // confidence below which the chain runs
+	Chain                 []string `json:"chain"`                  // capability names, tried in order
+	ClarificationQuestion string   `json:"clarification_question"` // answer of the "clarify" capability
+}
+
+func (c FallbackConfig) validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("fallback threshold must not be negative")
+	}
+	for _, name := range c.Chain {
+		if name == "" {
+			return fmt.Errorf("fallback chain entries must not be empty")
+		}
+	}
+	return nil
+}
+
+// ClarifyCapability always answers with question
+func ClarifyCapability(question string) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, input string) (string, error) {
+		if question == "" {
+			return "", fmt.Errorf("no clarification question configured")
+		}
+		return question, nil
+	}
+}
+
+// runFallbackChain tries each capability of the chain that policy permits
+// until one answers. ok is false when every hop failed.
+func (go_ *GenesisOrchestrator) runFallbackChain(ctx context.Context, input string, confidence Confidence, policy *CapabilityPolicy) (output string, decisions []Decision, ok bool) {
+	go_.mu.RLock()
+	chain := go_.fallbackChain
+	go_.mu.RUnlock()
+
+	path := []string{"liquid_brain"}
+	for i, name := range chain {
+		go_.mu.RLock()
+		neuron := go_.neurons[name]
+		go_.mu.RUnlock()
+
+		path = append(path, name)
+		decision := Decision{
+			Input:     input,
+			Path:      append([]string{}, path...),
+			Timestamp: time.Now(),
+		}
+		if neuron == nil {
+			fmt.Printf("   → Fallback %d: %s is not registered, skipping\n", i+1, name)
+			decision.Reasoning = fmt.Sprintf("Fallback hop %d: no capability %q", i+1, name)
+			decisions = append(decisions, decision)
+			continue
+		}
+		if !policy.Permits(name) {
+			fmt.Printf("   → Fallback %d: %s denied by policy %q, skipping\n", i+1, name, policy.Name)
+			decision.Reasoning = fmt.Sprintf("Fallback hop %d: %s denied by policy %q", i+1, name, policy.Name)
+			decisions = append(decisions, decision)
+			continue
+		}
+
+		fmt.Printf("   → Fallback %d: trying %s\n", i+1, name)
+		result, violations, err := neuron.call(ctx, input)
+		decision.Energy = EnergyReport{ExternalTokens: externalTokens(input, result)}
+		decision.Sandbox = violations
+		if err != nil {
+			decision.Reasoning = fmt.Sprintf("Fallback hop %d: %s failed: %v", i+1, name, err)
+			decisions = append(decisions, decision)
+			continue
+		}
+		decision.Reasoning = fmt.Sprintf("Fallback hop %d: low confidence (%.2f), answered by %s", i+1, confidence.Score, name)
+		decision.Output = result
+		return result, append(decisions, decision), true
+	}
+	return "", decisions, false
+}
+
+// SetFallbackChain sets the capabilities tried, in order, when the brain
+// isn't confident; an empty chain answers from the brain's understanding
+func (go_ *GenesisOrchestrator) SetFallbackChain(chain ...string) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	go_.fallbackChain = append([]string{}, chain...)
+}
@@ -24,6 +24,7 @@ type BaseGate struct {
 	inputs   []Gate
 	output   chan Signal
 	function func([]Signal) Signal
+	rng      *rand.Rand // mutation choices
 	mu       sync.RWMutex
 }
 
@@ -33,6 +34,7 @@ func NewBaseGate(id string, fn func([]Signal) Signal) *BaseGate {
 		inputs:   make([]Gate, 0),
 		output:   make(chan Signal, 100),
 		function: fn,
+		rng:      globalRand,
 	}
 }
 
@@ -115,19 +117,19 @@ func (g *BaseGate) ComplexityWithVisited(visited map[string]bool) int {
 func (g *BaseGate) Mutate() Gate {
 	mutations := []func(){
 		func() {
-			if len(g.inputs) > 0 && rand.Float32() < 0.3 {
-				idx := rand.Intn(len(g.inputs))
+			if len(g.inputs) > 0 && g.rng.Float32() < 0.3 {
+				idx := g.rng.Intn(len(g.inputs))
 				g.inputs = append(g.inputs[:idx], g.inputs[idx+1:]...)
 			}
 		},
 		func() {
-			if rand.Float32() < 0.2 {
-				g.function = RandomFunction()
+			if g.rng.Float32() < 0.2 {
+				g.function = randomFunction(g.rng)
 			}
 		},
 	}
 	
-	mutation := mutations[rand.Intn(len(mutations))]
+	mutation := mutations[g.rng.Intn(len(mutations))]
 	mutation()
 	
 	return g
@@ -138,10 +140,11 @@ func (g *BaseGate) Clone() Gate {
 	defer g.mu.RUnlock()
 	
 	clone := &BaseGate{
-		id:       fmt.Sprintf("%s_clone_%d", g.id, rand.Int()),
+		id:       fmt.Sprintf("%s_clone_%d", g.id, g.rng.Int()),
 		inputs:   make([]Gate, len(g.inputs)),
 		output:   make(chan Signal, 100),
 		function: g.function,
+		rng:      g.rng,
 	}
 	copy(clone.inputs, g.inputs)
 	return clone
@@ -161,6 +164,11 @@ func extractBools(signals []Signal) []bool {
 }
 
 func RandomFunction() func([]Signal) Signal {
+	return randomFunction(globalRand)
+}
+
+// randomFunction picks a gate function with rng
+func randomFunction(rng *rand.Rand) func([]Signal) Signal {
 	functions := []func([]Signal) Signal{
 		func(inputs []Signal) Signal {
 			if len(inputs) == 0 {
@@ -236,7 +244,7 @@ func RandomFunction() func([]Signal) Signal {
 		},
 	}
 	
-	return functions[rand.Intn(len(functions))]
+	return functions[rng.Intn(len(functions))]
 }
 
 type AdaptiveGate struct {
@@ -312,8 +320,8 @@ func (ag *AdaptiveGate) ComplexityWithVisited(visited map[string]bool) int {
 func (ag *AdaptiveGate) Mutate() Gate {
 	ag.BaseGate.Mutate()
 	
-	if rand.Float32() < 0.3 {
-		ag.memorySize = ag.memorySize + rand.Intn(5) - 2
+	if ag.rng.Float32() < 0.3 {
+		ag.memorySize = ag.memorySize + ag.rng.Intn(5) - 2
 		if ag.memorySize < 1 {
 			ag.memorySize = 1
 		}
@@ -329,26 +337,37 @@ type EvolvingCircuit struct {
 	gates      []Gate
 	fitness    float64
 	generation int
+	rng        *rand.Rand // construction and mutation choices, shared with the gates
 	mu         sync.RWMutex
 }
 
 func NewEvolvingCircuit(initialGates int) *EvolvingCircuit {
+	return newEvolvingCircuit(initialGates, globalRand)
+}
+
+// newEvolvingCircuit builds a random circuit with rng
+func newEvolvingCircuit(initialGates int, rng *rand.Rand) *EvolvingCircuit {
 	ec := &EvolvingCircuit{
 		gates:      make([]Gate, initialGates),
 		generation: 0,
+		rng:        rng,
 	}
 	
 	for i := 0; i < initialGates; i++ {
-		if rand.Float32() < 0.5 {
-			ec.gates[i] = NewBaseGate(fmt.Sprintf("gate_%d", i), RandomFunction())
+		if rng.Float32() < 0.5 {
+			gate := NewBaseGate(fmt.Sprintf("gate_%d", i), randomFunction(rng))
+			gate.rng = rng
+			ec.gates[i] = gate
 		} else {
-			ec.gates[i] = NewAdaptiveGate(fmt.Sprintf("adaptive_%d", i))
+			gate := NewAdaptiveGate(fmt.Sprintf("adaptive_%d", i))
+			gate.rng = rng
+			ec.gates[i] = gate
 		}
 	}
 	
 	for i := 0; i < initialGates*2; i++ {
-		from := rand.Intn(len(ec.gates))
-		to := rand.Intn(len(ec.gates))
+		from := rng.Intn(len(ec.gates))
+		to := rng.Intn(len(ec.gates))
 		if from != to {
 			ec.gates[to].Connect(ec.gates[from])
 		}
@@ -389,36 +408,38 @@ func (ec *EvolvingCircuit) Mutate() *EvolvingCircuit {
 	mutated := &EvolvingCircuit{
 		gates:      make([]Gate, len(ec.gates)),
 		generation: ec.generation + 1,
+		rng:        ec.rng,
 	}
 	
 	for i, gate := range ec.gates {
-		if rand.Float32() < 0.8 {
+		if ec.rng.Float32() < 0.8 {
 			mutated.gates[i] = gate.Clone()
 		} else {
 			mutated.gates[i] = gate.Clone().Mutate()
 		}
 	}
 	
-	if rand.Float32() < 0.3 && len(mutated.gates) < 20 {
-		newGate := NewBaseGate(fmt.Sprintf("new_%d", ec.generation), RandomFunction())
+	if ec.rng.Float32() < 0.3 && len(mutated.gates) < 20 {
+		newGate := NewBaseGate(fmt.Sprintf("new_%d", ec.generation), randomFunction(ec.rng))
+		newGate.rng = ec.rng
 		mutated.gates = append(mutated.gates, newGate)
 		
-		for i := 0; i < rand.Intn(3)+1; i++ {
-			target := rand.Intn(len(mutated.gates))
+		for i := 0; i < ec.rng.Intn(3)+1; i++ {
+			target := ec.rng.Intn(len(mutated.gates))
 			mutated.gates[target].Connect(newGate)
 		}
 	}
 	
-	if rand.Float32() < 0.1 && len(mutated.gates) > 3 {
-		idx := rand.Intn(len(mutated.gates))
+	if ec.rng.Float32() < 0.1 && len(mutated.gates) > 3 {
+		idx := ec.rng.Intn(len(mutated.gates))
 		mutated.gates = append(mutated.gates[:idx], mutated.gates[idx+1:]...)
 	}
 	
-	for i := 0; i < rand.Intn(5); i++ {
-		from := rand.Intn(len(mutated.gates))
-		to := rand.Intn(len(mutated.gates))
+	for i := 0; i < ec.rng.Intn(5); i++ {
+		from := ec.rng.Intn(len(mutated.gates))
+		to := ec.rng.Intn(len(mutated.gates))
 		if from != to {
-			if rand.Float32() < 0.5 {
+			if ec.rng.Float32() < 0.5 {
 				mutated.gates[to].Connect(mutated.gates[from])
 			} else {
 				mutated.gates[to].Disconnect(mutated.gates[from])
@@ -440,17 +461,25 @@ type Evolution struct {
 	bestCircuit  *EvolvingCircuit
 	bestFitness  float64
 	logFrequency int
+	rng          *rand.Rand // population, selection and mutation choices
 }
 
 func NewEvolution(populationSize int, testCases []TestCase) *Evolution {
+	return NewEvolutionWithRand(populationSize, testCases, globalRand)
+}
+
+// NewEvolutionWithRand creates an evolution whose random choices come from
+// rng, so a seeded rng evolves the same circuits every run
+func NewEvolutionWithRand(populationSize int, testCases []TestCase, rng *rand.Rand) *Evolution {
 	e := &Evolution{
 		population:   make([]*EvolvingCircuit, populationSize),
 		testCases:    testCases,
 		logFrequency: 10,
+		rng:          rng,
 	}
 	
 	for i := 0; i < populationSize; i++ {
-		e.population[i] = NewEvolvingCircuit(rand.Intn(5) + 3)
+		e.population[i] = newEvolvingCircuit(rng.Intn(5) + 3, rng)
 	}
 	
 	return e
@@ -459,7 +488,7 @@ func NewEvolution(populationSize int, testCases []TestCase) *Evolution {
 func (e *Evolution) RunGeneration() {
 	for _, circuit := range e.population {
 		fitness := circuit.Evaluate(e.testCases)
-		if fitness > e.bestFitness {
+		if e.bestCircuit == nil || fitness > e.bestFitness {
 			e.bestFitness = fitness
 			e.bestCircuit = circuit
 		}
@@ -483,7 +512,7 @@ func (e *Evolution) RunGeneration() {
 func (e *Evolution) selectParent() *EvolvingCircuit {
 	tournament := make([]*EvolvingCircuit, 3)
 	for i := 0; i < 3; i++ {
-		tournament[i] = e.population[rand.Intn(len(e.population))]
+		tournament[i] = e.population[e.rng.Intn(len(e.population))]
 	}
 	
 	best := tournament[0]
@@ -512,12 +541,81 @@ func (e *Evolution) Run(generations int) {
 }
 
 func main() {
+	// Clean up whatever the chosen mode registers on Ctrl-C or termination
+	ProcessShutdown().Listen()
+	defer ProcessShutdown().Shutdown()
+
 	// Check command line arguments
 	if len(os.Args) > 1 && os.Args[1] == "train" {
 		// Run training mode
 		TrainMain()
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		// Export internals as NumPy arrays
+		ExportMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dataset" {
+		// Download curated public corpora
+		DatasetMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		// Serve chat sessions, embeddings and summaries
+		ServeMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		// Re-run a recorded serving session
+		ReplayMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay-flow" {
+		// Re-run recorded parallel orchestrator flows
+		ReplayFlowMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		// Preview an orchestration's capability calls and cost
+		PlanMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "golden" {
+		// Record or check golden reasoning traces
+		GoldenMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		// Verify a reasoning audit log
+		AuditMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		// Report drift between two model checkpoints
+		DiffMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "embeddings" {
+		// Serve OpenAI-compatible embeddings
+		EmbeddingsMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		// Diagnose config, datasets, memory, capabilities and inference
+		DoctorMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		// Benchmark reservoir memory and separability, or scripted dialogues
+		BenchMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "runs" {
+		// List, show or compare tracked runs
+		RunsMain(os.Args[2:])
+		return
+	}
 	
 	// Otherwise run demos
 	fmt.Println("Genesis LLM - Choose a mode:")
@@ -530,7 +628,7 @@ func main() {
 	fmt.Println("7. Orchestration demo")
 	fmt.Println("8. Parallel orchestration demo")
 	fmt.Println("9. Scaling behavior demo")
-	fmt.Println("10. Test actual responses")
+	fmt.Println("10. Conversation benchmark")
 	fmt.Print("\nSelection (default=3): ")
 	
 	var selection string
@@ -554,7 +652,7 @@ func main() {
 	case "9":
 		ShowScalingBehavior()
 	case "10":
-		TestActualResponses()
+		ConversationBenchMain([]string{"benchmarks/dialogues.yaml"})
 	default:
 		RunAutoDemo()
 	}
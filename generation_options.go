@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Per-request generation limits: API callers can cap a response's length in
+// tokens (words) and give stop sequences that end it. A beam ending in a
+// stop sequence completes, and the sequence itself is left out of the
+// response. Explanations report why generation finished. Callers can also
+// weight words, like logit bias: each candidate's score is multiplied by
+// its word's bias, so domain terms can be boosted, and a bias of 0 bans a
+// word outright.
+//
+// Beam search makes one random choice: the order of equally scored
+// candidate words, which decides which of them survive. Each call draws it
+// from an RNG seeded by the request's seed, or by a fresh seed from the
+// global RNG, and reports the seed in the explanation so a weird response
+// can be reproduced exactly by sending the same input with that seed.
+
+// Request limits
+const (
+	maxRequestTokens = 256 // caps GenerationOptions.MaxTokens
+	maxWordBiases    = 300 // words a request may weight
+	maxWordBias      = 100 // largest multiplier
+)
+
+// Finish reasons reported in explanations
+const (
+	finishStop   = "stop"   // natural end or a stop sequence
+	finishLength = "length" // the token limit
+)
+
+// GenerationOptions are per-request generation limits. The zero value uses
+// the generator's defaults.
+type GenerationOptions struct {
+	MaxTokens int                `json:"max_tokens,omitempty"` // 0 uses the generator's limit
+	Stop      []string           `json:"stop,omitempty"`       // word sequences that end the response
+	WordBias  map[string]float64 `json:"word_bias,omitempty"`  // word -> score multiplier; 0 bans the word
+	// Diagnostics returns the beam search tree in the explanation
+	Diagnostics bool `json:"diagnostics,omitempty"`
+	// N returns the N best distinct responses in the explanation
+	N int `json:"n,omitempty"`
+	// Seed seeds the call's random choices; nil draws a fresh seed
+	Seed *int64 `json:"seed,omitempty"`
+	// budget is the request's resource limits; nil is unlimited
+	budget *requestBudget
+	// session is the conversation whose learned facts the generator recalls
+	session string
+	// query is the text retrieval, answers, facts and language look up; empty
+	// uses the generator's input
+	query string
+	// thoughts sees each of Understand's thoughts as it happens; nil ignores them
+	thoughts func(ThoughtTrace)
+}
+
+func (o GenerationOptions) validate() error {
+	if o.MaxTokens < 0 || o.MaxTokens > maxRequestTokens {
+		return fmt.Errorf("max_tokens must be between 0 and %d", maxRequestTokens)
+	}
+	if o.N < 0 || o.N > maxNBest {
+		return fmt.Errorf("n must be between 0 and %d", maxNBest)
+	}
+	if len(o.Stop) > 4 {
+		return fmt.Errorf("at most 4 stop sequences are allowed")
+	}
+	for _, stop := range o.Stop {
+		if strings.TrimSpace(stop) == "" {
+			return fmt.Errorf("stop sequences must not be empty")
+		}
+	}
+	if len(o.WordBias) > maxWordBiases {
+		return fmt.Errorf("at most %d words may be biased", maxWordBiases)
+	}
+	for word, bias := range o.WordBias {
+		if len(strings.Fields(word)) != 1 {
+			return fmt.Errorf("word_bias keys must be single words, got %q", word)
+		}
+		// Written to also reject NaN
+		if !(bias >= 0 && bias <= maxWordBias) {
+			return fmt.Errorf("word_bias for %q must be between 0 and %d", word, maxWordBias)
+		}
+	}
+	return nil
+}
+
+// wordBiases returns the biases keyed by lowercase word
+func (o GenerationOptions) wordBiases() map[string]float64 {
+	if len(o.WordBias) == 0 {
+		return nil
+	}
+	biases := make(map[string]float64, len(o.WordBias))
+	for word, bias := range o.WordBias {
+		biases[strings.ToLower(strings.TrimSpace(word))] = bias
+	}
+	return biases
+}
+
+// stopWords splits the stop sequences into lowercase words
+func (o GenerationOptions) stopWords() [][]string {
+	sequences := make([][]string, 0, len(o.Stop))
+	for _, stop := range o.Stop {
+		sequences = append(sequences, strings.Fields(strings.ToLower(stop)))
+	}
+	return sequences
+}
+
+// GenerateWithOptions generates a response within the request's limits
+func (gen *ResponseGenerator) GenerateWithOptions(input string, activeConcepts []string, options GenerationOptions) (string, *Explanation) {
+	return gen.generate(nil, input, activeConcepts, options)
+}
+
+// SetSeed makes the seeds drawn for calls that don't set their own
+// reproducible, starting from seed; 0 draws them from the global math/rand
+func (gen *ResponseGenerator) SetSeed(seed int64) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.seeds = newRand(seed)
+}
+
+// setOptions applies options to a Generate call's copy of the generator,
+// see begin. Callers hold gen.mu.
+func (gen *ResponseGenerator) setOptions(options GenerationOptions) {
+	gen.tokenLimit = options.MaxTokens
+	gen.stops = options.stopWords()
+	gen.wordBias = options.wordBiases()
+	gen.nBest = options.N
+	gen.factSession = options.session
+	gen.query = options.query
+	gen.seed = gen.seeds.Int63()
+	if options.Seed != nil {
+		gen.seed = *options.Seed
+	}
+	gen.rng = rand.New(rand.NewSource(gen.seed))
+	gen.tree = nil
+	if options.Diagnostics {
+		gen.tree = &BeamTree{Chosen: -1}
+	}
+}
+
+// banned reports whether the current call bans word
+func (gen *ResponseGenerator) banned(word string) bool {
+	if len(gen.wordBias) == 0 {
+		return false
+	}
+	bias, ok := gen.wordBias[strings.ToLower(strings.Trim(word, ".,!?;:"))]
+	return ok && bias == 0
+}
+
+// bannedIn reports whether text contains a banned word
+func (gen *ResponseGenerator) bannedIn(text string) bool {
+	for _, word := range strings.Fields(text) {
+		if gen.banned(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxTokens returns the current call's token limit
+func (gen *ResponseGenerator) maxTokens() int {
+	if gen.tokenLimit > 0 {
+		return gen.tokenLimit
+	}
+	return gen.maxLength
+}
+
+// stopMatch returns the length of the stop sequence words end with, or 0
+func (gen *ResponseGenerator) stopMatch(words []string) int {
+	for _, stop := range gen.stops {
+		if len(stop) == 0 || len(stop) > len(words) {
+			continue
+		}
+		tail := words[len(words)-len(stop):]
+		matched := true
+		for i, word := range stop {
+			if strings.ToLower(tail[i]) != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(stop)
+		}
+	}
+	return 0
+}
+
+// limitWords drops banned words and cuts words before the first stop
+// sequence and at the token limit, returning the words kept and the finish
+// reason
+func (gen *ResponseGenerator) limitWords(words []string) ([]string, string) {
+	if len(gen.wordBias) > 0 {
+		allowed := make([]string, 0, len(words))
+		for _, word := range words {
+			if !gen.banned(word) {
+				allowed = append(allowed, word)
+			}
+		}
+		words = allowed
+	}
+	for end := 1; end <= len(words); end++ {
+		if n := gen.stopMatch(words[:end]); n > 0 {
+			return words[:end-n], finishStop
+		}
+	}
+	if len(words) > gen.maxTokens() {
+		return words[:gen.maxTokens()], finishLength
+	}
+	return words, finishStop
+}
+
+// breakTies orders equally scored candidates by the call's RNG, so the
+// choice among them depends on the seed rather than map iteration order.
+// candidates must be sorted by descending score.
+func (gen *ResponseGenerator) breakTies(candidates []wordCandidate) {
+	if gen.rng == nil {
+		return
+	}
+	for start := 0; start < len(candidates); {
+		end := start + 1
+		for end < len(candidates) && candidates[end].score == candidates[start].score {
+			end++
+		}
+		if tied := candidates[start:end]; len(tied) > 1 {
+			sort.Slice(tied, func(i, j int) bool { return tied[i].word < tied[j].word })
+			gen.rng.Shuffle(len(tied), func(i, j int) { tied[i], tied[j] = tied[j], tied[i] })
+		}
+		start = end
+	}
+}
+
+// seedUsed returns the current call's seed for its explanation
+func (gen *ResponseGenerator) seedUsed() *int64 {
+	if gen.rng == nil {
+		return nil
+	}
+	seed := gen.seed
+	return &seed
+}
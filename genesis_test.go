@@ -1,10 +1,32 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TestDatasetLoader tests the dataset loading functionality
@@ -43,6 +65,25 @@ func TestDatasetLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("Embedded Starter Corpus", func(t *testing.T) {
+		configNone := config
+		configNone.DatasetPaths = []string{"nonexistent.txt"}
+
+		loader, err := NewDatasetLoader(configNone)
+		if err != nil {
+			t.Fatalf("Expected fallback to starter corpus, got: %v", err)
+		}
+		docs := loader.GetDocuments()
+		if len(docs) != 1 || docs[0].Path != starterCorpusPath {
+			t.Errorf("Expected the starter corpus document, got %d documents", len(docs))
+		}
+
+		configNone.DisableStarterCorpus = true
+		if _, err := NewDatasetLoader(configNone); err == nil {
+			t.Error("Expected an error with the starter corpus disabled")
+		}
+	})
+
 	t.Run("Missing Files Handling", func(t *testing.T) {
 		configMissing := config
 		configMissing.DatasetPaths = []string{"nonexistent.txt", testFile}
@@ -209,7 +250,7 @@ func TestTransparentLLM(t *testing.T) {
 		}
 		defer llm.Cleanup()
 
-		if len(llm.concepts) == 0 {
+		if llm.concepts.Len() == 0 {
 			t.Error("LLM should have some concepts")
 		}
 	})
@@ -582,6 +623,8916 @@ func BenchmarkLiquidBrain(b *testing.B) {
 	}
 }
 
+// BenchmarkLiquidBrainSustainedThink measures GC pressure while the reservoir
+// is kept busy with back-to-back Think calls
+func BenchmarkLiquidBrainSustainedThink(b *testing.B) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 2000
+	brain := NewLiquidStateBrainWithConfig(10, config)
+	if brain == nil {
+		b.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		brain.Think("hello help me understand this code error")
+	}
+	b.StopTimer()
+
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+}
+
+// BenchmarkSpikeBatch measures pooled spike delivery without synaptic delays
+func BenchmarkSpikeBatch(b *testing.B) {
+	targets := make([]*LiquidNeuron, spikeBatchCapacity)
+	for i := range targets {
+		targets[i] = &LiquidNeuron{}
+		targets[i].state.Store(0.0)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch := acquireSpikeBatch()
+		for _, t := range targets {
+			batch.events = append(batch.events, spikeEvent{target: t, strength: 0.2})
+		}
+		batch.deliver(RealClock)
+		releaseSpikeBatch(batch)
+	}
+}
+
+// BenchmarkTransparentLLMConcurrent measures Understand throughput with 32
+// callers hitting the concept map at once
+func BenchmarkTransparentLLMConcurrent(b *testing.B) {
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 100
+	llm := NewTransparentLLMWithConfig(config)
+	if llm == nil {
+		b.Fatal("Failed to create LLM")
+	}
+	defer llm.Cleanup()
+
+	b.SetParallelism(max(1, 32/runtime.GOMAXPROCS(0)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, thoughts := llm.Understand("benchmark concurrent test")
+			for range thoughts {
+			}
+		}
+	})
+}
+
+// TestStageProfiler tests latency percentile recording
+func TestStageProfiler(t *testing.T) {
+	t.Run("Nil Profiler", func(t *testing.T) {
+		var p *StageProfiler
+		call := p.Begin("think")
+		call.Mark("injection")
+		call.Done()
+		if len(p.Percentiles()) != 0 {
+			t.Error("Nil profiler should record nothing")
+		}
+	})
+
+	t.Run("Percentiles", func(t *testing.T) {
+		p := NewStageProfiler(100)
+		for i := 1; i <= 100; i++ {
+			p.Record("understand", "parsing", time.Duration(i)*time.Millisecond)
+		}
+		stats := p.Percentiles()["understand"]
+		if len(stats) != 1 || stats[0].Stage != "parsing" {
+			t.Fatalf("Unexpected stats: %+v", stats)
+		}
+		if stats[0].P50Ms != 50 || stats[0].P99Ms != 99 || stats[0].MaxMs != 100 {
+			t.Errorf("Unexpected percentiles: %+v", stats[0])
+		}
+
+		// Nearest rank: p99 of 10 samples is the largest, p50 of 3 the second
+		sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		if got := percentile(sorted, 0.99); got != 10 {
+			t.Errorf("Expected p99 of 10 samples to be the 10th, got %v", got)
+		}
+		if got := percentile(sorted[:3], 0.5); got != 2 {
+			t.Errorf("Expected p50 of 3 samples to be the 2nd, got %v", got)
+		}
+		if got := percentile(sorted, 0); got != 1 {
+			t.Errorf("Expected p0 to clamp to the smallest, got %v", got)
+		}
+	})
+
+	t.Run("Metrics Endpoint", func(t *testing.T) {
+		p := NewStageProfiler(10)
+		call := p.Begin("think")
+		call.Mark("injection")
+		call.Done()
+
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if !strings.Contains(rec.Body.String(), `"injection"`) {
+			t.Errorf("Metrics output missing stage: %s", rec.Body.String())
+		}
+	})
+}
+
+// TestActivityTracker tests completion signaling for in-flight work
+func TestActivityTracker(t *testing.T) {
+	tracker := newActivityTracker()
+	if !tracker.WaitQuiet(time.Millisecond, 100*time.Millisecond) {
+		t.Error("Idle tracker should be quiet immediately")
+	}
+
+	tracker.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			tracker.Done()
+		}()
+	}
+
+	start := time.Now()
+	if !tracker.WaitQuiet(time.Millisecond, time.Second) {
+		t.Fatal("Tracker should become quiet once work finishes")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("WaitQuiet took %v, expected it to return on completion", elapsed)
+	}
+
+	tracker.Add(1) // never finishes
+	if tracker.WaitQuiet(time.Millisecond, 20*time.Millisecond) {
+		t.Error("WaitQuiet should time out while work is pending")
+	}
+}
+
+// TestNpyExport tests the NumPy array writer
+func TestNpyExport(t *testing.T) {
+	t.Run("Float Matrix", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteNpyFloat64(&buf, []int{2, 3}, []float64{1, 2, 3, 4, 5, 6}); err != nil {
+			t.Fatalf("Failed to write npy: %v", err)
+		}
+		data := buf.Bytes()
+		if !bytes.HasPrefix(data, []byte("\x93NUMPY\x01\x00")) {
+			t.Fatal("Missing npy magic")
+		}
+		headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+		if (10+headerLen)%64 != 0 {
+			t.Errorf("Header not 64-byte aligned: %d", 10+headerLen)
+		}
+		header := string(data[10 : 10+headerLen])
+		if !strings.Contains(header, "'shape': (2, 3)") || !strings.Contains(header, "'<f8'") {
+			t.Errorf("Unexpected header: %q", header)
+		}
+		if len(data)-10-headerLen != 6*8 {
+			t.Errorf("Expected 48 data bytes, got %d", len(data)-10-headerLen)
+		}
+	})
+
+	t.Run("Shape Mismatch", func(t *testing.T) {
+		if err := WriteNpyInt64(io.Discard, []int{3}, []int64{1, 2}); err == nil {
+			t.Error("Mismatched shape should be rejected")
+		}
+	})
+
+	t.Run("Strings", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteNpyStrings(&buf, []string{"hi", "héllo"}); err != nil {
+			t.Fatalf("Failed to write strings: %v", err)
+		}
+		if !strings.Contains(buf.String(), "'<U5'") {
+			t.Error("String width should be the longest rune count")
+		}
+	})
+}
+
+// TestConnectivityExport tests exporting the reservoir wiring as sparse matrices
+func TestConnectivityExport(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	config.Topology.InhibitoryFraction = 0.3
+	brain := NewLiquidStateBrainWithConfig(5, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	m := brain.SparseConnectivity()
+
+	t.Run("Matrix", func(t *testing.T) {
+		excitatory, inhibitory := brain.SynapseCounts()
+		if m.Neurons != 5*5*2 || len(m.Weights) != excitatory+inhibitory || len(m.Thresholds) != m.Neurons {
+			t.Fatalf("Expected %d neurons and %d synapses, got %d and %d", 50, excitatory+inhibitory, m.Neurons, len(m.Weights))
+		}
+		negative := 0
+		for i := range m.Weights {
+			if i > 0 && (m.Rows[i] < m.Rows[i-1] || (m.Rows[i] == m.Rows[i-1] && m.Cols[i] <= m.Cols[i-1])) {
+				t.Fatalf("Entry %d out of row and column order", i)
+			}
+			if m.Weights[i] < 0 {
+				negative++
+			}
+		}
+		if negative != inhibitory {
+			t.Errorf("Expected %d negative weights, got %d", inhibitory, negative)
+		}
+
+		n := brain.reservoir[2][3][1]
+		row := brain.neuronIndex(n)
+		if row != (2*5+3)*2+1 {
+			t.Errorf("Expected neuron 2,3,1 at index 27, got %d", row)
+		}
+		indptr := m.IndPtr()
+		if got := indptr[row+1] - indptr[row]; got != int64(len(n.connections)) {
+			t.Errorf("Expected row %d to hold %d synapses, got %d", row, len(n.connections), got)
+		}
+		if indptr[len(indptr)-1] != int64(len(m.Weights)) {
+			t.Error("Expected the last row pointer to be the synapse count")
+		}
+	})
+
+	t.Run("Npz", func(t *testing.T) {
+		members := map[string][]string{
+			SparseCSR: {"indices", "indptr", "data", "shape", "format", "coords", "thresholds"},
+			SparseCOO: {"row", "col", "data", "shape", "format", "coords", "thresholds"},
+		}
+		for format, want := range members {
+			path := filepath.Join(t.TempDir(), "connectivity.npz")
+			if err := ExportConnectivity(brain, path, format); err != nil {
+				t.Fatalf("Failed to export %s: %v", format, err)
+			}
+			archive, err := zip.OpenReader(path)
+			if err != nil {
+				t.Fatalf("Failed to open %s export: %v", format, err)
+			}
+			var got []string
+			for _, f := range archive.File {
+				got = append(got, strings.TrimSuffix(f.Name, ".npy"))
+			}
+			archive.Close()
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("Expected %s members %v, got %v", format, want, got)
+			}
+		}
+		if err := m.WriteNpz(filepath.Join(t.TempDir(), "bad.npz"), "dense"); err == nil {
+			t.Error("Expected an unknown format to be rejected")
+		}
+	})
+
+	t.Run("Matrix Market", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := m.WriteMatrixMarket(&buf); err != nil {
+			t.Fatalf("Failed to write Matrix Market: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if lines[0] != "%%MatrixMarket matrix coordinate real general" {
+			t.Errorf("Unexpected banner %q", lines[0])
+		}
+		if want := fmt.Sprintf("50 50 %d", len(m.Weights)); lines[2] != want || len(lines) != 3+len(m.Weights) {
+			t.Errorf("Expected size line %q and %d entries, got %q and %d", want, len(m.Weights), lines[2], len(lines)-3)
+		}
+		if want := fmt.Sprintf("%d %d ", m.Rows[0]+1, m.Cols[0]+1); !strings.HasPrefix(lines[3], want) {
+			t.Errorf("Expected 1-based first entry %q, got %q", want, lines[3])
+		}
+	})
+}
+
+// TestEmbeddingsAPI tests the OpenAI-compatible embeddings endpoint
+func TestEmbeddingsAPI(t *testing.T) {
+	testFile := "test_embeddings.txt"
+	err := os.WriteFile(testFile, []byte("hello world machine learning hello world"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer os.Remove(testFile)
+
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{testFile},
+		EmbeddingDim: 16,
+		MinWordFreq:  1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dataset loader: %v", err)
+	}
+	handler := NewEmbeddingsHandler(loader)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("Batch Input", func(t *testing.T) {
+		rec := post(`{"model": "genesis", "input": ["hello", "machine learning"]}`)
+		if rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Object string `json:"object"`
+			Data   []struct {
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			} `json:"data"`
+			Usage EmbeddingUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid response JSON: %v", err)
+		}
+		if resp.Object != "list" || len(resp.Data) != 2 || resp.Data[1].Index != 1 {
+			t.Fatalf("Unexpected response: %+v", resp)
+		}
+		if len(resp.Data[0].Embedding) != 16 {
+			t.Errorf("Expected 16-dim embedding, got %d", len(resp.Data[0].Embedding))
+		}
+		if resp.Usage.PromptTokens != 3 {
+			t.Errorf("Expected 3 prompt tokens, got %d", resp.Usage.PromptTokens)
+		}
+	})
+
+	t.Run("Base64 Encoding", func(t *testing.T) {
+		rec := post(`{"input": "hello", "encoding_format": "base64"}`)
+		var resp struct {
+			Data []struct {
+				Embedding string `json:"embedding"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		raw, err := base64.StdEncoding.DecodeString(resp.Data[0].Embedding)
+		if err != nil || len(raw) != 16*4 {
+			t.Errorf("Expected 64 bytes of float32 data, got %d (%v)", len(raw), err)
+		}
+	})
+
+	t.Run("Invalid Input", func(t *testing.T) {
+		if rec := post(`{"input": 42}`); rec.Code != 400 {
+			t.Errorf("Expected 400 for numeric input, got %d", rec.Code)
+		}
+	})
+}
+
+// TestDatasetFetch tests corpus download, resume and checksum verification
+func TestDatasetFetch(t *testing.T) {
+	body := "header\n*** START OF THE EBOOK ***\nonce upon a time there was a test\n*** END OF THE EBOOK ***\nlicense\n"
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "corpus.txt", time.Time{}, strings.NewReader(body))
+	}))
+	defer server.Close()
+
+	stored := "once upon a time there was a test\n"
+	sum := sha256.Sum256([]byte(stored))
+	corpus := PublicCorpus{Name: "test", URL: server.URL, File: "test.txt", SHA256: hex.EncodeToString(sum[:]), Gutenberg: true}
+
+	t.Run("Resume And Verify", func(t *testing.T) {
+		dir := t.TempDir()
+		// Simulate an interrupted download
+		os.WriteFile(dir+"/test.txt.part", []byte(body[:10]), 0644)
+
+		entry, err := FetchCorpus(server.Client(), corpus, dir)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if ranges[len(ranges)-1] != "bytes=10-" {
+			t.Errorf("Expected a resumed range request, got %q", ranges[len(ranges)-1])
+		}
+		if entry.Bytes != int64(len(stored)) {
+			t.Errorf("Expected %d bytes, got %d", len(stored), entry.Bytes)
+		}
+
+		text, _ := os.ReadFile(dir + "/test.txt")
+		if string(text) != stored {
+			t.Errorf("Gutenberg boilerplate not stripped: %q", text)
+		}
+
+		manifest, err := LoadDatasetManifest(dir)
+		if err != nil || manifest.Corpora["test"].SHA256 != corpus.SHA256 {
+			t.Errorf("Manifest not recorded: %v", err)
+		}
+	})
+
+	t.Run("Checksum Mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		bad := corpus
+		bad.SHA256 = strings.Repeat("0", 64)
+		if _, err := FetchCorpus(server.Client(), bad, dir); err == nil {
+			t.Error("Expected checksum mismatch error")
+		}
+		if _, err := os.Stat(dir + "/test.txt"); err == nil {
+			t.Error("Unverified corpus should not be moved into place")
+		}
+	})
+
+	t.Run("Already Fetched", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := FetchCorpus(server.Client(), corpus, dir); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		requests := len(ranges)
+		if _, err := FetchCorpus(server.Client(), corpus, dir); err != nil || len(ranges) != requests {
+			t.Errorf("Expected a verified corpus not to be downloaded again: %v", err)
+		}
+
+		os.WriteFile(dir+"/test.txt", []byte("tampered"), 0644)
+		if _, err := FetchCorpus(server.Client(), corpus, dir); err != nil || len(ranges) == requests {
+			t.Errorf("Expected a corpus that fails its checksum to be downloaded again: %v", err)
+		}
+		if text, _ := os.ReadFile(dir + "/test.txt"); string(text) != stored {
+			t.Errorf("Expected the corpus to be restored, got %q", text)
+		}
+	})
+
+	t.Run("Unpinned", func(t *testing.T) {
+		dir := t.TempDir()
+		unpinned := corpus
+		unpinned.SHA256 = ""
+		requests := len(ranges)
+		if _, err := FetchCorpus(server.Client(), unpinned, dir); err == nil || !strings.Contains(err.Error(), "dataset pin") {
+			t.Errorf("Expected an unpinned corpus to be refused, got %v", err)
+		}
+		if len(ranges) != requests {
+			t.Error("Refused corpus should not be downloaded")
+		}
+
+		if _, err := FetchUnpinnedCorpus(server.Client(), unpinned, dir); err != nil {
+			t.Fatalf("Trusted fetch failed: %v", err)
+		}
+		// Once trusted, the manifest is the reference
+		if _, err := FetchCorpus(server.Client(), unpinned, dir); err != nil {
+			t.Errorf("Expected a trusted corpus to verify against the manifest: %v", err)
+		}
+	})
+
+	t.Run("Re-pin", func(t *testing.T) {
+		upstream := "first revision\n"
+		revised := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "corpus.txt", time.Time{}, strings.NewReader(upstream))
+		}))
+		defer revised.Close()
+
+		dir := t.TempDir()
+		unpinned := PublicCorpus{Name: "revised", URL: revised.URL, File: "revised.txt"}
+		if _, err := PinCorpus(revised.Client(), unpinned, dir); err != nil {
+			t.Fatalf("Pin failed: %v", err)
+		}
+		pins, err := LoadCorpusPins(dir)
+		first := sha256.Sum256([]byte(upstream))
+		if err != nil || pins.Corpora["revised"].SHA256 != hex.EncodeToString(first[:]) {
+			t.Fatalf("Pin not recorded: %+v, %v", pins, err)
+		}
+
+		// Upstream revises the text; a fresh checkout must not accept it
+		upstream = "second revision\n"
+		os.Remove(dir + "/revised.txt")
+		os.Remove(dir + "/" + datasetManifestFile)
+		if _, err := FetchCorpus(revised.Client(), unpinned, dir); err == nil || !strings.Contains(err.Error(), "dataset pin revised") {
+			t.Errorf("Expected a mismatch that says how to re-pin, got %v", err)
+		}
+
+		if _, err := PinCorpus(revised.Client(), unpinned, dir); err != nil {
+			t.Fatalf("Re-pin failed: %v", err)
+		}
+		if _, err := FetchCorpus(revised.Client(), unpinned, dir); err != nil {
+			t.Errorf("Expected the re-pinned corpus to verify: %v", err)
+		}
+		if text, _ := os.ReadFile(dir + "/revised.txt"); string(text) != upstream {
+			t.Errorf("Expected the revised text, got %q", text)
+		}
+
+		// A pin taken from another URL doesn't apply
+		moved := unpinned
+		moved.URL = revised.URL + "/moved"
+		if _, err := FetchCorpus(revised.Client(), moved, t.TempDir()); err == nil {
+			t.Error("Expected a pin for another URL to be ignored")
+		}
+	})
+}
+
+// TestRetrievalIndex tests document chunking and embedding search
+func TestRetrievalIndex(t *testing.T) {
+	t.Run("Chunk Overlap", func(t *testing.T) {
+		spans := ChunkText("a b c d e f g h i j", 4, 1)
+		want := [][2]int{{0, 4}, {3, 7}, {6, 10}}
+		if fmt.Sprint(spans) != fmt.Sprint(want) {
+			t.Errorf("Expected %v, got %v", want, spans)
+		}
+		if ChunkText("a b c", 4, 4) != nil {
+			t.Error("Overlap equal to window should be rejected")
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(dir+"/cats.txt", []byte("cats purr and cats sleep in the warm sun all day"), 0644)
+		os.WriteFile(dir+"/rockets.txt", []byte("rockets launch into orbit with powerful engines and fuel"), 0644)
+
+		loader, err := NewDatasetLoader(TrainingConfig{
+			DatasetPaths: []string{dir},
+			MaxVocabSize: 1000,
+			EmbeddingDim: 64,
+			MinWordFreq:  1,
+			MaxDocuments: 10,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		index, err := NewRetrievalIndex(loader, RetrievalConfig{ChunkTokens: 6, ChunkOverlap: 2})
+		if err != nil {
+			t.Fatalf("Failed to build index: %v", err)
+		}
+		if index.Len() != 5 {
+			t.Errorf("Expected 5 chunks, got %d", index.Len())
+		}
+
+		hits := index.Search("rockets orbit", 2)
+		if len(hits) == 0 || !strings.HasSuffix(hits[0].Chunk.Path, "rockets.txt") {
+			t.Fatalf("Expected rockets chunk first, got %+v", hits)
+		}
+		if len(hits) > 1 && hits[0].Score < hits[1].Score {
+			t.Error("Results should be sorted by score")
+		}
+		if index.Search("zzzz", 3) != nil {
+			t.Error("Unknown query should return no results")
+		}
+	})
+}
+
+// TestGroundedGeneration tests retrieval-augmented responses and citations
+func TestGroundedGeneration(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/rockets.txt", []byte("rockets launch into orbit with powerful engines. rockets need fuel to reach orbit."), 0644)
+	os.WriteFile(dir+"/gardens.txt", []byte("gardens grow flowers in spring. gardens need water and sunlight."), 0644)
+
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{dir},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	index, err := NewRetrievalIndex(loader, RetrievalConfig{ChunkTokens: 8, ChunkOverlap: 2, TopK: 2})
+	if err != nil {
+		t.Fatalf("Failed to build index: %v", err)
+	}
+
+	gen := NewResponseGenerator(loader)
+	gen.SetRetrievalIndex(index, 2)
+
+	response, explanation := gen.GenerateExplained("tell me about rockets and orbit", []string{"rockets"})
+	if response == "" {
+		t.Fatal("Empty response")
+	}
+	if len(explanation.Retrieved) == 0 {
+		t.Fatal("Expected retrieved chunks in the explanation")
+	}
+	if !strings.HasSuffix(explanation.Retrieved[0].Path, "rockets.txt") {
+		t.Errorf("Expected rockets chunk first, got %s", explanation.Retrieved[0].Path)
+	}
+	if len(explanation.Cited) == 0 {
+		t.Errorf("Expected the response %q to cite a chunk", response)
+	}
+
+	// A separate query grounds generation from another text, such as a
+	// crystallized meaning, in chunks retrieved for the query
+	_, explanation = gen.GenerateWithOptions("gardens flowers", nil, GenerationOptions{query: "tell me about rockets and orbit"})
+	if len(explanation.Retrieved) == 0 || !strings.HasSuffix(explanation.Retrieved[0].Path, "rockets.txt") {
+		t.Errorf("Expected chunks retrieved for the query, got %+v", explanation.Retrieved)
+	}
+
+	// Without an index the explanation has no retrieval section
+	gen.SetRetrievalIndex(nil, 0)
+	_, explanation = gen.GenerateExplained("rockets", nil)
+	if len(explanation.Retrieved) != 0 {
+		t.Error("Retrieval should be disabled")
+	}
+}
+
+// TestExtractiveQA tests answering questions with sentences from the corpus
+func TestExtractiveQA(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/facts.txt", []byte("The moon orbits the earth every month. Photosynthesis turns sunlight into chemical energy. Rust prevents data races at compile time."), 0644)
+
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{dir},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	index, err := NewRetrievalIndex(loader, RetrievalConfig{ChunkTokens: 12, ChunkOverlap: 4})
+	if err != nil {
+		t.Fatalf("Failed to build index: %v", err)
+	}
+
+	t.Run("Sentence Splitting", func(t *testing.T) {
+		got := splitSentences("Version 1.5 is out. Is it good? Yes!")
+		if len(got) != 3 || got[0] != "Version 1.5 is out." {
+			t.Errorf("Unexpected sentences: %q", got)
+		}
+	})
+
+	t.Run("Extracts Answer", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.SetRetrievalIndex(index, 3)
+
+		response, explanation := gen.GenerateExplained("what does photosynthesis turn sunlight into?", nil)
+		if response != "Photosynthesis turns sunlight into chemical energy." {
+			t.Errorf("Unexpected answer: %q", response)
+		}
+		if explanation.Answer == nil || len(explanation.Cited) != 1 || explanation.Cited[0] != explanation.Answer.ChunkID {
+			t.Errorf("Answer not cited: %+v", explanation)
+		}
+	})
+
+	t.Run("Low Confidence Falls Back", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.SetRetrievalIndex(index, 3)
+		gen.SetAnswerThreshold(2)
+
+		_, explanation := gen.GenerateExplained("what does photosynthesis turn sunlight into?", nil)
+		if explanation.Answer != nil {
+			t.Error("Expected generative fallback above the threshold")
+		}
+	})
+}
+
+// TestSummarize tests extractive summaries of text, documents and conversations
+func TestSummarize(t *testing.T) {
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{"nonexistent.txt"},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load starter corpus: %v", err)
+	}
+
+	text := "The code has a bug. The bug breaks the code in tests. Bananas are yellow. We fixed the bug in the code."
+
+	t.Run("Central Sentences", func(t *testing.T) {
+		summary := loader.SummarizeText(text, 2)
+		if summary.Total != 4 || len(summary.Sentences) != 2 {
+			t.Fatalf("Unexpected summary: %+v", summary)
+		}
+		if strings.Contains(summary.Text, "Bananas") {
+			t.Errorf("Off-topic sentence chosen: %q", summary.Text)
+		}
+		if summary.Sentences[0].Index > summary.Sentences[1].Index {
+			t.Error("Summary should keep source order")
+		}
+	})
+
+	t.Run("Conversation And Document", func(t *testing.T) {
+		summary := loader.SummarizeConversation([]string{"My code has a bug.", "What does the bug do?"}, 1)
+		if len(summary.Sentences) != 1 {
+			t.Errorf("Expected one sentence, got %d", len(summary.Sentences))
+		}
+		if _, err := loader.SummarizeDocument("missing.txt", 2); err == nil {
+			t.Error("Expected error for unknown document")
+		}
+	})
+
+	t.Run("HTTP Endpoint", func(t *testing.T) {
+		handler := NewSummarizeHandler(loader)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/summarize", strings.NewReader(`{"text": "`+text+`", "sentences": 1}`)))
+		var summary Summary
+		if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil || len(summary.Sentences) != 1 {
+			t.Errorf("Bad response %d: %s", rec.Code, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/summarize", strings.NewReader(`{"text": "a.", "turns": ["b."]}`)))
+		if rec.Code != 400 {
+			t.Errorf("Expected 400 for multiple sources, got %d", rec.Code)
+		}
+	})
+}
+
+// TestKeyphrases tests RAKE/TF-IDF keyphrase extraction
+func TestKeyphrases(t *testing.T) {
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{"nonexistent.txt"},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load starter corpus: %v", err)
+	}
+
+	keyphrases := loader.ExtractKeyphrases("How do I fix the memory leak in my goroutine pool? The memory leak grows.", 3)
+	if len(keyphrases) == 0 {
+		t.Fatal("No keyphrases extracted")
+	}
+	if !strings.Contains(keyphrases[0].Phrase, "memory leak") {
+		t.Errorf("Expected 'memory leak' in the top phrase, got %+v", keyphrases)
+	}
+	for _, kp := range keyphrases {
+		for _, word := range kp.Words {
+			if stopWords[word] {
+				t.Errorf("Stopword %q in keyphrase %q", word, kp.Phrase)
+			}
+		}
+	}
+
+	weights := keywordWeights(keyphrases)
+	if weights["memory"] != 1 {
+		t.Errorf("Best keyword should have weight 1, got %v", weights)
+	}
+	if len(loader.CorpusKeyphrases(5)) != 5 {
+		t.Error("Expected 5 corpus keyphrases")
+	}
+}
+
+// TestTopicDiscovery tests k-means topics, mixtures and generation bias
+func TestTopicDiscovery(t *testing.T) {
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{"nonexistent.txt"},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load starter corpus: %v", err)
+	}
+
+	model, err := loader.DiscoverTopics(4, 20, 1)
+	if err != nil {
+		t.Fatalf("Failed to discover topics: %v", err)
+	}
+	topics := model.Topics()
+	if len(topics) != 4 {
+		t.Fatalf("Expected 4 topics, got %d", len(topics))
+	}
+
+	again, _ := loader.DiscoverTopics(4, 20, 1)
+	for i := range topics {
+		if topics[i].Name != again.Topics()[i].Name {
+			t.Errorf("Topic %d differs across runs with the same seed", i)
+		}
+	}
+
+	docs := loader.GetDocuments()
+	mixture, err := model.DocumentTopics(docs[0].Path)
+	if err != nil {
+		t.Fatalf("Document mixture failed: %v", err)
+	}
+	total := 0.0
+	for _, share := range mixture {
+		total += share
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("Mixture should sum to 1, got %f", total)
+	}
+
+	word := topics[0].Words[0]
+	gen := NewResponseGenerator(loader)
+	before := gen.scoreWord(word, nil, nil)
+	if err := gen.SetTopicBias(model, 0, 1.0); err != nil {
+		t.Fatalf("SetTopicBias failed: %v", err)
+	}
+	if after := gen.scoreWord(word, nil, nil); after <= before {
+		t.Errorf("Topic bias should raise %q's score (%f -> %f)", word, before, after)
+	}
+	if gen.SetTopicBias(model, 99, 1.0) == nil {
+		t.Error("Expected error for unknown topic")
+	}
+}
+
+// fakeQdrant is an in-memory stand-in for the Qdrant REST API
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]map[int]qdrantPoint
+	points      map[int]qdrantPoint // the last collection touched
+	upserts     int
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "status": "ok"})
+	}
+	if f.collections == nil {
+		f.collections = make(map[string]map[int]qdrantPoint)
+	}
+	name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	points, exists := f.collections[name]
+	f.points = points
+	if !exists && !(r.Method == "PUT" && rest == "") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == "GET" && rest == "":
+		reply(map[string]string{})
+	case r.Method == "PUT" && rest == "":
+		f.points = make(map[int]qdrantPoint)
+		f.collections[name] = f.points
+		reply(true)
+	case r.Method == "DELETE" && rest == "":
+		delete(f.collections, name)
+		f.points = nil
+		reply(true)
+	case r.Method == "PUT" && rest == "points":
+		var body struct{ Points []qdrantPoint }
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, p := range body.Points {
+			f.points[p.ID] = p
+		}
+		f.upserts++
+		reply(map[string]string{"status": "completed"})
+	case r.Method == "POST" && rest == "points/count":
+		reply(map[string]int{"count": len(f.points)})
+	case r.Method == "POST" && rest == "points/scroll":
+		var body struct {
+			Limit  int
+			Offset *int
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var ids []int
+		for id := range f.points {
+			if body.Offset == nil || id >= *body.Offset {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+		var next interface{}
+		if len(ids) > body.Limit {
+			next, ids = ids[body.Limit], ids[:body.Limit]
+		}
+		page := make([]qdrantPoint, len(ids))
+		for i, id := range ids {
+			page[i] = f.points[id]
+		}
+		reply(map[string]interface{}{"points": page, "next_page_offset": next})
+	case r.Method == "POST" && rest == "points/search":
+		var body struct {
+			Vector []float64
+			Limit  int
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var hits []qdrantPoint
+		for _, p := range f.points {
+			hits = append(hits, qdrantPoint{ID: p.ID, Payload: p.Payload, Score: dotProduct(body.Vector, p.Vector)})
+		}
+		sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if len(hits) > body.Limit {
+			hits = hits[:body.Limit]
+		}
+		reply(hits)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+// TestVectorStores tests the retrieval index on each vector store backend
+func TestVectorStores(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/cats.txt", []byte("cats purr and cats sleep in the warm sun all day"), 0644)
+	os.WriteFile(dir+"/rockets.txt", []byte("rockets launch into orbit with powerful engines and fuel"), 0644)
+	trainConfig := TrainingConfig{
+		DatasetPaths: []string{dir},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 64,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	}
+	loader, err := NewDatasetLoader(trainConfig)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	config := RetrievalConfig{ChunkTokens: 6, ChunkOverlap: 2}
+
+	t.Run("Persisted Memory Store", func(t *testing.T) {
+		config := config
+		config.VectorStore = VectorStoreConfig{Type: "memory", Path: dir + "/vectors.gob"}
+		index, err := NewRetrievalIndex(loader, config)
+		if err != nil {
+			t.Fatalf("Failed to build index: %v", err)
+		}
+		if err := index.Close(); err != nil {
+			t.Fatalf("Failed to save store: %v", err)
+		}
+
+		store, err := OpenMemoryVectorStore(dir + "/vectors.gob")
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		if n, _ := store.Count(context.Background()); n != index.Len() {
+			t.Errorf("Expected %d stored vectors, got %d", index.Len(), n)
+		}
+		reopened, err := NewRetrievalIndexWithStore(loader, config, store)
+		if err != nil {
+			t.Fatalf("Failed to reuse store: %v", err)
+		}
+		hits := reopened.Search("rockets orbit", 1)
+		if len(hits) != 1 || !strings.HasSuffix(hits[0].Chunk.Path, "rockets.txt") {
+			t.Errorf("Unexpected hits from reused store: %+v", hits)
+		}
+	})
+
+	t.Run("Reuse Without Embedding", func(t *testing.T) {
+		store := NewMemoryVectorStore()
+		if _, err := NewRetrievalIndexWithStore(loader, config, store); err != nil {
+			t.Fatalf("Failed to build index: %v", err)
+		}
+
+		// Mark the stored vectors; a reused index must load them rather
+		// than embed the chunks again
+		records, _ := store.All(context.Background())
+		for i := range records {
+			records[i].Vector = append([]float64{42}, records[i].Vector[1:]...)
+		}
+		store.Upsert(context.Background(), records)
+		reused, err := NewRetrievalIndexWithStore(loader, config, store)
+		if err != nil {
+			t.Fatalf("Failed to reuse store: %v", err)
+		}
+		for _, chunk := range reused.Chunks() {
+			if chunk.Vector != nil && chunk.Vector[0] != 42 {
+				t.Fatalf("Chunk %d was embedded again instead of loaded", chunk.ID)
+			}
+		}
+
+		// An interrupted upload is not reused
+		incomplete := NewMemoryVectorStore()
+		incomplete.Upsert(context.Background(), records[1:])
+		rebuilt, err := NewRetrievalIndexWithStore(loader, config, incomplete)
+		if err != nil {
+			t.Fatalf("Failed to rebuild: %v", err)
+		}
+		if rebuilt.Chunks()[0].Vector[0] == 42 {
+			t.Error("Expected an incomplete store to be rebuilt")
+		}
+		if n, _ := incomplete.Count(context.Background()); n != len(records) {
+			t.Errorf("Expected %d vectors after rebuild, got %d", len(records), n)
+		}
+	})
+
+	t.Run("Qdrant Store", func(t *testing.T) {
+		fake := &fakeQdrant{}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		config := config
+		config.VectorStore = VectorStoreConfig{Type: "qdrant", URL: server.URL, Collection: "test"}
+		index, err := NewRetrievalIndex(loader, config)
+		if err != nil {
+			t.Fatalf("Failed to build index: %v", err)
+		}
+		if len(fake.points) != index.Len() {
+			t.Errorf("Expected %d points in qdrant, got %d", index.Len(), len(fake.points))
+		}
+		hits := index.Search("cats purr", 2)
+		if len(hits) == 0 || !strings.HasSuffix(hits[0].Chunk.Path, "cats.txt") {
+			t.Errorf("Unexpected hits: %+v", hits)
+		}
+
+		// The same corpus reuses its collection
+		upserts := fake.upserts
+		if _, err := NewRetrievalIndex(loader, config); err != nil || fake.upserts != upserts {
+			t.Errorf("Expected the stored collection to be reused: %v", err)
+		}
+
+		// Other chunking gets its own collection
+		other := config
+		other.ChunkTokens = 4
+		if _, err := NewRetrievalIndex(loader, other); err != nil {
+			t.Fatalf("Failed to build index: %v", err)
+		}
+		if len(fake.collections) != 2 {
+			t.Errorf("Expected a collection per fingerprint, got %d", len(fake.collections))
+		}
+		for name := range fake.collections {
+			if !strings.HasPrefix(name, "test_") {
+				t.Errorf("Expected collections prefixed with the configured name, got %q", name)
+			}
+		}
+	})
+
+	t.Run("Unknown Store", func(t *testing.T) {
+		if _, err := NewVectorStore(VectorStoreConfig{Type: "nope"}); err == nil {
+			t.Error("Expected error for unknown store type")
+		}
+	})
+}
+
+// TestSessions tests session persistence, restore, expiry and the HTTP API
+func TestSessions(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/cats.txt", []byte("cats purr and cats sleep in the warm sun all day. cats love fish."), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{dir},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 32,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	generator := NewResponseGenerator(loader)
+
+	t.Run("Restore From Disk", func(t *testing.T) {
+		store, err := NewFileSessionStore(dir + "/sessions")
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		session, err := NewSessionManager(store, time.Hour).Create()
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		NewSessionManager(store, time.Hour).Update(session.ID, func(s *Session) error {
+			reply, _ := generator.GenerateWithState(&s.Generator, "cats purr", nil)
+			s.History = append(s.History, ChatMessage{Role: "assistant", Content: reply})
+			return nil
+		})
+
+		// A fresh manager, as after a restart, sees the conversation
+		restored, err := NewSessionManager(store, time.Hour).Get(session.ID)
+		if err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		if len(restored.History) != 1 || len(restored.Generator.ContextWindow) == 0 {
+			t.Errorf("Session state was not persisted: %+v", restored)
+		}
+		if len(generator.contextWindow) != 0 {
+			t.Error("Session generation should not touch the shared context window")
+		}
+		if _, err := store.Load("../../etc/passwd"); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound for a bad id, got %v", err)
+		}
+	})
+
+	t.Run("SQL Store", func(t *testing.T) {
+		store, err := NewSessionStore(SessionConfig{Store: "sql", Driver: "sqlite3", DSN: dir + "/sessions.db", Table: "chat_sessions"})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		session, err := NewSessionManager(store, time.Hour).Create()
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if _, err := store.Load(session.ID); err != nil {
+			t.Errorf("Failed to load from the configured table: %v", err)
+		}
+
+		closer, ok := store.(io.Closer)
+		if !ok {
+			t.Fatal("Expected the sql store to be closable")
+		}
+		if err := closer.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if _, err := store.Load(session.ID); err == nil {
+			t.Error("Expected the database to be closed")
+		}
+
+		if _, err := NewSessionStore(SessionConfig{Store: "sql", Driver: "sqlite3", DSN: dir + "/sessions.db", Table: "bad name"}); err == nil {
+			t.Error("Expected an invalid table name to be rejected")
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		sm := NewSessionManager(NewMemorySessionStore(), time.Millisecond)
+		session, _ := sm.Create()
+		time.Sleep(5 * time.Millisecond)
+		if n, _ := sm.ExpireIdle(); n != 1 {
+			t.Errorf("Expected 1 expired session, got %d", n)
+		}
+		if _, err := sm.Get(session.ID); err != ErrSessionNotFound {
+			t.Errorf("Expected expired session to be gone, got %v", err)
+		}
+	})
+
+	t.Run("Locks", func(t *testing.T) {
+		sm := NewSessionManager(NewMemorySessionStore(), time.Hour)
+		session, _ := sm.Create()
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sm.Update(session.ID, func(s *Session) error { return nil })
+			}()
+		}
+		wg.Wait()
+		sm.Update("unknown", func(s *Session) error { return nil })
+		if len(sm.locks) != 0 {
+			t.Errorf("Expected no locks once updates finish, got %d", len(sm.locks))
+		}
+	})
+
+	t.Run("HTTP API", func(t *testing.T) {
+		sm := NewSessionManager(NewMemorySessionStore(), time.Hour)
+		server := httptest.NewServer(NewSessionHandler(sm, generator))
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		if err != nil || resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Create failed: %v %v", err, resp)
+		}
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"do cats purr?"}`))
+		var msg MessageResponse
+		json.NewDecoder(resp.Body).Decode(&msg)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || msg.Message.Role != "assistant" || msg.Index != 1 {
+			t.Fatalf("Unexpected message response %d: %+v", resp.StatusCode, msg)
+		}
+
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/feedback", "application/json", strings.NewReader(`{"message_index":1,"rating":1}`))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Feedback failed with %d", resp.StatusCode)
+		}
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/feedback", "application/json", strings.NewReader(`{"message_index":0,"rating":1}`))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Feedback on a user message should fail, got %d", resp.StatusCode)
+		}
+
+		stored, _ := sm.Get(session.ID)
+		if len(stored.History) != 2 || len(stored.Feedback) != 1 {
+			t.Errorf("Unexpected stored session: %+v", stored)
+		}
+
+		resp, _ = http.Get(server.URL + "/v1/sessions/" + strings.Repeat("0", 32))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 for unknown session, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// TestAPIAuth tests API keys and their rate limits
+func TestAPIAuth(t *testing.T) {
+	now := time.Now()
+	auth := NewAPIAuth(ServerConfig{APIKeys: []APIKeyConfig{
+		{Name: "ci", Key: "ci-key", RequestsPerMinute: 2},
+		{Name: "batch", Key: "batch-key", TokensPerMinute: 10},
+		{Name: "open", Key: "open-key"},
+	}})
+	auth.now = func() time.Time { return now }
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body) // echoes, so a request costs twice its words
+	}))
+	send := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/think", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Keys", func(t *testing.T) {
+		for _, key := range []string{"", "wrong", "ci-key-extra"} {
+			if rec := send(key, "hi"); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Key %q: expected 401, got %d", key, rec.Code)
+			}
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/think", nil)
+		req.Header.Set("Authorization", "open-key")
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a key without Bearer to be refused, got %d", rec.Code)
+		}
+		for i := 0; i < 5; i++ {
+			if rec := send("open-key", "hi"); rec.Code != 200 || rec.Body.String() != "hi" {
+				t.Fatalf("Expected an unlimited key through, got %d %q", rec.Code, rec.Body.String())
+			}
+		}
+		unguarded := NewAPIAuth(ServerConfig{}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		rec = httptest.NewRecorder()
+		unguarded.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/think", nil))
+		if rec.Code != 200 {
+			t.Errorf("Expected no keys to leave the API open, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Requests", func(t *testing.T) {
+		send("ci-key", "one")
+		send("ci-key", "two")
+		rec := send("ci-key", "three")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+			t.Fatalf("Expected 429 retrying in 30s, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if !strings.Contains(rec.Body.String(), "request rate") {
+			t.Errorf("Expected the limit in the error, got %s", rec.Body.String())
+		}
+		if rec := send("open-key", "hi"); rec.Code != 200 {
+			t.Errorf("Expected other keys unaffected, got %d", rec.Code)
+		}
+		now = now.Add(30 * time.Second)
+		if rec := send("ci-key", "four"); rec.Code != 200 {
+			t.Errorf("Expected the rate to have refilled, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		if rec := send("batch-key", "a b c"); rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+		// 6 words charged; 4 left, so this one goes through into overdraft
+		if rec := send("batch-key", "d e f g h i"); rec.Code != 200 {
+			t.Fatalf("Expected the balance to admit a request, got %d", rec.Code)
+		}
+		rec := send("batch-key", "j")
+		if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "token rate") {
+			t.Fatalf("Expected an overdrawn key refused, got %d %s", rec.Code, rec.Body.String())
+		}
+		// 8 words overdrawn at 10 a minute
+		if got := rec.Header().Get("Retry-After"); got != "48" {
+			t.Errorf("Expected Retry-After 48, got %q", got)
+		}
+		now = now.Add(48 * time.Second)
+		if rec := send("batch-key", "j"); rec.Code != 200 {
+			t.Errorf("Expected the overdraft paid back, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		bad := []ServerConfig{
+			{APIKeys: []APIKeyConfig{{Name: "ci"}}},
+			{APIKeys: []APIKeyConfig{{Name: "ci", Key: "k"}, {Name: "cd", Key: "k"}}},
+			{APIKeys: []APIKeyConfig{{Name: "ci", Key: "k", TokensPerMinute: -1}}},
+		}
+		for _, c := range bad {
+			if err := c.validate(); err == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+		if err := (ServerConfig{}).validate(); err != nil {
+			t.Errorf("Expected no keys to be valid, got %v", err)
+		}
+	})
+}
+
+// TestHealthChecks tests liveness and readiness reporting
+func TestHealthChecks(t *testing.T) {
+	orchestrator := &GenesisOrchestrator{neurons: make(map[string]*OrchestratorNeuron)}
+	orchestrator.RegisterCapability("flaky", func(ctx context.Context, input string) (string, error) {
+		return "", fmt.Errorf("upstream down")
+	})
+
+	health := NewHealthChecker()
+	health.Register("goroutines", GoroutineBudgetCheck(100000), true)
+	health.Register("dataset", DatasetHealthCheck(nil), false)
+	health.Register("orchestrator", OrchestratorHealthCheck(orchestrator), false)
+	mux := http.NewServeMux()
+	health.Mount(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Liveness Ignores Readiness Checks", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var report HealthReport
+		json.NewDecoder(resp.Body).Decode(&report)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(report.Components) != 1 {
+			t.Errorf("Unexpected liveness report %d: %+v", resp.StatusCode, report)
+		}
+	})
+
+	t.Run("Readiness Reports Failing Components", func(t *testing.T) {
+		for i := 0; i < capabilityFailureLimit; i++ {
+			orchestrator.neurons["flaky"].call(context.Background(), "ping")
+		}
+		resp, err := http.Get(server.URL + "/readyz")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var report HealthReport
+		json.NewDecoder(resp.Body).Decode(&report)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || report.Status != "unavailable" {
+			t.Errorf("Expected 503, got %d: %+v", resp.StatusCode, report)
+		}
+		for _, c := range report.Components {
+			if c.Name == "orchestrator" && (c.Healthy || !strings.Contains(c.Detail, "flaky")) {
+				t.Errorf("Orchestrator should report the flaky capability: %+v", c)
+			}
+		}
+	})
+
+	t.Run("Over Budget", func(t *testing.T) {
+		if status := GoroutineBudgetCheck(1)(context.Background()); status.Healthy {
+			t.Error("A budget of one goroutine should fail")
+		}
+	})
+}
+
+// TestInferenceQueue tests admission, shedding and 429 responses
+func TestInferenceQueue(t *testing.T) {
+	t.Run("Full Queue Rejects", func(t *testing.T) {
+		q := NewInferenceQueue(1, 1, time.Second)
+		release, err := q.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("First acquire failed: %v", err)
+		}
+
+		waited := make(chan error)
+		go func() {
+			r, err := q.Acquire(context.Background())
+			if err == nil {
+				r()
+			}
+			waited <- err
+		}()
+		for q.Stats().Waiting == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if _, err := q.Acquire(context.Background()); err != ErrQueueFull {
+			t.Errorf("Expected ErrQueueFull, got %v", err)
+		}
+		release()
+		if err := <-waited; err != nil {
+			t.Errorf("Queued request should get the freed slot, got %v", err)
+		}
+	})
+
+	t.Run("Timeout Sheds", func(t *testing.T) {
+		q := NewInferenceQueue(1, 5, 10*time.Millisecond)
+		release, _ := q.Acquire(context.Background())
+		defer release()
+		if _, err := q.Acquire(context.Background()); err != ErrQueueTimeout {
+			t.Errorf("Expected ErrQueueTimeout, got %v", err)
+		}
+		if q.Stats().Shed != 1 {
+			t.Errorf("Expected 1 shed request, got %d", q.Stats().Shed)
+		}
+	})
+
+	t.Run("Middleware Returns 429", func(t *testing.T) {
+		q := NewInferenceQueue(1, 0, time.Second)
+		block := make(chan struct{})
+		server := httptest.NewServer(q.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		})))
+		defer server.Close()
+
+		go http.Get(server.URL)
+		for q.Stats().Running == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		close(block)
+		if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+			t.Errorf("Expected 429 with Retry-After, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// TestAdminAPI tests runtime introspection and control endpoints
+func TestAdminAPI(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	config.Profiling.Enabled = true
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	defer SetLogLevel(LogInfo)
+
+	sessions := NewSessionManager(NewMemorySessionStore(), time.Hour)
+	sessions.Create()
+	// Serve mode hands the brain over once it is built
+	admin := NewAdminHandler(AdminConfig{Token: "secret", SnapshotDir: t.TempDir()}, sessions, nil, nil)
+	admin.SetBrain(brain)
+	server := httptest.NewServer(admin)
+	defer server.Close()
+
+	do := func(method, path, body, token string) (*http.Response, map[string]interface{}) {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Requires Token", func(t *testing.T) {
+		if resp, _ := do("GET", "/admin/sessions", "", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		_, out := do("GET", "/admin/sessions", "", "secret")
+		if list, ok := out["sessions"].([]interface{}); !ok || len(list) != 1 {
+			t.Errorf("Expected one session, got %v", out)
+		}
+	})
+
+	t.Run("Pause And Resume", func(t *testing.T) {
+		_, out := do("POST", "/admin/reservoir/pause", "", "secret")
+		if out["paused"] != true || !brain.Paused() {
+			t.Errorf("Reservoir should be paused: %v", out)
+		}
+		brain.activity.WaitQuiet(pulseSettleGrace, pulseSettleTimeout) // in-flight spikes still land
+		before := brain.StateSnapshot()
+		time.Sleep(30 * time.Millisecond)
+		if fmt.Sprint(before) != fmt.Sprint(brain.StateSnapshot()) {
+			t.Error("Paused reservoir state should not change")
+		}
+		do("POST", "/admin/reservoir/resume", "", "secret")
+		if brain.Paused() {
+			t.Error("Reservoir should be running again")
+		}
+	})
+
+	t.Run("Profile", func(t *testing.T) {
+		brain.Think("hello world")
+		_, out := do("GET", "/admin/profile", "", "secret")
+		profile, _ := out["profile"].(map[string]interface{})
+		if stages, _ := profile["think"].([]interface{}); len(stages) == 0 {
+			t.Errorf("Expected think stage latencies, got %v", out)
+		}
+	})
+
+	t.Run("Log Level", func(t *testing.T) {
+		if resp, _ := do("PUT", "/admin/loglevel", `{"level":"loud"}`, "secret"); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for unknown level, got %d", resp.StatusCode)
+		}
+		do("PUT", "/admin/loglevel", `{"level":"warn"}`, "secret")
+		if CurrentLogLevel() != LogWarn || logEnabled(LogInfo) {
+			t.Errorf("Expected warn level, got %v", CurrentLogLevel())
+		}
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		_, out := do("POST", "/admin/snapshot", "", "secret")
+		files, _ := out["files"].([]interface{})
+		if len(files) != 1 {
+			t.Fatalf("Expected one snapshot file, got %v", out)
+		}
+		if _, err := os.Stat(files[0].(string)); err != nil {
+			t.Errorf("Snapshot file missing: %v", err)
+		}
+	})
+}
+
+// TestBrainManager tests lazy creation, routing and LRU eviction
+func TestBrainManager(t *testing.T) {
+	liquid := func() *Config {
+		config := DefaultConfig()
+		config.Model.Type = "liquid"
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		return config
+	}
+
+	bm := NewBrainManager(1, 0)
+	defer bm.Close()
+	bm.Register(InstanceSpec{Name: "alpha", Config: liquid(), Size: 4})
+	bm.Register(InstanceSpec{Name: "beta", Config: liquid(), Size: 4})
+
+	t.Run("Lazy Creation", func(t *testing.T) {
+		for _, s := range bm.Instances() {
+			if s.Loaded {
+				t.Errorf("%s should not load before its first request", s.Name)
+			}
+		}
+		if _, err := bm.Process("alpha", "hello"); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		if !bm.Instances()[0].Loaded {
+			t.Error("alpha should be loaded after a request")
+		}
+	})
+
+	t.Run("Routing", func(t *testing.T) {
+		if got := bm.Route("unknown-tenant", "hi"); got != "alpha" {
+			t.Errorf("Unknown tenants should fall back to the first instance, got %s", got)
+		}
+		bm.SetRouter(func(tenant, input string) string {
+			if strings.Contains(input, "beta") {
+				return "beta"
+			}
+			return ""
+		})
+		defer bm.SetRouter(nil)
+		if got := bm.Route("alpha", "ask beta"); got != "beta" {
+			t.Errorf("Router should pick beta, got %s", got)
+		}
+	})
+
+	t.Run("LRU Eviction", func(t *testing.T) {
+		if _, err := bm.Process("beta", "hello"); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		loaded := map[string]bool{}
+		for _, s := range bm.Instances() {
+			loaded[s.Name] = s.Loaded
+		}
+		if loaded["alpha"] || !loaded["beta"] {
+			t.Errorf("Loading beta past the limit should evict alpha: %v", loaded)
+		}
+	})
+
+	t.Run("Memory Budget", func(t *testing.T) {
+		heap := 0.0
+		budgeted := NewBrainManager(0, 100)
+		defer budgeted.Close()
+		budgeted.heapMB = func() float64 { return heap }
+		for _, name := range []string{"a", "b", "c"} {
+			budgeted.Register(InstanceSpec{Name: name, Config: liquid(), Size: 4})
+			if _, err := budgeted.Process(name, "hello"); err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+		}
+
+		// Three instances of 50MB each on a heap that hasn't been collected
+		// yet; evicting one brings it within budget
+		budgeted.mu.Lock()
+		for _, inst := range budgeted.instances {
+			inst.memoryMB = 50
+		}
+		budgeted.mu.Unlock()
+		heap = 150
+		budgeted.enforceLimits()
+
+		loaded := 0
+		for _, s := range budgeted.Instances() {
+			if s.Loaded {
+				loaded++
+			}
+		}
+		if loaded != 2 {
+			t.Errorf("Expected one eviction to meet the budget, %d instances still loaded", loaded)
+		}
+	})
+
+	t.Run("Unknown Instance", func(t *testing.T) {
+		if _, _, err := bm.acquire("gamma"); !errors.Is(err, ErrUnknownInstance) {
+			t.Errorf("Expected ErrUnknownInstance, got %v", err)
+		}
+	})
+}
+
+// TestBrainPool tests template cloning and the warm pool
+func TestBrainPool(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+
+	source := NewLiquidStateBrainWithConfig(4, config)
+	if source == nil {
+		t.Fatal("Failed to create brain")
+	}
+	template := source.Template()
+	source.Cleanup()
+
+	t.Run("Clone Keeps Topology", func(t *testing.T) {
+		clone := template.Instantiate()
+		defer clone.Cleanup()
+		if clone.dimensions != template.dims || clone.dataLoader != template.loader {
+			t.Fatalf("Clone differs from template: %+v", clone.dimensions)
+		}
+		for i, conns := range template.connections {
+			x, y, z := i/(template.dims.Y*template.dims.Z), (i/template.dims.Z)%template.dims.Y, i%template.dims.Z
+			if len(clone.reservoir[x][y][z].connections) != len(conns) {
+				t.Fatalf("Neuron %d has %d connections, template has %d", i, len(clone.reservoir[x][y][z].connections), len(conns))
+			}
+		}
+	})
+
+	t.Run("Clone Copies Clock", func(t *testing.T) {
+		clock := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		source := NewLiquidStateBrainWithClock(4, config, clock)
+		if source == nil {
+			t.Fatal("Failed to create brain")
+		}
+		template := source.Template()
+		source.Cleanup()
+
+		clone := template.Instantiate()
+		defer clone.Cleanup()
+		other := template.Instantiate()
+		defer other.Cleanup()
+		cloned, ok := clone.Clock().(*VirtualClock)
+		if !ok || cloned == clock || clone.reservoir[0][0][0].clock != clone.Clock() {
+			t.Fatal("Expected the clone to run on its own copy of the template brain's clock")
+		}
+		if !cloned.Now().Equal(clock.Now()) {
+			t.Errorf("Expected the copy to start at %v, got %v", clock.Now(), cloned.Now())
+		}
+
+		// Stepping one pooled brain's time doesn't move the others
+		start := other.Clock().Now()
+		cloned.Advance(time.Second)
+		if !other.Clock().Now().Equal(start) || !clock.Now().Equal(start) {
+			t.Error("Advancing one clone's clock moved another brain's clock")
+		}
+	})
+
+	t.Run("Warm Get", func(t *testing.T) {
+		pool := NewBrainPool(template, 2)
+		defer pool.Close()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for pool.Stats().Ready < 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		brain := pool.Get()
+		defer brain.Cleanup()
+		if brain.Paused() || brain.workers == 0 {
+			t.Error("Brains handed out should be running")
+		}
+		if stats := pool.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+			t.Errorf("Expected a pool hit, got %+v", stats)
+		}
+		if brain.Think("hello") == "" {
+			t.Error("Pooled brain should respond")
+		}
+	})
+}
+
+// TestVirtualClock tests virtual time and brains driven by it
+func TestVirtualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Tickers And Sleepers", func(t *testing.T) {
+		clock := NewVirtualClock(start)
+		ticker := clock.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		woke := make(chan time.Time)
+		go func() {
+			clock.Sleep(25 * time.Millisecond)
+			woke <- clock.Now()
+		}()
+		for clock.Pending() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		clock.Advance(10 * time.Millisecond)
+		if tick := <-ticker.Chan(); !tick.Equal(start.Add(10 * time.Millisecond)) {
+			t.Errorf("Unexpected tick time %v", tick)
+		}
+		if moved, ok := clock.Step(); !ok || moved != 10*time.Millisecond {
+			t.Errorf("Step should move to the next tick, moved %v", moved)
+		}
+		clock.Advance(5 * time.Millisecond)
+		if got := <-woke; !got.Equal(start.Add(25 * time.Millisecond)) {
+			t.Errorf("Sleeper woke at %v", got)
+		}
+	})
+
+	t.Run("Frozen Brain", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		clock := NewVirtualClock(start)
+		brain := NewLiquidStateBrainWithClock(4, config, clock)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		before := fmt.Sprint(brain.StateSnapshot())
+		time.Sleep(20 * time.Millisecond)
+		if fmt.Sprint(brain.StateSnapshot()) != before {
+			t.Error("Reservoir should not evolve while virtual time stands still")
+		}
+		for i := 0; i < 20; i++ {
+			clock.Advance(10 * time.Millisecond)
+			time.Sleep(time.Millisecond)
+		}
+		if fmt.Sprint(brain.StateSnapshot()) == before {
+			t.Error("Reservoir should evolve once virtual time advances")
+		}
+	})
+}
+
+// TestRecordReplay tests recording a run and replaying it
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/cats.txt", []byte("cats purr and cats sleep in the warm sun all day. cats love fish and milk."), 0644)
+	config := DefaultConfig()
+	config.Training.DatasetPaths = []string{dir}
+	config.Knowledge = KnowledgeConfig{Path: dir + "/knowledge.db", Scope: KnowledgeSession}
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	// Record through the serving session path
+	path := dir + "/run.jsonl"
+	recorder, err := NewRecorder(path, config, loader, 42)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	gen := NewResponseGenerator(loader)
+	gen.SetSeed(42)
+	handler, closeKnowledge, err := newServingSessionHandler(config, NewSessionManager(NewMemorySessionStore(), time.Hour), gen, loader)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	handler.SetRecorder(recorder)
+	a, _ := handler.sessions.Create()
+	b, _ := handler.sessions.Create()
+	for _, step := range []struct {
+		session string
+		body    string
+	}{
+		{a.ID, `{"content": "cats purr"}`},
+		{b.ID, `{"content": "warm sun", "max_tokens": 4, "word_bias": {"cats": 3}}`},
+		{a.ID, `{"content": "my name is Ada"}`},
+		{a.ID, `{"content": "what is my name"}`},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/sessions/"+step.session+"/messages", strings.NewReader(step.body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Message failed: %d %s", rec.Code, rec.Body.String())
+		}
+	}
+	recorder.Close()
+	closeKnowledge()
+
+	replay, err := LoadReplay(path)
+	if err != nil {
+		t.Fatalf("Failed to load replay: %v", err)
+	}
+	if len(replay.Steps) != 4 || replay.Header.Seed != 42 || replay.Header.Corpus != loader.CorpusFingerprint() {
+		t.Fatalf("Unexpected replay: %+v", replay.Header)
+	}
+	if len(replay.Steps[0].Events) != 1 {
+		t.Errorf("Expected the explanation event to be recorded, got %d events", len(replay.Steps[0].Events))
+	}
+	if req := replay.Steps[1].Request; req.MaxTokens != 4 || req.WordBias["cats"] != 3 {
+		t.Errorf("Expected the generation options to be recorded, got %+v", req)
+	}
+	if req := replay.Steps[3].Request; req.Recalled == 0 {
+		t.Errorf("Expected recalled facts to be recorded, got %+v", req)
+	}
+
+	// Replays get fresh sessions and an empty in-memory knowledge store
+	replayer := func() *SessionHandler {
+		replayConfig := *config
+		replayConfig.Knowledge.Path = ":memory:"
+		h, closeKnowledge, err := newServingSessionHandler(&replayConfig, NewSessionManager(NewMemorySessionStore(), time.Hour), NewResponseGenerator(loader), loader)
+		if err != nil {
+			t.Fatalf("Failed to create replay handler: %v", err)
+		}
+		t.Cleanup(closeKnowledge)
+		return h
+	}
+
+	t.Run("Reproduces", func(t *testing.T) {
+		if report := replay.Run(replayer()); report.Steps != 4 || len(report.Divergences) != 0 {
+			t.Errorf("Replay diverged: %+v", report)
+		}
+	})
+
+	t.Run("Inference Requests Unverified", func(t *testing.T) {
+		withInference := *replay
+		withInference.Steps = append([]ReplayStep{
+			{Seq: 10, Input: "hi", Output: "hello", Request: ReplayRequest{Endpoint: "/v1/think"}},
+			{Seq: 11, Input: "hi", Output: "hello", Request: ReplayRequest{Endpoint: "/v1/understand"}},
+		}, replay.Steps...)
+		report := withInference.Run(replayer())
+		if report.Steps != 4 || report.Skipped != 2 || report.SkippedEndpoints["/v1/think"] != 1 {
+			t.Errorf("Expected the inference requests to be skipped: %+v", report)
+		}
+		verdict := report.Verdict()
+		if strings.Contains(verdict, "All 4 steps") || !strings.Contains(verdict, "2 inference requests were not replayed") {
+			t.Errorf("Expected the verdict to call out unverified requests, got %q", verdict)
+		}
+		if verdict := (ReplayReport{Steps: 4}).Verdict(); verdict != "✅ All 4 steps reproduced exactly" {
+			t.Errorf("Unexpected verdict for a full replay: %q", verdict)
+		}
+	})
+
+	t.Run("Detects Divergence", func(t *testing.T) {
+		replay.Steps[1].Output = "something else"
+		report := replay.Run(replayer())
+		if len(report.Divergences) != 1 || report.Divergences[0].Seq != 2 {
+			t.Errorf("Expected step 2 to diverge: %+v", report.Divergences)
+		}
+	})
+}
+
+// TestChaos tests that the brain and orchestrator survive injected faults
+func TestChaos(t *testing.T) {
+	t.Run("Neurons Restart After Kills", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+
+		brain := NewLiquidStateBrainWithConfig(4, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		chaos := NewChaos(ChaosConfig{
+			DropWaveRate:   0.5,
+			SpikeDelayRate: 0.5,
+			SpikeDelay:     time.Millisecond,
+			NeuronKillRate: 0.2,
+			Seed:           1,
+		})
+		brain.SetChaos(chaos)
+
+		if response := brain.Think("hello world"); response == "" {
+			t.Error("Think should still answer under chaos")
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for brain.Restarts() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if brain.Restarts() == 0 || chaos.Stats().KilledNeurons == 0 {
+			t.Errorf("Expected killed neurons to be restarted, got %d restarts, %+v", brain.Restarts(), chaos.Stats())
+		}
+
+		done := make(chan struct{})
+		go func() {
+			brain.Cleanup()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Cleanup hung under chaos")
+		}
+	})
+
+	t.Run("Capability Errors", func(t *testing.T) {
+		orchestrator := &GenesisOrchestrator{neurons: make(map[string]*OrchestratorNeuron)}
+		orchestrator.SetChaos(NewChaos(ChaosConfig{CapabilityErrorRate: 1}))
+		orchestrator.RegisterCapability("echo", func(ctx context.Context, input string) (string, error) {
+			return input, nil
+		})
+
+		for i := 0; i < capabilityFailureLimit; i++ {
+			if _, _, err := orchestrator.neurons["echo"].call(context.Background(), "ping"); !errors.Is(err, errChaosCapability) {
+				t.Fatalf("Expected an injected failure, got %v", err)
+			}
+		}
+		for _, status := range orchestrator.CapabilityHealth() {
+			if status.Healthy {
+				t.Errorf("Capability should be unhealthy: %+v", status)
+			}
+		}
+
+		orchestrator.SetChaos(nil)
+		if out, _, err := orchestrator.neurons["echo"].call(context.Background(), "ping"); err != nil || out != "ping" {
+			t.Errorf("Detached chaos should stop failures, got %q, %v", out, err)
+		}
+	})
+}
+
+// TestEnergyAccounting tests per-request work counts
+func TestEnergyAccounting(t *testing.T) {
+	t.Run("Report Arithmetic", func(t *testing.T) {
+		a := EnergyReport{NeuronUpdates: 5, SpikesPropagated: 3, BeamsExpanded: 2}
+		b := EnergyReport{NeuronUpdates: 1, ExternalTokens: 4}
+		if got := a.Add(b).Sub(b); got != a {
+			t.Errorf("Add then Sub should round-trip, got %+v", got)
+		}
+		var meter *EnergyMeter
+		meter.neuronUpdate()
+		if meter.Snapshot() != (EnergyReport{}) {
+			t.Error("A nil meter should count nothing")
+		}
+	})
+
+	t.Run("Liquid Brain", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+
+		brain := NewLiquidStateBrainWithConfig(4, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		_, energy := brain.ThinkMetered("hello world")
+		if energy.NeuronUpdates == 0 {
+			t.Errorf("Expected neuron updates, got %+v", energy)
+		}
+	})
+
+	t.Run("Transparent LLM", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.MaxConcepts = 100
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create TransparentLLM")
+		}
+		defer llm.Cleanup()
+
+		var word string
+		llm.concepts.Range(func(concept string, _ *ConceptNeuron) bool {
+			word = concept
+			return false
+		})
+		_, explanation, thoughts := llm.UnderstandExplained(word)
+		for range thoughts {
+		}
+		if explanation.Energy.NeuronUpdates == 0 {
+			t.Errorf("Expected concept activations, got %+v", explanation.Energy)
+		}
+	})
+
+	t.Run("External Tokens", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestrator(4)
+		if orchestrator.liquidBrain == nil {
+			t.Fatal("Failed to create orchestrator")
+		}
+		defer orchestrator.liquidBrain.Cleanup()
+
+		_, decisions := orchestrator.Process("calculate two plus two")
+		last := decisions[len(decisions)-1]
+		if last.Energy.ExternalTokens != externalTokens("calculate two plus two", last.Output) {
+			t.Errorf("Capability step should count its tokens, got %+v", last.Energy)
+		}
+	})
+}
+
+// TestWordMatching tests typo and phonetic matching of input words
+func TestWordMatching(t *testing.T) {
+	t.Run("Edit Distance", func(t *testing.T) {
+		cases := []struct {
+			a, b string
+			want int
+		}{
+			{"helo", "hello", 1},
+			{"debg", "debug", 1},
+			{"hlelo", "hello", 1}, // transposition
+			{"kitten", "sitting", 3},
+		}
+		for _, c := range cases {
+			if got := boundedEditDistance(c.a, c.b, 5); got != c.want {
+				t.Errorf("distance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+			}
+		}
+		if got := boundedEditDistance("kitten", "sitting", 1); got != 2 {
+			t.Errorf("Bounded distance should stop at limit+1, got %d", got)
+		}
+	})
+
+	t.Run("Soundex", func(t *testing.T) {
+		for word, want := range map[string]string{
+			"Robert": "R163", "Rupert": "R163", "Ashcraft": "A261",
+			"Tymczak": "T522", "Pfister": "P236", "Lee": "L000",
+		} {
+			if got := soundex(word); got != want {
+				t.Errorf("soundex(%q) = %q, want %q", word, got, want)
+			}
+		}
+	})
+
+	t.Run("Similarity", func(t *testing.T) {
+		matcher := NewWordMatcher(MatchingConfig{MaxEditDistance: 2, MinSimilarity: 0.75, MinWordLength: 4})
+		if s := matcher.Similarity("helo!", "hello"); s != 0.8 {
+			t.Errorf("helo ~ hello = %.2f, want 0.80", s)
+		}
+		if s := matcher.Similarity("the", "she"); s != 0 {
+			t.Errorf("Short words should only match exactly, got %.2f", s)
+		}
+		if s := matcher.Similarity("debug", "design"); s != 0 {
+			t.Errorf("Distant words should not match, got %.2f", s)
+		}
+		phonetic := NewWordMatcher(MatchingConfig{Phonetic: "soundex", PhoneticSimilarity: 0.6})
+		if s := phonetic.Similarity("rupert", "robert"); s != 0.6 {
+			t.Errorf("Phonetic match = %.2f, want 0.60", s)
+		}
+		var off *WordMatcher
+		if off.Similarity("helo", "hello") != 0 || NewWordMatcher(MatchingConfig{}) != nil {
+			t.Error("Disabled matching should match nothing")
+		}
+	})
+
+	t.Run("Typo Activates Concept", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.MaxConcepts = 100
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create TransparentLLM")
+		}
+		defer llm.Cleanup()
+
+		var target *ConceptNeuron
+		llm.concepts.Range(func(concept string, neuron *ConceptNeuron) bool {
+			if len(concept) >= 6 {
+				target = neuron
+				return false
+			}
+			return true
+		})
+		if target == nil {
+			t.Skip("No long enough concept in the vocabulary")
+		}
+		typo := target.id[:2] + target.id[3:]
+		llm.activateWord(typo)
+		if a := target.getActivation(); a < 0.5 {
+			t.Errorf("Typo %q should activate %q, activation %.2f", typo, target.id, a)
+		}
+	})
+}
+
+// TestSimilarityCache tests the pair similarity LRU and normalized vectors
+func TestSimilarityCache(t *testing.T) {
+	t.Run("LRU Eviction", func(t *testing.T) {
+		cache := NewSimilarityCache(similarityCacheShards) // one pair per shard
+		cache.Put("alpha", "beta", 0.5)
+		if v, ok := cache.Get("beta", "alpha"); !ok || v != 0.5 {
+			t.Fatalf("Pairs should be unordered, got %v, %v", v, ok)
+		}
+		for i := 0; i < 100; i++ {
+			cache.Put("alpha", fmt.Sprintf("w%d", i), float64(i))
+		}
+		stats := cache.Stats()
+		if stats.Size > stats.Capacity || stats.Capacity != similarityCacheShards {
+			t.Errorf("Cache grew past its bound: %+v", stats)
+		}
+		if _, ok := cache.Get("alpha", "beta"); ok {
+			t.Error("The oldest pair should have been evicted")
+		}
+	})
+
+	t.Run("Matches Embeddings", func(t *testing.T) {
+		loader, err := NewDatasetLoader(DefaultConfig().Training)
+		if err != nil {
+			t.Fatalf("Failed to load datasets: %v", err)
+		}
+		vocab := loader.GetVocabulary()
+		if len(vocab) < 2 {
+			t.Skip("Vocabulary too small")
+		}
+		a, b := vocab[0], vocab[1]
+		emb1, _ := loader.GetEmbedding(a)
+		emb2, _ := loader.GetEmbedding(b)
+		want := dot(emb1, emb2)
+
+		for i := 0; i < 2; i++ {
+			if got := loader.ComputeSimilarity(a, b); math.Abs(got-want) > 1e-9 {
+				t.Errorf("ComputeSimilarity = %v, want %v", got, want)
+			}
+		}
+		if stats := loader.SimilarityCacheStats(); stats.Hits != 1 || stats.Size != 1 {
+			t.Errorf("Second lookup should hit the cache: %+v", stats)
+		}
+		if loader.ComputeSimilarity(a, "notaword") != 0 {
+			t.Error("Unknown words should have zero similarity")
+		}
+	})
+}
+
+// BenchmarkSemanticConnections compares building semantic connections from
+// per-pair embedding map lookups against the normalized embedding matrix
+func BenchmarkSemanticConnections(b *testing.B) {
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 100
+	llm := NewTransparentLLMWithConfig(config)
+	if llm == nil || llm.dataLoader == nil {
+		b.Fatal("Failed to create LLM")
+	}
+	defer llm.Cleanup()
+	vocab := llm.dataLoader.GetVocabulary()
+	b.Logf("%d vocabulary words", len(vocab))
+
+	b.Run("MapLookups", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for i, word1 := range vocab {
+				kept := 0
+				for j, word2 := range vocab {
+					if i == j {
+						continue
+					}
+					emb1, ok1 := llm.dataLoader.GetEmbedding(word1)
+					emb2, ok2 := llm.dataLoader.GetEmbedding(word2)
+					if ok1 && ok2 && dot(emb1, emb2) > 0.5 && kept < 10 {
+						llm.connect(word1, word2, 0.5)
+						kept++
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("NormalizedMatrix", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			llm.createSemanticConnections()
+		}
+	})
+}
+
+// BenchmarkComputeSimilarity measures repeated pair lookups with and
+// without the similarity cache
+func BenchmarkComputeSimilarity(b *testing.B) {
+	loader, err := NewDatasetLoader(DefaultConfig().Training)
+	if err != nil {
+		b.Fatalf("Failed to load datasets: %v", err)
+	}
+	vocab := loader.GetVocabulary()
+	if len(vocab) > 64 {
+		vocab = vocab[:64]
+	}
+	cache := loader.simCache
+
+	for _, cached := range []bool{false, true} {
+		name := "Uncached"
+		loader.simCache = nil
+		if cached {
+			name = "Cached"
+			loader.simCache = cache
+		}
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				loader.ComputeSimilarity(vocab[n%len(vocab)], vocab[(n/len(vocab))%len(vocab)])
+			}
+		})
+	}
+}
+
+// TestVocabularyOrder tests that the vocabulary is frequency-sorted and stable
+func TestVocabularyOrder(t *testing.T) {
+	first, err := NewDatasetLoader(DefaultConfig().Training)
+	if err != nil {
+		t.Fatalf("Failed to load datasets: %v", err)
+	}
+	second, err := NewDatasetLoader(DefaultConfig().Training)
+	if err != nil {
+		t.Fatalf("Failed to load datasets: %v", err)
+	}
+
+	vocab := first.GetVocabulary()
+	for i := 1; i < len(vocab); i++ {
+		if first.wordFreq[vocab[i-1]] < first.wordFreq[vocab[i]] {
+			t.Fatalf("%q (%v) sorts before more frequent %q (%v)", vocab[i-1], first.wordFreq[vocab[i-1]], vocab[i], first.wordFreq[vocab[i]])
+		}
+	}
+	if fmt.Sprint(vocab) != fmt.Sprint(second.GetVocabulary()) {
+		t.Error("Vocabulary order should be the same on every load")
+	}
+
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 10
+	llm := NewTransparentLLMWithConfig(config)
+	if llm == nil {
+		t.Fatal("Failed to create TransparentLLM")
+	}
+	defer llm.Cleanup()
+	for _, word := range vocab[:10] {
+		if _, ok := llm.concepts.Get(word); !ok {
+			t.Errorf("Frequent word %q should have a concept neuron", word)
+		}
+	}
+}
+
+// TestLanguageDetection tests language tags and language-constrained generation
+func TestLanguageDetection(t *testing.T) {
+	t.Run("Detect", func(t *testing.T) {
+		cases := map[string]string{
+			"What is the best way to learn how to code?":    "en",
+			"Hola, ¿cómo estás? Quiero aprender a programar": "es",
+			"Bonjour, comment ça va? Je suis très content":   "fr",
+			"Hallo, wie geht es dir? Ich bin müde":           "de",
+			"Привет, как дела?":                              "ru",
+			"こんにちは、元気ですか":                                  "ja",
+			"":                                               languageUnknown,
+		}
+		for text, want := range cases {
+			if got, _ := DetectLanguage(text); got != want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+			}
+		}
+	})
+
+	t.Run("Generation Stays In Input Language", func(t *testing.T) {
+		dir := t.TempDir()
+		english := strings.Repeat("The weather is nice today and the sun is shining. You can go for a walk in the park with your friends. ", 5)
+		spanish := strings.Repeat("El tiempo es muy bueno hoy y el sol brilla. Puedes ir a caminar por el parque con tus amigos. ", 5)
+		os.WriteFile(dir+"/en.txt", []byte(english), 0644)
+		os.WriteFile(dir+"/es.txt", []byte(spanish), 0644)
+
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: []string{dir}, MinWordFreq: 1, DisableStarterCorpus: true})
+		if err != nil {
+			t.Fatalf("Failed to load corpus: %v", err)
+		}
+		if langs := loader.Languages(); langs["en"] != 1 || langs["es"] != 1 {
+			t.Fatalf("Expected one English and one Spanish document, got %v", langs)
+		}
+		if lang := loader.GenerationLanguage("hola, ¿como es el tiempo hoy?"); lang != "es" {
+			t.Fatalf("GenerationLanguage = %q, want es", lang)
+		}
+
+		gen := NewResponseGenerator(loader)
+		response, explanation := gen.GenerateExplained("hola, ¿como es el tiempo hoy?", nil)
+		if explanation.Language != "es" {
+			t.Errorf("Explanation language = %q, want es", explanation.Language)
+		}
+		for _, word := range loader.tokenize(response) {
+			if !loader.InLanguage("es", word) {
+				t.Errorf("Response %q contains non-Spanish word %q", response, word)
+			}
+		}
+	})
+}
+
+// TestConceptSchema tests loading concept word lists from a schema file
+func TestConceptSchema(t *testing.T) {
+	t.Run("Default Schema", func(t *testing.T) {
+		schema := DefaultConceptSchema()
+		if len(schema.InputWords) == 0 || len(schema.MeaningNames()) != 6 {
+			t.Fatalf("Unexpected default schema: %d input words, meanings %v", len(schema.InputWords), schema.MeaningNames())
+		}
+		if sim, ok := schema.Similarity("stuck", "frustration"); !ok || sim != 0.8 {
+			t.Errorf("Similarity(stuck, frustration) = %v, %v", sim, ok)
+		}
+	})
+
+	t.Run("Custom Schema", func(t *testing.T) {
+		path := t.TempDir() + "/concepts.json"
+		os.WriteFile(path, []byte(`{
+			"input_words": ["hola", "ayuda"],
+			"meanings": [{"name": "saludo", "concepts": ["hola", "bienvenido"], "response": "¡Hola!"}],
+			"fallback_responses": [{"when": ["ayuda"], "response": "Te ayudo."}],
+			"default_response": "Cuéntame más.",
+			"greeting_words": ["hola"],
+			"question_words": ["qué", "cómo"],
+			"starters": {"greeting": ["hola"]}
+		}`), 0644)
+
+		config := DefaultConfig()
+		config.Concepts.SchemaPath = path
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Config with custom schema should validate: %v", err)
+		}
+		schema := conceptSchemaFromConfig(config)
+
+		brain := &LiquidStateBrain{schema: schema}
+		if got := brain.getActivatedConcepts(map[string]float64{"saludo": 0.9}); len(got) != 2 || got[0] != "hola" {
+			t.Errorf("Activated concepts = %v", got)
+		}
+		if got := brain.simpleInterpretation(map[string]float64{"saludo": 0.9}); got != "¡Hola!" {
+			t.Errorf("Interpretation = %q", got)
+		}
+
+		llm := &TransparentLLM{schema: schema}
+		if got := llm.generateSimpleResponse("ayuda_solución", nil); got != "Te ayudo." {
+			t.Errorf("Fallback response = %q", got)
+		}
+		if got := llm.generateSimpleResponse("otro", nil); got != "Cuéntame más." {
+			t.Errorf("Default response = %q", got)
+		}
+
+		gen := NewResponseGenerator(nil)
+		gen.SetConceptSchema(schema)
+		if got := gen.classifyInput([]string{"hola"}); got != "greeting" {
+			t.Errorf("classifyInput(hola) = %q", got)
+		}
+		if got := gen.classifyInput([]string{"cómo"}); got != "question" {
+			t.Errorf("classifyInput(cómo) = %q", got)
+		}
+	})
+
+	t.Run("Invalid Schema", func(t *testing.T) {
+		path := t.TempDir() + "/concepts.json"
+		os.WriteFile(path, []byte(`{"input_words": [], "meanings": []}`), 0644)
+
+		config := DefaultConfig()
+		config.Concepts.SchemaPath = path
+		if err := config.Validate(); err == nil {
+			t.Error("Expected an empty schema to fail validation")
+		}
+		config.Concepts.SchemaPath = path + ".missing"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected a missing schema file to fail validation")
+		}
+	})
+}
+
+// TestPrivacyRedaction tests PII redaction of text, sessions and decisions
+func TestPrivacyRedaction(t *testing.T) {
+	redactor := NewRedactor(PrivacyConfig{RedactEmails: true, RedactPhones: true, RedactNames: true})
+
+	t.Run("Redact Text", func(t *testing.T) {
+		cases := map[string]string{
+			"Email me at jane.doe@example.com today":        "Email me at [EMAIL] today",
+			"Call +1 (555) 123-4567 after lunch":            "Call [PHONE] after lunch",
+			"The meeting is in room 42 at 3pm":              "The meeting is in room 42 at 3pm",
+			"Yesterday I talked to John Smith about it.":    "Yesterday I talked to [NAME] about it.",
+			"Hello, my name is Alice and I need help":       "Hello, my name is [NAME] and I need help",
+			"Also the annual report was due on Monday.":     "Also the annual report was due on Monday.",
+		}
+		for input, want := range cases {
+			if got := redactor.Redact(input); got != want {
+				t.Errorf("Redact(%q) = %q, want %q", input, got, want)
+			}
+		}
+		if got := (*Redactor)(nil).Redact("bob@example.com"); got != "bob@example.com" {
+			t.Errorf("Nil redactor changed text: %q", got)
+		}
+		if NewRedactor(PrivacyConfig{Sessions: true}) != nil {
+			t.Error("Expected no redactor when no kind is enabled")
+		}
+		if err := (PrivacyConfig{Sessions: true}).validate(); err == nil {
+			t.Error("Expected sessions redaction without a kind to fail validation")
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		store := NewMemorySessionStore()
+		sm := NewSessionManager(store, time.Hour)
+		sm.SetRedactor(redactor)
+		session, _ := sm.Create()
+		sm.Update(session.ID, func(s *Session) error {
+			s.History = append(s.History, ChatMessage{Role: "user", Content: "I'm Bob, reach me at bob@example.com"})
+			s.Feedback = append(s.Feedback, Feedback{Comment: "call 555-123-4567"})
+			return nil
+		})
+
+		stored, err := store.Load(session.ID)
+		if err != nil {
+			t.Fatalf("Failed to load session: %v", err)
+		}
+		if got := stored.History[0].Content; got != "I'm [NAME], reach me at [EMAIL]" {
+			t.Errorf("Stored message = %q", got)
+		}
+		if got := stored.Feedback[0].Comment; got != "call [PHONE]" {
+			t.Errorf("Stored feedback = %q", got)
+		}
+	})
+
+	t.Run("Decision Logs", func(t *testing.T) {
+		orchestrator := &GenesisOrchestrator{decisions: make(chan Decision, 1)}
+		orchestrator.SetRedactor(redactor)
+		orchestrator.logDecisions([]Decision{{Input: "find user data for John Doe", Output: "[DB result for: John Doe]"}})
+
+		logged := <-orchestrator.decisions
+		if logged.Input != "find user data for [NAME]" || logged.Output != "[DB result for: [NAME]]" {
+			t.Errorf("Logged decision not redacted: %+v", logged)
+		}
+	})
+
+	t.Run("Decision Store", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "runs.jsonl")
+		store, err := OpenDecisionStore(path)
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		store.SetRedactor(redactor)
+		run := &FlowRun{
+			Input:     "email jane.doe@example.com",
+			Decisions: []FlowDecision{{Decision: "Contact jane.doe@example.com"}},
+			Pattern:   FlowPattern{Consensus: "Contact jane.doe@example.com"},
+			Consensus: "Contact jane.doe@example.com",
+			Result:    ConsensusResult{Decision: "Contact jane.doe@example.com", Summary: "reached: Contact jane.doe@example.com"},
+		}
+		if err := store.Append(run); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+		store.Close()
+
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), "jane.doe") || !strings.Contains(string(data), "[EMAIL]") {
+			t.Errorf("Stored run not redacted: %s", data)
+		}
+		if run.Input != "email jane.doe@example.com" || run.Decisions[0].Decision != "Contact jane.doe@example.com" {
+			t.Error("Append changed the caller's run")
+		}
+	})
+
+	t.Run("NER Model", func(t *testing.T) {
+		var model TinyModel = NERModel{}
+		if names, confidence := model.Process("Ask Dr Grace Hopper about compilers"); names != "Grace Hopper" || confidence == 0 {
+			t.Errorf("Process = %q, %v", names, confidence)
+		}
+		if names, _ := model.Process("Nothing to see here."); names != "" {
+			t.Errorf("Expected no names, got %q", names)
+		}
+	})
+}
+
+// TestConfidence tests confidence estimates and confidence-based escalation
+func TestConfidence(t *testing.T) {
+	t.Run("Signals", func(t *testing.T) {
+		if m := activationMargin([]float64{0.8, 0.2, 0.1}); math.Abs(m-0.75) > 1e-9 {
+			t.Errorf("activationMargin = %v, want 0.75", m)
+		}
+		if m := activationMargin(nil); m != 0 {
+			t.Errorf("activationMargin(nil) = %v, want 0", m)
+		}
+		if p := beamProbability(1, []float64{1, 1}); math.Abs(p-0.5) > 1e-9 {
+			t.Errorf("beamProbability of a tie = %v, want 0.5", p)
+		}
+		var c Confidence
+		c.combine(0.5, 1.5)
+		if c.Score != 0.75 {
+			t.Errorf("combine clamps signals to [0, 1], got %v", c.Score)
+		}
+	})
+
+	t.Run("Generator", func(t *testing.T) {
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: []string{"datasets/conversational_corpus.txt"}, MinWordFreq: 1})
+		if err != nil {
+			t.Skip("Dataset not available")
+		}
+		_, explanation := NewResponseGenerator(loader).GenerateExplained("tell me about learning", nil)
+		c := explanation.Confidence
+		if c.Score <= 0 || c.Score > 1 {
+			t.Errorf("Expected a confidence in (0, 1], got %+v", c)
+		}
+	})
+
+	t.Run("Liquid Brain And Escalation", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestrator(4)
+		if orchestrator.liquidBrain == nil {
+			t.Fatal("Failed to create orchestrator")
+		}
+		defer orchestrator.liquidBrain.Cleanup()
+
+		_, confidence, _ := orchestrator.liquidBrain.ThinkScored("hello world")
+		if confidence.Score < 0 || confidence.Score > 1 || confidence.ActivationMargin < 0 || confidence.ActivationMargin > 1 {
+			t.Errorf("Confidence out of range: %+v", confidence)
+		}
+
+		orchestrator.SetEscalationThreshold(0)
+		output, decisions := orchestrator.Process("explain quantum computing")
+		if last := decisions[len(decisions)-1]; len(last.Path) != 1 || output != decisions[0].Output {
+			t.Errorf("Expected a local answer at threshold 0, got %v -> %q", last.Path, output)
+		}
+
+		orchestrator.SetEscalationThreshold(2)
+		// Skip retrieval QA, whose answers depend on the corpus embeddings
+		orchestrator.SetFallbackChain("gpt4")
+		_, decisions = orchestrator.Process("explain quantum computing")
+		if last := decisions[len(decisions)-1]; last.Path[len(last.Path)-1] != "gpt4" {
+			t.Errorf("Expected escalation at threshold 2, got %v", last.Path)
+		}
+	})
+
+	t.Run("Transparent LLM", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.MaxConcepts = 100
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create TransparentLLM")
+		}
+		defer llm.Cleanup()
+
+		_, explanation, thoughts := llm.UnderstandExplained("how do I learn to code")
+		for range thoughts {
+		}
+		if c := explanation.Confidence; c.Score < 0 || c.Score > 1 || c.CircuitStrength > 1 {
+			t.Errorf("Confidence out of range: %+v", c)
+		}
+	})
+}
+
+// TestFallbackChain tests the low-confidence fallback chain
+func TestFallbackChain(t *testing.T) {
+	t.Run("Hops", func(t *testing.T) {
+		orchestrator := &GenesisOrchestrator{neurons: make(map[string]*OrchestratorNeuron)}
+		orchestrator.RegisterCapability("qa", func(ctx context.Context, input string) (string, error) {
+			return "", errors.New("no answer")
+		})
+		orchestrator.RegisterCapability("external", func(ctx context.Context, input string) (string, error) {
+			return "external answer", nil
+		})
+		orchestrator.RegisterCapability("clarify", ClarifyCapability("Could you rephrase?"))
+
+		orchestrator.SetFallbackChain("missing", "qa", "external", "clarify")
+		output, hops, ok := orchestrator.runFallbackChain(context.Background(), "question", Confidence{Score: 0.1}, nil)
+		if !ok || output != "external answer" {
+			t.Fatalf("Expected the external answer, got %q (ok=%v)", output, ok)
+		}
+		if len(hops) != 3 {
+			t.Fatalf("Expected 3 recorded hops, got %d", len(hops))
+		}
+		if !strings.Contains(hops[0].Reasoning, "no capability") || !strings.Contains(hops[1].Reasoning, "no answer") {
+			t.Errorf("Failed hops not recorded: %q, %q", hops[0].Reasoning, hops[1].Reasoning)
+		}
+		if path := hops[2].Path; len(path) != 4 || path[3] != "external" {
+			t.Errorf("Unexpected path %v", path)
+		}
+
+		orchestrator.SetFallbackChain("qa")
+		if _, hops, ok := orchestrator.runFallbackChain(context.Background(), "question", Confidence{}, nil); ok || len(hops) != 1 {
+			t.Errorf("Expected an exhausted chain with one hop, got ok=%v, %d hops", ok, len(hops))
+		}
+	})
+
+	t.Run("Process", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestrator(4)
+		if orchestrator.liquidBrain == nil {
+			t.Fatal("Failed to create orchestrator")
+		}
+		defer orchestrator.liquidBrain.Cleanup()
+
+		orchestrator.SetEscalationThreshold(2)
+		orchestrator.SetFallbackChain("clarify")
+		output, decisions := orchestrator.Process("explain quantum computing")
+		if output != DefaultConfig().Fallback.ClarificationQuestion {
+			t.Errorf("Expected the clarification question, got %q", output)
+		}
+		if last := decisions[len(decisions)-1]; last.Path[len(last.Path)-1] != "clarify" {
+			t.Errorf("Expected the clarify hop last, got %v", last.Path)
+		}
+
+		orchestrator.SetFallbackChain()
+		understanding, decisions := orchestrator.Process("explain quantum computing")
+		if understanding != decisions[0].Output || !strings.Contains(decisions[len(decisions)-1].Reasoning, "exhausted") {
+			t.Errorf("Expected the brain's own response after an empty chain, got %q", understanding)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Fallback.Threshold = -1
+		if err := config.Validate(); err == nil {
+			t.Error("Expected a negative threshold to fail validation")
+		}
+	})
+}
+
+// TestClarification tests clarification questions for near-tied meanings
+func TestClarification(t *testing.T) {
+	circuit := func(from, to string, strength float64) CircuitPath {
+		return CircuitPath{nodes: []*ConceptNeuron{{id: from}, {id: to}}, strength: strength}
+	}
+	circuits := []CircuitPath{
+		circuit("code", "error", 0.8),
+		circuit("help", "solution", 0.78),
+		circuit("pattern", "similar", 0.3),
+	}
+
+	t.Run("Competing Meanings", func(t *testing.T) {
+		competing := competingMeanings(circuits, ClarificationConfig{Enabled: true, Margin: 0.1, MaxOptions: 3})
+		if len(competing) != 2 || competing[0] != "code_error" || competing[1] != "help_solution" {
+			t.Fatalf("Unexpected competing meanings %v", competing)
+		}
+		if q := DefaultConceptSchema().clarificationQuestion(competing); q != "Do you mean code error or help solution?" {
+			t.Errorf("Unexpected question %q", q)
+		}
+		if q := DefaultConceptSchema().clarificationQuestion([]string{"a_b", "c_c", "d_e"}); q != "Do you mean a b, c or d e?" {
+			t.Errorf("Unexpected three-way question %q", q)
+		}
+		if competing := competingMeanings(circuits[:1], ClarificationConfig{Enabled: true, Margin: 0.1, MaxOptions: 2}); competing != nil {
+			t.Errorf("A single meaning is not ambiguous, got %v", competing)
+		}
+		if competing := competingMeanings(circuits, ClarificationConfig{Enabled: true, Margin: 0.01, MaxOptions: 2}); competing != nil {
+			t.Errorf("Meanings outside the margin should not compete, got %v", competing)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if err := (ClarificationConfig{Enabled: true, Margin: 0.1, MaxOptions: 1}).validate(); err == nil {
+			t.Error("Expected max_options below 2 to fail validation")
+		}
+		if _, err := parseConceptSchema([]byte(`{"input_words": ["a"], "meanings": [{"name": "m"}], "clarification_template": "Which one?"}`)); err == nil {
+			t.Error("Expected a template without a placeholder to fail validation")
+		}
+	})
+
+	t.Run("Transparent LLM", func(t *testing.T) {
+		// The fallback concept network, without a dataset, has several
+		// strong circuits for this input
+		config := DefaultConfig()
+		config.Training.DatasetPaths = []string{t.TempDir() + "/missing.txt"}
+		config.Training.DisableStarterCorpus = true
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create TransparentLLM")
+		}
+		defer llm.Cleanup()
+
+		// A wide margin makes any two circuit patterns compete
+		llm.SetClarification(ClarificationConfig{Enabled: true, Margin: 0.99, MaxOptions: 2})
+		response, explanation, thoughts := llm.UnderstandExplained("frustration stuck code error question")
+		for range thoughts {
+		}
+		if len(explanation.Clarification) != 2 {
+			t.Fatalf("Expected two competing meanings, got %v (response %q)", explanation.Clarification, response)
+		}
+		if response != llm.schema.clarificationQuestion(explanation.Clarification) {
+			t.Errorf("Clarification response %q doesn't ask about %v", response, explanation.Clarification)
+		}
+	})
+}
+
+// TestTraceJSON tests the shared versioned JSON format for traces and decisions
+func TestTraceJSON(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Thought", func(t *testing.T) {
+		thought := ThoughtTrace{
+			stage:   "UNDERSTANDING",
+			insight: "code error",
+			circuits: []CircuitPath{{
+				nodes:     []*ConceptNeuron{{id: "code"}, {id: "error"}},
+				strength:  0.8,
+				meaning:   "code→error",
+				timestamp: now,
+			}},
+		}
+		data, err := json.Marshal(thought)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var fields map[string]interface{}
+		json.Unmarshal(data, &fields)
+		if fields["schema"] != traceSchema || fields["kind"] != "thought" || fields["stage"] != "UNDERSTANDING" {
+			t.Errorf("Unexpected thought JSON: %s", data)
+		}
+		circuit := fields["circuits"].([]interface{})[0].(map[string]interface{})
+		if circuit["kind"] != "circuit" || circuit["nodes"].([]interface{})[1] != "error" {
+			t.Errorf("Expected circuit nodes as concept ids: %s", data)
+		}
+
+		var decoded ThoughtTrace
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded.stage != thought.stage || len(decoded.circuits) != 1 || decoded.circuits[0].nodes[0].id != "code" || !decoded.circuits[0].timestamp.Equal(now) {
+			t.Errorf("Thought didn't round-trip: %+v", decoded)
+		}
+	})
+
+	t.Run("Decision", func(t *testing.T) {
+		decision := Decision{
+			Input:      "fix my code",
+			Path:       []string{"liquid_brain", "gpt4"},
+			Reasoning:  "low confidence",
+			Output:     "try this",
+			Timestamp:  now,
+			Energy:     EnergyReport{ExternalTokens: 12},
+			Confidence: Confidence{Score: 0.4},
+		}
+		data, err := json.Marshal(decision)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if !strings.Contains(string(data), `"kind":"decision"`) || !strings.Contains(string(data), `"external_tokens":12`) {
+			t.Errorf("Unexpected decision JSON: %s", data)
+		}
+		var decoded Decision
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded.Output != "try this" || len(decoded.Path) != 2 || decoded.Confidence.Score != 0.4 || decoded.Energy.ExternalTokens != 12 {
+			t.Errorf("Decision didn't round-trip: %+v", decoded)
+		}
+	})
+
+	t.Run("FlowDecision", func(t *testing.T) {
+		data, err := json.Marshal(FlowDecision{NeuronID: 3, Activation: 0.7, Decision: "route", Confidence: 0.9, Timestamp: now})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded FlowDecision
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded.NeuronID != 3 || decoded.Decision != "route" || decoded.Confidence != 0.9 {
+			t.Errorf("Flow decision didn't round-trip: %+v", decoded)
+		}
+	})
+
+	t.Run("RejectsOtherVersions", func(t *testing.T) {
+		var d Decision
+		if err := json.Unmarshal([]byte(`{"schema":"genesis.trace/v2","kind":"decision"}`), &d); err == nil {
+			t.Error("Expected an unknown schema version to be rejected")
+		}
+		if err := json.Unmarshal([]byte(`{"schema":"genesis.trace/v1","kind":"thought"}`), &d); err == nil {
+			t.Error("Expected a thought to be rejected as a decision")
+		}
+	})
+
+	t.Run("ReplayDecisions", func(t *testing.T) {
+		dir := t.TempDir()
+		config := DefaultConfig()
+		config.Training.DatasetPaths = []string{dir}
+		loader, err := NewDatasetLoader(config.Training)
+		if err != nil {
+			t.Fatalf("Failed to create loader: %v", err)
+		}
+		path := dir + "/run.jsonl"
+		recorder, err := NewRecorder(path, config, loader, 1)
+		if err != nil {
+			t.Fatalf("Failed to create recorder: %v", err)
+		}
+		recorder.Record("a", ReplayRequest{MessageRequest: MessageRequest{Content: "hi"}}, "hello", nil)
+		recorder.RecordEvent("a", "decision", Decision{Input: "hi", Output: "hello", Timestamp: now})
+		recorder.Close()
+
+		replay, err := LoadReplay(path)
+		if err != nil {
+			t.Fatalf("Failed to load replay: %v", err)
+		}
+		if len(replay.Steps) != 1 || len(replay.Steps[0].Events) != 1 {
+			t.Fatalf("Expected one decision event, got %+v", replay.Steps)
+		}
+		var decoded Decision
+		if err := json.Unmarshal(replay.Steps[0].Events[0].Data, &decoded); err != nil || decoded.Output != "hello" {
+			t.Errorf("Recorded decision didn't decode: %v %+v", err, decoded)
+		}
+	})
+}
+
+// echoModel is a plugin model type that repeats its input
+type echoModel struct{ prefix string }
+
+func (m echoModel) Respond(ctx context.Context, req Request) (Response, error) {
+	return Response{Output: m.prefix + req.Input}, nil
+}
+func (m echoModel) Save(path string) error { return nil }
+func (m echoModel) Load(path string) error { return nil }
+func (m echoModel) Close() error           { return nil }
+
+// TestModel tests the shared Model interface and the model factory
+func TestModel(t *testing.T) {
+	configFor := func(modelType string) *Config {
+		config := DefaultConfig()
+		config.Model.Type = modelType
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		return config
+	}
+
+	t.Run("Factory", func(t *testing.T) {
+		for _, modelType := range []string{"liquid", "enhanced", "orchestrator", "parallel"} {
+			model, err := NewModel(configFor(modelType), 4)
+			if err != nil {
+				t.Fatalf("NewModel(%s) failed: %v", modelType, err)
+			}
+			response, err := model.Respond(context.Background(), Request{Input: "hello world"})
+			if err != nil || response.Output == "" {
+				t.Errorf("%s: expected a response, got %q (%v)", modelType, response.Output, err)
+			}
+			if modelType == "orchestrator" && len(response.Decisions) == 0 {
+				t.Error("Expected the orchestrator to report its decisions")
+			}
+			if err := model.Close(); err != nil {
+				t.Errorf("%s: Close failed: %v", modelType, err)
+			}
+		}
+	})
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		if _, err := NewModel(configFor("evolving"), 4); err == nil {
+			t.Error("Expected an unknown model type to be rejected")
+		}
+		if err := configFor("evolving").Validate(); err == nil {
+			t.Error("Expected Validate to reject an unknown model type")
+		}
+	})
+
+	t.Run("Canceled Context", func(t *testing.T) {
+		model := NewParallelOrchestrator(4)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := model.Respond(ctx, Request{Input: "hello"}); err == nil {
+			t.Error("Expected a canceled context to stop the request")
+		}
+	})
+
+	t.Run("Plugin", func(t *testing.T) {
+		type echoOptions struct {
+			Prefix string `json:"prefix"`
+		}
+		err := RegisterModelType("echo", ModelType{
+			Options: func() any { return &echoOptions{} },
+			New: func(config *Config, options any, size int) (Model, error) {
+				return echoModel{prefix: options.(*echoOptions).Prefix}, nil
+			},
+		})
+		if err != nil && !contains(ModelTypeNames(), "echo") { // registered by an earlier -count run
+			t.Fatalf("RegisterModelType failed: %v", err)
+		}
+		if err := RegisterModelType("echo", ModelType{New: func(*Config, any, int) (Model, error) { return nil, nil }}); err == nil {
+			t.Error("Expected a duplicate model type to be rejected")
+		}
+		if !contains(ModelTypeNames(), "echo") {
+			t.Errorf("Expected echo among the model types, got %v", ModelTypeNames())
+		}
+
+		config := configFor("echo")
+		config.Model.Options = json.RawMessage(`{"prefix": ">> "}`)
+		model, err := NewModel(config, 0)
+		if err != nil {
+			t.Fatalf("NewModel failed: %v", err)
+		}
+		if response, _ := model.Respond(context.Background(), Request{Input: "hi"}); response.Output != ">> hi" {
+			t.Errorf("Expected the plugin's options to be applied, got %q", response.Output)
+		}
+
+		config.Model.Options = json.RawMessage(`{"prefix": 3}`)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected Validate to reject options the model type can't decode")
+		}
+	})
+
+	t.Run("Checkpoint", func(t *testing.T) {
+		dir := t.TempDir()
+		brain := NewLiquidStateBrainWithConfig(4, configFor("liquid"))
+		defer brain.Close()
+		brain.reservoir[1][2][0].state.Store(0.75)
+		if err := brain.Save(dir + "/brain.json"); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		restored := NewLiquidStateBrainWithConfig(4, configFor("liquid"))
+		defer restored.Close()
+		if err := restored.Load(dir + "/brain.json"); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if got := restored.reservoir[1][2][0].state.Load().(float64); got != 0.75 {
+			t.Errorf("Expected restored state 0.75, got %f", got)
+		}
+
+		parallel := NewParallelOrchestrator(4)
+		if err := parallel.Load(dir + "/brain.json"); err == nil {
+			t.Error("Expected a liquid checkpoint to be rejected by the parallel orchestrator")
+		}
+		if err := parallel.Save(dir + "/parallel.json"); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if err := NewParallelOrchestrator(5).Load(dir + "/parallel.json"); err == nil {
+			t.Error("Expected a checkpoint of another size to be rejected")
+		}
+	})
+}
+
+// TestEvolvedReadout tests evolving gate circuits as the liquid brain's readout
+func TestEvolvedReadout(t *testing.T) {
+	t.Run("Discretize", func(t *testing.T) {
+		bits := discretize(map[string]float64{"a": 0.5, "b": 0.45, "c": 0.1}, []string{"a", "b", "c"}, 0.8)
+		if !bits[0] || !bits[1] || bits[2] {
+			t.Errorf("Expected outputs near the strongest to be on, got %v", bits)
+		}
+		if bits := discretize(map[string]float64{}, []string{"a"}, 0.8); bits[0] {
+			t.Error("Expected a silent output layer to be all off")
+		}
+	})
+
+	t.Run("Classify", func(t *testing.T) {
+		rand.Seed(1)
+		outputs := []string{"greeting", "help", "question"}
+		var samples []readoutSample
+		for i := 0; i < 3; i++ {
+			samples = append(samples,
+				readoutSample{bits: []bool{true, false, false}, category: "greeting"},
+				readoutSample{bits: []bool{false, true, false}, category: "help"},
+				readoutSample{bits: []bool{false, false, true}, category: "question"})
+		}
+		readout := evolveReadout(samples, outputs, ReadoutConfig{Mode: "evolved", Level: 0.8, Population: 40, Generations: 40}, globalRand)
+
+		for _, category := range outputs {
+			activations := map[string]float64{"greeting": 0.1, "help": 0.1, "question": 0.1}
+			activations[category] = 0.9
+			if got, ok := readout.Classify(activations); !ok || got != category {
+				t.Errorf("Expected %s, got %q (fitness %v)", category, got, readout.Fitness())
+			}
+		}
+		if _, ok := (*EvolvedReadout)(nil).Classify(map[string]float64{"help": 1}); ok {
+			t.Error("Expected a nil readout to classify nothing")
+		}
+	})
+
+	t.Run("Brain", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		config.Readout = ReadoutConfig{Mode: "evolved", Level: 0.8, Population: 4, Generations: 2}
+		brain := NewLiquidStateBrainWithConfig(4, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+		if brain.readout.Load() == nil {
+			t.Fatal("Expected the evolved mode to breed a readout")
+		}
+		if response := brain.Think("hello there"); response == "" {
+			t.Error("Expected a response through the evolved readout")
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		for _, c := range []ReadoutConfig{
+			{Mode: "magic"},
+			{Mode: "evolved", Level: 0, Population: 10, Generations: 10},
+			{Mode: "evolved", Level: 0.8, Population: 1, Generations: 10},
+		} {
+			if err := c.validate(); err == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+	})
+}
+
+// TestStateVector tests reservoir state feature extraction
+func TestStateVector(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	brain := NewLiquidStateBrainWithConfig(4, config) // 4 x 4 x 2
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+
+	t.Run("Features", func(t *testing.T) {
+		features := brain.Features("hello help", StatePooling{})
+		if len(features) != 32 || len(features) != brain.StateVectorLen(StatePooling{}) {
+			t.Errorf("Expected one feature per neuron, got %d", len(features))
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		brain.Pause()
+		defer brain.Resume()
+		brain.stimulate("hello help")
+		brain.ResetState()
+		for i, state := range brain.StateSnapshot() {
+			if state != 0 {
+				t.Fatalf("Expected neuron %d to be cleared, got %v", i, state)
+			}
+		}
+	})
+
+	t.Run("Pooling", func(t *testing.T) {
+		brain.Pause()
+		defer brain.Resume()
+		brain.activity.WaitQuiet(pulseSettleGrace, pulseSettleTimeout)
+		for x := 0; x < 4; x++ {
+			for y := 0; y < 4; y++ {
+				for z := 0; z < 2; z++ {
+					brain.reservoir[x][y][z].state.Store(0.0)
+				}
+			}
+		}
+		brain.reservoir[0][0][0].state.Store(0.8)
+		brain.reservoir[3][3][1].state.Store(0.4)
+
+		mean := brain.StateVector(StatePooling{Size: 2})
+		if len(mean) != 4 || len(mean) != brain.StateVectorLen(StatePooling{Size: 2}) {
+			t.Fatalf("Expected 2 x 2 x 1 blocks, got %d", len(mean))
+		}
+		if math.Abs(mean[0]-0.1) > 1e-9 || math.Abs(mean[3]-0.05) > 1e-9 || mean[1] != 0 {
+			t.Errorf("Unexpected mean-pooled vector %v", mean)
+		}
+		if peak := brain.StateVector(StatePooling{Size: 2, Max: true}); peak[0] != 0.8 || peak[3] != 0.4 {
+			t.Errorf("Unexpected max-pooled vector %v", peak)
+		}
+	})
+}
+
+// TestOutputTraining tests fitting output neuron weights to labeled inputs
+func TestOutputTraining(t *testing.T) {
+	t.Run("Delta Rule", func(t *testing.T) {
+		// Two outputs reading the same two neurons; each label lights one
+		weights := []*outputWeights{
+			{weights: []float64{0.5, 0.5}},
+			{weights: []float64{0.5, 0.5}},
+		}
+		a, b := []float64{0.9, 0.1}, []float64{0.1, 0.9}
+		samples := [][][]float64{{a, a}, {b, b}, {a, a}, {b, b}}
+		if accuracy := fitOutputWeights(weights, samples, []int{0, 1, 0, 1}); accuracy != 1 {
+			t.Errorf("Expected separable examples to be learned, got accuracy %.2f", accuracy)
+		}
+		if weights[0].apply(a) < 0.7 || weights[0].apply(b) > 0.3 {
+			t.Errorf("Output 0 should fire for a only: %.2f / %.2f", weights[0].apply(a), weights[0].apply(b))
+		}
+	})
+
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+
+	t.Run("Brain", func(t *testing.T) {
+		if _, err := brain.TrainOutputs([]LabeledInput{{Input: "hi", Meaning: "nonsense"}}); err == nil {
+			t.Error("Expected an unknown meaning to be rejected")
+		}
+		var examples []LabeledInput
+		for _, e := range brain.schema.readoutExamples() {
+			examples = append(examples, LabeledInput{Input: e.Input, Meaning: e.Category})
+		}
+		if _, err := brain.TrainOutputs(examples); err != nil {
+			t.Fatalf("TrainOutputs failed: %v", err)
+		}
+		for _, output := range brain.outputLayer {
+			if output.weights.Load() == nil {
+				t.Errorf("Expected %s to be trained", output.meaning)
+			}
+		}
+	})
+
+	t.Run("Checkpoint", func(t *testing.T) {
+		path := t.TempDir() + "/brain.json"
+		if err := brain.Save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		restored := NewLiquidStateBrainWithConfig(4, config)
+		defer restored.Cleanup()
+		if err := restored.Load(path); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		for i, output := range restored.outputLayer {
+			got, want := output.weights.Load(), brain.outputLayer[i].weights.Load()
+			if got == nil || fmt.Sprint(got.weights) != fmt.Sprint(want.weights) || got.connections[0].x != want.connections[0].x {
+				t.Errorf("Output %s weights didn't survive the checkpoint", output.meaning)
+			}
+		}
+	})
+}
+
+// TestOutputCalibration tests per-output activation calibration
+func TestOutputCalibration(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		if err := (CalibrationConfig{}).validate(); err != nil {
+			t.Errorf("Expected disabled calibration to need no settings, got %v", err)
+		}
+		for _, c := range []CalibrationConfig{
+			{Enabled: true, ActiveShare: 0, Quantiles: 21},
+			{Enabled: true, ActiveShare: 1, Quantiles: 21},
+			{Enabled: true, ActiveShare: 0.25, Quantiles: 1},
+		} {
+			if c.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+	})
+
+	t.Run("Normalize", func(t *testing.T) {
+		samples := make([]float64, 101)
+		for i := range samples {
+			samples[i] = 0.02 + 0.001*float64(i) // raw scale 0.02 to 0.12
+		}
+		c := newOutputCalibration(samples, 0.25, 21)
+		if math.Abs(c.Threshold-0.095) > 1e-9 || math.Abs(c.Mean-0.07) > 1e-9 {
+			t.Errorf("Expected threshold 0.095 and mean 0.07, got %+v", c)
+		}
+		if l := c.normalize(c.Threshold); math.Abs(l-conceptActivationLevel) > 1e-9 {
+			t.Errorf("Expected the threshold to map to %.1f, got %v", conceptActivationLevel, l)
+		}
+		if c.normalize(0) != 0 || c.normalize(0.2) != 1 {
+			t.Error("Expected the distribution's ends to map to 0 and 1")
+		}
+		previous := -1.0
+		for raw := 0.0; raw <= 0.13; raw += 0.0005 {
+			l := c.normalize(raw)
+			if l < previous {
+				t.Fatalf("Expected normalization to be monotonic, dropped at %v", raw)
+			}
+			previous = l
+		}
+
+		flat := newOutputCalibration([]float64{0.3, 0.3, 0.3}, 0.25, 21)
+		if !flat.flat() || flat.normalize(0.3) != 0 || flat.normalize(0.9) != 0 {
+			t.Error("Expected an output that never varied to read as inactive")
+		}
+	})
+
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	settings := CalibrationConfig{ActiveShare: 0.25, Quantiles: 11}
+
+	t.Run("Brain", func(t *testing.T) {
+		if _, err := brain.CalibrateOutputs([]string{"hello"}, settings); err == nil {
+			t.Error("Expected a single input to be rejected")
+		}
+		inputs, err := calibrationInputs(settings, brain.schema)
+		if err != nil || len(inputs) < 2 {
+			t.Fatalf("Expected the schema's seed words, got %v (%v)", inputs, err)
+		}
+		calibrations, err := brain.CalibrateOutputs(inputs, settings)
+		if err != nil {
+			t.Fatalf("CalibrateOutputs failed: %v", err)
+		}
+		if len(calibrations) != len(brain.outputLayer) || len(brain.OutputCalibrations()) != len(brain.outputLayer) {
+			t.Fatalf("Expected every output calibrated, got %d", len(calibrations))
+		}
+		for meaning, level := range brain.readOutput() {
+			c := calibrations[meaning]
+			if want := c.normalize(brain.outputLayer[0].read()); meaning == brain.outputLayer[0].meaning && level != want && !c.flat() {
+				t.Errorf("Expected %s to read calibrated, got %v want %v", meaning, level, want)
+			}
+			if level < 0 || level > 1 || len(c.Quantiles) != 11 {
+				t.Errorf("Unexpected %s level %v with %d quantiles", meaning, level, len(c.Quantiles))
+			}
+		}
+	})
+
+	t.Run("Checkpoint And Pool", func(t *testing.T) {
+		path := t.TempDir() + "/brain.json"
+		if err := brain.Save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		restored := NewLiquidStateBrainWithConfig(4, config)
+		defer restored.Cleanup()
+		if err := restored.Load(path); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		want := fmt.Sprint(brain.OutputCalibrations())
+		if got := fmt.Sprint(restored.OutputCalibrations()); got != want {
+			t.Errorf("Calibration didn't survive the checkpoint: %s vs %s", got, want)
+		}
+		for _, output := range restored.outputLayer {
+			if output.weights.Load() != nil {
+				t.Error("Expected an untrained output to stay untrained")
+			}
+		}
+
+		clone := brain.Template().Instantiate()
+		defer clone.Cleanup()
+		if got := fmt.Sprint(clone.OutputCalibrations()); got != want {
+			t.Error("Expected pooled clones to keep the calibration")
+		}
+	})
+}
+
+// TestEnsemble tests running several liquid brains on one input
+func TestEnsemble(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		if err := (EnsembleConfig{}).validate(); err != nil {
+			t.Errorf("Expected the zero ensemble config to be valid, got %v", err)
+		}
+		for _, c := range []EnsembleConfig{{Size: -1}, {Size: maxEnsembleSize + 1}, {Size: 2, Combine: "median"}} {
+			if c.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+	})
+
+	readouts := []map[string]float64{
+		{"greeting": 0.9, "question": 0.2, "code": 0.1},
+		{"greeting": 0.3, "question": 0.6, "code": 0.0},
+		{"greeting": 0.6, "question": 0.1, "code": 0.1},
+		{"greeting": 0.4, "question": 0.4, "code": 0.2},
+	}
+
+	t.Run("Mean", func(t *testing.T) {
+		combined := combineReadouts(readouts, CombineMean)
+		if math.Abs(combined["greeting"]-0.55) > 1e-9 || math.Abs(combined["question"]-0.325) > 1e-9 || math.Abs(combined["code"]-0.1) > 1e-9 {
+			t.Errorf("Unexpected mean readout %v", combined)
+		}
+	})
+
+	t.Run("Vote", func(t *testing.T) {
+		combined := combineReadouts(readouts, CombineVote)
+		// The tied last reservoir splits its vote
+		if combined["greeting"] != 0.625 || combined["question"] != 0.375 || combined["code"] != 0 {
+			t.Errorf("Unexpected vote readout %v", combined)
+		}
+		if _, ok := combined["code"]; !ok {
+			t.Error("Expected outputs without votes to read as 0")
+		}
+		if a := readoutAgreement(readouts); a != 0.75 {
+			t.Errorf("Expected 75%% agreement, got %v", a)
+		}
+		if len(combineReadouts(nil, CombineVote)) != 0 {
+			t.Error("Expected no readouts to combine to nothing")
+		}
+	})
+
+	t.Run("Model", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		config.Model.Type = "ensemble"
+		config.Ensemble = EnsembleConfig{Size: 2, Combine: CombineVote, Seed: 7}
+		model, err := NewModel(config, 4)
+		if err != nil {
+			t.Fatalf("NewModel failed: %v", err)
+		}
+		defer model.Close()
+		ensemble := model.(*LiquidEnsemble)
+		if len(ensemble.Members()) != 2 {
+			t.Fatalf("Expected 2 reservoirs, got %d", len(ensemble.Members()))
+		}
+
+		response, err := model.Respond(context.Background(), Request{Input: "hello there"})
+		if err != nil || response.Output == "" {
+			t.Fatalf("Expected a response, got %q (%v)", response.Output, err)
+		}
+		if response.Energy.NeuronUpdates <= 0 {
+			t.Errorf("Expected the reservoirs' energy to be reported, got %+v", response.Energy)
+		}
+
+		path := t.TempDir() + "/brain.json"
+		if err := model.Save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		for k := range ensemble.Members() {
+			if _, err := os.Stat(memberPath(path, k)); err != nil {
+				t.Errorf("Expected reservoir %d's checkpoint: %v", k, err)
+			}
+		}
+		if err := model.Load(path); err != nil {
+			t.Errorf("Load failed: %v", err)
+		}
+	})
+}
+
+// TestRunTracking tests recording and reading back tracked runs
+func TestRunTracking(t *testing.T) {
+	t.Run("Off", func(t *testing.T) {
+		run, err := StartRun(RunTrackingConfig{}, "train", nil, nil)
+		if run != nil || err != nil {
+			t.Fatalf("Expected no run with tracking off, got %v (%v)", run, err)
+		}
+		run.SetParam("epochs", "1")
+		run.LogMetricStep("accuracy", 1, 0.5)
+		if err := run.SaveJSONArtifact("report.json", 1); err != nil || run.Finish(nil) != nil || run.Dir() != "" {
+			t.Error("Expected a nil run to record nothing")
+		}
+	})
+
+	t.Run("Dataset Hashes", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(dir+"/a.txt", []byte("hello world"), 0644)
+		os.MkdirAll(dir+"/corpus/sub", 0755)
+		os.WriteFile(dir+"/corpus/one.txt", []byte("one"), 0644)
+		os.WriteFile(dir+"/corpus/sub/two.txt", []byte("two"), 0644)
+
+		file := hashDataset(dir + "/a.txt")
+		if file.SHA256 != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" || file.Bytes != 11 || file.Files != 1 {
+			t.Errorf("Unexpected file hash %+v", file)
+		}
+		corpus := hashDataset(dir + "/corpus")
+		if corpus.Files != 2 || corpus.Bytes != 6 || corpus.SHA256 == "" {
+			t.Errorf("Unexpected directory hash %+v", corpus)
+		}
+		os.WriteFile(dir+"/corpus/sub/two.txt", []byte("TWO"), 0644)
+		if hashDataset(dir+"/corpus").SHA256 == corpus.SHA256 {
+			t.Error("Expected a changed file to change the directory's hash")
+		}
+		if missing := hashDataset(dir + "/missing"); missing.Error == "" || missing.SHA256 != "" {
+			t.Errorf("Expected a missing dataset to be reported, got %+v", missing)
+		}
+	})
+
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.Runs.Dir = dir
+	config.Training.DatasetPaths = []string{"datasets/dialogue_patterns.txt"}
+
+	first := TrackRun(config, "train", map[string]string{"epochs": "2"})
+	if first == nil {
+		t.Fatal("Expected a tracked run")
+	}
+	first.LogMetricStep("accuracy", 1, 0.25)
+	first.LogMetricStep("accuracy", 2, 0.5)
+	if err := first.SaveJSONArtifact("report.json", map[string]int{"answer": 42}); err != nil {
+		t.Fatalf("SaveJSONArtifact failed: %v", err)
+	}
+	if err := first.Finish(nil); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	second := TrackRun(config, "train", map[string]string{"epochs": "3"})
+	second.LogMetric("accuracy", 0.75)
+	second.Finish(fmt.Errorf("interrupted"))
+
+	t.Run("Read Back", func(t *testing.T) {
+		runs, err := ListRuns(dir)
+		if err != nil || len(runs) != 2 {
+			t.Fatalf("Expected 2 runs, got %d (%v)", len(runs), err)
+		}
+		run, err := LoadRun(dir, first.ID[:len(first.ID)-2])
+		if err != nil || run.ID != first.ID {
+			t.Fatalf("Expected to find the run by prefix, got %v", err)
+		}
+		if run.Status != RunFinished || run.Finished == nil || run.Params["epochs"] != "2" {
+			t.Errorf("Unexpected run %+v", run)
+		}
+		if run.Metrics["accuracy"] != 0.5 || len(run.History["accuracy"]) != 2 {
+			t.Errorf("Expected the accuracy history, got %v %v", run.Metrics, run.History)
+		}
+		if len(run.Datasets) != 1 || run.Datasets[0].SHA256 == "" || len(run.Config) == 0 {
+			t.Errorf("Expected the config and dataset hash recorded, got %+v", run.Datasets)
+		}
+		if _, err := os.Stat(filepath.Join(run.Dir(), "artifacts", "report.json")); err != nil || len(run.Artifacts) != 1 {
+			t.Errorf("Expected the report artifact, got %v (%v)", run.Artifacts, err)
+		}
+		if failed, _ := LoadRun(dir, second.ID); failed.Status != RunFailed || failed.Error != "interrupted" {
+			t.Errorf("Expected the second run to have failed, got %+v", failed)
+		}
+		if _, err := LoadRun(dir, "nope"); err == nil {
+			t.Error("Expected an unknown run to be an error")
+		}
+	})
+
+	t.Run("Compare", func(t *testing.T) {
+		var buf bytes.Buffer
+		CompareRuns(&buf, []*TrackedRun{first, second})
+		out := buf.String()
+		for _, want := range []string{"* epochs", "* accuracy", "  kind", "datasets/dialogue_patterns.txt"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in the comparison:\n%s", want, out)
+			}
+		}
+		buf.Reset()
+		PrintRuns(&buf, []*TrackedRun{first, second})
+		if strings.Count(buf.String(), "\n") != 3 {
+			t.Errorf("Expected a header and a line per run:\n%s", buf.String())
+		}
+	})
+}
+
+// TestKnowledgeStore tests learning facts and recalling them
+func TestKnowledgeStore(t *testing.T) {
+	t.Run("Extraction", func(t *testing.T) {
+		cases := map[string]string{
+			"My project uses Go.":        "user's project|uses|Go",
+			"our favorite color is blue": "user's favorite color|is|blue",
+			"I work at Acme Corp!":       "user|works at|Acme Corp",
+			"I'm a teacher":              "user|is|a teacher",
+			"I love pizza. Do you?":      "user|likes|pizza",
+			"my dog is not friendly":     "",
+			"what does my project use?":  "",
+			"the weather is nice":        "",
+		}
+		for input, want := range cases {
+			var got []string
+			for _, f := range ExtractFacts(input) {
+				got = append(got, f.Subject+"|"+f.Relation+"|"+f.Object)
+			}
+			if strings.Join(got, ",") != want {
+				t.Errorf("ExtractFacts(%q) = %v, want %q", input, got, want)
+			}
+		}
+		if f := ExtractFacts("My project uses Go")[0]; f.Sentence() != "Your project uses Go." || f.Provenance == "" {
+			t.Errorf("Unexpected fact %+v: %s", f, f.Sentence())
+		}
+		if s := (Fact{Subject: "user", Relation: "works at", Object: "Acme"}).Sentence(); s != "You work at Acme." {
+			t.Errorf("Unexpected sentence %q", s)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if err := (KnowledgeConfig{}).validate(); err != nil {
+			t.Errorf("Expected the zero config to be valid, got %v", err)
+		}
+		for _, c := range []KnowledgeConfig{{Scope: "team"}, {MinConfidence: 1.5}} {
+			if c.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+	})
+
+	config := KnowledgeConfig{Path: filepath.Join(t.TempDir(), "facts.db"), Scope: KnowledgeSession, MinConfidence: 0.5}
+	store, err := OpenKnowledgeStore(config)
+	if err != nil {
+		t.Fatalf("OpenKnowledgeStore failed: %v", err)
+	}
+	defer store.Close()
+
+	t.Run("Store", func(t *testing.T) {
+		if _, err := store.Learn("a", "My project uses Go. I work at Acme."); err != nil {
+			t.Fatalf("Learn failed: %v", err)
+		}
+		store.Learn("a", "my project uses go")
+		store.Assert(Fact{Subject: "user", Relation: "likes", Object: "tea", Confidence: 0.3, Session: "a"})
+		store.Assert(Fact{Subject: "user", Relation: "speaks", Object: "French", Confidence: 0.9})
+
+		facts, err := store.Query("a", "user's project", "uses")
+		if err != nil || len(facts) != 1 || math.Abs(facts[0].Confidence-0.96) > 1e-9 || facts[0].Object != "Go" {
+			t.Fatalf("Expected a repeated fact to gain confidence, got %+v (%v)", facts, err)
+		}
+		if facts, _ := store.Query("b", "user's project", ""); len(facts) != 0 {
+			t.Errorf("Expected session facts to stay in their session, got %+v", facts)
+		}
+		if facts, _ := store.Query("b", "user", ""); len(facts) != 1 || facts[0].Object != "French" {
+			t.Errorf("Expected global facts to be visible everywhere, got %+v", facts)
+		}
+		if facts, _ := store.Query("a", "user", "likes"); len(facts) != 0 {
+			t.Error("Expected facts below min_confidence to be left out")
+		}
+		if err := store.Assert(Fact{Subject: "user", Relation: "is"}); err == nil {
+			t.Error("Expected a fact without an object to be rejected")
+		}
+	})
+
+	t.Run("Recall", func(t *testing.T) {
+		facts, err := store.Recall("a", "what does my project use?")
+		if err != nil || len(facts) != 1 || facts[0].Sentence() != "Your project uses Go." {
+			t.Fatalf("Unexpected recall %+v (%v)", facts, err)
+		}
+		facts, _ = store.Recall("a", "where do I work?")
+		if len(facts) != 1 || facts[0].Relation != "works at" {
+			t.Errorf("Expected the relation the question names, got %+v", facts)
+		}
+		if facts, _ := store.Recall("a", "what do you know about me?"); len(facts) != 2 {
+			t.Errorf("Expected every confident fact about the user, got %+v", facts)
+		}
+		if facts, _ := store.Recall("a", "why is the sky blue?"); facts != nil {
+			t.Errorf("Expected nothing recalled for an unrelated question, got %+v", facts)
+		}
+	})
+
+	t.Run("Reopen And Forget", func(t *testing.T) {
+		reopened, err := OpenKnowledgeStore(config)
+		if err != nil {
+			t.Fatalf("Reopen failed: %v", err)
+		}
+		defer reopened.Close()
+		if facts, _ := reopened.Facts("a"); len(facts) != 4 {
+			t.Errorf("Expected the facts to persist, got %d", len(facts))
+		}
+		reopened.Forget("a")
+		if facts, _ := reopened.Facts("a"); len(facts) != 1 {
+			t.Errorf("Expected only the global fact left, got %+v", facts)
+		}
+		var nilStore *KnowledgeStore
+		if facts, err := nilStore.Learn("a", "my project uses Go"); facts != nil || err != nil {
+			t.Error("Expected a nil store to learn nothing")
+		}
+	})
+
+	t.Run("Session API", func(t *testing.T) {
+		loader, err := NewDatasetLoader(TrainingConfig{EmbeddingDim: 16, MinWordFreq: 1})
+		if err != nil {
+			t.Fatalf("Failed to create dataset loader: %v", err)
+		}
+		generator := NewResponseGenerator(loader)
+		generator.SetKnowledgeStore(store)
+		handler := NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), generator)
+		handler.SetKnowledgeStore(store, config)
+		handler.SetResponseCache(NewResponseCache(ResponseCacheConfig{Enabled: true, Capacity: 10}, RealClock))
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		send := func(id, content string) MessageResponse {
+			resp, err := http.Post(server.URL+"/v1/sessions/"+id+"/messages", "application/json", strings.NewReader(fmt.Sprintf(`{"content":%q}`, content)))
+			if err != nil {
+				t.Fatalf("Message failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var msg MessageResponse
+			json.NewDecoder(resp.Body).Decode(&msg)
+			return msg
+		}
+		create := func() string {
+			resp, err := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var session Session
+			json.NewDecoder(resp.Body).Decode(&session)
+			return session.ID
+		}
+
+		first, second := create(), create()
+		send(first, "My team uses Rust.")
+		msg := send(first, "What does my team use?")
+		if msg.Message.Content != "Your team uses Rust." || msg.Explanation == nil || len(msg.Explanation.Facts) != 1 {
+			t.Errorf("Expected the learned fact as the answer, got %q", msg.Message.Content)
+		}
+		if msg := send(second, "What does my team use?"); msg.Explanation != nil && len(msg.Explanation.Facts) != 0 {
+			t.Errorf("Expected another session not to know it, got %q", msg.Message.Content)
+		}
+
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/v1/sessions/"+first, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		resp.Body.Close()
+		if facts, _ := store.Query(first, "user's team", ""); len(facts) != 0 {
+			t.Errorf("Expected a deleted session's facts to be forgotten, got %+v", facts)
+		}
+	})
+}
+
+// TestLiquidCheckpoint tests rebuilding a liquid brain from its checkpoint
+func TestLiquidCheckpoint(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	dir := t.TempDir()
+
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	brain.Pause()
+	brain.reservoir[1][2][0].state.Store(0.75)
+	calibration := &OutputCalibration{Threshold: 0.3, ActiveShare: 0.25, Quantiles: []float64{0.1, 0.3, 0.6}}
+	brain.outputLayer[0].calibration.Store(calibration)
+	path := dir + "/brain.json"
+	if err := brain.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	t.Run("Round Trip", func(t *testing.T) {
+		restored, err := LoadLiquidStateBrainWithConfig(path, config)
+		if err != nil {
+			t.Fatalf("LoadLiquidStateBrainWithConfig failed: %v", err)
+		}
+		defer restored.Cleanup()
+		if restored.dimensions != brain.dimensions {
+			t.Fatalf("Expected dimensions %+v, got %+v", brain.dimensions, restored.dimensions)
+		}
+		if !restored.Paused() {
+			t.Error("Expected a brain saved paused to load paused")
+		}
+
+		want, got := brain.Template(), restored.Template()
+		for name, pair := range map[string][2]any{
+			"thresholds":   {want.thresholds, got.thresholds},
+			"refractory":   {want.refractory, got.refractory},
+			"connections":  {want.connections, got.connections},
+			"inhibitory":   {want.inhibitory, got.inhibitory},
+			"input words":  {want.inputWords, got.inputWords},
+			"inputs":       {want.inputs, got.inputs},
+			"output words": {want.outputWords, got.outputWords},
+			"outputs":      {want.outputs, got.outputs},
+			"states":       {brain.StateSnapshot(), restored.StateSnapshot()},
+		} {
+			if fmt.Sprint(pair[0]) != fmt.Sprint(pair[1]) {
+				t.Errorf("%s didn't survive the checkpoint", name)
+			}
+		}
+		if len(got.taps) != len(want.taps) {
+			t.Errorf("Expected %d taps, got %d", len(want.taps), len(got.taps))
+		}
+		if c := restored.outputLayer[0].calibration.Load(); c == nil || c.Threshold != calibration.Threshold {
+			t.Errorf("Expected the output calibration to be restored, got %+v", c)
+		}
+	})
+
+	rewrite := func(t *testing.T, name string, change func(*modelCheckpoint)) string {
+		checkpoint, err := loadCheckpoint(path)
+		if err != nil {
+			t.Fatalf("loadCheckpoint failed: %v", err)
+		}
+		change(checkpoint)
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		changed := dir + "/" + name
+		if err := os.WriteFile(changed, data, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return changed
+	}
+
+	t.Run("Runs Unpaused", func(t *testing.T) {
+		running := rewrite(t, "running.json", func(c *modelCheckpoint) { c.Paused = false })
+		restored, err := LoadLiquidStateBrainWithConfig(running, config)
+		if err != nil {
+			t.Fatalf("LoadLiquidStateBrainWithConfig failed: %v", err)
+		}
+		defer restored.Cleanup()
+		if restored.Paused() {
+			t.Fatal("Expected a brain saved running to load running")
+		}
+		if restored.Think("hello world") == "" {
+			t.Error("Expected the restored brain to answer")
+		}
+		if restored.Restarts() != 0 {
+			t.Errorf("Expected the restored brain's goroutines to run cleanly, got %d restarts", restored.Restarts())
+		}
+	})
+
+	t.Run("Rejects Newer Format", func(t *testing.T) {
+		newer := rewrite(t, "newer.json", func(c *modelCheckpoint) { c.Version = checkpointVersion + 1 })
+		if _, err := LoadLiquidStateBrainWithConfig(newer, config); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("Expected a newer checkpoint format to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Needs Topology", func(t *testing.T) {
+		bare := rewrite(t, "bare.json", func(c *modelCheckpoint) { c.Version, c.Topology = 0, nil })
+		if _, err := LoadLiquidStateBrainWithConfig(bare, config); err == nil {
+			t.Error("Expected a checkpoint without topology to be rejected")
+		}
+		// Load still restores it into a brain of the same size
+		same := NewLiquidStateBrainWithConfig(4, config)
+		defer same.Cleanup()
+		if err := same.Load(bare); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+	})
+
+	t.Run("Rejects Bad Wiring", func(t *testing.T) {
+		bad := rewrite(t, "bad.json", func(c *modelCheckpoint) {
+			c.Topology.Connections[0] = append(c.Topology.Connections[0], int32(len(c.States)))
+		})
+		if _, err := LoadLiquidStateBrainWithConfig(bad, config); err == nil {
+			t.Error("Expected a connection outside the reservoir to be rejected")
+		}
+	})
+}
+
+// TestEntityLinking tests resolving references to earlier mentions
+func TestEntityLinking(t *testing.T) {
+	t.Run("Resolve", func(t *testing.T) {
+		var tracker EntityTracker
+		turns := []struct{ input, want string }{
+			{"What does it do?", "What does it do?"},
+			{"My project uses Go.", "My project uses Go."},
+			{"What does it use?", "What does my project use?"},
+			{"I work at Acme. It is a startup.", "I work at Acme. Acme is a startup."},
+			{"Does this project have tests?", "Does my project have tests?"},
+			{"The weather is nice", "The weather is nice"},
+		}
+		for _, turn := range turns {
+			if got, _ := tracker.Resolve(turn.input); got != turn.want {
+				t.Errorf("Resolve(%q) = %q, want %q", turn.input, got, turn.want)
+			}
+		}
+		_, links := tracker.Resolve("Is it big?")
+		if len(links) != 1 || links[0].Mention != "it" || links[0].Subject != "user's project" {
+			t.Errorf("Expected it linked to the project, got %+v", links)
+		}
+
+		for i := 0; i < 2*maxTrackedEntities; i++ {
+			tracker.Resolve(fmt.Sprintf("I met Person%d.", i))
+		}
+		if len(tracker.Recent) != maxTrackedEntities {
+			t.Errorf("Expected %d tracked entities, got %d", maxTrackedEntities, len(tracker.Recent))
+		}
+	})
+
+	t.Run("Named Facts", func(t *testing.T) {
+		cases := map[string]string{
+			"Acme Corp makes rockets.": "acme corp|makes|rockets",
+			"Go is fast":               "go|is|fast",
+			"What is Go?":              "",
+			"This is fine":             "",
+		}
+		for input, want := range cases {
+			var got []string
+			for _, f := range ExtractFacts(input) {
+				got = append(got, f.Subject+"|"+f.Relation+"|"+f.Object)
+			}
+			if strings.Join(got, ",") != want {
+				t.Errorf("ExtractFacts(%q) = %v, want %q", input, got, want)
+			}
+		}
+	})
+
+	t.Run("Session API", func(t *testing.T) {
+		config := KnowledgeConfig{Path: filepath.Join(t.TempDir(), "knowledge.db"), Scope: KnowledgeSession, MinConfidence: 0.5}
+		store, err := OpenKnowledgeStore(config)
+		if err != nil {
+			t.Fatalf("OpenKnowledgeStore failed: %v", err)
+		}
+		defer store.Close()
+		loader, err := NewDatasetLoader(TrainingConfig{EmbeddingDim: 16, MinWordFreq: 1})
+		if err != nil {
+			t.Fatalf("Failed to create dataset loader: %v", err)
+		}
+		generator := NewResponseGenerator(loader)
+		generator.SetKnowledgeStore(store)
+		handler := NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), generator)
+		handler.SetKnowledgeStore(store, config)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+		send := func(content string) MessageResponse {
+			resp, err := http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(fmt.Sprintf(`{"content":%q}`, content)))
+			if err != nil {
+				t.Fatalf("Message failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var msg MessageResponse
+			json.NewDecoder(resp.Body).Decode(&msg)
+			return msg
+		}
+
+		send("My project uses Go.")
+		send("It needs Postgres.")
+		if facts, _ := store.Query(session.ID, "user's project", "needs"); len(facts) != 1 || facts[0].Object != "Postgres" {
+			t.Errorf("Expected a fact about the project learned through it, got %+v", facts)
+		}
+		msg := send("What does it need?")
+		if msg.Message.Content != "Your project needs Postgres." {
+			t.Errorf("Expected the project's fact as the answer, got %q", msg.Message.Content)
+		}
+		if e := msg.Explanation; e == nil || len(e.Entities) != 1 || e.Entities[0].Subject != "user's project" || !e.Entities[0].Known {
+			t.Errorf("Expected it linked to the known project, got %+v", e)
+		}
+	})
+}
+
+// TestInferenceAPI tests the think and understand endpoints
+func TestInferenceAPI(t *testing.T) {
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 100
+	config.Resources.ChannelBufferSize = 10
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	llm := NewTransparentLLMWithConfig(config)
+	defer llm.Cleanup()
+	handler := NewInferenceHandler(brain, llm)
+
+	post := func(handler http.Handler, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("Think", func(t *testing.T) {
+		rec := post(handler, "/v1/think", `{"input": "hello there", "seed": 7}`)
+		if rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp ThinkResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Output == "" {
+			t.Errorf("Expected a reply, got %s (%v)", rec.Body.String(), err)
+		}
+	})
+
+	t.Run("Understand", func(t *testing.T) {
+		rec := post(handler, "/v1/understand", `{"input": "what is love"}`)
+		if rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp UnderstandResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Output == "" || resp.Explanation == nil {
+			t.Errorf("Expected a reply and explanation, got %s (%v)", rec.Body.String(), err)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		rec := post(handler, "/v1/understand", `{"input": "tell me about neural networks", "stream": true}`)
+		if rec.Code != 200 || rec.Header().Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var events []string
+		var last string
+		for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+			event, data, ok := strings.Cut(block, "\n")
+			if !ok || !strings.HasPrefix(event, "event: ") || !strings.HasPrefix(data, "data: ") {
+				t.Fatalf("Malformed event %q", block)
+			}
+			event, data = strings.TrimPrefix(event, "event: "), strings.TrimPrefix(data, "data: ")
+			events = append(events, event)
+			last = data
+			if event == "thought" {
+				var thought ThoughtTrace
+				if err := json.Unmarshal([]byte(data), &thought); err != nil {
+					t.Errorf("Thought event isn't trace JSON: %v", err)
+				}
+			}
+		}
+		if len(events) < 2 || events[0] != "thought" || events[len(events)-1] != "response" {
+			t.Fatalf("Expected thoughts then a response, got %v", events)
+		}
+		var resp UnderstandResponse
+		if err := json.Unmarshal([]byte(last), &resp); err != nil || resp.Output == "" {
+			t.Errorf("Expected the reply in the response event, got %s (%v)", last, err)
+		}
+	})
+
+	t.Run("Audit And Record", func(t *testing.T) {
+		dir := t.TempDir()
+		recorder, err := NewRecorder(filepath.Join(dir, "replay.jsonl"), config, nil, 7)
+		if err != nil {
+			t.Fatalf("Failed to create recorder: %v", err)
+		}
+		audit, err := OpenAuditLog(filepath.Join(dir, "audit.jsonl"), "", "transparent", "test")
+		if err != nil {
+			t.Fatalf("Failed to open audit log: %v", err)
+		}
+		logged := NewInferenceHandler(brain, llm)
+		logged.SetRecorder(recorder)
+		logged.SetAuditLog(audit)
+		for _, path := range []string{"/v1/think", "/v1/understand"} {
+			if rec := post(logged, path, `{"input": "hello there", "seed": 3}`); rec.Code != 200 {
+				t.Fatalf("POST %s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+			}
+		}
+		recorder.Close()
+		audit.Close()
+
+		replay, err := LoadReplay(filepath.Join(dir, "replay.jsonl"))
+		if err != nil {
+			t.Fatalf("Failed to load replay: %v", err)
+		}
+		if len(replay.Steps) != 2 || replay.Steps[0].Request.Endpoint != "/v1/think" || replay.Steps[1].Request.Endpoint != "/v1/understand" || replay.Steps[0].Request.Seed == nil {
+			t.Errorf("Expected both requests recorded with their endpoints and options, got %+v", replay.Steps)
+		}
+		var models []string
+		if err := readAuditLog(filepath.Join(dir, "audit.jsonl"), func(rec AuditRecord) error {
+			models = append(models, rec.Model)
+			return nil
+		}); err != nil {
+			t.Fatalf("Failed to read audit log: %v", err)
+		}
+		if strings.Join(models, " ") != "liquid transparent" {
+			t.Errorf("Expected audit records from the answering models, got %v", models)
+		}
+		if n, err := VerifyAuditLog(filepath.Join(dir, "audit.jsonl"), ""); err != nil || n != 2 {
+			t.Errorf("Expected a valid chain of 2 records, got %d (%v)", n, err)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/think", strings.NewReader(`{"input": "hello there"}`)).WithContext(ctx))
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "canceled") {
+			t.Errorf("Expected a canceled request to stop, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := []struct {
+			handler http.Handler
+			path    string
+			body    string
+			code    int
+		}{
+			{handler, "/v1/think", `{"input": "  "}`, http.StatusBadRequest},
+			{handler, "/v1/think", `{"input": "hi", "stream": true}`, http.StatusBadRequest},
+			{handler, "/v1/understand", `{"input": "hi", "max_tokens": -1}`, http.StatusBadRequest},
+			{handler, "/v1/other", `{"input": "hi"}`, http.StatusNotFound},
+			{NewInferenceHandler(nil, llm), "/v1/think", `{"input": "hi"}`, http.StatusNotFound},
+			{NewInferenceHandler(brain, nil), "/v1/understand", `{"input": "hi"}`, http.StatusNotFound},
+		}
+		for _, c := range cases {
+			if rec := post(c.handler, c.path, c.body); rec.Code != c.code {
+				t.Errorf("POST %s %s: expected %d, got %d", c.path, c.body, c.code, rec.Code)
+			}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/think", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected GET to be rejected, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if err := (InferenceConfig{Think: true, BrainSize: 1}).validate(); err == nil {
+			t.Error("Expected a brain too small to think to be rejected")
+		}
+		if err := (InferenceConfig{Understand: true}).validate(); err != nil {
+			t.Errorf("Expected understand alone not to need a brain size: %v", err)
+		}
+	})
+}
+
+// TestModelComparison tests running one input through several models
+func TestModelComparison(t *testing.T) {
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 100
+	config.Resources.ChannelBufferSize = 10
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	llm := NewTransparentLLMWithConfig(config)
+	defer llm.Cleanup()
+	orchestrator := NewGenesisOrchestratorWithConfig(4, config)
+	if orchestrator.liquidBrain == nil {
+		t.Fatal("Failed to create orchestrator")
+	}
+	defer orchestrator.Close()
+	models := []ComparedModel{
+		{Name: "transparent", Model: llm},
+		{Name: "liquid", Model: brain},
+		{Name: "orchestrator", Model: orchestrator},
+	}
+
+	t.Run("Compare", func(t *testing.T) {
+		var broken *LiquidStateBrain
+		seed := int64(3)
+		comparison := CompareModels(context.Background(), append(models, ComparedModel{Name: "broken", Model: broken}), Request{Input: "calculate 2 plus 2", Seed: &seed})
+		if comparison.Input != "calculate 2 plus 2" || len(comparison.Results) != 4 {
+			t.Fatalf("Unexpected comparison %+v", comparison)
+		}
+		for i, name := range []string{"transparent", "liquid", "orchestrator"} {
+			r := comparison.Results[i]
+			if r.Model != name || r.Error != "" || r.Output == "" {
+				t.Errorf("Expected an answer from %s, got %+v", name, r)
+			}
+			if r.LatencyMS <= 0 || r.LatencyMS > comparison.LatencyMS+1 {
+				t.Errorf("%s latency %.3fms out of range (comparison %.3fms)", name, r.LatencyMS, comparison.LatencyMS)
+			}
+		}
+		if comparison.Results[0].Explanation == nil {
+			t.Error("Expected the transparent model's explanation")
+		}
+		if len(comparison.Results[2].Decisions) < 2 {
+			t.Errorf("Expected the orchestrator's decisions, got %+v", comparison.Results[2].Decisions)
+		}
+		if broken := comparison.Results[3]; broken.Error == "" || broken.Output != "" {
+			t.Errorf("Expected the broken model to report its error alone, got %+v", broken)
+		}
+	})
+
+	handler := NewInferenceHandler(brain, llm)
+	handler.SetComparison(models...)
+	post := func(handler http.Handler, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("API", func(t *testing.T) {
+		rec := post(handler, "/v1/compare", `{"input": "calculate 2 plus 2", "models": ["orchestrator", "liquid"], "seed": 3, "max_tokens": 10}`)
+		if rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var comparison Comparison
+		if err := json.Unmarshal(rec.Body.Bytes(), &comparison); err != nil {
+			t.Fatalf("Failed to decode comparison: %v", err)
+		}
+		if len(comparison.Results) != 2 || comparison.Results[0].Model != "orchestrator" || comparison.Results[1].Model != "liquid" {
+			t.Fatalf("Expected the requested models in order, got %s", rec.Body.String())
+		}
+		if len(comparison.Results[0].Decisions) == 0 {
+			t.Errorf("Expected decisions in the orchestrator's result, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := []struct {
+			handler http.Handler
+			path    string
+			body    string
+			code    int
+		}{
+			{handler, "/v1/compare", `{"input": "hi", "models": ["gpt"]}`, http.StatusBadRequest},
+			{handler, "/v1/compare", `{"input": "hi", "stop": ["end"]}`, http.StatusBadRequest},
+			{handler, "/v1/compare", `{"input": "hi", "stream": true}`, http.StatusBadRequest},
+			{handler, "/v1/think", `{"input": "hi", "models": ["liquid"]}`, http.StatusBadRequest},
+			{NewInferenceHandler(brain, llm), "/v1/compare", `{"input": "hi"}`, http.StatusNotFound},
+		}
+		for _, c := range cases {
+			if rec := post(c.handler, c.path, c.body); rec.Code != c.code {
+				t.Errorf("POST %s %s: expected %d, got %d", c.path, c.body, c.code, rec.Code)
+			}
+		}
+		if err := (InferenceConfig{Compare: true, BrainSize: 1}).validate(); err == nil {
+			t.Error("Expected a brain too small to compare to be rejected")
+		}
+	})
+
+	t.Run("Capability Policy", func(t *testing.T) {
+		var calls atomic.Int64
+		orchestrator.RegisterCapability("claude", func(ctx context.Context, input string) (string, error) {
+			calls.Add(1)
+			return "external answer", nil
+		})
+		orchestrator.SetEscalationThreshold(0)
+
+		handler.SetCapabilityPolicies(CapabilityPolicyConfig{
+			Policies: map[string]CapabilityPolicy{"local_only": {Deny: []string{"gpt4", "claude"}}},
+			APIKeys:  map[string]string{"private": "local_only"},
+		})
+		auth := NewAPIAuth(ServerConfig{APIKeys: []APIKeyConfig{{Name: "private", Key: "secret-1"}, {Name: "open", Key: "secret-2"}}})
+		compare := func(key string) Comparison {
+			req := httptest.NewRequest("POST", "/v1/compare", strings.NewReader(`{"input": "write a creative story about robots", "models": ["orchestrator"]}`))
+			req.Header.Set("Authorization", "Bearer "+key)
+			rec := httptest.NewRecorder()
+			auth.Middleware(handler).ServeHTTP(rec, req)
+			var comparison Comparison
+			if err := json.Unmarshal(rec.Body.Bytes(), &comparison); err != nil || len(comparison.Results) != 1 {
+				t.Fatalf("Compare failed: %d %s", rec.Code, rec.Body.String())
+			}
+			return comparison
+		}
+
+		result := compare("secret-1").Results[0]
+		if calls.Load() != 0 || result.Output == "external answer" {
+			t.Errorf("A denied capability should not be engaged, made %d calls", calls.Load())
+		}
+		for _, d := range result.Decisions {
+			if d.Policy != "local_only" {
+				t.Errorf("Expected every decision under the caller's policy, got %q", d.Policy)
+			}
+		}
+		if result := compare("secret-2").Results[0]; calls.Load() != 1 || result.Output != "external answer" {
+			t.Errorf("Callers without a policy should still reach claude, got %q", result.Output)
+		}
+	})
+}
+
+// TestCapabilitySandbox tests the limits capability calls run under
+func TestCapabilitySandbox(t *testing.T) {
+	echo := func(ctx context.Context, input string) (string, error) {
+		return input, nil
+	}
+
+	t.Run("Sizes", func(t *testing.T) {
+		limits := SandboxLimits{MaxPromptBytes: 5, MaxResponseBytes: 2, Oversize: OversizeTruncate}
+		result, violations, err := sandboxed(context.Background(), "echo", limits, echo, "héllo world")
+		if err != nil {
+			t.Fatalf("Truncation shouldn't fail the call: %v", err)
+		}
+		if result != "h" {
+			t.Errorf("Expected the prompt and response cut on character boundaries, got %q", result)
+		}
+		if len(violations) != 2 || violations[0].Limit != "prompt_size" || violations[1].Limit != "response_size" || violations[0].Action != "truncated" {
+			t.Errorf("Unexpected violations %+v", violations)
+		}
+
+		limits.Oversize = OversizeReject
+		_, violations, err = sandboxed(context.Background(), "echo", limits, echo, "hello world")
+		if !errors.Is(err, ErrCapabilityPromptSize) || len(violations) != 1 || violations[0].Action != "rejected" {
+			t.Errorf("Expected a rejected prompt, got %v, %+v", err, violations)
+		}
+		_, _, err = sandboxed(context.Background(), "echo", limits, echo, "hello")
+		if !errors.Is(err, ErrCapabilityResponseSize) {
+			t.Errorf("Expected a rejected response, got %v", err)
+		}
+	})
+
+	t.Run("ContentType", func(t *testing.T) {
+		limits := SandboxLimits{ContentTypes: []string{"text/plain"}}
+		if _, _, err := sandboxed(context.Background(), "echo", limits, echo, "<html><body>hi</body></html>"); !errors.Is(err, ErrCapabilityContentType) {
+			t.Errorf("Expected HTML to be rejected, got %v", err)
+		}
+		if result, violations, err := sandboxed(context.Background(), "echo", limits, echo, "plain answer"); err != nil || result != "plain answer" || len(violations) != 0 {
+			t.Errorf("Expected plain text through untouched, got %q, %v, %v", result, violations, err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		hang := func(ctx context.Context, input string) (string, error) {
+			<-release // ignores its context
+			return "late", nil
+		}
+		start := time.Now()
+		_, violations, err := sandboxed(context.Background(), "hang", SandboxLimits{TimeoutMS: 20}, hang, "input")
+		if !errors.Is(err, ErrCapabilityTimeout) || len(violations) != 1 {
+			t.Errorf("Expected a timeout, got %v, %+v", err, violations)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Hanging call wasn't abandoned (%v)", elapsed)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, violations, err := sandboxed(ctx, "hang", SandboxLimits{TimeoutMS: 1000}, hang, "input"); err != context.Canceled || len(violations) != 0 {
+			t.Errorf("Expected a cancelled caller to be no violation, got %v, %+v", err, violations)
+		}
+	})
+
+	t.Run("Decisions", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestrator(4)
+		if orchestrator.liquidBrain == nil {
+			t.Fatal("Failed to create orchestrator")
+		}
+		defer orchestrator.liquidBrain.Cleanup()
+
+		orchestrator.RegisterCapability("calculator", func(ctx context.Context, input string) (string, error) {
+			return strings.Repeat("4", 100), nil
+		})
+		orchestrator.SetCapabilitySandbox(CapabilitySandboxConfig{
+			SandboxLimits: SandboxLimits{MaxResponseBytes: 1000},
+			Capabilities:  map[string]SandboxLimits{"calculator": {MaxResponseBytes: 10, Oversize: OversizeTruncate}},
+		})
+		output, decisions := orchestrator.Process("calculate 2 + 2")
+		if output != strings.Repeat("4", 10) {
+			t.Errorf("Expected the truncated response, got %q", output)
+		}
+		last := decisions[len(decisions)-1]
+		if len(last.Sandbox) != 1 || last.Sandbox[0].Capability != "calculator" || last.Sandbox[0].Limit != "response_size" {
+			t.Fatalf("Violation not recorded: %+v", last.Sandbox)
+		}
+
+		data, err := json.Marshal(last)
+		if err != nil {
+			t.Fatalf("Failed to encode decision: %v", err)
+		}
+		var decoded Decision
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to decode decision: %v", err)
+		}
+		if len(decoded.Sandbox) != 1 || !errors.Is(decoded.Sandbox[0], ErrCapabilityResponseSize) {
+			t.Errorf("Violation lost in the trace JSON: %s", data)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		config := DefaultConfig()
+		if config.CapabilitySandbox.MaxResponseBytes <= 0 || config.CapabilitySandbox.Oversize != OversizeTruncate {
+			t.Errorf("Unexpected default sandbox %+v", config.CapabilitySandbox)
+		}
+		config.CapabilitySandbox.Oversize = "explode"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected an unknown oversize action to fail validation")
+		}
+		config.CapabilitySandbox.Oversize = OversizeReject
+		config.CapabilitySandbox.Capabilities = map[string]SandboxLimits{"calculator": {TimeoutMS: -1}}
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "calculator") {
+			t.Errorf("Expected a negative per-capability limit to fail validation, got %v", err)
+		}
+	})
+}
+
+// TestDeterministicMode tests reproducing runs from the config's seed
+func TestDeterministicMode(t *testing.T) {
+	wiring := func(t *testing.T, brain *LiquidStateBrain) []byte {
+		t.Helper()
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		data, err := json.Marshal(brain.Template().topology())
+		if err != nil {
+			t.Fatalf("Failed to encode topology: %v", err)
+		}
+		return data
+	}
+
+	t.Run("Brain", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Seed = 42
+		first := NewLiquidStateBrainWithConfig(4, config)
+		defer first.Cleanup()
+		second := NewLiquidStateBrainWithConfig(4, config)
+		defer second.Cleanup()
+		if !bytes.Equal(wiring(t, first), wiring(t, second)) {
+			t.Error("Expected brains built from the same seed to be wired alike")
+		}
+
+		config.Seed = 43
+		other := NewLiquidStateBrainWithConfig(4, config)
+		defer other.Cleanup()
+		if bytes.Equal(wiring(t, first), wiring(t, other)) {
+			t.Error("Expected another seed to wire the brain differently")
+		}
+	})
+
+	t.Run("Embeddings", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "rockets.txt"), []byte("rockets launch into orbit with powerful engines and fuel"), 0644)
+		config := TrainingConfig{DatasetPaths: []string{dir}, MaxVocabSize: 1000, EmbeddingDim: 16, MinWordFreq: 1, MaxDocuments: 10}
+		first, err := NewDatasetLoader(config)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		second, _ := NewDatasetLoader(config)
+		a, _ := first.GetEmbedding("orbit")
+		b, _ := second.GetEmbedding("orbit")
+		if len(a) == 0 || fmt.Sprint(a) != fmt.Sprint(b) {
+			t.Error("Expected a corpus to get the same embeddings on every load")
+		}
+	})
+
+	t.Run("Ensemble", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Seed = 42
+		config.Ensemble = EnsembleConfig{Size: 2, Combine: CombineMean}
+		ensemble, err := NewLiquidEnsemble(4, config)
+		if err != nil {
+			t.Fatalf("Failed to build ensemble: %v", err)
+		}
+		defer ensemble.Close()
+		members := ensemble.Members()
+		if bytes.Equal(wiring(t, members[0]), wiring(t, members[1])) {
+			t.Error("Expected seeded members to be wired from their own seeds")
+		}
+		if members[1].config.Seed != 43 || config.Seed != 42 {
+			t.Errorf("Unexpected member seed %d (config seed %d)", members[1].config.Seed, config.Seed)
+		}
+	})
+
+	t.Run("Evolution", func(t *testing.T) {
+		testCases := []TestCase{
+			{[]bool{false, false}, false},
+			{[]bool{false, true}, true},
+			{[]bool{true, false}, true},
+			{[]bool{true, true}, false},
+		}
+		evolve := func() []string {
+			evolution := NewEvolutionWithRand(30, testCases, newRand(7))
+			var trace []string
+			for gen := 0; gen < 15; gen++ {
+				evolution.RunGeneration()
+				ids := make([]string, 0, len(evolution.bestCircuit.gates))
+				for _, gate := range evolution.bestCircuit.gates {
+					ids = append(ids, gate.ID())
+				}
+				trace = append(trace, fmt.Sprintf("%.4f %v", evolution.bestFitness, ids))
+			}
+			return trace
+		}
+		first, second := evolve(), evolve()
+		if strings.Join(first, "\n") != strings.Join(second, "\n") {
+			t.Errorf("Expected the same evolution from the same seed:\n%v\n%v", first, second)
+		}
+	})
+
+	t.Run("Generation", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+		loader, err := NewDatasetLoader(TrainingConfig{
+			DatasetPaths:         []string{dir + "/pets.txt"},
+			MaxVocabSize:         100,
+			EmbeddingDim:         16,
+			MinWordFreq:          1,
+			MaxDocuments:         10,
+			DisableStarterCorpus: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		generate := func() []string {
+			gen := NewResponseGenerator(loader)
+			gen.SetSeed(9)
+			var responses []string
+			for _, input := range []string{"the cats and the dogs", "dogs play in the park"} {
+				response, explanation := gen.GenerateExplained(input, strings.Fields(input))
+				responses = append(responses, fmt.Sprintf("%d %s", *explanation.Seed, response))
+			}
+			return responses
+		}
+		first, second := generate(), generate()
+		if strings.Join(first, "\n") != strings.Join(second, "\n") {
+			t.Errorf("Expected the same responses from the same seed:\n%v\n%v", first, second)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		build := func(seed int64) string {
+			po := NewParallelOrchestratorWithSeed(50, seed)
+			run := po.snapshot("")
+			return fmt.Sprint(run.Thresholds, run.Capabilities, run.Connections, po.injectionPoints(nil))
+		}
+		first := build(42)
+		if first != build(42) {
+			t.Error("Expected orchestrators from the same seed to be wired and injected alike")
+		}
+		if first == build(43) {
+			t.Error("Expected another seed to wire the orchestrator differently")
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if seed := DefaultConfig().Seed; seed != 0 {
+			t.Errorf("Expected runs to be unseeded by default, got seed %d", seed)
+		}
+		if newRand(0) != globalRand || newRand(1) == globalRand {
+			t.Error("Expected only seed 0 to use the global math/rand")
+		}
+	})
+}
+
+// TestOpenInferenceExport tests mapping responses onto OpenInference spans
+func TestOpenInferenceExport(t *testing.T) {
+	start := time.Now()
+	seed := int64(7)
+
+	t.Run("Transparent", func(t *testing.T) {
+		response := Response{
+			Output:     "water is wet",
+			Confidence: Confidence{Score: 0.8},
+			Explanation: &Explanation{
+				ActiveConcepts: []string{"water"},
+				Retrieved:      []Citation{{ChunkID: 3, Path: "facts.txt", Score: 0.9, Text: "Water is wet."}},
+				FinishReason:   "stop",
+				Seed:           &seed,
+			},
+		}
+		thoughts := []TracedThought{
+			{Thought: ThoughtTrace{stage: "CONCEPT_ACTIVATION", insight: "water"}, At: start.Add(time.Millisecond)},
+			{Thought: ThoughtTrace{stage: "GENERATION_FAILED", insight: "no words"}, At: start.Add(2 * time.Millisecond)},
+		}
+		spans := OpenInferenceSpans("transparent", "is water wet", start, response, thoughts, nil)
+		if len(spans) != 4 {
+			t.Fatalf("Expected a root, a retrieval and 2 thought spans, got %+v", spans)
+		}
+		root := spans[0]
+		if root.SpanKind != spanKindLLM || root.ParentID != "" || root.StatusCode != spanStatusOK {
+			t.Errorf("Unexpected root span %+v", root)
+		}
+		if len(root.Context.TraceID) != 32 || len(root.Context.SpanID) != 16 {
+			t.Errorf("Expected 32 and 16 hex digit IDs, got %+v", root.Context)
+		}
+		if root.Attributes["llm.token_count.prompt"] != 3 || root.Attributes["llm.token_count.total"] != 6 || root.Attributes["output.value"] != "water is wet" {
+			t.Errorf("Unexpected root attributes %v", root.Attributes)
+		}
+		if root.Attributes["llm.invocation_parameters"] != `{"seed":7}` || root.Attributes["genesis.confidence"] != 0.8 {
+			t.Errorf("Expected the seed and confidence on the root, got %v", root.Attributes)
+		}
+		for _, span := range spans[1:] {
+			if span.Context.TraceID != root.Context.TraceID || span.ParentID != root.Context.SpanID {
+				t.Errorf("Expected %s to be a child of the root, got %+v", span.Name, span.Context)
+			}
+		}
+		if retrieval := spans[1]; retrieval.SpanKind != spanKindRetriever || retrieval.Attributes["retrieval.documents.0.document.content"] != "Water is wet." {
+			t.Errorf("Unexpected retrieval span %+v", retrieval)
+		}
+		if thought := spans[2]; thought.SpanKind != spanKindChain || thought.Name != "concept_activation" || !thought.EndTime.Equal(thoughts[0].At.UTC()) {
+			t.Errorf("Unexpected thought span %+v", thought)
+		}
+		if failed := spans[3]; failed.StatusCode != spanStatusError || failed.StatusMessage != "no words" || !failed.StartTime.Equal(thoughts[0].At.UTC()) {
+			t.Errorf("Expected the failed thought to be an error from the one before, got %+v", failed)
+		}
+
+		spans = OpenInferenceSpans("liquid", "hi", start, Response{}, nil, errors.New("brain is shut down"))
+		if len(spans) != 1 || spans[0].StatusCode != spanStatusError || spans[0].StatusMessage != "brain is shut down" {
+			t.Errorf("Expected one failed span, got %+v", spans)
+		}
+	})
+
+	t.Run("Decisions", func(t *testing.T) {
+		response := Response{
+			Output: "4",
+			Decisions: []Decision{
+				{Input: "calculate 2 plus 2", Path: []string{"input", "liquid_brain"}, Output: "math", Timestamp: start.Add(time.Millisecond)},
+				{Input: "2 plus 2", Path: []string{"liquid_brain", "calculator"}, Output: "4", Policy: "no_external", Timestamp: start.Add(2 * time.Millisecond),
+					Sandbox: []SandboxViolation{{Capability: "calculator", Limit: "timeout", Action: "rejected", Detail: "took 2s"}}},
+			},
+		}
+		spans := OpenInferenceSpans("orchestrator", "calculate 2 plus 2", start, response, nil, nil)
+		if len(spans) != 3 || spans[0].SpanKind != spanKindAgent {
+			t.Fatalf("Expected an agent span and 2 steps, got %+v", spans)
+		}
+		if brain := spans[1]; brain.SpanKind != spanKindLLM || brain.Attributes["llm.model_name"] != "genesis-liquid" {
+			t.Errorf("Expected the brain's step as an LLM span, got %+v", brain)
+		}
+		tool := spans[2]
+		if tool.SpanKind != spanKindTool || tool.Attributes["tool.name"] != "calculator" || tool.Attributes["genesis.policy"] != "no_external" {
+			t.Errorf("Unexpected tool span %+v", tool)
+		}
+		if tool.StatusCode != spanStatusError || !strings.Contains(tool.Attributes["genesis.sandbox"].(string), `"timeout"`) {
+			t.Errorf("Expected the rejected call to fail its span, got %+v", tool)
+		}
+	})
+
+	t.Run("Comparison", func(t *testing.T) {
+		comparison := &Comparison{Input: "hi", LatencyMS: 5, Results: []ModelResult{
+			{Model: "liquid", Output: "hello", LatencyMS: 5},
+			{Model: "broken", Error: "boom", LatencyMS: 1},
+		}}
+		spans := ComparisonSpans(comparison, start)
+		if len(spans) != 3 || spans[0].SpanKind != spanKindChain || spans[0].Name != "compare" {
+			t.Fatalf("Expected a comparison span over 2 models, got %+v", spans)
+		}
+		for i, name := range []string{"liquid", "broken"} {
+			if span := spans[i+1]; span.Name != name || span.ParentID != spans[0].Context.SpanID {
+				t.Errorf("Expected %s under the comparison, got %+v", name, span)
+			}
+		}
+		if spans[2].StatusCode != spanStatusError || !spans[0].EndTime.Equal(start.Add(5*time.Millisecond).UTC()) {
+			t.Errorf("Unexpected spans %+v", spans)
+		}
+	})
+
+	read := func(t *testing.T, path string) []OpenInferenceSpan {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read spans: %v", err)
+		}
+		var spans []OpenInferenceSpan
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var span OpenInferenceSpan
+			if err := json.Unmarshal([]byte(line), &span); err != nil {
+				t.Fatalf("Failed to decode span %q: %v", line, err)
+			}
+			spans = append(spans, span)
+		}
+		return spans
+	}
+
+	t.Run("Exporter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "spans.jsonl")
+		exporter, err := OpenSpanExporter(OpenInferenceConfig{Path: path}, NewRedactor(PrivacyConfig{RedactEmails: true}))
+		if err != nil {
+			t.Fatalf("Failed to open exporter: %v", err)
+		}
+		spans := OpenInferenceSpans("liquid", "mail bob@example.com", start, Response{Output: "ok"}, nil, errors.New("bounced from bob@example.com"))
+		if err := exporter.Export(spans); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		if err := exporter.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		exported := read(t, path)
+		if len(exported) != 1 || exported[0].Attributes["input.value"] != "mail [EMAIL]" || exported[0].StatusMessage != "bounced from [EMAIL]" {
+			t.Errorf("Expected redacted spans, got %+v", exported)
+		}
+		if spans[0].Attributes["input.value"] != "mail bob@example.com" {
+			t.Error("Expected export to leave the caller's spans alone")
+		}
+
+		var off *SpanExporter
+		if err := off.Export(spans); err != nil || off.Close() != nil {
+			t.Error("Expected a nil exporter to do nothing")
+		}
+		if _, err := OpenSpanExporter(OpenInferenceConfig{Path: filepath.Join(path, "spans.jsonl")}, nil); err == nil {
+			t.Error("Expected an unwritable path to fail")
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.MaxConcepts = 100
+		config.Resources.ChannelBufferSize = 10
+		llm := NewTransparentLLMWithConfig(config)
+		defer llm.Cleanup()
+		path := filepath.Join(t.TempDir(), "spans.jsonl")
+		exporter, err := OpenSpanExporter(OpenInferenceConfig{Path: path}, nil)
+		if err != nil {
+			t.Fatalf("Failed to open exporter: %v", err)
+		}
+		handler := NewInferenceHandler(nil, llm)
+		handler.SetSpanExporter(exporter)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/understand", strings.NewReader(`{"input": "what is water", "seed": 1}`)))
+		if rec.Code != 200 {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		exporter.Close()
+		spans := read(t, path)
+		if len(spans) < 2 || spans[0].Name != "transparent" || spans[0].Attributes["input.value"] != "what is water" {
+			t.Fatalf("Expected the request's spans, got %+v", spans)
+		}
+		chains := 0
+		for _, span := range spans[1:] {
+			if span.SpanKind == spanKindChain && span.ParentID == spans[0].Context.SpanID {
+				chains++
+			}
+		}
+		if chains == 0 {
+			t.Errorf("Expected a span per thought, got %+v", spans)
+		}
+	})
+}
+
+// TestDriftReport tests diffing model checkpoints
+func TestDriftReport(t *testing.T) {
+	t.Run("Concept Graph", func(t *testing.T) {
+		dir := t.TempDir()
+		writeCheckpoint(dir+"/before.json", modelCheckpoint{
+			Model:       "transparent",
+			Activations: map[string]float64{"code": 0.1, "error": 0.2, "cat": 0.0},
+			Connections: map[string]map[string]float64{
+				"code":  {"error": 0.5, "cat": 0.1},
+				"error": {"code": 0.35},
+			},
+		})
+		writeCheckpoint(dir+"/after.json", modelCheckpoint{
+			Model:       "transparent",
+			Activations: map[string]float64{"code": 0.9, "error": 0.8, "bug": 0.7},
+			Connections: map[string]map[string]float64{
+				"code":  {"error": 0.9},
+				"error": {"code": 0.4, "bug": 0.6},
+			},
+		})
+		before, err := loadCheckpoint(dir + "/before.json")
+		if err != nil {
+			t.Fatalf("loadCheckpoint failed: %v", err)
+		}
+		after, _ := loadCheckpoint(dir + "/after.json")
+
+		report, err := DiffCheckpoints(before, after, 1)
+		if err != nil {
+			t.Fatalf("DiffCheckpoints failed: %v", err)
+		}
+		if fmt.Sprint(report.AddedConcepts) != "[bug]" || fmt.Sprint(report.RemovedConcepts) != "[cat]" {
+			t.Errorf("Unexpected concept changes: +%v -%v", report.AddedConcepts, report.RemovedConcepts)
+		}
+		if report.AddedConnections != 1 || report.RemovedConnections != 1 {
+			t.Errorf("Expected one added and one removed connection, got +%d -%d", report.AddedConnections, report.RemovedConnections)
+		}
+		if len(report.ChangedConnections) != 1 || report.ChangedConnections[0].From != "code" || report.ChangedConnections[0].To != "error" {
+			t.Errorf("Expected the largest change to be code → error, got %+v", report.ChangedConnections)
+		}
+		if math.Abs(report.Activation.MeanAbsChange-0.7) > 1e-9 || report.Activation.AfterMean <= report.Activation.BeforeMean {
+			t.Errorf("Unexpected activation shift %+v", report.Activation)
+		}
+		if math.Abs(report.Activation.TotalVariation-1) > 1e-9 {
+			t.Errorf("Expected disjoint distributions, got total variation %.2f", report.Activation.TotalVariation)
+		}
+
+		var out bytes.Buffer
+		report.Print(&out)
+		if !strings.Contains(out.String(), "code → error") {
+			t.Errorf("Expected the printed report to list the change:\n%s", out.String())
+		}
+	})
+
+	t.Run("Reservoir", func(t *testing.T) {
+		before := &modelCheckpoint{Model: "liquid", States: []float64{0.1, 0.2}, Outputs: map[string]checkpointOutput{
+			"help": {Connections: [][3]int{{0, 0, 0}}, Weights: []float64{1}},
+		}}
+		after := &modelCheckpoint{Model: "liquid", States: []float64{0.1, 0.4}, Outputs: map[string]checkpointOutput{
+			"help":     {Connections: [][3]int{{0, 0, 0}}, Weights: []float64{1}, Bias: 0.5},
+			"greeting": {Connections: [][3]int{{1, 0, 0}}, Weights: []float64{2}},
+		}}
+		report, err := DiffCheckpoints(before, after, 0)
+		if err != nil {
+			t.Fatalf("DiffCheckpoints failed: %v", err)
+		}
+		if len(report.ChangedOutputs) != 2 || !report.ChangedOutputs[0].Rewired || report.ChangedOutputs[1].Rewired || report.ChangedOutputs[1].WeightChange != 0.5 {
+			t.Errorf("Unexpected output changes %+v", report.ChangedOutputs)
+		}
+		if math.Abs(report.Activation.MeanAbsChange-0.1) > 1e-9 {
+			t.Errorf("Expected a mean change of 0.1, got %f", report.Activation.MeanAbsChange)
+		}
+
+		if _, err := DiffCheckpoints(before, &modelCheckpoint{Model: "transparent"}, 0); err == nil {
+			t.Error("Expected checkpoints of different models to be rejected")
+		}
+		if _, err := DiffCheckpoints(before, &modelCheckpoint{Model: "liquid", States: []float64{1}}, 0); err == nil {
+			t.Error("Expected reservoirs of different sizes to be rejected")
+		}
+	})
+}
+
+// TestAuditLog tests the hash-chained reasoning audit log
+func TestAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/audit.jsonl"
+
+	log, err := OpenAuditLog(path, "secret", "transparent", "test")
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	log.Append("s1", "hello", "hi there", &Explanation{Input: "hello", Response: "hi there", ActiveConcepts: []string{"greeting"}})
+	log.Append("s1", "what is code", "code is text", nil)
+	log.Close()
+
+	t.Run("Reopen Continues Chain", func(t *testing.T) {
+		log, err := OpenAuditLog(path, "secret", "transparent", "test")
+		if err != nil {
+			t.Fatalf("OpenAuditLog failed: %v", err)
+		}
+		if err := log.Append("s2", "bye", "goodbye", nil); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		log.Close()
+
+		count, err := VerifyAuditLog(path, "secret")
+		if err != nil || count != 3 {
+			t.Fatalf("Expected 3 verified records, got %d: %v", count, err)
+		}
+		if _, err := VerifyAuditLog(path, ""); err != nil {
+			t.Errorf("Hash chain should verify without a key: %v", err)
+		}
+	})
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		if count, err := VerifyAuditLog(path, "other"); err == nil || count != 0 {
+			t.Errorf("Expected a signature failure at the first record, got %d: %v", count, err)
+		}
+	})
+
+	t.Run("Tampered Record", func(t *testing.T) {
+		tampered := dir + "/tampered.jsonl"
+		edited := append([]string{}, lines...)
+		edited[1] = strings.Replace(edited[1], "code is text", "code is art", 1)
+		os.WriteFile(tampered, []byte(strings.Join(edited, "\n")+"\n"), 0600)
+		if count, err := VerifyAuditLog(tampered, ""); err == nil || count != 1 {
+			t.Errorf("Expected a hash mismatch after 1 record, got %d: %v", count, err)
+		}
+	})
+
+	t.Run("Removed Record", func(t *testing.T) {
+		removed := dir + "/removed.jsonl"
+		os.WriteFile(removed, []byte(lines[0]+"\n"+lines[2]+"\n"), 0600)
+		if count, err := VerifyAuditLog(removed, "secret"); err == nil || count != 1 {
+			t.Errorf("Expected a broken chain after 1 record, got %d: %v", count, err)
+		}
+	})
+}
+
+// TestDistillation tests logging capability exchanges as a training dataset
+func TestDistillation(t *testing.T) {
+	dir := t.TempDir()
+	config := DistillConfig{
+		Enabled:          true,
+		Path:             dir + "/distilled.jsonl",
+		Capabilities:     []string{"external"},
+		MinResponseWords: 3,
+		MaxResponseWords: 20,
+	}
+
+	t.Run("Filters", func(t *testing.T) {
+		log, err := OpenDistillationLog(config, nil)
+		if err != nil {
+			t.Fatalf("OpenDistillationLog failed: %v", err)
+		}
+		defer log.Close()
+
+		cases := []struct {
+			capability, prompt, response string
+			kept                         bool
+		}{
+			{"external", "what is a bug", "A bug is an error in a program.", true},
+			{"external", "what is a bug", "a bug is  an error in a program.", false}, // duplicate
+			{"external", "hi", "Hello.", false},                                      // too short
+			{"external", "list", strings.Repeat("word ", 21), false},                 // too long
+			{"external", "story", "[GPT-4 response to: story]", false},               // placeholder
+			{"external", "say this back", "Say this back", false},                    // echo
+			{"external", "numbers", "1 2 3 4 + 5", false},                            // not text
+			{"calculator", "what is a loop", "A loop repeats a block of code.", false},
+		}
+		for _, c := range cases {
+			kept, err := log.Record(c.capability, c.prompt, c.response)
+			if err != nil || kept != c.kept {
+				t.Errorf("Record(%q) = %v, %v; want %v", c.response, kept, err, c.kept)
+			}
+		}
+		stats := log.Stats()
+		if stats.Kept != 1 || stats.Dropped["duplicate"] != 1 || stats.Dropped["placeholder"] != 1 || stats.Dropped["not_text"] != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("Reopen Remembers Examples", func(t *testing.T) {
+		log, err := OpenDistillationLog(config, nil)
+		if err != nil {
+			t.Fatalf("OpenDistillationLog failed: %v", err)
+		}
+		defer log.Close()
+		if kept, _ := log.Record("external", "what is a bug", "A bug is an error in a program."); kept {
+			t.Error("Example from the existing dataset was logged again")
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		first, err := OpenDistillationLog(config, nil)
+		if err != nil {
+			t.Fatalf("OpenDistillationLog failed: %v", err)
+		}
+		second, err := OpenDistillationLog(config, nil)
+		if err != nil || second != first {
+			t.Fatalf("Expected the open log to be shared, got %v", err)
+		}
+		other := config
+		other.MinResponseWords = 1
+		if _, err := OpenDistillationLog(other, nil); err == nil {
+			t.Error("Expected reopening with other settings to fail")
+		}
+
+		first.Close()
+		if kept, err := second.Record("external", "what is a loop", "A loop repeats a block of code."); !kept || err != nil {
+			t.Errorf("Expected the log to stay open for its other opener: %v", err)
+		}
+		second.Close()
+		if _, ok := distillLogs[first.path]; ok {
+			t.Error("Expected the log to be released once every opener closed it")
+		}
+	})
+
+	t.Run("Orchestrator", func(t *testing.T) {
+		log, err := OpenDistillationLog(config, NewRedactor(PrivacyConfig{RedactEmails: true}))
+		if err != nil {
+			t.Fatalf("OpenDistillationLog failed: %v", err)
+		}
+		orchestrator := &GenesisOrchestrator{neurons: make(map[string]*OrchestratorNeuron)}
+		orchestrator.SetDistillationLog(log)
+		orchestrator.RegisterCapability("external", func(ctx context.Context, input string) (string, error) {
+			return "Write to help@example.com about the compiler error.", nil
+		})
+		orchestrator.SetFallbackChain("external")
+		if _, _, ok := orchestrator.runFallbackChain(context.Background(), "who fixes compiler errors", Confidence{}, nil); !ok {
+			t.Fatal("Fallback chain failed")
+		}
+		if err := orchestrator.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(config.Path)
+		if !strings.Contains(string(data), "compiler error") || strings.Contains(string(data), "help@example.com") {
+			t.Errorf("Expected the redacted exchange in the dataset, got %s", data)
+		}
+	})
+
+	t.Run("Dataset", func(t *testing.T) {
+		training := DefaultConfig().Training
+		training.DatasetPaths = []string{config.Path}
+		training.DisableStarterCorpus = true
+		training.MinWordFreq = 1
+		loader, err := NewDatasetLoader(training)
+		if err != nil {
+			t.Fatalf("NewDatasetLoader failed: %v", err)
+		}
+		docs := loader.GetDocuments()
+		if len(docs) != 1 || strings.Contains(docs[0].Content, "prompt") || !strings.Contains(docs[0].Content, "compiler") {
+			t.Fatalf("Expected the responses as one document, got %+v", docs)
+		}
+		if _, ok := loader.GetTransitions("bug"); !ok {
+			t.Error("Distilled responses weren't learned as transitions")
+		}
+
+		// In a dataset directory only the configured distillation dataset
+		// is loaded
+		os.WriteFile(dir+"/notes.txt", []byte("notes about the project"), 0644)
+		os.WriteFile(dir+"/other.jsonl", []byte(`{"response": "should not be learned"}`+"\n"), 0644)
+		training.DatasetPaths = []string{dir}
+		training.DistillPath = config.Path
+		loader, err = NewDatasetLoader(training)
+		if err != nil {
+			t.Fatalf("NewDatasetLoader failed: %v", err)
+		}
+		var paths []string
+		for _, doc := range loader.GetDocuments() {
+			paths = append(paths, filepath.Base(doc.Path))
+		}
+		sort.Strings(paths)
+		if fmt.Sprint(paths) != "[distilled.jsonl notes.txt]" {
+			t.Errorf("Expected notes.txt and the distillation dataset, got %v", paths)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		bad := config
+		bad.Path = dir + "/distilled.txt"
+		if bad.validate() == nil {
+			t.Error("Expected a non-JSONL path to be rejected")
+		}
+
+		path := dir + "/config.json"
+		os.WriteFile(path, []byte(`{"distill": {"enabled": true, "path": "`+config.Path+`", "capabilities": ["gpt4"]}}`), 0644)
+		loaded, err := LoadConfig(path)
+		if err != nil || loaded.Training.DistillPath != config.Path {
+			t.Errorf("Expected the distillation dataset to be loaded for training: %v", err)
+		}
+	})
+}
+
+// TestTokenHooks tests per-token generation hooks
+func TestTokenHooks(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats. Cats sleep all day and dogs play all day. The dogs love the park. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	t.Run("Final Pass", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		var final []string
+		searched := 0
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if e.Final {
+				final = append(final, e.Token)
+			} else {
+				searched++
+			}
+			return TokenContinue
+		})
+		response := gen.Generate("the dogs", []string{"dogs"})
+		if searched == 0 {
+			t.Error("Hook never saw a search candidate")
+		}
+		trim := func(s string) string { return strings.TrimRight(strings.ToLower(s), ".?!") }
+		if trim(strings.Join(final, " ")) != trim(response) {
+			t.Errorf("Final tokens %v don't spell the response %q", final, response)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		entered, release := make(chan struct{}), make(chan struct{})
+		var tokens atomic.Int64
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if tokens.Add(1) == 1 {
+				close(entered)
+				<-release
+			}
+			return TokenContinue
+		})
+		seed := int64(5)
+		blocked := make(chan string)
+		go func() {
+			response, _ := gen.GenerateWithStateOptions(&GeneratorState{}, "the dogs", nil, GenerationOptions{Seed: &seed})
+			blocked <- response
+		}()
+		<-entered
+
+		// A second call generates while the first is stuck mid-search
+		done := make(chan string)
+		go func() {
+			response, _ := gen.GenerateWithStateOptions(&GeneratorState{}, "the dogs", nil, GenerationOptions{Seed: &seed})
+			done <- response
+		}()
+		var second string
+		select {
+		case second = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a second call to generate while the first searches")
+		}
+		close(release)
+		if response := <-blocked; response != second {
+			t.Errorf("Expected the same seed and fresh state to give the same response, got %q and %q", response, second)
+		}
+	})
+
+	t.Run("Drop And Rewrite", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if strings.HasPrefix(e.Token, "cat") {
+				return TokenDrop
+			}
+			return TokenContinue
+		})
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if e.Final && e.Token == "dogs" {
+				e.Token = "d**s"
+			}
+			return TokenContinue
+		})
+		for _, input := range []string{"the cats", "cats and dogs", "the dogs play"} {
+			response := strings.ToLower(gen.Generate(input, []string{"cats", "dogs"}))
+			if strings.Contains(response, "cat") || strings.Contains(response, "dogs") {
+				t.Errorf("Hooks not applied to %q", response)
+			}
+		}
+	})
+
+	t.Run("Stop Sequence", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if e.Token == "and" {
+				return TokenEndBeam
+			}
+			return TokenContinue
+		})
+		for _, input := range []string{"the cats", "cats and dogs", "the dogs play"} {
+			words := strings.Fields(strings.ToLower(gen.Generate(input, []string{"cats", "dogs"})))
+			for i, word := range words {
+				if strings.TrimRight(word, ".?!") == "and" && i != len(words)-1 {
+					t.Errorf("Generation continued past the stop word: %v", words)
+				}
+			}
+		}
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		calls, afterAbort := 0, 0
+		aborted := false
+		gen.OnToken(func(e *TokenEvent) TokenAction {
+			if e.Final {
+				return TokenContinue
+			}
+			if aborted {
+				afterAbort++
+			}
+			calls++
+			if calls == 3 {
+				aborted = true
+				return TokenAbort
+			}
+			return TokenContinue
+		})
+		if response := gen.Generate("the dogs", []string{"dogs"}); response == "" {
+			t.Error("Expected the partial response")
+		}
+		if afterAbort != 0 {
+			t.Errorf("Search continued for %d tokens after the abort", afterAbort)
+		}
+
+		gen.ClearTokenHooks()
+		calls = 0
+		gen.Generate("the dogs", []string{"dogs"})
+		if calls != 0 {
+			t.Error("Cleared hook still ran")
+		}
+	})
+}
+
+// TestGenerationOptions tests per-request max tokens and stop sequences
+func TestGenerationOptions(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	inputs := []string{"the cats", "cats and dogs", "the dogs play", "tell me about the park"}
+	words := func(response string) []string {
+		return strings.Fields(strings.ToLower(strings.TrimRight(response, ".?!")))
+	}
+
+	t.Run("Max Tokens", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		lengthLimited := false
+		for _, input := range inputs {
+			response, explanation := gen.GenerateWithOptions(input, []string{"cats"}, GenerationOptions{MaxTokens: 3})
+			if n := len(words(response)); n > 3 {
+				t.Errorf("Response %q has %d tokens, limit 3", response, n)
+			}
+			if explanation.FinishReason == finishLength {
+				lengthLimited = true
+			}
+		}
+		if !lengthLimited {
+			t.Error("Expected a response cut at the token limit")
+		}
+		if gen.tokenLimit != 0 || gen.stops != nil {
+			t.Error("Request options leaked into the generator")
+		}
+	})
+
+	t.Run("Stop Sequences", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		for _, input := range inputs {
+			response, explanation := gen.GenerateWithOptions(input, []string{"dogs"}, GenerationOptions{Stop: []string{"and", "Green Park"}})
+			text := " " + strings.Join(words(response), " ") + " "
+			if strings.Contains(text, " and ") || strings.Contains(text, " green park ") {
+				t.Errorf("Response %q contains a stop sequence", response)
+			}
+			if explanation.FinishReason == "" {
+				t.Error("Expected a finish reason")
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, options := range []GenerationOptions{{MaxTokens: -1}, {MaxTokens: maxRequestTokens + 1}, {Stop: []string{" "}}} {
+			if options.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", options)
+			}
+		}
+	})
+
+	t.Run("HTTP API", func(t *testing.T) {
+		server := httptest.NewServer(NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), NewResponseGenerator(loader)))
+		defer server.Close()
+		resp, _ := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"the cats","max_tokens":2,"stop":["park"]}`))
+		var msg MessageResponse
+		json.NewDecoder(resp.Body).Decode(&msg)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(words(msg.Message.Content)) > 2 || msg.Explanation.FinishReason == "" {
+			t.Fatalf("Unexpected message response %d: %+v", resp.StatusCode, msg)
+		}
+
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"the cats","max_tokens":-1}`))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for invalid max_tokens, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// TestWordBias tests per-request word weighting
+func TestWordBias(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	inputs := []string{"the cats", "cats and dogs", "the dogs play", "tell me about the park"}
+
+	t.Run("Ban", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		for _, input := range inputs {
+			response, _ := gen.GenerateWithOptions(input, []string{"cats", "dogs"}, GenerationOptions{WordBias: map[string]float64{"Dogs": 0, "cats": 0}})
+			lower := strings.ToLower(response)
+			if strings.Contains(lower, "dogs") || strings.Contains(lower, "cats") {
+				t.Errorf("Response %q contains a banned word", response)
+			}
+		}
+		if gen.wordBias != nil {
+			t.Error("Word bias leaked into the generator")
+		}
+	})
+
+	t.Run("Boost", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		unbiased := gen.scoreWord("park", nil, nil)
+		gen.setOptions(GenerationOptions{WordBias: map[string]float64{"Park": 5}})
+		if got, want := gen.scoreWord("park", nil, nil), 5*unbiased; math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected the boosted score %.3f, got %.3f", want, got)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, bias := range []map[string]float64{{"cats": -1}, {"cats": maxWordBias + 1}, {"two words": 2}, {"cats": math.NaN()}} {
+			if (GenerationOptions{WordBias: bias}).validate() == nil {
+				t.Errorf("Expected %v to be rejected", bias)
+			}
+		}
+	})
+}
+
+// TestBeamTree tests beam-search diagnostics
+func TestBeamTree(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	t.Run("Tree", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		response, explanation := gen.GenerateWithOptions("the cats chase", []string{"cats", "dogs"}, GenerationOptions{Diagnostics: true, WordBias: map[string]float64{"the": 0}})
+		tree := explanation.BeamTree
+		if tree == nil || len(tree.Nodes) == 0 {
+			t.Fatal("Expected a beam tree")
+		}
+		reasons := make(map[string]int)
+		for i, node := range tree.Nodes {
+			if node.ID != i || node.Parent >= i {
+				t.Fatalf("Node %d is out of order: %+v", i, node)
+			}
+			if node.Parent >= 0 && tree.Nodes[node.Parent].Step != node.Step-1 {
+				t.Errorf("Node %d isn't a step after its parent", i)
+			}
+			reasons[node.Pruned]++
+		}
+		if reasons[pruneBanned] == 0 {
+			t.Errorf("Expected the banned starter to be recorded, got %v", reasons)
+		}
+		path := strings.ToLower(strings.Join(tree.Path(tree.Chosen), " "))
+		if got := strings.ToLower(strings.TrimRight(response, ".?!")); path != got {
+			t.Errorf("Chosen path %q doesn't match the response %q", path, got)
+		}
+		if tree.Nodes[tree.Chosen].FinalScore == 0 {
+			t.Error("Chosen beam has no final score")
+		}
+	})
+
+	t.Run("Off By Default", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		if _, explanation := gen.GenerateExplained("the cats chase", []string{"cats"}); explanation.BeamTree != nil {
+			t.Error("Beam tree recorded without diagnostics")
+		}
+	})
+
+	t.Run("HTTP API", func(t *testing.T) {
+		server := httptest.NewServer(NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), NewResponseGenerator(loader)))
+		defer server.Close()
+		resp, _ := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+
+		resp, _ = http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"the dogs","diagnostics":true}`))
+		var body struct {
+			Explanation struct {
+				BeamTree *BeamTree `json:"beam_tree"`
+			} `json:"explanation"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if body.Explanation.BeamTree == nil || len(body.Explanation.BeamTree.Nodes) == 0 {
+			t.Error("Expected the beam tree in the API response")
+		}
+	})
+}
+
+// TestBeamSearch tests length normalization and diverse beam groups
+func TestBeamSearch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	t.Run("Length Normalization", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.SetBeamSearch(BeamSearchConfig{LengthPenalty: 1, Groups: 1})
+		beam := Beam{words: strings.Fields("one two three four five six seven"), score: 2}
+		if got := gen.normalizedScore(beam); math.Abs(got-1) > 1e-9 {
+			t.Errorf("Expected 2 / ((5+7)/6) = 1, got %.3f", got)
+		}
+		gen.SetBeamSearch(BeamSearchConfig{Groups: 1})
+		if gen.normalizedScore(beam) != 2 {
+			t.Error("A zero length penalty should compare raw scores")
+		}
+	})
+
+	t.Run("Diversity Penalty", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		candidates := []wordCandidate{{"cats", 1}, {"dogs", 0.8}, {"park", 0.1}}
+		gen.diversify(candidates, map[string]int{"cats": 1})
+		if candidates[0].word != "dogs" || math.Abs(candidates[1].score-1/1.5) > 1e-9 {
+			t.Errorf("Expected the chosen word penalized below the next, got %+v", candidates)
+		}
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		gen.SetBeamSearch(BeamSearchConfig{LengthPenalty: 0.6, Groups: 2, DiversityPenalty: 2})
+		_, explanation := gen.GenerateWithOptions("the cats and the dogs", []string{"cats", "dogs"}, GenerationOptions{Diagnostics: true})
+		tree := explanation.BeamTree
+		finals := make(map[string]bool)
+		for _, node := range tree.Nodes {
+			if node.FinalScore != 0 {
+				finals[strings.Join(tree.Path(node.ID), " ")] = true
+			}
+		}
+		if len(finals) < 2 {
+			t.Errorf("Expected several distinct final beams, got %v", finals)
+		}
+		if gen.beamGroups(1) != 1 || gen.groupWidth(2) != gen.beamWidth {
+			t.Error("Unexpected group sizing")
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		gen := NewResponseGenerator(loader)
+		if gen.SetBeamSearch(BeamSearchConfig{}) == nil || gen.search != defaultBeamSearch {
+			t.Error("Expected the zero config to be rejected and the defaults kept")
+		}
+		if DefaultConfig().BeamSearch != defaultBeamSearch {
+			t.Error("defaultBeamSearch doesn't match the starter config")
+		}
+	})
+}
+
+// TestNBest tests returning the N best distinct responses
+func TestNBest(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+
+	t.Run("Generator", func(t *testing.T) {
+		loader, err := NewDatasetLoader(TrainingConfig{
+			DatasetPaths:         []string{dir + "/pets.txt"},
+			MaxVocabSize:         100,
+			EmbeddingDim:         16,
+			MinWordFreq:          1,
+			MaxDocuments:         10,
+			DisableStarterCorpus: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		gen := NewResponseGenerator(loader)
+		candidates, explanation := gen.GenerateNBest("the cats and the dogs", []string{"cats", "dogs"}, 3)
+		if len(candidates) < 2 || len(candidates) > 3 {
+			t.Fatalf("Expected 2 or 3 candidates, got %+v", candidates)
+		}
+		if candidates[0].Response != explanation.Response {
+			t.Errorf("First candidate %q isn't the response %q", candidates[0].Response, explanation.Response)
+		}
+		seen := make(map[string]bool)
+		total := 0.0
+		for i, c := range candidates {
+			if seen[c.Response] {
+				t.Errorf("Duplicate candidate %q", c.Response)
+			}
+			seen[c.Response] = true
+			if i > 0 && c.Score > candidates[i-1].Score {
+				t.Errorf("Candidates not ordered by score: %+v", candidates)
+			}
+			total += c.Probability
+		}
+		if total <= 0 || total > 1+1e-9 {
+			t.Errorf("Probabilities should be shares of the final beams, sum %.3f", total)
+		}
+		if _, explanation := gen.GenerateExplained("the cats", nil); explanation.Alternatives != nil {
+			t.Error("Alternatives returned without being requested")
+		}
+		if (GenerationOptions{N: maxNBest + 1}).validate() == nil {
+			t.Error("Expected n above the cap to be rejected")
+		}
+	})
+
+	t.Run("TransparentLLM", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Training.DatasetPaths = []string{dir}
+		config.Training.DisableStarterCorpus = true
+		config.Training.MinWordFreq = 1
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create LLM")
+		}
+		defer llm.Cleanup()
+
+		candidates, explanation, err := llm.UnderstandNBest("cats chase dogs", 2)
+		if err != nil {
+			t.Fatalf("UnderstandNBest failed: %v", err)
+		}
+		if len(candidates) == 0 || len(candidates) > 2 || candidates[0].Response != explanation.Response {
+			t.Errorf("Unexpected candidates %+v for response %q", candidates, explanation.Response)
+		}
+		if _, _, err := llm.UnderstandNBest("cats", 0); err == nil {
+			t.Error("Expected n = 0 to be rejected")
+		}
+	})
+}
+
+// TestCoherence tests reranking session replies by conversation coherence
+func TestCoherence(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. The soup needs more salt and the bread needs more butter in the warm kitchen. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	config := CoherenceConfig{Enabled: true, Candidates: 4, HistoryTurns: 4, Weight: 0.5, EntityWeight: 0.4}
+	scorer := NewCoherenceScorer(loader, config)
+	history := []string{"do the cats chase the dogs", "the dogs play in the park"}
+
+	t.Run("Score", func(t *testing.T) {
+		on := scorer.Score("the cats chase the dogs around the park", history)
+		off := scorer.Score("the soup needs more salt", history)
+		if on.Score <= off.Score {
+			t.Errorf("Expected the on-topic response to score higher: %+v vs %+v", on, off)
+		}
+		if on.EntityOverlap == 0 {
+			t.Errorf("Expected shared entities, got %+v", on)
+		}
+		if on.Score < 0 || on.Score > 1 {
+			t.Errorf("Score out of range: %+v", on)
+		}
+	})
+
+	t.Run("Rerank", func(t *testing.T) {
+		ranked := scorer.Rerank([]ResponseCandidate{
+			{Response: "the soup needs more salt", Probability: 0.55},
+			{Response: "the cats chase the dogs around the park", Probability: 0.45},
+		}, history)
+		if ranked[0].Response != "the cats chase the dogs around the park" {
+			t.Errorf("Expected the coherent response first, got %+v", ranked)
+		}
+		if ranked[0].Coherence <= ranked[1].Coherence {
+			t.Errorf("Coherence not recorded: %+v", ranked)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		if (CoherenceConfig{Enabled: true, Candidates: 1, HistoryTurns: 1}).validate() == nil {
+			t.Error("Expected a single candidate to be rejected")
+		}
+		if (CoherenceConfig{}).validate() != nil {
+			t.Error("A disabled config should be valid")
+		}
+	})
+
+	t.Run("HTTP API", func(t *testing.T) {
+		handler := NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), NewResponseGenerator(loader))
+		handler.SetCoherence(scorer)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		resp, _ := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+
+		send := func(content string) MessageResponse {
+			resp, err := http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(content))
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var body MessageResponse
+			json.NewDecoder(resp.Body).Decode(&body)
+			return body
+		}
+		body := send(`{"content":"the cats chase the dogs"}`)
+		if body.Message.Content == "" || body.Explanation.Response != body.Message.Content {
+			t.Errorf("Reply %q doesn't match the explanation %q", body.Message.Content, body.Explanation.Response)
+		}
+		if body.Explanation.Alternatives != nil {
+			t.Error("Alternatives returned without being requested")
+		}
+		body = send(`{"content":"do the dogs play","n":2}`)
+		if len(body.Explanation.Alternatives) == 0 || len(body.Explanation.Alternatives) > 2 {
+			t.Errorf("Expected at most the 2 requested alternatives, got %+v", body.Explanation.Alternatives)
+		} else if body.Explanation.Alternatives[0].Response != body.Message.Content {
+			t.Errorf("First alternative %q isn't the reply %q", body.Explanation.Alternatives[0].Response, body.Message.Content)
+		}
+	})
+}
+
+// TestTemplates tests the response template library
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	path := dir + "/templates.yaml"
+	os.WriteFile(path, []byte(`# Canned responses
+intents:
+  - name: greeting
+    match: [hello, hi, good morning]
+    templates:
+      - "Hello! What would you like to know about {topic}?"
+  - name: farewell # no dataset needed
+    match:
+      - bye
+    templates:
+      - 'See you, {name}.'
+      - Goodbye for now.
+  - name: sleepy
+    templates: ["Time for a nap."]
+`), 0644)
+
+	t.Run("Load", func(t *testing.T) {
+		library, err := LoadTemplateLibrary(path)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if len(library.Intents) != 3 || len(library.Intents[0].Match) != 3 || library.Intents[1].Match[0] != "bye" {
+			t.Fatalf("Unexpected library %+v", library)
+		}
+		if intent, ok := library.Match("well, good morning there"); !ok || intent.Name != "greeting" {
+			t.Errorf("Expected the greeting intent, got %+v", intent)
+		}
+		if _, ok := library.Match("the cats chase"); ok {
+			t.Error("Expected no intent to match")
+		}
+		if got, ok := library.Respond("bye", "sleepy"); !ok || got != "Time for a nap." {
+			t.Errorf("Expected the meaning's template, got %q", got)
+		}
+		if got, ok := library.Respond("bye", ""); !ok || got != "Goodbye for now." {
+			t.Errorf("Expected the template without a name slot, got %q", got)
+		}
+		if got, _ := library.Respond("bye Alice Smith", ""); got != "See you, Alice Smith." && got != "Goodbye for now." {
+			t.Errorf("Unexpected farewell %q", got)
+		}
+
+		bad := dir + "/bad.yaml"
+		os.WriteFile(bad, []byte("intents:\n  - name: x\n    templates: [\"Hi {nobody}\"]\n"), 0644)
+		if _, err := LoadTemplateLibrary(bad); err == nil {
+			t.Error("Expected an unknown slot to be rejected")
+		}
+		config := DefaultConfig()
+		config.Templates = TemplateConfig{Path: path, ReplaceBelow: 0.5, BlendBelow: 0.4}
+		if config.Validate() == nil {
+			t.Error("Expected replace_below above blend_below to be rejected")
+		}
+	})
+
+	t.Run("Plain Scalars", func(t *testing.T) {
+		words := dir + "/words.yaml"
+		os.WriteFile(words, []byte("intents:\n  - name: 2024\n    match: [2024, inf, nan, true, 1e3]\n    templates: [yes]\n"), 0644)
+		library, err := LoadTemplateLibrary(words)
+		if err != nil {
+			t.Fatalf("Failed to load plain scalars as strings: %v", err)
+		}
+		if intent := library.Intents[0]; intent.Name != "2024" || strings.Join(intent.Match, " ") != "2024 inf nan true 1e3" || intent.Templates[0] != "yes" {
+			t.Errorf("Expected plain scalars to stay as written, got %+v", intent)
+		}
+
+		protocol := dir + "/protocol.yaml"
+		os.WriteFile(protocol, []byte("name: 42\nsample_ms: 10\nduration_ms: 100\nsteps:\n  - at_ms: 0\n    input: 7\n    amplitude: 1.5\n"), 0644)
+		loaded, err := LoadStimulusProtocol(protocol)
+		if err != nil {
+			t.Fatalf("Failed to load numeric fields: %v", err)
+		}
+		if loaded.Name != "42" || loaded.SampleMS != 10 || loaded.Steps[0].Input != "7" || loaded.Steps[0].Amplitude != 1.5 {
+			t.Errorf("Unexpected protocol %+v", loaded)
+		}
+		for _, value := range []string{"inf", "-Infinity", "NaN", "ten"} {
+			os.WriteFile(protocol, []byte("name: x\nsample_ms: 10\nduration_ms: 100\nsteps:\n  - input: hi\n    amplitude: "+value+"\n"), 0644)
+			if _, err := LoadStimulusProtocol(protocol); err == nil || !strings.Contains(err.Error(), "amplitude") {
+				t.Errorf("Expected amplitude %s to be rejected by name, got %v", value, err)
+			}
+		}
+	})
+
+	t.Run("Generator", func(t *testing.T) {
+		loader, err := NewDatasetLoader(TrainingConfig{
+			DatasetPaths:         []string{dir + "/pets.txt"},
+			MaxVocabSize:         100,
+			EmbeddingDim:         16,
+			MinWordFreq:          1,
+			MaxDocuments:         10,
+			DisableStarterCorpus: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		library, _ := LoadTemplateLibrary(path)
+		gen := NewResponseGenerator(loader)
+
+		gen.SetTemplates(library, TemplateConfig{ReplaceBelow: 1.01, BlendBelow: 1.01})
+		response, explanation := gen.GenerateExplained("hello, tell me about the dogs", nil)
+		if explanation.Template == nil || explanation.Template.Mode != templateReplace || !strings.HasPrefix(response, "Hello! What would you like to know about") {
+			t.Errorf("Expected the template to replace the response, got %q %+v", response, explanation.Template)
+		}
+
+		gen.SetTemplates(library, TemplateConfig{ReplaceBelow: 0, BlendBelow: 1.01})
+		response, explanation = gen.GenerateExplained("hello, tell me about the dogs", nil)
+		if explanation.Template == nil || explanation.Template.Mode != templateBlend || !strings.HasPrefix(response, "Hello!") || len(strings.Fields(response)) <= 8 {
+			t.Errorf("Expected the template to lead the response, got %q %+v", response, explanation.Template)
+		}
+
+		gen.SetTemplates(library, TemplateConfig{})
+		if _, explanation := gen.GenerateExplained("hello, tell me about the dogs", nil); explanation.Template != nil {
+			t.Error("Template used above the confidence thresholds")
+		}
+	})
+
+	t.Run("LiquidBrain", func(t *testing.T) {
+		library, _ := LoadTemplateLibrary(path)
+		brain := &LiquidStateBrain{templates: library}
+		if got := brain.interpretCategory("sleepy"); got != "Time for a nap." {
+			t.Errorf("Expected the template response, got %q", got)
+		}
+		if got := brain.interpretCategory("curious"); got != "Wave patterns suggest: curious" {
+			t.Errorf("Expected the fallback for meanings without an intent, got %q", got)
+		}
+	})
+}
+
+// TestGenerationSeed tests reproducing responses with per-request seeds
+func TestGenerationSeed(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/pets.txt", []byte(strings.Repeat("The cats chase the dogs and the dogs chase the cats around the big green park every single day. Cats sleep all day and dogs play all day. ", 5)), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths:         []string{dir + "/pets.txt"},
+		MaxVocabSize:         100,
+		EmbeddingDim:         16,
+		MinWordFreq:          1,
+		MaxDocuments:         10,
+		DisableStarterCorpus: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	t.Run("Reproduce", func(t *testing.T) {
+		first, explanation := NewResponseGenerator(loader).GenerateExplained("the cats and the dogs", []string{"cats", "dogs"})
+		if explanation.Seed == nil {
+			t.Fatal("Expected the explanation to report the seed")
+		}
+		seed := *explanation.Seed
+		for i := 0; i < 3; i++ {
+			again, explanation := NewResponseGenerator(loader).GenerateWithOptions("the cats and the dogs", []string{"cats", "dogs"}, GenerationOptions{Seed: &seed})
+			if again != first || *explanation.Seed != seed {
+				t.Errorf("Seed %d gave %q, first %q", seed, again, first)
+			}
+		}
+	})
+
+	t.Run("Ties", func(t *testing.T) {
+		order := func(seed int64) string {
+			gen := NewResponseGenerator(loader)
+			gen.setOptions(GenerationOptions{Seed: &seed})
+			candidates := []wordCandidate{{"top", 2}, {"c", 1}, {"a", 1}, {"d", 1}, {"b", 1}, {"last", 0.5}}
+			gen.breakTies(candidates)
+			if candidates[0].word != "top" || candidates[5].word != "last" {
+				t.Errorf("Untied candidates moved: %+v", candidates)
+			}
+			words := make([]string, len(candidates))
+			for i, c := range candidates {
+				words[i] = c.word
+			}
+			return strings.Join(words, " ")
+		}
+		if order(1) != order(1) {
+			t.Error("The same seed should order ties the same way")
+		}
+		differs := false
+		for seed := int64(2); seed < 20 && !differs; seed++ {
+			differs = order(seed) != order(1)
+		}
+		if !differs {
+			t.Error("Expected different seeds to order ties differently")
+		}
+	})
+
+	t.Run("HTTP API", func(t *testing.T) {
+		server := httptest.NewServer(NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), NewResponseGenerator(loader)))
+		defer server.Close()
+		resp, _ := http.Post(server.URL+"/v1/sessions", "application/json", nil)
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		resp.Body.Close()
+
+		resp, err := http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"the dogs","seed":0}`))
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		var body MessageResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if body.Explanation == nil || body.Explanation.Seed == nil || *body.Explanation.Seed != 0 {
+			t.Errorf("Expected seed 0 in the explanation, got %+v", body.Explanation)
+		}
+	})
+}
+
+// TestOutputTaps tests wiring outputs to reservoir layers and regions
+func TestOutputTaps(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		valid := OutputTapConfig{Primary: "middle", Taps: []OutputTap{
+			{Name: "middle", Layers: []int{1}},
+			{Name: "corner", Region: &TapRegion{To: [3]float64{0.5, 0.5, 1}}},
+		}}
+		if err := valid.validate(); err != nil {
+			t.Errorf("Expected a valid config, got %v", err)
+		}
+		invalid := []OutputTapConfig{
+			{Primary: "missing"},
+			{Taps: []OutputTap{{Name: "both", Layers: []int{0}, Region: &TapRegion{To: [3]float64{1, 1, 1}}}}},
+			{Taps: []OutputTap{{Name: "neither"}}},
+			{Taps: []OutputTap{{Name: "inverted", Region: &TapRegion{From: [3]float64{0.8, 0, 0}, To: [3]float64{0.2, 1, 1}}}}},
+			{Taps: []OutputTap{{Name: "twice", Layers: []int{0}}, {Name: "twice", Layers: []int{1}}}},
+		}
+		for _, c := range invalid {
+			if c.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+	})
+
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	config.OutputTaps = OutputTapConfig{Primary: "first", Connections: 20, Taps: []OutputTap{
+		{Name: "first", Layers: []int{0}},
+		{Name: "last", Layers: []int{-1}},
+		{Name: "corner", Region: &TapRegion{To: [3]float64{0.5, 0.5, 0.5}}},
+		{Name: "beyond", Layers: []int{50}},
+	}}
+	brain := NewLiquidStateBrainWithConfig(4, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+
+	t.Run("Wiring", func(t *testing.T) {
+		for _, output := range brain.outputLayer {
+			if len(output.connections) != 20 {
+				t.Fatalf("Expected 20 connections, got %d", len(output.connections))
+			}
+			for _, n := range output.connections {
+				if n.z != 0 {
+					t.Fatalf("Primary output reads layer %d, want the first", n.z)
+				}
+			}
+		}
+		if names := brain.TapNames(); fmt.Sprint(names) != "[first last corner]" {
+			t.Errorf("Expected the empty tap to be skipped, got %v", names)
+		}
+		for _, tap := range brain.taps {
+			for _, n := range tap.outputs[0].connections {
+				switch tap.name {
+				case "last":
+					if n.z != brain.dimensions.Z-1 {
+						t.Errorf("Tap last reads layer %d", n.z)
+					}
+				case "corner":
+					if n.x >= 2 || n.y >= 2 || n.z >= 1 {
+						t.Errorf("Tap corner reads (%d, %d, %d)", n.x, n.y, n.z)
+					}
+				}
+			}
+		}
+		readout := brain.TapReadout()
+		if len(readout) != 3 || len(readout["last"]) != len(brain.outputLayer) {
+			t.Errorf("Unexpected tap readout %v", readout)
+		}
+	})
+
+	t.Run("Train", func(t *testing.T) {
+		var examples []LabeledInput
+		for _, e := range brain.schema.readoutExamples() {
+			examples = append(examples, LabeledInput{Input: e.Input, Meaning: e.Category})
+		}
+		accuracies, err := brain.TrainTaps(examples)
+		if err != nil {
+			t.Fatalf("TrainTaps failed: %v", err)
+		}
+		for _, name := range brain.TapNames() {
+			if a, ok := accuracies[name]; !ok || a < 0 || a > 1 {
+				t.Errorf("Tap %s accuracy %v", name, a)
+			}
+		}
+		for _, output := range brain.outputLayer {
+			if output.weights.Load() != nil {
+				t.Error("Training taps shouldn't train the output layer")
+			}
+		}
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		clone := brain.Template().Instantiate()
+		defer clone.Cleanup()
+		if fmt.Sprint(clone.TapNames()) != fmt.Sprint(brain.TapNames()) || clone.taps[0].outputs[0].connections[0].z != 0 {
+			t.Error("Expected the pooled brain to keep the taps' wiring")
+		}
+	})
+}
+
+// TestReservoirConnectivity tests configurable reservoir wiring
+func TestReservoirConnectivity(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		if (ConnectivityConfig{}).orDefault() != defaultConnectivity || defaultTopology.Connectivity != defaultConnectivity {
+			t.Error("Expected the zero connectivity to use the default")
+		}
+		if err := (ConnectivityConfig{}).validate(); err != nil {
+			t.Errorf("Expected the zero connectivity to be valid, got %v", err)
+		}
+		bad := []ConnectivityConfig{
+			{Radius: 0, BaseProbability: 0.3, DistanceFalloff: 1},
+			{Radius: maxConnectionRadius + 1, BaseProbability: 0.3},
+			{Radius: 2, BaseProbability: 1.5},
+			{Radius: 2, BaseProbability: 0.3, DistanceFalloff: -1},
+			{Radius: 2, BaseProbability: 0.3, MaxOutDegree: -1},
+			{Radius: 2, BaseProbability: 0.3, MinOutDegree: 5, MaxOutDegree: 4},
+			{Radius: 1, BaseProbability: 0.3, MinOutDegree: 27},
+		}
+		for _, c := range bad {
+			if c.validate() == nil {
+				t.Errorf("Expected %+v to be rejected", c)
+			}
+		}
+		if p := defaultConnectivity.probability(2); math.Abs(p-0.15) > 1e-9 {
+			t.Errorf("Expected 0.3/2 at distance 2, got %v", p)
+		}
+		if p := (ConnectivityConfig{BaseProbability: 0.4}).probability(3); p != 0.4 {
+			t.Errorf("Expected no falloff to keep the base probability, got %v", p)
+		}
+		if p := (ConnectivityConfig{BaseProbability: 1, DistanceFalloff: 1}).probability(0.5); p != 1 {
+			t.Errorf("Expected probabilities capped at 1, got %v", p)
+		}
+	})
+
+	build := func(connectivity ConnectivityConfig) *LiquidStateBrain {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		config.Topology.Connectivity = connectivity
+		brain := NewLiquidStateBrainWithConfig(6, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		return brain
+	}
+	each := func(brain *LiquidStateBrain, fn func(n *LiquidNeuron)) {
+		for x := range brain.reservoir {
+			for y := range brain.reservoir[x] {
+				for _, n := range brain.reservoir[x][y] {
+					fn(n)
+				}
+			}
+		}
+	}
+
+	t.Run("Full Neighborhood", func(t *testing.T) {
+		brain := build(ConnectivityConfig{Radius: 1, BaseProbability: 1})
+		defer brain.Cleanup()
+		if got := len(brain.reservoir[2][2][1].connections); got != 26 {
+			t.Errorf("Expected an interior neuron to reach all 26 neighbors, got %d", got)
+		}
+		near := func(a, b int) bool { return a-b <= 1 && b-a <= 1 }
+		each(brain, func(n *LiquidNeuron) {
+			for _, c := range n.connections {
+				if !near(c.x, n.x) || !near(c.y, n.y) || !near(c.z, n.z) {
+					t.Fatalf("Connection from %d,%d,%d to %d,%d,%d is outside radius 1", n.x, n.y, n.z, c.x, c.y, c.z)
+				}
+			}
+		})
+	})
+
+	t.Run("Max Out Degree", func(t *testing.T) {
+		brain := build(ConnectivityConfig{Radius: 2, BaseProbability: 1, MaxOutDegree: 3})
+		defer brain.Cleanup()
+		each(brain, func(n *LiquidNeuron) {
+			if len(n.connections) != 3 {
+				t.Fatalf("Expected every neuron capped at 3 connections, got %d", len(n.connections))
+			}
+		})
+	})
+
+	t.Run("Min Out Degree", func(t *testing.T) {
+		connectivity := ConnectivityConfig{Radius: 2, BaseProbability: 0.01, DistanceFalloff: 1, MinOutDegree: 4, MaxOutDegree: 6}
+		brain := build(connectivity)
+		defer brain.Cleanup()
+		each(brain, func(n *LiquidNeuron) {
+			if len(n.connections) < 4 || len(n.connections) > 6 {
+				t.Fatalf("Expected 4 to 6 connections, got %d", len(n.connections))
+			}
+			seen := make(map[*LiquidNeuron]bool)
+			for _, c := range n.connections {
+				if c == n || seen[c] {
+					t.Fatalf("Expected distinct neighbors without self-connections at %d,%d,%d", n.x, n.y, n.z)
+				}
+				seen[c] = true
+			}
+		})
+		// Sparse wiring is filled with the nearest neighbors first. Fill a
+		// fresh neuron in a shell whose dynamics never start, so random
+		// wiring can't decide the result and no goroutine reads it meanwhile.
+		shell := newBrainShell(brain.dimensions, brain.config, RealClock)
+		defer shell.cancel()
+		for x := range shell.reservoir {
+			shell.reservoir[x] = make([][]*LiquidNeuron, brain.dimensions.Y)
+			for y := range shell.reservoir[x] {
+				shell.reservoir[x][y] = make([]*LiquidNeuron, brain.dimensions.Z)
+				for z := range shell.reservoir[x][y] {
+					shell.reservoir[x][y][z] = &LiquidNeuron{x: x, y: y, z: z}
+				}
+			}
+		}
+		n := shell.reservoir[2][2][1]
+		shell.fillConnections(n, connectivity)
+		if len(n.connections) != 4 {
+			t.Fatalf("Expected fill to reach 4 connections, got %d", len(n.connections))
+		}
+		for _, c := range n.connections {
+			if d := (c.x-n.x)*(c.x-n.x) + (c.y-n.y)*(c.y-n.y) + (c.z-n.z)*(c.z-n.z); d != 1 {
+				t.Errorf("Expected fill to pick the nearest neighbors, got one at squared distance %d", d)
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		brain := build(ConnectivityConfig{Radius: 2, BaseProbability: 0.3, DistanceFalloff: 1})
+		defer brain.Cleanup()
+		stats := brain.ConnectivityStats()
+		synapses, in, out := 0, make(map[*LiquidNeuron]int), 0
+		neurons := 0
+		each(brain, func(n *LiquidNeuron) {
+			neurons++
+			synapses += len(n.connections)
+			out = max(out, len(n.connections))
+			for _, c := range n.connections {
+				in[c]++
+			}
+		})
+		if stats.Synapses != synapses || stats.OutDegree.Max != out {
+			t.Errorf("Expected %d synapses with max out-degree %d, got %+v", synapses, out, stats)
+		}
+		if want := float64(synapses) / float64(neurons); math.Abs(stats.OutDegree.Mean-want) > 1e-9 || math.Abs(stats.InDegree.Mean-want) > 1e-9 {
+			t.Errorf("Expected mean degrees of %.3f, got %.3f out and %.3f in", want, stats.OutDegree.Mean, stats.InDegree.Mean)
+		}
+		total := 0
+		for _, count := range stats.OutDegree.Histogram {
+			total += count
+		}
+		if total != neurons || len(stats.OutDegree.Histogram) != out+1 {
+			t.Errorf("Expected the histogram to cover %d neurons up to degree %d, got %v", neurons, out, stats.OutDegree.Histogram)
+		}
+		if brain.Metrics().Wiring.Synapses != synapses {
+			t.Error("Expected metrics to report the wiring")
+		}
+
+		d := degreeStats([]int{1, 3, 3, 1})
+		if d.Min != 1 || d.Max != 3 || d.Mean != 2 || d.StdDev != 1 || fmt.Sprint(d.Histogram) != "[0 2 0 2]" {
+			t.Errorf("Unexpected degree stats %+v", d)
+		}
+	})
+
+	t.Run("Density", func(t *testing.T) {
+		sparse := build(ConnectivityConfig{Radius: 2, BaseProbability: 0.05, DistanceFalloff: 2})
+		defer sparse.Cleanup()
+		dense := build(ConnectivityConfig{Radius: 2, BaseProbability: 0.9, DistanceFalloff: 0.5})
+		defer dense.Cleanup()
+		s, _ := sparse.SynapseCounts()
+		d, _ := dense.SynapseCounts()
+		if s >= d {
+			t.Errorf("Expected the dense reservoir to have more synapses, got %d vs %d", d, s)
+		}
+	})
+}
+
+// TestInhibition tests inhibitory synapses and Dale's principle
+func TestInhibition(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		if (TopologyConfig{}).orDefault() != defaultTopology {
+			t.Error("Expected the zero topology to use the default")
+		}
+		bad := defaultTopology
+		bad.InhibitoryFraction = 1.5
+		if bad.validate() == nil {
+			t.Error("Expected a fraction above 1 to be rejected")
+		}
+		bad = defaultTopology
+		bad.InhibitoryWeight = WeightRange{Min: 0.6, Max: 0.2}
+		if bad.validate() == nil {
+			t.Error("Expected min above max to be rejected")
+		}
+	})
+
+	t.Run("Neuron", func(t *testing.T) {
+		topology := defaultTopology
+		n := &LiquidNeuron{topology: &topology, connections: make([]*LiquidNeuron, 2), inhibitory: []bool{false, true}}
+		if s := n.synapseStrength(0); s < 0.1 || s > 0.5 {
+			t.Errorf("Excitatory strength %.2f out of range", s)
+		}
+		if s := n.synapseStrength(1); s > -0.1 || s < -0.5 {
+			t.Errorf("Inhibitory strength %.2f out of range", s)
+		}
+		n.state.Store(0.2)
+		n.stimulate(-0.5)
+		if state := n.state.Load().(float64); state != 0 {
+			t.Errorf("Expected inhibition to floor the state at 0, got %.2f", state)
+		}
+	})
+
+	build := func(fraction float64, dales bool) *LiquidStateBrain {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		config.Topology.InhibitoryFraction = fraction
+		config.Topology.DalesPrinciple = dales
+		brain := NewLiquidStateBrainWithConfig(6, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		return brain
+	}
+	mixed := func(brain *LiquidStateBrain) bool {
+		for x := range brain.reservoir {
+			for y := range brain.reservoir[x] {
+				for _, n := range brain.reservoir[x][y] {
+					for _, inhibitory := range n.inhibitory {
+						if inhibitory != n.inhibitory[0] {
+							return true
+						}
+					}
+				}
+			}
+		}
+		return false
+	}
+
+	t.Run("Dale's Principle", func(t *testing.T) {
+		brain := build(0.3, true)
+		defer brain.Cleanup()
+		if _, inhibitory := brain.SynapseCounts(); inhibitory == 0 {
+			t.Error("Expected inhibitory synapses")
+		}
+		if mixed(brain) {
+			t.Error("Under Dale's principle a neuron's synapses should share a sign")
+		}
+		clone := brain.Template().Instantiate()
+		defer clone.Cleanup()
+		e1, i1 := brain.SynapseCounts()
+		e2, i2 := clone.SynapseCounts()
+		if e1 != e2 || i1 != i2 {
+			t.Errorf("Pooled brain has %d/%d synapses, template %d/%d", e2, i2, e1, i1)
+		}
+	})
+
+	t.Run("Per Synapse", func(t *testing.T) {
+		brain := build(0.5, false)
+		defer brain.Cleanup()
+		if !mixed(brain) {
+			t.Error("Expected neurons with both excitatory and inhibitory synapses")
+		}
+	})
+
+	t.Run("Excitatory Only", func(t *testing.T) {
+		brain := build(0, true)
+		defer brain.Cleanup()
+		if _, inhibitory := brain.SynapseCounts(); inhibitory != 0 {
+			t.Errorf("Expected no inhibitory synapses, got %d", inhibitory)
+		}
+	})
+}
+
+// TestModulation tests the reward and attention modulation channels
+func TestModulation(t *testing.T) {
+	config := ModulationConfig{
+		Enabled:    true,
+		HalfLifeMS: 1000,
+		Channels: []ModulationChannel{
+			{Name: "reward", PlasticityGain: 1},
+			{Name: "attention", Region: &TapRegion{From: [3]float64{0, 0, 0}, To: [3]float64{1, 1, 0.5}}, DecayGain: -0.5, PlasticityGain: 0.5},
+		},
+	}
+
+	t.Run("Config", func(t *testing.T) {
+		if err := config.validate(); err != nil {
+			t.Errorf("Expected config to be valid: %v", err)
+		}
+		bad := config
+		bad.HalfLifeMS = 0
+		if bad.validate() == nil {
+			t.Error("Expected a zero half-life to be rejected")
+		}
+		bad = config
+		bad.Channels = []ModulationChannel{{Name: "reward"}, {Name: "reward"}}
+		if bad.validate() == nil {
+			t.Error("Expected duplicate channel names to be rejected")
+		}
+		if (ModulationConfig{HalfLifeMS: -1}).validate() != nil {
+			t.Error("Expected a disabled config to skip validation")
+		}
+	})
+
+	t.Run("Levels", func(t *testing.T) {
+		clock := NewVirtualClock(time.Unix(0, 0))
+		m := NewModulator(config, clock)
+		if err := m.Set("reward", 3); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if level := m.Level("reward"); level != 1 {
+			t.Errorf("Expected the level clamped to 1, got %.2f", level)
+		}
+		clock.Advance(time.Second)
+		if level := m.Level("reward"); math.Abs(level-0.5) > 1e-9 {
+			t.Errorf("Expected the level halved after a half-life, got %.3f", level)
+		}
+		if m.Set("dopamine", 1) == nil {
+			t.Error("Expected an unknown channel to be rejected")
+		}
+		if m.Level("dopamine") != 0 {
+			t.Error("Expected an unknown channel's level to be 0")
+		}
+
+		var off *Modulator
+		if off.Level("reward") != 0 || off.plasticity([]int{0}) != 1 || off.retention([]int{0}) != neuronRetention {
+			t.Error("Expected a nil modulator to scale nothing")
+		}
+		if off.Set("reward", 1) == nil {
+			t.Error("Expected Set on a nil modulator to fail")
+		}
+	})
+
+	t.Run("Scaling", func(t *testing.T) {
+		m := NewModulator(config, NewVirtualClock(time.Unix(0, 0)))
+		m.Set("reward", 1)
+		if p := m.plasticity([]int{0}); p != 2 {
+			t.Errorf("Expected full reward to double plasticity, got %.2f", p)
+		}
+		m.Set("reward", -1)
+		if p := m.plasticity([]int{0}); p != 0 {
+			t.Errorf("Expected full punishment to stop plasticity, got %.2f", p)
+		}
+		m.Set("attention", 1)
+		if r := m.retention([]int{1}); r <= neuronRetention {
+			t.Errorf("Expected attention to slow decay, retention %.3f", r)
+		}
+		if m.retention(nil) != neuronRetention || m.plasticity(nil) != 1 {
+			t.Error("Expected neurons outside every channel to be unaffected")
+		}
+	})
+
+	t.Run("Reservoir", func(t *testing.T) {
+		brainConfig := DefaultConfig()
+		brainConfig.Resources.MaxNeurons = 1000
+		brainConfig.Resources.MaxGoroutines = 50
+		brainConfig.Modulation = config
+		brain := NewLiquidStateBrainWithConfig(6, brainConfig)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+		if brain.Modulator() == nil {
+			t.Fatal("Expected a modulator")
+		}
+
+		last := brain.dimensions.Z - 1
+		if n := brain.reservoir[0][0][0]; len(n.channels) != 2 {
+			t.Errorf("Expected both channels at the front, got %v", n.channels)
+		}
+		if n := brain.reservoir[0][0][last]; len(n.channels) != 1 {
+			t.Errorf("Expected only reward at the back, got %v", n.channels)
+		}
+
+		brain.attend([]string{"cats", "the", "purr", "a"}, func(w string) float64 {
+			if w == "cats" || w == "purr" {
+				return 1
+			}
+			return 0
+		})
+		if level := brain.Modulator().Level("attention"); level < 0.45 || level > 0.5 {
+			t.Errorf("Expected attention near 0.5, got %.3f", level)
+		}
+		if brain.Plasticity(0, 0, 0) <= brain.Plasticity(0, 0, last) {
+			t.Error("Expected attention to raise plasticity only inside its region")
+		}
+
+		off := DefaultConfig()
+		off.Resources.MaxNeurons = 1000
+		off.Resources.MaxGoroutines = 50
+		plain := NewLiquidStateBrainWithConfig(4, off)
+		defer plain.Cleanup()
+		if plain.Modulator() != nil || plain.Plasticity(0, 0, 0) != 1 {
+			t.Error("Expected modulation off by default")
+		}
+	})
+
+	t.Run("Feedback", func(t *testing.T) {
+		m := NewModulator(config, RealClock)
+		handler := NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), nil)
+		handler.OnFeedback(m.FeedbackListener())
+		for _, listener := range handler.listeners {
+			listener("s1", Feedback{MessageIndex: 1, Rating: -1})
+		}
+		if level := m.Level("reward"); level > -0.9 {
+			t.Errorf("Expected negative feedback to set reward near -1, got %.3f", level)
+		}
+	})
+}
+
+// TestConduction tests distance-based synaptic delays
+func TestConduction(t *testing.T) {
+	t.Run("Config", func(t *testing.T) {
+		if (ConductionConfig{}).orDefault() != defaultConduction {
+			t.Error("Expected the zero conduction config to use the default")
+		}
+		if (ConductionConfig{MSPerUnit: -1}).validate() == nil {
+			t.Error("Expected a negative delay to be rejected")
+		}
+		if (ConductionConfig{MSPerUnit: 1, Jitter: 1.5}).validate() == nil {
+			t.Error("Expected jitter above 1 to be rejected")
+		}
+		c := ConductionConfig{SynapticDelayMS: 1, MSPerUnit: 2}
+		if d := c.delay(1.5); d != 4*time.Millisecond {
+			t.Errorf("Expected 4ms over 1.5 units, got %v", d)
+		}
+		c.Jitter = 0.5
+		for i := 0; i < 100; i++ {
+			if d := c.jitter(4 * time.Millisecond); d < 2*time.Millisecond || d > 6*time.Millisecond {
+				t.Fatalf("Jittered delay %v outside 2-6ms", d)
+			}
+		}
+	})
+
+	t.Run("Reservoir", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		brain := NewLiquidStateBrainWithConfig(6, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		for x := range brain.reservoir {
+			for y := range brain.reservoir[x] {
+				for _, n := range brain.reservoir[x][y] {
+					if len(n.delays) != len(n.connections) {
+						t.Fatalf("Neuron has %d delays for %d connections", len(n.delays), len(n.connections))
+					}
+					for i, target := range n.connections {
+						if want := brain.conduction.delay(n.distanceTo(target)); n.delays[i] != want {
+							t.Fatalf("Delay %v over %.2f units, want %v", n.delays[i], n.distanceTo(target), want)
+						}
+					}
+				}
+			}
+		}
+
+		clone := brain.Template().Instantiate()
+		defer clone.Cleanup()
+		for x := range brain.reservoir {
+			for y := range brain.reservoir[x] {
+				for z, n := range brain.reservoir[x][y] {
+					c := clone.reservoir[x][y][z]
+					for i := range n.delays {
+						if c.delays[i] != n.delays[i] {
+							t.Fatalf("Pooled brain delay %v, template %v", c.delays[i], n.delays[i])
+						}
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Traveling Wave", func(t *testing.T) {
+		conduction := ConductionConfig{SynapticDelayMS: 1, MSPerUnit: 2}
+		source := &LiquidNeuron{conduction: &conduction}
+		near := &LiquidNeuron{x: 1}
+		far := &LiquidNeuron{x: 2}
+		near.state.Store(0.0)
+		far.state.Store(0.0)
+		source.connections = []*LiquidNeuron{far, near}
+
+		clock := NewVirtualClock(time.Unix(0, 0))
+		batch := acquireSpikeBatch()
+		for i, target := range source.connections {
+			batch.events = append(batch.events, spikeEvent{target: target, strength: 0.5, delay: source.synapseDelay(i)})
+		}
+		done := make(chan struct{})
+		go func() {
+			batch.deliver(clock)
+			close(done)
+		}()
+		waitPending := func() {
+			for clock.Pending() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		waitPending()
+		clock.Advance(3 * time.Millisecond)
+		waitPending()
+		if near.state.Load().(float64) == 0 || far.state.Load().(float64) != 0 {
+			t.Error("Expected only the near neuron to be reached after 3ms")
+		}
+		clock.Advance(2 * time.Millisecond)
+		<-done
+		if far.state.Load().(float64) == 0 {
+			t.Error("Expected the far neuron to be reached after 5ms")
+		}
+	})
+}
+
+// TestStimulusProtocol tests scripted injection protocols
+func TestStimulusProtocol(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/impulse.yaml"
+	os.WriteFile(path, []byte(`# impulse then a short train
+name: impulse
+sample_ms: 10
+duration_ms: 40
+steps:
+  - at_ms: 10
+    input: cat
+    amplitude: 2
+  - at_ms: 20
+    input: dog
+    repeat: 5
+    every_ms: 10
+`), 0644)
+
+	protocol, err := LoadStimulusProtocol(path)
+	if err != nil {
+		t.Fatalf("Failed to load protocol: %v", err)
+	}
+
+	t.Run("Timeline", func(t *testing.T) {
+		events := protocol.timeline()
+		var samples, injections int
+		for i, event := range events {
+			if event.input == "" {
+				samples++
+			} else {
+				injections++
+			}
+			if i > 0 && event.at < events[i-1].at {
+				t.Fatal("Expected events in time order")
+			}
+		}
+		// Samples at 0-40ms; dog repeats past 40ms are dropped
+		if samples != 5 || injections != 4 {
+			t.Errorf("Expected 5 samples and 4 injections, got %d and %d", samples, injections)
+		}
+		if events[1].at != 10*time.Millisecond || events[1].input != "" || events[2].input != "cat" || events[2].amplitude != 2 {
+			t.Errorf("Expected the 10ms sample before the cat impulse, got %+v %+v", events[1], events[2])
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		for name, bad := range map[string]StimulusProtocol{
+			"no sampling":   {DurationMS: 10},
+			"late step":     {SampleMS: 1, DurationMS: 10, Steps: []StimulusStep{{AtMS: 20, Input: "cat"}}},
+			"empty input":   {SampleMS: 1, DurationMS: 10, Steps: []StimulusStep{{Input: " "}}},
+			"repeat no gap": {SampleMS: 1, DurationMS: 10, Steps: []StimulusStep{{Input: "cat", Repeat: 3}}},
+		} {
+			if bad.Validate() == nil {
+				t.Errorf("Expected %s to be rejected", name)
+			}
+		}
+		os.WriteFile(dir+"/bad.json", []byte(`{"sample_ms": 0}`), 0644)
+		if _, err := LoadStimulusProtocol(dir + "/bad.json"); err == nil {
+			t.Error("Expected an invalid protocol file to fail")
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		brain := NewLiquidStateBrainWithConfig(4, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		recordPath := dir + "/run.jsonl"
+		recorder, err := NewRecorder(recordPath, config, nil, 7)
+		if err != nil {
+			t.Fatalf("Failed to create recorder: %v", err)
+		}
+		run, err := brain.RunProtocol(protocol, recorder)
+		recorder.Close()
+		if err != nil {
+			t.Fatalf("Protocol failed: %v", err)
+		}
+		if len(run.Samples) != 5 || len(run.Injections) != 4 {
+			t.Fatalf("Expected 5 samples and 4 injections, got %d and %d", len(run.Samples), len(run.Injections))
+		}
+		neurons := brain.dimensions.X * brain.dimensions.Y * brain.dimensions.Z
+		for i, sample := range run.Samples {
+			if len(sample.States) != neurons {
+				t.Fatalf("Sample has %d states, want %d", len(sample.States), neurons)
+			}
+			if i > 0 && sample.AtMS < run.Samples[i-1].AtMS {
+				t.Error("Expected samples in time order")
+			}
+		}
+		if run.Injections[0].AtMS < 10 {
+			t.Errorf("Cat injected at %.1fms, before its 10ms", run.Injections[0].AtMS)
+		}
+
+		replay, err := LoadReplay(recordPath)
+		if err != nil {
+			t.Fatalf("Failed to load recording: %v", err)
+		}
+		counts := make(map[string]int)
+		for _, step := range replay.Steps {
+			for _, event := range step.Events {
+				if event.Session == "protocol:impulse" {
+					counts[event.Event]++
+				}
+			}
+		}
+		if counts["state"] != 5 || counts["stimulus"] != 4 {
+			t.Errorf("Expected 5 state and 4 stimulus events recorded, got %v", counts)
+		}
+		if report := replay.Run(nil); report.Steps != 0 {
+			t.Errorf("Expected nothing to re-execute from a protocol recording, got %d steps", report.Steps)
+		}
+	})
+}
+
+// TestReservoirBench tests the reservoir computing benchmarks
+func TestReservoirBench(t *testing.T) {
+	t.Run("Ridge", func(t *testing.T) {
+		var rows [][]float64
+		var targets []float64
+		for i := 0; i < 20; i++ {
+			x := float64(i) / 10
+			rows = append(rows, []float64{x, x * x})
+			targets = append(targets, 2*x-x*x+1)
+		}
+		readout, err := fitRidge(rows, 1e-9)
+		if err != nil {
+			t.Fatalf("Fit failed: %v", err)
+		}
+		w := readout.solve(targets)
+		for i, want := range []float64{2, -1, 1} {
+			if math.Abs(w[i]-want) > 1e-4 {
+				t.Errorf("Weight %d = %.5f, want %.0f", i, w[i], want)
+			}
+		}
+		if r2 := squaredCorrelation([]float64{1, 2, 3}, []float64{2, 4, 6}); math.Abs(r2-1) > 1e-9 {
+			t.Errorf("Expected perfect correlation, got %.3f", r2)
+		}
+		if e := nrmse([]float64{2, 2, 2}, []float64{1, 2, 3}); math.Abs(e-1) > 1e-9 {
+			t.Errorf("Expected predicting the mean to score 1, got %.3f", e)
+		}
+	})
+
+	t.Run("NARMA", func(t *testing.T) {
+		u, y := narma10(200, rand.New(rand.NewSource(1)))
+		for i := range u {
+			if u[i] < 0 || u[i] > 0.5 || math.IsNaN(y[i]) || y[i] > 1 {
+				t.Fatalf("Step %d out of range: u %.3f y %.3f", i, u[i], y[i])
+			}
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if err := DefaultReservoirBench.validate(); err != nil {
+			t.Errorf("Expected the default to be valid: %v", err)
+		}
+		bad := DefaultReservoirBench
+		bad.Washout = 5
+		if bad.validate() == nil {
+			t.Error("Expected a washout shorter than max delay to be rejected")
+		}
+		bad = DefaultReservoirBench
+		bad.Steps = bad.Washout + 3
+		if bad.validate() == nil {
+			t.Error("Expected too few steps to be rejected")
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		brain := NewLiquidStateBrainWithConfig(4, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		bench := ReservoirBenchConfig{Steps: 60, Washout: 10, Step: 2 * time.Millisecond, MaxDelay: 5, Ridge: 1e-2, Classes: 2, Trials: 2, Seed: 3}
+		report, err := BenchReservoir(brain, bench)
+		if err != nil {
+			t.Fatalf("Bench failed: %v", err)
+		}
+		if len(report.MemoryByDelay) != 5 || report.MemoryCapacity < 0 || report.MemoryCapacity > 5 {
+			t.Errorf("Unexpected memory capacity %.3f over %d delays", report.MemoryCapacity, len(report.MemoryByDelay))
+		}
+		if math.IsNaN(report.NARMA10NRMSE) || report.NARMA10NRMSE < 0 {
+			t.Errorf("Unexpected NARMA-10 NRMSE %.3f", report.NARMA10NRMSE)
+		}
+		if len(report.SeparationClasses) != 2 || report.SeparationAccuracy < 0 || report.SeparationAccuracy > 1 {
+			t.Errorf("Unexpected separability %+v", report)
+		}
+		var out strings.Builder
+		report.Print(&out)
+		if !strings.Contains(out.String(), "Memory capacity") {
+			t.Errorf("Unexpected report:\n%s", out.String())
+		}
+	})
+}
+
+// TestGoroutineBudget tests sharing max_goroutines between components
+func TestGoroutineBudget(t *testing.T) {
+	t.Run("Grants", func(t *testing.T) {
+		b := NewGoroutineBudget(ResourceLimits{MaxGoroutines: 100, GoroutineShares: map[string]float64{"brain": 0.5}})
+		if n := b.Request("brain", 80, 1); n != 50 {
+			t.Errorf("Expected the brain capped at its share of 50, got %d", n)
+		}
+		if n := b.Request("llm", 80, 1); n != 50 {
+			t.Errorf("Expected the llm to get what's left, got %d", n)
+		}
+		if n := b.Request("server", 8, 2); n != 2 {
+			t.Errorf("Expected the server's minimum past the limit, got %d", n)
+		}
+		stats := b.Stats()
+		if stats.Available != 0 || stats.Overcommitted != 2 || stats.Held["brain"] != 50 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+		b.Release("llm", 50)
+		b.Release("server", 2)
+		if stats := b.Stats(); stats.Available != 50 || stats.Overcommitted != 0 || stats.Held["llm"] != 0 {
+			t.Errorf("Expected released workers back, got %+v", stats)
+		}
+		if n := NewGoroutineBudget(ResourceLimits{}).Request("brain", 5000, 1); n != 5000 {
+			t.Errorf("Expected no limit to grant everything, got %d", n)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		config := DefaultConfig()
+		if config.Resources.GoroutineShares[budgetBrain] == 0 {
+			t.Error("Expected a default brain share")
+		}
+		config.Resources.GoroutineShares = map[string]float64{"brain": 1.5}
+		if config.Validate() == nil {
+			t.Error("Expected a share above 1 to be rejected")
+		}
+	})
+
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	config.Model.MaxConcepts = 100
+	budget := SharedGoroutineBudget()
+
+	t.Run("Brain", func(t *testing.T) {
+		before := budget.Stats().Held[budgetBrain]
+		brain := NewLiquidStateBrainWithConfig(6, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		if held := budget.Stats().Held[budgetBrain]; held != before+brain.workers || brain.workers > 25+len(brain.outputLayer) {
+			t.Errorf("Brain holds %d workers, budget shows %d more", brain.workers, held-before)
+		}
+		brain.Cleanup()
+		if held := budget.Stats().Held[budgetBrain]; held != before {
+			t.Errorf("Expected the brain's workers back, %d held, %d before", held, before)
+		}
+	})
+
+	t.Run("LLM", func(t *testing.T) {
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create LLM")
+		}
+		defer llm.Cleanup()
+		if llm.workers < 1 || llm.workers > 15 {
+			t.Errorf("Expected at most the llm share of 15 workers, got %d", llm.workers)
+		}
+
+		// Batched concept neurons still take in pulses
+		var neuron *ConceptNeuron
+		llm.concepts.Range(func(_ string, n *ConceptNeuron) bool {
+			neuron = n
+			return false
+		})
+		llm.activity.Add(1)
+		neuron.visual <- Pulse{intensity: 0.5, source: "test"}
+		deadline := time.Now().Add(time.Second)
+		for neuron.getActivation() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if neuron.getActivation() == 0 {
+			t.Error("Expected the pulse to activate the concept")
+		}
+	})
+
+	t.Run("Orchestrator", func(t *testing.T) {
+		before := budget.Stats().Held[budgetOrchestrator]
+		NewParallelOrchestrator(50).ProcessInParallel("route this")
+		deadline := time.Now().Add(time.Second)
+		for budget.Stats().Held[budgetOrchestrator] != before && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if held := budget.Stats().Held[budgetOrchestrator]; held != before {
+			t.Errorf("Expected the orchestrator's workers back, %d held, %d before", held, before)
+		}
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		queue := NewInferenceQueueFromLimits(config.Resources)
+		if capacity := queue.Stats().Capacity; capacity < 1 || capacity > config.Resources.MaxConcurrentRequests {
+			t.Errorf("Unexpected queue capacity %d", capacity)
+		}
+	})
+}
+
+// TestDegradation tests switching to degraded behavior under resource pressure
+func TestDegradation(t *testing.T) {
+	config := DefaultConfig().Degradation
+	config.SustainChecks = 3
+
+	t.Run("Sustained Pressure Degrades", func(t *testing.T) {
+		d := NewDegrader(config, NewVirtualClock(time.Unix(0, 0)))
+		d.Observe(0.95)
+		d.Observe(0.95)
+		d.Observe(0.5) // a dip resets the streak
+		d.Observe(0.95)
+		d.Observe(0.95)
+		if d.Degraded() {
+			t.Fatal("Expected full behavior before pressure is sustained")
+		}
+		d.Observe(0.95)
+		if !d.Degraded() {
+			t.Fatal("Expected degraded behavior after sustained pressure")
+		}
+		if d.BeamWidth(4) != config.BeamWidth || d.CircuitDepth(maxCircuitDepth) != config.CircuitDepth {
+			t.Errorf("Expected degraded limits, got beam width %d and depth %d", d.BeamWidth(4), d.CircuitDepth(maxCircuitDepth))
+		}
+		if d.BeamWidth(1) != 1 {
+			t.Error("Degraded beam width should never widen the search")
+		}
+	})
+
+	t.Run("Recovery Applies Deferred Updates", func(t *testing.T) {
+		d := NewDegrader(config, NewVirtualClock(time.Unix(0, 0)))
+		for i := 0; i < config.SustainChecks; i++ {
+			d.Observe(1)
+		}
+		var applied []int
+		for i := 0; i < 3; i++ {
+			i := i
+			d.Defer(func() { applied = append(applied, i) })
+		}
+		if len(applied) != 0 || d.Status().Deferred != 3 {
+			t.Fatalf("Expected 3 deferred updates, applied %v", applied)
+		}
+
+		d.Observe(0.8) // between the ratios, still pressured
+		for i := 0; i < config.SustainChecks; i++ {
+			d.Observe(0.8)
+		}
+		if !d.Degraded() {
+			t.Fatal("Expected to stay degraded above the recovery ratio")
+		}
+		for i := 0; i < config.SustainChecks; i++ {
+			d.Observe(0.2)
+		}
+		if d.Degraded() {
+			t.Fatal("Expected full behavior once pressure eased")
+		}
+		if fmt.Sprint(applied) != "[0 1 2]" {
+			t.Errorf("Expected deferred updates in order, got %v", applied)
+		}
+		if d.BeamWidth(4) != 4 || d.CircuitDepth(maxCircuitDepth) != maxCircuitDepth {
+			t.Error("Expected full limits after recovery")
+		}
+		status := d.Status()
+		if status.Transitions != 2 || status.Deferred != 0 {
+			t.Errorf("Unexpected status after recovery: %+v", status)
+		}
+
+		ran := false
+		d.Defer(func() { ran = true })
+		if !ran {
+			t.Error("Expected updates to run immediately at full behavior")
+		}
+	})
+
+	t.Run("Disabled Never Degrades", func(t *testing.T) {
+		var nilDegrader *Degrader
+		if nilDegrader.Degraded() || nilDegrader.BeamWidth(4) != 4 {
+			t.Error("A nil degrader should never degrade")
+		}
+		d := NewDegrader(DegradationConfig{}, RealClock)
+		for i := 0; i < 10; i++ {
+			d.Observe(2)
+		}
+		if d.Degraded() {
+			t.Error("A disabled degrader should never degrade")
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		bad := config
+		bad.RecoverRatio = config.PressureRatio + 0.1
+		if bad.validate() == nil {
+			t.Error("Expected recover_ratio above pressure_ratio to be rejected")
+		}
+		bad = config
+		bad.BeamWidth = 0
+		if bad.validate() == nil {
+			t.Error("Expected a zero degraded beam width to be rejected")
+		}
+	})
+
+	t.Run("Admin Endpoint", func(t *testing.T) {
+		admin := NewAdminHandler(AdminConfig{Token: "secret"}, nil, nil, nil)
+		admin.degrader = NewDegrader(config, NewVirtualClock(time.Unix(0, 0)))
+		for i := 0; i < config.SustainChecks; i++ {
+			admin.degrader.Observe(0.97)
+		}
+		req := httptest.NewRequest("GET", "/admin/degradation", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		var status DegradationStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("Expected a degradation status, got %d: %v", rec.Code, err)
+		}
+		if !status.Degraded || status.Pressure != 0.97 || status.BeamWidth != config.BeamWidth {
+			t.Errorf("Unexpected degradation status: %+v", status)
+		}
+	})
+}
+
+// TestShutdownStages tests ordered, run-once shutdown cleanups
+func TestShutdownStages(t *testing.T) {
+	t.Run("Stage Order", func(t *testing.T) {
+		shutdown := NewGracefulShutdown(time.Second)
+		var order []string
+		record := func(name string) func() { return func() { order = append(order, name) } }
+		shutdown.Register(ShutdownStores, "store", record("store"))
+		shutdown.Register(ShutdownModels, "model", record("model"))
+		shutdown.Register(ShutdownServers, "server", record("server"))
+		shutdown.Register(ShutdownOrchestrators, "orchestrator", record("orchestrator"))
+		shutdown.Register(ShutdownModels, "second model", record("second model"))
+
+		shutdown.Shutdown()
+		shutdown.Shutdown()
+		want := "[server orchestrator second model model store]"
+		if fmt.Sprint(order) != want {
+			t.Errorf("Expected cleanups %s exactly once, got %v", want, order)
+		}
+	})
+
+	t.Run("Release Runs Once", func(t *testing.T) {
+		shutdown := NewGracefulShutdown(time.Second)
+		calls := 0
+		release := shutdown.Register(ShutdownModels, "model", func() { calls++ })
+		release()
+		release()
+		shutdown.Shutdown()
+		if calls != 1 {
+			t.Errorf("Expected a released cleanup to run once, ran %d times", calls)
+		}
+		if len(shutdown.pending()) != 0 {
+			t.Error("Expected a released cleanup to be unregistered")
+		}
+	})
+
+	t.Run("Signals", func(t *testing.T) {
+		if len(shutdownSignals) == 0 || shutdownSignals[0] != os.Interrupt {
+			t.Errorf("Expected interrupt among the shutdown signals, got %v", shutdownSignals)
+		}
+	})
+}
+
+// TestDoctor tests the startup self-test report
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name string, config interface{}) string {
+		data, err := json.Marshal(config)
+		if err != nil {
+			t.Fatalf("Failed to marshal config: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+	find := func(report *DoctorReport, name string) DoctorCheck {
+		for _, check := range report.Checks {
+			if check.Name == name {
+				return check
+			}
+		}
+		t.Fatalf("No %q check in %+v", name, report.Checks)
+		return DoctorCheck{}
+	}
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		path := writeConfig("bad.json", map[string]interface{}{"resources": map[string]int{"max_memory_mb": -1}})
+		report := RunDoctor(path)
+		if report.Status != doctorFail || len(report.Checks) != 1 || report.Checks[0].Hint == "" {
+			t.Errorf("Expected a single failed config check with a hint, got %+v", report)
+		}
+	})
+
+	t.Run("Diagnoses Problems", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Training.DatasetPaths = []string{filepath.Join(dir, "missing.txt")}
+		config.Fallback.Chain = []string{"gpt4", "oracle"}
+		report := RunDoctor(writeConfig("config.json", config))
+
+		if check := find(report, "dataset paths"); check.Status != doctorWarn || check.Hint == "" {
+			t.Errorf("Expected a warning about the starter corpus, got %+v", check)
+		}
+		if check := find(report, "dataset"); check.Status == doctorFail {
+			t.Errorf("Expected the starter corpus to load, got %+v", check)
+		}
+		if check := find(report, "fallback chain"); check.Status != doctorFail || !strings.Contains(check.Detail, "oracle") {
+			t.Errorf("Expected the unknown fallback capability to fail, got %+v", check)
+		}
+		if check := find(report, "capability calculator"); check.Status != doctorOK {
+			t.Errorf("Expected the calculator to answer, got %+v", check)
+		}
+		if check := find(report, "inference"); check.Status == doctorFail {
+			t.Errorf("Expected inference to produce a response, got %+v", check)
+		}
+		if report.Status != doctorFail {
+			t.Errorf("Expected the report to fail with its worst check, got %s", report.Status)
+		}
+
+		var out bytes.Buffer
+		report.Print(&out)
+		if !strings.Contains(out.String(), "oracle") || !strings.Contains(out.String(), "Overall: fail") {
+			t.Errorf("Unexpected printed report:\n%s", out.String())
+		}
+	})
+}
+
+// TestCorpusStats tests the corpus statistics and quality report
+func TestCorpusStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	corpus := "hello there friend. the cat sat on the mat.\nthe cat ran and the dog sat on the mat because it was warm!\n"
+	if err := os.WriteFile(path, []byte(corpus), 0644); err != nil {
+		t.Fatalf("Failed to write corpus: %v", err)
+	}
+	loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: []string{path}, MinWordFreq: 1, DisableStarterCorpus: true})
+	if err != nil {
+		t.Fatalf("Failed to load corpus: %v", err)
+	}
+	stats := ComputeCorpusStats(loader, nil, 3)
+
+	t.Run("Counts", func(t *testing.T) {
+		if stats.Documents != 1 || stats.Tokens != 23 {
+			t.Errorf("Expected 1 document of 23 tokens, got %d and %d", stats.Documents, stats.Tokens)
+		}
+		if stats.Types >= stats.Tokens || stats.TypeTokenRatio != float64(stats.Types)/float64(stats.Tokens) {
+			t.Errorf("Unexpected types %d and ratio %.3f", stats.Types, stats.TypeTokenRatio)
+		}
+	})
+
+	t.Run("Sentence Lengths", func(t *testing.T) {
+		d := stats.Sentences
+		if d.Count != 3 || d.Max != 14 || d.Median != 6 {
+			t.Errorf("Expected sentences of 3, 6 and 14 tokens, got %+v", d)
+		}
+		total := 0
+		for _, bucket := range d.Histogram {
+			total += bucket.Count
+		}
+		if total != d.Count || d.Histogram[0].Count != 1 {
+			t.Errorf("Unexpected histogram %+v", d.Histogram)
+		}
+	})
+
+	t.Run("NGrams", func(t *testing.T) {
+		if len(stats.NGrams) != 3 || stats.NGrams[0].Top[0] != (NGramCount{Gram: "the", Count: 5}) {
+			t.Fatalf("Expected \"the\" as the top unigram, got %+v", stats.NGrams)
+		}
+		if top := stats.NGrams[1].Top[0]; top.Gram != "on the" && top.Gram != "the cat" && top.Gram != "the mat" {
+			t.Errorf("Unexpected top bigram %+v", top)
+		}
+		if len(stats.NGrams[2].Top) != 3 {
+			t.Errorf("Expected the top 3 trigrams, got %d", len(stats.NGrams[2].Top))
+		}
+	})
+
+	t.Run("Coverage And Warnings", func(t *testing.T) {
+		var connectors WordListCoverage
+		for _, c := range stats.Coverage {
+			if c.List == "connectors" {
+				connectors = c
+			}
+		}
+		if connectors.InVocabulary != 2 || connectors.WithTransitions != 2 {
+			t.Errorf("Expected \"and\" and \"because\" covered, got %+v", connectors)
+		}
+		warned := false
+		for _, w := range stats.Warnings {
+			if strings.Contains(w, "tokens") {
+				warned = true
+			}
+		}
+		if !warned {
+			t.Errorf("Expected a small corpus warning, got %v", stats.Warnings)
+		}
+	})
+}
+
+// TestVocabularyPruning tests document frequency, TF-IDF and list pruning
+func TestVocabularyPruning(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range []string{
+		"the cat sat on the mat with the hat",
+		"the dog sat on the log with the frog",
+		"the bird sang in the tree with zebra",
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("doc%d.txt", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	load := func(pruning VocabularyPruning, maxVocab int) *DatasetLoader {
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: paths, MinWordFreq: 1, MaxVocabSize: maxVocab, DisableStarterCorpus: true, Pruning: pruning})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		return loader
+	}
+
+	t.Run("Document Frequency", func(t *testing.T) {
+		loader := load(VocabularyPruning{MinDocFreq: 2, MaxDocFreqRatio: 0.9}, 100)
+		if !loader.InVocabulary("sat") {
+			t.Error("Expected a word in 2 of 3 documents to be kept")
+		}
+		if loader.InVocabulary("cat") || loader.InVocabulary("the") {
+			t.Error("Expected words in 1 or all 3 documents to be pruned")
+		}
+		if stats := loader.PruneStats(); stats.DocFreq == 0 || stats.Candidates != 15 {
+			t.Errorf("Unexpected prune stats %+v", stats)
+		}
+	})
+
+	t.Run("TF-IDF Band", func(t *testing.T) {
+		// "the" is frequent but in every document, so its weight is
+		// below that of the rarer, document-specific words
+		loader := load(VocabularyPruning{MaxTFIDF: 0.3}, 100)
+		if loader.InVocabulary("the") {
+			t.Error("Expected the heaviest word to fall above the band")
+		}
+		if !loader.InVocabulary("zebra") {
+			t.Error("Expected a lighter word to stay within the band")
+		}
+		loader = load(VocabularyPruning{MinTFIDF: 0.25}, 100)
+		if loader.InVocabulary("zebra") || !loader.InVocabulary("the") {
+			t.Error("Expected the minimum to cut light words and keep heavy ones")
+		}
+	})
+
+	t.Run("Keep And Drop Lists", func(t *testing.T) {
+		loader := load(VocabularyPruning{MinDocFreq: 2, Keep: []string{"zebra", "cat"}, Drop: []string{"sat", "cat"}}, 100)
+		if !loader.InVocabulary("zebra") {
+			t.Error("Expected a kept word to survive document frequency pruning")
+		}
+		if loader.InVocabulary("sat") || loader.InVocabulary("cat") {
+			t.Error("Expected dropped words to be pruned, even when also kept")
+		}
+	})
+
+	t.Run("Deterministic Truncation", func(t *testing.T) {
+		first := load(VocabularyPruning{Keep: []string{"zebra"}}, 4)
+		vocab := first.GetVocabulary()
+		if len(vocab) != 4 || vocab[0] != "the" || vocab[3] != "zebra" {
+			t.Fatalf("Expected the most frequent words with the kept word last, got %v", vocab)
+		}
+		if stats := first.PruneStats(); stats.Truncated != 11 {
+			t.Errorf("Expected 11 words truncated, got %+v", stats)
+		}
+		for i := 0; i < 3; i++ {
+			if again := load(VocabularyPruning{Keep: []string{"zebra"}}, 4).GetVocabulary(); fmt.Sprint(again) != fmt.Sprint(vocab) {
+				t.Fatalf("Truncation differs between runs: %v vs %v", again, vocab)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		if (VocabularyPruning{MaxDocFreqRatio: 1.5}).validate() == nil || (VocabularyPruning{MinTFIDF: 0.5, MaxTFIDF: 0.1}).validate() == nil {
+			t.Error("Expected invalid bounds to be rejected")
+		}
+	})
+}
+
+// TestIncrementalCorpus tests adding documents to a built loader
+func TestIncrementalCorpus(t *testing.T) {
+	load := func(maxVocab int, pruning VocabularyPruning) *DatasetLoader {
+		dir := t.TempDir()
+		var paths []string
+		for i, content := range []string{"the cat sat on the mat", "the cat ran to the mat"} {
+			path := filepath.Join(dir, fmt.Sprintf("doc%d.txt", i))
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write document: %v", err)
+			}
+			paths = append(paths, path)
+		}
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: paths, MinWordFreq: 2, MaxVocabSize: maxVocab, EmbeddingDim: 16, DisableStarterCorpus: true, Pruning: pruning})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		return loader
+	}
+
+	t.Run("Updates Tables", func(t *testing.T) {
+		loader := load(100, VocabularyPruning{})
+		before, _ := loader.GetTransitions("the")
+		if loader.InVocabulary("ran") {
+			t.Fatal("Expected a word seen once to be below the threshold")
+		}
+
+		update, err := loader.AddDocument("live", "the cat ran")
+		if err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+		if len(update.NewWords) != 1 || update.NewWords[0] != "ran" || update.Skipped != 0 {
+			t.Errorf("Expected only ran to be admitted, got %+v", update)
+		}
+		vocab := loader.GetVocabulary()
+		if !loader.InVocabulary("ran") || vocab[len(vocab)-1] != "ran" {
+			t.Errorf("Expected ran appended to the vocabulary, got %v", vocab)
+		}
+		if len(loader.GetDocuments()) != 3 {
+			t.Errorf("Expected 3 documents, got %d", len(loader.GetDocuments()))
+		}
+
+		after, _ := loader.GetTransitions("the")
+		if math.Abs(after["cat"]-0.6) > 1e-9 {
+			t.Errorf("Expected the->cat to be 3/5, got %v", after["cat"])
+		}
+		if before["cat"] != 0.5 {
+			t.Errorf("Expected a table already handed out to stay unchanged, got %v", before["cat"])
+		}
+		if next, ok := loader.GetTransitions("cat"); !ok || next["ran"] != 1 {
+			t.Errorf("Expected the admitted word to be reachable, got %v", next)
+		}
+		if _, ok := loader.GetEmbedding("ran"); !ok {
+			t.Error("Expected the admitted word to be embedded")
+		}
+		if sim := loader.ComputeSimilarity("ran", "cat"); sim == 0 {
+			t.Error("Expected similarity with the admitted word")
+		}
+	})
+
+	t.Run("Vocabulary Limits", func(t *testing.T) {
+		loader := load(3, VocabularyPruning{})
+		update, err := loader.AddDocument("live", "the cat ran")
+		if err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+		if len(update.NewWords) != 0 || update.Skipped != 1 || loader.InVocabulary("ran") {
+			t.Errorf("Expected ran to be skipped at the limit, got %+v", update)
+		}
+
+		loader = load(100, VocabularyPruning{Drop: []string{"ran"}})
+		if update, _ := loader.AddDocument("live", "the cat ran"); len(update.NewWords) != 0 {
+			t.Errorf("Expected the drop-list to hold, got %+v", update)
+		}
+	})
+
+	t.Run("From File", func(t *testing.T) {
+		loader := load(100, VocabularyPruning{})
+		path := filepath.Join(t.TempDir(), "new.txt")
+		if err := os.WriteFile(path, []byte("the mat sat there"), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		update, err := loader.AddDocumentFile(path)
+		if err != nil || update.Source != path || !loader.InVocabulary("sat") {
+			t.Errorf("Expected sat admitted from %s, got %+v, %v", path, update, err)
+		}
+		if _, err := loader.AddDocumentFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+		if _, err := loader.AddDocument("empty", ""); err == nil {
+			t.Error("Expected an error for empty text")
+		}
+	})
+
+	t.Run("Concurrent Readers", func(t *testing.T) {
+		loader := load(100, VocabularyPruning{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					loader.GetNextWord("the", 1.0)
+					loader.ComputeSimilarity("cat", "mat")
+					loader.GetVocabulary()
+				}
+			}()
+		}
+		for i := 0; i < 20; i++ {
+			if _, err := loader.AddDocument(fmt.Sprintf("live%d", i), fmt.Sprintf("the cat word%d ran", i%5)); err != nil {
+				t.Errorf("AddDocument failed: %v", err)
+			}
+		}
+		wg.Wait()
+		if !loader.InVocabulary("word0") {
+			t.Error("Expected repeated new words to be admitted")
+		}
+	})
+}
+
+// TestTransitionCache tests persisting transition tables between runs
+func TestTransitionCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	path := filepath.Join(dir, "doc.txt")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+	}
+	load := func() *DatasetLoader {
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: []string{path}, MinWordFreq: 1, DisableStarterCorpus: true, CacheDir: cacheDir})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		return loader
+	}
+	cached := func() []string {
+		files, _ := filepath.Glob(filepath.Join(cacheDir, "transitions-*.gob"))
+		return files
+	}
+	write("Hello there friend. The cat sat on the mat and the cat ran")
+
+	built := load()
+	if built.tablesPath != "" || len(cached()) != 1 {
+		t.Fatalf("Expected the first run to build and cache the tables, got %v", cached())
+	}
+	first := cached()
+	want, _ := built.GetTransitions("the")
+	same := func(got map[string]float64) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for word, p := range want {
+			if got[word] != p {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("Loads Lazily", func(t *testing.T) {
+		loader := load()
+		if loader.tablesPath == "" || loader.transitions.Len() != 0 {
+			t.Fatal("Expected the cached tables to wait for first use")
+		}
+		got, ok := loader.GetTransitions("the")
+		if !ok || !same(got) {
+			t.Errorf("Expected cached transitions %v, got %v", want, got)
+		}
+		if loader.GetStarterWord() != built.GetStarterWord() || loader.IsEnder("ran") != built.IsEnder("ran") {
+			t.Error("Expected cached starters and enders to match the built ones")
+		}
+		if _, err := loader.AddDocument("live", "the dog ran"); err != nil {
+			t.Errorf("Expected incremental updates after loading: %v", err)
+		}
+	})
+
+	t.Run("Corpus Change Rebuilds", func(t *testing.T) {
+		write("A different corpus about the dog")
+		defer write("Hello there friend. The cat sat on the mat and the cat ran")
+		loader := load()
+		if loader.tablesPath != "" {
+			t.Error("Expected a changed corpus to rebuild")
+		}
+		if files := cached(); len(files) != 1 || files[0] == first[0] {
+			t.Errorf("Expected only the new corpus's tables to be cached, got %v", files)
+		}
+	})
+
+	t.Run("Unreadable Cache Rebuilds", func(t *testing.T) {
+		load() // cache the original corpus again
+		files := cached()
+		if err := os.WriteFile(files[0], []byte("not gob"), 0644); err != nil {
+			t.Fatalf("Failed to corrupt cache: %v", err)
+		}
+		loader := load()
+		if got, ok := loader.GetTransitions("the"); !ok || !same(got) {
+			t.Errorf("Expected rebuilt transitions %v, got %v", want, got)
+		}
+	})
+}
+
+// TestLoaderViews tests that loader getters can't be used to corrupt it
+func TestLoaderViews(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range []string{"the zebra ran to the mat", "the cat sat on the mat"} {
+		path := filepath.Join(dir, fmt.Sprintf("doc%d.txt", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	load := func(copyOnWrite bool) *DatasetLoader {
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: paths, MinWordFreq: 1, EmbeddingDim: 8, DisableStarterCorpus: true, CopyOnWrite: copyOnWrite})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		return loader
+	}
+
+	t.Run("Document Snapshots", func(t *testing.T) {
+		loader := load(false)
+		docs := loader.GetDocuments()
+		sort.Slice(docs, func(i, j int) bool { return docs[i].Path > docs[j].Path })
+		docs[0].Path = "changed"
+		if tokens := docs[1].Tokens; cap(tokens) != len(tokens) {
+			t.Error("Expected token capacity capped so appends reallocate")
+		}
+		if got := loader.GetDocuments(); got[0].Path != paths[0] || got[1].Path != paths[1] {
+			t.Errorf("Expected the loader's documents untouched, got %s, %s", got[0].Path, got[1].Path)
+		}
+	})
+
+	t.Run("Copy On Write", func(t *testing.T) {
+		loader := load(true)
+		loader.GetDocuments()[0].Tokens[0] = "changed"
+		loader.GetVocabulary()[0] = "changed"
+		loader.GetEmbedding("the")
+		emb, _ := loader.GetEmbedding("the")
+		emb[0] = 42
+		next, _ := loader.GetTransitions("the")
+		next["changed"] = 1
+
+		if loader.GetDocuments()[0].Tokens[0] != "the" || loader.GetVocabulary()[0] != "the" {
+			t.Error("Expected documents and vocabulary untouched")
+		}
+		if emb, _ := loader.GetEmbedding("the"); emb[0] == 42 {
+			t.Error("Expected the embedding untouched")
+		}
+		if next, _ := loader.GetTransitions("the"); next["changed"] != 0 {
+			t.Error("Expected the transition table untouched")
+		}
+	})
+
+	t.Run("Concurrent Readers", func(t *testing.T) {
+		loader := load(false)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					loader.CorpusFingerprint() // sorts the documents it gets
+					loader.GetDocuments()
+				}
+			}()
+		}
+		for i := 0; i < 20; i++ {
+			loader.AddDocument(fmt.Sprintf("live%d", i), "the cat ran")
+		}
+		wg.Wait()
+		if docs := loader.GetDocuments(); docs[0].Path != paths[0] || len(docs) != 22 {
+			t.Errorf("Expected documents in load order, got %s first of %d", docs[0].Path, len(docs))
+		}
+	})
+}
+
+// TestLanguageQuery tests the word frequency and transition query API
+func TestLanguageQuery(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range []string{"the cat sat on the mat", "the cat ran to the mat", "a dog ran"} {
+		path := filepath.Join(dir, fmt.Sprintf("doc%d.txt", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: paths, MinWordFreq: 1, DisableStarterCorpus: true})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	t.Run("Loader", func(t *testing.T) {
+		if got := loader.GetWordFrequency("The"); got != 4 {
+			t.Errorf("Expected the to occur 4 times, got %d", got)
+		}
+		if got := loader.GetWordFrequency("zebra"); got != 0 {
+			t.Errorf("Expected an unseen word to occur 0 times, got %d", got)
+		}
+		top := loader.GetTopTransitions("the", 0)
+		if len(top) != 2 || top[0] != (WordProbability{"cat", 0.5}) || top[1] != (WordProbability{"mat", 0.5}) {
+			t.Errorf("Expected cat and mat equally likely after the, got %v", top)
+		}
+		if top := loader.GetTopTransitions("the", 1); len(top) != 1 {
+			t.Errorf("Expected k to limit the transitions, got %v", top)
+		}
+		if top := loader.GetTopTransitions("zebra", 5); top != nil {
+			t.Errorf("Expected no transitions for an unseen word, got %v", top)
+		}
+		starters := loader.GetStarterDistribution()
+		if len(starters) != 2 || starters[0].Word != "the" || math.Abs(starters[0].Probability-2.0/3) > 1e-9 || starters[1].Word != "dog" {
+			t.Errorf("Expected the then dog as starters, got %v", starters)
+		}
+	})
+
+	t.Run("Admin Endpoints", func(t *testing.T) {
+		admin := NewAdminHandler(AdminConfig{Token: "secret"}, nil, nil, nil)
+		get := func(path string) (int, map[string]interface{}) {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			admin.ServeHTTP(rec, req)
+			var out map[string]interface{}
+			json.NewDecoder(rec.Body).Decode(&out)
+			return rec.Code, out
+		}
+		if code, _ := get("/admin/words?word=the"); code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 without a dataset, got %d", code)
+		}
+		admin.SetLoader(loader)
+
+		code, out := get("/admin/words?word=The&k=1")
+		transitions, _ := out["transitions"].([]interface{})
+		if code != http.StatusOK || out["frequency"] != 4.0 || out["in_vocabulary"] != true || len(transitions) != 1 {
+			t.Errorf("Unexpected word response %d: %v", code, out)
+		}
+		for _, path := range []string{"/admin/words", "/admin/words?word=the&k=0", "/admin/starters?n=x"} {
+			if code, _ := get(path); code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", path, code)
+			}
+		}
+		code, out = get("/admin/starters?n=1")
+		if starters, _ := out["starters"].([]interface{}); code != http.StatusOK || len(starters) != 1 {
+			t.Errorf("Unexpected starters response %d: %v", code, out)
+		}
+	})
+}
+
+// TestPhraseTransitions tests two-word context transitions with backoff
+func TestPhraseTransitions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.txt")
+	if err := os.WriteFile(path, []byte("i am going home\nwe are going out"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	load := func(weight float64, cacheDir string) *DatasetLoader {
+		loader, err := NewDatasetLoader(TrainingConfig{DatasetPaths: []string{path}, MinWordFreq: 1, DisableStarterCorpus: true, TrigramWeight: weight, CacheDir: cacheDir})
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		return loader
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	t.Run("Mixed Backoff", func(t *testing.T) {
+		loader := load(0.6, "")
+		mixed, ok := loader.GetTransitionsAfter("am", "going")
+		if !ok || !near(mixed["home"], 0.8) || !near(mixed["out"], 0.2) {
+			t.Errorf("Expected the two-word context to favor home, got %v", mixed)
+		}
+		backoff, _ := loader.GetTransitionsAfter("they", "going")
+		if !near(backoff["home"], 0.5) || !near(backoff["out"], 0.5) {
+			t.Errorf("Expected an unseen context to back off to one word, got %v", backoff)
+		}
+		if plain, _ := load(0, "").GetTransitionsAfter("am", "going"); !near(plain["home"], 0.5) {
+			t.Errorf("Expected a zero weight to use one word only, got %v", plain)
+		}
+	})
+
+	t.Run("Incremental And Cached", func(t *testing.T) {
+		cacheDir := filepath.Join(dir, "cache")
+		load(0.6, cacheDir)
+		loader := load(0.6, cacheDir)
+		if loader.tablesPath == "" {
+			t.Fatal("Expected the second load to use the cache")
+		}
+		if mixed, _ := loader.GetTransitionsAfter("am", "going"); !near(mixed["home"], 0.8) {
+			t.Errorf("Expected cached two-word contexts, got %v", mixed)
+		}
+		if _, err := loader.AddDocument("live", "we are going home"); err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+		// "are going" now continues with home and out alike, while
+		// "going" alone continues with home two times in three
+		mixed, _ := loader.GetTransitionsAfter("are", "going")
+		if !near(mixed["home"], 0.6*0.5+0.4*2.0/3) {
+			t.Errorf("Expected the added context to be mixed in, got %v", mixed)
+		}
+	})
+}
+
+// TestResponseCache tests caching responses in front of generation
+func TestResponseCache(t *testing.T) {
+	config := ResponseCacheConfig{Enabled: true, Capacity: 2, TTLSeconds: 60}
+
+	t.Run("Keys", func(t *testing.T) {
+		if responseCacheKey("Hello   there!", GenerationOptions{}, nil) != responseCacheKey("hello there", GenerationOptions{}, nil) {
+			t.Error("Expected inputs differing in case, spacing and punctuation to share a key")
+		}
+		base := responseCacheKey("hello", GenerationOptions{}, nil)
+		seed := int64(7)
+		session := &Session{Generator: GeneratorState{ContextWindow: []string{"cats"}}}
+		for name, key := range map[string]string{
+			"options": responseCacheKey("hello", GenerationOptions{Seed: &seed}, nil),
+			"session": responseCacheKey("hello", GenerationOptions{}, session),
+		} {
+			if key == base {
+				t.Errorf("Expected different %s to change the key", name)
+			}
+		}
+	})
+
+	t.Run("LRU, TTL And Invalidation", func(t *testing.T) {
+		if NewResponseCache(ResponseCacheConfig{}, RealClock) != nil {
+			t.Error("Expected a disabled cache to be nil")
+		}
+		clock := NewVirtualClock(time.Unix(0, 0))
+		cache := NewResponseCache(config, clock)
+		cache.Put("a", CachedResponse{Output: "A"})
+		cache.Put("b", CachedResponse{Output: "B"})
+		cache.Get("a")
+		cache.Put("c", CachedResponse{Output: "C"})
+		if _, ok := cache.Get("b"); ok {
+			t.Error("Expected the least recently used response to be evicted")
+		}
+		if got, ok := cache.Get("a"); !ok || got.Output != "A" {
+			t.Errorf("Expected a cached response, got %+v", got)
+		}
+
+		clock.Advance(time.Minute)
+		if _, ok := cache.Get("a"); ok {
+			t.Error("Expected the response to expire after the TTL")
+		}
+		cache.Put("d", CachedResponse{Output: "D"})
+		ModelUpdated()
+		if _, ok := cache.Get("d"); ok {
+			t.Error("Expected a model update to invalidate the response")
+		}
+		if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 3 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		loader, err := NewDatasetLoader(DefaultConfig().Training)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		sessions := NewSessionManager(NewMemorySessionStore(), time.Hour)
+		handler := NewSessionHandler(sessions, NewResponseGenerator(loader))
+		cache := NewResponseCache(config, RealClock)
+		handler.SetResponseCache(cache)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		ask := func() (*Session, string) {
+			session, _ := sessions.Create()
+			resp, err := http.Post(server.URL+"/v1/sessions/"+session.ID+"/messages", "application/json", strings.NewReader(`{"content":"do cats purr?","seed":3}`))
+			if err != nil {
+				t.Fatalf("Message failed: %v", err)
+			}
+			var msg MessageResponse
+			json.NewDecoder(resp.Body).Decode(&msg)
+			resp.Body.Close()
+			stored, _ := sessions.Get(session.ID)
+			return stored, msg.Message.Content
+		}
+		first, reply := ask()
+		second, cached := ask()
+		if stats := cache.Stats(); stats.Hits != 1 || cached != reply {
+			t.Errorf("Expected a fresh session asking again to hit the cache, got %+v, %q vs %q", stats, cached, reply)
+		}
+		if fmt.Sprint(second.Generator) != fmt.Sprint(first.Generator) || len(second.History) != 2 {
+			t.Errorf("Expected a hit to advance the session like generation, got %+v", second)
+		}
+	})
+
+	t.Run("Think", func(t *testing.T) {
+		brainConfig := DefaultConfig()
+		brainConfig.Resources.MaxNeurons = 1000
+		brainConfig.Resources.MaxGoroutines = 50
+		brainConfig.ResponseCache = config
+		brain := NewLiquidStateBrainWithConfig(4, brainConfig)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		response, _, _ := brain.ThinkWithOptions("hello there", GenerationOptions{})
+		cached, _, energy := brain.ThinkWithOptions("Hello there!", GenerationOptions{})
+		if cached != response || energy != (EnergyReport{}) {
+			t.Errorf("Expected a cached response costing no work, got %q vs %q, %+v", cached, response, energy)
+		}
+	})
+}
+
+// TestWaveFrames tests configurable rendering of reservoir wave frames
+func TestWaveFrames(t *testing.T) {
+	config := DefaultConfig()
+	config.Resources.MaxNeurons = 1000
+	config.Resources.MaxGoroutines = 50
+	brain := NewLiquidStateBrainWithConfig(6, config)
+	if brain == nil {
+		t.Fatal("Failed to create brain")
+	}
+	defer brain.Cleanup()
+	brain.Pause()
+	dims := brain.dimensions
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				brain.reservoir[x][y][z].state.Store(0.0)
+			}
+		}
+	}
+	brain.reservoir[1][4][2].state.Store(0.9)
+	brain.reservoir[0][0][0].state.Store(0.5)
+
+	t.Run("Top View Aggregates Layers", func(t *testing.T) {
+		frame, err := brain.RenderWaveFrame(VisualizationConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Width != dims.X || frame.Height != dims.Y {
+			t.Fatalf("Expected %dx%d frame, got %dx%d", dims.X, dims.Y, frame.Width, frame.Height)
+		}
+		if frame.Intensity[4][1] != 0.9 || frame.Intensity[0][0] != 0.5 {
+			t.Errorf("Unexpected intensities: %v", frame.Intensity)
+		}
+		if got := []rune(frame.Rows[4])[1]; got != '●' {
+			t.Errorf("Expected ● for a strong cell, got %q", got)
+		}
+	})
+
+	t.Run("Plane Slices", func(t *testing.T) {
+		frame, err := brain.RenderWaveFrame(VisualizationConfig{Axis: "z", Plane: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Intensity[4][1] != 0 || frame.Intensity[0][0] != 0.5 {
+			t.Errorf("Plane 0 should only show layer 0: %v", frame.Intensity)
+		}
+		side, err := brain.RenderWaveFrame(VisualizationConfig{Axis: "x", Plane: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Looking along x, columns are y and rows are z
+		if side.Intensity[2][4] != 0.9 {
+			t.Errorf("Expected the neuron at y=4 z=2 in the x=1 plane: %v", side.Intensity)
+		}
+		if _, err := brain.RenderWaveFrame(VisualizationConfig{Axis: "z", Plane: dims.Z}); err == nil {
+			t.Error("Expected an error for a plane outside the reservoir")
+		}
+		if _, err := brain.RenderWaveFrame(VisualizationConfig{Axis: "w"}); err == nil {
+			t.Error("Expected an error for an unknown axis")
+		}
+	})
+
+	t.Run("Resolution And Charset", func(t *testing.T) {
+		frame, err := brain.RenderWaveFrame(VisualizationConfig{Axis: "z", Plane: -1, Width: 2, Height: 2, Charset: ".#"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Width != 2 || frame.Height != 2 {
+			t.Fatalf("Expected 2x2 frame, got %dx%d", frame.Width, frame.Height)
+		}
+		if frame.Intensity[1][0] != 0.9 {
+			t.Errorf("Downsampled cell should keep its strongest neuron: %v", frame.Intensity)
+		}
+		if frame.String() != "#.\n#." {
+			t.Errorf("Unexpected frame text %q", frame.String())
+		}
+		colored, _ := brain.RenderWaveFrame(VisualizationConfig{Axis: "z", Plane: -1, Color: true})
+		if !strings.Contains(colored.String(), "\033[") {
+			t.Error("Expected ANSI colors when color is on")
+		}
+	})
+
+	t.Run("Origins", func(t *testing.T) {
+		brain.waveOrigins.record([3]int{3, 1, 0}, brain.clock.Now())
+		frame, _ := brain.RenderWaveFrame(VisualizationConfig{})
+		if len(frame.Origins) != 1 || frame.Origins[0] != [2]int{3, 1} {
+			t.Errorf("Expected origin at [3 1], got %v", frame.Origins)
+		}
+		if got := []rune(frame.Rows[1])[3]; got != '✦' {
+			t.Errorf("Expected origin marker, got %q", got)
+		}
+	})
+
+	t.Run("Admin Route", func(t *testing.T) {
+		admin := NewAdminHandler(AdminConfig{}, nil, brain, nil)
+		req := httptest.NewRequest("GET", "/admin/waves?axis=y&plane=2&width=3", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		var frame WaveFrame
+		if err := json.NewDecoder(rec.Body).Decode(&frame); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("Expected a frame, got %d: %v", rec.Code, err)
+		}
+		if frame.Axis != "y" || frame.Plane != 2 || frame.Width != 3 || len(frame.Rows) != dims.Z {
+			t.Errorf("Unexpected frame %+v", frame)
+		}
+		req = httptest.NewRequest("GET", "/admin/waves?plane=99", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec = httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a bad plane, got %d", rec.Code)
+		}
+	})
+}
+
+// TestStageDeadlines tests per-stage deadlines and completion in Understand
+func TestStageDeadlines(t *testing.T) {
+	config := DefaultConfig()
+	config.Model.MaxConcepts = 100
+	config.Resources.ChannelBufferSize = 10
+	llm := NewTransparentLLMWithConfig(config)
+	if llm == nil {
+		t.Fatal("Failed to create TransparentLLM")
+	}
+	defer llm.Cleanup()
+
+	collect := func(stream <-chan ThoughtTrace) []ThoughtTrace {
+		var thoughts []ThoughtTrace
+		for thought := range stream {
+			thoughts = append(thoughts, thought)
+		}
+		return thoughts
+	}
+
+	t.Run("Completes", func(t *testing.T) {
+		response, explanation, stream := llm.UnderstandContext(context.Background(), "hello world", GenerationOptions{})
+		thoughts := collect(stream)
+		if response == "" || len(thoughts) == 0 {
+			t.Fatal("Expected a response and thoughts")
+		}
+		if last := thoughts[len(thoughts)-1]; last.stage != "COMPLETE" {
+			t.Errorf("Expected the stream to end with COMPLETE, got %s", last.stage)
+		}
+		if len(explanation.Timeouts) != 0 {
+			t.Errorf("Expected no timeouts, got %v", explanation.Timeouts)
+		}
+	})
+
+	t.Run("Expired Context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		response, explanation, stream := llm.UnderstandContext(ctx, "hello world", GenerationOptions{})
+		thoughts := collect(stream)
+		if response == "" {
+			t.Error("Expected a simple response when generation has no time")
+		}
+		want := []string{stageParsing, stageCircuitSearch, stageGeneration}
+		if strings.Join(explanation.Timeouts, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected timeouts %v, got %v", want, explanation.Timeouts)
+		}
+		reported := 0
+		for _, thought := range thoughts {
+			if thought.stage == "TIMEOUT" {
+				reported++
+			}
+		}
+		if reported != len(want) {
+			t.Errorf("Expected %d TIMEOUT thoughts, got %d", len(want), reported)
+		}
+		last := thoughts[len(thoughts)-1]
+		if last.stage != "COMPLETE" || !strings.Contains(last.insight, "timed out") {
+			t.Errorf("Expected a COMPLETE thought noting the timeouts, got %+v", last)
+		}
+	})
+
+	t.Run("Deadline Bounds Waiting", func(t *testing.T) {
+		if d := untilDeadline(context.Background(), time.Second); d != time.Second {
+			t.Errorf("Expected the stage limit without a deadline, got %v", d)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if d := untilDeadline(ctx, time.Second); d > 10*time.Millisecond {
+			t.Errorf("Expected the caller's deadline to shorten the wait, got %v", d)
+		}
+		if (StageTimeoutConfig{ParsingMS: -1}).validate() == nil {
+			t.Error("Expected negative stage timeouts to be rejected")
+		}
+	})
+}
+
+// TestChannelBackpressure tests overflow policies and drop accounting
+func TestChannelBackpressure(t *testing.T) {
+	fill := func() chan int {
+		ch := make(chan int, 2)
+		ch <- 1
+		ch <- 2
+		return ch
+	}
+	drain := func(ch chan int) []int {
+		close(ch)
+		var got []int
+		for v := range ch {
+			got = append(got, v)
+		}
+		return got
+	}
+
+	t.Run("Policies", func(t *testing.T) {
+		var counter ChannelCounter
+		var discarded []int
+		discard := func(v int) { discarded = append(discarded, v) }
+
+		ch := fill()
+		if offer(ch, 3, ChannelPolicy{Policy: policyDropNewest}, &counter, discard) {
+			t.Error("drop_newest should refuse a send on a full channel")
+		}
+		if got := drain(ch); len(got) != 2 || got[1] != 2 {
+			t.Errorf("drop_newest should keep the queue, got %v", got)
+		}
+
+		ch = fill()
+		if !offer(ch, 3, ChannelPolicy{Policy: policyDropOldest}, &counter, discard) {
+			t.Error("drop_oldest should make room for the send")
+		}
+		if got := drain(ch); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+			t.Errorf("drop_oldest should discard the oldest item, got %v", got)
+		}
+
+		ch = fill()
+		start := time.Now()
+		if offer(ch, 3, ChannelPolicy{Policy: policyBlock, TimeoutMS: 20}, &counter, discard) {
+			t.Error("block should drop once its deadline passes")
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("block returned after %v, before its deadline", elapsed)
+		}
+		ch = fill()
+		go func() {
+			time.Sleep(5 * time.Millisecond)
+			<-ch
+		}()
+		if !offer(ch, 3, ChannelPolicy{Policy: policyBlock, TimeoutMS: 1000}, &counter, discard) {
+			t.Error("block should deliver once the receiver catches up")
+		}
+
+		stats := counter.Stats(ChannelPolicy{Policy: policyBlock})
+		if stats.Sent != 2 || stats.Dropped != 3 {
+			t.Errorf("Expected 2 sent and 3 dropped, got %+v", stats)
+		}
+		if len(discarded) != 3 || discarded[0] != 3 || discarded[1] != 1 || discarded[2] != 3 {
+			t.Errorf("Expected the dropped items to be discarded, got %v", discarded)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if (BackpressureConfig{Pulses: ChannelPolicy{Policy: "spill"}}).validate() == nil {
+			t.Error("Expected an unknown policy to be rejected")
+		}
+		if (BackpressureConfig{Thoughts: ChannelPolicy{Policy: policyBlock}}).validate() == nil {
+			t.Error("Expected block without a timeout to be rejected")
+		}
+		if got := (BackpressureConfig{}).orDefault(); got != defaultBackpressure {
+			t.Errorf("Expected the default policies, got %+v", got)
+		}
+	})
+
+	t.Run("Understand Accounting", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.MaxConcepts = 100
+		config.Resources.ChannelBufferSize = 10
+		llm := NewTransparentLLMWithConfig(config)
+		if llm == nil {
+			t.Fatal("Failed to create TransparentLLM")
+		}
+		defer llm.Cleanup()
+		_, _, stream := llm.UnderstandContext(context.Background(), "hello world", GenerationOptions{})
+		received := 0
+		for range stream {
+			received++
+		}
+		stats := llm.ChannelStats()
+		if stats["thoughts"].Sent != int64(received) || stats["visualization"].Sent != int64(received) {
+			t.Errorf("Expected %d thoughts counted on both channels, got %+v", received, stats)
+		}
+		if stats["thoughts"].Dropped != 0 || stats["pulses"].Policy != policyDropNewest {
+			t.Errorf("Unexpected channel stats %+v", stats)
+		}
+
+		admin := NewAdminHandler(AdminConfig{}, nil, nil, nil)
+		admin.SetLLM(llm)
+		req := httptest.NewRequest("GET", "/admin/channels", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		var out map[string]map[string]ChannelStats
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil || out["concepts"]["thoughts"].Sent != int64(received) {
+			t.Errorf("Expected channel stats from the admin API, got %v (%v)", out, err)
+		}
+	})
+}
+
+// TestFlowDecisionStore tests persisting and replaying parallel orchestrator runs
+func TestFlowDecisionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flows.jsonl")
+
+	t.Run("Persists Runs With Flow Paths", func(t *testing.T) {
+		store, err := OpenDecisionStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		po := NewParallelOrchestrator(50)
+		po.SetDecisionStore(store)
+		first := po.ProcessInParallel("analyze this")
+		po.ProcessInParallel("create that")
+		if err := po.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		runs, err := LoadFlowRuns(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 2 || runs[0].Input != "analyze this" || runs[0].Consensus != first {
+			t.Fatalf("Expected both runs persisted, got %d", len(runs))
+		}
+		run := runs[0]
+		if len(run.Thresholds) != 50 || len(run.Injected) == 0 || len(run.Decisions) == 0 {
+			t.Fatalf("Run is missing replay data: %+v", run)
+		}
+		if run.Pattern.ActiveNeurons != len(run.Decisions) || len(run.Pattern.FlowPaths) != len(run.Decisions) {
+			t.Fatalf("Expected a flow path per decision, got %+v", run.Pattern)
+		}
+		injected := make(map[int]bool)
+		for _, idx := range run.Injected {
+			injected[idx] = true
+		}
+		for i, flow := range run.Pattern.FlowPaths {
+			if !injected[flow[0]] || flow[len(flow)-1] != run.Decisions[i].NeuronID {
+				t.Errorf("Flow path %v should run from an injection point to neuron %d", flow, run.Decisions[i].NeuronID)
+			}
+			for j := 1; j < len(flow); j++ {
+				linked := false
+				for _, target := range run.Connections[flow[j-1]] {
+					linked = linked || target == flow[j]
+				}
+				if !linked {
+					t.Errorf("Flow path %v follows a missing connection %d->%d", flow, flow[j-1], flow[j])
+				}
+			}
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		// Without connections every decision is deterministic
+		run := &FlowRun{
+			Input:        "plan a trip",
+			Thresholds:   []float64{0.5, 0.5, 0.5, 0.5},
+			Capabilities: []string{"gpt_caller", "claude_caller", "tool_caller", "tool_caller"},
+			Connections:  [][]int{{}, {}, {}, {}},
+			Activations:  []float64{0, 0, 0, 0},
+			Injected:     []int{0, 2},
+		}
+		po, err := newOrchestratorFromRun(run)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded := po.process(run.Input, run.Injected, nil)
+		if len(recorded.Decisions) != 2 || !strings.HasPrefix(recorded.Consensus, "CONSENSUS: GPT-4[neuron_0]") {
+			t.Fatalf("Unexpected run %+v", recorded)
+		}
+		data, err := json.Marshal(recorded)
+		if err != nil || !strings.Contains(string(data), `"kind":"flow_run"`) {
+			t.Fatalf("Expected a flow_run trace object, got %s (%v)", data, err)
+		}
+		var decoded FlowRun
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := ReplayFlowRun(&decoded)
+		if err != nil || !report.Matched() {
+			t.Fatalf("Expected the replay to match, got %+v (%v)", report, err)
+		}
+		decoded.Thresholds[2] = 1.5
+		decoded.Capabilities[0] = "tool_caller"
+		report, _ = ReplayFlowRun(&decoded)
+		if report.Matched() || len(report.Missing) != 1 || report.Missing[0] != 2 || len(report.Changed) != 1 || report.Changed[0] != 0 {
+			t.Errorf("Expected neuron 2 missing and neuron 0 changed, got %+v", report)
+		}
+		decoded.Connections[1] = []int{9}
+		if _, err := ReplayFlowRun(&decoded); err == nil {
+			t.Error("Expected an error for a connection to an unknown neuron")
+		}
+	})
+}
+
+// TestConsensusStrategies tests combining parallel decisions
+func TestConsensusStrategies(t *testing.T) {
+	capabilities := map[int]string{0: "gpt_caller", 1: "tool_caller", 2: "tool_caller", 3: "claude_caller"}
+	capability := func(id int) string { return capabilities[id] }
+	decisions := []FlowDecision{
+		{NeuronID: 0, Decision: "gpt", Confidence: 0.9},
+		{NeuronID: 1, Decision: "tools a", Confidence: 0.6},
+		{NeuronID: 2, Decision: "tools b", Confidence: 0.7},
+		{NeuronID: 3, Decision: "claude", Confidence: 0.3},
+	}
+
+	t.Run("Highest", func(t *testing.T) {
+		result := consensusOf(decisions, capability, ConsensusConfig{})
+		if result.Strategy != consensusHighest || result.Decision != "gpt" || !result.Reached {
+			t.Errorf("Expected the most confident decision, got %+v", result)
+		}
+		if result.Summary != "CONSENSUS: gpt (confidence: 0.90 from 4 parallel decisions)" {
+			t.Errorf("Unexpected summary %q", result.Summary)
+		}
+		if math.Abs(result.Tallies["tool_caller"]-1.3) > 1e-9 {
+			t.Errorf("Expected tallies to be reported, got %v", result.Tallies)
+		}
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		result := consensusOf(decisions, capability, ConsensusConfig{Strategy: consensusWeighted})
+		if result.Capability != "tool_caller" || result.Decision != "tools b" || result.TieBrokenBy != "" {
+			t.Errorf("Expected the tool callers' best decision, got %+v", result)
+		}
+		if math.Abs(result.Share-1.3/2.5) > 1e-9 {
+			t.Errorf("Expected a %.2f share, got %.2f", 1.3/2.5, result.Share)
+		}
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		result := consensusOf(decisions, capability, ConsensusConfig{Strategy: consensusQuorum, Quorum: 0.6})
+		if result.Reached || result.Decision != "" || !strings.Contains(result.Summary, "quorum is 60%") {
+			t.Errorf("Expected no consensus below quorum, got %+v", result)
+		}
+		result = consensusOf(decisions, capability, ConsensusConfig{Strategy: consensusQuorum, Quorum: 0.5})
+		if !result.Reached || result.Capability != "tool_caller" {
+			t.Errorf("Expected consensus at quorum, got %+v", result)
+		}
+	})
+
+	t.Run("Ties", func(t *testing.T) {
+		tied := []FlowDecision{
+			{NeuronID: 0, Decision: "gpt", Confidence: 0.6},
+			{NeuronID: 1, Decision: "tools", Confidence: 0.5},
+			{NeuronID: 2, Decision: "tools", Confidence: 0.1},
+		}
+		result := consensusOf(tied, capability, ConsensusConfig{Strategy: consensusWeighted, Arbiter: "tool_caller"})
+		if result.Capability != "tool_caller" || result.TieBrokenBy != "arbiter" {
+			t.Errorf("Expected the arbiter to win the tie, got %+v", result)
+		}
+		result = consensusOf(tied, capability, ConsensusConfig{Strategy: consensusWeighted, Arbiter: "claude_caller"})
+		if result.Capability != "gpt_caller" || result.TieBrokenBy != "confidence" {
+			t.Errorf("Expected confidence to break the tie, got %+v", result)
+		}
+	})
+
+	t.Run("Config And Replay", func(t *testing.T) {
+		if (ConsensusConfig{Strategy: consensusQuorum}).validate() == nil {
+			t.Error("Expected quorum without a share to be rejected")
+		}
+		if (ConsensusConfig{Strategy: "plurality"}).validate() == nil {
+			t.Error("Expected an unknown strategy to be rejected")
+		}
+		if empty := consensusOf(nil, capability, ConsensusConfig{}); empty.Reached {
+			t.Error("Expected no consensus without decisions")
+		}
+
+		run := &FlowRun{
+			Input:        "plan",
+			Thresholds:   []float64{0.5, 0.5, 0.5},
+			Capabilities: []string{"gpt_caller", "tool_caller", "tool_caller"},
+			Connections:  [][]int{{}, {}, {}},
+			Activations:  []float64{0, 0, 0},
+			Injected:     []int{0, 1, 2},
+			Result:       ConsensusResult{Strategy: consensusWeighted},
+		}
+		po, err := newOrchestratorFromRun(run)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded := po.process(run.Input, run.Injected, nil)
+		if recorded.Result.Strategy != consensusWeighted || recorded.Result.Capability != "tool_caller" {
+			t.Fatalf("Expected the recorded strategy to be used, got %+v", recorded.Result)
+		}
+		if report, err := ReplayFlowRun(recorded); err != nil || !report.Matched() {
+			t.Errorf("Expected the weighted run to replay, got %+v (%v)", report, err)
+		}
+	})
+}
+
+// TestCapabilityRouting tests routing orchestrator activation by input
+func TestCapabilityRouting(t *testing.T) {
+	po := NewParallelOrchestrator(100)
+	capabilityGain := func(gains []float64, capability string) float64 {
+		for i, n := range po.neurons {
+			if n.capability == capability {
+				return gains[i]
+			}
+		}
+		t.Fatalf("No %s neuron", capability)
+		return 0
+	}
+
+	t.Run("Gains", func(t *testing.T) {
+		if po.routingGains("write a poem") != nil {
+			t.Error("Expected no gains with routing off")
+		}
+		po.SetCapabilityRouting(CapabilityRoutingConfig{Enabled: true, Strength: 0.8})
+		gains := po.routingGains("write a poem")
+		if math.Abs(capabilityGain(gains, "claude_caller")-1) > 1e-9 {
+			t.Errorf("Expected creative neurons to match a poem best, got %v", capabilityGain(gains, "claude_caller"))
+		}
+		if g := capabilityGain(gains, "tool_caller"); g >= 1 || g < 0.2-1e-9 {
+			t.Errorf("Expected tool neurons' gain in [0.2, 1), got %v", g)
+		}
+		if gains := po.routingGains("analyze the data"); math.Abs(capabilityGain(gains, "tool_caller")-1) > 1e-9 {
+			t.Error("Expected tool neurons to match data analysis best")
+		}
+	})
+
+	t.Run("Full Strength Skips Unrelated Neurons", func(t *testing.T) {
+		po.SetCapabilityRouting(CapabilityRoutingConfig{Enabled: true, Strength: 1})
+		po.SetConsensus(ConsensusConfig{Strategy: consensusWeighted})
+		for _, n := range po.neurons {
+			n.activation.Store(0.0)
+		}
+		result := po.Deliberate("write a poem")
+		if !result.Reached || result.Capability != "claude_caller" {
+			t.Errorf("Expected a creative consensus, got %+v", result)
+		}
+		if result.Tallies["tool_caller"] > 0 {
+			t.Errorf("Expected unrelated tool neurons not to decide, got %.2f", result.Tallies["tool_caller"])
+		}
+		if result.Tallies["claude_caller"] <= result.Tallies["gpt_caller"] {
+			t.Errorf("Expected creative neurons to dominate, got %v", result.Tallies)
+		}
+	})
+
+	t.Run("Custom Embedder And Replay", func(t *testing.T) {
+		po.SetEmbedder(func(text string) ([]float64, bool) {
+			if strings.Contains(text, "analyze") || strings.Contains(text, "numbers") {
+				return []float64{1, 0}, true
+			}
+			return []float64{0, 1}, true
+		})
+		po.SetCapabilityRouting(CapabilityRoutingConfig{Enabled: true, Strength: 1})
+		gains := po.routingGains("crunch numbers")
+		if math.Abs(capabilityGain(gains, "tool_caller")-1) > 1e-9 || capabilityGain(gains, "claude_caller") != 0 {
+			t.Errorf("Expected the custom embedder to route to tools, got %v", gains[:5])
+		}
+		for _, n := range po.neurons {
+			n.activation.Store(0.0)
+		}
+		run := po.process("crunch numbers", po.injectionPoints(gains), gains)
+		data, _ := json.Marshal(run)
+		var decoded FlowRun
+		if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Gains) != len(po.neurons) {
+			t.Fatalf("Expected gains to be recorded, got %d (%v)", len(decoded.Gains), err)
+		}
+		if (CapabilityRoutingConfig{Enabled: true}).validate() == nil {
+			t.Error("Expected routing without strength to be rejected")
+		}
+	})
+}
+
+// TestOrchestrationPlan tests planning orchestrations without calling
+// capabilities
+func TestOrchestrationPlan(t *testing.T) {
+	orchestrator := NewGenesisOrchestrator(4)
+	if orchestrator.liquidBrain == nil {
+		t.Fatal("Failed to create orchestrator")
+	}
+	defer orchestrator.liquidBrain.Cleanup()
+
+	calls := 0
+	counted := func(ctx context.Context, input string) (string, error) {
+		calls++
+		return "answer", nil
+	}
+	orchestrator.RegisterCapability("claude", counted)
+	orchestrator.RegisterCapability("gpt4", counted)
+	orchestrator.SetCapabilityEstimate("claude", CapabilityEstimate{CostPer1KTokens: 0.02, LatencyMS: 1500, OutputTokens: 96})
+
+	t.Run("Plan", func(t *testing.T) {
+		plan := orchestrator.Plan("write a creative story about robots")
+		if len(plan.Steps) != 1 || plan.Steps[0].Capability != "claude" || plan.Local {
+			t.Fatalf("Expected a plan calling claude, got %+v", plan)
+		}
+		if plan.EstimatedTokens != 102 || math.Abs(plan.EstimatedCost-0.00204) > 1e-12 || plan.EstimatedLatencyMS != 1500 {
+			t.Errorf("Expected 102 tokens, $0.00204 and 1500ms, got %+v", plan)
+		}
+		if calls != 0 {
+			t.Errorf("Planning should call no capability, made %d calls", calls)
+		}
+	})
+
+	t.Run("Fallback Chain", func(t *testing.T) {
+		orchestrator.SetEscalationThreshold(2)
+		orchestrator.SetFallbackChain("gpt4", "missing", "clarify")
+		defer orchestrator.SetEscalationThreshold(defaultEscalationThreshold)
+
+		plan := orchestrator.Plan("explain quantum computing")
+		if len(plan.Steps) != 2 || plan.Steps[0].Capability != "gpt4" || plan.Steps[1].Capability != "clarify" {
+			t.Fatalf("Expected registered fallback hops, got %+v", plan.Steps)
+		}
+		if plan.Steps[0].Conditional || !plan.Steps[1].Conditional {
+			t.Errorf("Only later hops should be conditional, got %+v", plan.Steps)
+		}
+		if clarify := plan.Steps[1]; clarify.EstimatedCost != 0 || clarify.EstimatedLatencyMS != 0 || clarify.EstimatedTokens != 3 {
+			t.Errorf("Capabilities without estimates should be free, got %+v", clarify)
+		}
+		if plan.EstimatedTokens != plan.Steps[0].EstimatedTokens+3 || plan.EstimatedCost != plan.Steps[0].EstimatedCost {
+			t.Errorf("Expected the worst case of every hop running, got %+v", plan)
+		}
+
+		orchestrator.SetEscalationThreshold(0)
+		if plan := orchestrator.Plan("explain quantum computing"); !plan.Local || len(plan.Steps) != 0 {
+			t.Errorf("Expected a local answer, got %+v", plan)
+		}
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		orchestrator.SetDryRun(true)
+		output, decisions := orchestrator.Process("write a creative story about robots")
+		orchestrator.SetDryRun(false)
+		if calls != 0 {
+			t.Errorf("A dry run should call no capability, made %d calls", calls)
+		}
+		if !strings.HasPrefix(output, "PLAN: claude") {
+			t.Errorf("Expected the plan as output, got %q", output)
+		}
+		if last := decisions[len(decisions)-1]; last.Path[len(last.Path)-1] != "claude" || last.Output != "" {
+			t.Errorf("Expected an unexecuted claude step, got %+v", last)
+		}
+
+		response, err := orchestrator.Respond(context.Background(), Request{Input: "write a creative story", DryRun: true})
+		if err != nil || response.Plan == nil || response.Output != response.Plan.Summary || calls != 0 {
+			t.Errorf("Expected a dry-run response with its plan, got %+v (%v)", response, err)
+		}
+
+		output, _ = orchestrator.Process("write a creative story about robots")
+		if output != "answer" || calls != 1 {
+			t.Errorf("Expected execution after the dry run, got %q with %d calls", output, calls)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		config := PlanConfig{Capabilities: map[string]CapabilityEstimate{"gpt4": {LatencyMS: -1}}}
+		if config.validate() == nil {
+			t.Error("Expected negative estimates to be rejected")
+		}
+	})
+}
+
+// TestCapabilityPolicies tests restricting the capabilities a caller may
+// trigger
+func TestCapabilityPolicies(t *testing.T) {
+	policies := CapabilityPolicyConfig{
+		Policies: map[string]CapabilityPolicy{
+			"local_only": {Deny: []string{"gpt4", "claude"}},
+			"tools":      {Allow: []string{"calculator", "clarify"}},
+		},
+		APIKeys: map[string]string{"k1": "local_only"},
+		Tenants: map[string]string{"private": "local_only"},
+	}
+	localOnly, err := policies.Policy("local_only")
+	if err != nil {
+		t.Fatalf("Policy failed: %v", err)
+	}
+
+	t.Run("Permits", func(t *testing.T) {
+		var unrestricted *CapabilityPolicy
+		if !unrestricted.Permits("gpt4") {
+			t.Error("A nil policy should permit everything")
+		}
+		if localOnly.Permits("claude") || !localOnly.Permits("calculator") {
+			t.Error("local_only should deny only external LLMs")
+		}
+		tools, _ := policies.Policy("tools")
+		if tools.Permits("database") || !tools.Permits("calculator") {
+			t.Error("An allow list should permit only its capabilities")
+		}
+		if err := policies.validate(); err != nil {
+			t.Errorf("Expected valid policies, got %v", err)
+		}
+		if (CapabilityPolicyConfig{Default: "missing"}).validate() == nil {
+			t.Error("Expected an unknown default policy to be rejected")
+		}
+	})
+
+	t.Run("Orchestrator Routing", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestrator(4)
+		if orchestrator.liquidBrain == nil {
+			t.Fatal("Failed to create orchestrator")
+		}
+		defer orchestrator.liquidBrain.Cleanup()
+		calls := 0
+		counted := func(ctx context.Context, input string) (string, error) {
+			calls++
+			return "external answer", nil
+		}
+		orchestrator.RegisterCapability("claude", counted)
+		orchestrator.RegisterCapability("gpt4", counted)
+
+		orchestrator.SetEscalationThreshold(0)
+		output, decisions := orchestrator.ProcessWithPolicy("write a creative story about robots", localOnly)
+		if calls != 0 || output == "external answer" {
+			t.Errorf("A denied capability should not be called, made %d calls", calls)
+		}
+		denied := false
+		for _, d := range decisions {
+			if d.Policy != "local_only" {
+				t.Errorf("Every decision should record the policy, got %q", d.Policy)
+			}
+			denied = denied || (d.Path[len(d.Path)-1] == "claude" && strings.Contains(d.Reasoning, "denied"))
+		}
+		if !denied {
+			t.Errorf("Expected the denial in the decision trace, got %+v", decisions)
+		}
+		if plan := orchestrator.PlanWithPolicy("write a creative story", localOnly); !plan.Local || len(plan.Denied) != 1 || plan.Policy != "local_only" {
+			t.Errorf("Expected a local plan with claude denied, got %+v", plan)
+		}
+
+		orchestrator.SetEscalationThreshold(2)
+		orchestrator.SetFallbackChain("gpt4", "clarify")
+		response, err := orchestrator.Respond(context.Background(), Request{Input: "explain quantum computing", Policy: localOnly})
+		if err != nil || calls != 0 {
+			t.Fatalf("Expected the fallback chain to skip gpt4, got %d calls (%v)", calls, err)
+		}
+		if hop := response.Decisions[1]; !strings.Contains(hop.Reasoning, "gpt4 denied by policy") {
+			t.Errorf("Expected the denied hop in the trace, got %+v", hop)
+		}
+		if last := response.Decisions[len(response.Decisions)-1]; last.Path[len(last.Path)-1] != "clarify" {
+			t.Errorf("Expected clarify to answer, got %+v", last)
+		}
+
+		if output, _ := orchestrator.Process("write a creative story"); output != "external answer" || calls != 1 {
+			t.Errorf("Unrestricted requests should still reach claude, got %q", output)
+		}
+	})
+
+	t.Run("Tenants", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Model.Type = "orchestrator"
+		config.Fallback.Threshold = 0.0001
+		bm := NewBrainManager(1, 0)
+		defer bm.Close()
+		bm.Register(InstanceSpec{Name: "shared", Config: config, Size: 4})
+		bm.SetCapabilityPolicies(policies)
+
+		if output, err := bm.Process("private", "write a creative story"); err != nil || strings.Contains(output, "Claude response") {
+			t.Errorf("The private tenant should not reach claude, got %q (%v)", output, err)
+		}
+		if output, err := bm.Process("public", "write a creative story"); err != nil || !strings.Contains(output, "Claude response") {
+			t.Errorf("Other tenants should reach claude, got %q (%v)", output, err)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		handler := NewSessionHandler(NewSessionManager(NewMemorySessionStore(), time.Hour), nil)
+		handler.SetCapabilityPolicies(policies)
+		auth := NewAPIAuth(ServerConfig{APIKeys: []APIKeyConfig{{Name: "k1", Key: "secret-1"}, {Name: "k2", Key: "secret-2"}}})
+		create := func(key, body string) (int, Session) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(body))
+			h := http.Handler(handler)
+			if key != "" {
+				req.Header.Set("Authorization", "Bearer "+key)
+				h = auth.Middleware(handler)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			var session Session
+			json.NewDecoder(rec.Body).Decode(&session)
+			return rec.Code, session
+		}
+
+		// Policies are picked by the key's name, never its secret
+		if code, session := create("secret-1", ""); code != http.StatusCreated || session.Policy != "local_only" {
+			t.Errorf("Expected the key's policy, got %d %+v", code, session)
+		}
+		if code, session := create("secret-2", ""); code != http.StatusCreated || session.Policy != "" {
+			t.Errorf("Expected the default for a key without a policy, got %d %+v", code, session)
+		}
+		if code, session := create("", `{"policy":"tools"}`); code != http.StatusCreated || session.Policy != "tools" {
+			t.Errorf("Expected an unrestricted caller to choose a policy, got %d %+v", code, session)
+		}
+		if code, session := create("", ""); code != http.StatusCreated || session.Policy != "" {
+			t.Errorf("Expected the unrestricted default, got %d %+v", code, session)
+		}
+		if code, _ := create("secret-1", `{"policy":"tools"}`); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for escaping the key's policy, got %d", code)
+		}
+		if code, _ := create("unknown", ""); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for an unknown key, got %d", code)
+		}
+		if code, _ := create("", `{"policy":"missing"}`); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown policy, got %d", code)
+		}
+
+		config := DefaultConfig()
+		config.CapabilityPolicies = policies
+		config.Server.APIKeys = []APIKeyConfig{{Name: "k1", Key: "secret-1"}}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected policies keyed by server key names to validate, got %v", err)
+		}
+		config.CapabilityPolicies.APIKeys = map[string]string{"secret-1": "local_only"}
+		if config.Validate() == nil {
+			t.Error("Expected a policy keyed by something other than a key name to be rejected")
+		}
+	})
+}
+
+// TestConversationBench tests the scripted dialogue benchmark
+func TestConversationBench(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dialogues.yaml")
+	os.WriteFile(path, []byte(`name: tiny
+dialogues:
+  - name: greeting
+    turns:
+      - user: hello there
+        references: [hello how are you]
+      - user: what is the cat doing
+        references:
+          - the cat sat on the mat
+`), 0644)
+
+	script, err := LoadDialogueScript(path)
+	if err != nil {
+		t.Fatalf("LoadDialogueScript failed: %v", err)
+	}
+	if len(script.Dialogues) != 1 || len(script.Dialogues[0].Turns) != 2 || script.Dialogues[0].Turns[1].References[0] != "the cat sat on the mat" {
+		t.Fatalf("Unexpected script %+v", script)
+	}
+
+	t.Run("Scoring", func(t *testing.T) {
+		if f1 := unigramF1("the cat sat", "the cat sat on the mat"); math.Abs(f1-2.0/3) > 1e-9 {
+			t.Errorf("Expected F1 2/3, got %v", f1)
+		}
+		if unigramF1("Dogs bark!", "the cat sat") != 0 || unigramF1("", "the cat") != 0 {
+			t.Error("Expected no overlap to score 0")
+		}
+		overlap, similarity := scoreTurn("The cat sat on the mat.", []string{"a dog barked", "the cat sat on the mat"}, hashedEmbedding)
+		if overlap != 1 || math.Abs(similarity-1) > 1e-9 {
+			t.Errorf("Expected a perfect match with the best reference, got %v %v", overlap, similarity)
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		config := DefaultConfig()
+		config.DecisionStore.Path = filepath.Join(dir, "flows.jsonl")
+		report, err := RunConversationBench(config, script, ConversationBenchConfig{Models: []string{"parallel", "missing"}, Size: 20, Seed: 7}, nil, "")
+		if err != nil {
+			t.Fatalf("RunConversationBench failed: %v", err)
+		}
+		if report.Embedding != "hashed" || len(report.Models) != 2 {
+			t.Fatalf("Unexpected report %+v", report)
+		}
+		parallel := report.Models[0]
+		if parallel.Error != "" || len(parallel.Dialogues) != 1 || len(parallel.Dialogues[0].Turns) != 2 || parallel.Errors != 0 {
+			t.Fatalf("Expected both turns answered, got %+v", parallel)
+		}
+		for _, turn := range parallel.Dialogues[0].Turns {
+			if turn.Response == "" || turn.Overlap < 0 || turn.Overlap > 1 {
+				t.Errorf("Unexpected turn %+v", turn)
+			}
+		}
+		if report.Models[1].Error == "" {
+			t.Error("Expected an unknown model type to be reported")
+		}
+		if _, err := os.Stat(config.DecisionStore.Path); !os.IsNotExist(err) {
+			t.Error("A benchmark should not persist flow runs")
+		}
+
+		var out bytes.Buffer
+		report.Print(&out, report)
+		if !strings.Contains(out.String(), "vs baseline: overlap +0.000") {
+			t.Errorf("Expected a comparison with the baseline, got %s", out.String())
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		bad := &DialogueScript{Dialogues: []Dialogue{{Turns: []DialogueTurn{{User: "hi"}}}}}
+		if bad.Validate() == nil {
+			t.Error("Expected a turn without references to be rejected")
+		}
+		if _, err := LoadDialogueScript(filepath.Join(dir, "missing.yaml")); err == nil {
+			t.Error("Expected a missing script to fail")
+		}
+	})
+
+	t.Run("Shipped Script", func(t *testing.T) {
+		if _, err := LoadDialogueScript("benchmarks/dialogues.yaml"); err != nil {
+			t.Errorf("The shipped dialogues should load: %v", err)
+		}
+	})
+}
+
+// TestGoldenTraces tests recording and structurally checking golden traces
+func TestGoldenTraces(t *testing.T) {
+	config := DefaultConfig()
+	golden, err := RecordGoldenTraces(config, []string{"hello", "calculate the square root of 144"}, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to record golden traces: %v", err)
+	}
+
+	t.Run("RecordsStructure", func(t *testing.T) {
+		if golden.Schema != goldenSchema || golden.Seed != 1 || golden.TopConcepts != defaultGoldenTopConcepts {
+			t.Errorf("Unexpected header: %+v", golden)
+		}
+		if len(golden.Traces) != 2 {
+			t.Fatalf("Expected 2 traces, got %d", len(golden.Traces))
+		}
+		for _, trace := range golden.Traces {
+			if len(trace.Stages) == 0 || trace.Stages[0] != "PARSING" || trace.Stages[len(trace.Stages)-1] != "COMPLETE" {
+				t.Errorf("%q: unexpected stages %v", trace.Prompt, trace.Stages)
+			}
+			if len(trace.TopConcepts) > defaultGoldenTopConcepts {
+				t.Errorf("%q: kept %d concepts", trace.Prompt, len(trace.TopConcepts))
+			}
+		}
+		if routing := golden.Traces[1].Routing; !equalStrings(routing, []string{"liquid_brain", "calculator"}) {
+			t.Errorf("Expected the calculator route, got %v", routing)
+		}
+	})
+
+	t.Run("MatchesItself", func(t *testing.T) {
+		if diffs := golden.Compare(golden, 1); len(diffs) != 0 {
+			t.Errorf("Expected no differences, got %v", diffs)
+		}
+	})
+
+	t.Run("ReportsChanges", func(t *testing.T) {
+		changed := &GoldenTraces{Traces: []GoldenTrace{golden.Traces[1]}}
+		changed.Traces[0].Stages = []string{"PARSING", "TIMEOUT", "COMPLETE"}
+		changed.Traces[0].Routing = []string{"liquid_brain", "gpt4"}
+		changed.Traces[0].TopConcepts = []string{"unrelated"}
+
+		fields := make(map[string]bool)
+		for _, d := range golden.Compare(changed, defaultGoldenMinOverlap) {
+			fields[d.Prompt+" "+d.Field] = true
+		}
+		for _, want := range []string{"hello prompt", "calculate the square root of 144 stages", "calculate the square root of 144 routing"} {
+			if !fields[want] {
+				t.Errorf("Expected a %q difference, got %v", want, fields)
+			}
+		}
+		if len(golden.Traces[1].TopConcepts) > 0 && !fields["calculate the square root of 144 top_concepts"] {
+			t.Errorf("Expected a top concepts difference, got %v", fields)
+		}
+	})
+
+	t.Run("ConceptOverlap", func(t *testing.T) {
+		cases := []struct {
+			a, b []string
+			want float64
+		}{
+			{nil, nil, 1},
+			{[]string{"a"}, nil, 0},
+			{[]string{"a", "b"}, []string{"b", "a", "c", "d"}, 1},
+			{[]string{"a", "b", "c", "d"}, []string{"a", "x"}, 0.5},
+		}
+		for _, c := range cases {
+			if got := conceptOverlap(c.a, c.b); got != c.want {
+				t.Errorf("conceptOverlap(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+			}
+		}
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "golden.json")
+		if err := golden.Save(path); err != nil {
+			t.Fatalf("Failed to save: %v", err)
+		}
+		loaded, err := LoadGoldenTraces(path)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if diffs := golden.Compare(loaded, 1); len(diffs) != 0 {
+			t.Errorf("Round trip changed traces: %v", diffs)
+		}
+
+		os.WriteFile(path, []byte(`{"schema": "other/v1"}`), 0644)
+		if _, err := LoadGoldenTraces(path); err == nil {
+			t.Error("Expected an unknown schema to be rejected")
+		}
+	})
+
+	t.Run("ShippedGoldenMatches", func(t *testing.T) {
+		shipped, err := LoadGoldenTraces("benchmarks/golden_traces.json")
+		if err != nil {
+			t.Fatalf("Failed to load shipped golden traces: %v", err)
+		}
+		config, err := LoadConfig("config.json")
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		diffs, err := CheckGoldenTraces(config, shipped, defaultGoldenMinOverlap)
+		if err != nil {
+			t.Fatalf("Failed to check golden traces: %v", err)
+		}
+		for _, d := range diffs {
+			t.Errorf("Reasoning changed: %s", d)
+		}
+	})
+}
+
+// TestInputNormalization tests hardening of the public input paths
+func TestInputNormalization(t *testing.T) {
+	t.Run("Normalizes", func(t *testing.T) {
+		cases := []struct{ input, want string }{
+			{"  hello\tworld \n", "hello world"},
+			{"he\u200bllo\u200d there\ufeff", "hello there"},
+			{"bell\x07 and\x00 null", "bell and null"},
+			{"bad \xff\xfe bytes", "bad bytes"},
+			{"\u202eevil\u202c text", "evil text"},
+			{"go go go go go Go stop", "go go go stop"},
+			{"\x00\u200b\t", ""},
+		}
+		for _, c := range cases {
+			if got := NormalizeInput(c.input); got != c.want {
+				t.Errorf("NormalizeInput(%q) = %q, want %q", c.input, got, c.want)
+			}
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		long := NormalizeInput(strings.Repeat("word"+strings.Repeat("x", 100)+" ", 100000))
+		if n := utf8.RuneCountInString(long); n > maxInputRunes {
+			t.Errorf("Expected at most %d runes, got %d", maxInputRunes, n)
+		}
+		for _, word := range strings.Fields(long) {
+			if n := utf8.RuneCountInString(word); n > maxInputWordRunes {
+				t.Fatalf("Expected words of at most %d runes, got %d", maxInputWordRunes, n)
+			}
+		}
+		if got := strings.Count(NormalizeInput(strings.Repeat("spam ", 1000)), "spam"); got != maxTokenRepeats {
+			t.Errorf("Expected %d repeats kept, got %d", maxTokenRepeats, got)
+		}
+	})
+
+	t.Run("RecoversPanics", func(t *testing.T) {
+		fails := func() (err error) {
+			defer recoverError(&err, "test")
+			var m map[string]int
+			m["x"]++
+			return nil
+		}
+		if err := fails(); err == nil || !strings.Contains(err.Error(), "test panicked") {
+			t.Errorf("Expected the panic as an error, got %v", err)
+		}
+	})
+
+	t.Run("PathologicalInputs", func(t *testing.T) {
+		llm := NewTransparentLLMWithConfig(DefaultConfig())
+		defer llm.Cleanup()
+		brain := NewLiquidStateBrainWithConfig(20, DefaultConfig())
+		if brain == nil {
+			t.Fatal("Failed to create liquid brain")
+		}
+		defer brain.Cleanup()
+
+		inputs := []string{
+			"",
+			strings.Repeat("a", 1<<20),
+			strings.Repeat("the ", 50000),
+			"\x00\x01\x1b[31m\u200b\u200c\u200d\ufeff",
+			"\xff\xfe\xfd",
+			strings.Repeat("🙂\u200d", 5000),
+		}
+		for _, input := range inputs {
+			response, err := llm.Respond(context.Background(), Request{Input: input})
+			if err != nil {
+				t.Errorf("Transparent LLM failed on %.20q: %v", input, err)
+			}
+			if !utf8.ValidString(response.Output) {
+				t.Errorf("Invalid UTF-8 response to %.20q", input)
+			}
+			if _, err := brain.Respond(context.Background(), Request{Input: input}); err != nil {
+				t.Errorf("Liquid brain failed on %.20q: %v", input, err)
+			}
+		}
+	})
+}
+
+// FuzzTokenize fuzzes input normalization and the corpus tokenizer
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{"hello world", "what is 2+2?", "\x00\u200b\xff", "don't (stop) \"now\"", strings.Repeat("ab ", 100)} {
+		f.Add(seed)
+	}
+	loader := &DatasetLoader{}
+	f.Fuzz(func(t *testing.T, input string) {
+		normalized := NormalizeInput(input)
+		if !utf8.ValidString(normalized) {
+			t.Fatalf("Invalid UTF-8 after normalizing %q", input)
+		}
+		if n := utf8.RuneCountInString(normalized); n > maxInputRunes {
+			t.Fatalf("Normalized %d runes, over the %d limit", n, maxInputRunes)
+		}
+		for _, r := range normalized {
+			if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || (unicode.IsSpace(r) && r != ' ') {
+				t.Fatalf("Normalized %q kept %U", input, r)
+			}
+		}
+		if NormalizeInput(normalized) != normalized {
+			t.Fatalf("Normalizing %q isn't idempotent", input)
+		}
+		for _, token := range loader.tokenize(input) {
+			if token == "" || strings.ContainsAny(token, " \t\n") {
+				t.Fatalf("Tokenizing %q gave token %q", input, token)
+			}
+		}
+	})
+}
+
+// FuzzGenerate fuzzes the response generator with arbitrary input
+func FuzzGenerate(f *testing.F) {
+	dir := f.TempDir()
+	os.WriteFile(dir+"/corpus.txt", []byte("the cat sat on the mat. the dog ran in the park. what is the answer? the answer is four."), 0644)
+	loader, err := NewDatasetLoader(TrainingConfig{
+		DatasetPaths: []string{dir},
+		MaxVocabSize: 1000,
+		EmbeddingDim: 16,
+		MinWordFreq:  1,
+		MaxDocuments: 10,
+	})
+	if err != nil {
+		f.Fatalf("Failed to load: %v", err)
+	}
+	gen := NewResponseGenerator(loader)
+
+	for _, seed := range []string{"the cat", "what is the answer?", "", "\u200b\x00", strings.Repeat("the ", 500)} {
+		f.Add(seed, int64(1))
+	}
+	f.Fuzz(func(t *testing.T, input string, seed int64) {
+		response, explanation := gen.GenerateWithOptions(input, strings.Fields(input), GenerationOptions{Seed: &seed, MaxTokens: 20})
+		if explanation == nil {
+			t.Fatalf("No explanation for %q", input)
+		}
+		if !utf8.ValidString(response) {
+			t.Fatalf("Invalid UTF-8 response to %q", input)
+		}
+	})
+}
+
+// TestRequestLimits tests per-request resource limits
+func TestRequestLimits(t *testing.T) {
+	t.Run("Within", func(t *testing.T) {
+		configured := RequestLimits{MaxWallMS: 1000, MaxTokens: 50, MaxWaves: 100}
+		got := configured.within(RequestLimits{MaxWallMS: 5000, MaxTokens: 10, MaxCircuits: 3})
+		want := RequestLimits{MaxWallMS: 1000, MaxTokens: 10, MaxCircuits: 3, MaxWaves: 100}
+		if got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+		if err := (RequestLimits{MaxWaves: -1}).validate(); err == nil {
+			t.Error("Expected negative limits to be rejected")
+		}
+		if err := (RequestLimits{MaxTokens: maxRequestTokens + 1}).validate(); err == nil {
+			t.Error("Expected a token limit over the maximum to be rejected")
+		}
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		if newRequestBudget(RequestLimits{}) != nil {
+			t.Error("Expected no budget without limits")
+		}
+		var unlimited *requestBudget
+		if unlimited.takeWaves(7) != 7 || unlimited.Hit() != nil {
+			t.Error("Expected a nil budget to be unlimited")
+		}
+
+		b := newRequestBudget(RequestLimits{MaxWaves: 10, MaxCircuits: 2})
+		if got := b.takeWaves(6); got != 6 {
+			t.Errorf("Expected 6 waves granted, got %d", got)
+		}
+		if got := b.takeWaves(6); got != 4 {
+			t.Errorf("Expected the last 4 waves granted, got %d", got)
+		}
+		if got := b.takeWaves(1); got != 0 {
+			t.Errorf("Expected no waves left, got %d", got)
+		}
+		circuits := b.circuits([]CircuitPath{{strength: 0.2}, {strength: 0.9}, {strength: 0.5}})
+		if len(circuits) != 2 || circuits[0].strength != 0.9 || circuits[1].strength != 0.5 {
+			t.Errorf("Expected the 2 strongest circuits, got %+v", circuits)
+		}
+		if hit := b.Hit(); strings.Join(hit, ",") != "circuits,waves" {
+			t.Errorf("Expected circuits and waves hit, got %v", hit)
+		}
+	})
+
+	config := DefaultConfig()
+	config.ResponseCache.Enabled = false
+
+	t.Run("TransparentPartialResults", func(t *testing.T) {
+		llm := NewTransparentLLMWithConfig(config)
+		defer llm.Cleanup()
+
+		response, err := llm.Respond(context.Background(), Request{Input: "why is the sky blue", Limits: RequestLimits{MaxCircuits: 1, MaxTokens: 3}})
+		if err != nil {
+			t.Fatalf("Respond failed: %v", err)
+		}
+		if response.Output == "" {
+			t.Error("Expected a partial response")
+		}
+		if n := len(strings.Fields(response.Output)); n > 3 {
+			t.Errorf("Expected at most 3 tokens, got %d: %q", n, response.Output)
+		}
+		if response.Explanation.Energy.CircuitsTraced > 1 {
+			t.Errorf("Expected at most 1 circuit traced, got %d", response.Explanation.Energy.CircuitsTraced)
+		}
+		if !contains(response.LimitsHit, limitCircuits) || !contains(response.Explanation.LimitsHit, limitCircuits) {
+			t.Errorf("Expected the circuit limit reported, got %v and %v", response.LimitsHit, response.Explanation.LimitsHit)
+		}
+
+		if _, err := llm.Respond(context.Background(), Request{Input: "hello", Limits: RequestLimits{MaxTokens: -1}}); err == nil {
+			t.Error("Expected invalid request limits to be rejected")
+		}
+	})
+
+	t.Run("LiquidWaves", func(t *testing.T) {
+		brain := NewLiquidStateBrainWithConfig(20, config)
+		if brain == nil {
+			t.Fatal("Failed to create liquid brain")
+		}
+		defer brain.Cleanup()
+
+		response, err := brain.Respond(context.Background(), Request{Input: "hello", Limits: RequestLimits{MaxWaves: 5}})
+		if err != nil {
+			t.Fatalf("Respond failed: %v", err)
+		}
+		if !contains(response.LimitsHit, limitWaves) {
+			t.Errorf("Expected the wave limit reported, got %v", response.LimitsHit)
+		}
+	})
+
+	t.Run("OrchestratorWallTime", func(t *testing.T) {
+		orchestrator := NewGenesisOrchestratorWithConfig(20, config)
+		defer orchestrator.Close()
+		orchestrator.RegisterCapability("gpt4", func(ctx context.Context, prompt string) (string, error) {
+			t.Error("Expected no capability call after the wall time ran out")
+			return "", nil
+		})
+
+		response, err := orchestrator.Respond(context.Background(), Request{Input: "hello there", Limits: RequestLimits{MaxWallMS: 1}})
+		if err != nil {
+			t.Fatalf("Respond failed: %v", err)
+		}
+		if !contains(response.LimitsHit, limitWallTime) {
+			t.Errorf("Expected the wall time limit reported, got %v", response.LimitsHit)
+		}
+		last := response.Decisions[len(response.Decisions)-1]
+		if len(last.Path) != 1 || !strings.Contains(last.Reasoning, "wall time") {
+			t.Errorf("Expected a local answer for the exhausted request, got %+v", last)
+		}
+	})
+}
+
+// TestScaleBench tests the scaling report
+func TestScaleBench(t *testing.T) {
+	t.Run("Measures", func(t *testing.T) {
+		bench := ScaleBenchConfig{Model: "parallel", Sizes: []int{5, 50}, Prompts: []string{"hello", "why is the sky blue"}, Repeats: 2, Seed: 1}
+		report, err := RunScaleBench(DefaultConfig(), bench)
+		if err != nil {
+			t.Fatalf("Scale benchmark failed: %v", err)
+		}
+		if len(report.Results) != 2 || report.Environment.CPUs == 0 || report.Environment.GoVersion == "" {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		for _, r := range report.Results {
+			if r.Error != "" || r.Errors != 0 {
+				t.Errorf("Size %d failed: %q, %d errors", r.Size, r.Error, r.Errors)
+			}
+			if r.CreateMS <= 0 || r.LatencyMeanMS <= 0 || r.LatencyP95MS < r.LatencyP50MS {
+				t.Errorf("Size %d: implausible timings %+v", r.Size, r)
+			}
+			for name, v := range map[string]float64{"distinct": r.Distinct, "variability": r.Variability, "seed drift": r.SeedDrift} {
+				if v < 0 || v > 1 {
+					t.Errorf("Size %d: %s %.3f outside [0, 1]", r.Size, name, v)
+				}
+			}
+		}
+
+		var out bytes.Buffer
+		report.Markdown(&out)
+		for _, want := range []string{"# Scaling report: parallel", "| Size |", "| 5 |", "| 50 |"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Markdown missing %q:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("Rejects", func(t *testing.T) {
+		bad := []ScaleBenchConfig{
+			{Model: "parallel", Prompts: []string{"hi"}, Repeats: 1},
+			{Model: "parallel", Sizes: []int{0}, Prompts: []string{"hi"}, Repeats: 1},
+			{Model: "parallel", Sizes: []int{5}, Repeats: 1},
+			{Model: "parallel", Sizes: []int{5}, Prompts: []string{"hi"}},
+			{Model: "no-such-model", Sizes: []int{5}, Prompts: []string{"hi"}, Repeats: 1},
+		}
+		for _, bench := range bad {
+			if _, err := RunScaleBench(DefaultConfig(), bench); err == nil {
+				t.Errorf("Expected %+v to be rejected", bench)
+			}
+		}
+		if _, err := parseSizes("10,x"); err == nil {
+			t.Error("Expected a bad size to be rejected")
+		}
+	})
+
+	t.Run("Variability", func(t *testing.T) {
+		if got := distinctShare([]string{"a", "a", "a"}); got != 0 {
+			t.Errorf("Expected identical responses to be 0 distinct, got %v", got)
+		}
+		if got := distinctShare([]string{"a", "b", "c"}); got != 1 {
+			t.Errorf("Expected different responses to be 1 distinct, got %v", got)
+		}
+		if got := meanDissimilarity([]string{"the cat sat", "the cat sat"}); got != 0 {
+			t.Errorf("Expected identical responses to be 0 apart, got %v", got)
+		}
+		if got := meanDissimilarity([]string{"the cat sat", "a dog ran"}); got != 1 {
+			t.Errorf("Expected disjoint responses to be 1 apart, got %v", got)
+		}
+		if got := responseSimilarity("", " "); got != 1 {
+			t.Errorf("Expected empty responses to match, got %v", got)
+		}
+	})
+}
+
+// TestInputHistory tests the input history and its temporal patterns
+func TestInputHistory(t *testing.T) {
+	events := func(concepts string, intensities ...float64) []InputEvent {
+		var out []InputEvent
+		for i, c := range strings.Fields(concepts) {
+			e := InputEvent{Concept: strings.Trim(c, "-")}
+			if i < len(intensities) {
+				e.Intensity = intensities[i]
+			}
+			out = append(out, e)
+		}
+		return out
+	}
+	kinds := func(patterns []TemporalPattern) string {
+		var k []string
+		for _, p := range patterns {
+			k = append(k, p.Kind)
+		}
+		return strings.Join(k, " ")
+	}
+
+	t.Run("Patterns", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			events []InputEvent
+			want   string
+		}{
+			{"Repetition", events("a b b b"), PatternRepetition},
+			{"Too Short To Repeat", events("a b b"), ""},
+			{"Alternation", events("c a b a b"), PatternAlternation},
+			{"Unclear Concepts", events("- - - -"), ""},
+			{"Escalation", events("a b c", 0, 0.25, 0.75), PatternEscalation},
+			{"Small Rise", events("a b c", 0, 0.1, 0.2), ""},
+			{"Escalating Repetition", events("a a a", 0, 0.5, 1), PatternEscalation + " " + PatternRepetition},
+		}
+		for _, c := range cases {
+			if got := kinds(detectPatterns(c.events)); got != c.want {
+				t.Errorf("%s: expected %q, got %q", c.name, c.want, got)
+			}
+		}
+		p, _ := detectAlternation(events("a b a b"))
+		if p.Span != 4 || strings.Join(p.Concepts, " ") != "a b" || p.Strength != 1 {
+			t.Errorf("Unexpected alternation %+v", p)
+		}
+	})
+
+	t.Run("Buffer", func(t *testing.T) {
+		if NewInputHistory(InputHistoryConfig{}, nil) != nil {
+			t.Error("Expected a zero size to turn the history off")
+		}
+		var off *InputHistory
+		off.Record(InputEvent{Input: "x"})
+		if off.Recent() != nil || off.Patterns() != nil {
+			t.Error("Expected a nil history to record nothing")
+		}
+
+		h := NewInputHistory(InputHistoryConfig{Size: 3, MaxAgeMS: 1000}, NewScaledClock(1000))
+		for _, input := range []string{"one", "two", "three", "four"} {
+			h.Record(InputEvent{Input: input})
+		}
+		recent := h.Recent()
+		if len(recent) != 3 || recent[0].Input != "two" || recent[0].At.IsZero() {
+			t.Fatalf("Expected the last 3 timestamped inputs, got %+v", recent)
+		}
+		time.Sleep(5 * time.Millisecond) // 5s on the scaled clock
+		if len(h.Recent()) != 0 {
+			t.Error("Expected inputs older than max_age_ms to be forgotten")
+		}
+	})
+
+	t.Run("Input Features", func(t *testing.T) {
+		if i := inputIntensity("help me"); i != 0 {
+			t.Errorf("Expected a calm input to have no intensity, got %v", i)
+		}
+		if i := inputIntensity("HELP me now!"); i != 0.5 {
+			t.Errorf("Expected a shouted word and an exclamation to give 0.5, got %v", i)
+		}
+		if i := inputIntensity("PLEASE HELP NOW!!!"); i != 1 {
+			t.Errorf("Expected intensity capped at 1, got %v", i)
+		}
+		schema := DefaultConceptSchema()
+		if c := schema.inputConcept("Can you HELP me fix this bug?", nil); c != "assistance" && c != "problem" {
+			t.Errorf("Expected a schema meaning, got %q", c)
+		}
+		if c := schema.inputConcept("tell me about oceans", map[string]float64{"oceans": 0.9, "tell": 0.1}); c != "oceans" {
+			t.Errorf("Expected the strongest keyword, got %q", c)
+		}
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		schema := DefaultConceptSchema()
+		if r := schema.patternRemark(TemporalPattern{Kind: PatternAlternation, Concepts: []string{"a", "b"}}); !strings.Contains(r, "a and b") {
+			t.Errorf("Unexpected alternation remark %q", r)
+		}
+		bad := *schema
+		bad.AlternationTemplate = "Between %s and others"
+		if bad.validate() == nil {
+			t.Error("Expected an alternation template with one verb to be rejected")
+		}
+	})
+
+	t.Run("Liquid Brain", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Resources.MaxNeurons = 1000
+		config.Resources.MaxGoroutines = 50
+		config.ResponseCache.Enabled = false
+		brain := NewLiquidStateBrainWithConfig(6, config)
+		if brain == nil {
+			t.Fatal("Failed to create brain")
+		}
+		defer brain.Cleanup()
+
+		var response Response
+		for _, input := range []string{"help me", "I need help", "please help"} {
+			var err error
+			if response, err = brain.Respond(context.Background(), Request{Input: input}); err != nil {
+				t.Fatalf("Respond failed: %v", err)
+			}
+		}
+		if kinds(response.Patterns) != PatternRepetition || response.Patterns[0].Concepts[0] != "assistance" {
+			t.Fatalf("Expected repeated assistance, got %+v", response.Patterns)
+		}
+		if !strings.HasPrefix(response.Output, "We keep coming back to assistance.") {
+			t.Errorf("Expected the response to remark on the repetition, got %q", response.Output)
+		}
+
+		brain.InputHistory().Reset()
+		response, _ = brain.Respond(context.Background(), Request{Input: "help me"})
+		if len(response.Patterns) != 0 || strings.HasPrefix(response.Output, "We keep") {
+			t.Errorf("Expected no patterns after a reset, got %+v", response.Patterns)
+		}
+	})
+}
+
+// TestShardedMap tests the sharded concurrent map
+func TestShardedMap(t *testing.T) {
+	m := newShardedMap[int]()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Set(fmt.Sprintf("key_%d_%d", i, j), j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if m.Len() != 3200 {
+		t.Errorf("Expected 3200 entries, got %d", m.Len())
+	}
+	if v, ok := m.Get("key_7_42"); !ok || v != 42 {
+		t.Errorf("Expected key_7_42=42, got %d (found=%v)", v, ok)
+	}
+
+	seen := 0
+	m.Range(func(key string, _ int) bool {
+		seen++
+		m.Delete(key) // Range must tolerate mutation from the callback
+		return true
+	})
+	if seen != 3200 || m.Len() != 0 {
+		t.Errorf("Range visited %d entries, %d left after delete", seen, m.Len())
+	}
+}
+
 // BenchmarkTransparentLLM benchmarks the transparent LLM performance
 func BenchmarkTransparentLLM(b *testing.B) {
 	config := DefaultConfig()
@@ -1,3 +1,5 @@
 module genesis
 
-go 1.21
\ No newline at end of file
+go 1.21
+
+require github.com/mattn/go-sqlite3 v1.14.33
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Golden traces: a fixed prompt set is run with a fixed seed and the
+// structure of what happened is recorded: the thought stages the
+// transparent LLM went through, the concepts of its strongest circuits, and
+// the capabilities the orchestrator routed to. Checking re-runs the prompts
+// and compares structurally, so a refactor of the reasoning pipeline that
+// changes behavior fails the check instead of passing silently. Response
+// text isn't compared. Every prompt runs on fresh models so earlier prompts
+// can't leave activation behind, but activation still spreads concurrently
+// and how many circuits are found varies, so top concepts only have to
+// overlap by a tolerance, while stages and routing must match exactly.
+
+// goldenSchema names the golden trace file format
+const goldenSchema = "genesis.golden/v1"
+
+// Default number of top concepts recorded per prompt
+const defaultGoldenTopConcepts = 10
+
+// Default least concept overlap for a golden trace to match
+const defaultGoldenMinOverlap = 0.5
+
+// DefaultGoldenPrompts exercise each routing choice and a range of concepts
+var DefaultGoldenPrompts = []string{
+	"hello",
+	"why is the sky blue",
+	"help me code",
+	"the cat sat on the mat",
+	"calculate the square root of 144",
+	"write a creative story about robots",
+	"find user data for John Doe",
+}
+
+// GoldenTrace is the recorded structure of one prompt's run
+type GoldenTrace struct {
+	Prompt      string   `json:"prompt"`
+	Stages      []string `json:"stages"`       // thought stages, in order
+	TopConcepts []string `json:"top_concepts"` // by their strongest circuit, strongest first
+	Routing     []string `json:"routing"`      // capability each orchestrator decision ended at
+}
+
+// GoldenTraces is a golden trace file
+type GoldenTraces struct {
+	Schema      string        `json:"schema"`
+	Seed        int64         `json:"seed"`
+	TopConcepts int           `json:"top_concepts"`
+	Traces      []GoldenTrace `json:"traces"`
+}
+
+// GoldenDiff is one structural difference from a golden trace
+type GoldenDiff struct {
+	Prompt string `json:"prompt"`
+	Field  string `json:"field"` // "stages", "top_concepts" or "routing"
+	Want   string `json:"want"`
+	Got    string `json:"got"`
+}
+
+func (d GoldenDiff) String() string {
+	return fmt.Sprintf("%q %s: want %s, got %s", d.Prompt, d.Field, d.Want, d.Got)
+}
+
+// goldenConfig is config with nothing cached or persisted between runs
+func goldenConfig(config *Config) *Config {
+	c := *config
+	c.ResponseCache.Enabled = false
+	c.Distill.Enabled = false
+	return &c
+}
+
+// RecordGoldenTraces runs each prompt and records its structure
+func RecordGoldenTraces(config *Config, prompts []string, seed int64, topConcepts int) (*GoldenTraces, error) {
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("golden traces need at least one prompt")
+	}
+	if topConcepts <= 0 {
+		topConcepts = defaultGoldenTopConcepts
+	}
+	config = goldenConfig(config)
+	golden := &GoldenTraces{Schema: goldenSchema, Seed: seed, TopConcepts: topConcepts}
+	for _, prompt := range prompts {
+		trace, err := captureGoldenTrace(config, prompt, seed, topConcepts)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %q: %w", prompt, err)
+		}
+		golden.Traces = append(golden.Traces, trace)
+	}
+	return golden, nil
+}
+
+// captureGoldenTrace runs prompt through a fresh transparent LLM and
+// orchestrator
+func captureGoldenTrace(config *Config, prompt string, seed int64, topConcepts int) (GoldenTrace, error) {
+	trace := GoldenTrace{Prompt: prompt, Stages: []string{}, Routing: []string{}}
+
+	llm := NewTransparentLLMWithConfig(config)
+	_, _, thoughts := llm.UnderstandContext(context.Background(), prompt, GenerationOptions{Seed: &seed})
+	strongest := make(map[string]float64)
+	for thought := range thoughts {
+		trace.Stages = append(trace.Stages, thought.stage)
+		for _, circuit := range thought.circuits {
+			for _, node := range circuit.nodes {
+				if node != nil && circuit.strength > strongest[node.id] {
+					strongest[node.id] = circuit.strength
+				}
+			}
+		}
+	}
+	llm.Cleanup()
+	trace.TopConcepts = topKeys(strongest, topConcepts)
+
+	orchestrator := NewGenesisOrchestratorWithConfig(defaultModelSize, config)
+	if orchestrator.liquidBrain == nil {
+		return trace, fmt.Errorf("failed to create orchestrator")
+	}
+	_, decisions := orchestrator.Process(prompt)
+	orchestrator.Close()
+	for _, d := range decisions {
+		if len(d.Path) > 0 {
+			trace.Routing = append(trace.Routing, d.Path[len(d.Path)-1])
+		}
+	}
+	return trace, nil
+}
+
+// topKeys returns the n keys with the highest values, ties by name
+func topKeys(values map[string]float64, n int) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if values[keys[i]] != values[keys[j]] {
+			return values[keys[i]] > values[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Compare returns how current differs from the golden traces. Top concepts
+// differ when their overlap is below minOverlap.
+func (g *GoldenTraces) Compare(current *GoldenTraces, minOverlap float64) []GoldenDiff {
+	var diffs []GoldenDiff
+	got := make(map[string]GoldenTrace, len(current.Traces))
+	for _, trace := range current.Traces {
+		got[trace.Prompt] = trace
+	}
+	for _, want := range g.Traces {
+		have, ok := got[want.Prompt]
+		if !ok {
+			diffs = append(diffs, GoldenDiff{Prompt: want.Prompt, Field: "prompt", Want: "recorded", Got: "missing"})
+			continue
+		}
+		if !equalStrings(want.Stages, have.Stages) {
+			diffs = append(diffs, GoldenDiff{Prompt: want.Prompt, Field: "stages", Want: fmt.Sprint(want.Stages), Got: fmt.Sprint(have.Stages)})
+		}
+		if overlap := conceptOverlap(want.TopConcepts, have.TopConcepts); overlap < minOverlap {
+			diffs = append(diffs, GoldenDiff{Prompt: want.Prompt, Field: "top_concepts",
+				Want: fmt.Sprint(want.TopConcepts), Got: fmt.Sprintf("%v (overlap %.2f)", have.TopConcepts, overlap)})
+		}
+		if !equalStrings(want.Routing, have.Routing) {
+			diffs = append(diffs, GoldenDiff{Prompt: want.Prompt, Field: "routing", Want: fmt.Sprint(want.Routing), Got: fmt.Sprint(have.Routing)})
+		}
+	}
+	return diffs
+}
+
+// CheckGoldenTraces re-runs the golden prompts and compares the results
+func CheckGoldenTraces(config *Config, golden *GoldenTraces, minOverlap float64) ([]GoldenDiff, error) {
+	prompts := make([]string, len(golden.Traces))
+	for i, trace := range golden.Traces {
+		prompts[i] = trace.Prompt
+	}
+	current, err := RecordGoldenTraces(config, prompts, golden.Seed, golden.TopConcepts)
+	if err != nil {
+		return nil, err
+	}
+	return golden.Compare(current, minOverlap), nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// conceptOverlap is the share of the smaller of a and b found in the
+// other: 1 when both are empty, 0 when only one is
+func conceptOverlap(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		if len(a) == len(b) {
+			return 1
+		}
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	shared := 0
+	for _, s := range a {
+		if in[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a))
+}
+
+// LoadGoldenTraces reads a golden trace file
+func LoadGoldenTraces(path string) (*GoldenTraces, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden traces: %w", err)
+	}
+	var golden GoldenTraces
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("failed to parse golden traces %s: %w", path, err)
+	}
+	if golden.Schema != goldenSchema {
+		return nil, fmt.Errorf("unsupported golden trace schema %q (want %q)", golden.Schema, goldenSchema)
+	}
+	return &golden, nil
+}
+
+// Save writes the golden traces to path
+func (g *GoldenTraces) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write golden traces: %w", err)
+	}
+	return nil
+}
+
+// readPrompts reads one prompt per non-empty line
+func readPrompts(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompts: %w", err)
+	}
+	defer file.Close()
+
+	var prompts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			prompts = append(prompts, line)
+		}
+	}
+	return prompts, scanner.Err()
+}
+
+// GoldenMain implements `go run . golden record|check <file>`
+func GoldenMain(args []string) {
+	if len(args) == 0 || (args[0] != "record" && args[0] != "check") {
+		fmt.Println("usage: genesis golden record|check [flags] <golden.json>")
+		os.Exit(2)
+	}
+	mode := args[0]
+	fs := flag.NewFlagSet("golden "+mode, flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	promptsPath := fs.String("prompts", "", "record: file with one prompt per line; empty uses the built-in set")
+	seed := fs.Int64("seed", 1, "record: generation seed")
+	top := fs.Int("top", defaultGoldenTopConcepts, "record: top concepts kept per prompt")
+	minOverlap := fs.Float64("min-overlap", defaultGoldenMinOverlap, "check: least share of top concepts that must still be found")
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fmt.Printf("usage: genesis golden %s [flags] <golden.json>\n", mode)
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	if mode == "record" {
+		prompts := DefaultGoldenPrompts
+		if *promptsPath != "" {
+			if prompts, err = readPrompts(*promptsPath); err != nil {
+				fmt.Printf("❌ ERROR: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		golden, err := RecordGoldenTraces(config, prompts, *seed, *top)
+		if err == nil {
+			err = golden.Save(fs.Arg(0))
+		}
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Recorded %d golden traces to %s\n", len(golden.Traces), fs.Arg(0))
+		return
+	}
+
+	golden, err := LoadGoldenTraces(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	diffs, err := CheckGoldenTraces(config, golden, *minOverlap)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if len(diffs) > 0 {
+		for _, d := range diffs {
+			fmt.Printf("❗ %s\n", d)
+		}
+		fmt.Printf("❌ %d differences from %d golden traces\n", len(diffs), len(golden.Traces))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ All %d golden traces match\n", len(golden.Traces))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Goroutine budget: Resources.MaxGoroutines is one budget for the whole
+// process. Components such as the liquid brain's neurons, the LLM's concept
+// neurons, orchestrators and servers request workers from a shared registry
+// instead of each assuming the whole budget is theirs, and give them back
+// when they shut down. Resources.GoroutineShares caps each component's part
+// of the budget so one large brain can't starve the rest. Components that
+// can batch their work onto fewer goroutines do so when granted less than
+// they asked for.
+
+// Budget components
+const (
+	budgetBrain        = "brain"
+	budgetLLM          = "llm"
+	budgetOrchestrator = "orchestrator"
+	budgetServer       = "server"
+)
+
+// GoroutineBudget hands out workers within a goroutine limit
+type GoroutineBudget struct {
+	mu     sync.Mutex
+	limit  int                // 0 is unlimited
+	shares map[string]float64 // per component share of limit; components not listed may use all of it
+	held   map[string]int
+}
+
+// BudgetStats is a snapshot of who holds the budget
+type BudgetStats struct {
+	Limit         int            `json:"limit"`
+	Held          map[string]int `json:"held"`
+	Available     int            `json:"available"`
+	Overcommitted int            `json:"overcommitted"` // workers granted past the limit to meet minimums
+}
+
+// NewGoroutineBudget creates a budget with the given limits
+func NewGoroutineBudget(limits ResourceLimits) *GoroutineBudget {
+	b := &GoroutineBudget{held: make(map[string]int)}
+	b.Configure(limits)
+	return b
+}
+
+// sharedBudget is the process-wide budget components draw from
+var sharedBudget = NewGoroutineBudget(ResourceLimits{})
+
+// configureGoroutineBudget applies limits to the process-wide budget and
+// returns it; the most recently loaded config wins
+func configureGoroutineBudget(limits ResourceLimits) *GoroutineBudget {
+	sharedBudget.Configure(limits)
+	return sharedBudget
+}
+
+// SharedGoroutineBudget returns the process-wide budget
+func SharedGoroutineBudget() *GoroutineBudget {
+	return sharedBudget
+}
+
+// Configure sets the limit and shares; workers already held are kept
+func (b *GoroutineBudget) Configure(limits ResourceLimits) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limits.MaxGoroutines
+	b.shares = limits.GoroutineShares
+}
+
+// capFor returns how many workers component may hold in all
+func (b *GoroutineBudget) capFor(component string) int {
+	share, ok := b.shares[component]
+	if !ok {
+		return b.limit
+	}
+	return int(share * float64(b.limit))
+}
+
+// total returns the workers held by every component
+func (b *GoroutineBudget) total() int {
+	total := 0
+	for _, n := range b.held {
+		total += n
+	}
+	return total
+}
+
+// Request grants component up to want workers, as many as the limit and
+// the component's share leave free, but never fewer than minimum: a
+// component that can't run on less is granted its minimum past the limit,
+// which Stats reports as overcommitted. Release the grant when done.
+func (b *GoroutineBudget) Request(component string, want, minimum int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	grant := want
+	if b.limit > 0 {
+		grant = min(grant, b.limit-b.total())
+		grant = min(grant, b.capFor(component)-b.held[component])
+	}
+	if grant < minimum {
+		if b.limit > 0 {
+			fmt.Printf("⚠️  Warning: goroutine budget exhausted, granting %s %d workers past the limit of %d\n", component, minimum-max(grant, 0), b.limit)
+		}
+		grant = minimum
+	}
+	b.held[component] += grant
+	return grant
+}
+
+// Release returns n of component's workers to the budget
+func (b *GoroutineBudget) Release(component string, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.held[component] = max(0, b.held[component]-n)
+	if b.held[component] == 0 {
+		delete(b.held, component)
+	}
+}
+
+// Stats returns a snapshot of the budget
+func (b *GoroutineBudget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := BudgetStats{Limit: b.limit, Held: make(map[string]int, len(b.held))}
+	for component, n := range b.held {
+		stats.Held[component] = n
+	}
+	if total := b.total(); b.limit > 0 {
+		stats.Available = max(0, b.limit-total)
+		stats.Overcommitted = max(0, total-b.limit)
+	}
+	return stats
+}
+
+// validateGoroutineShares checks that every share is a fraction
+func validateGoroutineShares(shares map[string]float64) error {
+	components := make([]string, 0, len(shares))
+	for component := range shares {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		if share := shares[component]; share <= 0 || share > 1 {
+			return fmt.Errorf("goroutine share of %q must be in (0, 1]", component)
+		}
+	}
+	return nil
+}
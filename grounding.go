@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Retrieval-augmented generation: chunks retrieved for the input seed the
+// generator's topic memory and contribute their word sequences as extra
+// beam candidates. Chunks whose content words end up in the response are
+// cited by ID in the Explanation, so every answer can be traced back to the
+// corpus text that shaped it.
+
+// Weight of a retrieved chunk's words in topic memory, scaled by its score
+const groundingTopicWeight = 0.6
+
+// Bonus for a candidate that continues a phrase from a retrieved chunk
+const groundingPhraseBonus = 2.0
+
+// Citation identifies a retrieved chunk
+type Citation struct {
+	ChunkID int     `json:"chunk_id"`
+	Path    string  `json:"path"`
+	Score   float64 `json:"score"`
+	Text    string  `json:"text"`
+}
+
+// Explanation describes how a response was produced
+type Explanation struct {
+	Input          string              `json:"input"`
+	Response       string              `json:"response"`
+	ActiveConcepts []string            `json:"active_concepts"`
+	Retrieved      []Citation          `json:"retrieved"`               // chunks found for the input, best first
+	Cited          []int               `json:"cited_chunk_ids"`         // retrieved chunks that contributed words to the response
+	Answer         *Answer             `json:"answer,omitempty"`        // set when the response was extracted rather than generated
+	Facts          []Fact              `json:"facts,omitempty"`         // learned facts the response states, when it answers from them
+	Entities       []EntityLink        `json:"entities,omitempty"`      // references in the input resolved to earlier mentions
+	Energy         EnergyReport        `json:"energy"`                  // work spent producing the response
+	Language       string              `json:"language,omitempty"`      // language generation was held to, if any
+	Confidence     Confidence          `json:"confidence"`              // how sure the producing model is of the response
+	Clarification  []string            `json:"clarification,omitempty"` // competing meanings, when the response asks which was meant
+	FinishReason   string              `json:"finish_reason,omitempty"` // "stop" or "length" (the token limit)
+	BeamTree       *BeamTree           `json:"beam_tree,omitempty"`     // the search, when diagnostics were requested
+	Alternatives   []ResponseCandidate `json:"alternatives,omitempty"`  // the N best responses, when requested
+	Coherence      float64             `json:"coherence,omitempty"`     // fit with the session history, when replies are reranked by it
+	Template       *TemplateUse        `json:"template,omitempty"`      // the template that replaced or led a low-confidence response
+	Seed           *int64              `json:"seed,omitempty"`          // the generator's seed; pass it back to reproduce the response
+	Timeouts       []string            `json:"timeouts,omitempty"`      // stages that ran out of time, leaving a partial result
+	Error          string              `json:"error,omitempty"`         // why generation failed, leaving a fallback response
+	LimitsHit      []string            `json:"limits_hit,omitempty"`    // request limits that cut the work short, leaving a partial result
+}
+
+// grounding holds the retrieval context for one Generate call
+type grounding struct {
+	hits    []ScoredChunk
+	phrases map[string]map[string]float64 // word -> next word -> weight, from retrieved chunks
+}
+
+// SetRetrievalIndex enables retrieval-augmented generation using the top k
+// chunks per input. A nil index or k <= 0 disables it.
+func (gen *ResponseGenerator) SetRetrievalIndex(index *RetrievalIndex, k int) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.retrieval = index
+	gen.retrievalK = k
+}
+
+// languageSearchFactor widens retrieval when hits are filtered by language
+const languageSearchFactor = 3
+
+// retrieve searches the index for input and builds the phrase table
+func (gen *ResponseGenerator) retrieve(input string) *grounding {
+	if gen.retrieval == nil || gen.retrievalK <= 0 {
+		return nil
+	}
+	var hits []ScoredChunk
+	if gen.language == "" {
+		hits = gen.retrieval.Search(input, gen.retrievalK)
+	} else {
+		// Chunks in other languages would leak their words into the
+		// response, so search deeper and keep the input's language only
+		for _, hit := range gen.retrieval.Search(input, gen.retrievalK*languageSearchFactor) {
+			if hit.Chunk.Language == gen.language && len(hits) < gen.retrievalK {
+				hits = append(hits, hit)
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	g := &grounding{hits: hits, phrases: make(map[string]map[string]float64)}
+	for _, hit := range hits {
+		tokens := hit.Chunk.Tokens
+		for i := 0; i+1 < len(tokens); i++ {
+			if !gen.dataLoader.InVocabulary(tokens[i+1]) {
+				continue
+			}
+			next := g.phrases[tokens[i]]
+			if next == nil {
+				next = make(map[string]float64)
+				g.phrases[tokens[i]] = next
+			}
+			next[tokens[i+1]] += hit.Score
+		}
+	}
+	return g
+}
+
+// seedTopicMemory raises the weight in topics of words in the retrieved
+// chunks
+func (gen *ResponseGenerator) seedTopicMemory(topics map[string]float64, g *grounding) {
+	for _, hit := range g.hits {
+		weight := groundingTopicWeight * hit.Score
+		for _, token := range hit.Chunk.Tokens {
+			if len(token) < 3 || !gen.dataLoader.InVocabulary(token) {
+				continue
+			}
+			if weight > topics[token] {
+				topics[token] = weight
+			}
+		}
+	}
+}
+
+// candidatesFor merges corpus transitions with phrase continuations from the
+// retrieved chunks. The loader's transition map is shared, so it is copied
+// only when there is something to merge.
+func (g *grounding) candidatesFor(word string, transitions map[string]float64) map[string]float64 {
+	if g == nil {
+		return transitions
+	}
+	next, ok := g.phrases[word]
+	if !ok {
+		return transitions
+	}
+
+	merged := make(map[string]float64, len(transitions)+len(next))
+	for w, p := range transitions {
+		merged[w] = p
+	}
+	for w, weight := range next {
+		merged[w] += weight / float64(len(g.hits))
+	}
+	return merged
+}
+
+// continuesPhrase reports whether next follows word in a retrieved chunk
+func (g *grounding) continuesPhrase(word, next string) bool {
+	if g == nil {
+		return false
+	}
+	_, ok := g.phrases[word][next]
+	return ok
+}
+
+// explain fills in the retrieval part of an Explanation for response
+func (g *grounding) explain(exp *Explanation, response []string) {
+	if g == nil {
+		return
+	}
+
+	used := make(map[string]bool, len(response))
+	for _, w := range response {
+		w = strings.ToLower(w)
+		if len(w) >= 3 {
+			used[w] = true
+		}
+	}
+
+	for _, hit := range g.hits {
+		exp.Retrieved = append(exp.Retrieved, Citation{
+			ChunkID: hit.Chunk.ID,
+			Path:    hit.Chunk.Path,
+			Score:   hit.Score,
+			Text:    hit.Chunk.Text,
+		})
+		for _, token := range hit.Chunk.Tokens {
+			if used[token] {
+				exp.Cited = append(exp.Cited, hit.Chunk.ID)
+				break
+			}
+		}
+	}
+}
+
+// String renders the explanation as a short human-readable summary
+func (e *Explanation) String() string {
+	if e == nil || len(e.Retrieved) == 0 {
+		return "no retrieved context"
+	}
+	ids := make([]string, len(e.Cited))
+	for i, id := range e.Cited {
+		ids[i] = fmt.Sprintf("#%d", id)
+	}
+	if len(ids) == 0 {
+		return fmt.Sprintf("retrieved %d chunks, none cited", len(e.Retrieved))
+	}
+	return fmt.Sprintf("retrieved %d chunks, cited %s", len(e.Retrieved), strings.Join(ids, ", "))
+}
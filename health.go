@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health checks back the /healthz (liveness) and /readyz (readiness)
+// endpoints. Each component registers a check; liveness runs only the
+// checks marked as such, readiness runs all of them. A probe answers 200
+// when every check it ran passed and 503 otherwise, so both endpoints can be
+// used directly as Kubernetes probes.
+
+// Longest a single check may take before it counts as failed
+const healthCheckTimeout = 2 * time.Second
+
+// Consecutive failures after which a capability is reported unhealthy
+const capabilityFailureLimit = 3
+
+// ComponentStatus is the result of one health check
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// HealthReport is the body of /healthz and /readyz
+type HealthReport struct {
+	Status     string            `json:"status"` // "ok" or "unavailable"
+	Components []ComponentStatus `json:"components"`
+}
+
+// HealthCheck reports the status of one component
+type HealthCheck func(ctx context.Context) ComponentStatus
+
+type registeredCheck struct {
+	name     string
+	check    HealthCheck
+	liveness bool
+}
+
+// HealthChecker runs registered checks
+type HealthChecker struct {
+	mu     sync.RWMutex
+	checks []registeredCheck
+}
+
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{}
+}
+
+// Register adds a check. Liveness checks also run for /healthz; use them
+// only for failures a restart would fix.
+func (hc *HealthChecker) Register(name string, check HealthCheck, liveness bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.checks = append(hc.checks, registeredCheck{name: name, check: check, liveness: liveness})
+}
+
+// Check runs the liveness checks, or every check when readiness is true
+func (hc *HealthChecker) Check(ctx context.Context, readiness bool) HealthReport {
+	hc.mu.RLock()
+	checks := append([]registeredCheck(nil), hc.checks...)
+	hc.mu.RUnlock()
+
+	var selected []registeredCheck
+	for _, c := range checks {
+		if readiness || c.liveness {
+			selected = append(selected, c)
+		}
+	}
+
+	statuses := make([]ComponentStatus, len(selected))
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func(i int, c registeredCheck) {
+			defer wg.Done()
+			statuses[i] = runHealthCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: "ok", Components: statuses}
+	for _, s := range statuses {
+		if !s.Healthy {
+			report.Status = "unavailable"
+		}
+	}
+	return report
+}
+
+// runHealthCheck runs one check with a timeout, turning panics and
+// overruns into failures
+func runHealthCheck(ctx context.Context, c registeredCheck) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result := make(chan ComponentStatus, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- ComponentStatus{Healthy: false, Detail: fmt.Sprintf("check panicked: %v", r)}
+			}
+		}()
+		result <- c.check(ctx)
+	}()
+
+	var status ComponentStatus
+	select {
+	case status = <-result:
+	case <-ctx.Done():
+		status = ComponentStatus{Healthy: false, Detail: "check timed out"}
+	}
+	status.Name = c.name
+	return status
+}
+
+// Handler serves /healthz when readiness is false and /readyz when true
+func (hc *HealthChecker) Handler(readiness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := hc.Check(r.Context(), readiness)
+		status := http.StatusOK
+		if report.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+// Mount registers /healthz and /readyz on mux
+func (hc *HealthChecker) Mount(mux *http.ServeMux) {
+	mux.Handle("/healthz", hc.Handler(false))
+	mux.Handle("/readyz", hc.Handler(true))
+}
+
+// DatasetHealthCheck passes once the loader has documents and a vocabulary
+func DatasetHealthCheck(loader *DatasetLoader) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		if loader == nil {
+			return ComponentStatus{Detail: "no dataset loader"}
+		}
+		docs, vocab := len(loader.GetDocuments()), len(loader.GetVocabulary())
+		return ComponentStatus{
+			Healthy: docs > 0 && vocab > 0,
+			Detail:  fmt.Sprintf("%d documents, %d words", docs, vocab),
+		}
+	}
+}
+
+// BrainHealthCheck passes while the liquid brain's reservoir is built and
+// it hasn't been cleaned up
+func BrainHealthCheck(brain *LiquidStateBrain) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		if brain == nil || brain.reservoir == nil {
+			return ComponentStatus{Detail: "brain not initialized"}
+		}
+		if brain.ctx.Err() != nil {
+			return ComponentStatus{Detail: "brain shut down"}
+		}
+		d := brain.dimensions
+		return ComponentStatus{
+			Healthy: true,
+			Detail:  fmt.Sprintf("%dx%dx%d reservoir, %d active waves", d.X, d.Y, d.Z, atomic.LoadInt64(&brain.activeWaves)),
+		}
+	}
+}
+
+// GoroutineBudgetCheck fails when the process runs more goroutines than
+// budget, which usually means leaked or stuck workers
+func GoroutineBudgetCheck(budget int) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		n := runtime.NumGoroutine()
+		return ComponentStatus{
+			Healthy: budget <= 0 || n <= budget,
+			Detail:  fmt.Sprintf("%d of %d goroutines", n, budget),
+		}
+	}
+}
+
+// SessionStoreHealthCheck passes while the session store can list sessions
+func SessionStoreHealthCheck(store SessionStore) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		ids, err := store.List()
+		if err != nil {
+			return ComponentStatus{Detail: err.Error()}
+		}
+		return ComponentStatus{Healthy: true, Detail: fmt.Sprintf("%d sessions", len(ids))}
+	}
+}
+
+// OrchestratorHealthCheck fails when any capability has failed its last
+// capabilityFailureLimit calls
+func OrchestratorHealthCheck(orchestrator *GenesisOrchestrator) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		statuses := orchestrator.CapabilityHealth()
+		var failing []string
+		for _, s := range statuses {
+			if !s.Healthy {
+				failing = append(failing, s.Name)
+			}
+		}
+		if len(failing) > 0 {
+			return ComponentStatus{Detail: fmt.Sprintf("failing capabilities: %v", failing)}
+		}
+		return ComponentStatus{Healthy: true, Detail: fmt.Sprintf("%d capabilities", len(statuses))}
+	}
+}
+
+// CapabilityHealth reports every registered capability, sorted by name
+func (go_ *GenesisOrchestrator) CapabilityHealth() []ComponentStatus {
+	go_.mu.RLock()
+	neurons := make([]*OrchestratorNeuron, 0, len(go_.neurons))
+	for _, n := range go_.neurons {
+		neurons = append(neurons, n)
+	}
+	go_.mu.RUnlock()
+
+	statuses := make([]ComponentStatus, len(neurons))
+	for i, n := range neurons {
+		statuses[i] = n.health()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
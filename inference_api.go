@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Inference API: server mode exposes the two models directly, next to the
+// session API, so remote clients can call them without a conversation.
+//
+//	POST /v1/think       {"input": "...", ...generation options} -> the liquid brain's reply
+//	POST /v1/understand  {"input": "...", "stream": true, ...}  -> the transparent model's reply
+//	POST /v1/compare     {"input": "...", "models": [...], ...} -> every model's reply; see comparison.go
+//
+// Understand explains itself as it goes. With "stream": true (or an Accept
+// header of text/event-stream) its thoughts are sent as server-sent events
+// while it works, one "thought" event per ThoughtTrace in the trace JSON
+// format (without the explanation), followed by a "response" event carrying
+// the reply and explanation, or an "error" event. Cached replies send no
+// thoughts.
+//
+// Like session messages, answered think and understand requests are
+// recorded and appended to the audit log when those are on, attributed to
+// the model that answered. Replays can't re-run them deterministically and
+// report them as unverified; see replay.go.
+
+// InferenceConfig controls the inference endpoints in server mode
+type InferenceConfig struct {
+	Think      bool `json:"think"`      // serve /v1/think from a liquid brain
+	Understand bool `json:"understand"` // serve /v1/understand from the transparent model
+	Compare    bool `json:"compare"`    // serve /v1/compare from both models and an orchestrator
+	BrainSize  int  `json:"brain_size"` // size of the liquid brains behind /v1/think and /v1/compare
+}
+
+func (c InferenceConfig) validate() error {
+	if (c.Think || c.Compare) && c.BrainSize < 2 {
+		return fmt.Errorf("inference brain_size must be at least 2")
+	}
+	return nil
+}
+
+// InferenceRequest is the body of POST /v1/think, /v1/understand and
+// /v1/compare
+type InferenceRequest struct {
+	Input  string   `json:"input"`
+	Stream bool     `json:"stream,omitempty"` // stream thoughts as server-sent events; /v1/understand only
+	Models []string `json:"models,omitempty"` // models to compare, all when empty; /v1/compare only
+	GenerationOptions
+}
+
+// ThinkResponse is the liquid brain's reply
+type ThinkResponse struct {
+	Output     string            `json:"output"`
+	Confidence Confidence        `json:"confidence"`
+	Energy     EnergyReport      `json:"energy"`
+	LimitsHit  []string          `json:"limits_hit,omitempty"`
+	Patterns   []TemporalPattern `json:"patterns,omitempty"` // patterns over the brain's recent inputs
+}
+
+// UnderstandResponse is the transparent model's reply and how it got there
+type UnderstandResponse struct {
+	Output      string       `json:"output"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+	LimitsHit   []string     `json:"limits_hit,omitempty"`
+}
+
+// InferenceHandler serves the inference endpoints. Either model may be nil,
+// turning its endpoint off.
+type InferenceHandler struct {
+	brain    *LiquidStateBrain
+	llm      *TransparentLLM
+	compare  []ComparedModel // models /v1/compare runs; none turns it off
+	spans    *SpanExporter   // exports each request's spans; nil when off
+	recorder *Recorder       // nil unless recording
+	audit    *AuditLog       // nil unless auditing
+	policies CapabilityPolicyConfig
+}
+
+func NewInferenceHandler(brain *LiquidStateBrain, llm *TransparentLLM) *InferenceHandler {
+	return &InferenceHandler{brain: brain, llm: llm}
+}
+
+// SetComparison sets the models /v1/compare runs; none turns it off
+func (h *InferenceHandler) SetComparison(models ...ComparedModel) {
+	h.compare = models
+}
+
+// SetSpanExporter exports each request's OpenInference spans; nil turns
+// export off
+func (h *InferenceHandler) SetSpanExporter(spans *SpanExporter) {
+	h.spans = spans
+}
+
+// SetCapabilityPolicies sets the policies /v1/compare holds its
+// orchestrator to, picked by the caller's API key
+func (h *InferenceHandler) SetCapabilityPolicies(policies CapabilityPolicyConfig) {
+	h.policies = policies
+}
+
+// SetRecorder records every answered think and understand request
+func (h *InferenceHandler) SetRecorder(recorder *Recorder) {
+	h.recorder = recorder
+}
+
+// SetAuditLog appends every answered think and understand request to a
+// hash-chained audit log
+func (h *InferenceHandler) SetAuditLog(audit *AuditLog) {
+	h.audit = audit
+}
+
+// log records and audits a request model answered, logging failures
+// rather than failing the request
+func (h *InferenceHandler) log(r *http.Request, model string, req InferenceRequest, output string, explanation *Explanation) {
+	if h.recorder != nil {
+		recorded := ReplayRequest{Endpoint: r.URL.Path, MessageRequest: MessageRequest{Content: req.Input, GenerationOptions: req.GenerationOptions}}
+		if err := h.recorder.Record("", recorded, output, explanation); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+	if h.audit != nil {
+		if err := h.audit.AppendFrom(model, "", req.Input, output, explanation); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+}
+
+// export exports spans, logging failures rather than failing the request
+func (h *InferenceHandler) export(spans []OpenInferenceSpan) {
+	if err := h.spans.Export(spans); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+}
+
+func (h *InferenceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+	var req InferenceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Input) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "input must not be empty")
+		return
+	}
+	if err := req.GenerationOptions.validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if len(req.Models) > 0 && r.URL.Path != "/v1/compare" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "only /v1/compare takes models")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/think":
+		if h.brain == nil {
+			writeAPIError(w, http.StatusNotFound, "not_found_error", "/v1/think is turned off (inference.think)")
+			return
+		}
+		if req.Stream {
+			writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "only /v1/understand streams")
+			return
+		}
+		h.think(w, r, req)
+	case "/v1/understand":
+		if h.llm == nil {
+			writeAPIError(w, http.StatusNotFound, "not_found_error", "/v1/understand is turned off (inference.understand)")
+			return
+		}
+		h.understand(w, r, req)
+	case "/v1/compare":
+		if len(h.compare) == 0 {
+			writeAPIError(w, http.StatusNotFound, "not_found_error", "/v1/compare is turned off (inference.compare)")
+			return
+		}
+		if req.Stream {
+			writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "only /v1/understand streams")
+			return
+		}
+		h.compareModels(w, r, req)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found_error", "unknown endpoint")
+	}
+}
+
+func (h *InferenceHandler) think(w http.ResponseWriter, r *http.Request, req InferenceRequest) {
+	start := time.Now()
+	resp, err := func() (resp ThinkResponse, err error) {
+		defer recoverError(&err, "liquid brain")
+		budget, err := startBudget(h.brain.config.RequestLimits, Request{Input: req.Input, Seed: req.Seed})
+		if err != nil {
+			return resp, err
+		}
+		// The budget enforces the wall time limit itself, answering with
+		// what it has; only a client that goes away stops the brain
+		resp.Output, resp.Confidence, resp.Energy, err = h.brain.ThinkContext(r.Context(), req.Input, budget.options(req.GenerationOptions))
+		if err != nil {
+			return resp, err
+		}
+		resp.LimitsHit = budget.Hit()
+		resp.Patterns = h.brain.history.Patterns()
+		return resp, nil
+	}()
+	if h.spans != nil {
+		h.export(OpenInferenceSpans("liquid", req.Input, start, Response{Output: resp.Output, Confidence: resp.Confidence, Energy: resp.Energy, LimitsHit: resp.LimitsHit}, nil, err))
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	h.log(r, "liquid", req, resp.Output, nil)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *InferenceHandler) understand(w http.ResponseWriter, r *http.Request, req InferenceRequest) {
+	stream := req.Stream || r.Header.Get("Accept") == "text/event-stream"
+	var events *eventStream
+	if stream {
+		var ok bool
+		if events, ok = newEventStream(w); !ok {
+			writeAPIError(w, http.StatusInternalServerError, "server_error", "streaming is not supported by this connection")
+			return
+		}
+		req.thoughts = func(thought ThoughtTrace) {
+			// The explanation is still being filled in; it arrives with
+			// the response event
+			thought.explanation = nil
+			events.send("thought", thought)
+		}
+	}
+	start := time.Now()
+	var thoughts []TracedThought
+	if h.spans != nil {
+		send := req.thoughts
+		req.thoughts = func(thought ThoughtTrace) {
+			thoughts = append(thoughts, TracedThought{Thought: thought, At: time.Now()})
+			if send != nil {
+				send(thought)
+			}
+		}
+	}
+
+	resp, err := func() (resp UnderstandResponse, err error) {
+		defer recoverError(&err, "transparent model")
+		budget, err := startBudget(h.llm.limits, Request{Input: req.Input, Seed: req.Seed})
+		if err != nil {
+			return resp, err
+		}
+		ctx, cancel := budget.context(r.Context())
+		defer cancel()
+		output, explanation, visualization := h.llm.UnderstandContext(ctx, req.Input, budget.options(req.GenerationOptions))
+		for range visualization {
+			// Drain so the streamer can finish
+		}
+		resp = UnderstandResponse{Output: output, Explanation: explanation, LimitsHit: budget.Hit()}
+		if explanation != nil && explanation.Error != "" {
+			return resp, fmt.Errorf("generation failed: %s", explanation.Error)
+		}
+		return resp, nil
+	}()
+	if h.spans != nil {
+		response := Response{Output: resp.Output, Explanation: resp.Explanation, LimitsHit: resp.LimitsHit}
+		if resp.Explanation != nil {
+			response.Confidence = resp.Explanation.Confidence
+			response.Energy = resp.Explanation.Energy
+		}
+		h.export(OpenInferenceSpans("transparent", req.Input, start, response, thoughts, err))
+	}
+	if err == nil {
+		h.log(r, "transparent", req, resp.Output, resp.Explanation)
+	}
+
+	switch {
+	case stream && err != nil:
+		events.send("error", apiErrorBody{Message: err.Error(), Type: "server_error"})
+	case stream:
+		events.send("response", resp)
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+	default:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (h *InferenceHandler) compareModels(w http.ResponseWriter, r *http.Request, req InferenceRequest) {
+	if len(req.Stop) > 0 || len(req.WordBias) > 0 || req.Diagnostics || req.N > 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "/v1/compare takes only the seed and max_tokens generation options")
+		return
+	}
+	models, err := selectModels(h.compare, req.Models)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	policy, err := h.policies.Policy(h.policies.ForAPIKey(APIKeyName(r)))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	start := time.Now()
+	comparison := CompareModels(r.Context(), models, Request{
+		Input:  req.Input,
+		Seed:   req.Seed,
+		Limits: RequestLimits{MaxTokens: req.MaxTokens},
+		Policy: policy,
+	})
+	if h.spans != nil {
+		h.export(ComparisonSpans(comparison, start))
+	}
+	writeJSON(w, http.StatusOK, comparison)
+}
+
+// eventStream writes server-sent events, flushing each one
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream starts an event stream response, if w can flush
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+// send writes one event with v as its JSON data
+func (s *eventStream) send(event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: failed to encode %s event: %v\n", event, err)
+		return
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data)
+	s.flusher.Flush()
+}
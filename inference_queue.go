@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// InferenceQueue bounds how many inference requests run at once. Requests
+// beyond the concurrency limit wait in a bounded queue; when the queue is
+// full they are rejected immediately, and when they wait longer than the
+// timeout they are shed. Every Think/Understand call fans out into many
+// goroutines, so admitting requests without a bound multiplies goroutines
+// far past ResourceLimits.MaxGoroutines.
+
+var (
+	ErrQueueFull    = errors.New("inference queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for an inference slot")
+)
+
+// InferenceQueue admits a bounded number of concurrent requests
+type InferenceQueue struct {
+	slots    chan struct{}
+	maxQueue int64
+	timeout  time.Duration // 0 waits until the caller's context ends
+	waiting  int64         // atomic
+	shed     int64         // atomic: rejected or timed out
+}
+
+// InferenceQueueStats is a snapshot of queue occupancy
+type InferenceQueueStats struct {
+	Running  int   `json:"running"`
+	Waiting  int   `json:"waiting"`
+	Capacity int   `json:"capacity"`
+	Shed     int64 `json:"shed"`
+}
+
+func NewInferenceQueue(concurrency, maxQueue int, timeout time.Duration) *InferenceQueue {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &InferenceQueue{
+		slots:    make(chan struct{}, concurrency),
+		maxQueue: int64(maxQueue),
+		timeout:  timeout,
+	}
+}
+
+// NewInferenceQueueFromLimits sizes a queue from the resource limits. Its
+// concurrency is drawn from the goroutine budget and held for the life of
+// the process.
+func NewInferenceQueueFromLimits(limits ResourceLimits) *InferenceQueue {
+	concurrency := configureGoroutineBudget(limits).Request(budgetServer, limits.MaxConcurrentRequests, 1)
+	return NewInferenceQueue(concurrency, limits.MaxQueuedRequests,
+		time.Duration(limits.QueueTimeoutMS)*time.Millisecond)
+}
+
+// Acquire waits for a slot. The returned release must be called exactly
+// once when the request finishes.
+func (q *InferenceQueue) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-q.slots }
+
+	// Fast path: a free slot needs no queueing
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if atomic.AddInt64(&q.waiting, 1) > q.maxQueue {
+		atomic.AddInt64(&q.waiting, -1)
+		atomic.AddInt64(&q.shed, 1)
+		return nil, ErrQueueFull
+	}
+	defer atomic.AddInt64(&q.waiting, -1)
+
+	var deadline <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	case <-deadline:
+		atomic.AddInt64(&q.shed, 1)
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Do runs fn once a slot is free
+func (q *InferenceQueue) Do(ctx context.Context, fn func() error) error {
+	release, err := q.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Stats returns current occupancy
+func (q *InferenceQueue) Stats() InferenceQueueStats {
+	return InferenceQueueStats{
+		Running:  len(q.slots),
+		Waiting:  int(atomic.LoadInt64(&q.waiting)),
+		Capacity: cap(q.slots),
+		Shed:     atomic.LoadInt64(&q.shed),
+	}
+}
+
+// Middleware admits requests to next through the queue, answering 429 when
+// the queue is full and 503 when the wait times out
+func (q *InferenceQueue) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, err := q.Acquire(r.Context())
+		switch {
+		case errors.Is(err, ErrQueueFull):
+			w.Header().Set("Retry-After", strconv.Itoa(q.retryAfter()))
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+			return
+		case errors.Is(err, ErrQueueTimeout):
+			w.Header().Set("Retry-After", strconv.Itoa(q.retryAfter()))
+			writeAPIError(w, http.StatusServiceUnavailable, "overloaded_error", err.Error())
+			return
+		case err != nil:
+			// Client went away while queued
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// retryAfter suggests a client backoff in seconds
+func (q *InferenceQueue) retryAfter() int {
+	if secs := int(q.timeout / time.Second); secs > 0 {
+		return secs
+	}
+	return 1
+}
+
+// QueueHealthCheck fails while the queue is shedding: all slots busy and
+// the wait queue full
+func QueueHealthCheck(q *InferenceQueue) HealthCheck {
+	return func(ctx context.Context) ComponentStatus {
+		s := q.Stats()
+		return ComponentStatus{
+			Healthy: s.Running < s.Capacity || int64(s.Waiting) < q.maxQueue,
+			Detail:  fmt.Sprintf("%d/%d running, %d waiting, %d shed", s.Running, s.Capacity, s.Waiting, s.Shed),
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Input hardening: every public input path (Understand, Think, Generate)
+// normalizes its input first, so pathological input can't blow up the
+// pipeline. Invalid UTF-8, control characters and invisible format
+// characters (zero-width spaces and joiners, byte order marks, bidi
+// overrides) are removed, whitespace is collapsed, overlong words are cut,
+// long runs of one repeated token are capped, and the whole input is cut to
+// a bounded length. Panics that still happen while answering are recovered
+// and returned as errors by the models' Respond.
+
+// Limits on normalized input
+const (
+	maxInputRunes     = 2048 // whole input, cut at a word boundary
+	maxInputWordRunes = 64   // one word
+	maxTokenRepeats   = 3    // consecutive copies of one token
+)
+
+// NormalizeInput makes untrusted input safe to process
+func NormalizeInput(input string) string {
+	// Nothing past the rune limit survives, so don't scan it
+	if limit := maxInputRunes * utf8.UTFMax; len(input) > limit {
+		input = input[:limit]
+	}
+	input = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError:
+			return -1
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			return ' '
+		case unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(input, ""))
+
+	var b strings.Builder
+	runes, repeats := 0, 0
+	previous := ""
+	for _, word := range strings.Fields(input) {
+		if n := utf8.RuneCountInString(word); n > maxInputWordRunes {
+			word = string([]rune(word)[:maxInputWordRunes])
+		}
+		if strings.EqualFold(word, previous) {
+			if repeats++; repeats >= maxTokenRepeats {
+				continue
+			}
+		} else {
+			previous, repeats = word, 0
+		}
+		n := utf8.RuneCountInString(word)
+		if runes > 0 {
+			n++
+		}
+		if runes+n > maxInputRunes {
+			break
+		}
+		if runes > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+		runes += n
+	}
+	return b.String()
+}
+
+// recoverError turns a panic in the function deferring it into an error
+func recoverError(err *error, what string) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%s panicked: %v", what, r)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Input history: the liquid brain keeps a sliding buffer of its recent
+// inputs with when they arrived, the concept each was about and how
+// emphatic it was. Readouts over the buffer detect how the conversation is
+// evolving: the same concept repeated, alternation between two concepts,
+// and escalation, each input more emphatic than the last. Detected patterns
+// are returned with the model's response, and with react on the response
+// leads with a remark about the strongest one, worded by the concept
+// schema's pattern templates.
+
+// InputHistoryConfig controls the liquid brain's input history
+type InputHistoryConfig struct {
+	Size     int  `json:"size"`       // inputs kept; 0 turns the history off
+	MaxAgeMS int  `json:"max_age_ms"` // older inputs are forgotten; 0 keeps them
+	React    bool `json:"react"`      // lead responses with a remark on a detected pattern
+}
+
+func (c InputHistoryConfig) validate() error {
+	if c.Size < 0 || c.MaxAgeMS < 0 {
+		return fmt.Errorf("input_history size and max_age_ms must not be negative")
+	}
+	return nil
+}
+
+// Temporal pattern kinds
+const (
+	PatternRepetition  = "repetition"
+	PatternAlternation = "alternation"
+	PatternEscalation  = "escalation"
+)
+
+// Shortest runs that count as a pattern, and the least rise in intensity
+// that counts as escalation
+const (
+	minRepetitionSpan  = 3
+	minAlternationSpan = 4
+	minEscalationSpan  = 3
+	minEscalationRise  = 0.3
+)
+
+// InputEvent is one input in the history
+type InputEvent struct {
+	Input     string    `json:"input"`
+	Concept   string    `json:"concept"`   // meaning or keyword the input is about; "" when unclear
+	Intensity float64   `json:"intensity"` // emphasis, from 0 (calm) to 1
+	At        time.Time `json:"at"`
+}
+
+// TemporalPattern is a pattern over the most recent inputs
+type TemporalPattern struct {
+	Kind     string   `json:"kind"`
+	Concepts []string `json:"concepts,omitempty"` // the repeated concept, or the two alternating ones
+	Span     int      `json:"span"`               // recent inputs the pattern covers
+	Strength float64  `json:"strength"`           // 0 to 1
+}
+
+// InputHistory is a sliding buffer of recent inputs. A nil history records
+// nothing.
+type InputHistory struct {
+	mu     sync.Mutex
+	size   int
+	maxAge time.Duration
+	clock  Clock
+	events []InputEvent // oldest first
+}
+
+// NewInputHistory returns a history for config; nil when it is off
+func NewInputHistory(config InputHistoryConfig, clock Clock) *InputHistory {
+	if config.Size <= 0 {
+		return nil
+	}
+	if clock == nil {
+		clock = RealClock
+	}
+	return &InputHistory{
+		size:   config.Size,
+		maxAge: time.Duration(config.MaxAgeMS) * time.Millisecond,
+		clock:  clock,
+	}
+}
+
+// Record adds an input, stamping it with the current time
+func (h *InputHistory) Record(event InputEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event.At = h.clock.Now()
+	h.events = append(h.events, event)
+	if len(h.events) > h.size {
+		h.events = append(h.events[:0], h.events[len(h.events)-h.size:]...)
+	}
+}
+
+// Recent returns the inputs still in the history, oldest first
+func (h *InputHistory) Recent() []InputEvent {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.maxAge > 0 {
+		now := h.clock.Now()
+		i := 0
+		for i < len(h.events) && now.Sub(h.events[i].At) > h.maxAge {
+			i++
+		}
+		h.events = append(h.events[:0], h.events[i:]...)
+	}
+	return append([]InputEvent(nil), h.events...)
+}
+
+// Reset forgets every input
+func (h *InputHistory) Reset() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = nil
+}
+
+// Patterns returns the patterns over the recent inputs, escalation first,
+// then repetition, then alternation
+func (h *InputHistory) Patterns() []TemporalPattern {
+	return detectPatterns(h.Recent())
+}
+
+// detectPatterns finds the patterns ending at the latest of events
+func detectPatterns(events []InputEvent) []TemporalPattern {
+	var patterns []TemporalPattern
+	for _, detect := range []func([]InputEvent) (TemporalPattern, bool){detectEscalation, detectRepetition, detectAlternation} {
+		if p, ok := detect(events); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// detectRepetition finds the latest inputs sharing one concept
+func detectRepetition(events []InputEvent) (TemporalPattern, bool) {
+	n := len(events)
+	if n == 0 || events[n-1].Concept == "" {
+		return TemporalPattern{}, false
+	}
+	span := 1
+	for span < n && events[n-1-span].Concept == events[n-1].Concept {
+		span++
+	}
+	if span < minRepetitionSpan {
+		return TemporalPattern{}, false
+	}
+	return TemporalPattern{
+		Kind:     PatternRepetition,
+		Concepts: []string{events[n-1].Concept},
+		Span:     span,
+		Strength: float64(span) / float64(n),
+	}, true
+}
+
+// detectAlternation finds the latest inputs switching between two concepts
+func detectAlternation(events []InputEvent) (TemporalPattern, bool) {
+	n := len(events)
+	if n < 2 {
+		return TemporalPattern{}, false
+	}
+	a, b := events[n-1].Concept, events[n-2].Concept
+	if a == "" || b == "" || a == b {
+		return TemporalPattern{}, false
+	}
+	span := 2
+	for span < n && events[n-1-span].Concept == events[n-1-span+2].Concept {
+		span++
+	}
+	if span < minAlternationSpan {
+		return TemporalPattern{}, false
+	}
+	return TemporalPattern{
+		Kind:     PatternAlternation,
+		Concepts: []string{b, a},
+		Span:     span,
+		Strength: float64(span) / float64(n),
+	}, true
+}
+
+// detectEscalation finds the latest inputs each more intense than the last
+func detectEscalation(events []InputEvent) (TemporalPattern, bool) {
+	n := len(events)
+	if n == 0 {
+		return TemporalPattern{}, false
+	}
+	span := 1
+	for span < n && events[n-1-span].Intensity < events[n-span].Intensity {
+		span++
+	}
+	rise := events[n-1].Intensity - events[n-span].Intensity
+	if span < minEscalationSpan || rise < minEscalationRise {
+		return TemporalPattern{}, false
+	}
+	return TemporalPattern{Kind: PatternEscalation, Span: span, Strength: math.Min(1, rise)}, true
+}
+
+// inputIntensity measures how emphatic input is from its exclamation marks
+// and shouted (all-caps) words: 0 for neither, 1 for four or more
+func inputIntensity(input string) float64 {
+	emphasis := strings.Count(input, "!")
+	for _, word := range strings.Fields(input) {
+		letters, upper := 0, 0
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		if letters >= 2 && upper == letters {
+			emphasis++
+		}
+	}
+	return math.Min(1, float64(emphasis)/4)
+}
+
+// inputConcept names what input is about: the schema meaning whose match
+// words it uses most, or else its strongest keyword
+func (s *ConceptSchema) inputConcept(input string, keywords map[string]float64) string {
+	words := strings.Fields(strings.ToLower(input))
+	for i, word := range words {
+		words[i] = strings.Trim(word, ".,;:!?\"'()")
+	}
+
+	best, bestCount := "", 0
+	for _, m := range s.orDefault().Meanings {
+		count := 0
+		for _, word := range words {
+			for _, match := range m.MatchWords {
+				if word == match {
+					count++
+				}
+			}
+		}
+		if count > bestCount {
+			best, bestCount = m.Name, count
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	weight := 0.0
+	for _, word := range words {
+		if keywords[word] > weight {
+			best, weight = word, keywords[word]
+		}
+	}
+	return best
+}
+
+// patternRemark words a pattern with the schema's templates; "" when the
+// schema has none for it
+func (s *ConceptSchema) patternRemark(p TemporalPattern) string {
+	s = s.orDefault()
+	switch p.Kind {
+	case PatternRepetition:
+		if s.RepetitionTemplate != "" && len(p.Concepts) == 1 {
+			return fmt.Sprintf(s.RepetitionTemplate, p.Concepts[0])
+		}
+	case PatternAlternation:
+		if s.AlternationTemplate != "" && len(p.Concepts) == 2 {
+			return fmt.Sprintf(s.AlternationTemplate, p.Concepts[0], p.Concepts[1])
+		}
+	case PatternEscalation:
+		return s.EscalationTemplate
+	}
+	return ""
+}
+
+// noteInput records input in the brain's history
+func (brain *LiquidStateBrain) noteInput(input string, keywords map[string]float64) {
+	if brain.history == nil {
+		return
+	}
+	brain.history.Record(InputEvent{
+		Input:     input,
+		Concept:   brain.schema.inputConcept(input, keywords),
+		Intensity: inputIntensity(input),
+	})
+}
+
+// reactToPatterns leads response with a remark on the strongest pattern in
+// the input history when reacting is on
+func (brain *LiquidStateBrain) reactToPatterns(response string) string {
+	if brain.history == nil || !brain.config.InputHistory.React {
+		return response
+	}
+	for _, p := range brain.history.Patterns() {
+		if remark := brain.schema.patternRemark(p); remark != "" {
+			fmt.Printf("🔁 Input pattern: %s (span %d)\n", p.Kind, p.Span)
+			return remark + " " + response
+		}
+	}
+	return response
+}
+
+// InputHistory returns the brain's input history; nil when it is off
+func (brain *LiquidStateBrain) InputHistory() *InputHistory {
+	return brain.history
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Keyphrase extraction combines RAKE and TF-IDF. Text is cut into candidate
+// phrases at punctuation and stopwords (RAKE); each word is scored by
+// degree/frequency within the text, weighted by its inverse passage
+// frequency in the corpus, and a phrase scores the sum of its words.
+// Passages are sentences rather than files so IDF stays meaningful when the
+// corpus is a single document.
+
+// Longest candidate phrase kept; longer runs are rarely real keyphrases
+const maxKeyphraseWords = 4
+
+// Keyphrases extracted from each input to steer injection and topic memory
+const inputKeyphrases = 5
+
+// Topic memory weight given to the strongest input keyword
+const keyphraseTopicWeight = 0.8
+
+// Extra injection strength for the strongest input keyword
+const keywordInjectionGain = 0.5
+
+// Keyphrase is a scored phrase from ExtractKeyphrases
+type Keyphrase struct {
+	Phrase string   `json:"phrase"`
+	Words  []string `json:"words"`
+	Score  float64  `json:"score"`
+}
+
+// buildPassageFrequencies counts, for every word, how many corpus sentences
+// contain it
+func (dl *DatasetLoader) buildPassageFrequencies() {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.passageFreq = make(map[string]int)
+	dl.passageCount = 0
+	for _, doc := range dl.documents {
+		dl.countPassages(doc.Content)
+	}
+}
+
+// countPassages adds the sentences of content to the passage frequencies.
+// Callers must hold dl.mu.
+func (dl *DatasetLoader) countPassages(content string) {
+	for _, sentence := range splitSentences(content) {
+		seen := make(map[string]bool)
+		for _, token := range dl.tokenize(sentence) {
+			if !seen[token] {
+				seen[token] = true
+				dl.passageFreq[token]++
+			}
+		}
+		dl.passageCount++
+	}
+}
+
+// idf returns the smoothed inverse passage frequency of word
+func (dl *DatasetLoader) idf(word string) float64 {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	return math.Log(float64(dl.passageCount+1)/float64(dl.passageFreq[word]+1)) + 1
+}
+
+// candidatePhrases splits text into runs of content words, breaking at
+// punctuation and stopwords
+func (dl *DatasetLoader) candidatePhrases(text string) [][]string {
+	fragments := strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune(".,;:!?()[]{}\"\n\t", r)
+	})
+
+	var phrases [][]string
+	for _, fragment := range fragments {
+		var current []string
+		flush := func() {
+			if len(current) > 0 && len(current) <= maxKeyphraseWords {
+				phrases = append(phrases, current)
+			}
+			current = nil
+		}
+		for _, token := range dl.tokenize(fragment) {
+			if stopWords[token] || len(token) < 3 {
+				flush()
+				continue
+			}
+			current = append(current, token)
+		}
+		flush()
+	}
+	return phrases
+}
+
+// ExtractKeyphrases returns the n highest scoring keyphrases of text, best
+// first. Repeated phrases are merged.
+func (dl *DatasetLoader) ExtractKeyphrases(text string, n int) []Keyphrase {
+	phrases := dl.candidatePhrases(text)
+	if len(phrases) == 0 || n <= 0 {
+		return nil
+	}
+
+	// RAKE word scores: co-occurrence degree over frequency
+	freq := make(map[string]float64)
+	degree := make(map[string]float64)
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			freq[word]++
+			degree[word] += float64(len(phrase))
+		}
+	}
+
+	scores := make(map[string]*Keyphrase)
+	for _, phrase := range phrases {
+		key := strings.Join(phrase, " ")
+		if _, seen := scores[key]; seen {
+			continue
+		}
+		score := 0.0
+		for _, word := range phrase {
+			score += degree[word] / freq[word] * dl.idf(word)
+		}
+		scores[key] = &Keyphrase{Phrase: key, Words: phrase, Score: score}
+	}
+
+	result := make([]Keyphrase, 0, len(scores))
+	for _, kp := range scores {
+		result = append(result, *kp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Phrase < result[j].Phrase
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// CorpusKeyphrases returns the n top keyphrases across all loaded documents
+func (dl *DatasetLoader) CorpusKeyphrases(n int) []Keyphrase {
+	docs := dl.GetDocuments()
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Content
+	}
+	return dl.ExtractKeyphrases(strings.Join(texts, "\n"), n)
+}
+
+// keywordWeights flattens keyphrases into per-word weights normalized so the
+// best word scores 1
+func keywordWeights(keyphrases []Keyphrase) map[string]float64 {
+	weights := make(map[string]float64)
+	best := 0.0
+	for _, kp := range keyphrases {
+		for _, word := range kp.Words {
+			if kp.Score > weights[word] {
+				weights[word] = kp.Score
+			}
+			if kp.Score > best {
+				best = kp.Score
+			}
+		}
+	}
+	if best > 0 {
+		for word := range weights {
+			weights[word] /= best
+		}
+	}
+	return weights
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Knowledge store: facts learned from what users say, kept as triples
+// (subject, relation, object) with a confidence and where they came from,
+// in a SQLite database so they outlive the process. The session API and
+// the transparent model extract first-person statements from each input
+// ("my project uses Go" becomes user's project / uses / Go), and
+// statements about named things ("Acme is a startup"), and assert them; asserting a fact again raises its confidence. Facts are scoped to
+// the session that taught them, or global (session "") and visible to
+// every session, depending on knowledge.scope. When a question asks about
+// something the store knows ("what does my project use?") the generator
+// answers from the recalled facts instead of the corpus, and the
+// explanation lists them.
+
+// KnowledgeConfig controls the knowledge store
+type KnowledgeConfig struct {
+	Path          string  `json:"path"`           // SQLite database file; "" turns the store off
+	Scope         string  `json:"scope"`          // "session" keeps facts per session, "global" shares them
+	MinConfidence float64 `json:"min_confidence"` // facts below it aren't recalled
+}
+
+// Knowledge scopes
+const (
+	KnowledgeSession = "session"
+	KnowledgeGlobal  = "global"
+)
+
+func (c KnowledgeConfig) validate() error {
+	switch c.Scope {
+	case "", KnowledgeSession, KnowledgeGlobal:
+	default:
+		return fmt.Errorf("unknown knowledge scope %q (want %s or %s)", c.Scope, KnowledgeSession, KnowledgeGlobal)
+	}
+	if c.MinConfidence < 0 || c.MinConfidence > 1 {
+		return fmt.Errorf("knowledge min_confidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// session returns the session facts learned in sessionID are kept under
+func (c KnowledgeConfig) session(sessionID string) string {
+	if c.Scope == KnowledgeGlobal {
+		return ""
+	}
+	return sessionID
+}
+
+// Fact is a learned (subject, relation, object) triple
+type Fact struct {
+	Subject    string    `json:"subject"`  // lowercase; "user" is whoever is talking
+	Relation   string    `json:"relation"` // lowercase, third person: "is", "uses", "works at"
+	Object     string    `json:"object"`   // as it was said
+	Confidence float64   `json:"confidence"`
+	Provenance string    `json:"provenance,omitempty"` // where the fact came from
+	Session    string    `json:"session,omitempty"`    // "" for global facts
+	Updated    time.Time `json:"updated"`
+}
+
+// Sentence states the fact to the user it is about
+func (f Fact) Sentence() string {
+	subject, relation := f.Subject, f.Relation
+	switch {
+	case subject == "user":
+		subject, relation = "you", secondPerson(relation)
+	case strings.HasPrefix(subject, "user's "):
+		subject = "your " + strings.TrimPrefix(subject, "user's ")
+	}
+	sentence := fmt.Sprintf("%s %s %s.", subject, relation, f.Object)
+	return strings.ToUpper(sentence[:1]) + sentence[1:]
+}
+
+// secondPerson turns a third-person relation into the form used with "you"
+func secondPerson(relation string) string {
+	verb, rest, _ := strings.Cut(relation, " ")
+	switch verb {
+	case "is":
+		verb = "are"
+	case "has":
+		verb = "have"
+	default:
+		verb = strings.TrimSuffix(verb, "s")
+	}
+	return strings.TrimSpace(verb + " " + rest)
+}
+
+// Confidence of facts extracted from statements, by how they were said
+const (
+	possessiveFactConfidence  = 0.8 // "my project uses Go"
+	firstPersonFactConfidence = 0.7 // "I work at Acme"
+	identityFactConfidence    = 0.6 // "I'm a teacher", which is often just small talk
+	namedFactConfidence       = 0.6 // "Acme is a startup", which the user may only have heard
+)
+
+var (
+	possessiveFact  = regexp.MustCompile(`(?i)^(?:my|our)\s+([a-z]+(?:\s+[a-z]+)?)\s+(is called|is named|runs on|is|are|uses|use|has|have|likes|like|needs|need)\s+(.+)$`)
+	firstPersonFact = regexp.MustCompile(`(?i)^i\s+(work at|work on|work for|live in|like|love|use|prefer|speak|need|have)\s+(.+)$`)
+	identityFact    = regexp.MustCompile(`(?i)^i(?:'m|’m|\s+am)\s+(.+)$`)
+	namedFact       = regexp.MustCompile(`^([A-Z][A-Za-z0-9]*(?:\s+[A-Z][A-Za-z0-9]*)*)\s+(is called|is named|runs on|is|are|uses|use|has|have|likes|like|needs|need|makes|builds)\s+(.+)$`)
+	negation        = regexp.MustCompile(`(?i)\b(?:not|never|no)\b|n't\b`)
+)
+
+// thirdPerson normalizes a matched verb phrase to the form stored
+var thirdPerson = map[string]string{
+	"are": "is", "use": "uses", "have": "has", "like": "likes", "love": "likes", "need": "needs",
+	"work at": "works at", "work on": "works on", "work for": "works for", "live in": "lives in",
+	"prefer": "prefers", "speak": "speaks",
+}
+
+// ExtractFacts finds the facts stated in input. Questions and negated
+// statements state nothing.
+func ExtractFacts(input string) []Fact {
+	var facts []Fact
+	for _, sentence := range splitStatements(input) {
+		if negation.MatchString(sentence) {
+			continue
+		}
+		fact, ok := extractFact(sentence)
+		if !ok {
+			continue
+		}
+		fact.Object = strings.TrimRight(strings.TrimSpace(fact.Object), ".,;:!")
+		if fact.Object == "" {
+			continue
+		}
+		fact.Provenance = fmt.Sprintf("said %q", sentence)
+		facts = append(facts, fact)
+	}
+	return facts
+}
+
+// extractFact matches one statement against the fact patterns
+func extractFact(sentence string) (Fact, bool) {
+	relation := func(verb string) string {
+		verb = strings.ToLower(strings.Join(strings.Fields(verb), " "))
+		if normalized, ok := thirdPerson[verb]; ok {
+			return normalized
+		}
+		return verb
+	}
+	if m := possessiveFact.FindStringSubmatch(sentence); m != nil {
+		return Fact{Subject: "user's " + strings.ToLower(m[1]), Relation: relation(m[2]), Object: m[3], Confidence: possessiveFactConfidence}, true
+	}
+	if m := firstPersonFact.FindStringSubmatch(sentence); m != nil {
+		return Fact{Subject: "user", Relation: relation(m[1]), Object: m[2], Confidence: firstPersonFactConfidence}, true
+	}
+	if m := identityFact.FindStringSubmatch(sentence); m != nil {
+		return Fact{Subject: "user", Relation: "is", Object: m[1], Confidence: identityFactConfidence}, true
+	}
+	if m := namedFact.FindStringSubmatch(sentence); m != nil && entityName(m[1]) == m[1] {
+		return Fact{Subject: strings.ToLower(m[1]), Relation: relation(m[2]), Object: m[3], Confidence: namedFactConfidence}, true
+	}
+	return Fact{}, false
+}
+
+// splitStatements splits input into sentences, leaving out questions
+func splitStatements(input string) []string {
+	var statements []string
+	start := 0
+	for i, r := range input {
+		if r != '.' && r != '!' && r != '?' && r != '\n' {
+			continue
+		}
+		if sentence := strings.TrimSpace(input[start:i]); sentence != "" && r != '?' {
+			statements = append(statements, sentence)
+		}
+		start = i + 1
+	}
+	if sentence := strings.TrimSpace(input[start:]); sentence != "" {
+		statements = append(statements, sentence)
+	}
+	return statements
+}
+
+// KnowledgeStore keeps facts in SQLite. A nil store knows nothing.
+type KnowledgeStore struct {
+	db            *sql.DB
+	minConfidence float64
+}
+
+// Schema version stored in the database's user_version
+const knowledgeSchemaVersion = 1
+
+const knowledgeSchema = `
+CREATE TABLE IF NOT EXISTS facts (
+	session    TEXT NOT NULL DEFAULT '',
+	subject    TEXT NOT NULL,
+	relation   TEXT NOT NULL,
+	object     TEXT NOT NULL COLLATE NOCASE,
+	confidence REAL NOT NULL,
+	provenance TEXT NOT NULL DEFAULT '',
+	created    INTEGER NOT NULL,
+	updated    INTEGER NOT NULL,
+	PRIMARY KEY (session, subject, relation, object)
+);
+CREATE INDEX IF NOT EXISTS facts_by_subject ON facts (subject, session);
+`
+
+// OpenKnowledgeStore opens or creates the SQLite database at config.Path
+func OpenKnowledgeStore(config KnowledgeConfig) (*KnowledgeStore, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(sqliteDriver, config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open knowledge store: %w", err)
+	}
+	// SQLite allows one writer; one connection keeps writers from failing
+	// with "database is locked"
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open knowledge store %s: %w", config.Path, err)
+	}
+	if version > knowledgeSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("knowledge store %s has schema version %d, newer than %d", config.Path, version, knowledgeSchemaVersion)
+	}
+	if _, err := db.Exec(knowledgeSchema + fmt.Sprintf("PRAGMA user_version = %d;", knowledgeSchemaVersion)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create knowledge schema: %w", err)
+	}
+	return &KnowledgeStore{db: db, minConfidence: config.MinConfidence}, nil
+}
+
+// Assert records fact. A fact already known gains confidence: the two
+// confidences combine as independent evidence, 1 - (1-a)(1-b).
+func (s *KnowledgeStore) Assert(fact Fact) error {
+	if s == nil {
+		return nil
+	}
+	if fact.Subject == "" || fact.Relation == "" || fact.Object == "" {
+		return fmt.Errorf("facts need a subject, relation and object")
+	}
+	if fact.Confidence <= 0 || fact.Confidence > 1 {
+		return fmt.Errorf("fact confidence must be in (0, 1], got %v", fact.Confidence)
+	}
+	now := time.Now().UnixNano()
+	_, err := s.db.Exec(`
+		INSERT INTO facts (session, subject, relation, object, confidence, provenance, created, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session, subject, relation, object) DO UPDATE SET
+			confidence = 1 - (1 - confidence) * (1 - excluded.confidence),
+			provenance = excluded.provenance,
+			updated    = excluded.updated`,
+		fact.Session, strings.ToLower(fact.Subject), strings.ToLower(fact.Relation), fact.Object,
+		fact.Confidence, fact.Provenance, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to store fact: %w", err)
+	}
+	return nil
+}
+
+// Learn asserts the facts stated in input under session, returning them
+func (s *KnowledgeStore) Learn(session, input string) ([]Fact, error) {
+	if s == nil {
+		return nil, nil
+	}
+	facts := ExtractFacts(input)
+	for i := range facts {
+		facts[i].Session = session
+		if err := s.Assert(facts[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(facts) > 0 {
+		fmt.Printf("📝 Learned %d fact(s)\n", len(facts))
+	}
+	return facts, nil
+}
+
+// Query returns the facts about subject visible to session, its own and
+// the global ones, most confident and then most recent first. An empty
+// relation matches every relation.
+func (s *KnowledgeStore) Query(session, subject, relation string) ([]Fact, error) {
+	if s == nil {
+		return nil, nil
+	}
+	query := `
+		SELECT session, subject, relation, object, confidence, provenance, updated FROM facts
+		WHERE subject = ? AND (session = ? OR session = '') AND confidence >= ?`
+	args := []any{strings.ToLower(subject), session, s.minConfidence}
+	if relation != "" {
+		query += " AND relation = ?"
+		args = append(args, strings.ToLower(relation))
+	}
+	return s.query(query+" ORDER BY confidence DESC, updated DESC", args...)
+}
+
+// Facts returns every fact visible to session
+func (s *KnowledgeStore) Facts(session string) ([]Fact, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return s.query(`
+		SELECT session, subject, relation, object, confidence, provenance, updated FROM facts
+		WHERE session = ? OR session = '' ORDER BY subject, confidence DESC`, session)
+}
+
+func (s *KnowledgeStore) query(query string, args ...any) ([]Fact, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []Fact
+	for rows.Next() {
+		var f Fact
+		var updated int64
+		if err := rows.Scan(&f.Session, &f.Subject, &f.Relation, &f.Object, &f.Confidence, &f.Provenance, &updated); err != nil {
+			return nil, fmt.Errorf("failed to read fact: %w", err)
+		}
+		f.Updated = time.Unix(0, updated)
+		facts = append(facts, f)
+	}
+	return facts, rows.Err()
+}
+
+// Forget deletes the facts kept for session; global facts stay
+func (s *KnowledgeStore) Forget(session string) error {
+	if s == nil || session == "" {
+		return nil
+	}
+	if _, err := s.db.Exec("DELETE FROM facts WHERE session = ?", session); err != nil {
+		return fmt.Errorf("failed to forget facts: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *KnowledgeStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Facts recalled to answer one question
+const maxRecalledFacts = 3
+
+// questionSubjects finds what a question asks about: "my X" asks about
+// the user's X, a name about what it names, and "I" or "me" about the
+// user
+var (
+	possessiveQuestion  = regexp.MustCompile(`(?i)\b(?:my|our)\s+([a-z]+(?:\s+[a-z]+)?)`)
+	firstPersonQuestion = regexp.MustCompile(`(?i)\b(?:i|me|am i|do i)\b`)
+)
+
+// Recall returns the facts visible to session that answer question, or
+// nil when it isn't a question about something the store knows
+func (s *KnowledgeStore) Recall(session, question string) ([]Fact, error) {
+	if s == nil {
+		return nil, nil
+	}
+	words := strings.Fields(strings.ToLower(question))
+	for i, word := range words {
+		words[i] = strings.Trim(word, ".,;:!?\"'()")
+	}
+
+	var subjects []string
+	if m := possessiveQuestion.FindStringSubmatch(question); m != nil {
+		// "my project use" has to try "user's project" too
+		noun := strings.Fields(strings.ToLower(m[1]))
+		subjects = append(subjects, "user's "+strings.Join(noun, " "))
+		if len(noun) > 1 {
+			subjects = append(subjects, "user's "+noun[0])
+		}
+	}
+	for _, name := range namedEntities(question) {
+		subjects = append(subjects, strings.ToLower(name))
+	}
+	if len(subjects) == 0 && firstPersonQuestion.MatchString(question) {
+		subjects = append(subjects, "user")
+	}
+
+	for _, subject := range subjects {
+		facts, err := s.Query(session, subject, "")
+		if err != nil || len(facts) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Prefer facts whose relation the question names
+		asked := func(f Fact) bool {
+			verb, _, _ := strings.Cut(f.Relation, " ")
+			for _, word := range words {
+				if word == verb || word == strings.TrimSuffix(verb, "s") || word == secondPerson(verb) {
+					return true
+				}
+			}
+			return false
+		}
+		sort.SliceStable(facts, func(i, j int) bool { return asked(facts[i]) && !asked(facts[j]) })
+		if asked(facts[0]) {
+			kept := facts[:0]
+			for _, f := range facts {
+				if asked(f) {
+					kept = append(kept, f)
+				}
+			}
+			facts = kept
+		}
+		if len(facts) > maxRecalledFacts {
+			facts = facts[:maxRecalledFacts]
+		}
+		return facts, nil
+	}
+	return nil, nil
+}
+
+// SetKnowledgeStore has questions the store knows about answered from it
+func (gen *ResponseGenerator) SetKnowledgeStore(store *KnowledgeStore) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.knowledge = store
+}
+
+// recallFacts returns the facts answering a question input, from the
+// current call's session. It runs on the call's copy of the generator.
+func (gen *ResponseGenerator) recallFacts(input string) []Fact {
+	if gen.knowledge == nil || !gen.isQuestionInput(input) {
+		return nil
+	}
+	facts, err := gen.knowledge.Recall(gen.factSession, input)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+		return nil
+	}
+	return facts
+}
+
+// factAnswer states the recalled facts
+func factAnswer(facts []Fact) string {
+	sentences := make([]string, len(facts))
+	for i, f := range facts {
+		sentences[i] = f.Sentence()
+	}
+	return strings.Join(sentences, " ")
+}
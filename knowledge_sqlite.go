@@ -0,0 +1,8 @@
+package main
+
+// The knowledge store's SQLite driver. It is the only third-party
+// dependency; building it needs cgo, and without cgo opening the store
+// fails with an error instead.
+import _ "github.com/mattn/go-sqlite3"
+
+const sqliteDriver = "sqlite3"
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Language detection tags documents and inputs with an ISO 639-1 code.
+// Non-Latin scripts decide the language outright; Latin-script text is
+// scored by how many of each language's most common function words it
+// contains. When a corpus mixes languages the loader keeps a vocabulary and
+// transition table per language, and generation stays inside the input's
+// language so mixed corpora don't produce macaronic output.
+
+// languageUnknown tags text whose language couldn't be determined
+const languageUnknown = "und"
+
+// languageStopwords lists frequent function words per Latin-script language
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "that", "it", "you", "for", "with", "this", "have", "not", "what", "how", "on", "be", "but", "they", "we", "my", "your", "can", "hello"},
+	"es": {"el", "los", "las", "es", "está", "que", "en", "un", "una", "por", "para", "con", "como", "pero", "muy", "qué", "hola", "estás", "soy", "yo", "su", "del", "al", "cómo", "más"},
+	"fr": {"le", "les", "est", "et", "une", "des", "du", "qui", "pas", "pour", "dans", "avec", "je", "vous", "nous", "il", "elle", "ce", "sur", "bonjour", "comment", "ça", "suis", "au", "mais"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "du", "sie", "wir", "mit", "auf", "für", "zu", "den", "dem", "von", "wie", "was", "es", "im", "auch", "hallo", "bin"},
+	"it": {"il", "lo", "gli", "di", "che", "è", "per", "non", "sono", "come", "ciao", "io", "lei", "della", "questo", "anche", "ma", "mi", "sei", "sta", "nel", "alla", "molto"},
+	"pt": {"os", "é", "não", "um", "uma", "do", "da", "em", "com", "eu", "você", "olá", "muito", "mas", "isso", "seu", "sua", "tudo", "bem", "está", "obrigado", "na", "no"},
+	"nl": {"het", "een", "niet", "ik", "je", "van", "dat", "met", "op", "voor", "zijn", "wat", "hoe", "ook", "maar", "wij", "er", "te", "hallo", "gaat", "goed"},
+}
+
+// languageScripts maps scripts that identify a language on their own
+var languageScripts = []struct {
+	lang   string
+	tables []*unicode.RangeTable
+}{
+	{"ru", []*unicode.RangeTable{unicode.Cyrillic}},
+	{"el", []*unicode.RangeTable{unicode.Greek}},
+	{"ar", []*unicode.RangeTable{unicode.Arabic}},
+	{"he", []*unicode.RangeTable{unicode.Hebrew}},
+	{"ja", []*unicode.RangeTable{unicode.Hiragana, unicode.Katakana}},
+	{"ko", []*unicode.RangeTable{unicode.Hangul}},
+	{"zh", []*unicode.RangeTable{unicode.Han}},
+}
+
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range languageStopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
+
+// maxLanguageSampleTokens bounds how much of a document is examined
+const maxLanguageSampleTokens = 5000
+
+// DetectLanguage returns the language of text and a confidence in [0, 1],
+// or languageUnknown when no language stands out
+func DetectLanguage(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	return detectTokensLanguage(words)
+}
+
+// detectTokensLanguage is DetectLanguage for lower-case tokens
+func detectTokensLanguage(tokens []string) (string, float64) {
+	if len(tokens) > maxLanguageSampleTokens {
+		tokens = tokens[:maxLanguageSampleTokens]
+	}
+
+	// A dominant non-Latin script settles it; Japanese text mixes kana
+	// with Han, so any kana outweighs Han
+	letters := 0
+	scripts := make(map[string]int)
+	for _, token := range tokens {
+		for _, r := range token {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			for _, s := range languageScripts {
+				if unicode.In(r, s.tables...) {
+					scripts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	for _, s := range languageScripts {
+		if n := scripts[s.lang]; letters > 0 && n*2 > letters {
+			return s.lang, float64(n) / float64(letters)
+		}
+	}
+
+	hits := make(map[string]int)
+	total := 0
+	for _, token := range tokens {
+		for _, lang := range stopwordLanguages[token] {
+			hits[lang]++
+			total++
+		}
+	}
+	best, bestHits, secondHits := "", 0, 0
+	for _, lang := range sortedKeys(hits) {
+		if n := hits[lang]; n > bestHits {
+			best, bestHits, secondHits = lang, n, bestHits
+		} else if n > secondHits {
+			secondHits = n
+		}
+	}
+	if bestHits == 0 || bestHits == secondHits {
+		return languageUnknown, 0
+	}
+	return best, float64(bestHits) / float64(total)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// languageModel holds the vocabulary and transitions of one language in a
+// multilingual corpus
+type languageModel struct {
+	vocab       map[string]struct{}
+	transitions map[string]map[string]float64
+	starters    map[string]float64
+}
+
+// Languages returns how many documents were tagged with each language
+func (dl *DatasetLoader) Languages() map[string]int {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, doc := range dl.documents {
+		counts[doc.Language]++
+	}
+	return counts
+}
+
+// GenerationLanguage returns the language generation for input should
+// stay in, or "" when it is unconstrained because the corpus has a single
+// language or the input's language isn't one of the corpus languages
+func (dl *DatasetLoader) GenerationLanguage(input string) string {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	if len(dl.languages) == 0 {
+		return ""
+	}
+	lang, _ := DetectLanguage(input)
+	if _, ok := dl.languages[lang]; !ok {
+		return ""
+	}
+	return lang
+}
+
+func (dl *DatasetLoader) languageModel(lang string) *languageModel {
+	if lang == "" {
+		return nil
+	}
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+	return dl.languages[lang]
+}
+
+// GetTransitionsIn is GetTransitions restricted to lang's documents; an
+// empty lang means all documents
+func (dl *DatasetLoader) GetTransitionsIn(lang, word string) (map[string]float64, bool) {
+	lm := dl.languageModel(lang)
+	if lm == nil {
+		return dl.GetTransitions(word)
+	}
+	transitions, ok := lm.transitions[word]
+	if !ok {
+		return nil, false
+	}
+	return dl.transitionsView(transitions), true
+}
+
+// InLanguage reports whether word occurs in lang's documents; an empty
+// lang means the whole vocabulary
+func (dl *DatasetLoader) InLanguage(lang, word string) bool {
+	lm := dl.languageModel(lang)
+	if lm == nil {
+		return dl.InVocabulary(word)
+	}
+	_, ok := lm.vocab[word]
+	return ok
+}
+
+// GetStarterWordIn returns the most common sentence starter of lang's
+// documents; an empty lang means all documents
+func (dl *DatasetLoader) GetStarterWordIn(lang string) string {
+	lm := dl.languageModel(lang)
+	if lm == nil || len(lm.starters) == 0 {
+		return dl.GetStarterWord()
+	}
+	best := ""
+	for word, count := range lm.starters {
+		if best == "" || count > lm.starters[best] || (count == lm.starters[best] && word < best) {
+			best = word
+		}
+	}
+	return best
+}
+
+// buildLanguageModels splits the vocabulary and transitions by document
+// language when the corpus has more than one. Callers must hold dl.mu.
+func (dl *DatasetLoader) buildLanguageModels() {
+	tagged := make(map[string]bool)
+	for _, doc := range dl.documents {
+		if doc.Language != languageUnknown {
+			tagged[doc.Language] = true
+		}
+	}
+	if len(tagged) < 2 {
+		dl.languages = nil
+		return
+	}
+
+	dl.languages = make(map[string]*languageModel, len(tagged))
+	for lang := range tagged {
+		dl.languages[lang] = &languageModel{
+			vocab:       make(map[string]struct{}),
+			transitions: make(map[string]map[string]float64),
+			starters:    make(map[string]float64),
+		}
+	}
+	for _, doc := range dl.documents {
+		lm := dl.languages[doc.Language]
+		if lm == nil {
+			continue
+		}
+		tokens := doc.Tokens
+		if len(tokens) > 0 {
+			lm.starters[tokens[0]]++
+		}
+		for i, token := range tokens {
+			if _, ok := dl.vocabulary[token]; !ok {
+				continue
+			}
+			lm.vocab[token] = struct{}{}
+			if i+1 < len(tokens) {
+				if _, ok := dl.vocabulary[tokens[i+1]]; ok {
+					next := lm.transitions[token]
+					if next == nil {
+						next = make(map[string]float64)
+						lm.transitions[token] = next
+					}
+					next[tokens[i+1]]++
+				}
+			}
+		}
+	}
+	for _, lm := range dl.languages {
+		for _, next := range lm.transitions {
+			total := 0.0
+			for _, count := range next {
+				total += count
+			}
+			for word, count := range next {
+				next[word] = count / total
+			}
+		}
+	}
+}
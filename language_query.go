@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Language model queries: read-only inspection of the frequencies and
+// transition tables generation actually draws from, for dashboards and
+// evaluation tooling. Results are fresh slices the caller owns.
+
+// WordProbability is one word of a distribution
+type WordProbability struct {
+	Word        string  `json:"word"`
+	Probability float64 `json:"probability"`
+}
+
+// GetWordFrequency returns how many times word occurs in the corpus
+func (dl *DatasetLoader) GetWordFrequency(word string) int {
+	return int(dl.wordFrequency(strings.ToLower(word)))
+}
+
+// GetTopTransitions returns the k most likely words to follow word, most
+// likely first; k <= 0 returns them all
+func (dl *DatasetLoader) GetTopTransitions(word string, k int) []WordProbability {
+	transitions, ok := dl.GetTransitions(strings.ToLower(word))
+	if !ok {
+		return nil
+	}
+	return rankDistribution(transitions, k)
+}
+
+// GetStarterDistribution returns the probability of each word starting a
+// document, most likely first
+func (dl *DatasetLoader) GetStarterDistribution() []WordProbability {
+	dl.ensureTables()
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+	return rankDistribution(dl.starters, 0)
+}
+
+// rankDistribution sorts a distribution by probability, ties alphabetically,
+// keeping the top k if k > 0
+func rankDistribution(distribution map[string]float64, k int) []WordProbability {
+	ranked := make([]WordProbability, 0, len(distribution))
+	for word, p := range distribution {
+		ranked = append(ranked, WordProbability{Word: word, Probability: p})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Probability != ranked[j].Probability {
+			return ranked[i].Probability > ranked[j].Probability
+		}
+		return ranked[i].Word < ranked[j].Word
+	})
+	if k > 0 && len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	return ranked
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,15 +18,38 @@ type LiquidStateBrain struct {
 	dimensions   Dimensions
 	inputLayer   []*InputNeuron
 	outputLayer  []*OutputNeuron
-	wavePatterns chan WavePattern
+	taps         []*outputTap // extra readouts, see OutputTapConfig
+	topology     TopologyConfig // synapse signs and strengths, shared by the neurons
+	conduction   ConductionConfig // synapse delays, shared by the neurons
+	modulator    *Modulator     // reward and attention channels; nil when off
+	budget       *GoroutineBudget // where the neuron workers were granted from
+	workers      int              // workers held from budget
+	wavePatterns chan *WavePattern
 	thoughts     chan string
 	activeWaves  int64
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
+	stimulating  sync.RWMutex // held shared while stimulations join wg; Cleanup fences them out
 	dataLoader   *DatasetLoader
 	config       *Config
 	generator    *ResponseGenerator
+	profiler     *StageProfiler // nil unless profiling is enabled
+	activity     *activityTracker // in-flight injections and spike batches
+	paused       atomic.Bool      // neurons hold their state while set
+	clock        Clock
+	chaos        atomic.Pointer[Chaos] // fault injection; nil when off
+	restarts     int64                 // atomic: supervised goroutines restarted after a panic
+	energy       EnergyMeter           // cumulative reservoir work
+	matcher      *WordMatcher          // typo-tolerant input matching; nil when off
+	schema       *ConceptSchema        // input words and output meanings
+	templates    *TemplateLibrary      // configurable simple responses; nil uses the schema's
+	readout      atomic.Pointer[EvolvedReadout] // picks the response category; nil answers with the strongest output
+	responses    *ResponseCache                 // repeated inputs; nil when caching is off
+	waveOrigins  waveOrigins                    // where recent waves started, for rendered frames
+	overflow     *channelOverflow               // wave channel policy and drop counts
+	history      *InputHistory                  // recent inputs; nil when off
+	rng          *rand.Rand                     // wiring choices, seeded by config.Seed
 }
 
 type Dimensions struct {
@@ -40,6 +64,17 @@ type LiquidNeuron struct {
 	lastFired    time.Time
 	refractoryMs int64
 	ctx          context.Context
+	activity     *activityTracker // shared with the owning brain
+	paused       *atomic.Bool     // the owning brain's pause flag
+	clock        Clock            // the owning brain's clock
+	chaos        *atomic.Pointer[Chaos] // the owning brain's fault injection
+	energy       *EnergyMeter           // the owning brain's work counters
+	topology     *TopologyConfig        // the owning brain's synapse signs and strengths
+	inhibitory   []bool                 // per connection; nil when every synapse excites
+	conduction   *ConductionConfig      // the owning brain's synapse delays
+	delays       []time.Duration        // per connection, from its length
+	modulator    *Modulator             // the owning brain's modulation; nil when off
+	channels     []int                  // modulation channels reaching this neuron
 }
 
 type InputNeuron struct {
@@ -51,6 +86,8 @@ type OutputNeuron struct {
 	connections []*LiquidNeuron
 	meaning     string
 	activation  atomic.Value // float64
+	weights     atomic.Pointer[outputWeights] // set by TrainOutputs; nil averages the connections
+	calibration atomic.Pointer[OutputCalibration] // set by CalibrateOutputs; nil reads raw activations
 }
 
 type WavePattern struct {
@@ -66,6 +103,11 @@ func NewLiquidStateBrain(size int) *LiquidStateBrain {
 }
 
 func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
+	return NewLiquidStateBrainWithClock(size, config, RealClock)
+}
+
+// NewLiquidStateBrainWithClock creates a brain whose dynamics run on clock
+func NewLiquidStateBrainWithClock(size int, config *Config, clock Clock) *LiquidStateBrain {
 	// Validate input parameters
 	if size <= 0 {
 		return nil
@@ -101,17 +143,9 @@ func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
 	}
 	
 	dims := Dimensions{X: size, Y: size, Z: max(1, size/2)} // Ensure Z is at least 1
-	ctx, cancel := context.WithCancel(context.Background())
-	
-	brain := &LiquidStateBrain{
-		reservoir:    make([][][]*LiquidNeuron, dims.X),
-		dimensions:   dims,
-		wavePatterns: make(chan WavePattern, config.Resources.ChannelBufferSize),
-		thoughts:     make(chan string, config.Resources.ChannelBufferSize/10),
-		ctx:          ctx,
-		cancel:       cancel,
-		config:       config,
-	}
+	brain := newBrainShell(dims, config, clock)
+	brain.schema = conceptSchemaFromConfig(config)
+	brain.templates = templateLibraryFromConfig(config)
 	
 	// Load dataset
 	dataLoader, err := NewDatasetLoader(config.Training)
@@ -120,6 +154,10 @@ func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
 	} else {
 		brain.dataLoader = dataLoader
 		brain.generator = NewResponseGenerator(dataLoader)
+		brain.generator.SetConceptSchema(brain.schema)
+		brain.generator.SetBeamSearch(config.BeamSearch)
+		brain.generator.SetTemplates(brain.templates, config.Templates)
+		brain.generator.SetSeed(config.Seed)
 	}
 	
 	// Initialize 3D reservoir with progress tracking
@@ -143,12 +181,18 @@ func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
 				
 				neuron := &LiquidNeuron{
 					x: x, y: y, z: z,
-					threshold:    0.5 + rand.Float64()*0.3,
-					refractoryMs: 5 + rand.Int63n(10),
+					threshold:    0.5 + brain.rng.Float64()*0.3,
+					refractoryMs: 5 + brain.rng.Int63n(10),
 					ctx:          brain.ctx,
+					activity:     brain.activity,
+					paused:       &brain.paused,
+					clock:        clock,
+					chaos:        &brain.chaos,
+					energy:       &brain.energy,
+					topology:     &brain.topology,
 					connections:  make([]*LiquidNeuron, 0, 10), // Pre-allocate with reasonable capacity
 				}
-				neuron.state.Store(rand.Float64() * 0.1)
+				neuron.state.Store(brain.rng.Float64() * 0.1)
 				brain.reservoir[x][y][z] = neuron
 				neuronsCreated++
 				
@@ -160,8 +204,11 @@ func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
 		}
 	}
 	
-	// Create local connections (nearby neurons)
+	// Create local connections (nearby neurons), some inhibitory
 	brain.connectReservoir()
+	brain.assignInhibition()
+	brain.assignDelays()
+	brain.initializeModulation()
 	
 	// Initialize input/output layers
 	brain.initializeIO()
@@ -169,12 +216,57 @@ func NewLiquidStateBrainWithConfig(size int, config *Config) *LiquidStateBrain {
 	// Start the liquid dynamics
 	brain.startDynamics()
 	
+	if config.OutputCalibration.Enabled {
+		inputs, err := calibrationInputs(config.OutputCalibration, brain.schema)
+		if err == nil {
+			_, err = brain.CalibrateOutputs(inputs, config.OutputCalibration)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Warning: output calibration disabled: %v\n", err)
+		}
+	}
+	
+	if config.Readout.Mode == "evolved" {
+		if _, err := brain.EvolveReadout(brain.schema.readoutExamples(), config.Readout); err != nil {
+			fmt.Printf("⚠️  Warning: evolved readout disabled: %v\n", err)
+		}
+	}
+	
 	return brain
 }
 
+// newBrainShell creates a brain with no neurons, dataset or dynamics yet
+func newBrainShell(dims Dimensions, config *Config, clock Clock) *LiquidStateBrain {
+	if clock == nil {
+		clock = RealClock
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	
+	return &LiquidStateBrain{
+		reservoir:    make([][][]*LiquidNeuron, dims.X),
+		dimensions:   dims,
+		wavePatterns: make(chan *WavePattern, config.Resources.ChannelBufferSize),
+		thoughts:     make(chan string, config.Resources.ChannelBufferSize/10),
+		ctx:          ctx,
+		cancel:       cancel,
+		config:       config,
+		topology:     config.Topology.orDefault(),
+		conduction:   config.Conduction.orDefault(),
+		profiler:     newProfilerFromConfig(config),
+		activity:     newActivityTracker(),
+		clock:        clock,
+		matcher:      newMatcherFromConfig(config),
+		responses:    NewResponseCache(config.ResponseCache, clock),
+		history:      NewInputHistory(config.InputHistory, clock),
+		overflow:     newChannelOverflow(config.Backpressure),
+		rng:          newRand(config.Seed),
+	}
+}
+
 func (brain *LiquidStateBrain) connectReservoir() {
 	// Each neuron connects to nearby neurons
-	radius := 2 // Connection radius
+	connectivity := brain.topology.Connectivity.orDefault()
+	radius := connectivity.Radius
 	
 	for x := 0; x < brain.dimensions.X; x++ {
 		for y := 0; y < brain.dimensions.Y; y++ {
@@ -198,7 +290,7 @@ func (brain *LiquidStateBrain) connectReservoir() {
 								
 								// Probability of connection decreases with distance
 								distance := math.Sqrt(float64(dx*dx + dy*dy + dz*dz))
-								if rand.Float64() < 0.3/distance {
+								if brain.rng.Float64() < connectivity.probability(distance) {
 									neighbor := brain.reservoir[nx][ny][nz]
 									neuron.connections = append(neuron.connections, neighbor)
 								}
@@ -206,16 +298,28 @@ func (brain *LiquidStateBrain) connectReservoir() {
 						}
 					}
 				}
+				
+				// Drop excess connections at random
+				if limit := connectivity.MaxOutDegree; limit > 0 && len(neuron.connections) > limit {
+					brain.rng.Shuffle(len(neuron.connections), func(i, j int) {
+						neuron.connections[i], neuron.connections[j] = neuron.connections[j], neuron.connections[i]
+					})
+					neuron.connections = neuron.connections[:limit]
+				}
+				// Top up sparse neurons from their nearest neighbors
+				if len(neuron.connections) < connectivity.MinOutDegree {
+					brain.fillConnections(neuron, connectivity)
+				}
 			}
 		}
 	}
 	
-	fmt.Printf("✓ Connected reservoir with local topology\n")
+	fmt.Printf("✓ Connected reservoir with local topology (radius %d): %s\n", radius, brain.ConnectivityStats())
 }
 
 func (brain *LiquidStateBrain) initializeIO() {
-	// Create input neurons for different concepts
-	concepts := []string{"hello", "help", "code", "error", "think", "understand"}
+	// Create input neurons for the schema's input words
+	concepts := brain.schema.orDefault().InputWords
 	brain.inputLayer = make([]*InputNeuron, len(concepts))
 	
 	// Safety check for empty reservoir
@@ -229,8 +333,8 @@ func (brain *LiquidStateBrain) initializeIO() {
 		
 		// Connect to random neurons in first layer
 		for j := 0; j < 100; j++ {
-			x := rand.Intn(brain.dimensions.X)
-			y := rand.Intn(brain.dimensions.Y)
+			x := brain.rng.Intn(brain.dimensions.X)
+			y := brain.rng.Intn(brain.dimensions.Y)
 			z := 0 // First layer
 			input.connections = append(input.connections, brain.reservoir[x][y][z])
 		}
@@ -238,38 +342,29 @@ func (brain *LiquidStateBrain) initializeIO() {
 		brain.inputLayer[i] = input
 	}
 	
-	// Create output neurons for interpretations
-	outputs := []string{"greeting", "assistance", "technical", "problem", "cognitive", "comprehension"}
-	brain.outputLayer = make([]*OutputNeuron, len(outputs))
-	
-	for i, meaning := range outputs {
-		output := &OutputNeuron{meaning: meaning}
-		output.activation.Store(0.0)
-		
-		// Connect to random neurons in last layer
-		for j := 0; j < 100; j++ {
-			x := rand.Intn(brain.dimensions.X)
-			y := rand.Intn(brain.dimensions.Y)
-			z := brain.dimensions.Z - 1 // Last layer
-			output.connections = append(output.connections, brain.reservoir[x][y][z])
-		}
-		
-		brain.outputLayer[i] = output
-	}
+	// Create output neurons for the schema's meanings, reading the last
+	// layer unless an output tap says otherwise
+	brain.initializeOutputs(brain.schema.MeaningNames())
 }
 
 func (brain *LiquidStateBrain) startDynamics() {
 	// Start reservoir dynamics with proper resource management
 	totalNeurons := 0
 	goroutineCount := 0
-	maxGoroutines := brain.config.Resources.MaxGoroutines
+	
+	// Neurons run on workers granted by the goroutine budget; every output
+	// monitor and the wave visualizer need one of their own
+	helpers := len(brain.outputLayer) + 1
+	totalNeuronsCount := brain.dimensions.X * brain.dimensions.Y * brain.dimensions.Z
+	brain.budget = configureGoroutineBudget(brain.config.Resources)
+	brain.workers = brain.budget.Request(budgetBrain, totalNeuronsCount+helpers, 1+helpers)
+	maxGoroutines := brain.workers - helpers
 	
 	// Calculate neurons per goroutine to stay within limits
 	neuronsPerGoroutine := 1
-	totalNeuronsCount := brain.dimensions.X * brain.dimensions.Y * brain.dimensions.Z
 	if totalNeuronsCount > maxGoroutines {
 		neuronsPerGoroutine = (totalNeuronsCount + maxGoroutines - 1) / maxGoroutines
-		fmt.Printf("⚡ Batching %d neurons per goroutine to stay within %d goroutine limit\n", neuronsPerGoroutine, maxGoroutines)
+		fmt.Printf("⚡ Batching %d neurons per goroutine to stay within %d granted workers\n", neuronsPerGoroutine, maxGoroutines)
 	}
 	
 	for x := 0; x < brain.dimensions.X; x++ {
@@ -284,24 +379,22 @@ func (brain *LiquidStateBrain) startDynamics() {
 					brain.wg.Add(1)
 					go func(start, end int) {
 						defer brain.wg.Done()
-						defer func() {
-							if r := recover(); r != nil {
-								fmt.Printf("🚨 Neuron goroutine panic recovered: %v\n", r)
-							}
-						}()
 						
-						// Run neurons in this batch
-						neuronIndex := 0
-						for bx := 0; bx < brain.dimensions.X && neuronIndex < end-start; bx++ {
-							for by := 0; by < brain.dimensions.Y && neuronIndex < end-start; by++ {
-								for bz := 0; bz < brain.dimensions.Z && neuronIndex < end-start; bz++ {
-									if neuronIndex >= start-start { // Within our batch
-										brain.reservoir[bx][by][bz].live()
+						// Crashed batches are restarted until shutdown
+						brain.supervise("neuron batch", func() {
+							// Run neurons in this batch
+							neuronIndex := 0
+							for bx := 0; bx < brain.dimensions.X && neuronIndex < end-start; bx++ {
+								for by := 0; by < brain.dimensions.Y && neuronIndex < end-start; by++ {
+									for bz := 0; bz < brain.dimensions.Z && neuronIndex < end-start; bz++ {
+										if neuronIndex >= start-start { // Within our batch
+											brain.reservoir[bx][by][bz].live()
+										}
+										neuronIndex++
 									}
-									neuronIndex++
 								}
 							}
-						}
+						})
 					}(batchStart, batchEnd)
 					goroutineCount++
 				}
@@ -315,12 +408,9 @@ func (brain *LiquidStateBrain) startDynamics() {
 		brain.wg.Add(1)
 		go func(o *OutputNeuron) {
 			defer brain.wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("🚨 Output monitor panic recovered: %v\n", r)
-				}
-			}()
-			o.monitor(brain.ctx)
+			brain.supervise("output monitor", func() {
+				o.monitor(brain.ctx, brain.clock)
+			})
 		}(output)
 	}
 	
@@ -348,6 +438,11 @@ func (brain *LiquidStateBrain) Cleanup() {
 	fmt.Println("🔄 Initiating brain cleanup...")
 	brain.cancel()
 	
+	// Stimulations check for cancellation before joining wg, so once this
+	// lock is taken no new ones can start and Wait covers the rest
+	brain.stimulating.Lock()
+	brain.stimulating.Unlock()
+	
 	// Wait for goroutines with timeout
 	done := make(chan struct{})
 	go func() {
@@ -361,6 +456,10 @@ func (brain *LiquidStateBrain) Cleanup() {
 	case <-time.After(5 * time.Second):
 		fmt.Println("⚠️  Cleanup timeout - some goroutines may still be running")
 	}
+	if brain.budget != nil {
+		brain.budget.Release(budgetBrain, brain.workers)
+		brain.workers = 0
+	}
 	
 	// Safely close channels
 	if brain.wavePatterns != nil {
@@ -378,88 +477,203 @@ func (brain *LiquidStateBrain) Cleanup() {
 
 // Process input and watch patterns emerge
 func (brain *LiquidStateBrain) Think(input string) string {
+	response, _ := brain.ThinkMetered(input)
+	return response
+}
+
+// ThinkMetered is Think that also reports the work the request cost
+func (brain *LiquidStateBrain) ThinkMetered(input string) (string, EnergyReport) {
+	response, _, energy := brain.ThinkScored(input)
+	return response, energy
+}
+
+// ThinkScored is Think that also reports how confident the brain is in the
+// response and the work the request cost
+func (brain *LiquidStateBrain) ThinkScored(input string) (string, Confidence, EnergyReport) {
+	return brain.ThinkWithOptions(input, GenerationOptions{})
+}
+
+// ThinkWithOptions is ThinkScored generating within the request's options,
+// such as its seed. The reservoir's spontaneous activity isn't seeded.
+func (brain *LiquidStateBrain) ThinkWithOptions(input string, options GenerationOptions) (string, Confidence, EnergyReport) {
+	response, confidence, energy, _ := brain.ThinkContext(context.Background(), input, options)
+	return response, confidence, energy
+}
+
+// ThinkContext is ThinkWithOptions for a request that may go away: once
+// ctx is done it injects no more words, generates nothing and returns
+// ctx's error
+func (brain *LiquidStateBrain) ThinkContext(ctx context.Context, input string, options GenerationOptions) (string, Confidence, EnergyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Confidence{}, EnergyReport{}, err
+	}
+	input = NormalizeInput(input)
+	var keywords map[string]float64
+	if brain.dataLoader != nil {
+		keywords = keywordWeights(brain.dataLoader.ExtractKeyphrases(input, inputKeyphrases))
+	}
+	brain.noteInput(input, keywords)
+	
+	key := responseCacheKey(input, options, nil)
+	if cached, ok := brain.responses.Get(key); ok {
+		fmt.Printf("\n⚡ Cached response for '%s'\n", input)
+		return brain.reactToPatterns(cached.Output), cached.Confidence, EnergyReport{}, nil
+	}
+	before := brain.energy.Snapshot()
 	fmt.Printf("\n🧠 Liquid brain processing: '%s'\n", input)
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	
-	// Inject input as waves
-	words := strings.Fields(strings.ToLower(input))
+	call := brain.profiler.Begin("think")
+	defer call.Done()
 	
-	for _, word := range words {
-		brain.injectWord(word)
+	activations := brain.perceive(ctx, input, keywords, options, call)
+	if err := ctx.Err(); err != nil {
+		return "", Confidence{}, brain.energy.Snapshot().Sub(before), err
 	}
 	
-	// Let waves propagate
-	time.Sleep(200 * time.Millisecond)
-	
-	// Generate response based on wave patterns
-	response := brain.generateResponse()
+	response, beams, confidence := brain.respondTo(activations, options)
+	call.Mark("generation")
 	
 	// Show active wave count
 	waves := atomic.LoadInt64(&brain.activeWaves)
 	fmt.Printf("\n📊 Active waves in reservoir: %d\n", waves)
+	fmt.Printf("🎯 Confidence: %s\n", confidence)
 	
-	return response
+	energy := brain.energy.Snapshot().Sub(before)
+	energy.BeamsExpanded = beams
+	options.budget.generated(response)
+	if len(options.budget.Hit()) == 0 {
+		brain.responses.Put(key, CachedResponse{Output: response, Confidence: confidence})
+	}
+	return brain.reactToPatterns(response), confidence, energy, nil
+}
+
+// perceive injects input into the reservoir, keywords first and stronger,
+// lets the waves settle and reads the output layer. Injection stops once
+// ctx is done.
+func (brain *LiquidStateBrain) perceive(ctx context.Context, input string, keywords map[string]float64, options GenerationOptions, call *CallProfile) map[string]float64 {
+	// Inject input as waves, keywords first and stronger
+	words := strings.Fields(strings.ToLower(input))
+	
+	weight := func(word string) float64 {
+		return keywords[strings.Trim(word, ".,;:!?\"'()")]
+	}
+	sort.SliceStable(words, func(i, j int) bool {
+		return weight(words[i]) > weight(words[j])
+	})
+	brain.attend(words, weight)
+	for _, word := range words {
+		if options.budget.expired() || ctx.Err() != nil {
+			break
+		}
+		brain.injectWordWithin(word, 1+keywordInjectionGain*weight(word), options.budget)
+	}
+	call.Mark("injection")
+	
+	// Let waves propagate until the reservoir goes quiet
+	brain.activity.WaitQuiet(settleGrace, options.budget.remaining(settleTimeout))
+	call.Mark("settle")
+	
+	// Read the wave patterns the response is based on
+	activations := brain.readOutput()
+	call.Mark("readout")
+	return activations
+}
+
+// Upper bound on how long Think waits for the reservoir to settle. A
+// self-sustaining reservoir never goes fully quiet, so this caps the wait.
+const settleTimeout = 1 * time.Second
+
+// Quiet period required before the reservoir counts as settled; longer than
+// one neuron tick so freshly excited neurons get a chance to fire
+const settleGrace = 15 * time.Millisecond
+
+// settle blocks until injected waves and the spikes they trigger have
+// finished propagating, or settleTimeout elapses
+func (brain *LiquidStateBrain) settle() bool {
+	return brain.activity.WaitQuiet(settleGrace, settleTimeout)
 }
 
 func (brain *LiquidStateBrain) injectWord(word string) {
+	brain.injectWordWithGain(word, 1)
+}
+
+// injectWordWithGain injects word with its stimulation scaled by gain
+func (brain *LiquidStateBrain) injectWordWithGain(word string, gain float64) {
+	brain.injectWordWithin(word, gain, nil)
+}
+
+// injectWordWithin is injectWordWithGain drawing its waves from budget
+func (brain *LiquidStateBrain) injectWordWithin(word string, gain float64, budget *requestBudget) {
 	// Find matching input neuron
 	for _, input := range brain.inputLayer {
-		similarity := brain.wordSimilarity(word, input.word)
+		similarity := math.Max(brain.wordSimilarity(word, input.word), brain.matcher.Similarity(word, input.word))
 		if similarity > 0.5 {
 			// Create ripples from this input
 			fmt.Printf("💉 Injecting '%s' (similarity to '%s': %.2f)\n", 
 				word, input.word, similarity)
 			
-			// Stimulate connected neurons
-			for _, neuron := range input.connections {
-				go func(n *LiquidNeuron, strength float64) {
-					defer func() {
-						if r := recover(); r != nil {
-							fmt.Printf("🚨 Neuron activation panic recovered: %v\n", r)
-						}
-					}()
-					
-					var current float64
-					if val := n.state.Load(); val != nil {
-						current = val.(float64)
-					}
-					n.state.Store(math.Min(1.0, current + strength))
-					
-					// Record wave pattern with non-blocking approach
-					select {
-					case brain.wavePatterns <- WavePattern{
-						origin:    [3]int{n.x, n.y, n.z},
-						intensity: strength,
-						timestamp: time.Now(),
-						meaning:   word,
-					}:
-						atomic.AddInt64(&brain.activeWaves, 1)
-					case <-time.After(5 * time.Millisecond):
-						// Channel blocked, skip this wave to prevent deadlock
-					default:
-						// Channel full, skip this wave
-					}
-				}(neuron, similarity)
-			}
+			brain.stimulateInputWithin(input, word, similarity*gain, budget)
 		}
 	}
 }
 
+// stimulateInput sends strength into the reservoir neurons input feeds,
+// recording a wave labeled word from each
+func (brain *LiquidStateBrain) stimulateInput(input *InputNeuron, word string, strength float64) {
+	brain.stimulateInputWithin(input, word, strength, nil)
+}
+
+// stimulateInputWithin is stimulateInput sending only the waves budget
+// grants
+func (brain *LiquidStateBrain) stimulateInputWithin(input *InputNeuron, word string, strength float64, budget *requestBudget) {
+	connections := input.connections[:budget.takeWaves(len(input.connections))]
+	
+	// The waves send on wavePatterns, which Cleanup closes once wg is done
+	brain.stimulating.RLock()
+	if brain.ctx.Err() != nil {
+		brain.stimulating.RUnlock()
+		return
+	}
+	brain.wg.Add(len(connections))
+	brain.stimulating.RUnlock()
+	
+	brain.activity.Add(int64(len(connections)))
+	for _, neuron := range connections {
+		go func(n *LiquidNeuron) {
+			defer brain.wg.Done()
+			defer brain.activity.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Printf("🚨 Neuron activation panic recovered: %v\n", r)
+				}
+			}()
+			
+			if brain.chaos.Load().dropWave() {
+				return
+			}
+			n.stimulate(strength)
+			
+			// Record wave pattern with non-blocking approach
+			wave := acquireWavePattern()
+			wave.origin = [3]int{n.x, n.y, n.z}
+			wave.intensity = strength
+			wave.timestamp = brain.clock.Now()
+			wave.meaning = word
+			
+			if offer(brain.wavePatterns, wave, brain.overflow.config.Waves, &brain.overflow.waves, releaseWavePattern) {
+				atomic.AddInt64(&brain.activeWaves, 1)
+			}
+		}(neuron)
+	}
+}
+
 func (brain *LiquidStateBrain) readOutput() map[string]float64 {
 	// Collect activation from output neurons
-	activations := make(map[string]float64)
+	activations := make(map[string]float64, len(brain.outputLayer))
 	
 	for _, output := range brain.outputLayer {
-		// Sum activation from connected neurons
-		totalActivation := 0.0
-		for _, neuron := range output.connections {
-			if val := neuron.state.Load(); val != nil {
-				totalActivation += val.(float64)
-			}
-		}
-		
-		avgActivation := totalActivation / float64(len(output.connections))
-		activations[output.meaning] = avgActivation
+		activations[output.meaning] = output.level()
 	}
 	
 	// Show activation pattern
@@ -474,23 +688,51 @@ func (brain *LiquidStateBrain) readOutput() map[string]float64 {
 
 func (brain *LiquidStateBrain) generateResponse() string {
 	// Get output activations
-	activations := brain.readOutput()
+	response, _, _ := brain.respondTo(brain.readOutput(), GenerationOptions{})
+	return response
+}
+
+// respondTo turns output activations into a response, also returning the
+// number of beams expanded and the response's confidence
+func (brain *LiquidStateBrain) respondTo(activations map[string]float64, options GenerationOptions) (string, int64, Confidence) {
+	levels := make([]float64, 0, len(activations))
+	for _, activation := range activations {
+		levels = append(levels, activation)
+	}
+	confidence := Confidence{ActivationMargin: activationMargin(levels)}
+	
+	// An evolved readout picks the category instead of the activation levels
+	category, evolved := brain.readout.Load().Classify(activations)
+	if evolved {
+		fmt.Printf("🧬 Evolved readout: %s\n", category)
+	}
 	
 	if brain.dataLoader == nil || brain.generator == nil {
 		// Fallback to simple interpretation
-		return brain.simpleInterpretation(activations)
+		confidence.combine(confidence.ActivationMargin)
+		if evolved {
+			return brain.interpretCategory(category), 0, confidence
+		}
+		return brain.simpleInterpretation(activations), 0, confidence
 	}
 	
 	// Convert activations to concepts
 	activeConcepts := brain.getActivatedConcepts(activations)
+	if evolved {
+		if m, ok := brain.schema.Meaning(category); ok {
+			activeConcepts = m.Concepts
+		}
+	}
 	
 	// Build input context from wave patterns
 	context := brain.getWaveContext()
 	
 	// Use enhanced generator
-	response := brain.generator.Generate(context, activeConcepts)
+	response, explanation := brain.generator.GenerateWithOptions(context, activeConcepts, options)
+	confidence.BeamScore = explanation.Confidence.BeamScore
+	confidence.combine(confidence.ActivationMargin, explanation.Confidence.Score)
 	
-	return response
+	return response, explanation.Energy.BeamsExpanded, confidence
 }
 
 func (brain *LiquidStateBrain) getActivatedConcepts(activations map[string]float64) []string {
@@ -498,21 +740,10 @@ func (brain *LiquidStateBrain) getActivatedConcepts(activations map[string]float
 	
 	// Get strongly activated outputs
 	for meaning, activation := range activations {
-		if activation > 0.5 {
+		if activation > conceptActivationLevel {
 			// Map to related concepts
-			switch meaning {
-			case "greeting":
-				concepts = append(concepts, "hello", "welcome", "greet")
-			case "assistance":
-				concepts = append(concepts, "help", "assist", "support", "guide")
-			case "technical":
-				concepts = append(concepts, "code", "system", "process", "compute")
-			case "problem":
-				concepts = append(concepts, "solve", "debug", "fix", "issue")
-			case "cognitive":
-				concepts = append(concepts, "think", "understand", "analyze", "reason")
-			case "comprehension":
-				concepts = append(concepts, "understand", "grasp", "see", "know")
+			if m, ok := brain.schema.Meaning(meaning); ok {
+				concepts = append(concepts, m.Concepts...)
 			}
 		}
 	}
@@ -548,33 +779,16 @@ func (brain *LiquidStateBrain) getActivatedWords(activations map[string]float64)
 	for meaning, activation := range activations {
 		if activation > 0.3 {
 			// Get words related to this meaning
-			switch meaning {
-			case "greeting":
-				words = append(words, "hello", "hi", "greetings")
-			case "assistance":
-				words = append(words, "help", "assist", "support")
-			case "technical":
-				words = append(words, "code", "system", "process")
-			case "problem":
-				words = append(words, "error", "issue", "challenge")
-			case "cognitive":
-				words = append(words, "think", "understand", "analyze")
-			case "comprehension":
-				words = append(words, "see", "know", "grasp")
+			if m, ok := brain.schema.Meaning(meaning); ok {
+				words = append(words, m.SeedWords...)
 			}
 		}
 	}
 	
 	// Filter to only words in vocabulary
 	vocabWords := []string{}
-	vocab := brain.dataLoader.GetVocabulary()
-	vocabMap := make(map[string]bool)
-	for _, v := range vocab {
-		vocabMap[v] = true
-	}
-	
 	for _, word := range words {
-		if vocabMap[word] {
+		if brain.dataLoader.InVocabulary(word) {
 			vocabWords = append(vocabWords, word)
 		}
 	}
@@ -601,17 +815,8 @@ func (brain *LiquidStateBrain) selectNextWordByWaves(currentWord string, activat
 
 func (brain *LiquidStateBrain) wordMatchesMeaning(word, meaning string) bool {
 	// Simple heuristic matching
-	meaningWords := map[string][]string{
-		"greeting":      {"hello", "hi", "hey", "greetings"},
-		"assistance":    {"help", "assist", "support", "aid"},
-		"technical":     {"code", "program", "system", "software"},
-		"problem":       {"error", "bug", "issue", "problem"},
-		"cognitive":     {"think", "thought", "mind", "brain"},
-		"comprehension": {"understand", "know", "see", "grasp"},
-	}
-	
-	if words, ok := meaningWords[meaning]; ok {
-		for _, w := range words {
+	if m, ok := brain.schema.Meaning(meaning); ok {
+		for _, w := range m.MatchWords {
 			if strings.Contains(word, w) || strings.Contains(w, word) {
 				return true
 			}
@@ -634,128 +839,95 @@ func (brain *LiquidStateBrain) simpleInterpretation(activations map[string]float
 	}
 	
 	// Generate simple response based on dominant meaning
-	switch dominantMeaning {
-	case "greeting":
-		return "Hello! The waves ripple with recognition."
-	case "assistance":
-		return "I sense you need help. Let the patterns guide us."
-	case "technical":
-		return "Technical waves detected. Processing computational patterns."
-	case "problem":
-		return "Error patterns emerging. Let's debug together."
-	case "cognitive":
-		return "Thought waves propagating through the reservoir."
-	case "comprehension":
-		return "Understanding crystallizes from the liquid patterns."
-	default:
-		return fmt.Sprintf("Wave patterns suggest: %s", dominantMeaning)
+	return brain.interpretCategory(dominantMeaning)
+}
+
+// interpretCategory returns the template library's or else the schema's
+// simple response for a meaning
+func (brain *LiquidStateBrain) interpretCategory(meaning string) string {
+	if response, ok := brain.templates.Respond("", meaning); ok {
+		return response
+	}
+	if m, ok := brain.schema.Meaning(meaning); ok && m.Response != "" {
+		return m.Response
 	}
+	return fmt.Sprintf("Wave patterns suggest: %s", meaning)
 }
 
 func (brain *LiquidStateBrain) visualizeWaves() {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := brain.clock.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 	
-	waveHistory := []WavePattern{}
-	
 	for {
 		select {
 		case <-brain.ctx.Done():
 			return
-		case wave := <-brain.wavePatterns:
-			waveHistory = append(waveHistory, wave)
-			
-			// Keep only recent waves
-			if len(waveHistory) > 1000 {
-				waveHistory = waveHistory[100:]
+		case wave, ok := <-brain.wavePatterns:
+			if !ok {
+				return
 			}
+			brain.waveOrigins.record(wave.origin, wave.timestamp)
+			releaseWavePattern(wave)
 			
-		case <-ticker.C:
-			// Periodic visualization
+		case <-ticker.Chan():
+			// Periodic visualization, paused while degraded
 			activeWaves := atomic.LoadInt64(&brain.activeWaves)
-			if len(waveHistory) > 0 && activeWaves > 0 {
-				brain.showWavePattern(waveHistory)
+			if activeWaves > 0 && logEnabled(LogInfo) && !SharedDegrader().Degraded() {
+				brain.showWavePattern()
 			}
 		}
 	}
 }
 
-func (brain *LiquidStateBrain) showWavePattern(waves []WavePattern) {
-	// Create a 2D slice visualization (top view)
-	grid := make([][]float64, brain.dimensions.X)
-	for i := range grid {
-		grid[i] = make([]float64, brain.dimensions.Y)
-	}
-	
-	// Aggregate wave intensities
-	for _, wave := range waves {
-		age := time.Since(wave.timestamp).Seconds()
-		if age < 1.0 { // Only show recent waves
-			// Wave decays over time
-			intensity := wave.intensity * math.Exp(-age*2)
-			
-			// Create ripple effect
-			for dx := -3; dx <= 3; dx++ {
-				for dy := -3; dy <= 3; dy++ {
-					x, y := wave.origin[0]+dx, wave.origin[1]+dy
-					if x >= 0 && x < brain.dimensions.X && 
-					   y >= 0 && y < brain.dimensions.Y {
-						distance := math.Sqrt(float64(dx*dx + dy*dy))
-						grid[x][y] += intensity * math.Exp(-distance)
-					}
-				}
-			}
-		}
+func (brain *LiquidStateBrain) showWavePattern() {
+	// Render the reservoir's actual activity: spikes travel along real
+	// connections with distance-based delays, so the strongest neuron in
+	// each cell traces the waves
+	frame, err := brain.RenderWaveFrame(brain.config.Visualization)
+	if err != nil {
+		fmt.Printf("⚠️  Wave visualization: %v\n", err)
+		return
 	}
 	
-	// Show wave visualization
 	fmt.Println("\n🌊 Wave patterns in liquid reservoir:")
-	for y := 0; y < min(10, brain.dimensions.Y); y++ {
-		fmt.Print("   ")
-		for x := 0; x < min(40, brain.dimensions.X); x++ {
-			intensity := grid[x][y]
-			if intensity > 0.8 {
-				fmt.Print("●")
-			} else if intensity > 0.5 {
-				fmt.Print("◉")
-			} else if intensity > 0.3 {
-				fmt.Print("○")
-			} else if intensity > 0.1 {
-				fmt.Print("·")
-			} else {
-				fmt.Print(" ")
-			}
-		}
-		fmt.Println()
+	for _, row := range frame.Rows {
+		fmt.Println("   " + row)
 	}
 }
 
 // Individual neuron dynamics
 func (n *LiquidNeuron) live() {
-	ticker := time.NewTicker(time.Duration(5+rand.Intn(5)) * time.Millisecond)
+	ticker := n.clock.NewTicker(time.Duration(5+rand.Intn(5)) * time.Millisecond)
 	defer ticker.Stop()
 	
 	for {
 		select {
 		case <-n.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.Chan():
+			if n.paused != nil && n.paused.Load() {
+				continue
+			}
+			if n.chaos != nil {
+				n.chaos.Load().maybeKillNeuron()
+			}
+			n.energy.neuronUpdate()
 			var state float64
 			if val := n.state.Load(); val != nil {
 				state = val.(float64)
 			}
 			
 			// Check if neuron should fire
-			if state > n.threshold && time.Since(n.lastFired).Milliseconds() > n.refractoryMs {
+			if state > n.threshold && clockSince(n.clock, n.lastFired).Milliseconds() > n.refractoryMs {
 				// Fire!
 				n.fire()
-				n.lastFired = time.Now()
+				n.lastFired = n.clock.Now()
 				
 				// Reset state
 				n.state.Store(0.1)
 			} else {
-				// Decay state
-				n.state.Store(state * 0.95)
+				// Decay state, as fast as modulation allows
+				n.state.Store(state * n.modulator.retention(n.channels))
 			}
 			
 			// Random spontaneous activity (keeps reservoir dynamic)
@@ -767,33 +939,53 @@ func (n *LiquidNeuron) live() {
 }
 
 func (n *LiquidNeuron) fire() {
-	// Send activation to all connected neurons
-	for _, target := range n.connections {
-		go func(t *LiquidNeuron) {
-			// Synaptic delay
-			time.Sleep(time.Duration(1+rand.Intn(3)) * time.Millisecond)
-			
-			// Activate target
-			var current float64
-			if val := t.state.Load(); val != nil {
-				current = val.(float64)
+	if len(n.connections) == 0 {
+		return
+	}
+	
+	// Send activation to all connected neurons as one pooled batch
+	batch := acquireSpikeBatch()
+	for i, target := range n.connections {
+		batch.events = append(batch.events, spikeEvent{
+			target:   target,
+			strength: n.synapseStrength(i), // Random synaptic strength, negative if inhibitory
+			delay:    n.synapseDelay(i),    // Conduction delay, longer for distant targets
+		})
+	}
+	
+	n.energy.spikesPropagated(len(batch.events))
+	n.activity.Add(1)
+	go func() {
+		defer n.activity.Done()
+		defer releaseSpikeBatch(batch)
+		if n.chaos != nil {
+			if d := n.chaos.Load().spikeDelay(); d > 0 {
+				n.clock.Sleep(d)
 			}
-			// Random synaptic strength
-			strength := 0.1 + rand.Float64()*0.4
-			t.state.Store(math.Min(1.0, current+strength))
-		}(target)
+		}
+		batch.deliver(n.clock)
+	}()
+}
+
+// stimulate adds activation to the neuron, saturating at 1.0; inhibitory
+// (negative) strengths lower it to no less than 0
+func (n *LiquidNeuron) stimulate(strength float64) {
+	var current float64
+	if val := n.state.Load(); val != nil {
+		current = val.(float64)
 	}
+	n.state.Store(math.Max(0, math.Min(1.0, current+strength)))
 }
 
-func (o *OutputNeuron) monitor(ctx context.Context) {
-	ticker := time.NewTicker(50 * time.Millisecond)
+func (o *OutputNeuron) monitor(ctx context.Context, clock Clock) {
+	ticker := clock.NewTicker(50 * time.Millisecond)
 	defer ticker.Stop()
 	
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.Chan():
 			// Calculate activation from connected neurons
 			total := 0.0
 			for _, neuron := range o.connections {
@@ -818,24 +1010,19 @@ func (brain *LiquidStateBrain) wordSimilarity(w1, w2 string) float64 {
 		return 1.0
 	}
 	
-	// Simple similarity for demo
-	similarities := map[string]map[string]float64{
-		"hello":      {"hi": 0.9, "greetings": 0.8, "hey": 0.85},
-		"help":       {"assist": 0.9, "support": 0.8, "aid": 0.85},
-		"code":       {"program": 0.9, "coding": 0.95, "software": 0.8},
-		"error":      {"bug": 0.9, "issue": 0.8, "problem": 0.85},
-		"think":      {"process": 0.8, "compute": 0.7, "consider": 0.85},
-		"understand": {"comprehend": 0.9, "grasp": 0.8, "know": 0.7},
-	}
-	
-	if score, ok := similarities[w1][w2]; ok {
-		return score
-	}
-	if score, ok := similarities[w2][w1]; ok {
-		return score
-	}
-	
-	return 0.0
+	// Fall back to the schema's similarity table
+	score, _ := brain.schema.Similarity(w1, w2)
+	return score
+}
+
+// Profiler returns the stage profiler, or nil when profiling is disabled
+func (brain *LiquidStateBrain) Profiler() *StageProfiler {
+	return brain.profiler
+}
+
+// Clock returns the clock the brain's dynamics run on
+func (brain *LiquidStateBrain) Clock() Clock {
+	return brain.clock
 }
 
 // max function is defined in utils.go
\ No newline at end of file
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Log levels gate Genesis' console output. Routine visualization (wave
+// patterns, thought streams) prints at info; operators of a long-running
+// instance can raise the level at runtime to quiet it, or lower it to see
+// debug detail.
+
+type LogLevel int32
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+var currentLogLevel = int32(LogInfo)
+
+func (l LogLevel) String() string {
+	if l < LogDebug || l > LogError {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel converts a level name to a LogLevel
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, n := range logLevelNames {
+		if strings.EqualFold(name, n) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+}
+
+// SetLogLevel changes the level for the whole process
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&currentLogLevel, int32(level))
+}
+
+// CurrentLogLevel returns the process log level
+func CurrentLogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32(&currentLogLevel))
+}
+
+// logEnabled reports whether output at level should be printed
+func logEnabled(level LogLevel) bool {
+	return level >= CurrentLogLevel()
+}
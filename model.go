@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Every servable model — the transparent LLM, the liquid brains and the
+// orchestrators — implements Model, and NewModel builds whichever one the
+// config's model.type names; other model types plug in with
+// RegisterModelType. Save and Load checkpoint the model's live
+// state (concept activations or reservoir states) as JSON, so a restarted
+// process can resume warm.
+
+// Request is one input to a model
+type Request struct {
+	Input  string
+	Seed   *int64            // seeds the request's random choices, where the model supports it; nil draws a fresh seed
+	DryRun bool              // orchestrators return their plan without calling capabilities; other models ignore it
+	Policy *CapabilityPolicy // capabilities orchestrators may engage; nil permits all
+	Limits RequestLimits     // tightens the config's request_limits for this request
+}
+
+// Response is a model's answer and what it cost
+type Response struct {
+	Output      string
+	Confidence  Confidence
+	Energy      EnergyReport
+	Explanation *Explanation       // nil when the model doesn't produce one
+	Decisions   []Decision         // orchestrator routing steps, if any
+	Plan        *OrchestrationPlan // set when an orchestrator planned instead of executing
+	LimitsHit   []string           // request limits that cut the work short
+	Patterns    []TemporalPattern  // patterns over the liquid brain's recent inputs
+}
+
+// Model is the calling convention shared by all model types
+type Model interface {
+	Respond(ctx context.Context, req Request) (Response, error)
+	Save(path string) error
+	Load(path string) error
+	Close() error
+}
+
+// defaultModelSize is the reservoir size (or orchestrator neuron count)
+// used when none is given
+const defaultModelSize = 30
+
+// ModelType describes a model NewModel can build. Experimental
+// architectures register one under a new name, typically from an init
+// function in their own file, and are then selected with model.type.
+type ModelType struct {
+	// Options returns a fresh pointer that model.options is decoded into;
+	// nil when the type takes no options
+	Options func() any
+	// New builds the model. options is the decoded value, or nil when the
+	// type takes none.
+	New func(config *Config, options any, size int) (Model, error)
+}
+
+var (
+	modelTypesMu sync.RWMutex
+	modelTypes   = map[string]ModelType{}
+)
+
+// RegisterModelType makes a model type available to NewModel under name
+func RegisterModelType(name string, modelType ModelType) error {
+	if name == "" || modelType.New == nil {
+		return fmt.Errorf("model type needs a name and a constructor")
+	}
+	modelTypesMu.Lock()
+	defer modelTypesMu.Unlock()
+
+	if _, exists := modelTypes[name]; exists {
+		return fmt.Errorf("model type %q is already registered", name)
+	}
+	modelTypes[name] = modelType
+	return nil
+}
+
+// ModelTypeNames returns the registered model types, sorted
+func ModelTypeNames() []string {
+	modelTypesMu.RLock()
+	defer modelTypesMu.RUnlock()
+
+	names := make([]string, 0, len(modelTypes))
+	for name := range modelTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupModelType returns the registered type named by model.type, which
+// defaults to "transparent", with model.options decoded for it
+func lookupModelType(model ModelConfig) (ModelType, any, error) {
+	name := model.Type
+	if name == "" {
+		name = "transparent"
+	}
+	modelTypesMu.RLock()
+	modelType, ok := modelTypes[name]
+	modelTypesMu.RUnlock()
+	if !ok {
+		return ModelType{}, nil, fmt.Errorf("unknown model type %q (want one of %v)", name, ModelTypeNames())
+	}
+
+	if modelType.Options == nil {
+		return modelType, nil, nil
+	}
+	options := modelType.Options()
+	if len(model.Options) > 0 {
+		if err := json.Unmarshal(model.Options, options); err != nil {
+			return ModelType{}, nil, fmt.Errorf("invalid options for model type %q: %w", name, err)
+		}
+	}
+	return modelType, options, nil
+}
+
+// NewModel builds the model config.Model.Type names. size is the reservoir
+// size for liquid brains and orchestrators; 0 uses defaultModelSize.
+func NewModel(config *Config, size int) (Model, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if size <= 0 {
+		size = defaultModelSize
+	}
+	modelType, options, err := lookupModelType(config.Model)
+	if err != nil {
+		return nil, err
+	}
+	return modelType.New(config, options, size)
+}
+
+// The built-in model types
+func init() {
+	builtin := map[string]func(config *Config, size int) (Model, bool){
+		"transparent": func(config *Config, size int) (Model, bool) {
+			return NewTransparentLLMWithConfig(config), true
+		},
+		"liquid": func(config *Config, size int) (Model, bool) {
+			brain := NewLiquidStateBrainWithConfig(size, config)
+			return brain, brain != nil
+		},
+		"enhanced": func(config *Config, size int) (Model, bool) {
+			brain := CreateEnhancedBrainWithConfig(size, config)
+			return brain, brain != nil
+		},
+		"orchestrator": func(config *Config, size int) (Model, bool) {
+			orchestrator := NewGenesisOrchestratorWithConfig(size, config)
+			return orchestrator, orchestrator.liquidBrain != nil
+		},
+		"ensemble": func(config *Config, size int) (Model, bool) {
+			ensemble, err := NewLiquidEnsemble(size, config)
+			if err != nil {
+				fmt.Printf("❌ ERROR: %v\n", err)
+			}
+			return ensemble, err == nil
+		},
+		"parallel": func(config *Config, size int) (Model, bool) {
+			po := NewParallelOrchestratorWithSeed(size, config.Seed)
+			po.SetConsensus(config.Consensus)
+			po.SetCapabilityRouting(config.CapabilityRouting)
+			if config.DecisionStore.Path != "" {
+				store, err := OpenDecisionStore(config.DecisionStore.Path)
+				if err != nil {
+					fmt.Printf("⚠️  Warning: flow runs won't be persisted: %v\n", err)
+				} else {
+					if config.Privacy.DecisionLogs {
+						store.SetRedactor(NewRedactor(config.Privacy))
+					}
+					po.SetDecisionStore(store)
+				}
+			}
+			return po, true
+		},
+	}
+	for name, build := range builtin {
+		name, build := name, build
+		RegisterModelType(name, ModelType{New: func(config *Config, options any, size int) (Model, error) {
+			model, ok := build(config, size)
+			if !ok {
+				return nil, fmt.Errorf("failed to create %s model of size %d", name, size)
+			}
+			return model, nil
+		}})
+	}
+}
+
+// modelCheckpoint is the file written by Save
+type modelCheckpoint struct {
+	Version     int                           `json:"version,omitempty"` // checkpointVersion when written; 0 predates versioning
+	Model       string                        `json:"model"`
+	Dimensions  *Dimensions                   `json:"dimensions,omitempty"`
+	States      []float64                     `json:"states,omitempty"`      // reservoir or neuron states, in order
+	Activations map[string]float64            `json:"activations,omitempty"` // concept activations
+	Connections map[string]map[string]float64 `json:"connections,omitempty"` // concept graph: from, to, strength
+	Outputs     map[string]checkpointOutput   `json:"outputs,omitempty"`     // trained output neurons, by meaning
+	Topology    *checkpointTopology           `json:"topology,omitempty"`    // reservoir wiring, see LoadLiquidStateBrain
+	Paused      bool                          `json:"paused,omitempty"`      // the reservoir was paused when saved
+}
+
+// checkpointOutput is a trained output neuron's wiring and weights, and
+// its calibration
+type checkpointOutput struct {
+	Connections [][3]int           `json:"connections"` // x, y, z of each weighted neuron
+	Weights     []float64          `json:"weights"`
+	Bias        float64            `json:"bias"`
+	Calibration *OutputCalibration `json:"calibration,omitempty"`
+}
+
+// Current checkpoint format. Checkpoints from a later version are rejected
+// rather than half read.
+const checkpointVersion = 1
+
+func writeCheckpoint(path string, checkpoint modelCheckpoint) error {
+	checkpoint.Version = checkpointVersion
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// loadCheckpoint reads a checkpoint of any model type
+func loadCheckpoint(path string) (*modelCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var checkpoint modelCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if checkpoint.Version > checkpointVersion {
+		return nil, fmt.Errorf("checkpoint format version %d is newer than this build supports (%d)", checkpoint.Version, checkpointVersion)
+	}
+	return &checkpoint, nil
+}
+
+// readCheckpoint reads a checkpoint, rejecting one saved by another model type
+func readCheckpoint(path, model string) (*modelCheckpoint, error) {
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Model != model {
+		return nil, fmt.Errorf("checkpoint is for a %s model, not %s", checkpoint.Model, model)
+	}
+	return checkpoint, nil
+}
+
+// Respond implements Model
+func (llm *TransparentLLM) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "transparent model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	budget, err := startBudget(llm.limits, req)
+	if err != nil {
+		return Response{}, err
+	}
+	ctx, cancel := budget.context(ctx)
+	defer cancel()
+	output, explanation, visualization := llm.UnderstandContext(ctx, req.Input, budget.options(GenerationOptions{Seed: req.Seed}))
+	for range visualization {
+		// Drain so the streamer can finish
+	}
+	response := Response{Output: output, Explanation: explanation, LimitsHit: budget.Hit()}
+	if explanation != nil {
+		response.Confidence = explanation.Confidence
+		response.Energy = explanation.Energy
+		if explanation.Error != "" {
+			return response, fmt.Errorf("generation failed: %s", explanation.Error)
+		}
+	}
+	return response, nil
+}
+
+// Save implements Model, checkpointing concept activations. The concept
+// graph is saved too, for auditing drift with `genesis diff`; Load doesn't
+// restore it, since the graph is rebuilt from the corpus.
+func (llm *TransparentLLM) Save(path string) error {
+	activations := make(map[string]float64)
+	connections := make(map[string]map[string]float64)
+	llm.concepts.Range(func(id string, neuron *ConceptNeuron) bool {
+		activations[id] = neuron.getActivation()
+		if len(neuron.connections) > 0 {
+			connections[id] = make(map[string]float64, len(neuron.connections))
+			for to, conn := range neuron.connections {
+				connections[id][to] = conn.strength
+			}
+		}
+		return true
+	})
+	return writeCheckpoint(path, modelCheckpoint{Model: "transparent", Activations: activations, Connections: connections})
+}
+
+// Load implements Model. Concepts missing from this network are skipped.
+func (llm *TransparentLLM) Load(path string) error {
+	checkpoint, err := readCheckpoint(path, "transparent")
+	if err != nil {
+		return err
+	}
+	for id, activation := range checkpoint.Activations {
+		if neuron, ok := llm.concepts.Get(id); ok {
+			neuron.activation.Store(activation)
+		}
+	}
+	ModelUpdated()
+	return nil
+}
+
+// Close implements Model
+func (llm *TransparentLLM) Close() error {
+	llm.Cleanup()
+	return nil
+}
+
+// Respond implements Model
+func (brain *LiquidStateBrain) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "liquid model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	budget, err := startBudget(brain.config.RequestLimits, req)
+	if err != nil {
+		return Response{}, err
+	}
+	output, confidence, energy, err := brain.ThinkContext(ctx, req.Input, budget.options(GenerationOptions{Seed: req.Seed}))
+	if err != nil {
+		return Response{Energy: energy}, err
+	}
+	return Response{Output: output, Confidence: confidence, Energy: energy, LimitsHit: budget.Hit(), Patterns: brain.history.Patterns()}, nil
+}
+
+// Save implements Model, checkpointing the reservoir state and wiring
+func (brain *LiquidStateBrain) Save(path string) error {
+	dims := brain.dimensions
+	checkpoint := modelCheckpoint{
+		Model:      "liquid",
+		Dimensions: &dims,
+		States:     brain.StateSnapshot(),
+		Topology:   brain.Template().topology(),
+		Paused:     brain.Paused(),
+	}
+	for _, output := range brain.outputLayer {
+		w, calibration := output.weights.Load(), output.calibration.Load()
+		if w == nil && calibration == nil {
+			continue
+		}
+		saved := checkpointOutput{Calibration: calibration}
+		if w != nil {
+			saved.Weights, saved.Bias = w.weights, w.bias
+			for _, n := range w.connections {
+				saved.Connections = append(saved.Connections, [3]int{n.x, n.y, n.z})
+			}
+		}
+		if checkpoint.Outputs == nil {
+			checkpoint.Outputs = make(map[string]checkpointOutput)
+		}
+		checkpoint.Outputs[output.meaning] = saved
+	}
+	return writeCheckpoint(path, checkpoint)
+}
+
+// Load implements Model. The checkpoint must come from a reservoir of the
+// same dimensions.
+func (brain *LiquidStateBrain) Load(path string) error {
+	checkpoint, err := readCheckpoint(path, "liquid")
+	if err != nil {
+		return err
+	}
+	if err := brain.restore(checkpoint); err != nil {
+		return err
+	}
+	ModelUpdated()
+	return nil
+}
+
+// restore applies a liquid checkpoint's states, output weights and
+// calibrations to a reservoir of the same dimensions
+func (brain *LiquidStateBrain) restore(checkpoint *modelCheckpoint) error {
+	dims := brain.dimensions
+	if checkpoint.Dimensions == nil || *checkpoint.Dimensions != dims || len(checkpoint.States) != dims.X*dims.Y*dims.Z {
+		return fmt.Errorf("checkpoint reservoir doesn't match %dx%dx%d", dims.X, dims.Y, dims.Z)
+	}
+	i := 0
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				brain.reservoir[x][y][z].state.Store(checkpoint.States[i])
+				i++
+			}
+		}
+	}
+	for _, output := range brain.outputLayer {
+		saved, ok := checkpoint.Outputs[output.meaning]
+		if !ok {
+			continue
+		}
+		if len(saved.Connections) != len(saved.Weights) {
+			return fmt.Errorf("checkpoint output %q has %d connections but %d weights", output.meaning, len(saved.Connections), len(saved.Weights))
+		}
+		if c := saved.Calibration; c != nil {
+			if len(c.Quantiles) < 2 || c.ActiveShare <= 0 || c.ActiveShare >= 1 {
+				return fmt.Errorf("checkpoint output %q has an invalid calibration", output.meaning)
+			}
+			output.calibration.Store(c)
+		}
+		if saved.Weights == nil {
+			continue
+		}
+		w := &outputWeights{weights: saved.Weights, bias: saved.Bias}
+		for _, c := range saved.Connections {
+			if c[0] < 0 || c[0] >= dims.X || c[1] < 0 || c[1] >= dims.Y || c[2] < 0 || c[2] >= dims.Z {
+				return fmt.Errorf("checkpoint output %q connects outside the reservoir", output.meaning)
+			}
+			w.connections = append(w.connections, brain.reservoir[c[0]][c[1]][c[2]])
+		}
+		output.weights.Store(w)
+	}
+	return nil
+}
+
+// Close implements Model
+func (brain *LiquidStateBrain) Close() error {
+	brain.Cleanup()
+	return nil
+}
+
+// Respond implements Model, letting the brain's tiny models contribute.
+// Save, Load and Close are the reservoir's.
+func (brain *EnhancedLiquidBrain) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "enhanced model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	before := brain.energy.Snapshot()
+	output := brain.ProcessWithModels(req.Input)
+	return Response{Output: output, Energy: brain.energy.Snapshot().Sub(before)}, nil
+}
+
+// Respond implements Model, reporting the routing decisions with the output,
+// or only the plan for dry runs
+func (go_ *GenesisOrchestrator) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "orchestrator model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	if req.DryRun {
+		plan := go_.PlanWithPolicy(req.Input, req.Policy)
+		return Response{Output: plan.Summary, Confidence: plan.Confidence, Decisions: withPolicy(plan.decisions(), req.Policy), Plan: &plan}, nil
+	}
+	budget, err := startBudget(go_.liquidBrain.config.RequestLimits, req)
+	if err != nil {
+		return Response{}, err
+	}
+	ctx, cancel := budget.context(ctx)
+	defer cancel()
+	output, decisions := go_.process(ctx, req.Input, req.Policy, budget.options(GenerationOptions{}))
+	response := Response{Output: output, Decisions: decisions, LimitsHit: budget.Hit()}
+	for _, d := range decisions {
+		response.Energy = response.Energy.Add(d.Energy)
+	}
+	if len(decisions) > 0 {
+		response.Confidence = decisions[len(decisions)-1].Confidence
+	}
+	return response, nil
+}
+
+// Save implements Model, checkpointing the orchestrator's liquid brain
+func (go_ *GenesisOrchestrator) Save(path string) error {
+	if go_.liquidBrain == nil {
+		return fmt.Errorf("orchestrator has no liquid brain")
+	}
+	return go_.liquidBrain.Save(path)
+}
+
+// Load implements Model
+func (go_ *GenesisOrchestrator) Load(path string) error {
+	if go_.liquidBrain == nil {
+		return fmt.Errorf("orchestrator has no liquid brain")
+	}
+	return go_.liquidBrain.Load(path)
+}
+
+// Close implements Model, closing any distillation dataset
+func (go_ *GenesisOrchestrator) Close() error {
+	if go_.liquidBrain != nil {
+		go_.liquidBrain.Cleanup()
+	}
+	go_.mu.RLock()
+	distill := go_.distill
+	go_.mu.RUnlock()
+	return distill.Close()
+}
+
+// Respond implements Model
+func (po *ParallelOrchestrator) Respond(ctx context.Context, req Request) (_ Response, err error) {
+	defer recoverError(&err, "parallel model")
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	return Response{Output: po.ProcessInParallel(req.Input)}, nil
+}
+
+// Save implements Model, checkpointing neuron activations
+func (po *ParallelOrchestrator) Save(path string) error {
+	states := make([]float64, len(po.neurons))
+	for i, n := range po.neurons {
+		states[i] = n.activation.Load().(float64)
+	}
+	return writeCheckpoint(path, modelCheckpoint{Model: "parallel", States: states})
+}
+
+// Load implements Model. The checkpoint must have one state per neuron.
+func (po *ParallelOrchestrator) Load(path string) error {
+	checkpoint, err := readCheckpoint(path, "parallel")
+	if err != nil {
+		return err
+	}
+	if len(checkpoint.States) != len(po.neurons) {
+		return fmt.Errorf("checkpoint has %d neurons, orchestrator has %d", len(checkpoint.States), len(po.neurons))
+	}
+	for i, n := range po.neurons {
+		n.activation.Store(checkpoint.States[i])
+	}
+	return nil
+}
+
+// Close implements Model, closing the decision store; the orchestrator
+// holds no background work
+func (po *ParallelOrchestrator) Close() error {
+	return po.store.Close()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Neuromodulation: global signals such as reward (from feedback on
+// responses) and attention (from how salient an input is) that wash over a
+// region of the reservoir and scale it. Each channel has a level in
+// [-1, 1] that fades back to 0 with a half-life. Where a channel reaches,
+// its level times the channel's gains scales how fast neuron states decay
+// and how strongly learning rules such as output training update, so
+// learning can be gated by outcome signals.
+
+// Built-in channels with automatic sources
+const (
+	modulationReward    = "reward"    // set from feedback ratings
+	modulationAttention = "attention" // set from each Think input's salience
+)
+
+// Resting decay: an idle neuron keeps this share of its state per tick
+const neuronRetention = 0.95
+
+// ModulationConfig defines the reservoir's modulation channels
+type ModulationConfig struct {
+	Enabled    bool                `json:"enabled"`
+	HalfLifeMS int                 `json:"half_life_ms"` // how fast levels fade back to 0
+	Channels   []ModulationChannel `json:"channels"`
+}
+
+// ModulationChannel is one global signal and what it scales
+type ModulationChannel struct {
+	Name           string     `json:"name"`
+	Region         *TapRegion `json:"region,omitempty"` // nil reaches the whole reservoir
+	DecayGain      float64    `json:"decay_gain"`       // decay rate scales by 1 + gain * level; negative slows decay
+	PlasticityGain float64    `json:"plasticity_gain"`  // learning rate scales by 1 + gain * level
+}
+
+func (c ModulationConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.HalfLifeMS <= 0 {
+		return fmt.Errorf("modulation half_life_ms must be positive")
+	}
+	names := make(map[string]bool, len(c.Channels))
+	for _, channel := range c.Channels {
+		if channel.Name == "" || names[channel.Name] {
+			return fmt.Errorf("modulation channel names must be unique and non-empty, got %q", channel.Name)
+		}
+		names[channel.Name] = true
+		if err := channel.Region.validate(); err != nil {
+			return fmt.Errorf("modulation channel %q: %w", channel.Name, err)
+		}
+	}
+	return nil
+}
+
+// modulationLevels is a snapshot of the channel levels as set at a time
+type modulationLevels struct {
+	levels []float64 // in channel order
+	at     []time.Time
+}
+
+// Modulator holds a reservoir's modulation levels. A nil Modulator scales
+// nothing.
+type Modulator struct {
+	config   ModulationConfig
+	clock    Clock
+	halfLife time.Duration
+	mu       sync.Mutex // serializes Set
+	state    atomic.Pointer[modulationLevels]
+}
+
+// NewModulator creates a modulator for config's channels, at rest
+func NewModulator(config ModulationConfig, clock Clock) *Modulator {
+	m := &Modulator{config: config, clock: clock, halfLife: time.Duration(config.HalfLifeMS) * time.Millisecond}
+	now := clock.Now()
+	state := &modulationLevels{levels: make([]float64, len(config.Channels)), at: make([]time.Time, len(config.Channels))}
+	for i := range state.at {
+		state.at[i] = now
+	}
+	m.state.Store(state)
+	return m
+}
+
+// channel returns the index of the channel called name, or -1
+func (m *Modulator) channel(name string) int {
+	for i, channel := range m.config.Channels {
+		if channel.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set sets a channel's level, clamped to [-1, 1]
+func (m *Modulator) Set(name string, level float64) error {
+	if m == nil {
+		return fmt.Errorf("modulation is disabled")
+	}
+	i := m.channel(name)
+	if i < 0 {
+		return fmt.Errorf("unknown modulation channel %q", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.state.Load()
+	next := &modulationLevels{levels: append([]float64{}, old.levels...), at: append([]time.Time{}, old.at...)}
+	next.levels[i] = math.Max(-1, math.Min(1, level))
+	next.at[i] = m.clock.Now()
+	m.state.Store(next)
+	return nil
+}
+
+// Level returns a channel's current level, 0 for unknown channels
+func (m *Modulator) Level(name string) float64 {
+	if m == nil {
+		return 0
+	}
+	i := m.channel(name)
+	if i < 0 {
+		return 0
+	}
+	return m.level(m.state.Load(), i)
+}
+
+// level returns channel i's level in state, faded since it was set
+func (m *Modulator) level(state *modulationLevels, i int) float64 {
+	if state.levels[i] == 0 {
+		return 0
+	}
+	elapsed := clockSince(m.clock, state.at[i])
+	return state.levels[i] * math.Pow(0.5, float64(elapsed)/float64(m.halfLife))
+}
+
+// scale multiplies 1 + gain * level over the given channels, never below 0
+func (m *Modulator) scale(channels []int, gain func(ModulationChannel) float64) float64 {
+	if m == nil || len(channels) == 0 {
+		return 1
+	}
+	state := m.state.Load()
+	scale := 1.0
+	for _, i := range channels {
+		scale *= math.Max(0, 1+gain(m.config.Channels[i])*m.level(state, i))
+	}
+	return scale
+}
+
+// retention returns the share of its state a neuron in channels keeps per
+// tick
+func (m *Modulator) retention(channels []int) float64 {
+	decay := (1 - neuronRetention) * m.scale(channels, func(c ModulationChannel) float64 { return c.DecayGain })
+	return clamp01(1 - decay)
+}
+
+// plasticity returns the learning rate scale for a neuron in channels
+func (m *Modulator) plasticity(channels []int) float64 {
+	return m.scale(channels, func(c ModulationChannel) float64 { return c.PlasticityGain })
+}
+
+// FeedbackListener returns a SessionHandler feedback listener that sets
+// the reward channel from each rating, clamped to [-1, 1]. Under resource
+// pressure the update waits until full behavior is restored.
+func (m *Modulator) FeedbackListener() func(sessionID string, fb Feedback) {
+	return func(sessionID string, fb Feedback) {
+		SharedDegrader().Defer(func() {
+			if err := m.Set(modulationReward, float64(fb.Rating)); err != nil {
+				fmt.Printf("⚠️  Warning: %v\n", err)
+			}
+		})
+	}
+}
+
+// initializeModulation creates the brain's modulator and records which
+// channels reach each neuron
+func (brain *LiquidStateBrain) initializeModulation() {
+	config := brain.config.Modulation
+	if !config.Enabled || len(config.Channels) == 0 {
+		return
+	}
+	brain.modulator = NewModulator(config, brain.clock)
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for z, neuron := range brain.reservoir[x][y] {
+				neuron.modulator = brain.modulator
+				neuron.channels = nil
+				for i, channel := range config.Channels {
+					if channel.Region == nil || channel.Region.contains(brain.dimensions, x, y, z) {
+						neuron.channels = append(neuron.channels, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Modulator returns the brain's modulation channels; nil when disabled
+func (brain *LiquidStateBrain) Modulator() *Modulator {
+	return brain.modulator
+}
+
+// Plasticity returns the learning rate scale at a reservoir neuron
+func (brain *LiquidStateBrain) Plasticity(x, y, z int) float64 {
+	return brain.modulator.plasticity(brain.reservoir[x][y][z].channels)
+}
+
+// meanPlasticity returns the mean learning rate scale over neurons
+func meanPlasticity(neurons []*LiquidNeuron) float64 {
+	if len(neurons) == 0 {
+		return 1
+	}
+	total := 0.0
+	for _, n := range neurons {
+		total += n.modulator.plasticity(n.channels)
+	}
+	return total / float64(len(neurons))
+}
+
+// attend sets the attention channel from the share of input words that are
+// keywords, going by their keyword weight
+func (brain *LiquidStateBrain) attend(words []string, weight func(string) float64) {
+	if brain.modulator == nil || brain.modulator.channel(modulationAttention) < 0 || len(words) == 0 {
+		return
+	}
+	salient := 0
+	for _, word := range words {
+		if weight(word) > 0 {
+			salient++
+		}
+	}
+	brain.modulator.Set(modulationAttention, float64(salient)/float64(len(words)))
+}
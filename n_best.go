@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// N-best responses: instead of a single string, callers can ask for the top
+// N distinct responses the beam search ended with, best first, each with its
+// response score and its softmax share among the final beams. Orchestration
+// layers, rerankers and A/B harnesses can then choose among alternatives.
+// Token hooks' final pass only sees the chosen response.
+
+// maxNBest caps GenerationOptions.N
+const maxNBest = 10
+
+// ResponseCandidate is one of the N best responses
+type ResponseCandidate struct {
+	Response    string  `json:"response"`
+	Score       float64 `json:"score"`               // the generator's response score
+	Probability float64 `json:"probability"`         // softmax share among the final beams
+	Coherence   float64 `json:"coherence,omitempty"` // fit with the session history, when reranked by it
+}
+
+// alternatives returns up to gen.nBest distinct responses from the final
+// beams, best first; the first is best's
+func (gen *ResponseGenerator) alternatives(beams []Beam, best Beam) []ResponseCandidate {
+	if gen.nBest <= 0 {
+		return nil
+	}
+	scores := make([]float64, len(beams))
+	for i, beam := range beams {
+		scores[i] = gen.scoreResponse(beam)
+	}
+	order := make([]int, len(beams))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	candidate := func(beam Beam, score float64) ResponseCandidate {
+		words, _ := gen.limitWords(append([]string{}, beam.words...))
+		return ResponseCandidate{
+			Response:    gen.formatResponse(Beam{words: words}),
+			Score:       score,
+			Probability: beamProbability(score, scores),
+		}
+	}
+	first := candidate(best, gen.scoreResponse(best))
+	candidates := []ResponseCandidate{first}
+	seen := map[string]bool{strings.ToLower(first.Response): true}
+	for _, i := range order {
+		if len(candidates) >= gen.nBest {
+			break
+		}
+		c := candidate(beams[i], scores[i])
+		if key := strings.ToLower(c.Response); !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// GenerateNBest generates a response and returns the n best distinct
+// responses, best first
+func (gen *ResponseGenerator) GenerateNBest(input string, activeConcepts []string, n int) ([]ResponseCandidate, *Explanation) {
+	_, explanation := gen.GenerateWithOptions(input, activeConcepts, GenerationOptions{N: n})
+	return explanation.Alternatives, explanation
+}
+
+// UnderstandNBest is UnderstandExplained returning the n best distinct
+// responses, best first. Responses that weren't generated, such as
+// clarification questions, come back as the only candidate.
+func (llm *TransparentLLM) UnderstandNBest(input string, n int) ([]ResponseCandidate, *Explanation, error) {
+	if n < 1 || n > maxNBest {
+		return nil, nil, fmt.Errorf("n must be between 1 and %d", maxNBest)
+	}
+	response, explanation, visualization := llm.understand(context.Background(), input, GenerationOptions{N: n})
+	for range visualization {
+		// Drain so the streamer can finish
+	}
+	if explanation == nil {
+		explanation = &Explanation{Input: input, Response: response}
+	}
+	candidates := explanation.Alternatives
+	if len(candidates) == 0 {
+		candidates = []ResponseCandidate{{Response: response, Score: explanation.Confidence.Score, Probability: 1}}
+	}
+	return candidates, explanation, nil
+}
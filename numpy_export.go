@@ -0,0 +1,499 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// NumPy export lets researchers load Genesis internals straight into
+// notebooks with np.load, without custom parsers. Everything is written in
+// the .npy v1.0 format (little-endian, C order); .npz files are plain zip
+// archives of .npy members, exactly what np.savez produces.
+
+// writeNpyHeader writes the magic string and the header dict for an array
+func writeNpyHeader(w io.Writer, descr string, shape []int) error {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = fmt.Sprintf("%d", d)
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shapeStr)
+
+	// Magic (6) + version (2) + header length (2) + header must be a
+	// multiple of 64, with the header terminated by a newline
+	total := 10 + len(header) + 1
+	if pad := total % 64; pad != 0 {
+		header += strings.Repeat(" ", 64-pad)
+	}
+	header += "\n"
+
+	if _, err := w.Write([]byte("\x93NUMPY\x01\x00")); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(header))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, header)
+	return err
+}
+
+// WriteNpyFloat64 writes data as a float64 array of the given shape
+func WriteNpyFloat64(w io.Writer, shape []int, data []float64) error {
+	if err := checkShape(shape, len(data)); err != nil {
+		return err
+	}
+	if err := writeNpyHeader(w, "<f8", shape); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 8)
+	for _, v := range data {
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteNpyInt64 writes data as an int64 array of the given shape
+func WriteNpyInt64(w io.Writer, shape []int, data []int64) error {
+	if err := checkShape(shape, len(data)); err != nil {
+		return err
+	}
+	if err := writeNpyHeader(w, "<i8", shape); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 8)
+	for _, v := range data {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteNpyStrings writes a 1-D unicode array (dtype <U), as NumPy stores str arrays
+func WriteNpyStrings(w io.Writer, values []string) error {
+	width := 1
+	for _, v := range values {
+		if n := utf8.RuneCountInString(v); n > width {
+			width = n
+		}
+	}
+	if err := writeNpyHeader(w, fmt.Sprintf("<U%d", width), []int{len(values)}); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 4)
+	for _, v := range values {
+		written := 0
+		for _, r := range v {
+			binary.LittleEndian.PutUint32(buf, uint32(r))
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+			written++
+		}
+		// Pad each element to the fixed width with NUL code points
+		for ; written < width; written++ {
+			if _, err := bw.Write([]byte{0, 0, 0, 0}); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func checkShape(shape []int, n int) error {
+	expected := 1
+	for _, d := range shape {
+		expected *= d
+	}
+	if expected != n {
+		return fmt.Errorf("shape %v needs %d values, got %d", shape, expected, n)
+	}
+	return nil
+}
+
+// NpzWriter writes named arrays into an .npz archive
+type NpzWriter struct {
+	file *os.File
+	zip  *zip.Writer
+}
+
+func NewNpzWriter(path string) (*NpzWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return &NpzWriter{file: file, zip: zip.NewWriter(file)}, nil
+}
+
+func (nw *NpzWriter) member(name string) (io.Writer, error) {
+	return nw.zip.CreateHeader(&zip.FileHeader{
+		Name:     name + ".npy",
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+func (nw *NpzWriter) AddFloat64(name string, shape []int, data []float64) error {
+	w, err := nw.member(name)
+	if err != nil {
+		return err
+	}
+	return WriteNpyFloat64(w, shape, data)
+}
+
+func (nw *NpzWriter) AddInt64(name string, shape []int, data []int64) error {
+	w, err := nw.member(name)
+	if err != nil {
+		return err
+	}
+	return WriteNpyInt64(w, shape, data)
+}
+
+func (nw *NpzWriter) AddStrings(name string, values []string) error {
+	w, err := nw.member(name)
+	if err != nil {
+		return err
+	}
+	return WriteNpyStrings(w, values)
+}
+
+// Close finalizes the archive
+func (nw *NpzWriter) Close() error {
+	if err := nw.zip.Close(); err != nil {
+		nw.file.Close()
+		return err
+	}
+	return nw.file.Close()
+}
+
+// ExportEmbeddingsNpz writes the vocabulary ("vocab", shape V) and its
+// embedding matrix ("embeddings", shape V x D) to an .npz file
+func ExportEmbeddingsNpz(dl *DatasetLoader, path string) error {
+	vocab := dl.GetVocabulary()
+	if len(vocab) == 0 {
+		return fmt.Errorf("vocabulary is empty")
+	}
+
+	dim := 0
+	if emb, ok := dl.GetEmbedding(vocab[0]); ok {
+		dim = len(emb)
+	}
+
+	matrix := make([]float64, 0, len(vocab)*dim)
+	for _, word := range vocab {
+		emb, ok := dl.GetEmbedding(word)
+		if !ok || len(emb) != dim {
+			emb = make([]float64, dim)
+		}
+		matrix = append(matrix, emb...)
+	}
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := nw.AddStrings("vocab", vocab); err != nil {
+		nw.Close()
+		return err
+	}
+	if err := nw.AddFloat64("embeddings", []int{len(vocab), dim}, matrix); err != nil {
+		nw.Close()
+		return err
+	}
+	return nw.Close()
+}
+
+// ExportTransitionsNpz writes the word transition matrix in COO form:
+// "rows", "cols" and "probs" index into "vocab", so
+// scipy.sparse.coo_matrix((probs, (rows, cols))) rebuilds it
+func ExportTransitionsNpz(dl *DatasetLoader, path string) error {
+	vocab := dl.GetVocabulary()
+	index := make(map[string]int64, len(vocab))
+	for i, word := range vocab {
+		index[word] = int64(i)
+	}
+
+	var rows, cols []int64
+	var probs []float64
+	for i, word := range vocab {
+		transitions, ok := dl.GetTransitions(word)
+		if !ok {
+			continue
+		}
+		for next, prob := range transitions {
+			j, ok := index[next]
+			if !ok {
+				continue
+			}
+			rows = append(rows, int64(i))
+			cols = append(cols, j)
+			probs = append(probs, prob)
+		}
+	}
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	for _, add := range []func() error{
+		func() error { return nw.AddStrings("vocab", vocab) },
+		func() error { return nw.AddInt64("rows", []int{len(rows)}, rows) },
+		func() error { return nw.AddInt64("cols", []int{len(cols)}, cols) },
+		func() error { return nw.AddFloat64("probs", []int{len(probs)}, probs) },
+	} {
+		if err := add(); err != nil {
+			nw.Close()
+			return err
+		}
+	}
+	return nw.Close()
+}
+
+// StateSnapshot returns every neuron's state flattened in x, y, z order
+func (brain *LiquidStateBrain) StateSnapshot() []float64 {
+	dims := brain.dimensions
+	states := make([]float64, 0, dims.X*dims.Y*dims.Z)
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				var state float64
+				if val := brain.reservoir[x][y][z].state.Load(); val != nil {
+					state = val.(float64)
+				}
+				states = append(states, state)
+			}
+		}
+	}
+	return states
+}
+
+// RecordStates samples the reservoir state every interval, returning a
+// samples x neurons matrix in row-major order
+func (brain *LiquidStateBrain) RecordStates(samples int, interval time.Duration) []float64 {
+	series := make([]float64, 0, samples*brain.dimensions.X*brain.dimensions.Y*brain.dimensions.Z)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; i < samples; i++ {
+		series = append(series, brain.StateSnapshot()...)
+		if i < samples-1 {
+			select {
+			case <-brain.ctx.Done():
+				return series
+			case <-ticker.C:
+			}
+		}
+	}
+	return series
+}
+
+// ExportReservoirStatesNpz records a state time-series and writes it as
+// "states" (T x X x Y x Z) plus the sampling interval in milliseconds
+func ExportReservoirStatesNpz(brain *LiquidStateBrain, path string, samples int, interval time.Duration) error {
+	series := brain.RecordStates(samples, interval)
+	dims := brain.dimensions
+	recorded := len(series) / (dims.X * dims.Y * dims.Z)
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := nw.AddFloat64("states", []int{recorded, dims.X, dims.Y, dims.Z}, series); err != nil {
+		nw.Close()
+		return err
+	}
+	if err := nw.AddFloat64("interval_ms", []int{1}, []float64{durationMs(interval)}); err != nil {
+		nw.Close()
+		return err
+	}
+	return nw.Close()
+}
+
+// ExportFeaturesNpz runs each input through the reservoir, from a reset
+// state, and writes the inputs ("inputs", N) and their state vectors
+// ("features", N x D), ready for training a classifier
+func ExportFeaturesNpz(brain *LiquidStateBrain, path string, inputs []string, pooling StatePooling) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no inputs to extract features for")
+	}
+	features := make([]float64, 0, len(inputs)*brain.StateVectorLen(pooling))
+	for _, input := range inputs {
+		features = append(features, brain.Features(input, pooling)...)
+	}
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := nw.AddStrings("inputs", inputs); err != nil {
+		nw.Close()
+		return err
+	}
+	if err := nw.AddFloat64("features", []int{len(inputs), brain.StateVectorLen(pooling)}, features); err != nil {
+		nw.Close()
+		return err
+	}
+	return nw.Close()
+}
+
+// ExportConceptsNpz writes concept ids, current activations and semantic
+// vectors of a TransparentLLM
+func ExportConceptsNpz(llm *TransparentLLM, path string) error {
+	var ids []string
+	var activations []float64
+	var vectors []float64
+	dim := -1
+
+	llm.concepts.Range(func(id string, neuron *ConceptNeuron) bool {
+		if dim < 0 {
+			dim = len(neuron.meaning)
+		}
+		vec := neuron.meaning
+		if len(vec) != dim {
+			vec = make([]float64, dim)
+		}
+		ids = append(ids, id)
+		activations = append(activations, neuron.getActivation())
+		vectors = append(vectors, vec...)
+		return true
+	})
+	if dim < 0 {
+		return fmt.Errorf("no concepts to export")
+	}
+
+	nw, err := NewNpzWriter(path)
+	if err != nil {
+		return err
+	}
+	for _, add := range []func() error{
+		func() error { return nw.AddStrings("ids", ids) },
+		func() error { return nw.AddFloat64("activations", []int{len(activations)}, activations) },
+		func() error { return nw.AddFloat64("meanings", []int{len(ids), dim}, vectors) },
+	} {
+		if err := add(); err != nil {
+			nw.Close()
+			return err
+		}
+	}
+	return nw.Close()
+}
+
+// ExportMain implements `go run . export`
+func ExportMain(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	outDir := fs.String("out", "export", "Output directory")
+	brainSize := fs.Int("brain-size", 10, "Liquid brain size for reservoir recording (0 to skip)")
+	samples := fs.Int("samples", 50, "Reservoir state samples to record")
+	interval := fs.Duration("interval", 20*time.Millisecond, "Reservoir sampling interval")
+	featuresPath := fs.String("features", "", "File of inputs, one per line, to extract reservoir features for")
+	pool := fs.Int("pool", 1, "Side of the neuron blocks averaged into each feature")
+	sparse := fs.String("connectivity", SparseCSR, "Reservoir connectivity format: csr or coo (.npz), mtx (Matrix Market), or none")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		fmt.Printf("❌ ERROR: failed to load datasets: %v\n", err)
+		os.Exit(1)
+	}
+
+	exports := []struct {
+		name string
+		fn   func(string) error
+	}{
+		{"embeddings.npz", func(p string) error { return ExportEmbeddingsNpz(loader, p) }},
+		{"transitions.npz", func(p string) error { return ExportTransitionsNpz(loader, p) }},
+	}
+	if *brainSize > 0 {
+		// The recorded states and the connectivity come from the same brain,
+		// so external simulations of the wiring can be compared against them
+		brain := NewLiquidStateBrainWithConfig(*brainSize, config)
+		if brain == nil {
+			fmt.Println("❌ ERROR: failed to create brain")
+			os.Exit(1)
+		}
+		defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
+		exports = append(exports, struct {
+			name string
+			fn   func(string) error
+		}{"reservoir_states.npz", func(p string) error {
+			return ExportReservoirStatesNpz(brain, p, *samples, *interval)
+		}})
+		if *sparse != "none" {
+			name := "connectivity.npz"
+			if *sparse == "mtx" {
+				name = "connectivity.mtx"
+			}
+			exports = append(exports, struct {
+				name string
+				fn   func(string) error
+			}{name, func(p string) error { return ExportConnectivity(brain, p, *sparse) }})
+		}
+	}
+	if *featuresPath != "" && *brainSize > 0 {
+		exports = append(exports, struct {
+			name string
+			fn   func(string) error
+		}{"features.npz", func(p string) error {
+			data, err := os.ReadFile(*featuresPath)
+			if err != nil {
+				return err
+			}
+			var inputs []string
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					inputs = append(inputs, line)
+				}
+			}
+			brain := NewLiquidStateBrainWithConfig(*brainSize, config)
+			if brain == nil {
+				return fmt.Errorf("failed to create brain")
+			}
+			defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
+			return ExportFeaturesNpz(brain, p, inputs, StatePooling{Size: *pool})
+		}})
+	}
+
+	for _, e := range exports {
+		path := filepath.Join(*outDir, e.name)
+		if err := e.fn(path); err != nil {
+			fmt.Printf("⚠️  Warning: failed to export %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("✅ Exported %s\n", path)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenInference export: teams running LLM observability stacks (Phoenix,
+// LangSmith and others that speak OpenInference) can ingest Genesis traces
+// without custom adapters. Responses map onto spans in OpenInference's JSON
+// span format, one span per line:
+//
+//	{name, context: {trace_id, span_id}, parent_id?, span_kind, start_time, end_time,
+//	 status_code, status_message?, attributes: {...}}
+//
+// Attributes follow OpenInference's semantic conventions (input.value,
+// output.value, llm.model_name, llm.token_count.*, tool.name,
+// retrieval.documents.N.document.*), with what they can't express under
+// genesis.*. The mapping:
+//
+//	transparent model  LLM span, a CHAIN span per thought, a RETRIEVER span for retrieved chunks
+//	liquid brain       LLM span
+//	orchestrator       AGENT span, an LLM span for the brain's steps and a TOOL span per capability call
+//	comparison         CHAIN span over each model's spans
+//
+// Genesis tokens are words, so token counts are word counts. With
+// openinference.path set, server mode appends the spans of every
+// /v1/think, /v1/understand and /v1/compare request to that file, redacted
+// like decision logs when privacy.decision_logs is on.
+
+// OpenInferenceConfig controls span export
+type OpenInferenceConfig struct {
+	Path string `json:"path"` // JSON lines file spans are appended to; "" turns export off
+}
+
+// OpenInference span kinds
+const (
+	spanKindLLM       = "LLM"
+	spanKindChain     = "CHAIN"
+	spanKindTool      = "TOOL"
+	spanKindAgent     = "AGENT"
+	spanKindRetriever = "RETRIEVER"
+)
+
+// Span status codes
+const (
+	spanStatusOK    = "OK"
+	spanStatusError = "ERROR"
+)
+
+// SpanContext identifies a span within its trace
+type SpanContext struct {
+	TraceID string `json:"trace_id"` // 32 hex digits
+	SpanID  string `json:"span_id"`  // 16 hex digits
+}
+
+// OpenInferenceSpan is one span in OpenInference's JSON span format
+type OpenInferenceSpan struct {
+	Name          string         `json:"name"`
+	Context       SpanContext    `json:"context"`
+	ParentID      string         `json:"parent_id,omitempty"`
+	SpanKind      string         `json:"span_kind"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       time.Time      `json:"end_time"`
+	StatusCode    string         `json:"status_code"`
+	StatusMessage string         `json:"status_message,omitempty"`
+	Attributes    map[string]any `json:"attributes"`
+}
+
+// TracedThought is one of Understand's thoughts and when it happened
+type TracedThought struct {
+	Thought ThoughtTrace
+	At      time.Time
+}
+
+// spanTrace builds the spans of one trace
+type spanTrace struct {
+	id    string
+	spans []OpenInferenceSpan
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf) // never fails
+	return hex.EncodeToString(buf)
+}
+
+func newSpanTrace() *spanTrace {
+	return &spanTrace{id: randomHex(16)}
+}
+
+// span adds a span and returns it for filling in; the pointer is valid
+// until the next span is added
+func (t *spanTrace) span(name, kind, parent string, start, end time.Time) *OpenInferenceSpan {
+	if end.Before(start) {
+		end = start
+	}
+	t.spans = append(t.spans, OpenInferenceSpan{
+		Name:       name,
+		Context:    SpanContext{TraceID: t.id, SpanID: randomHex(8)},
+		ParentID:   parent,
+		SpanKind:   kind,
+		StartTime:  start.UTC(),
+		EndTime:    end.UTC(),
+		StatusCode: spanStatusOK,
+		Attributes: map[string]any{"openinference.span.kind": kind},
+	})
+	return &t.spans[len(t.spans)-1]
+}
+
+// fail marks the span failed
+func (s *OpenInferenceSpan) fail(message string) {
+	s.StatusCode = spanStatusError
+	s.StatusMessage = message
+}
+
+// io sets the span's input and output
+func (s *OpenInferenceSpan) io(input, output string) {
+	s.Attributes["input.value"] = input
+	s.Attributes["input.mime_type"] = "text/plain"
+	s.Attributes["output.value"] = output
+	s.Attributes["output.mime_type"] = "text/plain"
+}
+
+// llm sets a model span's model and word counts
+func (s *OpenInferenceSpan) llm(model, input, output string) {
+	s.io(input, output)
+	prompt, completion := len(strings.Fields(input)), len(strings.Fields(output))
+	s.Attributes["llm.model_name"] = "genesis-" + model
+	s.Attributes["llm.token_count.prompt"] = prompt
+	s.Attributes["llm.token_count.completion"] = completion
+	s.Attributes["llm.token_count.total"] = prompt + completion
+}
+
+// scores sets Genesis's confidence, energy and limits on the span
+func (s *OpenInferenceSpan) scores(confidence Confidence, energy EnergyReport, limitsHit []string) {
+	s.Attributes["genesis.confidence"] = confidence.Score
+	s.Attributes["genesis.energy.neuron_updates"] = energy.NeuronUpdates
+	s.Attributes["genesis.energy.spikes_propagated"] = energy.SpikesPropagated
+	s.Attributes["genesis.energy.circuits_traced"] = energy.CircuitsTraced
+	s.Attributes["genesis.energy.beams_expanded"] = energy.BeamsExpanded
+	s.Attributes["genesis.energy.external_tokens"] = energy.ExternalTokens
+	if len(limitsHit) > 0 {
+		s.Attributes["genesis.limits_hit"] = limitsHit
+	}
+}
+
+// OpenInferenceSpans maps a model's response to input onto the spans of a
+// new trace. model is a model type ("transparent", "liquid",
+// "orchestrator", ...); thoughts are the transparent model's, if seen; err
+// is the response's error, if any.
+func OpenInferenceSpans(model, input string, start time.Time, response Response, thoughts []TracedThought, err error) []OpenInferenceSpan {
+	trace := newSpanTrace()
+	trace.response("", model, input, start, time.Now(), response, thoughts, err)
+	return trace.spans
+}
+
+// ComparisonSpans maps a comparison that started at start onto the spans of
+// a new trace, each model's under one CHAIN span
+func ComparisonSpans(comparison *Comparison, start time.Time) []OpenInferenceSpan {
+	trace := newSpanTrace()
+	end := start.Add(time.Duration(comparison.LatencyMS * float64(time.Millisecond)))
+	root := trace.span("compare", spanKindChain, "", start, end)
+	root.io(comparison.Input, "")
+	models := make([]string, len(comparison.Results))
+	for i, r := range comparison.Results {
+		models[i] = r.Model
+	}
+	root.Attributes["genesis.models"] = models
+	rootID := root.Context.SpanID
+
+	for _, r := range comparison.Results {
+		var err error
+		if r.Error != "" {
+			err = errors.New(r.Error)
+		}
+		response := Response{
+			Output:      r.Output,
+			Confidence:  r.Confidence,
+			Energy:      r.Energy,
+			Explanation: r.Explanation,
+			Decisions:   r.Decisions,
+			LimitsHit:   r.LimitsHit,
+		}
+		trace.response(rootID, r.Model, comparison.Input, start, start.Add(time.Duration(r.LatencyMS*float64(time.Millisecond))), response, nil, err)
+	}
+	return trace.spans
+}
+
+// response adds the spans of one model response under parent
+func (t *spanTrace) response(parent, model, input string, start, end time.Time, response Response, thoughts []TracedThought, err error) {
+	if len(response.Decisions) > 0 {
+		t.decisions(parent, model, input, start, end, response, err)
+		return
+	}
+
+	root := t.span(model, spanKindLLM, parent, start, end)
+	root.llm(model, input, response.Output)
+	root.scores(response.Confidence, response.Energy, response.LimitsHit)
+	if err != nil {
+		root.fail(err.Error())
+	}
+	explanation := response.Explanation
+	if explanation != nil {
+		if len(explanation.ActiveConcepts) > 0 {
+			root.Attributes["genesis.active_concepts"] = explanation.ActiveConcepts
+		}
+		if explanation.FinishReason != "" {
+			root.Attributes["genesis.finish_reason"] = explanation.FinishReason
+		}
+		if explanation.Seed != nil {
+			parameters, _ := json.Marshal(map[string]int64{"seed": *explanation.Seed})
+			root.Attributes["llm.invocation_parameters"] = string(parameters)
+		}
+	}
+	rootID := root.Context.SpanID
+
+	if explanation != nil && len(explanation.Retrieved) > 0 {
+		retrieval := t.span("retrieve", spanKindRetriever, rootID, start, start)
+		retrieval.Attributes["input.value"] = input
+		for i, c := range explanation.Retrieved {
+			prefix := fmt.Sprintf("retrieval.documents.%d.document.", i)
+			retrieval.Attributes[prefix+"id"] = fmt.Sprint(c.ChunkID)
+			retrieval.Attributes[prefix+"content"] = c.Text
+			retrieval.Attributes[prefix+"score"] = c.Score
+			metadata, _ := json.Marshal(map[string]string{"path": c.Path})
+			retrieval.Attributes[prefix+"metadata"] = string(metadata)
+		}
+	}
+
+	// A thought spans the time since the one before it
+	from := start
+	for _, traced := range thoughts {
+		thought := traced.Thought
+		span := t.span(strings.ToLower(thought.stage), spanKindChain, rootID, from, traced.At)
+		span.Attributes["genesis.stage"] = thought.stage
+		span.Attributes["output.value"] = thought.insight
+		if len(thought.circuits) > 0 {
+			circuits := make([]string, 0, len(thought.circuits))
+			for _, c := range thought.circuits {
+				nodes := make([]string, 0, len(c.nodes))
+				for _, node := range c.nodes {
+					if node != nil {
+						nodes = append(nodes, node.id)
+					}
+				}
+				circuits = append(circuits, strings.Join(nodes, " → "))
+			}
+			span.Attributes["genesis.circuits"] = circuits
+		}
+		if thought.stage == "GENERATION_FAILED" {
+			span.fail(thought.insight)
+		}
+		from = traced.At
+	}
+}
+
+// decisions adds an orchestrator's AGENT span and a span per decision, each
+// spanning the time since the step before it
+func (t *spanTrace) decisions(parent, model, input string, start, end time.Time, response Response, err error) {
+	root := t.span(model, spanKindAgent, parent, start, end)
+	root.io(input, response.Output)
+	root.scores(response.Confidence, response.Energy, response.LimitsHit)
+	if err != nil {
+		root.fail(err.Error())
+	}
+	rootID := root.Context.SpanID
+
+	from := start
+	for _, d := range response.Decisions {
+		at := d.Timestamp
+		if at.IsZero() {
+			at = from
+		}
+		step := model
+		if len(d.Path) > 0 {
+			step = d.Path[len(d.Path)-1]
+		}
+		var span *OpenInferenceSpan
+		if step == "liquid_brain" {
+			span = t.span(step, spanKindLLM, rootID, from, at)
+			span.llm("liquid", d.Input, d.Output)
+		} else {
+			span = t.span(step, spanKindTool, rootID, from, at)
+			span.io(d.Input, d.Output)
+			span.Attributes["tool.name"] = step
+		}
+		span.Attributes["genesis.path"] = d.Path
+		span.Attributes["genesis.reasoning"] = d.Reasoning
+		span.scores(d.Confidence, d.Energy, nil)
+		if len(d.Sandbox) > 0 {
+			violations, _ := json.Marshal(d.Sandbox)
+			span.Attributes["genesis.sandbox"] = string(violations)
+			for _, v := range d.Sandbox {
+				if v.Action == "rejected" {
+					span.fail(v.Error())
+				}
+			}
+		}
+		if d.Policy != "" {
+			span.Attributes["genesis.policy"] = d.Policy
+		}
+		from = at
+	}
+}
+
+// SpanExporter appends spans to a JSON lines file
+type SpanExporter struct {
+	mu       sync.Mutex
+	file     *os.File
+	redactor *Redactor
+}
+
+// OpenSpanExporter opens the span file for appending. Inputs and outputs
+// are redacted with redactor; nil leaves them as they are.
+func OpenSpanExporter(config OpenInferenceConfig, redactor *Redactor) (*SpanExporter, error) {
+	file, err := os.OpenFile(config.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open span file: %w", err)
+	}
+	fmt.Printf("🔭 Exporting OpenInference spans to %s\n", config.Path)
+	return &SpanExporter{file: file, redactor: redactor}, nil
+}
+
+// redactedAttributes hold text users wrote or models generated
+var redactedAttributes = []string{".value", ".content", "genesis.reasoning"}
+
+// Export appends spans, one per line
+func (e *SpanExporter) Export(spans []OpenInferenceSpan) error {
+	if e == nil || len(spans) == 0 {
+		return nil
+	}
+	var lines []byte
+	for _, span := range spans {
+		if e.redactor != nil {
+			attributes := make(map[string]any, len(span.Attributes))
+			for key, value := range span.Attributes {
+				if text, ok := value.(string); ok {
+					for _, suffix := range redactedAttributes {
+						if strings.HasSuffix(key, suffix) {
+							value = e.redactor.Redact(text)
+							break
+						}
+					}
+				}
+				attributes[key] = value
+			}
+			span.Attributes = attributes
+			span.StatusMessage = e.redactor.Redact(span.StatusMessage)
+		}
+		line, err := json.Marshal(span)
+		if err != nil {
+			return fmt.Errorf("failed to encode span: %w", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// One write per trace, so concurrent traces don't interleave
+	if _, err := e.file.Write(lines); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Close closes the span file
+func (e *SpanExporter) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.file.Close()
+}
@@ -13,14 +13,77 @@ type OrchestratorNeuron struct {
 	*LiquidNeuron
 	capability string
 	endpoint   func(context.Context, string) (string, error)
+	
+	mu        sync.Mutex // guards chaos, distill, sandbox and the call statistics below
+	chaos     *Chaos
+	distill   *DistillationLog // nil unless distilling
+	sandbox   SandboxLimits    // limits every call runs under
+	calls     int
+	failures  int // consecutive
+	lastError string
+	lastCall  time.Time
+}
+
+// call invokes the capability within its sandbox limits, returning the
+// limits it broke, and records the outcome for health checks
+func (n *OrchestratorNeuron) call(ctx context.Context, input string) (string, []SandboxViolation, error) {
+	n.mu.Lock()
+	chaos, distill, limits := n.chaos, n.distill, n.sandbox
+	n.mu.Unlock()
+	
+	var violations []SandboxViolation
+	result, err := "", chaos.capabilityError()
+	if err == nil {
+		result, violations, err = sandboxed(ctx, n.capability, limits, n.endpoint, input)
+	}
+	if err == nil {
+		if _, derr := distill.Record(n.capability, input, result); derr != nil {
+			fmt.Printf("⚠️  Warning: %v\n", derr)
+		}
+	}
+	
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	n.lastCall = time.Now()
+	if err != nil {
+		n.failures++
+		n.lastError = err.Error()
+	} else {
+		n.failures = 0
+	}
+	return result, violations, err
+}
+
+// health reports the capability unhealthy after repeated failures
+func (n *OrchestratorNeuron) health() ComponentStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	
+	status := ComponentStatus{
+		Name:    n.capability,
+		Healthy: n.failures < capabilityFailureLimit,
+		Detail:  fmt.Sprintf("%d calls", n.calls),
+	}
+	if n.failures > 0 {
+		status.Detail = fmt.Sprintf("%d calls, %d consecutive failures, last: %s", n.calls, n.failures, n.lastError)
+	}
+	return status
 }
 
 // GenesisOrchestrator - Transparent AI orchestration layer
 type GenesisOrchestrator struct {
-	liquidBrain *LiquidStateBrain
-	neurons     map[string]*OrchestratorNeuron
-	decisions   chan Decision
-	mu          sync.RWMutex
+	liquidBrain   *LiquidStateBrain
+	neurons       map[string]*OrchestratorNeuron
+	decisions     chan Decision
+	chaos         *Chaos    // fault injection for new capabilities; nil when off
+	redactor      *Redactor // applied to logged decisions; nil when off
+	distill       *DistillationLog // capability exchanges kept as training data; nil when off
+	escalation    float64   // general queries below this confidence run the fallback chain
+	fallbackChain []string  // capabilities tried in order when confidence is low
+	plan          PlanConfig // cost estimates and dry-run mode
+	sandbox       CapabilitySandboxConfig // limits capability calls run under
+	mu            sync.RWMutex
 }
 
 type Decision struct {
@@ -29,8 +92,17 @@ type Decision struct {
 	Reasoning string
 	Output    string
 	Timestamp time.Time
+	Energy     EnergyReport       // work this step cost
+	Confidence Confidence         // how sure the step was of its output
+	Policy     string             // capability policy the step obeyed; empty when unrestricted
+	Sandbox    []SandboxViolation // capability sandbox limits the step broke
 }
 
+// General queries the liquid brain understands with at least this
+// confidence are answered locally instead of escalated, unless the config
+// sets fallback.threshold
+const defaultEscalationThreshold = 0.6
+
 // Example external capabilities (in production, these would call real APIs)
 func mockGPT4(ctx context.Context, prompt string) (string, error) {
 	return fmt.Sprintf("[GPT-4 response to: %s]", prompt), nil
@@ -50,10 +122,21 @@ func mockDatabase(ctx context.Context, query string) (string, error) {
 
 // NewGenesisOrchestrator creates a transparent orchestration system
 func NewGenesisOrchestrator(size int) *GenesisOrchestrator {
+	return NewGenesisOrchestratorWithConfig(size, DefaultConfig())
+}
+
+// NewGenesisOrchestratorWithConfig creates an orchestrator whose liquid
+// brain and capabilities use config
+func NewGenesisOrchestratorWithConfig(size int, config *Config) *GenesisOrchestrator {
+	if config == nil {
+		config = DefaultConfig()
+	}
 	go_ := &GenesisOrchestrator{
-		liquidBrain: NewLiquidStateBrain(size),
+		liquidBrain: NewLiquidStateBrainWithConfig(size, config),
 		neurons:     make(map[string]*OrchestratorNeuron),
 		decisions:   make(chan Decision, 100),
+		escalation:  defaultEscalationThreshold,
+		sandbox:     config.CapabilitySandbox,
 	}
 	
 	// Register capabilities as special neurons
@@ -61,6 +144,35 @@ func NewGenesisOrchestrator(size int) *GenesisOrchestrator {
 	go_.RegisterCapability("claude", mockClaude)
 	go_.RegisterCapability("calculator", mockCalculator)
 	go_.RegisterCapability("database", mockDatabase)
+	if go_.liquidBrain != nil && go_.liquidBrain.dataLoader != nil {
+		go_.RegisterCapability("summarizer", SummarizeCapability(go_.liquidBrain.dataLoader))
+	}
+	if go_.liquidBrain != nil && go_.liquidBrain.dataLoader != nil && config.Retrieval.TopK > 0 {
+		// In memory, so the orchestrator holds nothing that needs closing
+		index, err := NewRetrievalIndexWithStore(go_.liquidBrain.dataLoader, config.Retrieval, NewMemoryVectorStore())
+		if err != nil {
+			fmt.Printf("⚠️  Warning: retrieval QA disabled: %v\n", err)
+		} else {
+			go_.RegisterCapability("retrieval_qa", RetrievalQACapability(index, config.Retrieval.TopK, config.Retrieval.AnswerThreshold))
+		}
+	}
+	go_.RegisterCapability("clarify", ClarifyCapability(config.Fallback.ClarificationQuestion))
+	go_.fallbackChain = config.Fallback.Chain
+	go_.plan = config.OrchestratorPlan
+	if config.Fallback.Threshold > 0 {
+		go_.escalation = config.Fallback.Threshold
+	}
+	if config.Privacy.DecisionLogs {
+		go_.redactor = NewRedactor(config.Privacy)
+	}
+	if config.Distill.Enabled {
+		log, err := OpenDistillationLog(config.Distill, NewRedactor(config.Privacy))
+		if err != nil {
+			fmt.Printf("⚠️  Warning: distillation disabled: %v\n", err)
+		} else {
+			go_.SetDistillationLog(log)
+		}
+	}
 	
 	return go_
 }
@@ -73,85 +185,208 @@ func (go_ *GenesisOrchestrator) RegisterCapability(name string, endpoint func(co
 	neuron := &OrchestratorNeuron{
 		capability: name,
 		endpoint:   endpoint,
+		chaos:      go_.chaos,
+		distill:    go_.distill,
+		sandbox:    go_.sandbox.limitsFor(name),
 	}
 	go_.neurons[name] = neuron
 }
 
 func (go_ *GenesisOrchestrator) Process(input string) (string, []Decision) {
-	ctx := context.Background()
+	return go_.ProcessWithPolicy(input, nil)
+}
+
+// ProcessWithPolicy is Process engaging only the capabilities policy
+// permits; a nil policy permits all
+func (go_ *GenesisOrchestrator) ProcessWithPolicy(input string, policy *CapabilityPolicy) (string, []Decision) {
+	return go_.process(context.Background(), input, policy, GenerationOptions{})
+}
+
+// process does the work of ProcessWithPolicy, calling capabilities under
+// ctx within the request budget in options
+func (go_ *GenesisOrchestrator) process(ctx context.Context, input string, policy *CapabilityPolicy, options GenerationOptions) (string, []Decision) {
 	decisions := []Decision{}
 	
 	// Phase 1: Liquid brain understands the input
 	fmt.Printf("\n🧠 UNDERSTANDING: Processing through liquid neural reservoir...\n")
-	understanding := go_.liquidBrain.Think(input)
+	understanding, confidence, energy := go_.liquidBrain.ThinkWithOptions(input, options)
 	
 	decision := Decision{
-		Input:     input,
-		Path:      []string{"liquid_brain"},
-		Reasoning: "Initial understanding through parallel neural processing",
-		Output:    understanding,
-		Timestamp: time.Now(),
+		Input:      input,
+		Path:       []string{"liquid_brain"},
+		Reasoning:  "Initial understanding through parallel neural processing",
+		Output:     understanding,
+		Timestamp:  time.Now(),
+		Energy:     energy,
+		Confidence: confidence,
 	}
 	decisions = append(decisions, decision)
 	
 	// Phase 2: Route to appropriate capabilities based on understanding
 	fmt.Printf("\n🔄 ROUTING: Determining which capabilities to engage...\n")
 	
-	// Simple routing logic (in production, this would be learned)
 	var finalOutput string
-	if containsAny(input, []string{"calculate", "math", "number"}) {
-		fmt.Printf("   → Routing to calculator\n")
-		result, _ := go_.neurons["calculator"].endpoint(ctx, input)
-		finalOutput = result
+	go_.mu.RLock()
+	escalation := go_.escalation
+	dryRun := go_.plan.DryRun
+	go_.mu.RUnlock()
+	r := go_.route(input, confidence, escalation, policy)
+	for _, denied := range r.denied {
+		fmt.Printf("   → %s denied by policy %q\n", denied, policy.Name)
 		decisions = append(decisions, Decision{
 			Input:     input,
-			Path:      []string{"liquid_brain", "calculator"},
-			Reasoning: "Detected mathematical intent",
-			Output:    result,
+			Path:      []string{"liquid_brain", denied},
+			Reasoning: fmt.Sprintf("Matched %s, denied by policy %q", denied, policy.Name),
 			Timestamp: time.Now(),
 		})
-	} else if containsAny(input, []string{"creative", "story", "write"}) {
-		fmt.Printf("   → Routing to Claude for creativity\n")
-		result, _ := go_.neurons["claude"].endpoint(ctx, input)
-		finalOutput = result
+	}
+	if dryRun {
+		plan := go_.planRoute(input, r, confidence, policy)
+		plan.print()
+		return plan.Summary, withPolicy(append(decisions, plan.decisions()...), policy)
+	}
+	if !r.local && options.budget.expired() {
+		// No time left for capabilities; the brain's understanding stands
+		fmt.Printf("   → Request wall time used up, answering locally\n")
+		finalOutput = understanding
 		decisions = append(decisions, Decision{
-			Input:     input,
-			Path:      []string{"liquid_brain", "claude"},
-			Reasoning: "Detected creative intent",
-			Output:    result,
-			Timestamp: time.Now(),
+			Input:      input,
+			Path:       []string{"liquid_brain"},
+			Reasoning:  "Request wall time used up - using the brain's own response",
+			Output:     understanding,
+			Timestamp:  time.Now(),
+			Confidence: confidence,
 		})
-	} else if containsAny(input, []string{"data", "query", "find"}) {
-		fmt.Printf("   → Routing to database\n")
-		result, _ := go_.neurons["database"].endpoint(ctx, input)
+	} else if r.neuron != nil {
+		fmt.Printf("   → Routing to %s\n", r.announce)
+		result, violations, err := r.neuron.call(ctx, input)
+		if err != nil {
+			result = fmt.Sprintf("[%s error: %v]", r.errorLabel, err)
+		}
 		finalOutput = result
 		decisions = append(decisions, Decision{
 			Input:     input,
-			Path:      []string{"liquid_brain", "database"},
-			Reasoning: "Detected data query intent",
+			Path:      []string{"liquid_brain", r.capability},
+			Reasoning: r.reasoning,
 			Output:    result,
 			Timestamp: time.Now(),
+			Energy:    EnergyReport{ExternalTokens: externalTokens(input, result)},
+			Sandbox:   violations,
 		})
-	} else {
-		fmt.Printf("   → Routing to GPT-4 for general query\n")
-		result, _ := go_.neurons["gpt4"].endpoint(ctx, input)
-		finalOutput = result
+	} else if r.local {
+		fmt.Printf("   → Answering locally (confidence %.2f)\n", confidence.Score)
+		finalOutput = understanding
 		decisions = append(decisions, Decision{
-			Input:     input,
-			Path:      []string{"liquid_brain", "gpt4"},
-			Reasoning: "General query - using GPT-4",
-			Output:    result,
-			Timestamp: time.Now(),
+			Input:      input,
+			Path:       []string{"liquid_brain"},
+			Reasoning:  r.reasoning,
+			Output:     understanding,
+			Timestamp:  time.Now(),
+			Confidence: confidence,
 		})
+	} else {
+		fmt.Printf("   → Low confidence (%.2f < %.2f), running fallback chain\n", confidence.Score, escalation)
+		output, hops, ok := go_.runFallbackChain(ctx, input, confidence, policy)
+		decisions = append(decisions, hops...)
+		if !ok {
+			// Nothing in the chain answered; the brain's understanding stands
+			output = understanding
+			decisions = append(decisions, Decision{
+				Input:      input,
+				Path:       []string{"liquid_brain"},
+				Reasoning:  "Fallback chain exhausted - using the brain's own response",
+				Output:     understanding,
+				Timestamp:  time.Now(),
+				Confidence: confidence,
+			})
+		}
+		finalOutput = output
 	}
 	
 	// Phase 3: Show complete decision trace
 	fmt.Printf("\n📊 DECISION TRACE:\n")
+	var total EnergyReport
 	for i, d := range decisions {
 		fmt.Printf("   Step %d: %s → %s\n", i+1, d.Path[len(d.Path)-1], d.Reasoning)
+		total = total.Add(d.Energy)
 	}
+	fmt.Printf("⚡ Energy: %s\n", total)
+	
+	return finalOutput, withPolicy(decisions, policy)
+}
+
+// withPolicy records the policy routing obeyed in every decision
+func withPolicy(decisions []Decision, policy *CapabilityPolicy) []Decision {
+	for i := range decisions {
+		decisions[i].Policy = policyName(policy)
+	}
+	return decisions
+}
+
+// intentRoutes are tried in order; the first whose keywords appear in the
+// input and whose capability is registered handles it
+// (simple routing logic - in production, this would be learned)
+var intentRoutes = []struct {
+	capability string
+	keywords   []string
+	announce   string // how the trace names the capability
+	errorLabel string
+	reasoning  string
+}{
+	{"summarizer", []string{"summarize", "summary", "tl;dr"}, "summarizer", "Summarizer", "Detected summarization intent"},
+	{"calculator", []string{"calculate", "math", "number"}, "calculator", "Calculator", "Detected mathematical intent"},
+	{"claude", []string{"creative", "story", "write"}, "Claude for creativity", "Claude", "Detected creative intent"},
+	{"database", []string{"data", "query", "find"}, "database", "Database", "Detected data query intent"},
+}
+
+// orchestratorRoute is how Process handles an input: with an intent's
+// capability, locally, or through the fallback chain
+type orchestratorRoute struct {
+	capability string
+	neuron     *OrchestratorNeuron // nil unless an intent matched
+	announce   string
+	errorLabel string
+	reasoning  string
+	local      bool     // answered from the brain's understanding
+	denied     []string // matched intent capabilities the policy denied
+}
+
+// route decides how Process handles input given the brain's confidence,
+// passing over intents whose capability policy denies
+func (go_ *GenesisOrchestrator) route(input string, confidence Confidence, escalation float64, policy *CapabilityPolicy) orchestratorRoute {
+	go_.mu.RLock()
+	defer go_.mu.RUnlock()
 	
-	return finalOutput, decisions
+	var denied []string
+	for _, intent := range intentRoutes {
+		neuron := go_.neurons[intent.capability]
+		if neuron == nil || !containsAny(input, intent.keywords) {
+			continue
+		}
+		if !policy.Permits(intent.capability) {
+			denied = append(denied, intent.capability)
+			continue
+		}
+		return orchestratorRoute{
+			capability: intent.capability,
+			neuron:     neuron,
+			announce:   intent.announce,
+			errorLabel: intent.errorLabel,
+			reasoning:  intent.reasoning,
+			denied:     denied,
+		}
+	}
+	if confidence.Score >= escalation {
+		return orchestratorRoute{
+			local:     true,
+			reasoning: fmt.Sprintf("General query - confident understanding (%.2f), no escalation", confidence.Score),
+			denied:    denied,
+		}
+	}
+	return orchestratorRoute{
+		reasoning: fmt.Sprintf("Low confidence (%.2f < %.2f) - fallback chain", confidence.Score, escalation),
+		denied:    denied,
+	}
 }
 
 func containsAny(s string, words []string) bool {
@@ -173,7 +408,7 @@ func DemoOrchestration() {
 	fmt.Println("=" + strings.Repeat("=", 49))
 	
 	orchestrator := NewGenesisOrchestrator(1000)
-	defer orchestrator.liquidBrain.Cleanup()
+	defer OnShutdown(ShutdownOrchestrators, "orchestrator", orchestrator.liquidBrain.Cleanup)()
 	
 	// Test different types of requests
 	tests := []string{
@@ -195,10 +430,27 @@ func DemoOrchestration() {
 
 func (go_ *GenesisOrchestrator) logDecisions(decisions []Decision) {
 	// In production: Send to monitoring system, store in database, etc.
-	// For demo: Just count them
+	// For demo: Just count them, with PII redacted
 	go_.mu.Lock()
 	for _, d := range decisions {
-		go_.decisions <- d
+		go_.decisions <- go_.redactor.RedactDecision(d)
 	}
 	go_.mu.Unlock()
-}
\ No newline at end of file
+}
+
+// SetRedactor sets the redactor applied to logged decisions; nil disables it
+func (go_ *GenesisOrchestrator) SetRedactor(r *Redactor) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+	
+	go_.redactor = r
+}
+
+// SetEscalationThreshold sets the confidence below which general queries
+// are escalated to an external model; 0 never escalates, above 1 always does
+func (go_ *GenesisOrchestrator) SetEscalationThreshold(threshold float64) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+	
+	go_.escalation = threshold
+}
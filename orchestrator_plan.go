@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Plan-only mode: Plan runs the orchestrator's local understanding and
+// routing for an input and returns the capabilities Process would engage,
+// with estimated tokens, cost and latency, without calling any of them. In
+// dry-run mode, set by orchestrator_plan.dry_run, SetDryRun or a request's
+// DryRun, Process answers with the plan instead of executing it, so an
+// expensive orchestration can be previewed and approved first. Estimates
+// come from the per-capability rates in orchestrator_plan.capabilities;
+// capabilities without one run locally and are free. Fallback hops only run
+// while the hops before them fail, so a plan's totals are the worst case of
+// every planned step running.
+
+// CapabilityEstimate is what one call to a capability is expected to cost
+type CapabilityEstimate struct {
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens"` // billed per thousand tokens in both directions
+	LatencyMS       int     `json:"latency_ms"`
+	OutputTokens    int     `json:"output_tokens"` // expected answer length
+}
+
+// PlanConfig controls orchestration planning
+type PlanConfig struct {
+	DryRun       bool                          `json:"dry_run"` // Process returns plans instead of executing them
+	Capabilities map[string]CapabilityEstimate `json:"capabilities"`
+}
+
+func (c PlanConfig) validate() error {
+	for name, estimate := range c.Capabilities {
+		if estimate.CostPer1KTokens < 0 || estimate.LatencyMS < 0 || estimate.OutputTokens < 0 {
+			return fmt.Errorf("orchestrator_plan estimates for %q must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// PlanStep is one capability call a plan would make
+type PlanStep struct {
+	Capability         string  `json:"capability"`
+	Reasoning          string  `json:"reasoning"`
+	Conditional        bool    `json:"conditional"` // runs only if the steps before it fail
+	EstimatedTokens    int64   `json:"estimated_tokens"`
+	EstimatedCost      float64 `json:"estimated_cost"`
+	EstimatedLatencyMS int     `json:"estimated_latency_ms"`
+}
+
+// OrchestrationPlan is what Process would do with an input
+type OrchestrationPlan struct {
+	Input              string     `json:"input"`
+	Confidence         Confidence `json:"confidence"` // the brain's, which routing depends on
+	Local              bool       `json:"local"`      // answered from the brain without capabilities
+	Steps              []PlanStep `json:"steps"`
+	EstimatedTokens    int64      `json:"estimated_tokens"`
+	EstimatedCost      float64    `json:"estimated_cost"`
+	EstimatedLatencyMS int        `json:"estimated_latency_ms"`
+	Summary            string     `json:"summary"`
+	Policy             string     `json:"policy,omitempty"` // capability policy the plan obeys
+	Denied             []string   `json:"denied,omitempty"` // capabilities routing passed over for the policy
+}
+
+// SetDryRun makes Process return plans instead of executing them
+func (go_ *GenesisOrchestrator) SetDryRun(dryRun bool) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	go_.plan.DryRun = dryRun
+}
+
+// SetCapabilityEstimate sets what a call to capability is expected to cost
+func (go_ *GenesisOrchestrator) SetCapabilityEstimate(capability string, estimate CapabilityEstimate) {
+	go_.mu.Lock()
+	defer go_.mu.Unlock()
+
+	estimates := make(map[string]CapabilityEstimate, len(go_.plan.Capabilities)+1)
+	for name, e := range go_.plan.Capabilities {
+		estimates[name] = e
+	}
+	estimates[capability] = estimate
+	go_.plan.Capabilities = estimates
+}
+
+// Plan returns what Process would do with input, calling no capability.
+// The brain still thinks about input, since routing depends on its
+// confidence.
+func (go_ *GenesisOrchestrator) Plan(input string) OrchestrationPlan {
+	return go_.PlanWithPolicy(input, nil)
+}
+
+// PlanWithPolicy is Plan under a capability policy
+func (go_ *GenesisOrchestrator) PlanWithPolicy(input string, policy *CapabilityPolicy) OrchestrationPlan {
+	_, confidence, _ := go_.liquidBrain.ThinkScored(input)
+	go_.mu.RLock()
+	escalation := go_.escalation
+	go_.mu.RUnlock()
+	return go_.planRoute(input, go_.route(input, confidence, escalation, policy), confidence, policy)
+}
+
+// planRoute estimates the calls route r makes for input under policy
+func (go_ *GenesisOrchestrator) planRoute(input string, r orchestratorRoute, confidence Confidence, policy *CapabilityPolicy) OrchestrationPlan {
+	go_.mu.RLock()
+	defer go_.mu.RUnlock()
+
+	plan := OrchestrationPlan{Input: input, Confidence: confidence, Local: r.local, Policy: policyName(policy), Denied: r.denied}
+	switch {
+	case r.neuron != nil:
+		plan.Steps = append(plan.Steps, go_.planStep(input, r.capability, r.reasoning, false))
+	case !r.local:
+		for i, name := range go_.fallbackChain {
+			if go_.neurons[name] == nil {
+				continue // Process skips unregistered hops
+			}
+			if !policy.Permits(name) {
+				plan.Denied = append(plan.Denied, name)
+				continue
+			}
+			reasoning := fmt.Sprintf("Fallback hop %d: %s", i+1, r.reasoning)
+			plan.Steps = append(plan.Steps, go_.planStep(input, name, reasoning, len(plan.Steps) > 0))
+		}
+	}
+	for _, step := range plan.Steps {
+		plan.EstimatedTokens += step.EstimatedTokens
+		plan.EstimatedCost += step.EstimatedCost
+		plan.EstimatedLatencyMS += step.EstimatedLatencyMS
+	}
+
+	switch {
+	case r.local:
+		plan.Summary = fmt.Sprintf("PLAN: answer locally (confidence %.2f), no capability calls", confidence.Score)
+	case len(plan.Steps) == 0:
+		plan.Summary = "PLAN: no fallback capability available, answer from the brain's own response"
+	default:
+		names := make([]string, len(plan.Steps))
+		for i, step := range plan.Steps {
+			names[i] = step.Capability
+		}
+		plan.Summary = fmt.Sprintf("PLAN: %s (up to %d tokens, $%.4f, %dms)",
+			strings.Join(names, " → "), plan.EstimatedTokens, plan.EstimatedCost, plan.EstimatedLatencyMS)
+	}
+	return plan
+}
+
+// planStep estimates one call to capability; the caller holds go_.mu
+func (go_ *GenesisOrchestrator) planStep(input, capability, reasoning string, conditional bool) PlanStep {
+	estimate := go_.plan.Capabilities[capability]
+	tokens := int64(len(strings.Fields(input)) + estimate.OutputTokens)
+	return PlanStep{
+		Capability:         capability,
+		Reasoning:          reasoning,
+		Conditional:        conditional,
+		EstimatedTokens:    tokens,
+		EstimatedCost:      float64(tokens) / 1000 * estimate.CostPer1KTokens,
+		EstimatedLatencyMS: estimate.LatencyMS,
+	}
+}
+
+// decisions records the plan's steps in the decision trace, unexecuted
+func (plan OrchestrationPlan) decisions() []Decision {
+	decisions := make([]Decision, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		decisions = append(decisions, Decision{
+			Input:     plan.Input,
+			Path:      []string{"liquid_brain", step.Capability},
+			Reasoning: "Planned, not executed: " + step.Reasoning,
+			Timestamp: time.Now(),
+		})
+	}
+	return decisions
+}
+
+func (plan OrchestrationPlan) print() {
+	fmt.Printf("   📝 Plan only - no capability is called\n")
+	for i, step := range plan.Steps {
+		when := ""
+		if step.Conditional {
+			when = " (if the above fail)"
+		}
+		fmt.Printf("   Step %d: %s%s → ~%d tokens, $%.4f, %dms\n",
+			i+1, step.Capability, when, step.EstimatedTokens, step.EstimatedCost, step.EstimatedLatencyMS)
+	}
+	fmt.Printf("   %s\n", plan.Summary)
+}
+
+// PlanMain implements `go run . plan <input>`
+func PlanMain(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	size := fs.Int("size", defaultModelSize, "Liquid brain size")
+	asJSON := fs.Bool("json", false, "Print the plan as JSON")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("usage: genesis plan [-config path] [-json] <input>")
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	orchestrator := NewGenesisOrchestratorWithConfig(*size, config)
+	if orchestrator.liquidBrain == nil {
+		fmt.Println("❌ ERROR: failed to create orchestrator")
+		os.Exit(1)
+	}
+	defer orchestrator.Close()
+
+	plan := orchestrator.Plan(strings.Join(fs.Args(), " "))
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(plan)
+		return
+	}
+	plan.print()
+}
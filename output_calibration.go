@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Output calibration: raw output activations are averages of reservoir
+// states whose scale depends on the brain's size and wiring, so fixed
+// thresholds like "active above 0.5" mean something different for every
+// brain. Calibration runs a corpus through the reservoir, records each
+// output's activation distribution and stores per-output quantiles and a
+// threshold: the activation the output reaches on its top active_share of
+// inputs. Calibrated outputs then read on a common scale, the position of
+// the raw activation in the output's own distribution, stretched so the
+// output's threshold lands exactly on conceptActivationLevel. Concept
+// activation then means the same thing across brain sizes. An output whose
+// activation doesn't vary over the corpus carries no information about the
+// input, so once calibrated it reads as inactive. Confidence margins are
+// taken between calibrated levels too.
+
+// CalibrationConfig controls output calibration
+type CalibrationConfig struct {
+	Enabled     bool    `json:"enabled"`      // calibrate when a brain is built
+	CorpusPath  string  `json:"corpus_path"`  // one input per line; "" uses the schema's seed words
+	ActiveShare float64 `json:"active_share"` // share of calibration inputs an output counts as active for
+	Quantiles   int     `json:"quantiles"`    // points kept of each output's distribution
+}
+
+func (c CalibrationConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	return c.check()
+}
+
+// check validates the settings whether or not calibration is enabled
+func (c CalibrationConfig) check() error {
+	if c.ActiveShare <= 0 || c.ActiveShare >= 1 {
+		return fmt.Errorf("output_calibration active_share must be in (0, 1)")
+	}
+	if c.Quantiles < 2 {
+		return fmt.Errorf("output_calibration quantiles must be at least 2")
+	}
+	return nil
+}
+
+// Calibrated activation an output's threshold maps to; concepts are active
+// above it
+const conceptActivationLevel = 0.5
+
+// OutputCalibration is one output's recorded activation distribution
+type OutputCalibration struct {
+	Mean        float64   `json:"mean"`
+	StdDev      float64   `json:"stddev"`
+	Threshold   float64   `json:"threshold"`    // raw activation the output counts as active from
+	ActiveShare float64   `json:"active_share"` // share of the corpus at or above the threshold
+	Quantiles   []float64 `json:"quantiles"`    // evenly spaced, from the minimum to the maximum
+}
+
+// rank returns the share of the calibration distribution below raw,
+// interpolating between quantiles
+func (c *OutputCalibration) rank(raw float64) float64 {
+	q := c.Quantiles
+	last := len(q) - 1
+	if raw <= q[0] {
+		return 0
+	}
+	if raw >= q[last] {
+		return 1
+	}
+	i := sort.SearchFloat64s(q, raw) // q[i-1] < raw <= q[i]
+	return (float64(i-1) + (raw-q[i-1])/(q[i]-q[i-1])) / float64(last)
+}
+
+// flat reports whether the output's activation didn't vary over the corpus
+func (c *OutputCalibration) flat() bool {
+	return c.Quantiles[0] == c.Quantiles[len(c.Quantiles)-1]
+}
+
+// normalize maps a raw activation onto the calibrated scale: its rank,
+// stretched so the threshold's rank becomes conceptActivationLevel
+func (c *OutputCalibration) normalize(raw float64) float64 {
+	if c.flat() {
+		return 0
+	}
+	r := c.rank(raw)
+	at := 1 - c.ActiveShare
+	if raw >= c.Threshold {
+		r = math.Max(r, at)
+	} else {
+		r = math.Min(r, at)
+	}
+	if r <= at {
+		return conceptActivationLevel * r / at
+	}
+	return conceptActivationLevel + (1-conceptActivationLevel)*(r-at)/(1-at)
+}
+
+// newOutputCalibration summarizes an output's raw activations
+func newOutputCalibration(samples []float64, activeShare float64, quantiles int) *OutputCalibration {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	c := &OutputCalibration{ActiveShare: activeShare, Quantiles: make([]float64, quantiles)}
+
+	at := func(p float64) float64 {
+		pos := p * float64(len(sorted)-1)
+		i := int(pos)
+		if i >= len(sorted)-1 {
+			return sorted[len(sorted)-1]
+		}
+		return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+	}
+	for i := range c.Quantiles {
+		c.Quantiles[i] = at(float64(i) / float64(quantiles-1))
+	}
+	c.Threshold = at(1 - activeShare)
+
+	for _, s := range samples {
+		c.Mean += s
+	}
+	c.Mean /= float64(len(samples))
+	for _, s := range samples {
+		c.StdDev += (s - c.Mean) * (s - c.Mean)
+	}
+	c.StdDev = math.Sqrt(c.StdDev / float64(len(samples)))
+	return c
+}
+
+// CalibrateOutputs stimulates the reservoir with each input, records every
+// output's activation and calibrates the outputs to their distributions
+func (brain *LiquidStateBrain) CalibrateOutputs(inputs []string, config CalibrationConfig) (map[string]OutputCalibration, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	if len(inputs) < 2 {
+		return nil, fmt.Errorf("calibration needs at least 2 inputs, got %d", len(inputs))
+	}
+	fmt.Printf("📏 Calibrating %d outputs on %d inputs\n", len(brain.outputLayer), len(inputs))
+
+	samples := make([][]float64, len(brain.outputLayer))
+	for _, input := range inputs {
+		brain.stimulate(input)
+		for i, output := range brain.outputLayer {
+			samples[i] = append(samples[i], output.read())
+		}
+	}
+
+	calibrations := make(map[string]OutputCalibration, len(brain.outputLayer))
+	for i, output := range brain.outputLayer {
+		c := newOutputCalibration(samples[i], config.ActiveShare, config.Quantiles)
+		output.calibration.Store(c)
+		calibrations[output.meaning] = *c
+		if c.flat() {
+			fmt.Printf("⚠️  Warning: output %s doesn't respond to the calibration inputs and will read as inactive\n", output.meaning)
+			continue
+		}
+		fmt.Printf("   %-15s mean %.3f ± %.3f, active above %.3f\n", output.meaning, c.Mean, c.StdDev, c.Threshold)
+	}
+	return calibrations, nil
+}
+
+// calibrationInputs reads the configured corpus, or uses the schema's seed
+// words alone and together
+func calibrationInputs(config CalibrationConfig, schema *ConceptSchema) ([]string, error) {
+	if config.CorpusPath != "" {
+		return readPrompts(config.CorpusPath)
+	}
+	var inputs []string
+	for _, example := range schema.readoutExamples() {
+		inputs = append(inputs, example.Input)
+	}
+	return inputs, nil
+}
+
+// level returns the output's activation, on the calibrated scale once
+// calibrated
+func (o *OutputNeuron) level() float64 {
+	raw := o.read()
+	if c := o.calibration.Load(); c != nil {
+		return c.normalize(raw)
+	}
+	return raw
+}
+
+// OutputCalibrations returns the calibrated outputs' distributions
+func (brain *LiquidStateBrain) OutputCalibrations() map[string]OutputCalibration {
+	calibrations := make(map[string]OutputCalibration)
+	for _, output := range brain.outputLayer {
+		if c := output.calibration.Load(); c != nil {
+			calibrations[output.meaning] = *c
+		}
+	}
+	return calibrations
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Output taps: by default the output neurons read the reservoir's last
+// layer. Taps name other places to read from, z-layers or a 3D region, and
+// the output layer can be wired to one of them instead. Every tap also gets
+// its own set of output neurons, one per meaning, read alongside the output
+// layer, so training a linear readout on each tap shows where in the
+// reservoir the meanings become linearly separable.
+
+// defaultTapConnections is how many reservoir neurons each output neuron
+// reads when the config doesn't say
+const defaultTapConnections = 100
+
+// OutputTapConfig places the liquid brain's output neurons
+type OutputTapConfig struct {
+	Primary     string      `json:"primary"`     // tap the output layer reads; "" is the last layer
+	Taps        []OutputTap `json:"taps"`        // readouts kept alongside the output layer
+	Connections int         `json:"connections"` // reservoir neurons per output neuron; 0 is 100
+}
+
+// OutputTap is a named place in the reservoir to read from: z-layers or a
+// region
+type OutputTap struct {
+	Name   string     `json:"name"`
+	Layers []int      `json:"layers,omitempty"` // z indices; negative ones count back from the last, -1 being the last
+	Region *TapRegion `json:"region,omitempty"`
+}
+
+// TapRegion is a box of the reservoir given as fractions of each dimension
+// (x, y, z), so it fits any brain size
+type TapRegion struct {
+	From [3]float64 `json:"from"`
+	To   [3]float64 `json:"to"`
+}
+
+func (c OutputTapConfig) validate() error {
+	if c.Connections < 0 {
+		return fmt.Errorf("output_taps connections must not be negative")
+	}
+	names := make(map[string]bool, len(c.Taps))
+	for _, tap := range c.Taps {
+		if tap.Name == "" || names[tap.Name] {
+			return fmt.Errorf("output tap names must be unique and non-empty, got %q", tap.Name)
+		}
+		names[tap.Name] = true
+		if (len(tap.Layers) == 0) == (tap.Region == nil) {
+			return fmt.Errorf("output tap %q needs either layers or a region", tap.Name)
+		}
+		if err := tap.Region.validate(); err != nil {
+			return fmt.Errorf("output tap %q: %w", tap.Name, err)
+		}
+	}
+	if c.Primary != "" && !names[c.Primary] {
+		return fmt.Errorf("primary output tap %q is not defined", c.Primary)
+	}
+	return nil
+}
+
+// connections returns the reservoir neurons per output neuron
+func (c OutputTapConfig) connections() int {
+	if c.Connections > 0 {
+		return c.Connections
+	}
+	return defaultTapConnections
+}
+
+// tap returns the tap called name
+func (c OutputTapConfig) tap(name string) (OutputTap, bool) {
+	for _, tap := range c.Taps {
+		if tap.Name == name {
+			return tap, true
+		}
+	}
+	return OutputTap{}, false
+}
+
+// neurons returns the reservoir neurons the tap covers, in x, y, z order
+func (tap OutputTap) neurons(brain *LiquidStateBrain) []*LiquidNeuron {
+	dims := brain.dimensions
+	inTap := func(x, y, z int) bool {
+		if tap.Region != nil {
+			return tap.Region.contains(dims, x, y, z)
+		}
+		for _, layer := range tap.Layers {
+			if layer < 0 {
+				layer += dims.Z
+			}
+			if layer == z {
+				return true
+			}
+		}
+		return false
+	}
+	var neurons []*LiquidNeuron
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				if inTap(x, y, z) {
+					neurons = append(neurons, brain.reservoir[x][y][z])
+				}
+			}
+		}
+	}
+	return neurons
+}
+
+// validate checks the region's bounds; a nil region is valid
+func (r *TapRegion) validate() error {
+	if r == nil {
+		return nil
+	}
+	for d := 0; d < 3; d++ {
+		if r.From[d] < 0 || r.To[d] > 1 || r.From[d] > r.To[d] {
+			return fmt.Errorf("region bounds must satisfy 0 <= from <= to <= 1")
+		}
+	}
+	return nil
+}
+
+// contains reports whether the neuron at x, y, z of a reservoir of size
+// dims lies in the region
+func (r *TapRegion) contains(dims Dimensions, x, y, z int) bool {
+	return regionSpan(r.From[0], r.To[0], dims.X, x) &&
+		regionSpan(r.From[1], r.To[1], dims.Y, y) &&
+		regionSpan(r.From[2], r.To[2], dims.Z, z)
+}
+
+// regionSpan reports whether index i of a dimension of size n lies within
+// the fractions [from, to]; a span always covers at least one index
+func regionSpan(from, to float64, n, i int) bool {
+	lo := min(n-1, int(math.Floor(from*float64(n))))
+	hi := max(lo, int(math.Ceil(to*float64(n)))-1)
+	return i >= lo && i <= hi
+}
+
+// lastLayer returns the neurons of the reservoir's last layer
+func (brain *LiquidStateBrain) lastLayer() []*LiquidNeuron {
+	return OutputTap{Layers: []int{-1}}.neurons(brain)
+}
+
+// wireOutputs creates an output neuron per meaning, each reading n neurons
+// drawn at random from pool
+func wireOutputs(meanings []string, pool []*LiquidNeuron, n int, rng *rand.Rand) []*OutputNeuron {
+	outputs := make([]*OutputNeuron, len(meanings))
+	for i, meaning := range meanings {
+		output := &OutputNeuron{meaning: meaning}
+		output.activation.Store(0.0)
+		for j := 0; j < n; j++ {
+			output.connections = append(output.connections, pool[rng.Intn(len(pool))])
+		}
+		outputs[i] = output
+	}
+	return outputs
+}
+
+// outputTap is a tap's own output neurons
+type outputTap struct {
+	name    string
+	outputs []*OutputNeuron // in output layer order
+}
+
+// initializeOutputs wires the output layer to the primary tap and gives
+// every tap its own output neurons. Taps covering no neuron at this brain
+// size are skipped, and an empty primary tap falls back to the last layer.
+func (brain *LiquidStateBrain) initializeOutputs(meanings []string) {
+	config := brain.config.OutputTaps
+	n := config.connections()
+
+	pool := brain.lastLayer()
+	if tap, ok := config.tap(config.Primary); ok {
+		if neurons := tap.neurons(brain); len(neurons) > 0 {
+			pool = neurons
+		} else {
+			fmt.Printf("⚠️  Warning: output tap %q covers no neurons, reading the last layer\n", tap.Name)
+		}
+	}
+	brain.outputLayer = wireOutputs(meanings, pool, n, brain.rng)
+
+	brain.taps = nil
+	for _, tap := range config.Taps {
+		neurons := tap.neurons(brain)
+		if len(neurons) == 0 {
+			fmt.Printf("⚠️  Warning: output tap %q covers no neurons, skipping\n", tap.Name)
+			continue
+		}
+		brain.taps = append(brain.taps, &outputTap{name: tap.Name, outputs: wireOutputs(meanings, neurons, n, brain.rng)})
+	}
+}
+
+// TapNames returns the brain's output taps in config order
+func (brain *LiquidStateBrain) TapNames() []string {
+	names := make([]string, len(brain.taps))
+	for i, tap := range brain.taps {
+		names[i] = tap.name
+	}
+	return names
+}
+
+// TapReadout returns every tap's output activations, tap -> meaning ->
+// activation
+func (brain *LiquidStateBrain) TapReadout() map[string]map[string]float64 {
+	readout := make(map[string]map[string]float64, len(brain.taps))
+	for _, tap := range brain.taps {
+		activations := make(map[string]float64, len(tap.outputs))
+		for _, output := range tap.outputs {
+			activations[output.meaning] = output.read()
+		}
+		readout[tap.name] = activations
+	}
+	return readout
+}
+
+// TrainTaps fits each tap's output neurons to the labeled examples, like
+// TrainOutputs, and returns each tap's training accuracy: how linearly
+// separable the meanings are at that tap
+func (brain *LiquidStateBrain) TrainTaps(examples []LabeledInput) (map[string]float64, error) {
+	if len(brain.taps) == 0 {
+		return nil, fmt.Errorf("brain has no output taps")
+	}
+	groups := make([][]*OutputNeuron, len(brain.taps))
+	for i, tap := range brain.taps {
+		groups[i] = tap.outputs
+	}
+	fmt.Printf("🎓 Training %d output taps on %d examples\n", len(groups), len(examples))
+	accuracies, err := brain.trainOutputGroups(groups, examples)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]float64, len(brain.taps))
+	for i, tap := range brain.taps {
+		result[tap.name] = accuracies[i]
+		fmt.Printf("   Tap %-12s accuracy %.1f%%\n", tap.name, accuracies[i]*100)
+	}
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Output training: an untrained output neuron reads the average state of
+// the reservoir neurons it happens to be wired to, so its activation says
+// little about its meaning. TrainOutputs fits a weight per connection and a
+// bias with the normalized delta rule, so each output fires for the inputs
+// labeled with its meaning and stays quiet for the others.
+
+// LabeledInput is an input labeled with the output meaning it should produce
+type LabeledInput struct {
+	Input   string
+	Meaning string
+}
+
+// Delta rule settings
+const (
+	outputLearningRate   = 0.5 // normalized by the squared input norm, so stable below 2
+	outputTrainingEpochs = 50
+)
+
+// outputWeights are an output neuron's trained connection weights
+type outputWeights struct {
+	connections []*LiquidNeuron // the neurons weighted, usually the output's own connections
+	weights     []float64       // one per connection
+	bias        float64
+	modulated   bool    // plasticity scales the learning rate
+	plasticity  float64 // learning rate scale from neuromodulation
+}
+
+func (w *outputWeights) apply(states []float64) float64 {
+	sum := w.bias
+	for i, s := range states {
+		sum += w.weights[i] * s
+	}
+	return sum
+}
+
+// neuronStates returns the states of neurons
+func neuronStates(neurons []*LiquidNeuron) []float64 {
+	states := make([]float64, len(neurons))
+	for i, neuron := range neurons {
+		if val := neuron.state.Load(); val != nil {
+			states[i] = val.(float64)
+		}
+	}
+	return states
+}
+
+// read returns the output's activation: the weighted state of its
+// connections once trained, their average before
+func (o *OutputNeuron) read() float64 {
+	if w := o.weights.Load(); w != nil {
+		return clamp01(w.apply(neuronStates(w.connections)))
+	}
+	states := neuronStates(o.connections)
+	total := 0.0
+	for _, s := range states {
+		total += s
+	}
+	return total / float64(len(states))
+}
+
+// TrainOutputs stimulates the reservoir with each example, from a reset
+// state so samples don't depend on example order, and fits the output
+// neurons' weights to the labels, returning the share of examples whose
+// labeled output ends up the strongest
+func (brain *LiquidStateBrain) TrainOutputs(examples []LabeledInput) (float64, error) {
+	fmt.Printf("🎓 Training %d output neurons on %d examples\n", len(brain.outputLayer), len(examples))
+	accuracies, err := brain.trainOutputGroups([][]*OutputNeuron{brain.outputLayer}, examples)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("✅ Output training accuracy: %.1f%%\n", accuracies[0]*100)
+	return accuracies[0], nil
+}
+
+// trainOutputGroups fits each group of output neurons, each in output
+// layer order, to the labeled examples, resetting and stimulating the
+// reservoir once per example, and returns each group's accuracy
+func (brain *LiquidStateBrain) trainOutputGroups(groups [][]*OutputNeuron, examples []LabeledInput) ([]float64, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("no labeled examples")
+	}
+	index := make(map[string]int, len(brain.outputLayer))
+	for i, output := range brain.outputLayer {
+		index[output.meaning] = i
+	}
+	for _, example := range examples {
+		if _, ok := index[example.Meaning]; !ok {
+			return nil, fmt.Errorf("example %q has unknown meaning %q", example.Input, example.Meaning)
+		}
+	}
+
+	trained := make([][]*outputWeights, len(groups))
+	for g, outputs := range groups {
+		trained[g] = make([]*outputWeights, len(outputs))
+		for o, output := range outputs {
+			w := &outputWeights{connections: output.connections, weights: make([]float64, len(output.connections))}
+			if current := output.weights.Load(); current != nil {
+				// Keep training from the current weights and wiring
+				w.connections = current.connections
+				w.weights = append([]float64{}, current.weights...)
+				w.bias = current.bias
+			} else {
+				// Start from the untrained average
+				for i := range w.weights {
+					w.weights[i] = 1 / float64(len(w.weights))
+				}
+			}
+			if brain.modulator != nil {
+				w.modulated, w.plasticity = true, meanPlasticity(w.connections)
+			}
+			trained[g][o] = w
+		}
+	}
+
+	// samples[g][e][o] is what output o of group g saw after example e
+	samples := make([][][][]float64, len(groups))
+	for g := range groups {
+		samples[g] = make([][][]float64, len(examples))
+	}
+	for e, example := range examples {
+		brain.ResetState()
+		brain.stimulate(example.Input)
+		for g, weights := range trained {
+			samples[g][e] = make([][]float64, len(weights))
+			for o, w := range weights {
+				samples[g][e][o] = neuronStates(w.connections)
+			}
+		}
+	}
+
+	labels := make([]int, len(examples))
+	for e, example := range examples {
+		labels[e] = index[example.Meaning]
+	}
+	accuracies := make([]float64, len(groups))
+	for g, outputs := range groups {
+		accuracies[g] = fitOutputWeights(trained[g], samples[g], labels)
+		for o, output := range outputs {
+			output.weights.Store(trained[g][o])
+		}
+	}
+	ModelUpdated()
+	return accuracies, nil
+}
+
+// fitOutputWeights trains weights with the delta rule, where samples[e][o]
+// is what output o saw for example e and labels[e] the output that should
+// fire, and returns the share of examples whose label is the strongest
+func fitOutputWeights(weights []*outputWeights, samples [][][]float64, labels []int) float64 {
+	for epoch := 0; epoch < outputTrainingEpochs; epoch++ {
+		for e, label := range labels {
+			for o, w := range weights {
+				target := 0.0
+				if o == label {
+					target = 1
+				}
+				states := samples[e][o]
+				norm := 1.0 // the bias input
+				for _, s := range states {
+					norm += s * s
+				}
+				rate := outputLearningRate
+				if w.modulated {
+					rate *= w.plasticity
+				}
+				step := rate * (target - w.apply(states)) / norm
+				for i, s := range states {
+					w.weights[i] += step * s
+				}
+				w.bias += step
+			}
+		}
+	}
+
+	correct := 0
+	for e, label := range labels {
+		best, strongest := -1, math.Inf(-1)
+		for o, w := range weights {
+			if y := w.apply(samples[e][o]); y > strongest {
+				best, strongest = o, y
+			}
+		}
+		if best == label {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(labels))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,6 +18,11 @@ type ParallelOrchestrator struct {
 	decisions   chan FlowDecision
 	flowViz     chan FlowPattern
 	active      int64
+	store       *DecisionStore // where runs are persisted; nil when off
+	consensus   ConsensusConfig
+	routing     CapabilityRoutingConfig
+	embed       Embedder   // embeds capabilities and inputs for routing
+	rng         *rand.Rand // wiring and injection choices, seeded by config.Seed
 }
 
 // SmartNeuron - A neuron that can make decisions and call services
@@ -34,6 +40,7 @@ type SmartNeuron struct {
 	// Transparent decision making
 	lastDecision string
 	confidence   float64
+	embedding    []float64 // the capability's, shared by neurons with the same capability
 }
 
 type FlowDecision struct {
@@ -44,50 +51,51 @@ type FlowDecision struct {
 	Timestamp  time.Time
 }
 
+// FlowPattern is how activation flowed to the neurons that decided
 type FlowPattern struct {
-	ActiveNeurons int
-	FlowPaths     [][]int
-	Consensus     string
+	ActiveNeurons int     `json:"active_neurons"`
+	FlowPaths     [][]int `json:"flow_paths"` // per decision, neuron ids from where the input entered
+	Consensus     string  `json:"consensus"`
 }
 
 // NewParallelOrchestrator - Create massive parallel decision maker
 func NewParallelOrchestrator(size int) *ParallelOrchestrator {
-	po := &ParallelOrchestrator{
-		neurons:     make([]*SmartNeuron, size),
-		connections: make(map[*SmartNeuron][]*SmartNeuron),
-		decisions:   make(chan FlowDecision, size),
-		flowViz:     make(chan FlowPattern, 100),
-	}
+	return NewParallelOrchestratorWithSeed(size, 0)
+}
+
+// NewParallelOrchestratorWithSeed creates an orchestrator whose wiring and
+// injection points are drawn from seed; 0 uses the global math/rand
+func NewParallelOrchestratorWithSeed(size int, seed int64) *ParallelOrchestrator {
+	po := newParallelOrchestratorShell(size)
+	po.rng = newRand(seed)
 	
 	// Create diverse neurons with different capabilities
 	for i := 0; i < size; i++ {
 		neuron := &SmartNeuron{
 			id:        i,
-			threshold: rand.Float64() * 0.5 + 0.3,
+			threshold: po.rng.Float64() * 0.5 + 0.3,
 		}
 		
 		// Assign capabilities randomly (in production: learned)
-		r := rand.Float64()
+		r := po.rng.Float64()
 		if r < 0.3 {
-			neuron.canCallGPT = true
-			neuron.capability = "gpt_caller"
+			neuron.setCapability("gpt_caller")
 		} else if r < 0.6 {
-			neuron.canCallClaude = true
-			neuron.capability = "claude_caller"
+			neuron.setCapability("claude_caller")
 		} else {
-			neuron.canCallTools = true
-			neuron.capability = "tool_caller"
+			neuron.setCapability("tool_caller")
 		}
 		
 		neuron.activation.Store(0.0)
 		po.neurons[i] = neuron
 	}
+	po.SetEmbedder(nil)
 	
 	// Create connections (local connectivity for efficiency)
 	for i, n := range po.neurons {
 		// Each neuron connects to ~10 neighbors
 		for j := 0; j < 10; j++ {
-			target := rand.Intn(size)
+			target := po.rng.Intn(size)
 			if target != i {
 				po.connections[n] = append(po.connections[n], po.neurons[target])
 			}
@@ -97,44 +105,107 @@ func NewParallelOrchestrator(size int) *ParallelOrchestrator {
 	return po
 }
 
+// newParallelOrchestratorShell allocates an orchestrator of size neurons
+// without creating them
+func newParallelOrchestratorShell(size int) *ParallelOrchestrator {
+	return &ParallelOrchestrator{
+		neurons:     make([]*SmartNeuron, size),
+		connections: make(map[*SmartNeuron][]*SmartNeuron),
+		decisions:   make(chan FlowDecision, size),
+		flowViz:     make(chan FlowPattern, 100),
+		rng:         globalRand,
+	}
+}
+
+// setCapability gives the neuron a capability and the calls it allows
+func (n *SmartNeuron) setCapability(capability string) {
+	n.capability = capability
+	n.canCallGPT = capability == "gpt_caller"
+	n.canCallClaude = capability == "claude_caller"
+	n.canCallTools = capability == "tool_caller"
+}
+
+// SetDecisionStore persists every run to store; nil stops persisting
+func (po *ParallelOrchestrator) SetDecisionStore(store *DecisionStore) {
+	po.store = store
+}
+
 // ProcessInParallel - True parallel processing where each neuron decides independently
 func (po *ParallelOrchestrator) ProcessInParallel(input string) string {
+	return po.Deliberate(input).Summary
+}
+
+// Deliberate is ProcessInParallel returning the consensus with its
+// strategy and vote tallies
+func (po *ParallelOrchestrator) Deliberate(input string) ConsensusResult {
+	gains := po.routingGains(input)
+	run := po.process(input, po.injectionPoints(gains), gains)
+	if err := po.store.Append(run); err != nil {
+		fmt.Printf("⚠️  Warning: flow run not persisted: %v\n", err)
+	}
+	return run.Result
+}
+
+// process runs input injected at the given neurons and routed by gains,
+// returning the run with the network as it was before
+func (po *ParallelOrchestrator) process(input string, injected []int, gains []float64) *FlowRun {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	
 	fmt.Printf("\n⚡ PARALLEL ORCHESTRATION: %d neurons processing simultaneously\n", len(po.neurons))
+	run := po.snapshot(input)
+	run.Injected = injected
+	run.Gains = gains
 	
 	// Phase 1: Inject input signal
-	po.injectSignal(input)
+	po.inject(injected)
+	
+	// The first deciding neuron to reach each neuron, for its flow path
+	// (an injection point is its own origin)
+	reachedFrom := make([]atomic.Int32, len(po.neurons))
+	for i := range reachedFrom {
+		reachedFrom[i].Store(-1)
+	}
+	for _, idx := range injected {
+		reachedFrom[idx].Store(int32(idx))
+	}
 	
 	// Phase 2: Let neurons process in parallel
 	var wg sync.WaitGroup
 	decisionCollector := make(chan FlowDecision, len(po.neurons))
 	
-	// Each neuron processes independently
+	// Each neuron processes independently, on as many workers as the
+	// goroutine budget grants
+	budget := SharedGoroutineBudget()
+	workers := budget.Request(budgetOrchestrator, len(po.neurons), 1)
+	queue := make(chan *SmartNeuron, len(po.neurons))
 	for _, neuron := range po.neurons {
+		queue <- neuron
+	}
+	close(queue)
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go func(n *SmartNeuron) {
+		go func() {
 			defer wg.Done()
-			
-			// Neuron processes based on activation
-			activation := n.activation.Load().(float64)
-			if activation > n.threshold {
-				decision := n.makeDecision(ctx, input, activation)
-				decisionCollector <- decision
-				
-				// Propagate to connected neurons
-				po.propagate(n, activation)
+			for n := range queue {
+				// Neuron processes based on activation
+				activation := n.activation.Load().(float64)
+				if activation > n.threshold {
+					decision := n.makeDecision(ctx, input, activation)
+					decisionCollector <- decision
+					
+					// Propagate to connected neurons
+					po.propagate(n, activation, gains, reachedFrom)
+				}
 			}
-		}(neuron)
+		}()
 	}
 	
-	// Wait for initial wave
-	time.Sleep(100 * time.Millisecond)
-	
-	// Collect decisions
+	// Collector closes, and the workers go back to the budget, once every
+	// neuron has decided
 	go func() {
 		wg.Wait()
+		budget.Release(budgetOrchestrator, workers)
 		close(decisionCollector)
 	}()
 	
@@ -151,17 +222,59 @@ func (po *ParallelOrchestrator) ProcessInParallel(input string) string {
 	po.visualizeFlow(decisions)
 	
 	// Return consensus
-	return po.formConsensus(decisions)
+	run.Decisions = decisions
+	run.Result = consensusOf(decisions, po.capabilityOf, po.consensus)
+	run.Consensus = run.Result.Summary
+	run.Pattern = flowPattern(decisions, reachedFrom, run.Consensus)
+	run.Timestamp = time.Now()
+	return run
+}
+
+// snapshot records the network before input is processed
+func (po *ParallelOrchestrator) snapshot(input string) *FlowRun {
+	run := &FlowRun{
+		Input:        input,
+		Thresholds:   make([]float64, len(po.neurons)),
+		Capabilities: make([]string, len(po.neurons)),
+		Connections:  make([][]int, len(po.neurons)),
+		Activations:  make([]float64, len(po.neurons)),
+	}
+	for i, n := range po.neurons {
+		run.Thresholds[i] = n.threshold
+		run.Capabilities[i] = n.capability
+		run.Activations[i] = n.activation.Load().(float64)
+		run.Connections[i] = []int{}
+		for _, target := range po.connections[n] {
+			run.Connections[i] = append(run.Connections[i], target.id)
+		}
+	}
+	return run
 }
 
-func (po *ParallelOrchestrator) injectSignal(input string) {
-	// Inject at random points to simulate distributed input
-	injectPoints := 10
-	for i := 0; i < injectPoints; i++ {
-		idx := rand.Intn(len(po.neurons))
+func (po *ParallelOrchestrator) inject(points []int) {
+	for _, idx := range points {
 		po.neurons[idx].activation.Store(1.0)
 	}
-	atomic.AddInt64(&po.active, int64(injectPoints))
+	atomic.AddInt64(&po.active, int64(len(points)))
+}
+
+// flowPattern traces each decision back along the neurons that activated
+// it to where the input entered
+func flowPattern(decisions []FlowDecision, reachedFrom []atomic.Int32, consensus string) FlowPattern {
+	pattern := FlowPattern{ActiveNeurons: len(decisions), FlowPaths: make([][]int, 0, len(decisions)), Consensus: consensus}
+	for _, d := range decisions {
+		path := []int{d.NeuronID}
+		seen := map[int]bool{d.NeuronID: true}
+		for from := int(reachedFrom[d.NeuronID].Load()); from >= 0 && !seen[from]; from = int(reachedFrom[from].Load()) {
+			path = append(path, from)
+			seen[from] = true
+		}
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		pattern.FlowPaths = append(pattern.FlowPaths, path)
+	}
+	return pattern
 }
 
 func (n *SmartNeuron) makeDecision(ctx context.Context, input string, activation float64) FlowDecision {
@@ -187,12 +300,17 @@ func (n *SmartNeuron) makeDecision(ctx context.Context, input string, activation
 	}
 }
 
-func (po *ParallelOrchestrator) propagate(source *SmartNeuron, signal float64) {
+func (po *ParallelOrchestrator) propagate(source *SmartNeuron, signal float64, gains []float64, reachedFrom []atomic.Int32) {
 	// Propagate activation to connected neurons
 	for _, target := range po.connections[source] {
+		// Decay signal as it propagates, routed by the target's capability
+		routed := signal * 0.7 * gain(gains, target.id)
+		if routed <= 0 {
+			continue
+		}
+		reachedFrom[target.id].CompareAndSwap(-1, int32(source.id))
 		current := target.activation.Load().(float64)
-		// Decay signal as it propagates
-		newActivation := current + signal*0.7
+		newActivation := current + routed
 		if newActivation > 1.0 {
 			newActivation = 1.0
 		}
@@ -235,24 +353,9 @@ func (po *ParallelOrchestrator) visualizeFlow(decisions []FlowDecision) {
 	fmt.Printf("   • Local processing: %d\n", localCount)
 }
 
-func (po *ParallelOrchestrator) formConsensus(decisions []FlowDecision) string {
-	if len(decisions) == 0 {
-		return "No consensus reached - insufficient activation"
-	}
-	
-	// Simple voting mechanism (in production: weighted by confidence)
-	highestConfidence := 0.0
-	bestDecision := ""
-	
-	for _, d := range decisions {
-		if d.Confidence > highestConfidence {
-			highestConfidence = d.Confidence
-			bestDecision = d.Decision
-		}
-	}
-	
-	return fmt.Sprintf("CONSENSUS: %s (confidence: %.2f from %d parallel decisions)", 
-		bestDecision, highestConfidence, len(decisions))
+// capabilityOf returns the capability of a neuron by id
+func (po *ParallelOrchestrator) capabilityOf(neuronID int) string {
+	return po.neurons[neuronID].capability
 }
 
 // DemoParallelOrchestration - Show true parallel decision making
@@ -276,28 +379,17 @@ func DemoParallelOrchestration() {
 	}
 }
 
-// ShowScalingBehavior - Demonstrate how behavior changes with scale
+// ShowScalingBehavior measures how the parallel orchestrator changes with
+// scale; `genesis bench scale` runs the same benchmark with more control
 func ShowScalingBehavior() {
 	fmt.Println("\n📈 Scaling Behavior Demo")
 	fmt.Println("=" + strings.Repeat("=", 49))
 	
-	sizes := []int{10, 100, 1000, 10000}
-	input := "Understand the nature of consciousness"
-	
-	for _, size := range sizes {
-		fmt.Printf("\n🔬 Testing with %d neurons:\n", size)
-		orch := NewParallelOrchestrator(size)
-		
-		start := time.Now()
-		result := orch.ProcessInParallel(input)
-		elapsed := time.Since(start)
-		
-		fmt.Printf("   Time: %v\n", elapsed)
-		fmt.Printf("   Result: %s\n", result)
-		
-		// At larger scales, more complex behaviors emerge
-		if size >= 1000 {
-			fmt.Printf("   💫 Emergent behavior detected at scale!\n")
-		}
+	report, err := RunScaleBench(DefaultConfig(), DefaultScaleBench)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		return
 	}
+	fmt.Println()
+	report.Markdown(os.Stdout)
 }
\ No newline at end of file
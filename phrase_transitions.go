@@ -0,0 +1,52 @@
+package main
+
+// Phrase-level transitions: alongside the one-word chain, the loader counts
+// which word follows each two-word context seen in the corpus. Generation
+// mixes the two, P(next | prev, word) = w·P(next | prev word) + (1−w)·
+// P(next | word), backing off to the one-word table alone when the context
+// never occurred. Because a two-word context only ever continues with words
+// the one-word table already offers, mixing reweights the candidates without
+// adding any: "going" after "am" and after "are" offers the same words, but
+// with "home" and "out" in the proportions each context actually had. With a
+// trigram weight of 0 nothing is counted and generation is unchanged.
+// Per-language tables of multilingual corpora stay one-word.
+
+// trigramContext keys the two-word context prev word
+func trigramContext(prev, word string) string {
+	return prev + " " + word
+}
+
+// GetTransitionsAfter returns the probabilities of words following word
+// when prev came before it, mixing two-word and one-word context. The map is
+// shared read-only when no mixing was needed.
+func (dl *DatasetLoader) GetTransitionsAfter(prev, word string) (map[string]float64, bool) {
+	transitions, ok := dl.GetTransitions(word)
+	if !ok || prev == "" || dl.trigramWeight <= 0 {
+		return transitions, ok
+	}
+	return dl.mixTrigrams(prev, word, transitions), true
+}
+
+// GetTransitionsInAfter is GetTransitionsAfter restricted to lang's
+// documents, where only one-word context is kept; an empty lang means all
+// documents
+func (dl *DatasetLoader) GetTransitionsInAfter(lang, prev, word string) (map[string]float64, bool) {
+	if dl.languageModel(lang) != nil {
+		return dl.GetTransitionsIn(lang, word)
+	}
+	return dl.GetTransitionsAfter(prev, word)
+}
+
+// mixTrigrams interpolates the two-word context table for prev word into the
+// one-word transitions
+func (dl *DatasetLoader) mixTrigrams(prev, word string, transitions map[string]float64) map[string]float64 {
+	context, ok := dl.trigrams.Get(trigramContext(prev, word))
+	if !ok {
+		return transitions
+	}
+	mixed := make(map[string]float64, len(transitions))
+	for next, p := range transitions {
+		mixed[next] = dl.trigramWeight*context[next] + (1-dl.trigramWeight)*p
+	}
+	return mixed
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// spikeEvent is a single synaptic transmission from a firing neuron
+type spikeEvent struct {
+	target   *LiquidNeuron
+	strength float64
+	delay    time.Duration
+}
+
+// spikeBatch holds every transmission produced by one firing. Batches are
+// recycled through spikeBatchPool so sustained firing doesn't allocate.
+type spikeBatch struct {
+	events []spikeEvent
+}
+
+// Expected fan-out of a reservoir neuron (matches connection pre-allocation)
+const spikeBatchCapacity = 16
+
+var spikeBatchPool = sync.Pool{
+	New: func() interface{} {
+		return &spikeBatch{events: make([]spikeEvent, 0, spikeBatchCapacity)}
+	},
+}
+
+var wavePatternPool = sync.Pool{
+	New: func() interface{} {
+		return new(WavePattern)
+	},
+}
+
+func acquireSpikeBatch() *spikeBatch {
+	return spikeBatchPool.Get().(*spikeBatch)
+}
+
+func releaseSpikeBatch(b *spikeBatch) {
+	// Drop neuron references so pooled batches don't pin the reservoir
+	for i := range b.events {
+		b.events[i].target = nil
+	}
+	b.events = b.events[:0]
+	spikeBatchPool.Put(b)
+}
+
+// deliver applies the batch in delay order, sleeping on clock only for the
+// gaps between distinct delays instead of once per target.
+func (b *spikeBatch) deliver(clock Clock) {
+	// Insertion sort: batches are small and sort.Slice would allocate
+	for i := 1; i < len(b.events); i++ {
+		for j := i; j > 0 && b.events[j].delay < b.events[j-1].delay; j-- {
+			b.events[j], b.events[j-1] = b.events[j-1], b.events[j]
+		}
+	}
+
+	var elapsed time.Duration
+	for _, ev := range b.events {
+		if ev.delay > elapsed {
+			clock.Sleep(ev.delay - elapsed)
+			elapsed = ev.delay
+		}
+		ev.target.stimulate(ev.strength)
+	}
+}
+
+func acquireWavePattern() *WavePattern {
+	return wavePatternPool.Get().(*WavePattern)
+}
+
+func releaseWavePattern(w *WavePattern) {
+	*w = WavePattern{}
+	wavePatternPool.Put(w)
+}
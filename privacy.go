@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PII redaction: a Redactor replaces email addresses, phone numbers and
+// person names with placeholders before text leaves the request that
+// produced it. Orchestrator decision logs, the decision store and persisted
+// sessions pass through the Redactor configured in the privacy section;
+// anything that later turns stored conversations into training data should
+// do the same.
+
+// PrivacyConfig controls PII redaction
+type PrivacyConfig struct {
+	RedactEmails bool `json:"redact_emails"`
+	RedactPhones bool `json:"redact_phones"`
+	RedactNames  bool `json:"redact_names"`  // detected by NERModel
+	DecisionLogs bool `json:"decision_logs"` // redact orchestrator decision logs
+	Sessions     bool `json:"sessions"`      // redact sessions before they are stored
+}
+
+// validate rejects redaction targets with nothing to redact
+func (c PrivacyConfig) validate() error {
+	if (c.DecisionLogs || c.Sessions) && !c.RedactEmails && !c.RedactPhones && !c.RedactNames {
+		return fmt.Errorf("privacy: decision_logs or sessions is set but no redact_* kind is enabled")
+	}
+	return nil
+}
+
+// Placeholders substituted for redacted text
+const (
+	redactedEmail = "[EMAIL]"
+	redactedPhone = "[PHONE]"
+	redactedName  = "[NAME]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Seven or more digits, optionally with a country code, area code in
+	// parentheses, and space, dot or dash separators
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]?\d{2,4}){1,3}`)
+)
+
+// Redactor removes PII from text. A nil *Redactor redacts nothing.
+type Redactor struct {
+	emails bool
+	phones bool
+	ner    *NERModel // nil unless names are redacted
+}
+
+// NewRedactor returns a redactor, or nil when config redacts nothing
+func NewRedactor(config PrivacyConfig) *Redactor {
+	if !config.RedactEmails && !config.RedactPhones && !config.RedactNames {
+		return nil
+	}
+	r := &Redactor{emails: config.RedactEmails, phones: config.RedactPhones}
+	if config.RedactNames {
+		r.ner = &NERModel{}
+	}
+	return r
+}
+
+// Redact returns text with PII replaced by placeholders
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	// Emails first, so their digits aren't taken for phone numbers
+	if r.emails {
+		text = emailPattern.ReplaceAllString(text, redactedEmail)
+	}
+	if r.phones {
+		text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+			digits := 0
+			for _, c := range match {
+				if c >= '0' && c <= '9' {
+					digits++
+				}
+			}
+			if digits < 7 {
+				return match
+			}
+			return redactedPhone
+		})
+	}
+	if r.ner != nil {
+		// Whole words only, so "Al" doesn't clobber "Also"
+		for _, name := range r.ner.Names(text) {
+			text = regexp.MustCompile(`\b`+regexp.QuoteMeta(name)+`\b`).ReplaceAllString(text, redactedName)
+		}
+	}
+	return text
+}
+
+// RedactDecision returns a copy of d with its text fields redacted
+func (r *Redactor) RedactDecision(d Decision) Decision {
+	if r == nil {
+		return d
+	}
+	d.Input = r.Redact(d.Input)
+	d.Reasoning = r.Redact(d.Reasoning)
+	d.Output = r.Redact(d.Output)
+	return d
+}
+
+// RedactFlowRun returns a copy of run with its input, decisions and
+// consensus redacted
+func (r *Redactor) RedactFlowRun(run *FlowRun) *FlowRun {
+	if r == nil || run == nil {
+		return run
+	}
+	redacted := *run
+	redacted.Input = r.Redact(run.Input)
+	redacted.Decisions = make([]FlowDecision, len(run.Decisions))
+	for i, d := range run.Decisions {
+		d.Decision = r.Redact(d.Decision)
+		redacted.Decisions[i] = d
+	}
+	redacted.Pattern.Consensus = r.Redact(run.Pattern.Consensus)
+	redacted.Consensus = r.Redact(run.Consensus)
+	redacted.Result.Decision = r.Redact(run.Result.Decision)
+	redacted.Result.Summary = r.Redact(run.Result.Summary)
+	return &redacted
+}
+
+// RedactSession redacts the session's messages, feedback comments and
+// context window in place
+func (r *Redactor) RedactSession(s *Session) {
+	if r == nil || s == nil {
+		return
+	}
+	for i := range s.History {
+		s.History[i].Content = r.Redact(s.History[i].Content)
+	}
+	for i := range s.Feedback {
+		s.Feedback[i].Comment = r.Redact(s.Feedback[i].Comment)
+	}
+	for i, word := range s.Generator.ContextWindow {
+		s.Generator.ContextWindow[i] = r.Redact(word)
+	}
+}
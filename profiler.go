@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StageProfiler records per-stage latencies of Think/Understand calls in
+// fixed-size rolling windows. A nil *StageProfiler is valid and records
+// nothing, so components can hold one unconditionally.
+type StageProfiler struct {
+	mu      sync.Mutex
+	window  int
+	samples map[string]map[string]*latencyWindow // operation -> stage -> samples
+	order   map[string][]string                  // operation -> stages in first-seen order
+}
+
+type latencyWindow struct {
+	values []time.Duration
+	next   int
+	total  int64
+}
+
+// StagePercentiles summarizes the samples currently held for one stage
+type StagePercentiles struct {
+	Stage string  `json:"stage"`
+	Count int64   `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P90Ms float64 `json:"p90_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// CallProfile times consecutive stages of a single call
+type CallProfile struct {
+	profiler  *StageProfiler
+	operation string
+	start     time.Time
+	last      time.Time
+}
+
+func NewStageProfiler(window int) *StageProfiler {
+	if window <= 0 {
+		window = 1000
+	}
+	return &StageProfiler{
+		window:  window,
+		samples: make(map[string]map[string]*latencyWindow),
+		order:   make(map[string][]string),
+	}
+}
+
+// newProfilerFromConfig returns a profiler when profiling is enabled, nil otherwise
+func newProfilerFromConfig(config *Config) *StageProfiler {
+	if config == nil || !config.Profiling.Enabled {
+		return nil
+	}
+	return NewStageProfiler(config.Profiling.WindowSize)
+}
+
+// Begin starts timing a call of the given operation ("think", "understand")
+func (p *StageProfiler) Begin(operation string) *CallProfile {
+	if p == nil {
+		return nil
+	}
+	now := time.Now()
+	return &CallProfile{profiler: p, operation: operation, start: now, last: now}
+}
+
+// Mark records the time since the previous mark (or Begin) as the named stage
+func (c *CallProfile) Mark(stage string) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.profiler.Record(c.operation, stage, now.Sub(c.last))
+	c.last = now
+}
+
+// Done records the whole call duration as the "total" stage
+func (c *CallProfile) Done() {
+	if c == nil {
+		return
+	}
+	c.profiler.Record(c.operation, "total", time.Since(c.start))
+}
+
+// Record adds one latency sample for operation/stage
+func (p *StageProfiler) Record(operation, stage string, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stages, ok := p.samples[operation]
+	if !ok {
+		stages = make(map[string]*latencyWindow)
+		p.samples[operation] = stages
+	}
+	w, ok := stages[stage]
+	if !ok {
+		w = &latencyWindow{values: make([]time.Duration, 0, p.window)}
+		stages[stage] = w
+		p.order[operation] = append(p.order[operation], stage)
+	}
+
+	if len(w.values) < p.window {
+		w.values = append(w.values, d)
+	} else {
+		w.values[w.next] = d
+	}
+	w.next = (w.next + 1) % p.window
+	w.total++
+}
+
+// Percentiles returns stage summaries per operation, stages in call order
+func (p *StageProfiler) Percentiles() map[string][]StagePercentiles {
+	result := make(map[string][]StagePercentiles)
+	if p == nil {
+		return result
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for operation, stages := range p.samples {
+		for _, stage := range p.order[operation] {
+			w := stages[stage]
+			sorted := append([]time.Duration(nil), w.values...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+			result[operation] = append(result[operation], StagePercentiles{
+				Stage: stage,
+				Count: w.total,
+				P50Ms: durationMs(percentile(sorted, 0.50)),
+				P90Ms: durationMs(percentile(sorted, 0.90)),
+				P99Ms: durationMs(percentile(sorted, 0.99)),
+				MaxMs: durationMs(percentile(sorted, 1.0)),
+			})
+		}
+	}
+	return result
+}
+
+// ServeHTTP exposes the current percentiles as JSON, e.g. mounted at /metrics
+func (p *StageProfiler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.Percentiles())
+}
+
+// percentile uses nearest-rank on an ascending slice
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Extractive question answering: for question inputs the retrieved chunks
+// are split into sentences and the sentence that best answers the question
+// is returned verbatim. Sentences are scored by embedding similarity to the
+// question plus the fraction of the question's content words they contain.
+// When no sentence clears the confidence threshold the generator falls back
+// to beam search.
+
+// Default minimum confidence for returning an extracted answer
+const defaultAnswerThreshold = 0.35
+
+// Weights of the two answer scoring signals
+const (
+	answerEmbeddingWeight = 0.4
+	answerOverlapWeight   = 0.6
+)
+
+// Answer is a sentence extracted from a retrieved chunk
+type Answer struct {
+	Text       string  `json:"text"`
+	ChunkID    int     `json:"chunk_id"`
+	Path       string  `json:"path"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Common function words that carry no content for matching
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true,
+	"is": true, "are": true, "was": true, "were": true, "be": true, "been": true,
+	"do": true, "does": true, "did": true, "of": true, "to": true, "in": true,
+	"on": true, "at": true, "for": true, "with": true, "by": true, "from": true,
+	"it": true, "its": true, "this": true, "that": true, "these": true, "those": true,
+	"i": true, "you": true, "we": true, "they": true, "he": true, "she": true,
+	"me": true, "my": true, "your": true, "our": true, "their": true,
+	"what": true, "how": true, "why": true, "when": true, "where": true, "who": true,
+	"which": true, "can": true, "could": true, "would": true, "should": true,
+	"will": true, "about": true, "tell": true, "please": true, "as": true, "if": true,
+	"so": true, "not": true, "no": true, "yes": true, "there": true, "here": true,
+}
+
+// contentWords returns the distinct non-stopword tokens of tokens
+func contentWords(tokens []string) map[string]bool {
+	words := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		if len(t) >= 2 && !stopWords[t] {
+			words[t] = true
+		}
+	}
+	return words
+}
+
+// splitSentences splits text after '.', '!' or '?' followed by whitespace,
+// keeping the punctuation with its sentence
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// Answer extracts the sentence from the top k chunks that best answers
+// question. ok is false when nothing relevant was found.
+func (idx *RetrievalIndex) Answer(question string, k int) (answer *Answer, ok bool) {
+	qvec, _, found := idx.loader.SentenceEmbedding(question)
+	if !found {
+		return nil, false
+	}
+	qwords := contentWords(idx.loader.tokenize(question))
+	if len(qwords) == 0 {
+		return nil, false
+	}
+
+	for _, hit := range idx.Search(question, k) {
+		for _, sentence := range splitSentences(idx.sentenceContext(hit.Chunk)) {
+			// A sentence that is itself a question doesn't answer one
+			if strings.HasSuffix(sentence, "?") {
+				continue
+			}
+
+			tokens := idx.loader.tokenize(sentence)
+			matched := 0
+			for word := range contentWords(tokens) {
+				if qwords[word] {
+					matched++
+				}
+			}
+			if matched == 0 {
+				continue
+			}
+			overlap := float64(matched) / float64(len(qwords))
+
+			similarity := 0.0
+			if svec, _, ok := idx.loader.SentenceEmbedding(sentence); ok {
+				similarity = dotProduct(qvec, svec)
+			}
+
+			confidence := answerEmbeddingWeight*similarity + answerOverlapWeight*overlap
+			if answer == nil || confidence > answer.Confidence {
+				answer = &Answer{
+					Text:       sentence,
+					ChunkID:    hit.Chunk.ID,
+					Path:       hit.Chunk.Path,
+					Confidence: confidence,
+				}
+			}
+		}
+	}
+	return answer, answer != nil
+}
+
+// sentenceContext widens a chunk to whole sentences, since chunk windows
+// usually start and end mid-sentence
+func (idx *RetrievalIndex) sentenceContext(chunk *Chunk) string {
+	words := idx.docWords[chunk.doc]
+	endsSentence := func(word string) bool {
+		return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+	}
+
+	// Unpunctuated text would otherwise pull in the whole document
+	limit := idx.config.ChunkTokens
+	start, end := chunk.Start, chunk.End
+	for start > 0 && chunk.Start-start < limit && !endsSentence(words[start-1]) {
+		start--
+	}
+	for end < len(words) && end-chunk.End < limit && !endsSentence(words[end-1]) {
+		end++
+	}
+	return strings.Join(words[start:end], " ")
+}
+
+// SetAnswerThreshold sets the minimum confidence for extractive answers.
+// Values above 1 effectively disable question answering.
+func (gen *ResponseGenerator) SetAnswerThreshold(threshold float64) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.answerThreshold = threshold
+}
+
+// isQuestionInput reports whether input should be answered extractively
+func (gen *ResponseGenerator) isQuestionInput(input string) bool {
+	if strings.HasSuffix(strings.TrimSpace(input), "?") {
+		return true
+	}
+	return gen.classifyInput(strings.Fields(strings.ToLower(input))) == "question"
+}
+
+// extractAnswer returns an answer for question inputs that clears the
+// confidence threshold. It runs on a call's copy of the generator.
+func (gen *ResponseGenerator) extractAnswer(input string) *Answer {
+	if gen.retrieval == nil || gen.retrievalK <= 0 || !gen.isQuestionInput(input) {
+		return nil
+	}
+	answer, ok := gen.retrieval.Answer(input, gen.retrievalK)
+	if !ok || answer.Confidence < gen.answerThreshold {
+		return nil
+	}
+	return answer
+}
+
+// RetrievalQACapability answers from the top k chunks of index, failing
+// when no sentence clears threshold
+func RetrievalQACapability(index *RetrievalIndex, k int, threshold float64) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, input string) (string, error) {
+		answer, ok := index.Answer(input, k)
+		if !ok {
+			return "", fmt.Errorf("no relevant passage")
+		}
+		if answer.Confidence < threshold {
+			return "", fmt.Errorf("best answer confidence %.2f is below %.2f", answer.Confidence, threshold)
+		}
+		return answer.Text, nil
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// activityTracker counts in-flight asynchronous work (spike batches,
+// concept pulses) and signals when the count drops to zero, so callers can
+// wait for propagation to finish instead of sleeping for a fixed time.
+type activityTracker struct {
+	mu         sync.Mutex
+	pending    int64
+	generation uint64        // bumped on every Add so waiters can detect new activity
+	idle       chan struct{} // closed while pending == 0
+}
+
+func newActivityTracker() *activityTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &activityTracker{idle: idle}
+}
+
+// Add registers n units of in-flight work
+func (t *activityTracker) Add(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.pending += n
+	t.generation++
+}
+
+// Done marks one unit of work as finished
+func (t *activityTracker) Done() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == 0 {
+		return
+	}
+	t.pending--
+	if t.pending == 0 {
+		close(t.idle)
+	}
+}
+
+// Pending returns the amount of in-flight work
+func (t *activityTracker) Pending() int64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending
+}
+
+func (t *activityTracker) state() (<-chan struct{}, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.idle, t.generation
+}
+
+// WaitQuiet blocks until no work has been in flight for the grace period,
+// or until maxWait elapses. It reports whether quiescence was reached.
+// The grace period covers the gap between one wave landing and the
+// neurons it excited firing on their next tick.
+func (t *activityTracker) WaitQuiet(grace, maxWait time.Duration) bool {
+	if t == nil {
+		return true
+	}
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+
+	for {
+		idle, gen := t.state()
+		select {
+		case <-idle:
+		case <-deadline.C:
+			return false
+		}
+
+		settle := time.NewTimer(grace)
+		select {
+		case <-settle.C:
+		case <-deadline.C:
+			settle.Stop()
+			return false
+		}
+
+		if _, current := t.state(); current == gen && t.Pending() == 0 {
+			return true
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Evolved readout: instead of answering with the most active output meaning,
+// the liquid brain can classify its output layer with gate circuits bred by
+// Evolution. Output activations are discretized into bits (an output is on
+// when it is close to the strongest), and one circuit per response category
+// learns to fire for that category. Training examples come from the concept
+// schema: each meaning's seed words are fed through the reservoir and the
+// resulting bits labeled with the meaning.
+
+// ReadoutConfig selects how the liquid brain picks a response category
+type ReadoutConfig struct {
+	Mode        string  `json:"mode"`        // "activation" (strongest output wins) or "evolved"
+	Level       float64 `json:"level"`       // outputs at least this fraction of the strongest count as on
+	Population  int     `json:"population"`  // circuits per category in the evolved mode
+	Generations int     `json:"generations"` // generations bred per category
+}
+
+func (c ReadoutConfig) validate() error {
+	switch c.Mode {
+	case "", "activation":
+		return nil
+	case "evolved":
+	default:
+		return fmt.Errorf("unknown readout mode %q", c.Mode)
+	}
+	if c.Level <= 0 || c.Level > 1 {
+		return fmt.Errorf("readout level must be in (0, 1]")
+	}
+	if c.Population < 2 || c.Generations < 1 {
+		return fmt.Errorf("evolved readout needs a population of at least 2 and at least one generation")
+	}
+	return nil
+}
+
+// ReadoutExample is an input labeled with the category it should produce
+type ReadoutExample struct {
+	Input    string
+	Category string // an output meaning
+}
+
+// readoutSample is one example's discretized output layer
+type readoutSample struct {
+	bits     []bool // in outputs order
+	category string
+}
+
+// EvolvedReadout classifies output activations with one evolved circuit per
+// category
+type EvolvedReadout struct {
+	outputs  []string // output meanings, in bit order
+	level    float64
+	circuits map[string]*EvolvingCircuit
+	fitness  map[string]float64
+}
+
+// discretize turns activations into one bit per output: on when the output
+// reaches level times the strongest activation
+func discretize(activations map[string]float64, outputs []string, level float64) []bool {
+	strongest := 0.0
+	for _, output := range outputs {
+		strongest = math.Max(strongest, activations[output])
+	}
+	bits := make([]bool, len(outputs))
+	if strongest <= 0 {
+		return bits
+	}
+	for i, output := range outputs {
+		bits[i] = activations[output] >= level*strongest
+	}
+	return bits
+}
+
+// circuitInput rotates bits so the category's own output comes first; the
+// random gate functions mostly look at their first inputs
+func circuitInput(bits []bool, own int) []bool {
+	input := make([]bool, 0, len(bits))
+	input = append(input, bits[own:]...)
+	return append(input, bits[:own]...)
+}
+
+// evolveReadout breeds a circuit per category that fires for that
+// category's samples and stays off for the others, with rng's choices
+func evolveReadout(samples []readoutSample, outputs []string, config ReadoutConfig, rng *rand.Rand) *EvolvedReadout {
+	readout := &EvolvedReadout{
+		outputs:  outputs,
+		level:    config.Level,
+		circuits: make(map[string]*EvolvingCircuit),
+		fitness:  make(map[string]float64),
+	}
+	for own, category := range outputs {
+		var positives, negatives []TestCase
+		for _, sample := range samples {
+			tc := TestCase{Input: circuitInput(sample.bits, own), Expected: sample.category == category}
+			if sample.category == category {
+				positives = append(positives, tc)
+			} else {
+				negatives = append(negatives, tc)
+			}
+		}
+		if len(positives) == 0 {
+			continue
+		}
+		// Repeat the positives so always-off circuits don't look accurate
+		testCases := append([]TestCase{}, negatives...)
+		for i := 0; i < max(len(negatives), len(positives)); i++ {
+			testCases = append(testCases, positives[i%len(positives)])
+		}
+
+		evolution := NewEvolutionWithRand(config.Population, testCases, rng)
+		for gen := 0; gen < config.Generations; gen++ {
+			evolution.RunGeneration()
+		}
+		if evolution.bestCircuit != nil {
+			readout.circuits[category] = evolution.bestCircuit
+			readout.fitness[category] = evolution.bestFitness
+		}
+	}
+	return readout
+}
+
+// Classify returns the category whose circuit fires for activations,
+// preferring the most active output when several fire. ok is false when
+// none does.
+func (r *EvolvedReadout) Classify(activations map[string]float64) (category string, ok bool) {
+	if r == nil {
+		return "", false
+	}
+	bits := discretize(activations, r.outputs, r.level)
+	best := -1.0
+	for own, output := range r.outputs {
+		circuit := r.circuits[output]
+		if circuit == nil || activations[output] <= best {
+			continue
+		}
+		circuit.mu.RLock()
+		fired := circuit.gates[len(circuit.gates)-1].Process(circuitInput(bits, own)) == true
+		circuit.mu.RUnlock()
+		if fired {
+			category, best = output, activations[output]
+		}
+	}
+	return category, category != ""
+}
+
+// Fitness returns the best fitness bred for each category
+func (r *EvolvedReadout) Fitness() map[string]float64 {
+	fitness := make(map[string]float64, len(r.fitness))
+	for category, f := range r.fitness {
+		fitness[category] = f
+	}
+	return fitness
+}
+
+// readoutExamples labels each meaning's seed words, alone and together,
+// with the meaning
+func (s *ConceptSchema) readoutExamples() []ReadoutExample {
+	s = s.orDefault()
+	var examples []ReadoutExample
+	for _, name := range s.MeaningNames() {
+		meaning, _ := s.Meaning(name)
+		for _, word := range meaning.SeedWords {
+			examples = append(examples, ReadoutExample{Input: word, Category: name})
+		}
+		if len(meaning.SeedWords) > 1 {
+			examples = append(examples, ReadoutExample{Input: strings.Join(meaning.SeedWords, " "), Category: name})
+		}
+	}
+	return examples
+}
+
+// EvolveReadout runs examples through the reservoir, breeds readout
+// circuits on the resulting output layers and makes the brain answer with
+// them
+func (brain *LiquidStateBrain) EvolveReadout(examples []ReadoutExample, config ReadoutConfig) (*EvolvedReadout, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("no readout examples")
+	}
+	outputs := brain.schema.MeaningNames()
+	fmt.Printf("🧬 Evolving readout circuits for %d categories from %d examples\n", len(outputs), len(examples))
+
+	samples := make([]readoutSample, 0, len(examples))
+	for _, example := range examples {
+		brain.stimulate(example.Input)
+		samples = append(samples, readoutSample{
+			bits:     discretize(brain.readOutput(), outputs, config.Level),
+			category: example.Category,
+		})
+	}
+
+	readout := evolveReadout(samples, outputs, config, brain.rng)
+	fitness := readout.Fitness()
+	categories := make([]string, 0, len(fitness))
+	for category := range fitness {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		fmt.Printf("   %-15s fitness %.3f\n", category, fitness[category])
+	}
+
+	brain.readout.Store(readout)
+	ModelUpdated()
+	return readout, nil
+}
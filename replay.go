@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record and replay: a Recorder writes everything needed to re-run a
+// serving session to a JSON Lines file — the config, the response
+// generator's seed and corpus fingerprint up front, then every request
+// with the events and output it produced. Requests are recorded whole,
+// generation options included, along with what the session path did with
+// them: the entity-resolved content, response cache hits and facts
+// recalled. A Replay sends the session messages, in order, through a
+// session handler built from the recorded config, with its generator
+// seeded from the recording, and reports each reply that differs. The
+// replay's knowledge store starts empty, so facts learned before recording
+// began aren't recalled again.
+//
+// Requests to the inference endpoints (/v1/think, /v1/understand and
+// /v1/compare) are recorded but not replayed: the liquid brain and the
+// transparent model run concurrent dynamics that a seed doesn't make
+// reproducible, so their outputs can't be checked. Replays count them as
+// skipped and report them as unverified.
+
+const replayFormatVersion = 2
+
+// ReplayRecord is one line of a replay file
+type ReplayRecord struct {
+	Type    string          `json:"type"` // "header", "input", "event" or "output"
+	Seq     int             `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Session string          `json:"session,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Request *ReplayRequest  `json:"request,omitempty"` // on inputs
+
+	// Header fields
+	Version int     `json:"version,omitempty"`
+	Config  *Config `json:"config,omitempty"`
+	Seed    int64   `json:"seed,omitempty"`
+	Corpus  string  `json:"corpus,omitempty"`
+}
+
+// ReplayRequest is a recorded request and what the serving path did with it
+type ReplayRequest struct {
+	Endpoint string `json:"endpoint,omitempty"` // "" for session messages
+	MessageRequest
+	Resolved string `json:"resolved,omitempty"` // content after entity resolution, when it changed
+	Cached   bool   `json:"cached,omitempty"`   // answered from the response cache
+	Recalled int    `json:"recalled,omitempty"` // learned facts recalled
+}
+
+// CorpusFingerprint hashes the paths and contents of the loaded documents,
+// so a replay can tell whether it runs on the recorded corpus
+func (dl *DatasetLoader) CorpusFingerprint() string {
+	docs := dl.GetDocuments()
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+
+	h := sha256.New()
+	for _, doc := range docs {
+		fmt.Fprintf(h, "%s\x00%d\x00", doc.Path, len(doc.Content))
+		io.WriteString(h, doc.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Recorder appends a run to a replay file
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	seq  int
+}
+
+// NewRecorder creates path and writes the run header. seed is the seed the
+// recorded run's response generator was given, which replays give theirs.
+func NewRecorder(path string, config *Config, loader *DatasetLoader, seed int64) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay file: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	r := &Recorder{file: file, buf: buf, enc: json.NewEncoder(buf)}
+
+	header := ReplayRecord{
+		Type:    "header",
+		Time:    time.Now().UTC(),
+		Version: replayFormatVersion,
+		Config:  config,
+		Seed:    seed,
+	}
+	if loader != nil {
+		header.Corpus = loader.CorpusFingerprint()
+	}
+	if err := r.write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	fmt.Printf("⏺️  Recording run to %s (seed %d)\n", path, seed)
+	return r, nil
+}
+
+func (r *Recorder) write(rec ReplayRecord) error {
+	if err := r.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write replay record: %w", err)
+	}
+	// Flush per record so a crash leaves a usable recording
+	return r.buf.Flush()
+}
+
+// Record logs one request, the explanation event and the output it produced
+func (r *Recorder) Record(session string, req ReplayRequest, output string, explanation *Explanation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	now := time.Now().UTC()
+	if err := r.write(ReplayRecord{Type: "input", Seq: r.seq, Time: now, Session: session, Text: req.Content, Request: &req}); err != nil {
+		return err
+	}
+	if explanation != nil {
+		data, err := json.Marshal(explanation)
+		if err != nil {
+			return err
+		}
+		if err := r.write(ReplayRecord{Type: "event", Seq: r.seq, Time: now, Session: session, Event: "explanation", Data: data}); err != nil {
+			return err
+		}
+	}
+	return r.write(ReplayRecord{Type: "output", Seq: r.seq, Time: now, Session: session, Text: output})
+}
+
+// RecordEvent logs a standalone event, such as a stimulus protocol's
+// injections, as part of the most recently recorded input
+func (r *Recorder) RecordEvent(session, event string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.write(ReplayRecord{Type: "event", Seq: r.seq, Time: time.Now().UTC(), Session: session, Event: event, Data: data})
+}
+
+// Close flushes and closes the replay file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.buf.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// ReplayStep is one recorded input and what it produced
+type ReplayStep struct {
+	Seq     int
+	Session string
+	Input   string
+	Request ReplayRequest
+	Output  string
+	Events  []ReplayRecord
+}
+
+// Replay is a loaded recording
+type Replay struct {
+	Header ReplayRecord
+	Steps  []ReplayStep
+}
+
+// Divergence is a step whose replayed output differs from the recording
+type Divergence struct {
+	Seq      int    `json:"seq"`
+	Session  string `json:"session,omitempty"`
+	Input    string `json:"input"`
+	Recorded string `json:"recorded"`
+	Replayed string `json:"replayed"`
+}
+
+// ReplayReport summarizes a replay
+type ReplayReport struct {
+	Steps            int            `json:"steps"`
+	Skipped          int            `json:"skipped"`                     // requests to endpoints other than session messages
+	SkippedEndpoints map[string]int `json:"skipped_endpoints,omitempty"` // skipped requests per endpoint
+	Divergences      []Divergence   `json:"divergences"`
+}
+
+// Verdict is a one-line summary of the report. It only claims what was
+// checked: skipped requests are called out as unverified.
+func (r ReplayReport) Verdict() string {
+	if len(r.Divergences) > 0 {
+		return fmt.Sprintf("❌ %d of %d steps diverged", len(r.Divergences), r.Steps)
+	}
+	if r.Skipped == 0 {
+		return fmt.Sprintf("✅ All %d steps reproduced exactly", r.Steps)
+	}
+
+	endpoints := make([]string, 0, len(r.SkippedEndpoints))
+	for endpoint, n := range r.SkippedEndpoints {
+		endpoints = append(endpoints, fmt.Sprintf("%d %s", n, endpoint))
+	}
+	sort.Strings(endpoints)
+	unverified := fmt.Sprintf("%d inference requests were not replayed and are unverified (%s)", r.Skipped, strings.Join(endpoints, ", "))
+	if r.Steps == 0 {
+		return "⚠️  No session messages to replay; " + unverified
+	}
+	return fmt.Sprintf("⚠️  All %d session messages reproduced exactly; %s", r.Steps, unverified)
+}
+
+// LoadReplay reads a replay file
+func LoadReplay(path string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer file.Close()
+
+	replay := &Replay{}
+	steps := make(map[int]*ReplayStep)
+	dec := json.NewDecoder(file)
+	for line := 1; ; line++ {
+		var rec ReplayRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("replay record %d: %w", line, err)
+		}
+
+		if rec.Type == "header" {
+			if rec.Version < 1 || rec.Version > replayFormatVersion {
+				return nil, fmt.Errorf("unsupported replay version %d", rec.Version)
+			}
+			replay.Header = rec
+			continue
+		}
+		step, ok := steps[rec.Seq]
+		if !ok {
+			step = &ReplayStep{Seq: rec.Seq, Session: rec.Session}
+			steps[rec.Seq] = step
+		}
+		switch rec.Type {
+		case "input":
+			step.Input = rec.Text
+			if rec.Request != nil {
+				step.Request = *rec.Request
+			}
+		case "output":
+			step.Output = rec.Text
+		case "event":
+			step.Events = append(step.Events, rec)
+		default:
+			return nil, fmt.Errorf("replay record %d: unknown type %q", line, rec.Type)
+		}
+	}
+	if replay.Header.Type != "header" {
+		return nil, fmt.Errorf("replay file has no header")
+	}
+
+	for _, step := range steps {
+		replay.Steps = append(replay.Steps, *step)
+	}
+	sort.Slice(replay.Steps, func(i, j int) bool { return replay.Steps[i].Seq < replay.Steps[j].Seq })
+	return replay, nil
+}
+
+// Run answers every recorded session message with h, in a new session per
+// recorded one, and reports replies that differ. h's generator is seeded
+// from the recording.
+func (r *Replay) Run(h *SessionHandler) ReplayReport {
+	report := ReplayReport{}
+	sessions := make(map[string]string) // recorded ID -> replayed ID
+	for _, step := range r.Steps {
+		if step.Input == "" && step.Output == "" {
+			// Only events, e.g. a stimulus protocol run; nothing to re-execute
+			continue
+		}
+		if endpoint := step.Request.Endpoint; endpoint != "" {
+			if report.SkippedEndpoints == nil {
+				report.SkippedEndpoints = make(map[string]int)
+			}
+			report.Skipped++
+			report.SkippedEndpoints[endpoint]++
+			continue
+		}
+		if report.Steps == 0 {
+			// Seed once there is something to generate
+			h.generator.SetSeed(r.Header.Seed)
+		}
+		report.Steps++
+
+		replayed, err := r.answer(h, sessions, step)
+		if err != nil {
+			replayed = fmt.Sprintf("error: %v", err)
+		}
+		if replayed != step.Output {
+			report.Divergences = append(report.Divergences, Divergence{
+				Seq:      step.Seq,
+				Session:  step.Session,
+				Input:    step.Input,
+				Recorded: step.Output,
+				Replayed: replayed,
+			})
+		}
+	}
+	return report
+}
+
+// answer sends step's message to the session replaying its recorded one
+func (r *Replay) answer(h *SessionHandler, sessions map[string]string, step ReplayStep) (string, error) {
+	id, ok := sessions[step.Session]
+	if !ok {
+		session, err := h.sessions.Create()
+		if err != nil {
+			return "", err
+		}
+		id = session.ID
+		sessions[step.Session] = id
+	}
+	req := step.Request.MessageRequest
+	if req.Content == "" {
+		// Recorded before requests were, as version 1 files are
+		req.Content = step.Input
+	}
+	if err := req.validate(); err != nil {
+		return "", err
+	}
+	resp, err := h.answer(id, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+// ReplayMain implements `go run . replay <file>`
+func ReplayMain(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: genesis replay <replay-file>")
+		os.Exit(2)
+	}
+
+	replay, err := LoadReplay(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	config := replay.Header.Config
+	if config == nil {
+		config = DefaultConfig()
+	}
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		fmt.Printf("❌ ERROR: failed to load datasets: %v\n", err)
+		os.Exit(1)
+	}
+	if fp := loader.CorpusFingerprint(); replay.Header.Corpus != "" && fp != replay.Header.Corpus {
+		fmt.Println("⚠️  Warning: corpus differs from the recording; outputs will likely diverge")
+	}
+	generator, closeGenerator := newServingGenerator(config, loader)
+	defer OnShutdown(ShutdownModels, "response generator", closeGenerator)()
+
+	// Sessions and learned facts start empty and stay in memory, so a replay
+	// neither sees nor touches the recorded run's stores
+	sessions := NewSessionManager(NewMemorySessionStore(), time.Duration(config.Sessions.TTLMinutes)*time.Minute)
+	replayConfig := *config
+	if replayConfig.Knowledge.Path != "" {
+		replayConfig.Knowledge.Path = ":memory:"
+	}
+	handler, closeKnowledge, err := newServingSessionHandler(&replayConfig, sessions, generator, loader)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	defer OnShutdown(ShutdownStores, "knowledge store", closeKnowledge)()
+
+	fmt.Printf("⏯️  Replaying %d steps (seed %d)\n", len(replay.Steps), replay.Header.Seed)
+	report := replay.Run(handler)
+	for _, d := range report.Divergences {
+		fmt.Printf("❗ Step %d diverged\n   input:    %q\n   recorded: %q\n   replayed: %q\n", d.Seq, d.Input, d.Recorded, d.Replayed)
+	}
+	fmt.Println(report.Verdict())
+	if len(report.Divergences) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Per-request resource limits: request_limits in the config bounds every
+// request a model answers, and a Request's own Limits can tighten them.
+// Each request gets a budget threaded through the pipeline: wall time
+// bounds the request's context, so stages and capability calls stop early;
+// generated tokens cap the response length; circuits traced cap what the
+// transparent LLM's circuit search keeps; waves injected cap how many waves
+// the liquid brain sends into its reservoir. Hitting a limit stops that
+// part of the work and the model answers with what it has, naming the
+// limits hit in the response, so one adversarial prompt can't consume the
+// whole process.
+
+// RequestLimits bound one request; zero fields are unlimited
+type RequestLimits struct {
+	MaxWallMS   int `json:"max_wall_ms"`
+	MaxTokens   int `json:"max_tokens"`   // generated tokens (words)
+	MaxCircuits int `json:"max_circuits"` // circuits traced through the concept network
+	MaxWaves    int `json:"max_waves"`    // waves injected into the reservoir
+}
+
+// Limit names reported when a request hits them
+const (
+	limitWallTime = "wall_time"
+	limitTokens   = "tokens"
+	limitCircuits = "circuits"
+	limitWaves    = "waves"
+)
+
+func (l RequestLimits) validate() error {
+	if l.MaxWallMS < 0 || l.MaxTokens < 0 || l.MaxCircuits < 0 || l.MaxWaves < 0 {
+		return fmt.Errorf("request_limits must not be negative")
+	}
+	if l.MaxTokens > maxRequestTokens {
+		return fmt.Errorf("request_limits max_tokens must be at most %d", maxRequestTokens)
+	}
+	return nil
+}
+
+// within returns the tighter of l and o for each limit
+func (l RequestLimits) within(o RequestLimits) RequestLimits {
+	tighter := func(a, b int) int {
+		if a == 0 || (b != 0 && b < a) {
+			return b
+		}
+		return a
+	}
+	return RequestLimits{
+		MaxWallMS:   tighter(l.MaxWallMS, o.MaxWallMS),
+		MaxTokens:   tighter(l.MaxTokens, o.MaxTokens),
+		MaxCircuits: tighter(l.MaxCircuits, o.MaxCircuits),
+		MaxWaves:    tighter(l.MaxWaves, o.MaxWaves),
+	}
+}
+
+// requestBudget tracks one request's use of its limits. A nil budget is
+// unlimited.
+type requestBudget struct {
+	limits    RequestLimits
+	deadline  time.Time // zero without a wall time limit
+	waves     atomic.Int64
+	tokensCut bool // the token limit was tighter than the request's own
+	mu        sync.Mutex
+	hit       map[string]bool
+}
+
+// newRequestBudget starts a budget for limits; nil when nothing is limited
+func newRequestBudget(limits RequestLimits) *requestBudget {
+	if limits == (RequestLimits{}) {
+		return nil
+	}
+	b := &requestBudget{limits: limits, hit: make(map[string]bool)}
+	if limits.MaxWallMS > 0 {
+		b.deadline = time.Now().Add(time.Duration(limits.MaxWallMS) * time.Millisecond)
+	}
+	return b
+}
+
+// startBudget starts the budget for req within the configured limits
+func startBudget(configured RequestLimits, req Request) (*requestBudget, error) {
+	if err := req.Limits.validate(); err != nil {
+		return nil, err
+	}
+	return newRequestBudget(configured.within(req.Limits)), nil
+}
+
+// context bounds ctx by the wall time limit
+func (b *requestBudget) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b == nil || b.deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, b.deadline)
+}
+
+// exceed records that the request hit limit
+func (b *requestBudget) exceed(limit string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hit[limit] = true
+}
+
+// expired reports, and records, whether the wall time limit has passed
+func (b *requestBudget) expired() bool {
+	if b == nil || b.deadline.IsZero() || time.Now().Before(b.deadline) {
+		return false
+	}
+	b.exceed(limitWallTime)
+	return true
+}
+
+// remaining returns how long the request may still wait, at most max
+func (b *requestBudget) remaining(max time.Duration) time.Duration {
+	if b == nil || b.deadline.IsZero() {
+		return max
+	}
+	left := time.Until(b.deadline)
+	if left < 0 {
+		return 0
+	}
+	if left < max {
+		return left
+	}
+	return max
+}
+
+// options carries the budget in generation options, capping their token
+// limit
+func (b *requestBudget) options(o GenerationOptions) GenerationOptions {
+	if b == nil {
+		return o
+	}
+	o.budget = b
+	if b.limits.MaxTokens > 0 && (o.MaxTokens == 0 || b.limits.MaxTokens < o.MaxTokens) {
+		o.MaxTokens = b.limits.MaxTokens
+		b.tokensCut = true
+	}
+	return o
+}
+
+// generated records whether response ran into the token limit
+func (b *requestBudget) generated(response string) {
+	if b != nil && b.tokensCut && len(strings.Fields(response)) >= b.limits.MaxTokens {
+		b.exceed(limitTokens)
+	}
+}
+
+// circuits keeps the strongest circuits the limit allows
+func (b *requestBudget) circuits(circuits []CircuitPath) []CircuitPath {
+	if b == nil || b.limits.MaxCircuits == 0 || len(circuits) <= b.limits.MaxCircuits {
+		return circuits
+	}
+	sort.SliceStable(circuits, func(i, j int) bool {
+		return circuits[i].strength > circuits[j].strength
+	})
+	b.exceed(limitCircuits)
+	return circuits[:b.limits.MaxCircuits]
+}
+
+// takeWaves grants up to n more waves
+func (b *requestBudget) takeWaves(n int) int {
+	if b == nil || b.limits.MaxWaves == 0 {
+		return n
+	}
+	used := b.waves.Add(int64(n))
+	over := used - int64(b.limits.MaxWaves)
+	if over <= 0 {
+		return n
+	}
+	b.exceed(limitWaves)
+	if over >= int64(n) {
+		return 0
+	}
+	return n - int(over)
+}
+
+// Hit returns the limits the request hit, sorted
+func (b *requestBudget) Hit() []string {
+	if b == nil {
+		return nil
+	}
+	b.expired()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var hit []string
+	for limit := range b.hit {
+		hit = append(hit, limit)
+	}
+	sort.Strings(hit)
+	return hit
+}
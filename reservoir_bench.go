@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Reservoir benchmarks: the standard reservoir computing tasks, so topology
+// and hyperparameter choices can be compared by number rather than by eye.
+// Memory capacity drives the reservoir with a random input and sums, over
+// recall delays k, how well a linear readout recovers the input from k steps
+// back. NARMA-10 asks the readout to track a nonlinear system with a ten
+// step memory, scored by normalized RMSE. Separability presents different
+// input words and compares how far apart their reservoir states land with
+// how much repeats of one word vary. The readouts are ridge regressions on
+// the (optionally pooled) state vector, fitted on the first part of a run
+// after a washout and scored on the rest.
+
+// Share of the post-washout steps used to fit the readouts
+const benchTrainFraction = 0.7
+
+// ReservoirBenchConfig sets the benchmark runs
+type ReservoirBenchConfig struct {
+	Steps    int           // driven steps per task, washout included
+	Washout  int           // initial steps left out of the readouts
+	Step     time.Duration // between inputs
+	MaxDelay int           // longest recall delay for memory capacity
+	Ridge    float64       // readout regularization
+	Classes  int           // input words compared for separability
+	Trials   int           // presentations of each word
+	Pooling  StatePooling  // readout features
+	Seed     int64         // input series
+}
+
+// DefaultReservoirBench runs each task for a few seconds
+var DefaultReservoirBench = ReservoirBenchConfig{
+	Steps:    400,
+	Washout:  50,
+	Step:     10 * time.Millisecond,
+	MaxDelay: 20,
+	Ridge:    1e-4,
+	Classes:  4,
+	Trials:   5,
+	Seed:     1,
+}
+
+func (c ReservoirBenchConfig) validate() error {
+	if c.Step <= 0 || c.Ridge <= 0 {
+		return fmt.Errorf("bench step and ridge must be positive")
+	}
+	if c.MaxDelay < 1 || c.Washout < c.MaxDelay {
+		return fmt.Errorf("bench washout must cover max delay, and max delay must be at least 1")
+	}
+	if train := int(float64(c.Steps-c.Washout) * benchTrainFraction); train < 2 || c.Steps-c.Washout-train < 2 {
+		return fmt.Errorf("bench needs more steps than washout to train and test on")
+	}
+	if c.Classes < 2 || c.Trials < 2 {
+		return fmt.Errorf("bench separability needs at least 2 classes and 2 trials")
+	}
+	return nil
+}
+
+// ReservoirBenchReport holds the benchmark metrics
+type ReservoirBenchReport struct {
+	Dimensions         Dimensions `json:"dimensions"`
+	Features           int        `json:"features"`
+	MemoryCapacity     float64    `json:"memory_capacity"` // sum of the per-delay capacities; at most max delay
+	MemoryByDelay      []float64  `json:"memory_by_delay"` // squared correlation of recall at delay 1, 2, ...
+	NARMA10NRMSE       float64    `json:"narma10_nrmse"`   // 1 is no better than predicting the mean
+	Separation         float64    `json:"separation"`      // mean distance between word centroids over mean spread around them
+	SeparationAccuracy float64    `json:"separation_accuracy"`
+	SeparationClasses  []string   `json:"separation_classes"`
+}
+
+// BenchReservoir runs the memory capacity, NARMA-10 and separability tasks
+// against the brain
+func BenchReservoir(brain *LiquidStateBrain, config ReservoirBenchConfig) (*ReservoirBenchReport, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(brain.inputLayer) == 0 {
+		return nil, fmt.Errorf("brain has no input neurons")
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+	report := &ReservoirBenchReport{Dimensions: brain.dimensions, Features: brain.StateVectorLen(config.Pooling)}
+
+	fmt.Printf("🧪 Memory capacity: %d steps, delays 1-%d\n", config.Steps, config.MaxDelay)
+	inputs := make([]float64, config.Steps)
+	for i := range inputs {
+		inputs[i] = rng.Float64()
+	}
+	states := driveReservoir(brain, inputs, config)
+	readout, err := fitRidge(states[config.Washout:trainEnd(config)], config.Ridge)
+	if err != nil {
+		return nil, err
+	}
+	for k := 1; k <= config.MaxDelay; k++ {
+		delayed := make([]float64, config.Steps)
+		for t := k; t < config.Steps; t++ {
+			delayed[t] = inputs[t-k]
+		}
+		capacity := squaredCorrelation(readout.evaluate(states, delayed, config))
+		report.MemoryByDelay = append(report.MemoryByDelay, capacity)
+		report.MemoryCapacity += capacity
+	}
+
+	fmt.Printf("🧪 NARMA-10: %d steps\n", config.Steps)
+	u, y := narma10(config.Steps, rng)
+	drive := make([]float64, len(u))
+	for i := range u {
+		drive[i] = 2 * u[i] // NARMA inputs span [0, 0.5]; use the full stimulus range
+	}
+	states = driveReservoir(brain, drive, config)
+	if readout, err = fitRidge(states[config.Washout:trainEnd(config)], config.Ridge); err != nil {
+		return nil, err
+	}
+	report.NARMA10NRMSE = nrmse(readout.evaluate(states, y, config))
+
+	fmt.Printf("🧪 Separability: %d words x %d trials\n", config.Classes, config.Trials)
+	if err := benchSeparability(brain, config, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// trainEnd returns the step the readout's training rows stop at
+func trainEnd(config ReservoirBenchConfig) int {
+	return config.Washout + int(float64(config.Steps-config.Washout)*benchTrainFraction)
+}
+
+// driveReservoir feeds one input per step into the first input neuron and
+// returns the state vector after each step
+func driveReservoir(brain *LiquidStateBrain, inputs []float64, config ReservoirBenchConfig) [][]float64 {
+	input := brain.inputLayer[0]
+	states := make([][]float64, len(inputs))
+	for t, u := range inputs {
+		brain.stimulateInput(input, "bench", u)
+		brain.clock.Sleep(config.Step)
+		states[t] = brain.StateVector(config.Pooling)
+	}
+	brain.settle()
+	return states
+}
+
+// narma10 returns a NARMA-10 input series in [0, 0.5] and its output
+func narma10(n int, rng *rand.Rand) (u, y []float64) {
+	u = make([]float64, n)
+	y = make([]float64, n)
+	for t := range u {
+		u[t] = rng.Float64() * 0.5
+	}
+	for t := 10; t < n; t++ {
+		sum := 0.0
+		for i := 1; i <= 10; i++ {
+			sum += y[t-i]
+		}
+		y[t] = 0.3*y[t-1] + 0.05*y[t-1]*sum + 1.5*u[t-10]*u[t-1] + 0.1
+	}
+	return u, y
+}
+
+// benchSeparability presents each of the first input words in turn,
+// letting the reservoir settle in between, and compares the states
+func benchSeparability(brain *LiquidStateBrain, config ReservoirBenchConfig, report *ReservoirBenchReport) error {
+	classes := min(config.Classes, len(brain.inputLayer))
+	if classes < 2 {
+		return fmt.Errorf("separability needs at least 2 input neurons, brain has %d", len(brain.inputLayer))
+	}
+	samples := make([][][]float64, classes)
+	for trial := 0; trial < config.Trials; trial++ {
+		for c := 0; c < classes; c++ {
+			input := brain.inputLayer[c]
+			brain.stimulateInput(input, input.word, 1)
+			brain.clock.Sleep(config.Step)
+			samples[c] = append(samples[c], brain.StateVector(config.Pooling))
+			brain.settle()
+		}
+	}
+	for c := 0; c < classes; c++ {
+		report.SeparationClasses = append(report.SeparationClasses, brain.inputLayer[c].word)
+	}
+
+	centroids := make([][]float64, classes)
+	spread := 0.0
+	for c, class := range samples {
+		centroids[c] = make([]float64, len(class[0]))
+		for _, s := range class {
+			for i, v := range s {
+				centroids[c][i] += v / float64(len(class))
+			}
+		}
+		for _, s := range class {
+			spread += euclidean(s, centroids[c])
+		}
+	}
+	spread /= float64(classes * config.Trials)
+	between, pairs := 0.0, 0
+	for a := range centroids {
+		for b := a + 1; b < len(centroids); b++ {
+			between += euclidean(centroids[a], centroids[b])
+			pairs++
+		}
+	}
+	between /= float64(pairs)
+	if spread > 0 {
+		report.Separation = between / spread
+	}
+
+	// Nearest centroid, each sample left out of its own class's centroid
+	correct := 0
+	for c, class := range samples {
+		for _, s := range class {
+			best, bestDistance := -1, math.Inf(1)
+			for other := range centroids {
+				centroid := centroids[other]
+				if other == c {
+					centroid = make([]float64, len(s))
+					n := float64(len(class) - 1)
+					for i := range centroid {
+						centroid[i] = (centroids[c][i]*float64(len(class)) - s[i]) / n
+					}
+				}
+				if d := euclidean(s, centroid); d < bestDistance {
+					best, bestDistance = other, d
+				}
+			}
+			if best == c {
+				correct++
+			}
+		}
+	}
+	report.SeparationAccuracy = float64(correct) / float64(classes*config.Trials)
+	return nil
+}
+
+// euclidean returns the distance between two vectors of equal length
+func euclidean(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// ridgeReadout is a Cholesky factored ridge regression on fixed training
+// rows, solvable for any number of targets
+type ridgeReadout struct {
+	rows [][]float64 // training features, a bias appended
+	l    [][]float64 // lower Cholesky factor of rows'rows + ridge I
+}
+
+// withBias returns x with a constant 1 appended
+func withBias(x []float64) []float64 {
+	return append(append(make([]float64, 0, len(x)+1), x...), 1)
+}
+
+// fitRidge factors the regularized normal equations of rows
+func fitRidge(rows [][]float64, ridge float64) (*ridgeReadout, error) {
+	r := &ridgeReadout{rows: make([][]float64, len(rows))}
+	for i, row := range rows {
+		r.rows[i] = withBias(row)
+	}
+	n := len(r.rows[0])
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n)
+		a[i][i] = ridge
+	}
+	for _, row := range r.rows {
+		for i := 0; i < n; i++ {
+			for j := 0; j <= i; j++ {
+				a[i][j] += row[i] * row[j]
+			}
+		}
+	}
+
+	r.l = make([][]float64, n)
+	for i := 0; i < n; i++ {
+		r.l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= r.l[i][k] * r.l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("readout normal equations are not positive definite")
+				}
+				r.l[i][i] = math.Sqrt(sum)
+			} else {
+				r.l[i][j] = sum / r.l[j][j]
+			}
+		}
+	}
+	return r, nil
+}
+
+// solve returns the weights fitting the training rows to targets
+func (r *ridgeReadout) solve(targets []float64) []float64 {
+	n := len(r.l)
+	b := make([]float64, n)
+	for t, row := range r.rows {
+		for i := range b {
+			b[i] += row[i] * targets[t]
+		}
+	}
+	// Forward then back substitution through L and L'
+	z := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= r.l[i][k] * z[k]
+		}
+		z[i] = sum / r.l[i][i]
+	}
+	w := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := z[i]
+		for k := i + 1; k < n; k++ {
+			sum -= r.l[k][i] * w[k]
+		}
+		w[i] = sum / r.l[i][i]
+	}
+	return w
+}
+
+// evaluate fits the readout to target over the training steps and returns
+// its predictions and the targets over the test steps
+func (r *ridgeReadout) evaluate(states [][]float64, target []float64, config ReservoirBenchConfig) (predicted, actual []float64) {
+	end := trainEnd(config)
+	w := r.solve(target[config.Washout:end])
+	for t := end; t < len(states); t++ {
+		p := 0.0
+		for i, v := range withBias(states[t]) {
+			p += w[i] * v
+		}
+		predicted = append(predicted, p)
+		actual = append(actual, target[t])
+	}
+	return predicted, actual
+}
+
+// squaredCorrelation returns the squared Pearson correlation of a and b,
+// 0 when either is constant
+func squaredCorrelation(a, b []float64) float64 {
+	ma, mb := mean(a), mean(b)
+	var cov, va, vb float64
+	for i := range a {
+		cov += (a[i] - ma) * (b[i] - mb)
+		va += (a[i] - ma) * (a[i] - ma)
+		vb += (b[i] - mb) * (b[i] - mb)
+	}
+	if va == 0 || vb == 0 {
+		return 0
+	}
+	return cov * cov / (va * vb)
+}
+
+// nrmse returns the RMSE of predicted over the standard deviation of actual
+func nrmse(predicted, actual []float64) float64 {
+	m := mean(actual)
+	var se, variance float64
+	for i := range actual {
+		se += (predicted[i] - actual[i]) * (predicted[i] - actual[i])
+		variance += (actual[i] - m) * (actual[i] - m)
+	}
+	if variance == 0 {
+		return 0
+	}
+	return math.Sqrt(se / variance)
+}
+
+// mean returns the mean of values
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Print writes the report for humans
+func (r *ReservoirBenchReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "📊 Reservoir benchmarks (%dx%dx%d, %d features)\n", r.Dimensions.X, r.Dimensions.Y, r.Dimensions.Z, r.Features)
+	fmt.Fprintf(w, "   Memory capacity: %.2f over %d delays\n", r.MemoryCapacity, len(r.MemoryByDelay))
+	for k, capacity := range r.MemoryByDelay {
+		fmt.Fprintf(w, "      delay %2d: %.3f\n", k+1, capacity)
+	}
+	fmt.Fprintf(w, "   NARMA-10 NRMSE: %.3f\n", r.NARMA10NRMSE)
+	fmt.Fprintf(w, "   Separation: %.2f (nearest centroid accuracy %.0f%% over %v)\n", r.Separation, r.SeparationAccuracy*100, r.SeparationClasses)
+}
+
+// record logs the report's scores and saves it in run, finishing the run
+func (r *ReservoirBenchReport) record(run *TrackedRun) {
+	run.LogMetric("memory_capacity", r.MemoryCapacity)
+	run.LogMetric("narma10_nrmse", r.NARMA10NRMSE)
+	run.LogMetric("separation", r.Separation)
+	run.LogMetric("separation_accuracy", r.SeparationAccuracy)
+	if err := run.SaveJSONArtifact("report.json", r); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	run.Finish(nil)
+}
+
+// BenchMain implements `go run . bench reservoir` and
+// `go run . bench conversation`
+func BenchMain(args []string) {
+	if len(args) > 0 && args[0] == "conversation" {
+		ConversationBenchMain(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "scale" {
+		ScaleBenchMain(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "reservoir" {
+		fmt.Println("usage: genesis bench reservoir|conversation|scale [flags]")
+		os.Exit(2)
+	}
+	defaults := DefaultReservoirBench
+	fs := flag.NewFlagSet("bench reservoir", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	brainSize := fs.Int("brain-size", 8, "Liquid brain size")
+	steps := fs.Int("steps", defaults.Steps, "Driven steps per task, washout included")
+	washout := fs.Int("washout", defaults.Washout, "Initial steps left out of the readouts")
+	step := fs.Duration("step", defaults.Step, "Time between inputs")
+	maxDelay := fs.Int("max-delay", defaults.MaxDelay, "Longest recall delay for memory capacity")
+	ridge := fs.Float64("ridge", defaults.Ridge, "Readout regularization")
+	classes := fs.Int("classes", defaults.Classes, "Input words compared for separability")
+	trials := fs.Int("trials", defaults.Trials, "Presentations of each word")
+	pool := fs.Int("pool", 1, "Side of the neuron blocks averaged into each feature")
+	seed := fs.Int64("seed", defaults.Seed, "Seed for the input series")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args[1:])
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	brain := NewLiquidStateBrainWithConfig(*brainSize, config)
+	if brain == nil {
+		fmt.Println("❌ ERROR: failed to create brain")
+		os.Exit(1)
+	}
+	defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
+
+	run := TrackRun(config, "bench-reservoir", map[string]string{
+		"brain_size": fmt.Sprint(*brainSize),
+		"steps":      fmt.Sprint(*steps),
+		"washout":    fmt.Sprint(*washout),
+		"ridge":      fmt.Sprint(*ridge),
+		"pool":       fmt.Sprint(*pool),
+		"seed":       fmt.Sprint(*seed),
+	})
+	report, err := BenchReservoir(brain, ReservoirBenchConfig{
+		Steps:    *steps,
+		Washout:  *washout,
+		Step:     *step,
+		MaxDelay: *maxDelay,
+		Ridge:    *ridge,
+		Classes:  *classes,
+		Trials:   *trials,
+		Pooling:  StatePooling{Size: *pool},
+		Seed:     *seed,
+	})
+	if err != nil {
+		run.Finish(err)
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	report.record(run)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	report.Print(os.Stdout)
+}
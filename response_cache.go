@@ -0,0 +1,210 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Response caching: deployments with repetitive query patterns can answer a
+// repeated request from an LRU cache in front of Think, Understand and
+// session messages instead of running the reservoir or concept network
+// again. The key is the normalized input (lowercased, whitespace collapsed,
+// trailing punctuation trimmed) plus a hash of the session context, meaning
+// the generator state and earlier turns, and the generation options, so a
+// reply is only reused where the same context would have produced it; the
+// typical hit is a fresh session asking a common first question. Entries
+// expire after a TTL, and every entry cached before the model last changed
+// (a corpus addition, a checkpoint load, output training) counts as a miss.
+// A hit does no reservoir or concept work, which its energy report shows.
+
+// ResponseCacheConfig controls the response cache; disabled by default
+type ResponseCacheConfig struct {
+	Enabled    bool `json:"enabled"`
+	Capacity   int  `json:"capacity"`    // responses kept
+	TTLSeconds int  `json:"ttl_seconds"` // 0 keeps responses until evicted or invalidated
+}
+
+func (c ResponseCacheConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Capacity <= 0 || c.TTLSeconds < 0 {
+		return fmt.Errorf("response_cache capacity must be positive and ttl_seconds not negative")
+	}
+	return nil
+}
+
+// modelVersion counts model updates; responses cached under an older
+// version are stale
+var modelVersion atomic.Uint64
+
+// ModelUpdated invalidates every cached response. Call it whenever
+// something responses are generated from changes.
+func ModelUpdated() {
+	modelVersion.Add(1)
+}
+
+// CachedResponse is what a cache hit returns
+type CachedResponse struct {
+	Output      string
+	Explanation *Explanation
+	Confidence  Confidence
+	State       *GeneratorState // the session's generator state after the response; nil outside sessions
+}
+
+// ResponseCacheStats reports the cache's size and hit counts
+type ResponseCacheStats struct {
+	Size     int   `json:"size"`
+	Capacity int   `json:"capacity"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+// ResponseCache is an LRU cache of responses with a TTL. A nil
+// ResponseCache caches nothing.
+type ResponseCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	clock    Clock
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+	hits     int64
+	misses   int64
+}
+
+type responseCacheEntry struct {
+	key      string
+	response CachedResponse
+	version  uint64
+	expires  time.Time // zero never expires
+}
+
+// NewResponseCache creates a cache from config; nil when disabled
+func NewResponseCache(config ResponseCacheConfig, clock Clock) *ResponseCache {
+	if !config.Enabled || config.Capacity <= 0 {
+		return nil
+	}
+	return &ResponseCache{
+		capacity: config.Capacity,
+		ttl:      time.Duration(config.TTLSeconds) * time.Second,
+		clock:    clock,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// responseCacheKey keys a request by its normalized input, the generation
+// options and, for a session message, the session context before the turn
+func responseCacheKey(input string, options GenerationOptions, session *Session) string {
+	h := sha256.New()
+	normalized := strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	fmt.Fprintf(h, "%s\x00", strings.TrimRight(normalized, ".!?,;: "))
+	opts, _ := json.Marshal(options) // map keys are sorted, so equal options encode equally
+	fmt.Fprintf(h, "%s\x00", opts)
+	if session != nil {
+		for _, word := range session.Generator.ContextWindow {
+			fmt.Fprintf(h, "%s\x00", word)
+		}
+		topics := make([]string, 0, len(session.Generator.TopicMemory))
+		for topic, weight := range session.Generator.TopicMemory {
+			topics = append(topics, fmt.Sprintf("%s=%g", topic, weight))
+		}
+		sort.Strings(topics)
+		fmt.Fprintf(h, "%s\x00", strings.Join(topics, ","))
+		for _, msg := range session.History {
+			fmt.Fprintf(h, "%s:%s\x00", msg.Role, msg.Content)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the response cached under key, if it is still current
+func (c *ResponseCache) Get(key string) (CachedResponse, bool) {
+	if c == nil {
+		return CachedResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*responseCacheEntry)
+		if entry.version != modelVersion.Load() || (!entry.expires.IsZero() && !c.clock.Now().Before(entry.expires)) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+			ok = false
+		}
+	}
+	if !ok {
+		c.misses++
+		return CachedResponse{}, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return copyCachedResponse(elem.Value.(*responseCacheEntry).response), true
+}
+
+// Put caches response under key, evicting the least recently used response
+// when full
+func (c *ResponseCache) Put(key string, response CachedResponse) {
+	if c == nil {
+		return
+	}
+	entry := &responseCacheEntry{key: key, response: copyCachedResponse(response), version: modelVersion.Load()}
+	if c.ttl > 0 {
+		entry.expires = c.clock.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+}
+
+// Stats returns the cache's size and hit counts
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	if c == nil {
+		return ResponseCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ResponseCacheStats{Size: c.order.Len(), Capacity: c.capacity, Hits: c.hits, Misses: c.misses}
+}
+
+// copyCachedResponse copies what callers go on to modify: the explanation
+// and the session state
+func copyCachedResponse(response CachedResponse) CachedResponse {
+	if response.Explanation != nil {
+		explanation := *response.Explanation
+		response.Explanation = &explanation
+	}
+	if response.State != nil {
+		state := GeneratorState{ContextWindow: append([]string(nil), response.State.ContextWindow...)}
+		if response.State.TopicMemory != nil {
+			state.TopicMemory = make(map[string]float64, len(response.State.TopicMemory))
+			for topic, weight := range response.State.TopicMemory {
+				state.TopicMemory[topic] = weight
+			}
+		}
+		response.State = &state
+	}
+	return response
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"maps"
 	"math"
+	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // ResponseGenerator handles advanced text generation with beam search
@@ -15,6 +18,30 @@ type ResponseGenerator struct {
 	topicMemory     map[string]float64
 	contextWindow   []string
 	grammarPatterns map[string][]string
+	retrieval       *RetrievalIndex // nil disables retrieval-augmented generation
+	retrievalK      int
+	answerThreshold float64 // minimum confidence for extractive answers
+	topicBias       map[string]float64 // word -> score multiplier, set by SetTopicBias
+	active          *grounding // retrieval context of the current Generate call
+	language        string     // language the current Generate call stays in; "" is unconstrained
+	schema          *ConceptSchema // question, greeting and starter words
+	tokenHooks      []TokenHook // run on every token, see OnToken
+	aborted         bool        // a token hook stopped the current Generate call
+	tokenLimit      int         // the current call's max tokens; 0 uses maxLength
+	stops           [][]string  // the current call's stop sequences, lowercase words
+	wordBias        map[string]float64 // the current call's word -> score multiplier
+	tree            *BeamTree          // the current call's search, when diagnostics were requested
+	search          BeamSearchConfig   // length normalization and diversity
+	nBest           int                // responses the current call returns as alternatives
+	templates       *TemplateLibrary   // nil disables template responses
+	templateConfig  TemplateConfig     // when templates replace or lead responses
+	seed            int64              // the current call's seed
+	seeds           *rand.Rand         // draws the seeds of calls that don't set one
+	rng             *rand.Rand         // the current call's random choices, seeded by seed
+	knowledge       *KnowledgeStore    // learned facts; nil disables recalling them
+	factSession     string             // session the current call recalls facts from
+	query           string             // what the current call retrieves and answers for; "" uses its input
+	mu              *sync.Mutex        // guards the configuration and shared conversation; shared by a call's copy, see begin
 }
 
 // Beam represents a partial response being generated
@@ -24,6 +51,8 @@ type Beam struct {
 	lastWord   string
 	topicScore float64
 	complete   bool
+	node       int // BeamTree node; -1 when not recording
+	group      int // diverse beam group
 }
 
 func NewResponseGenerator(dataLoader *DatasetLoader) *ResponseGenerator {
@@ -35,11 +64,25 @@ func NewResponseGenerator(dataLoader *DatasetLoader) *ResponseGenerator {
 		topicMemory:     make(map[string]float64),
 		contextWindow:   make([]string, 0),
 		grammarPatterns: initializeGrammarPatterns(),
+		answerThreshold: defaultAnswerThreshold,
+		search:          defaultBeamSearch,
+		schema:          DefaultConceptSchema(),
+		seeds:           globalRand,
+		mu:              new(sync.Mutex),
 	}
 	
 	return gen
 }
 
+// SetConceptSchema replaces the word lists used to classify inputs and pick
+// starter words. A nil schema restores the default.
+func (gen *ResponseGenerator) SetConceptSchema(schema *ConceptSchema) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+	
+	gen.schema = schema.orDefault()
+}
+
 func initializeGrammarPatterns() map[string][]string {
 	return map[string][]string{
 		"greeting_start": {"hello", "hi", "greetings", "hey"},
@@ -52,35 +95,207 @@ func initializeGrammarPatterns() map[string][]string {
 
 // Generate creates a response using beam search
 func (gen *ResponseGenerator) Generate(input string, activeConcepts []string) string {
+	response, _ := gen.GenerateExplained(input, activeConcepts)
+	return response
+}
+
+// GenerateExplained generates a response and reports which concepts and
+// retrieved chunks shaped it
+func (gen *ResponseGenerator) GenerateExplained(input string, activeConcepts []string) (string, *Explanation) {
+	return gen.generate(nil, input, activeConcepts, GenerationOptions{})
+}
+
+// generate runs one Generate call on state's conversation, or on the
+// shared one when state is nil. Only reading and updating the conversation
+// holds gen.mu: the search runs on the call's own copy of the generator, so
+// concurrent calls don't wait for each other.
+func (gen *ResponseGenerator) generate(state *GeneratorState, input string, activeConcepts []string, options GenerationOptions) (string, *Explanation) {
+	input = NormalizeInput(input)
+	call := gen.begin(state, input, activeConcepts, options)
+	response, explanation := call.run(input, activeConcepts)
+	if call.active != nil {
+		gen.mu.Lock()
+		defer gen.mu.Unlock()
+		if state != nil {
+			gen.seedTopicMemory(state.TopicMemory, call.active)
+		} else {
+			gen.seedTopicMemory(gen.topicMemory, call.active)
+		}
+	}
+	return response, explanation
+}
+
+// begin adds input to the conversation under gen.mu and returns the call's
+// copy of the generator: the configuration, a snapshot of the conversation
+// and the call's options. The copy shares gen.mu but never takes it.
+func (gen *ResponseGenerator) begin(state *GeneratorState, input string, activeConcepts []string, options GenerationOptions) *ResponseGenerator {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+	
+	call := *gen
+	if state != nil {
+		if state.TopicMemory == nil {
+			state.TopicMemory = make(map[string]float64)
+		}
+		call.topicMemory, call.contextWindow = state.TopicMemory, state.ContextWindow
+	}
+	
 	// Update context and topic memory
-	gen.updateContext(input)
-	gen.updateTopicMemory(activeConcepts)
+	call.updateContext(input)
+	call.updateTopicMemory(activeConcepts)
+	call.seedKeywords(input)
+	if state != nil {
+		state.TopicMemory, state.ContextWindow = call.topicMemory, call.contextWindow
+	} else {
+		gen.topicMemory, gen.contextWindow = call.topicMemory, call.contextWindow
+	}
+	
+	// Later calls keep updating the conversation while this one searches
+	call.topicMemory = maps.Clone(call.topicMemory)
+	call.contextWindow = append([]string(nil), call.contextWindow...)
+	call.setOptions(options)
+	return &call
+}
+
+// run does the work of a Generate call on the call's copy of the generator,
+// see begin
+func (gen *ResponseGenerator) run(input string, activeConcepts []string) (string, *Explanation) {
+	query := input
+	if gen.query != "" {
+		query = NormalizeInput(gen.query)
+	}
+	
+	// Answer in the input's language when the corpus mixes languages
+	gen.language = gen.dataLoader.GenerationLanguage(query)
+	
+	// Ground the response in retrieved corpus chunks
+	gen.active = gen.retrieve(query)
+	if gen.active != nil {
+		gen.seedTopicMemory(gen.topicMemory, gen.active)
+	}
+	
+	// Questions about what the user has told us get the recalled facts
+	if facts := gen.recallFacts(query); len(facts) > 0 {
+		response := factAnswer(facts)
+		explanation := &Explanation{
+			Input:          query,
+			Response:       response,
+			ActiveConcepts: activeConcepts,
+			Facts:          facts,
+			Language:       gen.language,
+			FinishReason:   finishStop,
+			BeamTree:       gen.tree, // empty: no search ran
+			Seed:           gen.seedUsed(),
+		}
+		explanation.Confidence.combine(facts[0].Confidence)
+		return response, explanation
+	}
 	
-	// Initialize beams with starter words
+	// Questions the corpus answers directly get the answering sentence
+	if answer := gen.extractAnswer(query); answer != nil && !gen.bannedIn(answer.Text) {
+		words := strings.Fields(answer.Text)
+		kept, finish := gen.limitWords(words)
+		kept = gen.finalTokens(kept)
+		if strings.Join(kept, " ") != strings.Join(words, " ") {
+			answer.Text = strings.Join(kept, " ")
+		}
+		explanation := &Explanation{
+			Input:          query,
+			Response:       answer.Text,
+			ActiveConcepts: activeConcepts,
+			Answer:         answer,
+			Language:       gen.language,
+			FinishReason:   finish,
+			BeamTree:       gen.tree, // empty: no search ran
+			Seed:           gen.seedUsed(),
+		}
+		explanation.Confidence.combine(answer.Confidence)
+		if gen.nBest > 0 {
+			explanation.Alternatives = []ResponseCandidate{{Response: answer.Text, Score: answer.Confidence, Probability: 1}}
+		}
+		gen.active.explain(explanation, nil)
+		explanation.Cited = []int{answer.ChunkID}
+		return answer.Text, explanation
+	}
+	
+	// Initialize beams with starter words, split into diverse groups
 	beams := gen.initializeBeams(input, activeConcepts)
+	groups := gen.beamGroups(len(beams))
+	for i := range beams {
+		beams[i].group = i % groups
+	}
 	
-	// Beam search
-	for step := 0; step < gen.maxLength && !gen.allBeamsComplete(beams); step++ {
-		newBeams := []Beam{}
+	// Beam search, one group after another at each step
+	var expanded int64
+	for step := 0; step < gen.maxTokens() && !gen.aborted && !gen.allBeamsComplete(beams); step++ {
+		chosen := make(map[string]int) // words earlier groups chose at this step
+		next := []Beam{}
 		
-		for _, beam := range beams {
-			if beam.complete {
-				newBeams = append(newBeams, beam)
-				continue
+		for group := 0; group < groups; group++ {
+			newBeams := []Beam{}
+			for _, beam := range beams {
+				if beam.group != group {
+					continue
+				}
+				if beam.complete || gen.aborted {
+					newBeams = append(newBeams, beam)
+					continue
+				}
+				
+				// Expand beam with possible next words
+				expansions := gen.expandBeam(beam, activeConcepts, chosen)
+				newBeams = append(newBeams, expansions...)
+				expanded++
 			}
 			
-			// Expand beam with possible next words
-			expansions := gen.expandBeam(beam, activeConcepts)
-			newBeams = append(newBeams, expansions...)
+			// Keep the group's top beams
+			kept := gen.selectTopBeams(newBeams, gen.groupWidth(groups))
+			for _, beam := range kept {
+				if len(beam.words) == step+2 {
+					chosen[beam.lastWord]++
+				}
+			}
+			next = append(next, kept...)
 		}
-		
-		// Keep top beams
-		beams = gen.selectTopBeams(newBeams)
+		beams = next
 	}
 	
 	// Select best complete response
 	bestBeam := gen.selectBestResponse(beams)
-	return gen.formatResponse(bestBeam)
+	confidence := gen.beamConfidence(beams, bestBeam)
+	if gen.tree != nil {
+		gen.tree.Chosen = bestBeam.node
+	}
+	alternatives := gen.alternatives(beams, bestBeam)
+	words, finish := gen.limitWords(bestBeam.words)
+	if finish == finishStop && len(words) == len(bestBeam.words) && len(words) >= gen.maxTokens() {
+		finish = finishLength
+	}
+	bestBeam.words = gen.finalTokens(words)
+	response := gen.formatResponse(bestBeam)
+	
+	explanation := &Explanation{
+		Input:          query,
+		Response:       response,
+		ActiveConcepts: activeConcepts,
+		Energy:         EnergyReport{BeamsExpanded: expanded},
+		Language:       gen.language,
+		Confidence:     confidence,
+		FinishReason:   finish,
+		BeamTree:       gen.tree,
+		Alternatives:   alternatives,
+		Seed:           gen.seedUsed(),
+	}
+	if len(alternatives) > 0 {
+		// Token hooks may have edited the chosen response
+		alternatives[0].Response = response
+	}
+	gen.active.explain(explanation, bestBeam.words)
+	
+	// Low-confidence responses fall back on the template library
+	response = gen.applyTemplate(explanation, activeConcepts)
+	
+	return response, explanation
 }
 
 func (gen *ResponseGenerator) updateContext(input string) {
@@ -108,6 +323,16 @@ func (gen *ResponseGenerator) updateTopicMemory(concepts []string) {
 	}
 }
 
+// seedKeywords raises topic memory for the input's keyphrase words
+func (gen *ResponseGenerator) seedKeywords(input string) {
+	for word, weight := range keywordWeights(gen.dataLoader.ExtractKeyphrases(input, inputKeyphrases)) {
+		weight *= keyphraseTopicWeight
+		if weight > gen.topicMemory[word] {
+			gen.topicMemory[word] = weight
+		}
+	}
+}
+
 func (gen *ResponseGenerator) initializeBeams(input string, activeConcepts []string) []Beam {
 	beams := []Beam{}
 	inputWords := strings.Fields(strings.ToLower(input))
@@ -119,22 +344,47 @@ func (gen *ResponseGenerator) initializeBeams(input string, activeConcepts []str
 	starters := gen.getStarterWords(responseType, activeConcepts)
 	
 	for _, starter := range starters {
+		if gen.aborted {
+			break
+		}
+		starter, action := gen.searchToken(starter, nil, 0)
+		score := gen.scoreWord(starter, nil, activeConcepts)
+		pruned := ""
+		switch {
+		case action == TokenDrop || action == TokenAbort:
+			pruned = pruneHook
+		case gen.stopMatch([]string{starter}) > 0:
+			pruned = pruneStop
+		case gen.banned(starter):
+			pruned = pruneBanned
+		}
+		node := gen.tree.add(-1, 0, starter, score, score)
+		if pruned != "" {
+			gen.tree.prune(node, pruned)
+			continue
+		}
 		beam := Beam{
 			words:      []string{starter},
-			score:      gen.scoreWord(starter, nil, activeConcepts),
+			score:      score,
 			lastWord:   starter,
 			topicScore: gen.calculateTopicRelevance(starter),
-			complete:   false,
+			complete:   action == TokenEndBeam,
+			node:       node,
+		}
+		if beam.complete {
+			gen.tree.complete(node)
 		}
 		beams = append(beams, beam)
 	}
 	
 	// Ensure we have at least one beam
 	if len(beams) == 0 {
+		starter := gen.dataLoader.GetStarterWordIn(gen.language)
 		beams = append(beams, Beam{
-			words:    []string{gen.dataLoader.GetStarterWord()},
-			lastWord: gen.dataLoader.GetStarterWord(),
+			words:    []string{starter},
+			lastWord: starter,
 			score:    1.0,
+			node:     gen.tree.add(-1, 0, starter, 1.0, 1.0),
 		})
 	}
 	
@@ -149,37 +399,22 @@ func (gen *ResponseGenerator) classifyInput(words []string) string {
 	firstWord := words[0]
 	
 	// Check for question words
-	questionWords := []string{"what", "how", "why", "when", "where", "who", "can", "do", "is", "are"}
-	for _, qw := range questionWords {
-		if firstWord == qw {
-			return "question"
-		}
+	if contains(gen.schema.QuestionWords, firstWord) {
+		return "question"
 	}
 	
 	// Check for greetings
-	greetings := []string{"hello", "hi", "hey", "greetings"}
-	for _, g := range greetings {
-		if firstWord == g {
-			return "greeting"
-		}
+	if contains(gen.schema.GreetingWords, firstWord) {
+		return "greeting"
 	}
 	
 	return "statement"
 }
 
 func (gen *ResponseGenerator) getStarterWords(responseType string, activeConcepts []string) []string {
-	starters := []string{}
-	
-	switch responseType {
-	case "greeting":
-		starters = append(starters, "hello", "hi", "greetings")
-	case "question":
-		// For questions, we might start with affirmative or explanation words
-		starters = append(starters, "yes", "i", "the", "this", "that")
-	default:
-		// For statements, use a mix of common starters
-		starters = append(starters, "i", "the", "this", "we", "that")
-	}
+	// Starters for the response type; questions might start with
+	// affirmative or explanation words, statements with common starters
+	starters := append([]string{}, gen.schema.Starters[responseType]...)
 	
 	// Add some activated concepts as potential starters
 	for i, concept := range activeConcepts {
@@ -188,16 +423,10 @@ func (gen *ResponseGenerator) getStarterWords(responseType string, activeConcept
 		}
 	}
 	
-	// Filter to only words in vocabulary
+	// Filter to only words in the vocabulary of the response language
 	validStarters := []string{}
-	vocab := gen.dataLoader.GetVocabulary()
-	vocabMap := make(map[string]bool)
-	for _, v := range vocab {
-		vocabMap[v] = true
-	}
-	
 	for _, starter := range starters {
-		if vocabMap[starter] {
+		if gen.dataLoader.InLanguage(gen.language, starter) {
 			validStarters = append(validStarters, starter)
 		}
 	}
@@ -205,36 +434,73 @@ func (gen *ResponseGenerator) getStarterWords(responseType string, activeConcept
 	return validStarters
 }
 
-func (gen *ResponseGenerator) expandBeam(beam Beam, activeConcepts []string) []Beam {
+// expandBeam extends beam with its top candidates, penalizing the words
+// earlier beam groups chose at this step
+func (gen *ResponseGenerator) expandBeam(beam Beam, activeConcepts []string, chosen map[string]int) []Beam {
 	expansions := []Beam{}
 	
 	// Get transition candidates
-	transitions, exists := gen.dataLoader.GetTransitions(beam.lastWord)
-	if !exists || len(transitions) == 0 {
+	prev := ""
+	if len(beam.words) > 1 {
+		prev = beam.words[len(beam.words)-2]
+	}
+	transitions, _ := gen.dataLoader.GetTransitionsInAfter(gen.language, prev, beam.lastWord)
+	transitions = gen.active.candidatesFor(beam.lastWord, transitions)
+	if len(transitions) == 0 {
 		// If no transitions, try to end the sentence gracefully
 		beam.complete = true
+		gen.tree.complete(beam.node)
 		return []Beam{beam}
 	}
 	
 	// Score and rank candidates
 	candidates := gen.rankCandidates(transitions, beam, activeConcepts)
+	gen.diversify(candidates, chosen)
+	gen.tree.expanded(beam.node, len(candidates))
 	
-	// Take top candidates
-	for i, candidate := range candidates {
-		if i >= gen.beamWidth {
+	// Take top candidates the token hooks accept
+	for _, candidate := range candidates {
+		if len(expansions) >= gen.width() {
 			break
 		}
+		word, action := gen.searchToken(candidate.word, beam.words, candidate.score)
+		if action == TokenDrop || action == TokenAbort {
+			gen.tree.prune(gen.tree.add(beam.node, len(beam.words), word, candidate.score, beam.score+candidate.score), pruneHook)
+		}
+		if action == TokenDrop {
+			continue
+		}
+		if action == TokenAbort {
+			// Keep what the beam had
+			beam.complete = true
+			gen.tree.complete(beam.node)
+			return append(expansions, beam)
+		}
 		
 		newBeam := Beam{
-			words:      append(append([]string{}, beam.words...), candidate.word),
+			words:      append(append([]string{}, beam.words...), word),
 			score:      beam.score + candidate.score,
-			lastWord:   candidate.word,
-			topicScore: beam.topicScore + gen.calculateTopicRelevance(candidate.word),
-			complete:   gen.shouldComplete(beam, candidate.word),
+			lastWord:   word,
+			topicScore: beam.topicScore + gen.calculateTopicRelevance(word),
+			complete:   action == TokenEndBeam || gen.shouldComplete(beam, word),
+			group:      beam.group,
+		}
+		if gen.stopMatch(newBeam.words) > 0 {
+			newBeam.complete = true
+		}
+		newBeam.node = gen.tree.add(beam.node, len(beam.words), word, candidate.score, newBeam.score)
+		if newBeam.complete {
+			gen.tree.complete(newBeam.node)
 		}
 		
 		expansions = append(expansions, newBeam)
 	}
+	if len(expansions) == 0 {
+		// The hooks dropped every candidate
+		beam.complete = true
+		gen.tree.complete(beam.node)
+		return []Beam{beam}
+	}
 	
 	return expansions
 }
@@ -269,7 +535,15 @@ func (gen *ResponseGenerator) rankCandidates(transitions map[string]float64, bea
 			continue
 		}
 		
+		// Skip words the request bans
+		if gen.banned(word) {
+			continue
+		}
+		
 		score := gen.scoreWord(word, &beam, activeConcepts) * prob
+		if gen.active.continuesPhrase(beam.lastWord, word) {
+			score *= groundingPhraseBonus
+		}
 		candidates = append(candidates, wordCandidate{word, score})
 	}
 	
@@ -277,6 +551,7 @@ func (gen *ResponseGenerator) rankCandidates(transitions map[string]float64, bea
 	sort.Slice(candidates, func(i, j int) bool {
 		return candidates[i].score > candidates[j].score
 	})
+	gen.breakTies(candidates)
 	
 	return candidates
 }
@@ -299,6 +574,16 @@ func (gen *ResponseGenerator) scoreWord(word string, beam *Beam, activeConcepts
 		score *= (1.0 + topicScore)
 	}
 	
+	// Discovered-topic bias
+	if bias, ok := gen.topicBias[word]; ok {
+		score *= bias
+	}
+	
+	// Per-request word bias
+	if bias, ok := gen.wordBias[strings.ToLower(word)]; ok {
+		score *= bias
+	}
+	
 	// Concept activation bonus
 	for _, concept := range activeConcepts {
 		if word == concept {
@@ -374,9 +659,15 @@ func (gen *ResponseGenerator) isGoodTransition(word1, word2 string) bool {
 func (gen *ResponseGenerator) calculateTopicRelevance(word string) float64 {
 	relevance := 0.0
 	
-	for topic, weight := range gen.topicMemory {
+	// Sum in topic order so the result doesn't depend on map iteration
+	topics := make([]string, 0, len(gen.topicMemory))
+	for topic := range gen.topicMemory {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
 		similarity := gen.wordSimilarity(word, topic)
-		relevance += similarity * weight
+		relevance += similarity * gen.topicMemory[topic]
 	}
 	
 	return relevance
@@ -388,16 +679,8 @@ func (gen *ResponseGenerator) wordSimilarity(word1, word2 string) float64 {
 	}
 	
 	// Check embeddings
-	emb1, exists1 := gen.dataLoader.GetEmbedding(word1)
-	emb2, exists2 := gen.dataLoader.GetEmbedding(word2)
-	
-	if exists1 && exists2 {
-		// Cosine similarity
-		dot := 0.0
-		for i := range emb1 {
-			dot += emb1[i] * emb2[i]
-		}
-		return dot
+	if sim, ok := gen.dataLoader.similarity(word1, word2); ok {
+		return sim
 	}
 	
 	// Simple substring matching
@@ -415,7 +698,7 @@ func (gen *ResponseGenerator) shouldComplete(beam Beam, nextWord string) bool {
 	}
 	
 	// Check length
-	if len(beam.words) >= gen.maxLength-1 {
+	if len(beam.words) >= gen.maxTokens()-1 {
 		return true
 	}
 	
@@ -427,15 +710,19 @@ func (gen *ResponseGenerator) shouldComplete(beam Beam, nextWord string) bool {
 	return false
 }
 
-func (gen *ResponseGenerator) selectTopBeams(beams []Beam) []Beam {
+// selectTopBeams keeps the width best beams by length-normalized score
+func (gen *ResponseGenerator) selectTopBeams(beams []Beam, width int) []Beam {
 	// Sort by score
 	sort.Slice(beams, func(i, j int) bool {
-		return beams[i].score > beams[j].score
+		return gen.normalizedScore(beams[i]) > gen.normalizedScore(beams[j])
 	})
 	
 	// Keep top beams
-	if len(beams) > gen.beamWidth*2 {
-		beams = beams[:gen.beamWidth*2]
+	if len(beams) > width {
+		for _, pruned := range beams[width:] {
+			gen.tree.prune(pruned.node, pruneBeamWidth)
+		}
+		beams = beams[:width]
 	}
 	
 	return beams
@@ -452,15 +739,17 @@ func (gen *ResponseGenerator) allBeamsComplete(beams []Beam) bool {
 
 func (gen *ResponseGenerator) selectBestResponse(beams []Beam) Beam {
 	if len(beams) == 0 {
-		return Beam{words: []string{"I", "understand"}}
+		return Beam{words: []string{"I", "understand"}, node: -1}
 	}
 	
 	// Score beams by multiple criteria
 	bestBeam := beams[0]
 	bestScore := gen.scoreResponse(beams[0])
+	gen.tree.scored(bestBeam.node, bestScore)
 	
 	for _, beam := range beams[1:] {
 		score := gen.scoreResponse(beam)
+		gen.tree.scored(beam.node, score)
 		if score > bestScore {
 			bestScore = score
 			bestBeam = beam
@@ -470,13 +759,24 @@ func (gen *ResponseGenerator) selectBestResponse(beams []Beam) Beam {
 	return bestBeam
 }
 
+// beamConfidence rates how clearly best won among the final beams
+func (gen *ResponseGenerator) beamConfidence(beams []Beam, best Beam) Confidence {
+	scores := make([]float64, len(beams))
+	for i, beam := range beams {
+		scores[i] = gen.scoreResponse(beam)
+	}
+	confidence := Confidence{BeamScore: beamProbability(gen.scoreResponse(best), scores)}
+	confidence.combine(confidence.BeamScore)
+	return confidence
+}
+
 func (gen *ResponseGenerator) scoreResponse(beam Beam) float64 {
 	if len(beam.words) == 0 {
 		return 0.0
 	}
 	
 	// Base score
-	score := beam.score
+	score := gen.normalizedScore(beam)
 	
 	// Length penalty (prefer medium length)
 	idealLength := 10.0
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RetrievalConfig controls how documents are split for retrieval
+type RetrievalConfig struct {
+	ChunkTokens     int               `json:"chunk_tokens"`     // words per chunk
+	ChunkOverlap    int               `json:"chunk_overlap"`    // words shared by consecutive chunks
+	TopK            int               `json:"top_k"`            // chunks used to ground each response; 0 disables grounding
+	AnswerThreshold float64           `json:"answer_threshold"` // minimum confidence for extractive answers to questions
+	VectorStore     VectorStoreConfig `json:"vector_store"`
+}
+
+// Chunk is a window of a loaded document. Text keeps the original wording
+// and punctuation so it can be quoted back; Tokens is the normalized form.
+type Chunk struct {
+	ID       int
+	Path     string
+	Start    int // index of the first word in the document
+	End      int // index one past the last word
+	Text     string
+	Tokens   []string
+	Vector   []float64 // L2-normalized mean of the token embeddings, nil if none are known
+	Language string    // language of the source document
+	doc      int       // index into RetrievalIndex.docWords
+}
+
+// ScoredChunk is a search hit
+type ScoredChunk struct {
+	Chunk *Chunk
+	Score float64 // cosine similarity to the query
+}
+
+// ChunkText splits text into windows of size words, each starting size-overlap
+// words after the previous one. The final window may be shorter.
+func ChunkText(text string, size, overlap int) [][2]int {
+	words := strings.Fields(text)
+	if size <= 0 || overlap < 0 || overlap >= size || len(words) == 0 {
+		return nil
+	}
+
+	stride := size - overlap
+	var spans [][2]int
+	for start := 0; start < len(words); start += stride {
+		end := start + size
+		if end > len(words) {
+			end = len(words)
+		}
+		spans = append(spans, [2]int{start, end})
+		if end == len(words) {
+			break
+		}
+	}
+	return spans
+}
+
+// RetrievalIndex holds embedded chunks of every loaded document, with the
+// vectors kept in a VectorStore. It is immutable once built, so Search is
+// safe for concurrent use.
+type RetrievalIndex struct {
+	loader   *DatasetLoader
+	chunks   []*Chunk
+	docWords [][]string // whitespace-split words of each indexed document
+	config   RetrievalConfig
+	store    VectorStore
+}
+
+// Records sent to a vector store per upsert call
+const vectorUpsertBatch = 256
+
+// Bump when chunking or sentence embeddings change, so stored vectors built
+// the old way are not reused
+const retrievalStoreVersion = 1
+
+// NewRetrievalIndex chunks and embeds all documents in loader, storing the
+// vectors in the store described by config.VectorStore. Qdrant collections
+// and sqlite-vec tables are suffixed with the corpus fingerprint, so indexes
+// of different corpora sharing a server don't overwrite each other.
+func NewRetrievalIndex(loader *DatasetLoader, config RetrievalConfig) (*RetrievalIndex, error) {
+	storeConfig := config.VectorStore
+	storeConfig.Collection = fingerprintCollection(storeConfig.Collection, retrievalFingerprint(loader, config))
+	store, err := NewVectorStore(storeConfig)
+	if err != nil {
+		return nil, err
+	}
+	index, err := NewRetrievalIndexWithStore(loader, config, store)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return index, nil
+}
+
+// NewRetrievalIndexWithStore is NewRetrievalIndex with an explicit store.
+// If the store already holds this corpus's vectors they are loaded from it
+// rather than embedded and uploaded again.
+func NewRetrievalIndexWithStore(loader *DatasetLoader, config RetrievalConfig, store VectorStore) (*RetrievalIndex, error) {
+	if config.ChunkTokens <= 0 {
+		return nil, fmt.Errorf("chunk_tokens must be positive")
+	}
+	if config.ChunkOverlap < 0 || config.ChunkOverlap >= config.ChunkTokens {
+		return nil, fmt.Errorf("chunk_overlap must be between 0 and chunk_tokens-1")
+	}
+
+	index := &RetrievalIndex{loader: loader, config: config, store: store}
+	fp := retrievalFingerprint(loader, config)
+	for _, doc := range loader.GetDocuments() {
+		words := strings.Fields(doc.Content)
+		index.docWords = append(index.docWords, words)
+		for _, span := range ChunkText(doc.Content, config.ChunkTokens, config.ChunkOverlap) {
+			text := strings.Join(words[span[0]:span[1]], " ")
+			index.chunks = append(index.chunks, &Chunk{
+				ID:       len(index.chunks),
+				Path:     doc.Path,
+				Start:    span[0],
+				End:      span[1],
+				Text:     text,
+				Tokens:   loader.tokenize(text),
+				Language: doc.Language,
+				doc:      len(index.docWords) - 1,
+			})
+		}
+	}
+
+	// Chunking is cheap; embedding is what a stored index saves
+	ctx := context.Background()
+	loaded, err := index.loadStore(ctx, fp)
+	if err != nil {
+		return nil, err
+	}
+	if !loaded {
+		for _, chunk := range index.chunks {
+			chunk.Vector, _, _ = loader.SentenceEmbedding(chunk.Text)
+		}
+		if err := index.uploadStore(ctx, fp); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("🔎 Retrieval index: %d chunks from %d documents\n", len(index.chunks), len(loader.GetDocuments()))
+	return index, nil
+}
+
+// retrievalFingerprint identifies the chunks and vectors loader's corpus
+// gives under config without embedding anything: the documents, the chunk
+// windows, and the vocabulary and dimension the embeddings derive from
+func retrievalFingerprint(loader *DatasetLoader, config RetrievalConfig) string {
+	loader.mu.RLock()
+	dim := loader.embeddingDim
+	loader.mu.RUnlock()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\x00%d/%d\x00dim=%d\x00", retrievalStoreVersion, config.ChunkTokens, config.ChunkOverlap, dim)
+	for _, doc := range loader.GetDocuments() {
+		fmt.Fprintf(h, "%s\x00%d\x00%s\x00", doc.Path, len(doc.Content), doc.Content)
+	}
+	if view := loader.vocabView.Load(); view != nil {
+		for _, word := range view.words {
+			fmt.Fprintf(h, "%s\x00", word)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintCollection names the collection or table for the corpus with
+// fingerprint fp
+func fingerprintCollection(collection, fp string) string {
+	if collection == "" {
+		collection = defaultVectorCollection
+	}
+	return collection + "_" + fp[:16]
+}
+
+// loadStore fills in the chunk vectors from the store if it holds the
+// complete set for fingerprint fp, reporting whether it did
+func (idx *RetrievalIndex) loadStore(ctx context.Context, fp string) (bool, error) {
+	records, err := idx.store.All(ctx)
+	if err != nil {
+		return false, fmt.Errorf("vector store load failed: %w", err)
+	}
+	if len(records) == 0 {
+		return false, nil
+	}
+
+	// Every record must be from this corpus, and an interrupted upload
+	// leaves fewer records than each one expects
+	total := strconv.Itoa(len(records))
+	for _, r := range records {
+		if r.Metadata["fingerprint"] != fp || r.Metadata["records"] != total ||
+			r.ID < 0 || r.ID >= len(idx.chunks) || r.Metadata["path"] != idx.chunks[r.ID].Path {
+			return false, nil
+		}
+	}
+	for _, r := range records {
+		idx.chunks[r.ID].Vector = r.Vector
+	}
+	fmt.Printf("♻️  Reusing %d stored chunk vectors\n", len(records))
+	return true, nil
+}
+
+// uploadStore replaces the store's contents with the chunk vectors
+func (idx *RetrievalIndex) uploadStore(ctx context.Context, fp string) error {
+	var embedded []*Chunk
+	for _, chunk := range idx.chunks {
+		if chunk.Vector != nil {
+			embedded = append(embedded, chunk)
+		}
+	}
+	if len(embedded) == 0 {
+		return nil
+	}
+
+	records := make([]VectorRecord, len(embedded))
+	for i, chunk := range embedded {
+		records[i] = VectorRecord{
+			ID:     chunk.ID,
+			Vector: chunk.Vector,
+			Metadata: map[string]string{
+				"path":        chunk.Path,
+				"start":       strconv.Itoa(chunk.Start),
+				"end":         strconv.Itoa(chunk.End),
+				"fingerprint": fp,
+				"records":     strconv.Itoa(len(records)),
+			},
+		}
+	}
+
+	if err := idx.store.Reset(ctx); err != nil {
+		return fmt.Errorf("vector store reset failed: %w", err)
+	}
+	for start := 0; start < len(records); start += vectorUpsertBatch {
+		end := min(start+vectorUpsertBatch, len(records))
+		if err := idx.store.Upsert(ctx, records[start:end]); err != nil {
+			return fmt.Errorf("vector store upsert failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close releases the vector store, saving it if it is file-backed
+func (idx *RetrievalIndex) Close() error {
+	return idx.store.Close()
+}
+
+// Len returns the number of indexed chunks
+func (idx *RetrievalIndex) Len() int {
+	return len(idx.chunks)
+}
+
+// Chunks returns the indexed chunks. The slice is shared; don't modify it.
+func (idx *RetrievalIndex) Chunks() []*Chunk {
+	return idx.chunks
+}
+
+// Search returns the k chunks most similar to query, best first. Chunks with
+// no similarity to the query are left out.
+func (idx *RetrievalIndex) Search(query string, k int) []ScoredChunk {
+	if k <= 0 {
+		return nil
+	}
+	qvec, _, ok := idx.loader.SentenceEmbedding(query)
+	if !ok {
+		return nil
+	}
+
+	hits, err := idx.store.Search(context.Background(), qvec, k)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: retrieval search failed: %v\n", err)
+		return nil
+	}
+
+	var results []ScoredChunk
+	for _, hit := range hits {
+		id := hit.Record.ID
+		if hit.Score <= 0 || id < 0 || id >= len(idx.chunks) {
+			continue
+		}
+		results = append(results, ScoredChunk{Chunk: idx.chunks[id], Score: hit.Score})
+	}
+	return results
+}
+
+func dotProduct(a, b []float64) float64 {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
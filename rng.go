@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Deterministic mode: random choices come from the global math/rand, so two
+// runs never build the same reservoir or give the same responses, which
+// makes failures hard to reproduce. With a nonzero seed in the config, each
+// component draws from its own generator seeded from it instead: liquid
+// brains wire their reservoirs (thresholds, refractory periods, initial
+// states, connections, inhibition and I/O wiring), the parallel
+// orchestrator wires its neurons and picks where inputs enter, gate
+// evolution builds and mutates its circuits, and response generators pick
+// the seeds of calls that don't set one. The same seed and inputs then build the same brains
+// and give the same responses. A seed of 0 keeps the global math/rand.
+// Word embeddings don't depend on the seed: each word's is drawn from a
+// generator seeded by the word itself, so embeddings, and the vector stores
+// built from them, are the same on every run.
+// Neuron dynamics run concurrently and aren't covered: spike timing depends
+// on the scheduler either way.
+
+// lockedSource is a seeded source safe for concurrent use
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// globalSource draws from the global math/rand
+type globalSource struct{}
+
+func (globalSource) Int63() int64    { return rand.Int63() }
+func (globalSource) Uint64() uint64  { return rand.Uint64() }
+func (globalSource) Seed(seed int64) { rand.Seed(seed) }
+
+// globalRand is the generator of unseeded components
+var globalRand = rand.New(globalSource{})
+
+// newRand returns a generator seeded with seed, safe for concurrent use;
+// seed 0 returns the global one
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		return globalRand
+	}
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
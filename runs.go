@@ -0,0 +1,560 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run tracking: training, evolution and benchmark runs used to leave
+// nothing behind but console output. Each run now gets a directory,
+// runs/<id>, holding run.json (the kind of run, its parameters, the config
+// it ran with, a hash of every dataset it read, its metrics with their
+// history, and its status) and an artifacts/ directory for checkpoints and
+// reports. `genesis runs list|show|compare` reads them back, so runs can be
+// compared long after the terminal has scrolled away. A nil run tracks
+// nothing, so tracking can be turned off by emptying runs.dir.
+
+// RunTrackingConfig controls run tracking
+type RunTrackingConfig struct {
+	Dir string `json:"dir"` // where runs are kept; "" turns tracking off
+}
+
+// Run statuses
+const (
+	RunRunning  = "running"
+	RunFinished = "finished"
+	RunFailed   = "failed"
+)
+
+const runFile = "run.json"
+
+// DatasetHash identifies the contents of a dataset a run read
+type DatasetHash struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"` // of the file, or of a directory's file names and contents
+	Bytes  int64  `json:"bytes"`
+	Files  int    `json:"files"`
+	Error  string `json:"error,omitempty"` // set when the dataset couldn't be read
+}
+
+// MetricPoint is a metric's value at one step of a run
+type MetricPoint struct {
+	Step  int     `json:"step"`
+	Value float64 `json:"value"`
+}
+
+// TrackedRun is one recorded run. A nil run records nothing.
+type TrackedRun struct {
+	ID        string                   `json:"id"`
+	Kind      string                   `json:"kind"` // "train", "evolution", "bench-reservoir", ...
+	Status    string                   `json:"status"`
+	Error     string                   `json:"error,omitempty"`
+	Started   time.Time                `json:"started"`
+	Finished  *time.Time               `json:"finished,omitempty"`
+	Params    map[string]string        `json:"params,omitempty"`
+	Config    json.RawMessage          `json:"config,omitempty"`
+	Datasets  []DatasetHash            `json:"datasets,omitempty"`
+	Metrics   map[string]float64       `json:"metrics,omitempty"` // latest value of each metric
+	History   map[string][]MetricPoint `json:"history,omitempty"` // metrics logged by step
+	Artifacts []string                 `json:"artifacts,omitempty"`
+
+	mu  sync.Mutex
+	dir string
+}
+
+// StartRun creates a run directory under tracking.Dir and records config
+// and the datasets it names; nil when tracking is off
+func StartRun(tracking RunTrackingConfig, kind string, config *Config, params map[string]string) (*TrackedRun, error) {
+	if tracking.Dir == "" {
+		return nil, nil
+	}
+	id, err := newRunID(kind)
+	if err != nil {
+		return nil, err
+	}
+	run := &TrackedRun{
+		ID:      id,
+		Kind:    kind,
+		Status:  RunRunning,
+		Started: time.Now(),
+		Params:  make(map[string]string),
+		Metrics: make(map[string]float64),
+		History: make(map[string][]MetricPoint),
+		dir:     filepath.Join(tracking.Dir, id),
+	}
+	for k, v := range params {
+		run.Params[k] = v
+	}
+	if config != nil {
+		if run.Config, err = json.Marshal(config); err != nil {
+			return nil, fmt.Errorf("failed to record config: %w", err)
+		}
+		for _, path := range config.Training.DatasetPaths {
+			run.Datasets = append(run.Datasets, hashDataset(path))
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(run.dir, "artifacts"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+	if err := run.save(); err != nil {
+		return nil, err
+	}
+	fmt.Printf("📒 Tracking run %s in %s\n", run.ID, run.dir)
+	return run, nil
+}
+
+// TrackRun starts a run with config's tracking settings, warning and
+// tracking nothing when it can't
+func TrackRun(config *Config, kind string, params map[string]string) *TrackedRun {
+	run, err := StartRun(config.Runs, kind, config, params)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: run won't be tracked: %v\n", err)
+		return nil
+	}
+	return run
+}
+
+// newRunID names a run by when it started and its kind, so IDs sort by time
+func newRunID(kind string) (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate run id: %w", err)
+	}
+	return fmt.Sprintf("%s-%s-%s", time.Now().UTC().Format("20060102-150405"), kind, hex.EncodeToString(buf)), nil
+}
+
+// hashDataset hashes a dataset file, or every file under a dataset
+// directory in name order
+func hashDataset(path string) DatasetHash {
+	d := DatasetHash{Path: path}
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if file != path {
+			rel, _ := filepath.Rel(path, file)
+			fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		}
+		n, err := io.Copy(h, f)
+		d.Bytes += n
+		d.Files++
+		return err
+	})
+	if err != nil {
+		return DatasetHash{Path: path, Error: err.Error()}
+	}
+	d.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return d
+}
+
+// Dir returns the run's directory; "" for a nil run
+func (r *TrackedRun) Dir() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// SetParam records a parameter of the run
+func (r *TrackedRun) SetParam(name, value string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Params[name] = value
+}
+
+// LogMetric records a metric's final value
+func (r *TrackedRun) LogMetric(name string, value float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Metrics[name] = value
+}
+
+// LogMetricStep records a metric's value at step, and keeps it as the
+// metric's latest value
+func (r *TrackedRun) LogMetricStep(name string, step int, value float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Metrics[name] = value
+	r.History[name] = append(r.History[name], MetricPoint{Step: step, Value: value})
+}
+
+// SaveArtifact has write create the artifact name in the run's artifacts
+// directory and records it
+func (r *TrackedRun) SaveArtifact(name string, write func(path string) error) error {
+	if r == nil {
+		return nil
+	}
+	if err := write(filepath.Join(r.dir, "artifacts", name)); err != nil {
+		return fmt.Errorf("failed to save artifact %s: %w", name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, artifact := range r.Artifacts {
+		if artifact == name {
+			return nil
+		}
+	}
+	r.Artifacts = append(r.Artifacts, name)
+	return nil
+}
+
+// SaveJSONArtifact saves v as the indented JSON artifact name
+func (r *TrackedRun) SaveJSONArtifact(name string, v any) error {
+	return r.SaveArtifact(name, func(path string) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, append(data, '\n'), 0644)
+	})
+}
+
+// Finish marks the run finished, or failed with err, and writes it
+func (r *TrackedRun) Finish(err error) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	now := time.Now()
+	r.Finished = &now
+	r.Status = RunFinished
+	if err != nil {
+		r.Status, r.Error = RunFailed, err.Error()
+	}
+	r.mu.Unlock()
+
+	fmt.Printf("📒 Run %s %s\n", r.ID, r.Status)
+	return r.save()
+}
+
+// save writes run.json
+func (r *TrackedRun) save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode run %s: %w", r.ID, err)
+	}
+	tmp := filepath.Join(r.dir, runFile+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write run %s: %w", r.ID, err)
+	}
+	return os.Rename(tmp, filepath.Join(r.dir, runFile))
+}
+
+// ListRuns reads every run in dir, oldest first
+func ListRuns(dir string) ([]*TrackedRun, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	var runs []*TrackedRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := readRun(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			fmt.Printf("⚠️  Warning: skipping %s: %v\n", entry.Name(), err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+	return runs, nil
+}
+
+// LoadRun reads the run in dir whose ID is or starts with id
+func LoadRun(dir, id string) (*TrackedRun, error) {
+	if run, err := readRun(filepath.Join(dir, id)); err == nil {
+		return run, nil
+	}
+	runs, err := ListRuns(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found *TrackedRun
+	for _, run := range runs {
+		if strings.HasPrefix(run.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("run id %q is ambiguous", id)
+			}
+			found = run
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no run %q in %s", id, dir)
+	}
+	return found, nil
+}
+
+func readRun(dir string) (*TrackedRun, error) {
+	data, err := os.ReadFile(filepath.Join(dir, runFile))
+	if err != nil {
+		return nil, err
+	}
+	run := &TrackedRun{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", runFile, err)
+	}
+	run.dir = dir
+	return run, nil
+}
+
+// duration is how long the run took, or has been running
+func (r *TrackedRun) duration() time.Duration {
+	if r.Finished == nil {
+		return time.Since(r.Started).Round(time.Second)
+	}
+	return r.Finished.Sub(r.Started).Round(time.Millisecond)
+}
+
+// PrintRuns writes one line per run
+func PrintRuns(w io.Writer, runs []*TrackedRun) {
+	if len(runs) == 0 {
+		fmt.Fprintln(w, "No runs recorded")
+		return
+	}
+	fmt.Fprintf(w, "%-40s %-18s %-9s %-20s %s\n", "ID", "KIND", "STATUS", "STARTED", "METRICS")
+	for _, run := range runs {
+		var metrics []string
+		for _, name := range sortedMeanings(run.Metrics) {
+			metrics = append(metrics, fmt.Sprintf("%s=%.4g", name, run.Metrics[name]))
+		}
+		fmt.Fprintf(w, "%-40s %-18s %-9s %-20s %s\n", run.ID, run.Kind, run.Status,
+			run.Started.Format("2006-01-02 15:04:05"), strings.Join(metrics, " "))
+	}
+}
+
+// Print writes the run's details
+func (r *TrackedRun) Print(w io.Writer) {
+	fmt.Fprintf(w, "Run %s (%s)\n", r.ID, r.Kind)
+	fmt.Fprintf(w, "  Status:   %s", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(w, ": %s", r.Error)
+	}
+	fmt.Fprintf(w, "\n  Started:  %s (%v)\n", r.Started.Format(time.RFC3339), r.duration())
+	if len(r.Params) > 0 {
+		fmt.Fprintln(w, "  Params:")
+		for _, name := range sortedParams(r.Params) {
+			fmt.Fprintf(w, "    %-24s %s\n", name, r.Params[name])
+		}
+	}
+	if len(r.Datasets) > 0 {
+		fmt.Fprintln(w, "  Datasets:")
+		for _, d := range r.Datasets {
+			if d.Error != "" {
+				fmt.Fprintf(w, "    %s: %s\n", d.Path, d.Error)
+				continue
+			}
+			fmt.Fprintf(w, "    %s: sha256 %s, %d files, %d bytes\n", d.Path, d.SHA256[:16], d.Files, d.Bytes)
+		}
+	}
+	if len(r.Metrics) > 0 {
+		fmt.Fprintln(w, "  Metrics:")
+		for _, name := range sortedMeanings(r.Metrics) {
+			fmt.Fprintf(w, "    %-24s %.6g", name, r.Metrics[name])
+			if points := r.History[name]; len(points) > 1 {
+				fmt.Fprintf(w, " (%d steps, first %.6g)", len(points), points[0].Value)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(r.Artifacts) > 0 {
+		fmt.Fprintln(w, "  Artifacts:")
+		for _, name := range r.Artifacts {
+			fmt.Fprintf(w, "    %s\n", filepath.Join(r.dir, "artifacts", name))
+		}
+	}
+}
+
+// CompareRuns writes the runs' params, dataset hashes and metrics side by
+// side, marking rows where the runs differ
+func CompareRuns(w io.Writer, runs []*TrackedRun) {
+	row := func(name string, values []string) {
+		mark := " "
+		for _, v := range values[1:] {
+			if v != values[0] {
+				mark = "*"
+			}
+		}
+		fmt.Fprintf(w, "%s %-28s", mark, name)
+		for _, v := range values {
+			fmt.Fprintf(w, " %-20s", v)
+		}
+		fmt.Fprintln(w)
+	}
+	ids := make([]string, len(runs))
+	for i, run := range runs {
+		ids[i] = run.ID
+		if len(ids[i]) > 20 {
+			ids[i] = ids[i][:20]
+		}
+	}
+	row("run", ids)
+
+	section := func(title string, names []string, value func(run *TrackedRun, name string) string) {
+		if len(names) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s:\n", title)
+		for _, name := range names {
+			values := make([]string, len(runs))
+			for i, run := range runs {
+				values[i] = value(run, name)
+			}
+			row(name, values)
+		}
+	}
+	params, metrics, datasets := make(map[string]string), make(map[string]float64), make(map[string]string)
+	for _, run := range runs {
+		for name := range run.Params {
+			params[name] = ""
+		}
+		for name := range run.Metrics {
+			metrics[name] = 0
+		}
+		for _, d := range run.Datasets {
+			datasets[d.Path] = ""
+		}
+	}
+	row("kind", mapRuns(runs, func(run *TrackedRun) string { return run.Kind }))
+	row("status", mapRuns(runs, func(run *TrackedRun) string { return run.Status }))
+	row("duration", mapRuns(runs, func(run *TrackedRun) string { return run.duration().String() }))
+	section("Params", sortedParams(params), func(run *TrackedRun, name string) string {
+		if v, ok := run.Params[name]; ok {
+			return v
+		}
+		return "-"
+	})
+	section("Datasets", sortedParams(datasets), func(run *TrackedRun, path string) string {
+		for _, d := range run.Datasets {
+			if d.Path == path && d.Error == "" {
+				return d.SHA256[:12]
+			}
+		}
+		return "-"
+	})
+	section("Metrics", sortedMeanings(metrics), func(run *TrackedRun, name string) string {
+		if v, ok := run.Metrics[name]; ok {
+			return fmt.Sprintf("%.6g", v)
+		}
+		return "-"
+	})
+}
+
+func mapRuns(runs []*TrackedRun, f func(run *TrackedRun) string) []string {
+	values := make([]string, len(runs))
+	for i, run := range runs {
+		values[i] = f(run)
+	}
+	return values
+}
+
+// sortedParams returns the map's keys in order
+func sortedParams(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RunsMain implements `go run . runs list|show|compare`
+func RunsMain(args []string) {
+	usage := "usage: genesis runs list | show <id> | compare <id> <id>... [-dir runs]"
+	if len(args) == 0 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("runs "+args[0], flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	dir := fs.String("dir", "", "Runs directory; empty uses the config's runs.dir")
+	asJSON := fs.Bool("json", false, "Print runs as JSON")
+	fs.Parse(args[1:])
+
+	if *dir == "" {
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		if *dir = config.Runs.Dir; *dir == "" {
+			fmt.Println("❌ ERROR: run tracking is off (runs.dir is empty)")
+			os.Exit(1)
+		}
+	}
+
+	var runs []*TrackedRun
+	var err error
+	switch {
+	case args[0] == "list" && fs.NArg() == 0:
+		runs, err = ListRuns(*dir)
+	case args[0] == "show" && fs.NArg() == 1, args[0] == "compare" && fs.NArg() >= 2:
+		for _, id := range fs.Args() {
+			var run *TrackedRun
+			if run, err = LoadRun(*dir, id); err != nil {
+				break
+			}
+			runs = append(runs, run)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(runs)
+		return
+	}
+	switch args[0] {
+	case "list":
+		PrintRuns(os.Stdout, runs)
+	case "show":
+		runs[0].Print(os.Stdout)
+	case "compare":
+		CompareRuns(os.Stdout, runs)
+	}
+}
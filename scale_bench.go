@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scaling benchmark: `genesis bench scale` builds one model type at each of
+// a list of sizes and measures what scale actually changes: how long the
+// model takes to create, how much heap and how many goroutines it holds,
+// how long it takes to answer a fixed prompt set, and how much its
+// responses vary, both across seeds and when a seed is repeated (which
+// should reproduce the response). The report records the environment it
+// ran in and renders as markdown or JSON, so runs can be tracked over time
+// instead of taking a demo's word for what scale does.
+
+// ScaleBenchConfig controls a scaling benchmark
+type ScaleBenchConfig struct {
+	Model   string   `json:"model"` // model type; sizes mean reservoir side or orchestrator neurons
+	Sizes   []int    `json:"sizes"`
+	Prompts []string `json:"prompts"`
+	Repeats int      `json:"repeats"` // responses per prompt, each with its own seed
+	Seed    int64    `json:"seed"`
+}
+
+// DefaultScaleBench is the benchmark `genesis bench scale` runs by default:
+// the parallel orchestrator at the sizes the scaling demo used to show
+var DefaultScaleBench = ScaleBenchConfig{
+	Model: "parallel",
+	Sizes: []int{10, 100, 1000, 10000},
+	Prompts: []string{
+		"Understand the nature of consciousness",
+		"hello",
+		"why is the sky blue",
+		"calculate the square root of 144",
+	},
+	Repeats: 3,
+	Seed:    1,
+}
+
+// ScaleResult is one size's measurements
+type ScaleResult struct {
+	Size          int     `json:"size"`
+	CreateMS      float64 `json:"create_ms"`
+	HeapBytes     int64   `json:"heap_bytes"` // heap the model holds after creation
+	Goroutines    int     `json:"goroutines"` // goroutines the model keeps running
+	LatencyMeanMS float64 `json:"latency_mean_ms"`
+	LatencyP50MS  float64 `json:"latency_p50_ms"`
+	LatencyP95MS  float64 `json:"latency_p95_ms"`
+	Distinct      float64 `json:"distinct"`    // share of a prompt's responses that differ, averaged over prompts
+	Variability   float64 `json:"variability"` // mean word-level dissimilarity of a prompt's responses across seeds
+	SeedDrift     float64 `json:"seed_drift"`  // dissimilarity of two responses with the same seed; 0 is reproducible
+	Errors        int     `json:"errors"`
+	Error         string  `json:"error,omitempty"` // set when the model couldn't be created
+}
+
+// ScaleEnvironment is where a benchmark ran
+type ScaleEnvironment struct {
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+}
+
+// ScaleReport is a scaling benchmark run
+type ScaleReport struct {
+	Config      ScaleBenchConfig `json:"config"`
+	Environment ScaleEnvironment `json:"environment"`
+	Started     time.Time        `json:"started"`
+	Results     []ScaleResult    `json:"results"`
+}
+
+func (c ScaleBenchConfig) validate() error {
+	if len(c.Sizes) == 0 {
+		return fmt.Errorf("scale benchmark needs at least one size")
+	}
+	for _, size := range c.Sizes {
+		if size <= 0 {
+			return fmt.Errorf("scale benchmark sizes must be positive, got %d", size)
+		}
+	}
+	if len(c.Prompts) == 0 {
+		return fmt.Errorf("scale benchmark needs at least one prompt")
+	}
+	if c.Repeats < 1 {
+		return fmt.Errorf("scale benchmark repeats must be at least 1")
+	}
+	return nil
+}
+
+// RunScaleBench measures the model type at each size. Models are built
+// without response caching, a decision store or distillation, so repeats
+// are really answered and nothing is persisted.
+func RunScaleBench(config *Config, bench ScaleBenchConfig) (*ScaleReport, error) {
+	if err := bench.validate(); err != nil {
+		return nil, err
+	}
+	if _, _, err := lookupModelType(ModelConfig{Type: bench.Model}); err != nil {
+		return nil, err
+	}
+	modelConfig := *config
+	modelConfig.Model = ModelConfig{Type: bench.Model}
+	modelConfig.ResponseCache.Enabled = false
+	modelConfig.DecisionStore.Path = ""
+	modelConfig.Distill.Enabled = false
+
+	report := &ScaleReport{
+		Config: bench,
+		Environment: ScaleEnvironment{
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			CPUs:      runtime.NumCPU(),
+		},
+		Started: time.Now(),
+	}
+	for _, size := range bench.Sizes {
+		fmt.Printf("📈 Scale benchmark: %s at size %d\n", bench.Model, size)
+		report.Results = append(report.Results, measureScale(&modelConfig, bench, size))
+	}
+	return report, nil
+}
+
+// measureScale creates the model at size and measures it
+func measureScale(config *Config, bench ScaleBenchConfig, size int) ScaleResult {
+	result := ScaleResult{Size: size}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	goroutines := runtime.NumGoroutine()
+	start := time.Now()
+	model, err := NewModel(config, size)
+	result.CreateMS = durationMs(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer model.Close()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	result.HeapBytes = int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	result.Goroutines = runtime.NumGoroutine() - goroutines
+
+	var latencies []time.Duration
+	respond := func(prompt string, seed int64) string {
+		start := time.Now()
+		response, err := model.Respond(context.Background(), Request{Input: prompt, Seed: &seed})
+		latencies = append(latencies, time.Since(start))
+		if err != nil {
+			result.Errors++
+		}
+		return response.Output
+	}
+	seed := bench.Seed
+	for _, prompt := range bench.Prompts {
+		responses := make([]string, bench.Repeats)
+		for i := range responses {
+			responses[i] = respond(prompt, seed+int64(i))
+		}
+		// The first seed again, which should reproduce its response
+		again := respond(prompt, seed)
+		seed += int64(bench.Repeats)
+
+		result.Distinct += distinctShare(responses)
+		result.Variability += meanDissimilarity(responses)
+		result.SeedDrift += 1 - responseSimilarity(responses[0], again)
+	}
+	n := float64(len(bench.Prompts))
+	result.Distinct /= n
+	result.Variability /= n
+	result.SeedDrift /= n
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	result.LatencyMeanMS = durationMs(total) / float64(len(latencies))
+	result.LatencyP50MS = durationMs(percentile(latencies, 0.5))
+	result.LatencyP95MS = durationMs(percentile(latencies, 0.95))
+	return result
+}
+
+// responseSimilarity is the word overlap of two responses; two empty
+// responses are the same
+func responseSimilarity(a, b string) float64 {
+	if strings.TrimSpace(a) == "" && strings.TrimSpace(b) == "" {
+		return 1
+	}
+	return unigramF1(a, b)
+}
+
+// distinctShare is the share of responses after the first that are new;
+// 0 when they're all the same, 1 when they all differ
+func distinctShare(responses []string) float64 {
+	if len(responses) < 2 {
+		return 0
+	}
+	seen := make(map[string]bool, len(responses))
+	for _, r := range responses {
+		seen[r] = true
+	}
+	return float64(len(seen)-1) / float64(len(responses)-1)
+}
+
+// meanDissimilarity averages 1 - responseSimilarity over every pair
+func meanDissimilarity(responses []string) float64 {
+	total, pairs := 0.0, 0
+	for i := range responses {
+		for j := i + 1; j < len(responses); j++ {
+			total += 1 - responseSimilarity(responses[i], responses[j])
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return total / float64(pairs)
+}
+
+// Markdown writes the report as a markdown document
+func (r *ScaleReport) Markdown(w io.Writer) {
+	fmt.Fprintf(w, "# Scaling report: %s\n\n", r.Config.Model)
+	fmt.Fprintf(w, "Run %s on %s %s/%s with %d CPUs; %d prompts, %d seeds each, starting at seed %d.\n\n",
+		r.Started.Format(time.RFC3339), r.Environment.GoVersion, r.Environment.OS, r.Environment.Arch,
+		r.Environment.CPUs, len(r.Config.Prompts), r.Config.Repeats, r.Config.Seed)
+	fmt.Fprintln(w, "| Size | Create (ms) | Heap (KB) | Goroutines | Mean (ms) | p50 (ms) | p95 (ms) | Distinct | Variability | Seed drift | Errors |")
+	fmt.Fprintln(w, "|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|")
+	for _, s := range r.Results {
+		if s.Error != "" {
+			fmt.Fprintf(w, "| %d | failed: %s |||||||||\n", s.Size, s.Error)
+			continue
+		}
+		fmt.Fprintf(w, "| %d | %.1f | %d | %d | %.2f | %.2f | %.2f | %.2f | %.3f | %.3f | %d |\n",
+			s.Size, s.CreateMS, s.HeapBytes/1024, s.Goroutines, s.LatencyMeanMS, s.LatencyP50MS, s.LatencyP95MS,
+			s.Distinct, s.Variability, s.SeedDrift, s.Errors)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Distinct is the share of a prompt's responses across seeds that differ; variability is their mean word-level")
+	fmt.Fprintln(w, "dissimilarity; seed drift is the dissimilarity between two responses with the same seed (0 is reproducible).")
+}
+
+// record logs each size's measurements, stepped by size, and saves the
+// report in run, finishing the run
+func (r *ScaleReport) record(run *TrackedRun) {
+	for _, s := range r.Results {
+		if s.Error != "" {
+			continue
+		}
+		run.LogMetricStep("create_ms", s.Size, s.CreateMS)
+		run.LogMetricStep("heap_bytes", s.Size, float64(s.HeapBytes))
+		run.LogMetricStep("latency_mean_ms", s.Size, s.LatencyMeanMS)
+		run.LogMetricStep("latency_p95_ms", s.Size, s.LatencyP95MS)
+		run.LogMetricStep("variability", s.Size, s.Variability)
+		run.LogMetricStep("seed_drift", s.Size, s.SeedDrift)
+	}
+	if err := run.SaveJSONArtifact("report.json", r); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	err := run.SaveArtifact("report.md", func(path string) error {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		r.Markdown(file)
+		return file.Close()
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	run.Finish(nil)
+}
+
+// parseSizes parses a comma-separated list of sizes
+func parseSizes(list string) ([]int, error) {
+	var sizes []int
+	for _, field := range strings.Split(list, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("bad size %q: %w", field, err)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// ScaleBenchMain implements `go run . bench scale`
+func ScaleBenchMain(args []string) {
+	defaults := DefaultScaleBench
+	fs := flag.NewFlagSet("bench scale", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	model := fs.String("model", defaults.Model, "Model type to scale")
+	sizeList := fs.String("sizes", "10,100,1000,10000", "Comma-separated sizes")
+	promptsPath := fs.String("prompts", "", "File with one prompt per line; empty uses the built-in set")
+	repeats := fs.Int("repeats", defaults.Repeats, "Responses per prompt, each with its own seed")
+	seed := fs.Int64("seed", defaults.Seed, "Seed of the first response")
+	out := fs.String("out", "", "Also write the JSON report here")
+	asJSON := fs.Bool("json", false, "Print the report as JSON instead of markdown")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	bench := ScaleBenchConfig{Model: *model, Prompts: defaults.Prompts, Repeats: *repeats, Seed: *seed}
+	if bench.Sizes, err = parseSizes(*sizeList); err == nil && *promptsPath != "" {
+		bench.Prompts, err = readPrompts(*promptsPath)
+	}
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	run := TrackRun(config, "bench-scale", map[string]string{
+		"model":   bench.Model,
+		"sizes":   *sizeList,
+		"prompts": fmt.Sprint(len(bench.Prompts)),
+		"repeats": fmt.Sprint(bench.Repeats),
+		"seed":    fmt.Sprint(bench.Seed),
+	})
+	report, err := RunScaleBench(config, bench)
+	if err != nil {
+		run.Finish(err)
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	report.record(run)
+	data, _ := json.MarshalIndent(report, "", "  ")
+	if *out != "" {
+		if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			fmt.Printf("❌ ERROR: failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *asJSON {
+		fmt.Println(string(data))
+		return
+	}
+	report.Markdown(os.Stdout)
+}
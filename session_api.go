@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Session HTTP API:
+//
+//	POST   /v1/sessions                 create a session, optionally {"policy": "..."}
+//	GET    /v1/sessions/{id}            fetch history and feedback
+//	DELETE /v1/sessions/{id}            end a session
+//	POST   /v1/sessions/{id}/messages   {"content": "...", "max_tokens": n, "stop": [...]} -> assistant reply
+//	POST   /v1/sessions/{id}/feedback   {"message_index": n, "rating": 1}
+
+// How often server mode sweeps for idle sessions
+const sessionExpiryInterval = time.Minute
+
+// Sessions are created under the capability policy of the caller's API key
+// (by the key's name in the server config), or the default policy without
+// one. A caller may name its own policy only when it isn't already
+// restricted.
+
+// CreateSessionRequest is the optional body of POST /v1/sessions
+type CreateSessionRequest struct {
+	Policy string `json:"policy"`
+}
+
+// MessageRequest is the body of POST /v1/sessions/{id}/messages
+type MessageRequest struct {
+	Content string `json:"content"`
+	GenerationOptions
+}
+
+func (req MessageRequest) validate() error {
+	if strings.TrimSpace(req.Content) == "" {
+		return fmt.Errorf("content must not be empty")
+	}
+	return req.GenerationOptions.validate()
+}
+
+// MessageResponse carries the assistant reply and why it was given
+type MessageResponse struct {
+	SessionID   string       `json:"session_id"`
+	Message     ChatMessage  `json:"message"`
+	Index       int          `json:"message_index"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// SessionHandler serves the session API on top of a SessionManager
+type SessionHandler struct {
+	sessions  *SessionManager
+	generator *ResponseGenerator
+	recorder  *Recorder        // nil unless recording
+	audit     *AuditLog        // nil unless auditing
+	coherence *CoherenceScorer // nil unless reranking by coherence
+	responses *ResponseCache   // nil unless caching responses
+	policies  CapabilityPolicyConfig
+	listeners []func(sessionID string, fb Feedback)
+	knowledge *KnowledgeStore // nil unless learning facts
+	scope     KnowledgeConfig // where learned facts are kept
+}
+
+func NewSessionHandler(sessions *SessionManager, generator *ResponseGenerator) *SessionHandler {
+	return &SessionHandler{sessions: sessions, generator: generator}
+}
+
+// SetRecorder records every message exchange for later replay
+func (h *SessionHandler) SetRecorder(recorder *Recorder) {
+	h.recorder = recorder
+}
+
+// OnFeedback calls listener with each piece of feedback accepted, e.g. to
+// feed a reservoir's reward channel
+func (h *SessionHandler) OnFeedback(listener func(sessionID string, fb Feedback)) {
+	h.listeners = append(h.listeners, listener)
+}
+
+// SetResponseCache answers repeated messages in the same session context
+// from cache
+func (h *SessionHandler) SetResponseCache(cache *ResponseCache) {
+	h.responses = cache
+}
+
+// SetAuditLog appends every answered message to a hash-chained audit log
+func (h *SessionHandler) SetAuditLog(audit *AuditLog) {
+	h.audit = audit
+}
+
+// SetKnowledgeStore learns facts from every message into store and
+// answers questions about them, keeping facts per session or globally as
+// config.Scope says
+func (h *SessionHandler) SetKnowledgeStore(store *KnowledgeStore, config KnowledgeConfig) {
+	h.knowledge = store
+	h.scope = config
+}
+
+// SetCapabilityPolicies sets the policies new sessions are created under
+func (h *SessionHandler) SetCapabilityPolicies(policies CapabilityPolicyConfig) {
+	h.policies = policies
+}
+
+func (h *SessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sessions"), "/")
+	parts := strings.Split(rest, "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		h.create(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		h.get(w, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := h.sessions.Delete(parts[0]); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if err := h.knowledge.Forget(h.scope.session(parts[0])); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "messages" && r.Method == http.MethodPost:
+		h.message(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "feedback" && r.Method == http.MethodPost:
+		h.feedback(w, r, parts[0])
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found_error", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *SessionHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil && err != io.EOF {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	policy := h.policies.ForAPIKey(APIKeyName(r))
+	if req.Policy != "" && req.Policy != policy {
+		if policy != "" {
+			writeAPIError(w, http.StatusForbidden, "permission_error", fmt.Sprintf("caller is restricted to capability policy %q", policy))
+			return
+		}
+		if _, err := h.policies.Policy(req.Policy); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("%v (want one of %v)", err, h.policies.Names()))
+			return
+		}
+		policy = req.Policy
+	}
+
+	session, err := h.sessions.CreateWithPolicy(policy)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (h *SessionHandler) get(w http.ResponseWriter, id string) {
+	session, err := h.sessions.Get(id)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (h *SessionHandler) message(w http.ResponseWriter, r *http.Request, id string) {
+	var req MessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	resp, err := h.answer(id, req)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// answer adds a validated message and its reply to session id. Replays
+// answer recorded messages through it too.
+func (h *SessionHandler) answer(id string, req MessageRequest) (MessageResponse, error) {
+	var resp MessageResponse
+	_, err := h.sessions.Update(id, func(s *Session) error {
+		// With a knowledge store, "it" and "this project" are resolved to
+		// what they refer to before learning, recalling and generating
+		content := req.Content
+		var links []EntityLink
+		if h.knowledge != nil {
+			content, links = s.Entities.Resolve(req.Content)
+		}
+		key := responseCacheKey(content, req.GenerationOptions, s)
+		now := time.Now().UTC()
+		s.History = append(s.History, ChatMessage{Role: "user", Content: req.Content, Time: now})
+
+		// Replies from learned facts change as facts are learned, so they
+		// aren't cached
+		session := h.scope.session(s.ID)
+		if _, err := h.knowledge.Learn(session, content); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+		if err := h.knowledge.Link(session, links); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+		recalled, err := h.knowledge.Recall(session, content)
+		if err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+		options := req.GenerationOptions
+		options.session = session
+
+		var reply string
+		var explanation *Explanation
+		cached, hit := h.responses.Get(key)
+		hit = hit && len(recalled) == 0
+		if hit {
+			reply, explanation = cached.Output, cached.Explanation
+			s.Generator = *cached.State
+		} else {
+			if h.coherence != nil {
+				reply, explanation = h.coherentReply(&s.Generator, content, s.History, options)
+			} else {
+				reply, explanation = h.generator.GenerateWithStateOptions(&s.Generator, content, nil, options)
+			}
+			if explanation == nil || len(explanation.Facts) == 0 {
+				h.responses.Put(key, CachedResponse{Output: reply, Explanation: explanation, State: &s.Generator})
+			}
+		}
+		if explanation != nil && len(links) > 0 {
+			linked := *explanation // the cached explanation is shared
+			linked.Entities = links
+			explanation = &linked
+		}
+		msg := ChatMessage{Role: "assistant", Content: reply, Time: time.Now().UTC()}
+		s.History = append(s.History, msg)
+
+		resp = MessageResponse{SessionID: s.ID, Message: msg, Index: len(s.History) - 1, Explanation: explanation}
+		if h.recorder != nil {
+			recorded := ReplayRequest{MessageRequest: req, Cached: hit, Recalled: len(recalled)}
+			if content != req.Content {
+				recorded.Resolved = content
+			}
+			if err := h.recorder.Record(s.ID, recorded, reply, explanation); err != nil {
+				fmt.Printf("⚠️  Warning: %v\n", err)
+			}
+		}
+		if h.audit != nil {
+			if err := h.audit.Append(s.ID, req.Content, reply, explanation); err != nil {
+				fmt.Printf("⚠️  Warning: %v\n", err)
+			}
+		}
+		return nil
+	})
+	return resp, err
+}
+
+func (h *SessionHandler) feedback(w http.ResponseWriter, r *http.Request, id string) {
+	var fb Feedback
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&fb); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	var invalid error
+	session, err := h.sessions.Update(id, func(s *Session) error {
+		if fb.MessageIndex < 0 || fb.MessageIndex >= len(s.History) || s.History[fb.MessageIndex].Role != "assistant" {
+			invalid = fmt.Errorf("message_index %d is not an assistant message", fb.MessageIndex)
+			return invalid
+		}
+		fb.Time = time.Now().UTC()
+		s.Feedback = append(s.Feedback, fb)
+		return nil
+	})
+	if invalid != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", invalid.Error())
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	for _, listener := range h.listeners {
+		listener(id, fb)
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found_error", err.Error())
+	case errors.Is(err, ErrSessionExpired):
+		writeAPIError(w, http.StatusGone, "session_expired", err.Error())
+	default:
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newServingGenerator builds the response generator used by server mode,
+// with retrieval when configured. The returned func releases the index.
+func newServingGenerator(config *Config, loader *DatasetLoader) (*ResponseGenerator, func()) {
+	generator := NewResponseGenerator(loader)
+	generator.SetBeamSearch(config.BeamSearch)
+	generator.SetTemplates(templateLibraryFromConfig(config), config.Templates)
+	generator.SetSeed(config.Seed)
+	if config.Retrieval.TopK <= 0 {
+		return generator, func() {}
+	}
+	index, err := NewRetrievalIndex(loader, config.Retrieval)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: retrieval disabled: %v\n", err)
+		return generator, func() {}
+	}
+	generator.SetRetrievalIndex(index, config.Retrieval.TopK)
+	generator.SetAnswerThreshold(config.Retrieval.AnswerThreshold)
+	return generator, func() { index.Close() }
+}
+
+// newServingSessionHandler builds the session handler used by server mode
+// and replays, with the response cache, capability policies, coherence
+// reranking and knowledge store as configured. The returned func closes the
+// knowledge store.
+func newServingSessionHandler(config *Config, sessions *SessionManager, generator *ResponseGenerator, loader *DatasetLoader) (*SessionHandler, func(), error) {
+	handler := NewSessionHandler(sessions, generator)
+	handler.SetResponseCache(NewResponseCache(config.ResponseCache, RealClock))
+	handler.SetCapabilityPolicies(config.CapabilityPolicies)
+	if config.Coherence.Enabled {
+		handler.SetCoherence(NewCoherenceScorer(loader, config.Coherence))
+	}
+	if config.Knowledge.Path == "" {
+		return handler, func() {}, nil
+	}
+	knowledge, err := OpenKnowledgeStore(config.Knowledge)
+	if err != nil {
+		return nil, nil, err
+	}
+	generator.SetKnowledgeStore(knowledge)
+	handler.SetKnowledgeStore(knowledge, config.Knowledge)
+	return handler, func() { knowledge.Close() }, nil
+}
+
+// ServeMain implements `go run . serve`: sessions, embeddings, summaries
+// and the inference endpoints behind one HTTP server
+func ServeMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	addr := fs.String("addr", ":8080", "Listen address")
+	port := fs.Int("port", 0, "Listen port on all interfaces; overrides -addr when set")
+	record := fs.String("record", "", "Record every message exchange to this replay file")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "Seed of the recorded run's response generator, written to the replay file")
+	fs.Parse(args)
+	if *port != 0 {
+		*addr = fmt.Sprintf(":%d", *port)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	loader, err := NewDatasetLoader(config.Training)
+	if err != nil {
+		fmt.Printf("❌ ERROR: failed to load datasets: %v\n", err)
+		os.Exit(1)
+	}
+
+	generator, closeGenerator := newServingGenerator(config, loader)
+	defer OnShutdown(ShutdownModels, "response generator", closeGenerator)()
+
+	store, err := NewSessionStore(config.Sessions)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer OnShutdown(ShutdownStores, "session store", func() { closer.Close() })()
+	}
+	sessions := NewSessionManager(store, time.Duration(config.Sessions.TTLMinutes)*time.Minute)
+	if config.Privacy.Sessions {
+		sessions.SetRedactor(NewRedactor(config.Privacy))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer OnShutdown(ShutdownOrchestrators, "session expiry", cancel)()
+	go sessions.RunExpiry(ctx, sessionExpiryInterval)
+
+	queue := NewInferenceQueueFromLimits(config.Resources)
+	if config.Degradation.Enabled {
+		monitor := NewResourceMonitor(config.Resources.MaxMemoryMB, time.Duration(config.Degradation.CheckIntervalMS)*time.Millisecond)
+		monitor.SetDegrader(configureDegradation(config.Degradation))
+		monitor.Start()
+		defer OnShutdown(ShutdownOrchestrators, "resource monitor", monitor.Stop)()
+	}
+
+	health := NewHealthChecker()
+	health.Register("goroutines", GoroutineBudgetCheck(config.Resources.MaxGoroutines), true)
+	health.Register("dataset", DatasetHealthCheck(loader), false)
+	health.Register("sessions", SessionStoreHealthCheck(store), false)
+	health.Register("inference_queue", QueueHealthCheck(queue), false)
+
+	sessionHandler, closeSessions, err := newServingSessionHandler(config, sessions, generator, loader)
+	if err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	defer OnShutdown(ShutdownStores, "knowledge store", closeSessions)()
+	var recorder *Recorder
+	if *record != "" {
+		recorder, err = NewRecorder(*record, config, loader, *seed)
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		defer OnShutdown(ShutdownStores, "replay recorder", func() { recorder.Close() })()
+		// Replays seed their generator the same way
+		generator.SetSeed(*seed)
+		sessionHandler.SetRecorder(recorder)
+	}
+	var audit *AuditLog
+	if config.Audit.Enabled {
+		model, version := modelIdentity(config, loader)
+		audit, err = OpenAuditLog(config.Audit.Path, config.Audit.signingKey(), model, version)
+		if err != nil {
+			fmt.Printf("❌ ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		defer OnShutdown(ShutdownStores, "audit log", func() { audit.Close() })()
+		sessionHandler.SetAuditLog(audit)
+	}
+	auth := NewAPIAuth(config.Server)
+	if auth != nil {
+		fmt.Printf("🔐 Requiring one of %d API keys\n", len(config.Server.APIKeys))
+	}
+	// Check keys before queueing, so refused callers don't hold slots
+	api := func(h http.Handler) http.Handler { return auth.Middleware(queue.Middleware(h)) }
+	mux := http.NewServeMux()
+	health.Mount(mux)
+	mux.Handle("/v1/sessions", api(sessionHandler))
+	mux.Handle("/v1/sessions/", api(sessionHandler))
+	admin := NewAdminHandler(config.Admin, sessions, nil, nil)
+	admin.SetLoader(loader)
+	mux.Handle("/admin/", admin)
+	mux.Handle("/v1/embeddings", api(NewEmbeddingsHandler(loader)))
+	mux.Handle("/summarize", api(NewSummarizeHandler(loader)))
+	if config.Inference.Think || config.Inference.Understand || config.Inference.Compare {
+		var brain *LiquidStateBrain
+		var llm *TransparentLLM
+		if config.Inference.Think || config.Inference.Compare {
+			if brain = NewLiquidStateBrainWithConfig(config.Inference.BrainSize, config); brain == nil {
+				fmt.Printf("❌ ERROR: failed to create liquid brain of size %d\n", config.Inference.BrainSize)
+				os.Exit(1)
+			}
+			defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
+			health.Register("brain", BrainHealthCheck(brain), false)
+		}
+		if config.Inference.Understand || config.Inference.Compare {
+			llm = NewTransparentLLMWithConfig(config)
+			defer OnShutdown(ShutdownModels, "transparent model", llm.Cleanup)()
+		}
+		// Models built for /v1/compare alone don't serve their own endpoint
+		think, understand := brain, llm
+		if !config.Inference.Think {
+			think = nil
+		}
+		if !config.Inference.Understand {
+			understand = nil
+		}
+		handler := NewInferenceHandler(think, understand)
+		handler.SetCapabilityPolicies(config.CapabilityPolicies)
+		handler.SetRecorder(recorder)
+		handler.SetAuditLog(audit)
+		admin.SetBrain(brain)
+		admin.SetLLM(llm)
+		if config.Inference.Compare {
+			orchestrator := NewGenesisOrchestratorWithConfig(config.Inference.BrainSize, config)
+			if orchestrator.liquidBrain == nil {
+				fmt.Printf("❌ ERROR: failed to create orchestrator of size %d\n", config.Inference.BrainSize)
+				os.Exit(1)
+			}
+			defer OnShutdown(ShutdownModels, "orchestrator", func() { orchestrator.Close() })()
+			health.Register("orchestrator", OrchestratorHealthCheck(orchestrator), false)
+			handler.SetComparison(
+				ComparedModel{Name: "transparent", Model: llm},
+				ComparedModel{Name: "liquid", Model: brain},
+				ComparedModel{Name: "orchestrator", Model: orchestrator},
+			)
+		}
+		if config.OpenInference.Path != "" {
+			var redactor *Redactor
+			if config.Privacy.DecisionLogs {
+				redactor = NewRedactor(config.Privacy)
+			}
+			spans, err := OpenSpanExporter(config.OpenInference, redactor)
+			if err != nil {
+				fmt.Printf("❌ ERROR: %v\n", err)
+				os.Exit(1)
+			}
+			defer OnShutdown(ShutdownStores, "span exporter", func() { spans.Close() })()
+			handler.SetSpanExporter(spans)
+		}
+		inference := api(handler)
+		mux.Handle("/v1/think", inference)
+		mux.Handle("/v1/understand", inference)
+		mux.Handle("/v1/compare", inference)
+	}
+
+	fmt.Printf("🚀 Serving sessions on %s/v1/sessions\n", *addr)
+	if err := serveUntilShutdown(*addr, mux); err != nil {
+		fmt.Printf("❌ ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
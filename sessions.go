@@ -0,0 +1,553 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sessions give server mode per-conversation memory. Each session carries
+// its chat history, the generator's topic memory and context window, and any
+// feedback on responses. Sessions are persisted through a SessionStore after
+// every change, restored on demand after a restart, and expire once idle for
+// longer than the configured TTL.
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+)
+
+// GeneratorState is the per-conversation part of ResponseGenerator
+type GeneratorState struct {
+	TopicMemory   map[string]float64 `json:"topic_memory"`
+	ContextWindow []string           `json:"context_window"`
+}
+
+// ChatMessage is one turn of a conversation
+type ChatMessage struct {
+	Role    string    `json:"role"` // "user" or "assistant"
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Feedback rates an assistant message
+type Feedback struct {
+	MessageIndex int       `json:"message_index"`
+	Rating       int       `json:"rating"` // e.g. -1, 0, 1
+	Comment      string    `json:"comment,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Session is a persisted conversation
+type Session struct {
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	History   []ChatMessage  `json:"history"`
+	Generator GeneratorState `json:"generator"`
+	Feedback  []Feedback     `json:"feedback"`
+	Policy    string         `json:"policy,omitempty"` // capability policy the session was created under
+	Entities  EntityTracker  `json:"entities"`         // what "it" and "this project" refer to
+}
+
+// GenerateWithState generates a response using (and updating) a session's
+// generator state instead of the generator's shared state
+func (gen *ResponseGenerator) GenerateWithState(state *GeneratorState, input string, activeConcepts []string) (string, *Explanation) {
+	return gen.GenerateWithStateOptions(state, input, activeConcepts, GenerationOptions{})
+}
+
+// GenerateWithStateOptions is GenerateWithState within the request's limits
+func (gen *ResponseGenerator) GenerateWithStateOptions(state *GeneratorState, input string, activeConcepts []string, options GenerationOptions) (string, *Explanation) {
+	return gen.generate(state, input, activeConcepts, options)
+}
+
+// SessionStore persists sessions
+type SessionStore interface {
+	Save(session *Session) error
+	// Load returns ErrSessionNotFound for unknown IDs
+	Load(id string) (*Session, error)
+	Delete(id string) error
+	List() ([]string, error)
+}
+
+var sessionIDPattern = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// MemorySessionStore keeps sessions in memory only
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string][]byte // JSON, so loads never alias a live session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string][]byte)}
+}
+
+func (s *MemorySessionStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sessions[session.ID] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemorySessionStore) Load(id string) (*Session, error) {
+	s.mu.RLock()
+	data, ok := s.sessions[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemorySessionStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FileSessionStore keeps one JSON file per session in a directory
+type FileSessionStore struct {
+	dir string
+}
+
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+func (s *FileSessionStore) path(id string) (string, error) {
+	// IDs come from clients; never let one escape the directory
+	if !sessionIDPattern.MatchString(id) {
+		return "", ErrSessionNotFound
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileSessionStore) Save(session *Session) error {
+	path, err := s.path(session.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *FileSessionStore) Load(id string) (*Session, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("corrupt session %s: %w", id, err)
+	}
+	return &session, nil
+}
+
+func (s *FileSessionStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileSessionStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if id != e.Name() && sessionIDPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SQLSessionStore keeps sessions in a database/sql table. It uses plain SQL
+// with ? placeholders, so any SQLite driver registered by the binary works.
+type SQLSessionStore struct {
+	db     *sql.DB
+	table  string
+	ownsDB bool // close db on Close
+}
+
+func NewSQLSessionStore(db *sql.DB, table string) (*SQLSessionStore, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, updated_at INTEGER NOT NULL, data TEXT NOT NULL)`, table)
+	if _, err := db.Exec(create); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", table, err)
+	}
+	return &SQLSessionStore{db: db, table: table}, nil
+}
+
+func (s *SQLSessionStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(fmt.Sprintf(`INSERT OR REPLACE INTO %s (id, updated_at, data) VALUES (?, ?, ?)`, s.table),
+		session.ID, session.UpdatedAt.Unix(), string(data))
+	return err
+}
+
+func (s *SQLSessionStore) Load(id string) (*Session, error) {
+	var data string
+	err := s.db.QueryRow(fmt.Sprintf(`SELECT data FROM %s WHERE id = ?`, s.table), id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, fmt.Errorf("corrupt session %s: %w", id, err)
+	}
+	return &session, nil
+}
+
+func (s *SQLSessionStore) Delete(id string) error {
+	_, err := s.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table), id)
+	return err
+}
+
+func (s *SQLSessionStore) List() ([]string, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT id FROM %s ORDER BY id`, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Close closes the database if the store opened it
+func (s *SQLSessionStore) Close() error {
+	if s.ownsDB {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// SessionConfig controls session storage and expiry in server mode
+type SessionConfig struct {
+	Store      string `json:"store"`       // "file" (default), "memory" or "sql"
+	Dir        string `json:"dir"`         // file store directory
+	Driver     string `json:"driver"`      // sql store: database/sql driver name
+	DSN        string `json:"dsn"`         // sql store: data source name
+	Table      string `json:"table"`       // sql store: table name, "sessions" by default
+	TTLMinutes int    `json:"ttl_minutes"` // idle time before a session expires; 0 never expires
+}
+
+// NewSessionStore creates the store described by config. Stores that hold
+// resources implement io.Closer; close them on shutdown.
+func NewSessionStore(config SessionConfig) (SessionStore, error) {
+	switch config.Store {
+	case "", "file":
+		dir := config.Dir
+		if dir == "" {
+			dir = "sessions"
+		}
+		return NewFileSessionStore(dir)
+	case "memory":
+		return NewMemorySessionStore(), nil
+	case "sql":
+		if config.Driver == "" || config.DSN == "" {
+			return nil, fmt.Errorf("sql session store needs a driver and dsn")
+		}
+		table := config.Table
+		if table == "" {
+			table = "sessions"
+		}
+		db, err := sql.Open(config.Driver, config.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open session database: %w", err)
+		}
+		store, err := NewSQLSessionStore(db, table)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		store.ownsDB = true
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q", config.Store)
+	}
+}
+
+// SessionManager creates, restores, updates and expires sessions. Updates
+// to one session are serialized; different sessions proceed in parallel.
+type SessionManager struct {
+	store    SessionStore
+	ttl      time.Duration // 0 disables expiry
+	redactor *Redactor     // applied before every save; nil when off
+	mu       sync.Mutex
+	locks    map[string]*sessionLock // only sessions being updated
+}
+
+// sessionLock serializes updates to one session; users counts the updates
+// holding or waiting for it, so it can be dropped when the last one is done
+type sessionLock struct {
+	sync.Mutex
+	users int
+}
+
+func NewSessionManager(store SessionStore, ttl time.Duration) *SessionManager {
+	return &SessionManager{
+		store: store,
+		ttl:   ttl,
+		locks: make(map[string]*sessionLock),
+	}
+}
+
+// SetRedactor sets the redactor applied to sessions before they are stored;
+// nil disables it. Call it before the manager is shared.
+func (sm *SessionManager) SetRedactor(r *Redactor) {
+	sm.redactor = r
+}
+
+// lock takes the lock serializing updates to id and returns its release.
+// Locks live only while updates use them, so ids that are never seen again,
+// whether unknown, expired or deleted, don't pile up.
+func (sm *SessionManager) lock(id string) func() {
+	sm.mu.Lock()
+	l, ok := sm.locks[id]
+	if !ok {
+		l = &sessionLock{}
+		sm.locks[id] = l
+	}
+	l.users++
+	sm.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		sm.mu.Lock()
+		if l.users--; l.users == 0 {
+			delete(sm.locks, id)
+		}
+		sm.mu.Unlock()
+	}
+}
+
+func (sm *SessionManager) expired(s *Session) bool {
+	return sm.ttl > 0 && time.Since(s.UpdatedAt) > sm.ttl
+}
+
+// Create starts and persists a new empty session
+func (sm *SessionManager) Create() (*Session, error) {
+	return sm.CreateWithPolicy("")
+}
+
+// CreateWithPolicy starts a session restricted to the named capability
+// policy
+func (sm *SessionManager) CreateWithPolicy(policy string) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &Session{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Generator: GeneratorState{TopicMemory: make(map[string]float64)},
+		Policy:    policy,
+	}
+	if err := sm.store.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Get restores a session from the store. Expired sessions are deleted and
+// reported as ErrSessionExpired.
+func (sm *SessionManager) Get(id string) (*Session, error) {
+	session, err := sm.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if sm.expired(session) {
+		sm.Delete(id)
+		return nil, ErrSessionExpired
+	}
+	return session, nil
+}
+
+// Update applies fn to the session and persists the result. fn's error
+// aborts the update without saving.
+func (sm *SessionManager) Update(id string, fn func(*Session) error) (*Session, error) {
+	unlock := sm.lock(id)
+	defer unlock()
+
+	session, err := sm.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(session); err != nil {
+		return nil, err
+	}
+	session.UpdatedAt = time.Now().UTC()
+	sm.redactor.RedactSession(session)
+	if err := sm.store.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SessionSummary describes a session without its full history
+type SessionSummary struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  int       `json:"messages"`
+	Feedback  int       `json:"feedback"`
+}
+
+// List summarizes every live session, most recently active first
+func (sm *SessionManager) List() ([]SessionSummary, error) {
+	ids, err := sm.store.List()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]SessionSummary, 0, len(ids))
+	for _, id := range ids {
+		session, err := sm.store.Load(id)
+		if err != nil || sm.expired(session) {
+			continue
+		}
+		summaries = append(summaries, SessionSummary{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			UpdatedAt: session.UpdatedAt,
+			Messages:  len(session.History),
+			Feedback:  len(session.Feedback),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+// Delete removes a session
+func (sm *SessionManager) Delete(id string) error {
+	return sm.store.Delete(id)
+}
+
+// ExpireIdle deletes every session idle for longer than the TTL and
+// returns how many were removed
+func (sm *SessionManager) ExpireIdle() (int, error) {
+	if sm.ttl <= 0 {
+		return 0, nil
+	}
+	ids, err := sm.store.List()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, id := range ids {
+		session, err := sm.store.Load(id)
+		if err != nil {
+			continue
+		}
+		if sm.expired(session) {
+			if err := sm.Delete(id); err == nil {
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
+
+// RunExpiry calls ExpireIdle every interval until ctx is cancelled
+func (sm *SessionManager) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := sm.ExpireIdle(); err != nil {
+				fmt.Printf("⚠️  Warning: session expiry failed: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("🧹 Expired %d idle sessions\n", n)
+			}
+		}
+	}
+}
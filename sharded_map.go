@@ -0,0 +1,97 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Number of shards in a shardedMap. A power of two keeps the shard pick cheap.
+const mapShardCount = 32
+
+// shardedMap is a string-keyed concurrent map split across independently
+// locked shards, so readers and writers on different keys don't serialize
+// behind one RWMutex.
+type shardedMap[V any] struct {
+	shards [mapShardCount]mapShard[V]
+}
+
+type mapShard[V any] struct {
+	mu    sync.RWMutex
+	items map[string]V
+}
+
+func newShardedMap[V any]() *shardedMap[V] {
+	m := &shardedMap[V]{}
+	for i := range m.shards {
+		m.shards[i].items = make(map[string]V)
+	}
+	return m
+}
+
+func (m *shardedMap[V]) shard(key string) *mapShard[V] {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &m.shards[h.Sum32()&(mapShardCount-1)]
+}
+
+// Get returns the value stored under key
+func (m *shardedMap[V]) Get(key string) (V, bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	v, ok := s.items[key]
+	s.mu.RUnlock()
+	return v, ok
+}
+
+// Set stores value under key, replacing any previous value
+func (m *shardedMap[V]) Set(key string, value V) {
+	s := m.shard(key)
+	s.mu.Lock()
+	s.items[key] = value
+	s.mu.Unlock()
+}
+
+// Delete removes key from the map
+func (m *shardedMap[V]) Delete(key string) {
+	s := m.shard(key)
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+}
+
+// Len returns the total number of entries across all shards
+func (m *shardedMap[V]) Len() int {
+	total := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		total += len(s.items)
+		s.mu.RUnlock()
+	}
+	return total
+}
+
+// Range calls fn for every entry until fn returns false. Each shard is
+// snapshotted before fn runs, so fn may safely call back into the map.
+func (m *shardedMap[V]) Range(fn func(key string, value V) bool) {
+	type entry struct {
+		key   string
+		value V
+	}
+
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		entries := make([]entry, 0, len(s.items))
+		for k, v := range s.items {
+			entries = append(entries, entry{k, v})
+		}
+		s.mu.RUnlock()
+
+		for _, e := range entries {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
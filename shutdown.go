@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// Graceful shutdown: every entry point registers what it starts with the
+// process-wide GracefulShutdown, each piece in a stage, and releases it with
+// the returned func when it finishes normally. On an interrupt or terminate
+// signal (per platform, see shutdownSignals) whatever is still registered is
+// cleaned up stage by stage: servers stop taking requests first, then
+// orchestrators, then the models they drive, and stores such as replay and
+// audit logs are closed last, once nothing writes to them. Each cleanup runs
+// at most once, whichever path reaches it first.
+
+// ShutdownStage orders cleanups; lower stages run first
+type ShutdownStage int
+
+const (
+	ShutdownServers ShutdownStage = iota
+	ShutdownOrchestrators
+	ShutdownModels
+	ShutdownStores
+	shutdownStages
+)
+
+// How long cleanups get before the process exits anyway
+const shutdownTimeout = 10 * time.Second
+
+var shutdownStageNames = [shutdownStages]string{"servers", "orchestrators", "models", "stores"}
+
+func (s ShutdownStage) String() string {
+	return shutdownStageNames[s]
+}
+
+// shutdownCleanup is one registered cleanup
+type shutdownCleanup struct {
+	name string
+	once sync.Once
+	fn   func()
+}
+
+func (c *shutdownCleanup) run() {
+	c.once.Do(c.fn)
+}
+
+// GracefulShutdown provides a way to handle cleanup during shutdown
+type GracefulShutdown struct {
+	mu       sync.Mutex
+	cleanups [shutdownStages][]*shutdownCleanup
+	timeout  time.Duration
+	once     sync.Once
+}
+
+func NewGracefulShutdown(timeout time.Duration) *GracefulShutdown {
+	return &GracefulShutdown{timeout: timeout}
+}
+
+// processShutdown is the process-wide shutdown entry points register with
+var processShutdown = NewGracefulShutdown(shutdownTimeout)
+
+// ProcessShutdown returns the process-wide shutdown
+func ProcessShutdown() *GracefulShutdown {
+	return processShutdown
+}
+
+// OnShutdown registers fn with the process-wide shutdown; see Register
+func OnShutdown(stage ShutdownStage, name string, fn func()) func() {
+	return processShutdown.Register(stage, name, fn)
+}
+
+// AddCleanup registers fn with the models stage
+func (gs *GracefulShutdown) AddCleanup(fn func()) {
+	gs.Register(ShutdownModels, "cleanup", fn)
+}
+
+// Register adds a named cleanup to stage. The returned func runs it now and
+// unregisters it, for components that finish before the process does;
+// `defer OnShutdown(...)()` covers both normal return and signals.
+func (gs *GracefulShutdown) Register(stage ShutdownStage, name string, fn func()) func() {
+	cleanup := &shutdownCleanup{name: name, fn: fn}
+	gs.mu.Lock()
+	gs.cleanups[stage] = append(gs.cleanups[stage], cleanup)
+	gs.mu.Unlock()
+	return func() {
+		gs.unregister(stage, cleanup)
+		cleanup.run()
+	}
+}
+
+func (gs *GracefulShutdown) unregister(stage ShutdownStage, cleanup *shutdownCleanup) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for i, c := range gs.cleanups[stage] {
+		if c == cleanup {
+			gs.cleanups[stage] = append(gs.cleanups[stage][:i], gs.cleanups[stage][i+1:]...)
+			return
+		}
+	}
+}
+
+// pending returns the registered cleanups in shutdown order: by stage, and
+// within a stage the most recently registered first, like defers
+func (gs *GracefulShutdown) pending() []*shutdownCleanup {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	var ordered []*shutdownCleanup
+	for stage := range gs.cleanups {
+		for i := len(gs.cleanups[stage]) - 1; i >= 0; i-- {
+			ordered = append(ordered, gs.cleanups[stage][i])
+		}
+		gs.cleanups[stage] = nil
+	}
+	return ordered
+}
+
+// Shutdown runs every registered cleanup in order, exiting the process if
+// they take longer than the timeout. Later calls wait for the first.
+func (gs *GracefulShutdown) Shutdown() {
+	gs.once.Do(func() {
+		cleanups := gs.pending()
+		if len(cleanups) == 0 {
+			return
+		}
+		fmt.Println("🔄 Starting graceful shutdown...")
+
+		done := make(chan struct{})
+		go func() {
+			for i, cleanup := range cleanups {
+				fmt.Printf("🧹 Running cleanup %d/%d: %s\n", i+1, len(cleanups), cleanup.name)
+				cleanup.run()
+			}
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			fmt.Println("✅ Graceful shutdown completed")
+		case <-time.After(gs.timeout):
+			fmt.Println("⚠️  Shutdown timeout exceeded, forcing exit")
+			os.Exit(1)
+		}
+	})
+}
+
+// Listen shuts down and exits when the process is signalled to stop; a
+// second signal during shutdown exits at once
+func (gs *GracefulShutdown) Listen() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, shutdownSignals...)
+	go func() {
+		sig := <-signals
+		fmt.Printf("\n🛑 Received %v, shutting down gracefully...\n", sig)
+		go func() {
+			<-signals
+			fmt.Println("⚠️  Second signal, forcing exit")
+			os.Exit(1)
+		}()
+		gs.Shutdown()
+		os.Exit(0)
+	}()
+}
+
+// serveUntilShutdown serves handler on addr until the process shuts down,
+// when the server stops accepting connections and lets requests in flight
+// finish before later stages run
+func serveUntilShutdown(addr string, handler http.Handler) error {
+	server := &http.Server{Addr: addr, Handler: handler}
+	defer OnShutdown(ShutdownServers, "http server on "+addr, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout/2)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Printf("⚠️  Warning: http server shutdown: %v\n", err)
+		}
+	})()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals stop the process on Linux, macOS and other Unix systems:
+// Ctrl-C, a service manager's terminate, and the terminal closing
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals stop the process on Windows: Ctrl-C or Ctrl-Break, and
+// the console closing, logoff or system shutdown, which Go delivers as
+// SIGTERM
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
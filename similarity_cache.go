@@ -0,0 +1,226 @@
+package main
+
+import (
+	"container/list"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Word similarities are computed in tight loops: every injected word is
+// compared against the input layer, every candidate word against topic
+// memory, and every vocabulary word against every other when semantic
+// connections are built. The loader keeps its embeddings L2-normalized in
+// one flat matrix, so a similarity is a single dot product over contiguous
+// memory, and remembers recent pair similarities in a sharded LRU cache.
+
+const defaultSimilarityCacheSize = 65536
+
+// embeddingMatrix holds every embedding, L2-normalized, in one flat slice
+type embeddingMatrix struct {
+	dim   int
+	index map[string]int
+	data  []float64
+}
+
+func newEmbeddingMatrix(embeddings map[string][]float64, dim int) *embeddingMatrix {
+	words := make([]string, 0, len(embeddings))
+	for word := range embeddings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	m := &embeddingMatrix{
+		dim:   dim,
+		index: make(map[string]int, len(words)),
+		data:  make([]float64, len(words)*dim),
+	}
+	for i, word := range words {
+		m.index[word] = i
+		row := m.data[i*dim : (i+1)*dim]
+		copy(row, embeddings[word])
+
+		norm := 0.0
+		for _, v := range row {
+			norm += v * v
+		}
+		if norm = math.Sqrt(norm); norm > 0 {
+			for k := range row {
+				row[k] /= norm
+			}
+		}
+	}
+	return m
+}
+
+// vector returns word's normalized embedding; callers must not modify it
+func (m *embeddingMatrix) vector(word string) ([]float64, bool) {
+	i, ok := m.index[word]
+	if !ok {
+		return nil, false
+	}
+	return m.data[i*m.dim : (i+1)*m.dim], true
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// SimilarityCache is a bounded LRU cache of word pair similarities, split
+// into shards that are locked independently. A nil *SimilarityCache caches
+// nothing.
+type SimilarityCache struct {
+	shards [similarityCacheShards]similarityShard
+	hits   int64 // atomic
+	misses int64 // atomic
+}
+
+const similarityCacheShards = 16
+
+type similarityShard struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[wordPair]*list.Element
+	order    *list.List // front is most recently used
+}
+
+// wordPair is an unordered pair stored in sorted order
+type wordPair [2]string
+
+type similarityEntry struct {
+	pair  wordPair
+	value float64
+}
+
+// SimilarityCacheStats reports cache usage
+type SimilarityCacheStats struct {
+	Size     int   `json:"size"`
+	Capacity int   `json:"capacity"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+// NewSimilarityCache creates a cache holding about capacity pairs
+func NewSimilarityCache(capacity int) *SimilarityCache {
+	perShard := (capacity + similarityCacheShards - 1) / similarityCacheShards
+	if perShard < 1 {
+		perShard = 1
+	}
+	c := &SimilarityCache{}
+	for i := range c.shards {
+		c.shards[i] = similarityShard{
+			capacity: perShard,
+			entries:  make(map[wordPair]*list.Element),
+			order:    list.New(),
+		}
+	}
+	return c
+}
+
+func newWordPair(a, b string) wordPair {
+	if b < a {
+		a, b = b, a
+	}
+	return wordPair{a, b}
+}
+
+// shard picks pair's shard by an inline FNV-1a hash, which unlike
+// hash/fnv doesn't allocate on the lookup path
+func (c *SimilarityCache) shard(pair wordPair) *similarityShard {
+	h := uint32(2166136261)
+	for _, word := range pair {
+		for i := 0; i < len(word); i++ {
+			h ^= uint32(word[i])
+			h *= 16777619
+		}
+		h *= 16777619 // separates the words
+	}
+	return &c.shards[h%similarityCacheShards]
+}
+
+// Get returns the cached similarity of a and b
+func (c *SimilarityCache) Get(a, b string) (float64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	pair := newWordPair(a, b)
+	s := c.shard(pair)
+	s.mu.Lock()
+	var value float64
+	elem, ok := s.entries[pair]
+	if ok {
+		s.order.MoveToFront(elem)
+		value = elem.Value.(*similarityEntry).value
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		atomic.AddInt64(&c.misses, 1)
+		return 0, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return value, true
+}
+
+// Put stores the similarity of a and b, evicting the least recently used
+// pair of its shard when full
+func (c *SimilarityCache) Put(a, b string, value float64) {
+	if c == nil {
+		return
+	}
+	pair := newWordPair(a, b)
+	s := c.shard(pair)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[pair]; ok {
+		elem.Value.(*similarityEntry).value = value
+		s.order.MoveToFront(elem)
+		return
+	}
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*similarityEntry).pair)
+	}
+	s.entries[pair] = s.order.PushFront(&similarityEntry{pair: pair, value: value})
+}
+
+// Stats returns the cache's size and hit counts
+func (c *SimilarityCache) Stats() SimilarityCacheStats {
+	if c == nil {
+		return SimilarityCacheStats{}
+	}
+	stats := SimilarityCacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+	}
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		stats.Size += s.order.Len()
+		stats.Capacity += s.capacity
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+// Reset empties the cache, keeping its hit counts, after the embeddings it
+// was computed from change
+func (c *SimilarityCache) Reset() {
+	if c == nil {
+		return
+	}
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.entries = make(map[wordPair]*list.Element)
+		s.order.Init()
+		s.mu.Unlock()
+	}
+}
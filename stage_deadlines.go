@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stage deadlines: Understand runs parsing, circuit search and generation
+// each under a deadline derived from the caller's context, tightened by the
+// stage's configured limit. A stage that runs out of time hands on what it
+// has, activation spread so far, circuits traced so far, or a simple
+// response in place of a generated one, and reports it as a TIMEOUT thought
+// and in the explanation's timeouts. Every thought stream ends with a
+// COMPLETE thought, so a consumer can tell a finished stream from one that
+// was cut short.
+
+// StageTimeoutConfig limits Understand's stages; 0 leaves a stage bounded
+// only by the caller's context
+type StageTimeoutConfig struct {
+	ParsingMS       int `json:"parsing_ms"`
+	CircuitSearchMS int `json:"circuit_search_ms"`
+	GenerationMS    int `json:"generation_ms"`
+}
+
+func (c StageTimeoutConfig) validate() error {
+	if c.ParsingMS < 0 || c.CircuitSearchMS < 0 || c.GenerationMS < 0 {
+		return fmt.Errorf("stage timeouts must not be negative")
+	}
+	return nil
+}
+
+// Stages of Understand with deadlines
+const (
+	stageParsing       = "parsing"
+	stageCircuitSearch = "circuit_search"
+	stageGeneration    = "generation"
+)
+
+// stageContext returns the context a stage runs under: the caller's,
+// bounded by ms when it is set
+func stageContext(ctx context.Context, ms int) (context.Context, context.CancelFunc) {
+	if ms <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// untilDeadline returns limit, or less if ctx's deadline comes sooner
+func untilDeadline(ctx context.Context, limit time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// timeoutThought reports a stage that ran out of time
+func timeoutThought(stage string, err error) ThoughtTrace {
+	return ThoughtTrace{
+		stage:   "TIMEOUT",
+		insight: fmt.Sprintf("%s stage stopped early: %v", stage, err),
+	}
+}
+
+// completeThought ends every thought stream
+func completeThought(timeouts []string) ThoughtTrace {
+	insight := "Understanding complete"
+	if len(timeouts) > 0 {
+		insight = fmt.Sprintf("Understanding complete; %d stage(s) timed out: %v", len(timeouts), timeouts)
+	}
+	return ThoughtTrace{stage: "COMPLETE", insight: insight}
+}
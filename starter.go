@@ -0,0 +1,38 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// The starter corpus and default config ship inside the binary so a fresh
+// checkout (or a binary run outside the repo) works without datasets/.
+// Configured dataset paths always take precedence; the starter corpus is
+// only used when none of them load, unless DisableStarterCorpus is set.
+
+//go:embed starter/corpus.txt
+var starterCorpus string
+
+//go:embed starter/config.json
+var defaultConfigJSON []byte
+
+// Path recorded on documents loaded from the embedded corpus
+const starterCorpusPath = "embedded:starter/corpus.txt"
+
+// loadStarterCorpus adds the embedded corpus as a single document
+func (dl *DatasetLoader) loadStarterCorpus() error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	return dl.addDocument(starterCorpusPath, starterCorpus)
+}
+
+// parseDefaultConfig decodes the embedded default config
+func parseDefaultConfig() (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(defaultConfigJSON, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded default config: %w", err)
+	}
+	return &config, nil
+}
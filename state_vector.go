@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// Reservoir features: the classic liquid state machine use is to train a
+// simple classifier on the reservoir's state after an input. StateVector
+// exposes that state as a flat feature vector, optionally pooled over
+// neighbouring neurons to keep it small, so it can be fed to scikit-learn,
+// gonum or any other learner.
+
+// StatePooling reduces a state vector by pooling neighbouring neurons
+type StatePooling struct {
+	Size int  // side of the cubic pooling block; 0 or 1 keeps every neuron
+	Max  bool // take each block's maximum instead of its mean
+}
+
+// blocks returns the number of pooling blocks along a side of n neurons
+func (p StatePooling) blocks(n int) int {
+	if p.Size <= 1 {
+		return n
+	}
+	return (n + p.Size - 1) / p.Size
+}
+
+// StateVectorLen returns the length of StateVector(pooling)
+func (brain *LiquidStateBrain) StateVectorLen(pooling StatePooling) int {
+	dims := brain.dimensions
+	return pooling.blocks(dims.X) * pooling.blocks(dims.Y) * pooling.blocks(dims.Z)
+}
+
+// StateVector returns the reservoir state as a feature vector. Without
+// pooling it is every neuron's state in x, y, z order, like StateSnapshot;
+// with pooling it is one value per Size x Size x Size block, blocks in x, y,
+// z order, where blocks at the far edges may be partial.
+func (brain *LiquidStateBrain) StateVector(pooling StatePooling) []float64 {
+	if pooling.Size <= 1 {
+		return brain.StateSnapshot()
+	}
+	dims := brain.dimensions
+	bx, by, bz := pooling.blocks(dims.X), pooling.blocks(dims.Y), pooling.blocks(dims.Z)
+	vector := make([]float64, bx*by*bz)
+	counts := make([]int, len(vector))
+	if pooling.Max {
+		for i := range vector {
+			vector[i] = math.Inf(-1)
+		}
+	}
+	for x := 0; x < dims.X; x++ {
+		for y := 0; y < dims.Y; y++ {
+			for z := 0; z < dims.Z; z++ {
+				var state float64
+				if val := brain.reservoir[x][y][z].state.Load(); val != nil {
+					state = val.(float64)
+				}
+				i := ((x/pooling.Size)*by+y/pooling.Size)*bz + z/pooling.Size
+				if pooling.Max {
+					vector[i] = math.Max(vector[i], state)
+				} else {
+					vector[i] += state
+				}
+				counts[i]++
+			}
+		}
+	}
+	if !pooling.Max {
+		for i := range vector {
+			vector[i] /= float64(counts[i])
+		}
+	}
+	return vector
+}
+
+// Features resets the reservoir, stimulates it with input, waits for it to
+// settle and returns its state vector, so an input's features don't depend
+// on the inputs before it. No response is generated.
+func (brain *LiquidStateBrain) Features(input string, pooling StatePooling) []float64 {
+	brain.ResetState()
+	brain.stimulate(input)
+	return brain.StateVector(pooling)
+}
+
+// ResetState waits for the reservoir to settle and clears every neuron's
+// state. Spontaneous activity carries on, but it is small next to an
+// input's stimulation.
+func (brain *LiquidStateBrain) ResetState() {
+	brain.settle()
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for _, neuron := range brain.reservoir[x][y] {
+				neuron.state.Store(0.0)
+			}
+		}
+	}
+}
+
+// stimulate injects input's words and waits for the reservoir to settle
+func (brain *LiquidStateBrain) stimulate(input string) {
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		brain.injectWord(word)
+	}
+	brain.settle()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stimulus protocols: scripted reservoir experiments. A protocol is a timed
+// sequence of input injections, each with an amplitude, plus how often to
+// sample the reservoir state and for how long. Running one against a brain
+// returns the sampled state time-series, and with a Recorder it also logs
+// every injection and sample to the replay file, so characterization
+// experiments such as an impulse response or a memory capacity sweep can be
+// written once and re-run. Protocols are YAML (the template library
+// subset) or JSON, chosen by extension:
+//
+//	name: impulse
+//	sample_ms: 10
+//	duration_ms: 500
+//	steps:
+//	  - at_ms: 50
+//	    input: cat
+//	    amplitude: 2
+
+// StimulusProtocol is a timed sequence of input injections
+type StimulusProtocol struct {
+	Name       string         `json:"name"`
+	SampleMS   int            `json:"sample_ms"`   // reservoir state sampling interval
+	DurationMS int            `json:"duration_ms"` // how long the protocol runs
+	Steps      []StimulusStep `json:"steps"`
+}
+
+// StimulusStep injects an input's words, optionally repeated
+type StimulusStep struct {
+	AtMS      int     `json:"at_ms"`
+	Input     string  `json:"input"`
+	Amplitude float64 `json:"amplitude"` // injection gain; 0 is 1
+	Repeat    int     `json:"repeat"`    // injections in all; 0 is 1
+	EveryMS   int     `json:"every_ms"`  // between repeats
+}
+
+// StimulusInjection is one injection as it happened
+type StimulusInjection struct {
+	AtMS      float64 `json:"at_ms"`
+	Input     string  `json:"input"`
+	Amplitude float64 `json:"amplitude"`
+}
+
+// StimulusSample is one reservoir state sample
+type StimulusSample struct {
+	AtMS   float64   `json:"at_ms"`
+	States []float64 `json:"states"` // in x, y, z order, like StateSnapshot
+}
+
+// StimulusRun is the outcome of running a protocol
+type StimulusRun struct {
+	Protocol   string              `json:"protocol"`
+	Dimensions Dimensions          `json:"dimensions"`
+	Injections []StimulusInjection `json:"injections"`
+	Samples    []StimulusSample    `json:"samples"`
+}
+
+// LoadStimulusProtocol reads a protocol from a YAML or JSON file
+func LoadStimulusProtocol(path string) (*StimulusProtocol, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stimulus protocol: %w", err)
+	}
+	var protocol StimulusProtocol
+	if err := unmarshalYAMLOrJSON(path, data, &protocol); err != nil {
+		return nil, fmt.Errorf("failed to parse stimulus protocol %s: %w", path, err)
+	}
+	if err := protocol.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid stimulus protocol %s: %w", path, err)
+	}
+	return &protocol, nil
+}
+
+// Validate checks the protocol's timing and steps
+func (p *StimulusProtocol) Validate() error {
+	if p.SampleMS <= 0 || p.DurationMS <= 0 {
+		return fmt.Errorf("sample_ms and duration_ms must be positive")
+	}
+	for i, step := range p.Steps {
+		if strings.TrimSpace(step.Input) == "" {
+			return fmt.Errorf("step %d has no input", i)
+		}
+		if step.AtMS < 0 || step.AtMS > p.DurationMS {
+			return fmt.Errorf("step %d at_ms must be within the protocol's duration", i)
+		}
+		if step.Amplitude < 0 || step.Repeat < 0 {
+			return fmt.Errorf("step %d amplitude and repeat must not be negative", i)
+		}
+		if step.Repeat > 1 && step.EveryMS <= 0 {
+			return fmt.Errorf("step %d repeats, so every_ms must be positive", i)
+		}
+	}
+	return nil
+}
+
+// stimulusEvent is an injection or a sample on the protocol's timeline
+type stimulusEvent struct {
+	at        time.Duration
+	input     string // "" for a sample
+	amplitude float64
+}
+
+// timeline returns the protocol's injections and samples in time order,
+// samples first at equal times so each one shows the state before any
+// injection at that moment. Repeats past the duration are dropped.
+func (p *StimulusProtocol) timeline() []stimulusEvent {
+	duration := time.Duration(p.DurationMS) * time.Millisecond
+	var events []stimulusEvent
+	for _, step := range p.Steps {
+		amplitude := step.Amplitude
+		if amplitude == 0 {
+			amplitude = 1
+		}
+		for k := 0; k < max(1, step.Repeat); k++ {
+			at := time.Duration(step.AtMS+k*step.EveryMS) * time.Millisecond
+			if at > duration {
+				break
+			}
+			events = append(events, stimulusEvent{at: at, input: step.Input, amplitude: amplitude})
+		}
+	}
+	for at := time.Duration(0); at <= duration; at += time.Duration(p.SampleMS) * time.Millisecond {
+		events = append(events, stimulusEvent{at: at})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].input == "" && events[j].input != ""
+	})
+	return events
+}
+
+// RunProtocol runs the protocol against the brain on its clock, returning
+// the injections and state samples. With a recorder, each injection and
+// sample is also logged as an event of a protocol session. The run stops
+// early if the brain shuts down.
+func (brain *LiquidStateBrain) RunProtocol(p *StimulusProtocol, recorder *Recorder) (*StimulusRun, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	run := &StimulusRun{Protocol: p.Name, Dimensions: brain.dimensions}
+	session := "protocol:" + p.Name
+	fmt.Printf("🧪 Running stimulus protocol %q for %dms\n", p.Name, p.DurationMS)
+
+	start := brain.clock.Now()
+	for _, event := range p.timeline() {
+		if wait := event.at - clockSince(brain.clock, start); wait > 0 {
+			brain.clock.Sleep(wait)
+		}
+		if brain.ctx.Err() != nil {
+			return run, fmt.Errorf("brain shut down during protocol %q", p.Name)
+		}
+
+		at := durationMs(clockSince(brain.clock, start))
+		var record interface{}
+		if event.input == "" {
+			sample := StimulusSample{AtMS: at, States: brain.StateSnapshot()}
+			run.Samples = append(run.Samples, sample)
+			record = sample
+		} else {
+			for _, word := range strings.Fields(strings.ToLower(event.input)) {
+				brain.injectWordWithGain(word, event.amplitude)
+			}
+			injection := StimulusInjection{AtMS: at, Input: event.input, Amplitude: event.amplitude}
+			run.Injections = append(run.Injections, injection)
+			record = injection
+		}
+		if recorder != nil {
+			kind := "stimulus"
+			if event.input == "" {
+				kind = "state"
+			}
+			if err := recorder.RecordEvent(session, kind, record); err != nil {
+				return run, err
+			}
+		}
+	}
+	return run, nil
+}
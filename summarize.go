@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Extractive summarization: every sentence is embedded, scored by its total
+// similarity to the other sentences (degree centrality, as in LexRank), and
+// the most central sentences are returned in their original order.
+
+// Sentences returned when the caller doesn't ask for a count
+const defaultSummarySentences = 3
+
+// SummarySentence is one sentence picked for a summary
+type SummarySentence struct {
+	Text  string  `json:"text"`
+	Index int     `json:"index"` // position in the source
+	Score float64 `json:"score"` // centrality
+}
+
+// Summary is an extractive summary of a document or conversation
+type Summary struct {
+	Text      string            `json:"summary"`
+	Sentences []SummarySentence `json:"sentences"`
+	Total     int               `json:"total_sentences"`
+}
+
+// SummarizeText summarizes text in at most n sentences
+func (dl *DatasetLoader) SummarizeText(text string, n int) *Summary {
+	return dl.summarizeSentences(splitSentences(text), n)
+}
+
+// SummarizeDocument summarizes the loaded document with the given path
+func (dl *DatasetLoader) SummarizeDocument(path string, n int) (*Summary, error) {
+	for _, doc := range dl.GetDocuments() {
+		if doc.Path == path {
+			return dl.SummarizeText(doc.Content, n), nil
+		}
+	}
+	return nil, fmt.Errorf("document %s is not loaded", path)
+}
+
+// SummarizeConversation summarizes conversation turns, oldest first
+func (dl *DatasetLoader) SummarizeConversation(turns []string, n int) *Summary {
+	var sentences []string
+	for _, turn := range turns {
+		sentences = append(sentences, splitSentences(turn)...)
+	}
+	return dl.summarizeSentences(sentences, n)
+}
+
+func (dl *DatasetLoader) summarizeSentences(sentences []string, n int) *Summary {
+	if n <= 0 {
+		n = defaultSummarySentences
+	}
+	summary := &Summary{Total: len(sentences)}
+	if len(sentences) == 0 {
+		return summary
+	}
+
+	vectors := make([][]float64, len(sentences))
+	for i, s := range sentences {
+		vectors[i], _, _ = dl.SentenceEmbedding(s)
+	}
+
+	scored := make([]SummarySentence, len(sentences))
+	for i := range sentences {
+		centrality := 0.0
+		if vectors[i] != nil {
+			for j := range sentences {
+				if i != j && vectors[j] != nil {
+					centrality += dotProduct(vectors[i], vectors[j])
+				}
+			}
+		}
+		if len(sentences) > 1 {
+			centrality /= float64(len(sentences) - 1)
+		}
+		scored[i] = SummarySentence{Text: sentences[i], Index: i, Score: centrality}
+	}
+
+	// Most central first, earlier sentences winning ties
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	if len(scored) > n {
+		scored = scored[:n]
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		return scored[i].Index < scored[j].Index
+	})
+
+	texts := make([]string, len(scored))
+	for i, s := range scored {
+		texts[i] = s.Text
+	}
+	summary.Sentences = scored
+	summary.Text = strings.Join(texts, " ")
+	return summary
+}
+
+// SummarizeCapability returns an orchestrator endpoint that summarizes the
+// loaded document named in the input, or the input text itself
+func SummarizeCapability(loader *DatasetLoader) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, input string) (string, error) {
+		for _, doc := range loader.GetDocuments() {
+			if strings.Contains(input, doc.Path) {
+				summary, err := loader.SummarizeDocument(doc.Path, defaultSummarySentences)
+				if err != nil {
+					return "", err
+				}
+				return summary.Text, nil
+			}
+		}
+
+		// Drop an instruction prefix like "summarize this:"
+		if i := strings.Index(input, ":"); i >= 0 && strings.Contains(strings.ToLower(input[:i]), "summar") {
+			input = input[i+1:]
+		}
+		summary := loader.SummarizeText(input, defaultSummarySentences)
+		if summary.Text == "" {
+			return "", fmt.Errorf("nothing to summarize")
+		}
+		return summary.Text, nil
+	}
+}
+
+// SummarizeRequest is the body of POST /summarize. Exactly one of Text,
+// Document or Turns must be set.
+type SummarizeRequest struct {
+	Text      string   `json:"text,omitempty"`
+	Document  string   `json:"document,omitempty"` // path of a loaded document
+	Turns     []string `json:"turns,omitempty"`    // conversation history, oldest first
+	Sentences int      `json:"sentences,omitempty"`
+}
+
+// SummarizeHandler serves extractive summaries over HTTP
+type SummarizeHandler struct {
+	loader *DatasetLoader
+}
+
+func NewSummarizeHandler(loader *DatasetLoader) *SummarizeHandler {
+	return &SummarizeHandler{loader: loader}
+}
+
+func (h *SummarizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+
+	var req SummarizeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	sources := 0
+	for _, set := range []bool{req.Text != "", req.Document != "", len(req.Turns) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "set exactly one of text, document or turns")
+		return
+	}
+
+	var summary *Summary
+	switch {
+	case req.Text != "":
+		summary = h.loader.SummarizeText(req.Text, req.Sentences)
+	case req.Document != "":
+		var err error
+		summary, err = h.loader.SummarizeDocument(req.Document, req.Sentences)
+		if err != nil {
+			writeAPIError(w, http.StatusNotFound, "not_found_error", err.Error())
+			return
+		}
+	default:
+		summary = h.loader.SummarizeConversation(req.Turns, req.Sentences)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Response templates: a user-supplied library maps intents to canned
+// response templates with slots such as {topic}. When the generator isn't
+// confident in a corpus-driven response, it answers from the template of
+// the intent the input matches instead, and in between it leads with the
+// template and follows with the generated response. Brains without a
+// dataset answer from the intent named after their output meaning. The
+// library is YAML (a block-style subset) or JSON, chosen by extension:
+//
+//	intents:
+//	  - name: greeting
+//	    match: [hello, hi, good morning]
+//	    templates:
+//	      - "Hello! What would you like to know about {topic}?"
+
+// Template modes reported in explanations
+const (
+	templateReplace = "replace" // the template is the response
+	templateBlend   = "blend"   // the template leads the generated response
+)
+
+// templateSlots are the slots templates may use
+var templateSlots = map[string]bool{
+	"input":    true, // the input as given
+	"topic":    true, // the input's top keyphrase, else the first active concept
+	"concepts": true, // up to three active concepts
+	"name":     true, // the first name mentioned in the input
+	"meaning":  true, // the brain's output meaning, for brains without a dataset
+}
+
+var templateSlotPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// TemplateConfig selects the template library and when it is used
+type TemplateConfig struct {
+	Path         string  `json:"path"`          // .yaml, .yml or .json; "" disables templates
+	ReplaceBelow float64 `json:"replace_below"` // confidence below which a template replaces the response
+	BlendBelow   float64 `json:"blend_below"`   // confidence below which a template leads the response
+}
+
+func (c TemplateConfig) validate() error {
+	if c.ReplaceBelow < 0 || c.BlendBelow > 1 || c.ReplaceBelow > c.BlendBelow {
+		return fmt.Errorf("templates need 0 <= replace_below <= blend_below <= 1")
+	}
+	if c.Path != "" {
+		if _, err := LoadTemplateLibrary(c.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TemplateLibrary is a set of intents with response templates
+type TemplateLibrary struct {
+	Intents []TemplateIntent `json:"intents"`
+}
+
+// TemplateIntent is one intent: the inputs it matches and its responses
+type TemplateIntent struct {
+	Name      string   `json:"name"`
+	Match     []string `json:"match"`     // words or phrases that signal the intent
+	Templates []string `json:"templates"` // responses with {slot} placeholders
+}
+
+// TemplateUse records a template shaping a response
+type TemplateUse struct {
+	Intent string `json:"intent"`
+	Mode   string `json:"mode"` // "replace" or "blend"
+}
+
+// LoadTemplateLibrary reads a template library file
+func LoadTemplateLibrary(path string) (*TemplateLibrary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template library: %w", err)
+	}
+	var library TemplateLibrary
+	if err := unmarshalYAMLOrJSON(path, data, &library); err != nil {
+		return nil, fmt.Errorf("failed to parse template library %s: %w", path, err)
+	}
+	if err := library.validate(); err != nil {
+		return nil, fmt.Errorf("invalid template library %s: %w", path, err)
+	}
+	return &library, nil
+}
+
+// templateLibraryFromConfig returns the library configured in config, or
+// nil when none is configured or it can't be loaded
+func templateLibraryFromConfig(config *Config) *TemplateLibrary {
+	if config == nil || config.Templates.Path == "" {
+		return nil
+	}
+	library, err := LoadTemplateLibrary(config.Templates.Path)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v, templates disabled\n", err)
+		return nil
+	}
+	return library
+}
+
+func (l *TemplateLibrary) validate() error {
+	seen := make(map[string]bool, len(l.Intents))
+	for _, intent := range l.Intents {
+		if intent.Name == "" || seen[intent.Name] {
+			return fmt.Errorf("intent names must be unique and non-empty, got %q", intent.Name)
+		}
+		seen[intent.Name] = true
+		if len(intent.Templates) == 0 {
+			return fmt.Errorf("intent %q has no templates", intent.Name)
+		}
+		for _, template := range intent.Templates {
+			for _, slot := range templateSlotPattern.FindAllStringSubmatch(template, -1) {
+				if !templateSlots[slot[1]] {
+					return fmt.Errorf("intent %q: unknown slot {%s}", intent.Name, slot[1])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Intent returns the intent called name
+func (l *TemplateLibrary) Intent(name string) (*TemplateIntent, bool) {
+	if l == nil {
+		return nil, false
+	}
+	for i := range l.Intents {
+		if l.Intents[i].Name == name {
+			return &l.Intents[i], true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the intent whose match words and phrases input mentions
+// most, the first on ties
+func (l *TemplateLibrary) Match(input string) (*TemplateIntent, bool) {
+	if l == nil {
+		return nil, false
+	}
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !(r == '\'' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	padded := " " + strings.Join(words, " ") + " "
+	var best *TemplateIntent
+	bestCount := 0
+	for i := range l.Intents {
+		count := 0
+		for _, phrase := range l.Intents[i].Match {
+			if phrase = strings.Join(strings.Fields(strings.ToLower(phrase)), " "); phrase != "" && strings.Contains(padded, " "+phrase+" ") {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = &l.Intents[i], count
+		}
+	}
+	return best, best != nil
+}
+
+// Render fills one of the intent's templates whose slots are all known,
+// chosen by hashing key so the same input gets the same template. ok is
+// false when none can be filled.
+func (intent *TemplateIntent) Render(key string, slots map[string]string) (string, bool) {
+	var fillable []string
+	for _, template := range intent.Templates {
+		filled := true
+		for _, slot := range templateSlotPattern.FindAllStringSubmatch(template, -1) {
+			if slots[slot[1]] == "" {
+				filled = false
+				break
+			}
+		}
+		if filled {
+			fillable = append(fillable, template)
+		}
+	}
+	if len(fillable) == 0 {
+		return "", false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	template := fillable[h.Sum32()%uint32(len(fillable))]
+	return templateSlotPattern.ReplaceAllStringFunc(template, func(slot string) string {
+		return slots[slot[1:len(slot)-1]]
+	}), true
+}
+
+// Respond renders the template of the intent named meaning, or else of the
+// intent input matches, for brains answering without a dataset
+func (l *TemplateLibrary) Respond(input, meaning string) (string, bool) {
+	intent, ok := l.Intent(meaning)
+	if !ok {
+		if intent, ok = l.Match(input); !ok {
+			return "", false
+		}
+	}
+	slots := map[string]string{"input": strings.TrimSpace(input), "meaning": meaning}
+	if names := (NERModel{}).Names(input); len(names) > 0 {
+		slots["name"] = names[0]
+	}
+	return intent.Render(input+meaning, slots)
+}
+
+// SetTemplates answers from library's templates when the generator isn't
+// confident, as config sets out; a nil library disables templates
+func (gen *ResponseGenerator) SetTemplates(library *TemplateLibrary, config TemplateConfig) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.templates = library
+	gen.templateConfig = config
+}
+
+// templateSlotValues returns the slots for input and the active concepts
+func (gen *ResponseGenerator) templateSlotValues(input string, activeConcepts []string) map[string]string {
+	slots := map[string]string{"input": strings.TrimSpace(input)}
+	if keyphrases := gen.dataLoader.ExtractKeyphrases(input, 1); len(keyphrases) > 0 {
+		slots["topic"] = keyphrases[0].Phrase
+	} else if len(activeConcepts) > 0 {
+		slots["topic"] = activeConcepts[0]
+	}
+	if len(activeConcepts) > 0 {
+		slots["concepts"] = strings.Join(activeConcepts[:min(3, len(activeConcepts))], ", ")
+	}
+	if names := (NERModel{}).Names(input); len(names) > 0 {
+		slots["name"] = names[0]
+	}
+	return slots
+}
+
+// applyTemplate replaces or leads a low-confidence response with the
+// matching intent's template, returning the response
+func (gen *ResponseGenerator) applyTemplate(explanation *Explanation, activeConcepts []string) string {
+	confidence := explanation.Confidence.Score
+	if gen.templates == nil || confidence >= gen.templateConfig.BlendBelow {
+		return explanation.Response
+	}
+	intent, ok := gen.templates.Match(explanation.Input)
+	if !ok {
+		return explanation.Response
+	}
+	text, ok := intent.Render(explanation.Input, gen.templateSlotValues(explanation.Input, activeConcepts))
+	if !ok || gen.bannedIn(text) {
+		return explanation.Response
+	}
+
+	use := &TemplateUse{Intent: intent.Name, Mode: templateReplace}
+	response := text
+	if confidence >= gen.templateConfig.ReplaceBelow && explanation.Response != "" {
+		use.Mode = templateBlend
+		response = text + " " + explanation.Response
+	}
+	explanation.Response = response
+	explanation.Template = use
+	if len(explanation.Alternatives) > 0 {
+		explanation.Alternatives[0].Response = response
+	}
+	return response
+}
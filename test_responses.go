@@ -16,7 +16,7 @@ func TestResponses() {
 	// Test TransparentLLM
 	fmt.Println("1. Testing TransparentLLM responses:")
 	llm := NewTransparentLLMWithConfig(config)
-	defer llm.Cleanup()
+	defer OnShutdown(ShutdownModels, "llm", llm.Cleanup)()
 	
 	testInputs := []string{
 		"hello",
@@ -43,7 +43,7 @@ func TestResponses() {
 	// Test LiquidStateBrain
 	fmt.Println("\n\n2. Testing LiquidStateBrain responses:")
 	brain := NewLiquidStateBrainWithConfig(10, config)
-	defer brain.Cleanup()
+	defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
 	
 	for _, input := range testInputs {
 		fmt.Printf("\n   Input: '%s'\n", input)
@@ -35,10 +35,10 @@ func TestSimple() {
 	// Test 3: TransparentLLM
 	fmt.Println("\n3. Testing TransparentLLM...")
 	llm := NewTransparentLLMWithConfig(config)
-	defer llm.Cleanup()
+	defer OnShutdown(ShutdownModels, "llm", llm.Cleanup)()
 	
-	if len(llm.concepts) > 0 {
-		fmt.Printf("   Initialized with %d concepts\n", len(llm.concepts))
+	if llm.concepts.Len() > 0 {
+		fmt.Printf("   Initialized with %d concepts\n", llm.concepts.Len())
 		
 		// Test a simple input
 		fmt.Println("   Testing input: 'hello world'")
@@ -59,7 +59,7 @@ func TestSimple() {
 	// Test 4: Basic LiquidStateBrain (smaller size)
 	fmt.Println("\n4. Testing LiquidStateBrain...")
 	brain := NewLiquidStateBrainWithConfig(10, config) // Very small brain
-	defer brain.Cleanup()
+	defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
 	
 	fmt.Printf("   Created brain: %dx%dx%d\n", brain.dimensions.X, brain.dimensions.Y, brain.dimensions.Z)
 	
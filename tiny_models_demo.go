@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 // TinyModel represents a small, specialized model that neurons can use
@@ -79,6 +82,113 @@ func (s SentimentModel) Process(input string) (string, float64) {
 func (s SentimentModel) Size() int              { return 50 } // 50MB
 func (s SentimentModel) Latency() time.Duration { return 10 * time.Millisecond }
 
+// NERModel is a tiny named-entity recognizer for person names: runs of
+// capitalized words, and the word after cues like "my name is". Capitalized
+// words that open a sentence count only as part of a longer run.
+type NERModel struct{}
+
+// nameCues precede a name in lower-case text
+var nameCues = []string{"my name is", "i am", "i'm", "this is", "call me", "ask", "contact", "mr", "mrs", "ms", "dr"}
+
+// nonNames are capitalized words that aren't names
+var nonNames = map[string]bool{
+	"I": true, "I'm": true, "I've": true, "I'll": true, "I'd": true,
+	"Monday": true, "Tuesday": true, "Wednesday": true, "Thursday": true, "Friday": true, "Saturday": true, "Sunday": true,
+	"January": true, "February": true, "March": true, "April": true, "June": true, "July": true,
+	"August": true, "September": true, "October": true, "November": true, "December": true,
+}
+
+// nameTitles introduce a name without being part of it
+var nameTitles = map[string]bool{"mr": true, "mrs": true, "ms": true, "dr": true, "ask": true, "contact": true}
+
+func (m NERModel) Process(input string) (string, float64) {
+	names := m.Names(input)
+	if len(names) == 0 {
+		return "", 0.0
+	}
+	return strings.Join(names, ", "), 0.7
+}
+func (m NERModel) Size() int              { return 20 } // 20MB
+func (m NERModel) Latency() time.Duration { return 3 * time.Millisecond }
+
+// Names returns the person names found in text, longest first
+func (m NERModel) Names(text string) []string {
+	words := strings.Fields(text)
+	found := make(map[string]bool)
+
+	sentenceStart := true
+	for i := 0; i < len(words); {
+		word := strings.Trim(words[i], ".,;:!?\"()[]")
+		if !isNameWord(word) {
+			sentenceStart = endsSentence(words[i])
+			i++
+			continue
+		}
+
+		// Collect the run of capitalized words starting here
+		run := []string{word}
+		j := i + 1
+		for j < len(words) && !endsSentence(words[j-1]) {
+			next := strings.Trim(words[j], ".,;:!?\"()[]")
+			if !isNameWord(next) {
+				break
+			}
+			run = append(run, next)
+			j++
+		}
+		if !sentenceStart || len(run) > 1 || followsCue(words[:i]) {
+			found[strings.Join(run, " ")] = true
+		}
+		sentenceStart = endsSentence(words[j-1])
+		i = j
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	// Longest first, so "John Doe" is replaced before "John"
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func isNameWord(word string) bool {
+	if len(word) < 2 || nonNames[word] || nameTitles[strings.ToLower(word)] {
+		return false
+	}
+	runes := []rune(word)
+	if !unicode.IsUpper(runes[0]) {
+		return false
+	}
+	for _, r := range runes[1:] {
+		if !unicode.IsLower(r) && r != '\'' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func endsSentence(word string) bool {
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+}
+
+// followsCue reports whether the words before a candidate end with a name cue
+func followsCue(before []string) bool {
+	tail := strings.ToLower(strings.Join(before[max(0, len(before)-3):], " "))
+	tail = strings.TrimRight(tail, ".,:")
+	for _, cue := range nameCues {
+		if tail == cue || strings.HasSuffix(tail, " "+cue) {
+			return true
+		}
+	}
+	return false
+}
+
 // EnhancedNeuron - A neuron that might have access to a tiny model
 type EnhancedNeuron struct {
 	*LiquidNeuron
@@ -101,13 +211,24 @@ type EnhancedLiquidBrain struct {
 
 // CreateEnhancedBrain - Create a brain where ~1% of neurons have specialized models
 func CreateEnhancedBrain(size int) *EnhancedLiquidBrain {
+	return CreateEnhancedBrainWithConfig(size, DefaultConfig())
+}
+
+// CreateEnhancedBrainWithConfig creates an enhanced brain whose reservoir
+// uses config, or nil when the reservoir can't be built
+func CreateEnhancedBrainWithConfig(size int, config *Config) *EnhancedLiquidBrain {
+	liquid := NewLiquidStateBrainWithConfig(size, config)
+	if liquid == nil {
+		return nil
+	}
 	brain := &EnhancedLiquidBrain{
-		LiquidStateBrain: NewLiquidStateBrain(size),
+		LiquidStateBrain: liquid,
 		enhancedNeurons:  make([]*EnhancedNeuron, 0),
 		modelRegistry: map[string]TinyModel{
 			"math":      MathModel{},
 			"date":      DateModel{},
 			"sentiment": SentimentModel{},
+			"ner":       NERModel{},
 		},
 	}
 	
@@ -144,14 +265,19 @@ func CreateEnhancedBrain(size int) *EnhancedLiquidBrain {
 // ProcessWithModels - Process input where neurons might use tiny models
 func (brain *EnhancedLiquidBrain) ProcessWithModels(input string) string {
 	// First, normal liquid processing
-	// brain.InjectSignal(input) // TODO: implement this method
-	time.Sleep(200 * time.Millisecond) // Let waves propagate
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		brain.injectWord(word)
+	}
+	brain.settle() // Let waves propagate
 	
 	// Enhanced neurons check if they should use their models
-	modelResults := make(chan string, 100)
+	modelResults := make(chan string, len(brain.enhancedNeurons))
+	var wg sync.WaitGroup
 	
 	for _, neuron := range brain.enhancedNeurons {
+		wg.Add(1)
 		go func(n *EnhancedNeuron) {
+			defer wg.Done()
 			activation := n.state.Load().(float64)
 			
 			// Neuron decides whether to use its model
@@ -180,8 +306,8 @@ func (brain *EnhancedLiquidBrain) ProcessWithModels(input string) string {
 		}(neuron)
 	}
 	
-	// Collect model insights
-	time.Sleep(50 * time.Millisecond)
+	// Collect model insights once every neuron has decided
+	wg.Wait()
 	close(modelResults)
 	
 	insights := []string{}
@@ -225,7 +351,7 @@ func DemoTinyModels() {
 	
 	// Create brain where some neurons have specialized models
 	brain := CreateEnhancedBrain(10000)
-	defer brain.Cleanup()
+	defer OnShutdown(ShutdownModels, "liquid brain", brain.Cleanup)()
 	
 	// Test various inputs
 	tests := []struct {
@@ -274,11 +400,13 @@ func DemoTinyModels() {
 		testBrain := CreateEnhancedBrain(size)
 		
 		start := time.Now()
+		before := testBrain.energy.Snapshot()
 		testBrain.ProcessWithModels("calculate the square root of 169 for today's analysis")
 		elapsed := time.Since(start)
 		
 		fmt.Printf("   Processing time: %v\n", elapsed)
 		fmt.Printf("   Model calls: %d\n", testBrain.totalModelCalls.Load())
+		fmt.Printf("   Energy: %s\n", testBrain.energy.Snapshot().Sub(before))
 		
 		// At larger scales, neurons are more selective about using models
 		if size >= 10000 {
@@ -0,0 +1,97 @@
+package main
+
+// Token hooks: integrators can watch, rewrite or stop generation one token
+// at a time without forking the beam search. Hooks registered with OnToken
+// run in two phases. During the search they see every candidate word before
+// it extends a beam (Final false) and can rewrite it, drop it, end the beam
+// after it or abort the search. Once the best beam is chosen they see the
+// response's words in order (Final true), which is the place for live
+// display and for last edits such as profanity masking; there, dropping
+// removes the word and ending or aborting truncates the response.
+
+// TokenAction tells the generator what to do with a token
+type TokenAction int
+
+const (
+	TokenContinue TokenAction = iota // keep the token, as rewritten in the event
+	TokenDrop                        // discard the token
+	TokenEndBeam                     // keep the token and end its beam (custom stop sequences)
+	TokenAbort                       // discard the token and stop generating
+)
+
+// TokenEvent is a token about to be added to a response. Hooks may rewrite
+// Token; an empty Token is dropped.
+type TokenEvent struct {
+	Token  string
+	Prefix []string // words before it in the beam or response; don't modify
+	Score  float64  // the beam search's score for the token; 0 in the final pass
+	Final  bool     // true in the pass over the chosen response
+}
+
+// TokenHook inspects a token and decides what happens to it
+type TokenHook func(event *TokenEvent) TokenAction
+
+// OnToken adds a hook run for every token, after the hooks already added
+func (gen *ResponseGenerator) OnToken(hook TokenHook) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.tokenHooks = append(gen.tokenHooks, hook)
+}
+
+// ClearTokenHooks removes every hook added with OnToken
+func (gen *ResponseGenerator) ClearTokenHooks() {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.tokenHooks = nil
+}
+
+// runTokenHooks passes event through the hooks in order until one doesn't
+// continue. It runs on a call's copy of the generator.
+func (gen *ResponseGenerator) runTokenHooks(event *TokenEvent) TokenAction {
+	for _, hook := range gen.tokenHooks {
+		if action := hook(event); action != TokenContinue {
+			return action
+		}
+		if event.Token == "" {
+			return TokenDrop
+		}
+	}
+	return TokenContinue
+}
+
+// searchToken runs the search-phase hooks on a candidate and returns the
+// word to use and its action; an abort stops the search
+func (gen *ResponseGenerator) searchToken(word string, prefix []string, score float64) (string, TokenAction) {
+	if len(gen.tokenHooks) == 0 {
+		return word, TokenContinue
+	}
+	event := &TokenEvent{Token: word, Prefix: prefix, Score: score}
+	action := gen.runTokenHooks(event)
+	if action == TokenAbort {
+		gen.aborted = true
+	}
+	return event.Token, action
+}
+
+// finalTokens runs the final-pass hooks over a chosen response's words and
+// returns the words kept
+func (gen *ResponseGenerator) finalTokens(words []string) []string {
+	if len(gen.tokenHooks) == 0 {
+		return words
+	}
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		event := &TokenEvent{Token: word, Prefix: kept, Final: true}
+		switch gen.runTokenHooks(event) {
+		case TokenContinue:
+			kept = append(kept, event.Token)
+		case TokenEndBeam:
+			return append(kept, event.Token)
+		case TokenAbort:
+			return kept
+		}
+	}
+	return kept
+}
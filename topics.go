@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Topic discovery clusters word embeddings with spherical k-means (cosine
+// similarity, k-means++ seeding). Each cluster is a topic named after its
+// most frequent, most central words. Stopwords and very short words are left
+// out so clusters form around content.
+
+// Words shown for each topic
+const topicTopWords = 10
+
+// Topic is one cluster of related vocabulary
+type Topic struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Words    []string  `json:"words"` // most representative first
+	Size     int       `json:"size"`
+	Centroid []float64 `json:"-"`
+}
+
+// TopicModel maps vocabulary words to topics. It is immutable once built.
+type TopicModel struct {
+	loader     *DatasetLoader
+	topics     []Topic
+	assignment map[string]int // word -> topic ID
+}
+
+// wordFrequency returns the corpus count of word
+func (dl *DatasetLoader) wordFrequency(word string) float64 {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	return dl.wordFreq[word]
+}
+
+// DiscoverTopics clusters the vocabulary into k topics. The same seed always
+// gives the same topics.
+func (dl *DatasetLoader) DiscoverTopics(k, iterations int, seed int64) (*TopicModel, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("topic count must be positive")
+	}
+	if iterations <= 0 {
+		iterations = 20
+	}
+
+	var words []string
+	var vectors [][]float64
+	for _, word := range dl.GetVocabulary() {
+		if len(word) < 3 || stopWords[word] {
+			continue
+		}
+		if emb, ok := dl.GetEmbedding(word); ok {
+			words = append(words, word)
+			vectors = append(vectors, emb)
+		}
+	}
+	if len(words) < k {
+		return nil, fmt.Errorf("only %d content words for %d topics", len(words), k)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	centroids := seedCentroids(vectors, k, rng)
+	assign := make([]int, len(vectors))
+
+	for iter := 0; iter < iterations; iter++ {
+		changed := 0
+		for i, v := range vectors {
+			best := nearestCentroid(v, centroids)
+			if best != assign[i] || iter == 0 {
+				changed++
+			}
+			assign[i] = best
+		}
+
+		// Recompute centroids as normalized means
+		sums := make([][]float64, k)
+		for c := range sums {
+			sums[c] = make([]float64, len(vectors[0]))
+		}
+		for i, v := range vectors {
+			for d, x := range v {
+				sums[assign[i]][d] += x
+			}
+		}
+		for c := range sums {
+			if normalizeVector(sums[c]) {
+				centroids[c] = sums[c]
+			}
+			// Empty clusters keep their previous centroid
+		}
+
+		if changed == 0 {
+			break
+		}
+	}
+
+	model := &TopicModel{
+		loader:     dl,
+		topics:     make([]Topic, k),
+		assignment: make(map[string]int, len(words)),
+	}
+	members := make([][]string, k)
+	for i, word := range words {
+		model.assignment[word] = assign[i]
+		members[assign[i]] = append(members[assign[i]], word)
+	}
+
+	for c := 0; c < k; c++ {
+		// Rank members by frequency weighted by closeness to the centroid
+		score := make(map[string]float64, len(members[c]))
+		for _, word := range members[c] {
+			emb, _ := dl.GetEmbedding(word)
+			score[word] = math.Log1p(dl.wordFrequency(word)) * (1 + dotProduct(emb, centroids[c]))
+		}
+		ranked := members[c]
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return score[ranked[i]] > score[ranked[j]]
+		})
+
+		top := ranked
+		if len(top) > topicTopWords {
+			top = top[:topicTopWords]
+		}
+		name := top
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		model.topics[c] = Topic{
+			ID:       c,
+			Name:     strings.Join(name, "/"),
+			Words:    append([]string(nil), top...),
+			Size:     len(members[c]),
+			Centroid: centroids[c],
+		}
+	}
+
+	fmt.Printf("🗂️  Discovered %d topics over %d words\n", k, len(words))
+	return model, nil
+}
+
+// seedCentroids picks k initial centroids with k-means++
+func seedCentroids(vectors [][]float64, k int, rng *rand.Rand) [][]float64 {
+	centroids := [][]float64{vectors[rng.Intn(len(vectors))]}
+	dist := make([]float64, len(vectors))
+
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range vectors {
+			// Cosine distance to the nearest chosen centroid, squared
+			d := 1 - dotProduct(v, centroids[nearestCentroid(v, centroids)])
+			dist[i] = d * d
+			total += dist[i]
+		}
+		if total == 0 {
+			centroids = append(centroids, vectors[rng.Intn(len(vectors))])
+			continue
+		}
+		target := rng.Float64() * total
+		for i, d := range dist {
+			target -= d
+			if target <= 0 {
+				centroids = append(centroids, vectors[i])
+				break
+			}
+		}
+		if target > 0 {
+			centroids = append(centroids, vectors[len(vectors)-1])
+		}
+	}
+
+	// Copy so centroid updates never alias the loader's embeddings
+	for c := range centroids {
+		centroids[c] = append([]float64(nil), centroids[c]...)
+	}
+	return centroids
+}
+
+func nearestCentroid(v []float64, centroids [][]float64) int {
+	best, bestSim := 0, math.Inf(-1)
+	for c, centroid := range centroids {
+		if sim := dotProduct(v, centroid); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	return best
+}
+
+// normalizeVector scales v to unit length, reporting false if it is zero
+func normalizeVector(v []float64) bool {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		return false
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] /= norm
+	}
+	return true
+}
+
+// Topics returns every topic. The slice is shared; don't modify it.
+func (tm *TopicModel) Topics() []Topic {
+	return tm.topics
+}
+
+// TopicOf returns the topic ID of word
+func (tm *TopicModel) TopicOf(word string) (int, bool) {
+	id, ok := tm.assignment[strings.ToLower(word)]
+	return id, ok
+}
+
+// TextTopics returns the topic mixture of text: the share of its clustered
+// tokens falling in each topic. All zeros if no token is clustered.
+func (tm *TopicModel) TextTopics(text string) []float64 {
+	mixture := make([]float64, len(tm.topics))
+	counted := 0
+	for _, token := range tm.loader.tokenize(text) {
+		if id, ok := tm.assignment[token]; ok {
+			mixture[id]++
+			counted++
+		}
+	}
+	if counted > 0 {
+		for i := range mixture {
+			mixture[i] /= float64(counted)
+		}
+	}
+	return mixture
+}
+
+// DocumentTopics returns the topic mixture of a loaded document
+func (tm *TopicModel) DocumentTopics(path string) ([]float64, error) {
+	for _, doc := range tm.loader.GetDocuments() {
+		if doc.Path == path {
+			return tm.TextTopics(doc.Content), nil
+		}
+	}
+	return nil, fmt.Errorf("document %s is not loaded", path)
+}
+
+// SetTopicBias makes the generator favour words from one topic: their scores
+// are multiplied by 1+strength. A nil model or strength <= 0 clears the bias.
+func (gen *ResponseGenerator) SetTopicBias(model *TopicModel, topicID int, strength float64) error {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	if model == nil || strength <= 0 {
+		gen.topicBias = nil
+		return nil
+	}
+	if topicID < 0 || topicID >= len(model.topics) {
+		return fmt.Errorf("unknown topic %d", topicID)
+	}
+
+	bias := make(map[string]float64)
+	for word, id := range model.assignment {
+		if id == topicID {
+			bias[word] = 1 + strength
+		}
+	}
+	gen.topicBias = bias
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Inhibition: by default every reservoir synapse excites its target. A
+// configurable fraction of synapses can instead be inhibitory, lowering the
+// target's state when the source fires, which keeps activity from running
+// away and stabilizes the reservoir's dynamics. Under Dale's principle the
+// fraction applies to neurons rather than synapses: each neuron is either
+// excitatory or inhibitory, and all of its outgoing synapses share its sign.
+//
+// Connectivity decides which synapses exist at all: each neuron connects to
+// neurons within a radius with a probability falling off with distance,
+// kept between a minimum and maximum out-degree. Connection density is the
+// biggest single driver of reservoir behavior, from silent to saturated, so
+// the degree distribution is reported after wiring and in the reservoir's
+// metrics, to check the topology matches intent.
+
+// TopologyConfig controls the sign and strength of reservoir synapses
+type TopologyConfig struct {
+	InhibitoryFraction float64            `json:"inhibitory_fraction"` // share of inhibitory neurons (or synapses without Dale's principle)
+	DalesPrinciple     bool               `json:"dales_principle"`     // a neuron's synapses all share its sign
+	ExcitatoryWeight   WeightRange        `json:"excitatory_weight"`   // drawn per spike
+	InhibitoryWeight   WeightRange        `json:"inhibitory_weight"`   // drawn per spike, subtracted from the target
+	Connectivity       ConnectivityConfig `json:"connectivity"`
+}
+
+// ConnectivityConfig controls which reservoir neurons connect. A neuron
+// connects to each neuron within Radius (on every axis) with probability
+// BaseProbability / distance^DistanceFalloff.
+type ConnectivityConfig struct {
+	Radius          int     `json:"radius"`
+	BaseProbability float64 `json:"base_probability"`
+	DistanceFalloff float64 `json:"distance_falloff"` // 0 connects at every distance alike
+	MinOutDegree    int     `json:"min_out_degree"`   // neurons short of it connect to their nearest unconnected neighbors
+	MaxOutDegree    int     `json:"max_out_degree"`   // excess connections are dropped at random; 0 is unlimited
+}
+
+// WeightRange is a uniform range of synaptic strengths
+type WeightRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// defaultConnectivity matches the reservoir before connectivity was
+// configurable
+var defaultConnectivity = ConnectivityConfig{Radius: 2, BaseProbability: 0.3, DistanceFalloff: 1}
+
+// defaultTopology matches the reservoir before inhibition was configurable
+var defaultTopology = TopologyConfig{
+	DalesPrinciple:   true,
+	ExcitatoryWeight: WeightRange{Min: 0.1, Max: 0.5},
+	InhibitoryWeight: WeightRange{Min: 0.1, Max: 0.5},
+	Connectivity:     defaultConnectivity,
+}
+
+// Connection radius limit; the candidates per neuron grow with its cube
+const maxConnectionRadius = 8
+
+func (c TopologyConfig) validate() error {
+	if c.InhibitoryFraction < 0 || c.InhibitoryFraction > 1 {
+		return fmt.Errorf("inhibitory_fraction must be between 0 and 1")
+	}
+	for name, r := range map[string]WeightRange{"excitatory_weight": c.ExcitatoryWeight, "inhibitory_weight": c.InhibitoryWeight} {
+		if r.Min < 0 || r.Min > r.Max || r.Max > 1 {
+			return fmt.Errorf("%s must satisfy 0 <= min <= max <= 1", name)
+		}
+	}
+	return c.Connectivity.validate()
+}
+
+func (c ConnectivityConfig) validate() error {
+	if c == (ConnectivityConfig{}) {
+		return nil
+	}
+	if c.Radius < 1 || c.Radius > maxConnectionRadius {
+		return fmt.Errorf("connectivity radius must be between 1 and %d", maxConnectionRadius)
+	}
+	if c.BaseProbability <= 0 || c.BaseProbability > 1 {
+		return fmt.Errorf("connectivity base_probability must be in (0, 1]")
+	}
+	if c.DistanceFalloff < 0 {
+		return fmt.Errorf("connectivity distance_falloff must not be negative")
+	}
+	if c.MinOutDegree < 0 || c.MaxOutDegree < 0 {
+		return fmt.Errorf("connectivity out-degrees must not be negative")
+	}
+	if c.MaxOutDegree > 0 && c.MinOutDegree > c.MaxOutDegree {
+		return fmt.Errorf("connectivity min_out_degree must not exceed max_out_degree")
+	}
+	if side := 2*c.Radius + 1; c.MinOutDegree > side*side*side-1 {
+		return fmt.Errorf("connectivity min_out_degree %d exceeds the %d neighbors within radius %d", c.MinOutDegree, side*side*side-1, c.Radius)
+	}
+	return nil
+}
+
+// orDefault lets topologies without a connectivity section keep the
+// default
+func (c ConnectivityConfig) orDefault() ConnectivityConfig {
+	if c == (ConnectivityConfig{}) {
+		return defaultConnectivity
+	}
+	return c
+}
+
+// probability is the chance of connecting to a neuron distance away
+func (c ConnectivityConfig) probability(distance float64) float64 {
+	return math.Min(1, c.BaseProbability/math.Pow(distance, c.DistanceFalloff))
+}
+
+// orDefault lets configs without a topology section keep the default
+func (c TopologyConfig) orDefault() TopologyConfig {
+	if c == (TopologyConfig{}) {
+		return defaultTopology
+	}
+	return c
+}
+
+// draw returns a random strength in the range
+func (r WeightRange) draw() float64 {
+	return r.Min + rand.Float64()*(r.Max-r.Min)
+}
+
+// synapseStrength returns a spike's signed strength for connection i
+func (n *LiquidNeuron) synapseStrength(i int) float64 {
+	topology := n.topology
+	if topology == nil {
+		topology = &defaultTopology
+	}
+	if n.inhibitory != nil && n.inhibitory[i] {
+		return -topology.InhibitoryWeight.draw()
+	}
+	return topology.ExcitatoryWeight.draw()
+}
+
+// assignInhibition marks the reservoir's inhibitory synapses, per neuron
+// under Dale's principle and per synapse otherwise
+func (brain *LiquidStateBrain) assignInhibition() {
+	topology := brain.topology
+	if topology.InhibitoryFraction == 0 {
+		return
+	}
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for _, neuron := range brain.reservoir[x][y] {
+				inhibitory := brain.rng.Float64() < topology.InhibitoryFraction
+				neuron.inhibitory = make([]bool, len(neuron.connections))
+				for i := range neuron.inhibitory {
+					if !topology.DalesPrinciple {
+						inhibitory = brain.rng.Float64() < topology.InhibitoryFraction
+					}
+					neuron.inhibitory[i] = inhibitory
+				}
+			}
+		}
+	}
+}
+
+// SynapseCounts returns how many reservoir synapses excite and inhibit
+// their targets
+func (brain *LiquidStateBrain) SynapseCounts() (excitatory, inhibitory int) {
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for _, neuron := range brain.reservoir[x][y] {
+				for i := range neuron.connections {
+					if neuron.inhibitory != nil && neuron.inhibitory[i] {
+						inhibitory++
+					} else {
+						excitatory++
+					}
+				}
+			}
+		}
+	}
+	return excitatory, inhibitory
+}
+
+// fillConnections connects neuron to its nearest unconnected neighbors
+// within the radius until it reaches the minimum out-degree, or runs out of
+// neighbors near the reservoir's edge. Equally near neighbors are picked at
+// random.
+func (brain *LiquidStateBrain) fillConnections(neuron *LiquidNeuron, connectivity ConnectivityConfig) {
+	connected := make(map[*LiquidNeuron]bool, len(neuron.connections))
+	for _, c := range neuron.connections {
+		connected[c] = true
+	}
+	type candidate struct {
+		neuron   *LiquidNeuron
+		distance int // squared
+	}
+	var candidates []candidate
+	r := connectivity.Radius
+	for dx := -r; dx <= r; dx++ {
+		for dy := -r; dy <= r; dy++ {
+			for dz := -r; dz <= r; dz++ {
+				x, y, z := neuron.x+dx, neuron.y+dy, neuron.z+dz
+				if x < 0 || x >= brain.dimensions.X || y < 0 || y >= brain.dimensions.Y || z < 0 || z >= brain.dimensions.Z {
+					continue
+				}
+				if n := brain.reservoir[x][y][z]; n != neuron && !connected[n] {
+					candidates = append(candidates, candidate{n, dx*dx + dy*dy + dz*dz})
+				}
+			}
+		}
+	}
+	brain.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	for _, c := range candidates {
+		if len(neuron.connections) >= connectivity.MinOutDegree {
+			break
+		}
+		neuron.connections = append(neuron.connections, c.neuron)
+	}
+}
+
+// DegreeStats summarizes a degree distribution
+type DegreeStats struct {
+	Min       int     `json:"min"`
+	Max       int     `json:"max"`
+	Mean      float64 `json:"mean"`
+	StdDev    float64 `json:"stddev"`
+	Histogram []int   `json:"histogram"` // neurons with each degree, from 0 to Max
+}
+
+// ConnectivityStats describes the reservoir's wiring
+type ConnectivityStats struct {
+	Synapses  int         `json:"synapses"`
+	Density   float64     `json:"density"` // synapses over the possible neuron pairs
+	OutDegree DegreeStats `json:"out_degree"`
+	InDegree  DegreeStats `json:"in_degree"`
+	Isolated  int         `json:"isolated"` // neurons with no synapse in either direction
+}
+
+func (s ConnectivityStats) String() string {
+	return fmt.Sprintf("%d synapses, out-degree %d-%d (mean %.1f ± %.1f), in-degree %d-%d (mean %.1f ± %.1f), %d isolated",
+		s.Synapses, s.OutDegree.Min, s.OutDegree.Max, s.OutDegree.Mean, s.OutDegree.StdDev,
+		s.InDegree.Min, s.InDegree.Max, s.InDegree.Mean, s.InDegree.StdDev, s.Isolated)
+}
+
+// ConnectivityStats measures the reservoir's wiring
+func (brain *LiquidStateBrain) ConnectivityStats() ConnectivityStats {
+	var out []int
+	in := make(map[*LiquidNeuron]int)
+	var neurons []*LiquidNeuron
+	for x := range brain.reservoir {
+		for y := range brain.reservoir[x] {
+			for _, neuron := range brain.reservoir[x][y] {
+				neurons = append(neurons, neuron)
+				out = append(out, len(neuron.connections))
+				for _, target := range neuron.connections {
+					in[target]++
+				}
+			}
+		}
+	}
+
+	var stats ConnectivityStats
+	inDegrees := make([]int, len(neurons))
+	for i, neuron := range neurons {
+		inDegrees[i] = in[neuron]
+		stats.Synapses += out[i]
+		if out[i] == 0 && inDegrees[i] == 0 {
+			stats.Isolated++
+		}
+	}
+	if n := len(neurons); n > 1 {
+		stats.Density = float64(stats.Synapses) / float64(n*(n-1))
+	}
+	stats.OutDegree = degreeStats(out)
+	stats.InDegree = degreeStats(inDegrees)
+	return stats
+}
+
+// degreeStats summarizes degrees
+func degreeStats(degrees []int) DegreeStats {
+	if len(degrees) == 0 {
+		return DegreeStats{}
+	}
+	stats := DegreeStats{Min: degrees[0], Max: degrees[0]}
+	sum := 0
+	for _, d := range degrees {
+		stats.Min = min(stats.Min, d)
+		stats.Max = max(stats.Max, d)
+		sum += d
+	}
+	stats.Mean = float64(sum) / float64(len(degrees))
+	stats.Histogram = make([]int, stats.Max+1)
+	variance := 0.0
+	for _, d := range degrees {
+		stats.Histogram[d]++
+		variance += (float64(d) - stats.Mean) * (float64(d) - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(degrees)))
+	return stats
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Trace JSON: thoughts, circuits, orchestrator decisions and parallel flow
+// decisions all serialize to one versioned format, so the server, dashboard,
+// decision store and replay tooling read and write the same objects. Every
+// object carries the schema name and its kind; consumers should reject a
+// schema they don't know rather than guess at its fields.
+//
+//	circuit:       {schema, kind, nodes[], strength, meaning, timestamp}
+//	thought:       {schema, kind, stage, insight, circuits[], explanation?}
+//	decision:      {schema, kind, input, path[], reasoning, output, timestamp, energy, confidence, sandbox[]?}
+//	flow_decision: {schema, kind, neuron_id, activation, decision, confidence, timestamp}
+//	flow_run:      {schema, kind, input, thresholds[], capabilities[], connections[][], activations[],
+//	                injected[], gains[]?, decisions[], pattern, consensus, result, timestamp}
+
+// traceSchema names the current version of the trace format; bump it when
+// a field changes meaning or is removed
+const traceSchema = "genesis.trace/v1"
+
+// Kinds of trace objects
+const (
+	traceKindCircuit      = "circuit"
+	traceKindThought      = "thought"
+	traceKindDecision     = "decision"
+	traceKindFlowDecision = "flow_decision"
+	traceKindFlowRun      = "flow_run"
+)
+
+// traceHeader starts every trace object
+type traceHeader struct {
+	Schema string `json:"schema"`
+	Kind   string `json:"kind"`
+}
+
+// check rejects objects of another schema version or kind
+func (h traceHeader) check(kind string) error {
+	if h.Schema != traceSchema {
+		return fmt.Errorf("unsupported trace schema %q (want %q)", h.Schema, traceSchema)
+	}
+	if h.Kind != kind {
+		return fmt.Errorf("trace object is a %q, not a %q", h.Kind, kind)
+	}
+	return nil
+}
+
+type circuitJSON struct {
+	traceHeader
+	Nodes     []string  `json:"nodes"` // concept ids, in firing order
+	Strength  float64   `json:"strength"`
+	Meaning   string    `json:"meaning"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MarshalJSON encodes the circuit with its nodes as concept ids
+func (c CircuitPath) MarshalJSON() ([]byte, error) {
+	nodes := make([]string, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		if node != nil {
+			nodes = append(nodes, node.id)
+		}
+	}
+	return json.Marshal(circuitJSON{
+		traceHeader: traceHeader{traceSchema, traceKindCircuit},
+		Nodes:       nodes,
+		Strength:    c.strength,
+		Meaning:     c.meaning,
+		Timestamp:   c.timestamp,
+	})
+}
+
+// UnmarshalJSON decodes a circuit. Its nodes are bare neurons carrying only
+// their concept id; they aren't wired into any network.
+func (c *CircuitPath) UnmarshalJSON(data []byte) error {
+	var v circuitJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(traceKindCircuit); err != nil {
+		return err
+	}
+	nodes := make([]*ConceptNeuron, len(v.Nodes))
+	for i, id := range v.Nodes {
+		nodes[i] = &ConceptNeuron{id: id}
+	}
+	*c = CircuitPath{nodes: nodes, strength: v.Strength, meaning: v.Meaning, timestamp: v.Timestamp}
+	return nil
+}
+
+type thoughtJSON struct {
+	traceHeader
+	Stage       string        `json:"stage"`
+	Insight     string        `json:"insight"`
+	Circuits    []CircuitPath `json:"circuits"`
+	Explanation *Explanation  `json:"explanation,omitempty"`
+}
+
+// MarshalJSON encodes the thought and the circuits it traced
+func (t ThoughtTrace) MarshalJSON() ([]byte, error) {
+	circuits := t.circuits
+	if circuits == nil {
+		circuits = []CircuitPath{}
+	}
+	return json.Marshal(thoughtJSON{
+		traceHeader: traceHeader{traceSchema, traceKindThought},
+		Stage:       t.stage,
+		Insight:     t.insight,
+		Circuits:    circuits,
+		Explanation: t.explanation,
+	})
+}
+
+// UnmarshalJSON decodes a thought
+func (t *ThoughtTrace) UnmarshalJSON(data []byte) error {
+	var v thoughtJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(traceKindThought); err != nil {
+		return err
+	}
+	*t = ThoughtTrace{stage: v.Stage, insight: v.Insight, circuits: v.Circuits, explanation: v.Explanation}
+	return nil
+}
+
+type decisionJSON struct {
+	traceHeader
+	Input      string             `json:"input"`
+	Path       []string           `json:"path"` // capabilities the query went through
+	Reasoning  string             `json:"reasoning"`
+	Output     string             `json:"output"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Energy     EnergyReport       `json:"energy"`
+	Confidence Confidence         `json:"confidence"`
+	Policy     string             `json:"policy,omitempty"`  // capability policy the step obeyed
+	Sandbox    []SandboxViolation `json:"sandbox,omitempty"` // capability limits the step broke
+}
+
+// MarshalJSON encodes the decision in the trace format
+func (d Decision) MarshalJSON() ([]byte, error) {
+	path := d.Path
+	if path == nil {
+		path = []string{}
+	}
+	return json.Marshal(decisionJSON{
+		traceHeader: traceHeader{traceSchema, traceKindDecision},
+		Input:       d.Input,
+		Path:        path,
+		Reasoning:   d.Reasoning,
+		Output:      d.Output,
+		Timestamp:   d.Timestamp,
+		Energy:      d.Energy,
+		Confidence:  d.Confidence,
+		Policy:      d.Policy,
+		Sandbox:     d.Sandbox,
+	})
+}
+
+// UnmarshalJSON decodes a decision
+func (d *Decision) UnmarshalJSON(data []byte) error {
+	var v decisionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(traceKindDecision); err != nil {
+		return err
+	}
+	*d = Decision{
+		Input:      v.Input,
+		Path:       v.Path,
+		Reasoning:  v.Reasoning,
+		Output:     v.Output,
+		Timestamp:  v.Timestamp,
+		Energy:     v.Energy,
+		Confidence: v.Confidence,
+		Policy:     v.Policy,
+		Sandbox:    v.Sandbox,
+	}
+	return nil
+}
+
+type flowDecisionJSON struct {
+	traceHeader
+	NeuronID   int       `json:"neuron_id"`
+	Activation float64   `json:"activation"`
+	Decision   string    `json:"decision"`
+	Confidence float64   `json:"confidence"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// MarshalJSON encodes the flow decision in the trace format
+func (d FlowDecision) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flowDecisionJSON{
+		traceHeader: traceHeader{traceSchema, traceKindFlowDecision},
+		NeuronID:    d.NeuronID,
+		Activation:  d.Activation,
+		Decision:    d.Decision,
+		Confidence:  d.Confidence,
+		Timestamp:   d.Timestamp,
+	})
+}
+
+// UnmarshalJSON decodes a flow decision
+func (d *FlowDecision) UnmarshalJSON(data []byte) error {
+	var v flowDecisionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(traceKindFlowDecision); err != nil {
+		return err
+	}
+	*d = FlowDecision{
+		NeuronID:   v.NeuronID,
+		Activation: v.Activation,
+		Decision:   v.Decision,
+		Confidence: v.Confidence,
+		Timestamp:  v.Timestamp,
+	}
+	return nil
+}
+
+type flowRunJSON struct {
+	traceHeader
+	Input        string          `json:"input"`
+	Thresholds   []float64       `json:"thresholds"`
+	Capabilities []string        `json:"capabilities"`
+	Connections  [][]int         `json:"connections"`
+	Activations  []float64       `json:"activations"`
+	Injected     []int           `json:"injected"`
+	Gains        []float64       `json:"gains,omitempty"`
+	Decisions    []FlowDecision  `json:"decisions"`
+	Pattern      FlowPattern     `json:"pattern"`
+	Consensus    string          `json:"consensus"`
+	Result       ConsensusResult `json:"result"`
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// MarshalJSON encodes the run in the trace format
+func (r FlowRun) MarshalJSON() ([]byte, error) {
+	decisions := r.Decisions
+	if decisions == nil {
+		decisions = []FlowDecision{}
+	}
+	return json.Marshal(flowRunJSON{
+		traceHeader:  traceHeader{traceSchema, traceKindFlowRun},
+		Input:        r.Input,
+		Thresholds:   r.Thresholds,
+		Capabilities: r.Capabilities,
+		Connections:  r.Connections,
+		Activations:  r.Activations,
+		Injected:     r.Injected,
+		Gains:        r.Gains,
+		Decisions:    decisions,
+		Pattern:      r.Pattern,
+		Consensus:    r.Consensus,
+		Result:       r.Result,
+		Timestamp:    r.Timestamp,
+	})
+}
+
+// UnmarshalJSON decodes a run
+func (r *FlowRun) UnmarshalJSON(data []byte) error {
+	var v flowRunJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(traceKindFlowRun); err != nil {
+		return err
+	}
+	*r = FlowRun{
+		Input:        v.Input,
+		Thresholds:   v.Thresholds,
+		Capabilities: v.Capabilities,
+		Connections:  v.Connections,
+		Activations:  v.Activations,
+		Injected:     v.Injected,
+		Gains:        v.Gains,
+		Decisions:    v.Decisions,
+		Pattern:      v.Pattern,
+		Consensus:    v.Consensus,
+		Result:       v.Result,
+		Timestamp:    v.Timestamp,
+	}
+	return nil
+}
@@ -2,15 +2,14 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"math"
 	"os"
-	"os/signal"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
@@ -46,12 +45,18 @@ func (tm *TrainingMetrics) String() string {
 
 // ModelTrainer handles training for different model types
 type ModelTrainer struct {
-	config         *Config
-	transparentLLM *TransparentLLM
-	liquidBrain    *LiquidStateBrain
-	dataLoader     *DatasetLoader
-	metrics        *TrainingMetrics
-	stopChan       chan struct{}
+	config     *Config
+	model      Model
+	dataLoader *DatasetLoader
+	metrics    *TrainingMetrics
+	stopChan   chan struct{}
+	run        *TrackedRun // nil unless the run is tracked
+}
+
+// conceptModel is implemented by models that expose concept activations,
+// so evaluation can check the target concept directly
+type conceptModel interface {
+	ConceptActivation(concept string) float64
 }
 
 func NewModelTrainer(configPath string) (*ModelTrainer, error) {
@@ -65,24 +70,19 @@ func NewModelTrainer(configPath string) (*ModelTrainer, error) {
 		return nil, fmt.Errorf("failed to load datasets: %w", err)
 	}
 
-	trainer := &ModelTrainer{
+	// Initialize the selected model (liquid brains are 30x30x15)
+	model, err := NewModel(config, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModelTrainer{
 		config:     config,
+		model:      model,
 		dataLoader: dataLoader,
 		metrics:    &TrainingMetrics{},
 		stopChan:   make(chan struct{}),
-	}
-
-	// Initialize the selected model
-	switch config.Model.Type {
-	case "transparent":
-		trainer.transparentLLM = NewTransparentLLMWithConfig(config)
-	case "liquid":
-		trainer.liquidBrain = NewLiquidStateBrainWithConfig(30, config) // 30x30x15 brain
-	default:
-		return nil, fmt.Errorf("unknown model type: %s", config.Model.Type)
-	}
-
-	return trainer, nil
+	}, nil
 }
 
 func (mt *ModelTrainer) Train(epochs int) error {
@@ -122,16 +122,7 @@ func (mt *ModelTrainer) runEpoch(epoch int, batches []TrainingBatch) {
 		for j, context := range batch.Inputs {
 			target := batch.Targets[j]
 			
-			// Process based on model type
-			var predicted string
-			var responseTime time.Duration
-			
-			switch mt.config.Model.Type {
-			case "transparent":
-				predicted, responseTime = mt.evaluateTransparent(context, target)
-			case "liquid":
-				predicted, responseTime = mt.evaluateLiquid(context, target)
-			}
+			predicted, responseTime := mt.evaluate(context, target)
 
 			correct := predicted == target
 			mt.metrics.Update(correct, responseTime)
@@ -145,50 +136,36 @@ func (mt *ModelTrainer) runEpoch(epoch int, batches []TrainingBatch) {
 
 	epochAccuracy := float64(correctPredictions) / float64(totalPredictions)
 	epochDuration := time.Since(epochStart)
+	mt.run.LogMetricStep("epoch_accuracy", epoch, epochAccuracy)
+	mt.run.LogMetricStep("epoch_seconds", epoch, epochDuration.Seconds())
 	
 	fmt.Printf("  Epoch %d complete - Accuracy: %.2f%% - Duration: %v\n",
 		epoch, epochAccuracy*100, epochDuration)
 }
 
-func (mt *ModelTrainer) evaluateTransparent(context []string, target string) (string, time.Duration) {
+func (mt *ModelTrainer) evaluate(window []string, target string) (string, time.Duration) {
 	start := time.Now()
 	
-	// Create input from context
-	input := strings.Join(context, " ")
+	// Create input from the context window
+	input := strings.Join(window, " ")
 	
 	// Get response
-	response, thoughtChan := mt.transparentLLM.Understand(input)
-	
-	// Drain thought channel
-	go func() {
-		for range thoughtChan {
-		}
-	}()
-	
-	// For now, return whether the model activated the target concept
-	targetNeuron := mt.transparentLLM.concepts[target]
-	if targetNeuron != nil && targetNeuron.getActivation() > 0.5 {
-		return target, time.Since(start)
+	response, err := mt.model.Respond(context.Background(), Request{Input: input})
+	if err != nil {
+		return "", time.Since(start)
 	}
 	
-	return response, time.Since(start)
-}
-
-func (mt *ModelTrainer) evaluateLiquid(context []string, target string) (string, time.Duration) {
-	start := time.Now()
-	
-	// Create input from context
-	input := strings.Join(context, " ")
-	
-	// Get response
-	response := mt.liquidBrain.Think(input)
-	
-	// Check if response contains target word
-	if strings.Contains(strings.ToLower(response), target) {
+	// Models with concepts predict the target by activating it; the others
+	// by saying it
+	if concepts, ok := mt.model.(conceptModel); ok {
+		if concepts.ConceptActivation(target) > 0.5 {
+			return target, time.Since(start)
+		}
+	} else if strings.Contains(strings.ToLower(response.Output), target) {
 		return target, time.Since(start)
 	}
 	
-	return response, time.Since(start)
+	return response.Output, time.Since(start)
 }
 
 func (mt *ModelTrainer) InteractiveTest() {
@@ -212,36 +189,37 @@ func (mt *ModelTrainer) InteractiveTest() {
 
 		start := time.Now()
 		
-		switch mt.config.Model.Type {
-		case "transparent":
-			response, thoughtChan := mt.transparentLLM.Understand(input)
-			
-			// Show thought process
-			fmt.Println("\nThought process:")
-			for range thoughtChan {
-				// Already visualized in the model
-			}
-			
-			fmt.Printf("\nResponse: %s\n", response)
-			
-		case "liquid":
-			response := mt.liquidBrain.Think(input)
-			fmt.Printf("\nResponse: %s\n", response)
+		// Models show their own thought process as they respond
+		response, err := mt.model.Respond(context.Background(), Request{Input: input})
+		if err != nil {
+			fmt.Printf("\nError: %v\n", err)
+			continue
 		}
+		fmt.Printf("\nResponse: %s\n", response.Output)
 		
 		fmt.Printf("Response time: %v\n", time.Since(start))
 	}
 }
 
+// recordRun records the final metrics and a checkpoint of the trained
+// model, and finishes the tracked run
+func (mt *ModelTrainer) recordRun() {
+	mt.metrics.mu.RLock()
+	mt.run.LogMetric("accuracy", mt.metrics.Accuracy)
+	mt.run.LogMetric("examples", float64(mt.metrics.TotalExamples))
+	mt.metrics.mu.RUnlock()
+	
+	err := mt.run.SaveArtifact("model.json", mt.model.Save)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	mt.run.Finish(nil)
+}
+
 func (mt *ModelTrainer) Cleanup() {
 	close(mt.stopChan)
 	
-	if mt.transparentLLM != nil {
-		mt.transparentLLM.Cleanup()
-	}
-	if mt.liquidBrain != nil {
-		mt.liquidBrain.Cleanup()
-	}
+	mt.model.Close()
 }
 
 // Main training entry point
@@ -262,29 +240,24 @@ func TrainMain() {
 	if err != nil {
 		log.Fatalf("Failed to create trainer: %v", err)
 	}
-	defer trainer.Cleanup()
-
-	// Handle graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	
-	go func() {
-		<-sigChan
-		fmt.Println("\nShutting down gracefully...")
-		trainer.Cleanup()
-		os.Exit(0)
-	}()
+	defer OnShutdown(ShutdownModels, "trainer", trainer.Cleanup)()
 
 	// Run training or test mode
 	if testMode {
 		trainer.InteractiveTest()
 	} else {
+		trainer.run = TrackRun(trainer.config, "train", map[string]string{
+			"model":  trainer.config.Model.Type,
+			"epochs": fmt.Sprint(epochs),
+		})
 		if err := trainer.Train(epochs); err != nil {
+			trainer.run.Finish(err)
 			log.Fatalf("Training failed: %v", err)
 		}
 		
 		// Show final metrics
 		fmt.Printf("\nFinal metrics: %s\n", trainer.metrics)
+		trainer.recordRun()
 		
 		// Optional: run interactive test after training
 		fmt.Println("\nTraining complete. Starting interactive test mode...")
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Transition table persistence: counting every bigram of the corpus
+// dominates startup for large corpora and gives the same tables every run,
+// so with a cache directory configured the raw transition, starter and
+// ender counts are saved after the first build. Later runs with the same
+// corpus skip the build and load the tables on first use instead, so a
+// process that never generates never pays for them. The cache is keyed by
+// a fingerprint of every document and the vocabulary, which is everything
+// the tables are computed from; any change to either rebuilds. Counts are
+// saved rather than probabilities so AddDocument keeps working after a
+// load. The tables use gob rather than the JSON of checkpoints, since
+// decoding maps this large from JSON would cost most of what the cache
+// saves.
+
+// Bumped whenever the cached format or what goes into the tables changes
+const transitionCacheVersion = 2
+
+// transitionTables is the file written to the cache directory
+type transitionTables struct {
+	Version     int
+	Fingerprint string
+	Counts      map[string]map[string]float64 // word -> next word -> count
+	Trigrams    map[string]map[string]float64 // "previous word" -> next word -> count
+	Starters    map[string]float64
+	Enders      map[string]bool
+}
+
+// corpusFingerprint hashes the documents and vocabulary the transition
+// tables are built from, and whether they include trigrams. Callers must
+// hold dl.mu.
+func (dl *DatasetLoader) corpusFingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\x00trigrams=%t\x00", transitionCacheVersion, dl.trigramWeight > 0)
+	for _, doc := range dl.documents {
+		fmt.Fprintf(h, "%s\x00%d\x00%s\x00", doc.Path, len(doc.Content), doc.Content)
+	}
+	if view := dl.vocabView.Load(); view != nil {
+		for _, word := range view.words {
+			fmt.Fprintf(h, "%s\x00", word)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// transitionCachePath returns where tables for fingerprint are cached in dir
+func transitionCachePath(dir, fingerprint string) string {
+	return filepath.Join(dir, "transitions-"+fingerprint[:16]+".gob")
+}
+
+// loadOrBuildTransitions defers to cached tables in cacheDir when they
+// match the corpus, and otherwise builds the tables and caches them
+func (dl *DatasetLoader) loadOrBuildTransitions(cacheDir string) {
+	if cacheDir == "" {
+		dl.buildTransitions()
+		return
+	}
+
+	dl.mu.Lock()
+	fingerprint := dl.corpusFingerprint()
+	path := transitionCachePath(cacheDir, fingerprint)
+	if _, err := os.Stat(path); err == nil {
+		dl.tablesPath, dl.tablesFingerprint = path, fingerprint
+		dl.buildLanguageModels()
+		dl.mu.Unlock()
+		fmt.Printf("💾 Transition tables cached at %s, loading on first use\n", path)
+		return
+	}
+	dl.mu.Unlock()
+
+	dl.buildTransitions()
+	if err := dl.saveTransitions(path, fingerprint); err != nil {
+		fmt.Printf("⚠️  Warning: failed to cache transition tables: %v\n", err)
+		return
+	}
+	fmt.Printf("💾 Cached transition tables at %s\n", path)
+}
+
+// ensureTables loads cached transition tables the first time they are
+// needed, rebuilding them if the cache can't be read. Callers must not
+// hold dl.mu.
+func (dl *DatasetLoader) ensureTables() {
+	if dl.tablesPath == "" {
+		return
+	}
+	dl.tablesOnce.Do(func() {
+		if err := dl.loadTransitions(dl.tablesPath, dl.tablesFingerprint); err != nil {
+			fmt.Printf("⚠️  Warning: failed to load cached transition tables, rebuilding: %v\n", err)
+			dl.buildTransitions()
+		}
+	})
+}
+
+// saveTransitions writes the raw counts to path atomically and removes
+// tables cached for other corpora
+func (dl *DatasetLoader) saveTransitions(path, fingerprint string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	dl.mu.RLock()
+	err = gob.NewEncoder(file).Encode(transitionTables{
+		Version:     transitionCacheVersion,
+		Fingerprint: fingerprint,
+		Counts:      dl.transitionCounts,
+		Trigrams:    dl.trigramCounts,
+		Starters:    dl.starterCounts,
+		Enders:      dl.enders,
+	})
+	dl.mu.RUnlock()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to encode transition tables: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write transition tables: %w", err)
+	}
+
+	stale, _ := filepath.Glob(filepath.Join(dir, "transitions-*.gob"))
+	for _, other := range stale {
+		if other != path {
+			os.Remove(other)
+		}
+	}
+	return nil
+}
+
+// loadTransitions publishes the tables cached at path, which must have been
+// built from the corpus with fingerprint
+func (dl *DatasetLoader) loadTransitions(path, fingerprint string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var tables transitionTables
+	if err := gob.NewDecoder(file).Decode(&tables); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if tables.Version != transitionCacheVersion || tables.Fingerprint != fingerprint {
+		return fmt.Errorf("%s was cached for another corpus or format", path)
+	}
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	dl.transitionCounts = tables.Counts
+	dl.trigramCounts = tables.Trigrams
+	dl.starterCounts = tables.Starters
+	dl.enders = tables.Enders
+	if dl.transitionCounts == nil {
+		dl.transitionCounts = make(map[string]map[string]float64)
+	}
+	if dl.trigramCounts == nil {
+		dl.trigramCounts = make(map[string]map[string]float64)
+	}
+	if dl.starterCounts == nil {
+		dl.starterCounts = make(map[string]float64)
+	}
+	if dl.enders == nil {
+		dl.enders = make(map[string]bool)
+	}
+	dl.publishAllTransitions()
+	fmt.Printf("💾 Loaded transition tables for %d words from %s\n", len(dl.transitionCounts), path)
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -55,7 +56,7 @@ func RunLiquidBrainDemo() {
 			size, size, size/2, size*size*(size/2))
 		
 		brain := NewLiquidStateBrain(size)
-		time.Sleep(500 * time.Millisecond) // Let it stabilize
+		brain.settle() // Let it stabilize
 		
 		// Test inputs
 		inputs := []string{
@@ -113,29 +114,35 @@ func RunComparisonDemo() {
 	
 	input := "I need help understanding why my code isn't working"
 	
-	// Transparent LLM
-	fmt.Println("\n1️⃣ TRANSPARENT LLM PROCESSING:")
-	fmt.Println("────────────────────────────")
 	llm := NewTransparentLLM()
-	_, thoughtStream := llm.Understand(input)
-	
-	for _ = range thoughtStream {
-		time.Sleep(50 * time.Millisecond)
+	if llm == nil {
+		fmt.Println("❌ Failed to create transparent LLM")
+		return
 	}
-	
-	// Liquid Brain
-	fmt.Println("\n2️⃣ LIQUID BRAIN PROCESSING:")
-	fmt.Println("──────────────────────────")
+	defer llm.Cleanup()
 	brain := NewLiquidStateBrain(25)
-	time.Sleep(200 * time.Millisecond)
-	
-	result := brain.Think(input)
-	fmt.Printf("\n%s\n", result)
+	if brain == nil {
+		fmt.Println("❌ Failed to create liquid brain")
+		return
+	}
+	defer brain.Cleanup()
+	brain.settle()
+	orchestrator := NewGenesisOrchestrator(25)
+	defer orchestrator.Close()
+	
+	// All three answer at once
+	comparison := CompareModels(context.Background(), []ComparedModel{
+		{Name: "transparent", Model: llm},
+		{Name: "liquid", Model: brain},
+		{Name: "orchestrator", Model: orchestrator},
+	}, Request{Input: input})
+	comparison.Print()
 	
 	// Show the difference
 	fmt.Println("\n📊 KEY DIFFERENCES:")
 	fmt.Println("• Transparent LLM: Shows exact concept connections")
 	fmt.Println("• Liquid Brain: Shows emergent wave patterns")
+	fmt.Println("• Orchestrator: Routes to capabilities when the brain is unsure")
 	fmt.Println("• Transparent: Traceable reasoning paths")
 	fmt.Println("• Liquid: Holographic, distributed understanding")
 }
@@ -177,10 +184,11 @@ func RunMassiveScaleDemo() {
 		
 		// Test response time
 		testStart := time.Now()
-		_ = brain.Think("hello")
+		_, energy := brain.ThinkMetered("hello")
 		elapsed := time.Since(testStart)
 		
 		fmt.Printf("Response time: %v\n", elapsed)
+		fmt.Printf("Energy: %s\n", energy)
 		fmt.Printf("Active waves: %d\n", atomic.LoadInt64(&brain.activeWaves))
 		
 		// Show that it still works smoothly
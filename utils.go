@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"runtime"
 	"time"
 )
@@ -53,49 +52,12 @@ func max(a, b int) int {
 	return b
 }
 
-// GracefulShutdown provides a way to handle cleanup during shutdown
-type GracefulShutdown struct {
-	cleanupFuncs []func()
-	timeout      time.Duration
-}
-
-func NewGracefulShutdown(timeout time.Duration) *GracefulShutdown {
-	return &GracefulShutdown{
-		cleanupFuncs: make([]func(), 0),
-		timeout:      timeout,
-	}
-}
-
-func (gs *GracefulShutdown) AddCleanup(fn func()) {
-	gs.cleanupFuncs = append(gs.cleanupFuncs, fn)
-}
-
-func (gs *GracefulShutdown) Shutdown() {
-	fmt.Println("🔄 Starting graceful shutdown...")
-	
-	done := make(chan struct{})
-	go func() {
-		for i, cleanup := range gs.cleanupFuncs {
-			fmt.Printf("🧹 Running cleanup %d/%d\n", i+1, len(gs.cleanupFuncs))
-			cleanup()
-		}
-		close(done)
-	}()
-	
-	select {
-	case <-done:
-		fmt.Println("✅ Graceful shutdown completed")
-	case <-time.After(gs.timeout):
-		fmt.Println("⚠️  Shutdown timeout exceeded, forcing exit")
-		os.Exit(1)
-	}
-}
-
 // ResourceMonitor tracks system resources to prevent overloads
 type ResourceMonitor struct {
 	maxMemoryMB   int
 	checkInterval time.Duration
 	shutdown      chan bool
+	degrader      *Degrader // optional, told the memory pressure on each check
 }
 
 func NewResourceMonitor(maxMemoryMB int, checkInterval time.Duration) *ResourceMonitor {
@@ -106,6 +68,11 @@ func NewResourceMonitor(maxMemoryMB int, checkInterval time.Duration) *ResourceM
 	}
 }
 
+// SetDegrader reports each check's memory pressure to degrader
+func (rm *ResourceMonitor) SetDegrader(degrader *Degrader) {
+	rm.degrader = degrader
+}
+
 func (rm *ResourceMonitor) Start() {
 	SafeGoroutine("resource-monitor", func() {
 		ticker := time.NewTicker(rm.checkInterval)
@@ -131,7 +98,9 @@ func (rm *ResourceMonitor) Start() {
 					if newMemoryMB > rm.maxMemoryMB {
 						fmt.Printf("🆘 CRITICAL: Memory still high after GC: %d MB\n", newMemoryMB)
 					}
+					currentMemoryMB = newMemoryMB
 				}
+				rm.degrader.Observe(float64(currentMemoryMB) / float64(rm.maxMemoryMB))
 			}
 		}
 	})
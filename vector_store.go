@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vector stores hold the retrieval index's chunk embeddings. The in-memory
+// store is the default; it can be persisted to a file so a corpus indexed
+// once is reused on the next run. The Qdrant and SQLite-vec stores keep the
+// vectors outside the process so several Genesis processes share one index.
+// Scores are cosine similarities (higher is better) for every backend.
+
+// VectorRecord is a stored vector with string metadata
+type VectorRecord struct {
+	ID       int
+	Vector   []float64
+	Metadata map[string]string
+}
+
+// VectorHit is a search result. Hit.Record.Vector may be nil for remote stores.
+type VectorHit struct {
+	Record VectorRecord
+	Score  float64
+}
+
+// VectorStore is a nearest-neighbour index over VectorRecords
+type VectorStore interface {
+	// Upsert inserts records, replacing any with the same ID
+	Upsert(ctx context.Context, records []VectorRecord) error
+	// Search returns the k records most similar to vector, best first
+	Search(ctx context.Context, vector []float64, k int) ([]VectorHit, error)
+	// Count returns the number of stored records
+	Count(ctx context.Context) (int, error)
+	// All returns every stored record, with its vector, in ID order
+	All(ctx context.Context) ([]VectorRecord, error)
+	// Reset removes every record
+	Reset(ctx context.Context) error
+	Close() error
+}
+
+// VectorStoreConfig selects and configures a vector store backend
+type VectorStoreConfig struct {
+	Type       string `json:"type"`                 // "memory" (default), "qdrant" or "sqlite-vec"
+	Path       string `json:"path,omitempty"`       // memory: persistence file; sqlite-vec: database DSN
+	URL        string `json:"url,omitempty"`        // qdrant: base URL, e.g. http://localhost:6333
+	APIKey     string `json:"api_key,omitempty"`    // qdrant: optional api-key header
+	Collection string `json:"collection,omitempty"` // qdrant collection or sqlite-vec table name; the retrieval index appends its corpus fingerprint
+	Driver     string `json:"driver,omitempty"`     // sqlite-vec: database/sql driver name, e.g. "sqlite3"
+}
+
+// Collection/table name used when none is configured
+const defaultVectorCollection = "genesis_chunks"
+
+// NewVectorStore creates the store described by config
+func NewVectorStore(config VectorStoreConfig) (VectorStore, error) {
+	collection := config.Collection
+	if collection == "" {
+		collection = defaultVectorCollection
+	}
+
+	switch config.Type {
+	case "", "memory":
+		if config.Path == "" {
+			return NewMemoryVectorStore(), nil
+		}
+		return OpenMemoryVectorStore(config.Path)
+	case "qdrant":
+		if config.URL == "" {
+			return nil, fmt.Errorf("qdrant vector store needs a url")
+		}
+		return NewQdrantVectorStore(config.URL, collection, config.APIKey), nil
+	case "sqlite-vec":
+		if config.Driver == "" || config.Path == "" {
+			return nil, fmt.Errorf("sqlite-vec vector store needs a driver and path")
+		}
+		db, err := sql.Open(config.Driver, config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", config.Path, err)
+		}
+		store, err := NewSQLiteVecStore(db, collection)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		store.ownsDB = true
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown vector store type %q", config.Type)
+	}
+}
+
+// MemoryVectorStore is a brute-force in-process store. With a path it loads
+// from and saves to a gob file.
+type MemoryVectorStore struct {
+	mu      sync.RWMutex
+	records map[int]VectorRecord
+	path    string // persistence file, empty for none
+}
+
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{records: make(map[int]VectorRecord)}
+}
+
+// OpenMemoryVectorStore loads the store persisted at path, or starts empty
+// if the file doesn't exist yet. Changes are saved on Close.
+func OpenMemoryVectorStore(path string) (*MemoryVectorStore, error) {
+	store := NewMemoryVectorStore()
+	store.path = path
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector store %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var records []VectorRecord
+	if err := gob.NewDecoder(f).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to read vector store %s: %w", path, err)
+	}
+	for _, r := range records {
+		store.records[r.ID] = r
+	}
+	return store, nil
+}
+
+func (s *MemoryVectorStore) Upsert(ctx context.Context, records []VectorRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return nil
+}
+
+func (s *MemoryVectorStore) Search(ctx context.Context, vector []float64, k int) ([]VectorHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits := make([]VectorHit, 0, len(s.records))
+	for _, r := range s.records {
+		hits = append(hits, VectorHit{Record: r, Score: dotProduct(vector, r.Vector)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Record.ID < hits[j].Record.ID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+func (s *MemoryVectorStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.records), nil
+}
+
+func (s *MemoryVectorStore) All(ctx context.Context) ([]VectorRecord, error) {
+	s.mu.RLock()
+	records := make([]VectorRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+func (s *MemoryVectorStore) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = make(map[int]VectorRecord)
+	return nil
+}
+
+// Save writes the store to its persistence file, if it has one
+func (s *MemoryVectorStore) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	records, _ := s.All(context.Background())
+
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to save vector store: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(records); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save vector store: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to save vector store: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *MemoryVectorStore) Close() error {
+	return s.Save()
+}
+
+// QdrantVectorStore talks to a Qdrant server over its REST API. The
+// collection is created with cosine distance on the first upsert.
+type QdrantVectorStore struct {
+	baseURL    string
+	collection string
+	apiKey     string
+	client     *http.Client
+}
+
+func NewQdrantVectorStore(baseURL, collection, apiKey string) *QdrantVectorStore {
+	return &QdrantVectorStore{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		collection: collection,
+		apiKey:     apiKey,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a JSON request and decodes the "result" field of the response into out
+func (q *QdrantVectorStore) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, q.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("qdrant %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, nil
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("qdrant %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		envelope := struct {
+			Result json.RawMessage `json:"result"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			return resp.StatusCode, fmt.Errorf("qdrant %s %s: bad response: %w", method, path, err)
+		}
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("qdrant %s %s: bad result: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (q *QdrantVectorStore) collectionPath() string {
+	return "/collections/" + q.collection
+}
+
+func (q *QdrantVectorStore) ensureCollection(ctx context.Context, dim int) error {
+	status, err := q.do(ctx, http.MethodGet, q.collectionPath(), nil, nil)
+	if err != nil || status != http.StatusNotFound {
+		return err
+	}
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": dim, "distance": "Cosine"},
+	}
+	_, err = q.do(ctx, http.MethodPut, q.collectionPath(), body, nil)
+	return err
+}
+
+type qdrantPoint struct {
+	ID      int               `json:"id"`
+	Vector  []float64         `json:"vector,omitempty"`
+	Payload map[string]string `json:"payload,omitempty"`
+	Score   float64           `json:"score,omitempty"`
+}
+
+func (q *QdrantVectorStore) Upsert(ctx context.Context, records []VectorRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := q.ensureCollection(ctx, len(records[0].Vector)); err != nil {
+		return err
+	}
+
+	points := make([]qdrantPoint, len(records))
+	for i, r := range records {
+		points[i] = qdrantPoint{ID: r.ID, Vector: r.Vector, Payload: r.Metadata}
+	}
+	_, err := q.do(ctx, http.MethodPut, q.collectionPath()+"/points?wait=true", map[string]interface{}{"points": points}, nil)
+	return err
+}
+
+func (q *QdrantVectorStore) Search(ctx context.Context, vector []float64, k int) ([]VectorHit, error) {
+	var points []qdrantPoint
+	body := map[string]interface{}{"vector": vector, "limit": k, "with_payload": true}
+	status, err := q.do(ctx, http.MethodPost, q.collectionPath()+"/points/search", body, &points)
+	if err != nil || status == http.StatusNotFound {
+		return nil, err
+	}
+
+	hits := make([]VectorHit, len(points))
+	for i, p := range points {
+		hits[i] = VectorHit{Record: VectorRecord{ID: p.ID, Metadata: p.Payload}, Score: p.Score}
+	}
+	return hits, nil
+}
+
+func (q *QdrantVectorStore) Count(ctx context.Context) (int, error) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	status, err := q.do(ctx, http.MethodPost, q.collectionPath()+"/points/count", map[string]bool{"exact": true}, &result)
+	if err != nil || status == http.StatusNotFound {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// Points fetched per scroll request
+const qdrantScrollPage = 256
+
+func (q *QdrantVectorStore) All(ctx context.Context) ([]VectorRecord, error) {
+	var records []VectorRecord
+	var offset interface{}
+	for {
+		var page struct {
+			Points []qdrantPoint `json:"points"`
+			Next   interface{}   `json:"next_page_offset"`
+		}
+		body := map[string]interface{}{"limit": qdrantScrollPage, "with_payload": true, "with_vector": true}
+		if offset != nil {
+			body["offset"] = offset
+		}
+		status, err := q.do(ctx, http.MethodPost, q.collectionPath()+"/points/scroll", body, &page)
+		if err != nil || status == http.StatusNotFound {
+			return nil, err
+		}
+		for _, p := range page.Points {
+			records = append(records, VectorRecord{ID: p.ID, Vector: p.Vector, Metadata: p.Payload})
+		}
+		if page.Next == nil || len(page.Points) == 0 {
+			break
+		}
+		offset = page.Next
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+func (q *QdrantVectorStore) Reset(ctx context.Context) error {
+	_, err := q.do(ctx, http.MethodDelete, q.collectionPath(), nil, nil)
+	return err
+}
+
+func (q *QdrantVectorStore) Close() error {
+	return nil
+}
+
+// SQLiteVecStore keeps vectors in a sqlite-vec vec0 virtual table. It works
+// with any database/sql SQLite driver that has the sqlite-vec extension
+// loaded. Genesis links mattn/go-sqlite3 as "sqlite3" but doesn't load the
+// extension itself; register it first (for example with sqlite_vec.Auto()).
+type SQLiteVecStore struct {
+	db     *sql.DB
+	table  string
+	ownsDB bool // close db on Close
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLiteVecStore uses table (plus table_meta for metadata) in db. The
+// vector table is created with the right dimension on the first upsert.
+func NewSQLiteVecStore(db *sql.DB, table string) (*SQLiteVecStore, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	store := &SQLiteVecStore{db: db, table: table}
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_meta (id INTEGER PRIMARY KEY, metadata TEXT NOT NULL)`, table)); err != nil {
+		return nil, fmt.Errorf("failed to create %s_meta: %w", table, err)
+	}
+	return store, nil
+}
+
+// encodeFloat32Blob packs vec the way sqlite-vec expects float vectors
+func encodeFloat32Blob(vec []float64) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return buf
+}
+
+// decodeFloat32Blob is the inverse of encodeFloat32Blob
+func decodeFloat32Blob(buf []byte) []float64 {
+	vec := make([]float64, len(buf)/4)
+	for i := range vec {
+		vec[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:])))
+	}
+	return vec
+}
+
+func (s *SQLiteVecStore) Upsert(ctx context.Context, records []VectorRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	create := fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING vec0(id INTEGER PRIMARY KEY, embedding float[%d] distance_metric=cosine)`,
+		s.table, len(records[0].Vector))
+	if _, err := s.db.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to create %s (is sqlite-vec loaded?): %w", s.table, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		meta, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return err
+		}
+		// vec0 tables don't support upserts, so replace explicitly
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table), r.ID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (id, embedding) VALUES (?, ?)`, s.table), r.ID, encodeFloat32Blob(r.Vector)); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT OR REPLACE INTO %s_meta (id, metadata) VALUES (?, ?)`, s.table), r.ID, string(meta)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteVecStore) Search(ctx context.Context, vector []float64, k int) ([]VectorHit, error) {
+	query := fmt.Sprintf(`SELECT v.id, v.distance, m.metadata
+		FROM (SELECT id, distance FROM %s WHERE embedding MATCH ? AND k = ?) v
+		JOIN %s_meta m ON m.id = v.id
+		ORDER BY v.distance`, s.table, s.table)
+	rows, err := s.db.QueryContext(ctx, query, encodeFloat32Blob(vector), k)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite-vec search: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []VectorHit
+	for rows.Next() {
+		var id int
+		var distance float64
+		var meta string
+		if err := rows.Scan(&id, &distance, &meta); err != nil {
+			return nil, err
+		}
+		record := VectorRecord{ID: id}
+		if err := json.Unmarshal([]byte(meta), &record.Metadata); err != nil {
+			return nil, fmt.Errorf("bad metadata for %d: %w", id, err)
+		}
+		// Cosine distance is 1 - similarity
+		hits = append(hits, VectorHit{Record: record, Score: 1 - distance})
+	}
+	return hits, rows.Err()
+}
+
+func (s *SQLiteVecStore) Count(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*) FROM %s_meta`, s.table)).Scan(&n)
+	return n, err
+}
+
+func (s *SQLiteVecStore) All(ctx context.Context) ([]VectorRecord, error) {
+	// The vector table only exists once something was upserted
+	if n, err := s.Count(ctx); err != nil || n == 0 {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT v.id, v.embedding, m.metadata
+		FROM %s v JOIN %s_meta m ON m.id = v.id
+		ORDER BY v.id`, s.table, s.table)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite-vec load: %w", err)
+	}
+	defer rows.Close()
+
+	var records []VectorRecord
+	for rows.Next() {
+		var record VectorRecord
+		var embedding []byte
+		var meta string
+		if err := rows.Scan(&record.ID, &embedding, &meta); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(meta), &record.Metadata); err != nil {
+			return nil, fmt.Errorf("bad metadata for %d: %w", record.ID, err)
+		}
+		record.Vector = decodeFloat32Blob(embedding)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteVecStore) Reset(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, s.table)); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s_meta`, s.table))
+	return err
+}
+
+func (s *SQLiteVecStore) Close() error {
+	if s.ownsDB {
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Wave visualization: a frame is a 2D view of the reservoir taken across one
+// axis, either a single plane or the strongest neuron along the axis (the
+// old top view), scaled down to a character resolution by keeping each
+// block's strongest neuron. Cells are drawn from a configurable character
+// set, weakest first, optionally in ANSI color, and recent wave origins are
+// marked. The console log and GET /admin/waves render the same frames; the
+// latter also returns the raw intensities for graphical dashboards.
+
+// VisualizationConfig controls rendered wave frames
+type VisualizationConfig struct {
+	Axis    string `json:"axis"`    // "x", "y" or "z": the axis the view looks along
+	Plane   int    `json:"plane"`   // index along the axis; -1 shows the strongest neuron across it
+	Width   int    `json:"width"`   // columns at most
+	Height  int    `json:"height"`  // rows at most
+	Charset string `json:"charset"` // one character per intensity level, weakest first
+	Origin  string `json:"origin"`  // marks where a recent wave started
+	Color   bool   `json:"color"`   // ANSI color by intensity
+}
+
+// defaultVisualization is the 40x10 top view across all layers
+var defaultVisualization = VisualizationConfig{
+	Axis:    "z",
+	Plane:   -1,
+	Width:   40,
+	Height:  10,
+	Charset: " ·○◉●",
+	Origin:  "✦",
+}
+
+// How long a wave's origin stays marked
+const waveOriginWindow = time.Second
+
+func (c VisualizationConfig) validate() error {
+	if c == (VisualizationConfig{}) {
+		return nil
+	}
+	switch c.Axis {
+	case "", "x", "y", "z":
+	default:
+		return fmt.Errorf("visualization axis must be x, y or z, got %q", c.Axis)
+	}
+	if c.Plane < -1 {
+		return fmt.Errorf("visualization plane must be -1 or a layer index")
+	}
+	if c.Width < 0 || c.Height < 0 {
+		return fmt.Errorf("visualization width and height must not be negative")
+	}
+	if c.Charset != "" && len([]rune(c.Charset)) < 2 {
+		return fmt.Errorf("visualization charset needs at least two characters")
+	}
+	return nil
+}
+
+// orDefault fills unset fields from the default view; configs without a
+// visualization section keep the old top view
+func (c VisualizationConfig) orDefault() VisualizationConfig {
+	if c == (VisualizationConfig{}) {
+		return defaultVisualization
+	}
+	if c.Axis == "" {
+		c.Axis = defaultVisualization.Axis
+	}
+	if c.Width == 0 {
+		c.Width = defaultVisualization.Width
+	}
+	if c.Height == 0 {
+		c.Height = defaultVisualization.Height
+	}
+	if c.Charset == "" {
+		c.Charset = defaultVisualization.Charset
+	}
+	if c.Origin == "" {
+		c.Origin = defaultVisualization.Origin
+	}
+	return c
+}
+
+// WaveFrame is one rendered view of the reservoir
+type WaveFrame struct {
+	Axis      string      `json:"axis"`
+	Plane     int         `json:"plane"`
+	Width     int         `json:"width"`
+	Height    int         `json:"height"`
+	Intensity [][]float64 `json:"intensity"` // [row][column], each cell's strongest neuron
+	Origins   [][2]int    `json:"origins"`   // [column, row] of recent wave origins
+	Rows      []string    `json:"rows"`      // the frame as text
+}
+
+// String returns the frame's text, one line per row
+func (f *WaveFrame) String() string {
+	return strings.Join(f.Rows, "\n")
+}
+
+// ANSI colors for intensity levels, weakest first
+var waveColors = []string{"34", "36", "32", "33", "31"}
+
+const (
+	waveOriginColor = "1;35"
+	ansiReset       = "\033[0m"
+)
+
+// waveOrigins remembers where recent waves started
+type waveOrigins struct {
+	mu    sync.Mutex
+	times map[[3]int]time.Time
+}
+
+// record marks origin as started at t
+func (o *waveOrigins) record(origin [3]int, t time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.times == nil {
+		o.times = make(map[[3]int]time.Time)
+	}
+	o.times[origin] = t
+}
+
+// recent returns the origins of waves started within waveOriginWindow of
+// now, forgetting older ones
+func (o *waveOrigins) recent(now time.Time) [][3]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var origins [][3]int
+	for origin, t := range o.times {
+		if now.Sub(t) < waveOriginWindow {
+			origins = append(origins, origin)
+		} else {
+			delete(o.times, origin)
+		}
+	}
+	return origins
+}
+
+// viewAxes returns the reservoir axes (0=x, 1=y, 2=z) shown as columns and
+// rows when looking along axis
+func viewAxes(axis string) (col, row, depth int) {
+	switch axis {
+	case "x":
+		return 1, 2, 0
+	case "y":
+		return 0, 2, 1
+	default:
+		return 0, 1, 2
+	}
+}
+
+// RenderWaveFrame renders the reservoir's current activity as configured;
+// zero fields in config take the default view's
+func (brain *LiquidStateBrain) RenderWaveFrame(config VisualizationConfig) (*WaveFrame, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	config = config.orDefault()
+	size := [3]int{brain.dimensions.X, brain.dimensions.Y, brain.dimensions.Z}
+	colAxis, rowAxis, depthAxis := viewAxes(config.Axis)
+	if config.Plane >= size[depthAxis] {
+		return nil, fmt.Errorf("visualization plane %d is outside the reservoir's %d %s layers", config.Plane, size[depthAxis], config.Axis)
+	}
+	cols, rows := min(config.Width, size[colAxis]), min(config.Height, size[rowAxis])
+	frame := &WaveFrame{
+		Axis:      config.Axis,
+		Plane:     config.Plane,
+		Width:     cols,
+		Height:    rows,
+		Intensity: make([][]float64, rows),
+	}
+	for r := range frame.Intensity {
+		frame.Intensity[r] = make([]float64, cols)
+	}
+
+	// Each cell keeps the strongest neuron of its block of the plane
+	depthFrom, depthTo := 0, size[depthAxis]
+	if config.Plane >= 0 {
+		depthFrom, depthTo = config.Plane, config.Plane+1
+	}
+	var pos [3]int
+	for pos[0] = 0; pos[0] < size[0]; pos[0]++ {
+		for pos[1] = 0; pos[1] < size[1]; pos[1]++ {
+			for pos[2] = 0; pos[2] < size[2]; pos[2]++ {
+				if pos[depthAxis] < depthFrom || pos[depthAxis] >= depthTo {
+					continue
+				}
+				val := brain.reservoir[pos[0]][pos[1]][pos[2]].state.Load()
+				if val == nil {
+					continue
+				}
+				c, r := pos[colAxis]*cols/size[colAxis], pos[rowAxis]*rows/size[rowAxis]
+				if v := val.(float64); v > frame.Intensity[r][c] {
+					frame.Intensity[r][c] = v
+				}
+			}
+		}
+	}
+
+	// Mark where recent waves started, if they lie in the plane
+	marked := make(map[[2]int]bool)
+	for _, origin := range brain.waveOrigins.recent(brain.clock.Now()) {
+		if origin[depthAxis] < depthFrom || origin[depthAxis] >= depthTo {
+			continue
+		}
+		cell := [2]int{origin[colAxis] * cols / size[colAxis], origin[rowAxis] * rows / size[rowAxis]}
+		if !marked[cell] {
+			marked[cell] = true
+			frame.Origins = append(frame.Origins, cell)
+		}
+	}
+
+	charset := []rune(config.Charset)
+	frame.Rows = make([]string, rows)
+	for r := range frame.Rows {
+		var line strings.Builder
+		for c := 0; c < cols; c++ {
+			if marked[[2]int{c, r}] {
+				line.WriteString(colorize(config.Origin, waveOriginColor, config.Color))
+				continue
+			}
+			level := int(frame.Intensity[r][c] * float64(len(charset)))
+			level = max(0, min(level, len(charset)-1))
+			color := waveColors[level*len(waveColors)/len(charset)]
+			line.WriteString(colorize(string(charset[level]), color, config.Color && level > 0))
+		}
+		frame.Rows[r] = line.String()
+	}
+	return frame, nil
+}
+
+// colorize wraps s in an ANSI color when on
+func colorize(s, color string, on bool) string {
+	if !on {
+		return s
+	}
+	return "\033[" + color + "m" + s + ansiReset
+}
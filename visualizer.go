@@ -85,6 +85,8 @@ type EvolutionLogger struct {
 	evolution    *Evolution
 	logFrequency int
 	history      []float64
+	run          *TrackedRun // nil unless the run is tracked
+	name         string      // prefix of the run's metrics
 }
 
 func NewEvolutionLogger(evolution *Evolution) *EvolutionLogger {
@@ -95,8 +97,17 @@ func NewEvolutionLogger(evolution *Evolution) *EvolutionLogger {
 	}
 }
 
+// Track records the evolution's fitness in run, as metrics named after
+// the experiment
+func (el *EvolutionLogger) Track(run *TrackedRun, name string) *EvolutionLogger {
+	el.run = run
+	el.name = name
+	return el
+}
+
 func (el *EvolutionLogger) LogGeneration(generation int) {
 	el.history = append(el.history, el.evolution.bestFitness)
+	el.run.LogMetricStep(el.name+"_best_fitness", generation, el.evolution.bestFitness)
 	
 	if generation%el.logFrequency == 0 || generation == 0 {
 		fmt.Printf("\nGeneration %d:\n", generation)
@@ -165,6 +176,9 @@ func (el *EvolutionLogger) PrintFitnessHistory() {
 
 func (el *EvolutionLogger) PrintFinalReport() {
 	fmt.Println("\n=== Evolution Complete ===")
+	if el.run != nil && el.evolution.bestCircuit != nil {
+		el.run.LogMetric(el.name+"_gates", float64(len(el.evolution.bestCircuit.gates)))
+	}
 	
 	if el.evolution.bestCircuit != nil {
 		visualizer := NewCircuitVisualizer(el.evolution.bestCircuit)
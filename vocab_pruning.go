@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Vocabulary pruning: beyond the MinWordFreq cut, words can be pruned by
+// how many documents they appear in and by TF-IDF band before the vocabulary
+// is truncated to MaxVocabSize. Document frequency bounds drop one-document
+// oddities and words so common they carry no topic; the TF-IDF band does the
+// same weighted by how much a word dominates the document it is most
+// important to. A keep-list protects words generation relies on, such as
+// starters, from every cut including truncation, and a drop-list removes
+// words outright. Pruned words get no embeddings or transitions, so a tighter
+// vocabulary saves memory as well as steering generation to content words.
+
+// VocabularyPruning narrows the vocabulary; the zero value prunes nothing
+type VocabularyPruning struct {
+	MinDocFreq      int      // documents a word must appear in
+	MaxDocFreqRatio float64  // drop words in more than this share of documents; 0 keeps them
+	MinTFIDF        float64  // drop words whose best TF-IDF in any document is lower
+	MaxTFIDF        float64  // drop words whose best TF-IDF is higher; 0 is unbounded
+	Keep            []string // always kept if seen, truncation included
+	Drop            []string // never kept
+}
+
+func (p VocabularyPruning) validate() error {
+	if p.MinDocFreq < 0 || p.MinTFIDF < 0 || p.MaxTFIDF < 0 {
+		return fmt.Errorf("vocabulary pruning bounds must not be negative")
+	}
+	if p.MaxDocFreqRatio < 0 || p.MaxDocFreqRatio > 1 {
+		return fmt.Errorf("vocabulary pruning MaxDocFreqRatio must be between 0 and 1")
+	}
+	if p.MaxTFIDF > 0 && p.MaxTFIDF < p.MinTFIDF {
+		return fmt.Errorf("vocabulary pruning MaxTFIDF must not be below MinTFIDF")
+	}
+	return nil
+}
+
+// weighted reports whether pruning needs document statistics
+func (p VocabularyPruning) weighted() bool {
+	return p.MinDocFreq > 0 || p.MaxDocFreqRatio > 0 || p.MinTFIDF > 0 || p.MaxTFIDF > 0
+}
+
+// PruneStats counts the words each cut removed
+type PruneStats struct {
+	Candidates int // words at or above MinWordFreq
+	DocFreq    int
+	TFIDF      int
+	Dropped    int
+	Truncated  int
+	Kept       int // keep-list words that bypassed a cut
+}
+
+// documentWeights returns each word's document frequency and its highest
+// TF-IDF in any document. IDF is smoothed, log((1+N)/(1+df)) + 1, so words
+// in every document keep a positive weight. Callers must hold dl.mu.
+func (dl *DatasetLoader) documentWeights() (map[string]int, map[string]float64) {
+	df := make(map[string]int)
+	counts := make([]map[string]int, len(dl.documents))
+	for i, doc := range dl.documents {
+		counts[i] = make(map[string]int)
+		for _, token := range doc.Tokens {
+			if counts[i][token] == 0 {
+				df[token]++
+			}
+			counts[i][token]++
+		}
+	}
+
+	n := float64(len(dl.documents))
+	tfidf := make(map[string]float64, len(df))
+	for i, doc := range dl.documents {
+		length := float64(len(doc.Tokens))
+		for word, count := range counts[i] {
+			idf := math.Log((1+n)/(1+float64(df[word]))) + 1
+			tfidf[word] = math.Max(tfidf[word], float64(count)/length*idf)
+		}
+	}
+	return df, tfidf
+}
+
+// prune filters candidate words by document frequency, TF-IDF band and the
+// drop-list, sparing keep-list words. Callers must hold dl.mu.
+func (dl *DatasetLoader) prune(words []string, pruning VocabularyPruning, keep map[string]bool, stats *PruneStats) []string {
+	drop := make(map[string]bool, len(pruning.Drop))
+	for _, word := range pruning.Drop {
+		drop[word] = true
+	}
+	var df map[string]int
+	var tfidf map[string]float64
+	if pruning.weighted() {
+		df, tfidf = dl.documentWeights()
+	}
+	maxDocs := len(dl.documents)
+	if pruning.MaxDocFreqRatio > 0 {
+		maxDocs = int(pruning.MaxDocFreqRatio * float64(len(dl.documents)))
+	}
+
+	kept := words[:0]
+	for _, word := range words {
+		cut := ""
+		switch {
+		case drop[word]:
+			stats.Dropped++
+			continue // the drop-list wins over the keep-list
+		case df != nil && (df[word] < pruning.MinDocFreq || df[word] > maxDocs):
+			cut = "doc freq"
+		case tfidf != nil && (tfidf[word] < pruning.MinTFIDF || (pruning.MaxTFIDF > 0 && tfidf[word] > pruning.MaxTFIDF)):
+			cut = "tf-idf"
+		}
+		if cut != "" && keep[word] {
+			stats.Kept++
+			cut = ""
+		}
+		switch cut {
+		case "doc freq":
+			stats.DocFreq++
+		case "tf-idf":
+			stats.TFIDF++
+		default:
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// truncateVocabulary keeps the n most frequent of the ranked words, except
+// that keep-list words take the places of the least frequent others
+func truncateVocabulary(ranked []string, n int, keep map[string]bool) []string {
+	kept := 0
+	for _, word := range ranked {
+		if keep[word] {
+			kept++
+		}
+	}
+	others := n - min(kept, n)
+	truncated := make([]string, 0, n)
+	for _, word := range ranked {
+		if len(truncated) == n {
+			break
+		}
+		if keep[word] {
+			truncated = append(truncated, word)
+		} else if others > 0 {
+			truncated = append(truncated, word)
+			others--
+		}
+	}
+	return truncated
+}
+
+// PruneStats reports what the last vocabulary build pruned
+func (dl *DatasetLoader) PruneStats() PruneStats {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+	return dl.pruneStats
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MatchingConfig controls typo-tolerant matching of input words against
+// the vocabulary during injection and concept activation
+type MatchingConfig struct {
+	MaxEditDistance    int     `json:"max_edit_distance"`   // 0 disables edit-distance matching
+	MinSimilarity      float64 `json:"min_similarity"`      // 1 - distance/length needed for a typo match
+	MinWordLength      int     `json:"min_word_length"`     // shorter words must match exactly
+	Phonetic           string  `json:"phonetic"`            // "" or "soundex"
+	PhoneticSimilarity float64 `json:"phonetic_similarity"` // score for words that sound alike
+}
+
+func (c MatchingConfig) validate() error {
+	if c.MaxEditDistance < 0 || c.MinWordLength < 0 {
+		return fmt.Errorf("max_edit_distance and min_word_length must not be negative")
+	}
+	if c.MinSimilarity < 0 || c.MinSimilarity > 1 || c.PhoneticSimilarity < 0 || c.PhoneticSimilarity > 1 {
+		return fmt.Errorf("min_similarity and phonetic_similarity must be between 0 and 1")
+	}
+	switch c.Phonetic {
+	case "", "soundex":
+	default:
+		return fmt.Errorf("unknown phonetic algorithm %q", c.Phonetic)
+	}
+	return nil
+}
+
+// WordMatcher scores how likely an input word is a misspelling of a
+// vocabulary word. A nil *WordMatcher matches nothing, so callers can hold
+// one unconditionally.
+type WordMatcher struct {
+	config MatchingConfig
+}
+
+// NewWordMatcher returns a matcher, or nil when config enables no matching
+func NewWordMatcher(config MatchingConfig) *WordMatcher {
+	if config.MaxEditDistance <= 0 && config.Phonetic == "" {
+		return nil
+	}
+	return &WordMatcher{config: config}
+}
+
+// newMatcherFromConfig returns the matcher configured in config, if any
+func newMatcherFromConfig(config *Config) *WordMatcher {
+	if config == nil {
+		return nil
+	}
+	return NewWordMatcher(config.Matching)
+}
+
+// Similarity returns a score in [0, 1] for input as a spelling of word:
+// 1 for an exact match, 1 - distance/length for close typos, the phonetic
+// score for words that sound alike, and 0 otherwise
+func (m *WordMatcher) Similarity(input, word string) float64 {
+	if m == nil {
+		return 0
+	}
+	input = strings.ToLower(strings.Trim(input, ".,;:!?\"'()"))
+	word = strings.ToLower(word)
+	if input == word {
+		return 1
+	}
+	if len(input) < m.config.MinWordLength || len(word) < m.config.MinWordLength {
+		return 0
+	}
+
+	score := 0.0
+	if m.config.MaxEditDistance > 0 {
+		if d := boundedEditDistance(input, word, m.config.MaxEditDistance); d <= m.config.MaxEditDistance {
+			if s := 1 - float64(d)/float64(max(len(input), len(word))); s >= m.config.MinSimilarity {
+				score = s
+			}
+		}
+	}
+	if m.config.Phonetic == "soundex" && soundex(input) == soundex(word) {
+		score = math.Max(score, m.config.PhoneticSimilarity)
+	}
+	return score
+}
+
+// boundedEditDistance returns the Damerau-Levenshtein (optimal string
+// alignment) distance between a and b, or limit+1 once it must exceed limit
+func boundedEditDistance(a, b string, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// soundexCodes maps letters to their Soundex digit; vowels and h, w, y are 0
+var soundexCodes = [26]byte{
+	'0', '1', '2', '3', '0', '1', '2', '0', '0', '2', '2', '4', '5',
+	'5', '0', '1', '2', '6', '2', '3', '0', '1', '0', '2', '0', '2',
+}
+
+// soundex returns the four-character American Soundex code of word, or ""
+// when it has no letters
+func soundex(word string) string {
+	code := make([]byte, 0, 4)
+	var last byte
+	for i := 0; i < len(word) && len(code) < 4; i++ {
+		c := word[i] | 0x20 // lower-case ASCII letters
+		if c < 'a' || c > 'z' {
+			continue
+		}
+		digit := soundexCodes[c-'a']
+		if len(code) == 0 {
+			code = append(code, c-0x20)
+			last = digit
+			continue
+		}
+		if digit != '0' && digit != last {
+			code = append(code, digit)
+		}
+		// h and w don't separate letters with the same code; vowels do
+		if c != 'h' && c != 'w' {
+			last = digit
+		}
+	}
+	if len(code) == 0 {
+		return ""
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
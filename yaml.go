@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// YAML: template libraries, stimulus protocols and dialogue scripts are
+// YAML or JSON. The YAML is a block-style subset parsed here without a
+// dependency, then converted to JSON and decoded with encoding/json. Plain
+// scalars are typed by the field they land in rather than by how they
+// look, so a word list can hold "2024" or "no" and only numeric fields
+// accept numbers.
+
+// yamlLine is a significant line of a YAML document
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlPlain is an unquoted scalar. Whether it's a string, number or bool
+// depends on the field it decodes into, so "2024" stays a string in a
+// []string and "inf" never reaches json.Marshal as a float.
+type yamlPlain string
+
+// unmarshalYAMLOrJSON decodes data into v as YAML when path ends in .yaml
+// or .yml, and as JSON otherwise
+func unmarshalYAMLOrJSON(path string, data []byte, v interface{}) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		value, err := parseYAML(string(data))
+		if err != nil {
+			return err
+		}
+		if value, err = resolveYAML(value, reflect.TypeOf(v)); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// resolveYAML types the plain scalars in a parsed document by the Go type
+// it decodes into; t is nil where the type is unknown
+func resolveYAML(value interface{}, t reflect.Type) (interface{}, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			var field reflect.Type
+			if t != nil && t.Kind() == reflect.Map {
+				field = t.Elem()
+			} else if t != nil && t.Kind() == reflect.Struct {
+				field = yamlFieldType(t, key)
+			}
+			resolved, err := resolveYAML(item, field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i, item := range v {
+			resolved, err := resolveYAML(item, elem)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			v[i] = resolved
+		}
+	case yamlPlain:
+		return resolveYAMLPlain(string(v), t)
+	}
+	return value, nil
+}
+
+// resolveYAMLPlain converts a plain scalar for a field of type t: numbers
+// for numeric fields, true/false for bools, and a string for anything else
+func resolveYAMLPlain(text string, t reflect.Type) (interface{}, error) {
+	if t == nil {
+		return text, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if text != "true" && text != "false" {
+			return nil, fmt.Errorf("expected true or false, got %q", text)
+		}
+		return text == "true", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, fmt.Errorf("expected a finite number, got %q", text)
+		}
+		return n, nil
+	}
+	return text, nil
+}
+
+// yamlFieldType returns the type of the struct field key decodes into,
+// matching JSON names case-insensitively as encoding/json does, or nil
+func yamlFieldType(t reflect.Type, key string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found := yamlFieldType(embedded, key); found != nil {
+					return found
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// parseYAML parses the block-style YAML subset genesis files use:
+// nested mappings and "- " sequences, flow sequences like [a, b], quoted
+// and plain scalars, and # comments. Anchors, multi-line scalars and
+// multiple documents aren't supported.
+func parseYAML(doc string) (interface{}, error) {
+	var lines []yamlLine
+	for i, line := range strings.Split(doc, "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't indent YAML", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(line) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	return value, nil
+}
+
+// opensYAMLQuote reports whether the quote at text[i] starts a quoted
+// scalar rather than being an apostrophe inside a plain one
+func opensYAMLQuote(text string, i int) bool {
+	return i == 0 || strings.ContainsRune(" [,:", rune(text[i-1]))
+}
+
+// stripYAMLComment removes a # comment outside quotes
+func stripYAMLComment(text string) string {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && opensYAMLQuote(text, i):
+			quote = r
+		case r == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// isYAMLItem reports whether text starts a sequence item
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseYAMLBlock parses the mapping or sequence starting at lines[i],
+// indented by indent, returning it and the index of the line after it
+func parseYAMLBlock(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if isYAMLItem(lines[i].text) {
+		var items []interface{}
+		for i < len(lines) && lines[i].indent == indent && isYAMLItem(lines[i].text) {
+			item := strings.TrimLeft(strings.TrimPrefix(lines[i].text, "-"), " ")
+			var value interface{}
+			var err error
+			switch {
+			case item == "":
+				value, i, err = parseYAMLNested(lines, i+1, indent)
+			case splitYAMLKey(item) != "":
+				// A mapping starting on the item's line
+				nested := append([]yamlLine{}, lines...)
+				nested[i] = yamlLine{number: lines[i].number, indent: indent + len(lines[i].text) - len(item), text: item}
+				value, i, err = parseYAMLBlock(nested, i, nested[i].indent)
+			default:
+				value, err = parseYAMLScalar(item)
+				i++
+			}
+			if err != nil {
+				return nil, i, err
+			}
+			items = append(items, value)
+		}
+		return items, i, nil
+	}
+
+	mapping := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key := splitYAMLKey(line.text)
+		if key == "" {
+			return nil, i, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		rest := strings.TrimSpace(line.text[len(key)+1:])
+		name, err := parseYAMLScalar(key)
+		if err != nil {
+			return nil, i, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		var value interface{}
+		if rest == "" {
+			value, i, err = parseYAMLNested(lines, i+1, indent)
+		} else {
+			value, err = parseYAMLScalar(rest)
+			i++
+		}
+		if err != nil {
+			return nil, i, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		mapping[fmt.Sprint(name)] = value
+	}
+	return mapping, i, nil
+}
+
+// parseYAMLNested parses the block under a key or item at indent: deeper
+// lines, or a sequence at the same indent, or nothing (null)
+func parseYAMLNested(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if i >= len(lines) {
+		return nil, i, nil
+	}
+	if lines[i].indent > indent || (lines[i].indent == indent && isYAMLItem(lines[i].text)) {
+		return parseYAMLBlock(lines, i, lines[i].indent)
+	}
+	return nil, i, nil
+}
+
+// splitYAMLKey returns the key of a "key: value" or "key:" line, or ""
+func splitYAMLKey(text string) string {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && opensYAMLQuote(text, i):
+			quote = r
+		case r == '[' || r == '{':
+			return ""
+		case r == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return text[:i]
+		}
+	}
+	return ""
+}
+
+// parseYAMLScalar parses a quoted or plain scalar or a flow sequence
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %q", text)
+		}
+		items := []interface{}{}
+		for _, part := range splitYAMLFlow(text[1 : len(text)-1]) {
+			item, err := parseYAMLScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case strings.HasPrefix(text, `"`):
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case text == "null" || text == "~":
+		return nil, nil
+	}
+	return yamlPlain(text), nil
+}
+
+// splitYAMLFlow splits a flow sequence's contents at commas outside quotes
+func splitYAMLFlow(text string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && opensYAMLQuote(text, i):
+			quote = r
+		case r == ',':
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}